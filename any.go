@@ -0,0 +1,396 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// Any is a JSON value that is parsed lazily: looking up a member or an element
+// only scans the document as far as needed, and the values scanned are cached
+// for later lookups. It suits code that inspects a small part of each document:
+//
+//	name := json.ParseAny(data).Get("users", 0, "name").ToString()
+//
+// Lookups never fail immediately. A missing member or element, or a syntax error
+// met while scanning, makes the returned Any hold the error, which Err reports and
+// which every value derived from it carries. The To methods convert the value
+// leniently and return the zero value when it cannot be converted.
+// An Any is not safe for concurrent use.
+type Any struct {
+	buf        []byte // nul-terminated document
+	start, end int64
+	err        error
+
+	next    int64 // cursor of the first member or element not scanned yet
+	done    bool  // all members or elements have been scanned
+	members map[string]*Any
+	keys    []string
+	elems   []*Any
+	str     *string
+}
+
+// ParseAny returns data as an Any. data is copied, so it may be modified afterwards.
+func ParseAny(data []byte) *Any {
+	buf := make([]byte, len(data)+1)
+	copy(buf, data)
+	a := &Any{buf: buf}
+	a.start = skipWhiteSpace(buf, 0)
+	end, err := skipAny(buf, a.start)
+	if err != nil {
+		return &Any{err: err}
+	}
+	a.end = end
+	a.next = a.start + 1
+	return a
+}
+
+func anyError(err error) *Any {
+	return &Any{err: err}
+}
+
+// skipAny returns the cursor after the value at cursor.
+func skipAny(buf []byte, cursor int64) (int64, error) {
+	cursor = skipWhiteSpace(buf, cursor)
+	switch buf[cursor] {
+	case nul:
+		return 0, errUnexpectedEndOfJSON("value", cursor)
+	case '"':
+		return skipStringBytes(buf, cursor)
+	case '{', '[':
+		return skipSubtree(buf, cursor)
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 't', 'f', 'n':
+		return skipValue(buf, cursor)
+	}
+	return 0, errInvalidCharacter(buf[cursor], "beginning of value", cursor)
+}
+
+// Err returns the error of the lookup or scan that produced a, or nil.
+func (a *Any) Err() error {
+	return a.err
+}
+
+// Exists reports whether a holds a value, that is, whether Err returns nil.
+func (a *Any) Exists() bool {
+	return a.err == nil
+}
+
+// Kind returns the kind of the value, or NullNode if a holds an error.
+func (a *Any) Kind() NodeKind {
+	if a.err != nil {
+		return NullNode
+	}
+	switch a.buf[a.start] {
+	case '{':
+		return ObjectNode
+	case '[':
+		return ArrayNode
+	case '"':
+		return StringNode
+	case 't', 'f':
+		return BoolNode
+	case 'n':
+		return NullNode
+	}
+	return NumberNode
+}
+
+// Raw returns the JSON text of the value, or nil if a holds an error.
+func (a *Any) Raw() RawMessage {
+	if a.err != nil {
+		return nil
+	}
+	return RawMessage(a.buf[a.start:a.end:a.end])
+}
+
+// Decode stores the value in the value pointed to by v, in the same manner as Unmarshal.
+func (a *Any) Decode(v interface{}) error {
+	if a.err != nil {
+		return a.err
+	}
+	return Unmarshal(a.Raw(), v)
+}
+
+// MarshalJSON returns the JSON text of the value.
+func (a *Any) MarshalJSON() ([]byte, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	return a.Raw(), nil
+}
+
+// UnmarshalJSON makes a hold a copy of data, to be parsed lazily.
+func (a *Any) UnmarshalJSON(data []byte) error {
+	*a = *ParseAny(data)
+	return a.err
+}
+
+// Get returns the value at path below a, where each element of path is
+// the string key of an object member or the int index of an array element.
+// Of duplicate members, the first one is returned.
+func (a *Any) Get(path ...interface{}) *Any {
+	cur := a
+	for _, p := range path {
+		if cur.err != nil {
+			return cur
+		}
+		switch p := p.(type) {
+		case string:
+			cur = cur.member(p)
+		case int:
+			cur = cur.index(p)
+		default:
+			return anyError(fmt.Errorf("json: invalid path element %v of type %T", p, p))
+		}
+	}
+	return cur
+}
+
+func (a *Any) member(key string) *Any {
+	if a.Kind() != ObjectNode {
+		return anyError(fmt.Errorf("json: cannot get member %q of %v", key, a.Kind()))
+	}
+	if v, exists := a.members[key]; exists {
+		return v
+	}
+	for !a.done {
+		k, v, err := a.scanMember()
+		if err != nil {
+			return anyError(err)
+		}
+		if v != nil && k == key {
+			return v
+		}
+	}
+	return anyError(fmt.Errorf("json: member %q not found", key))
+}
+
+func (a *Any) index(i int) *Any {
+	if a.Kind() != ArrayNode {
+		return anyError(fmt.Errorf("json: cannot get element %d of %v", i, a.Kind()))
+	}
+	for len(a.elems) <= i && !a.done {
+		if err := a.scanElem(); err != nil {
+			return anyError(err)
+		}
+	}
+	if i < 0 || i >= len(a.elems) {
+		return anyError(fmt.Errorf("json: index %d out of range of array of length %d", i, len(a.elems)))
+	}
+	return a.elems[i]
+}
+
+// scanMember scans the next member of an object, returning a nil value at its end.
+func (a *Any) scanMember() (string, *Any, error) {
+	cursor, err := a.scanSeparator('}')
+	if err != nil || a.done {
+		return "", nil, err
+	}
+	if a.buf[cursor] != '"' {
+		return "", nil, errInvalidCharacter(a.buf[cursor], "object key", cursor)
+	}
+	keyEnd, err := skipStringBytes(a.buf, cursor)
+	if err != nil {
+		return "", nil, err
+	}
+	key, err := anyKey(a.buf[cursor:keyEnd])
+	if err != nil {
+		return "", nil, err
+	}
+	cursor = skipWhiteSpace(a.buf, keyEnd)
+	if a.buf[cursor] != ':' {
+		return "", nil, errExpected("colon after object key", cursor)
+	}
+	v, err := a.scanValue(cursor + 1)
+	if err != nil {
+		return "", nil, err
+	}
+	if a.members == nil {
+		a.members = map[string]*Any{}
+	}
+	if _, exists := a.members[key]; !exists {
+		a.keys = append(a.keys, key)
+		a.members[key] = v
+	}
+	return key, v, nil
+}
+
+func (a *Any) scanElem() error {
+	cursor, err := a.scanSeparator(']')
+	if err != nil || a.done {
+		return err
+	}
+	v, err := a.scanValue(cursor)
+	if err != nil {
+		return err
+	}
+	a.elems = append(a.elems, v)
+	return nil
+}
+
+// scanSeparator skips the comma before the next member or element
+// and returns its cursor, marking a as done at the closing bracket.
+func (a *Any) scanSeparator(closer byte) (int64, error) {
+	cursor := skipWhiteSpace(a.buf, a.next)
+	if a.buf[cursor] == closer {
+		a.done = true
+		return cursor, nil
+	}
+	if len(a.keys) > 0 || len(a.elems) > 0 {
+		if a.buf[cursor] != ',' {
+			if closer == '}' {
+				return 0, errExpected("comma after object element", cursor)
+			}
+			return 0, errExpected("comma after array element", cursor)
+		}
+		cursor = skipWhiteSpace(a.buf, cursor+1)
+	}
+	return cursor, nil
+}
+
+func (a *Any) scanValue(cursor int64) (*Any, error) {
+	start := skipWhiteSpace(a.buf, cursor)
+	end, err := skipAny(a.buf, start)
+	if err != nil {
+		return nil, err
+	}
+	a.next = end
+	return &Any{buf: a.buf, start: start, end: end, next: start + 1}, nil
+}
+
+// anyKey returns the value of the quoted object key raw.
+func anyKey(raw []byte) (string, error) {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return string(raw[1 : len(raw)-1]), nil
+	}
+	var key string
+	if err := Unmarshal(raw, &key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Len returns the number of members of an object or the number of elements of an array, and 0 otherwise.
+func (a *Any) Len() int {
+	switch a.Kind() {
+	case ObjectNode:
+		return len(a.Keys())
+	case ArrayNode:
+		for !a.done {
+			if err := a.scanElem(); err != nil {
+				return len(a.elems)
+			}
+		}
+		return len(a.elems)
+	}
+	return 0
+}
+
+// Keys returns the keys of the members of an object in document order, or nil.
+func (a *Any) Keys() []string {
+	if a.Kind() != ObjectNode {
+		return nil
+	}
+	for !a.done {
+		if _, _, err := a.scanMember(); err != nil {
+			break
+		}
+	}
+	return append([]string{}, a.keys...)
+}
+
+// ToString returns the value of a string, the empty string for null and
+// an error, and the JSON text of any other value.
+func (a *Any) ToString() string {
+	switch a.Kind() {
+	case StringNode:
+		if a.str == nil {
+			var s string
+			if err := Unmarshal(a.Raw(), &s); err != nil {
+				return ""
+			}
+			a.str = &s
+		}
+		return *a.str
+	case NullNode:
+		return ""
+	}
+	return string(a.Raw())
+}
+
+// number returns the text of a number, or of the number held by a string.
+func (a *Any) number() string {
+	switch a.Kind() {
+	case NumberNode:
+		return string(a.Raw())
+	case StringNode:
+		return a.ToString()
+	}
+	return ""
+}
+
+// ToInt64 returns the value of a number or of a string holding a number,
+// truncated towards zero, 1 for true and 0 otherwise.
+func (a *Any) ToInt64() int64 {
+	if a.Kind() == BoolNode {
+		return int64(boolInt(a.ToBool()))
+	}
+	s := a.number()
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return int64(f)
+}
+
+// ToInt returns ToInt64 as an int.
+func (a *Any) ToInt() int {
+	return int(a.ToInt64())
+}
+
+// ToUint64 returns the value of a non-negative number or of a string holding one,
+// truncated towards zero, 1 for true and 0 otherwise.
+func (a *Any) ToUint64() uint64 {
+	if a.Kind() == BoolNode {
+		return uint64(boolInt(a.ToBool()))
+	}
+	s := a.number()
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n
+	}
+	if f, _ := strconv.ParseFloat(s, 64); f > 0 {
+		return uint64(f)
+	}
+	return 0
+}
+
+// ToFloat64 returns the value of a number or of a string holding a number, 1 for true and 0 otherwise.
+func (a *Any) ToFloat64() float64 {
+	if a.Kind() == BoolNode {
+		return float64(boolInt(a.ToBool()))
+	}
+	f, _ := strconv.ParseFloat(a.number(), 64)
+	return f
+}
+
+// ToBool returns the value of a boolean, whether a number is not zero,
+// the value of a string holding a boolean, and false otherwise.
+func (a *Any) ToBool() bool {
+	switch a.Kind() {
+	case BoolNode:
+		return a.buf[a.start] == 't'
+	case NumberNode:
+		return a.ToFloat64() != 0
+	case StringNode:
+		b, _ := strconv.ParseBool(a.ToString())
+		return b
+	}
+	return false
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package json_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_Any(t *testing.T) {
+	data := []byte(` {"users": [{"name": "alice", "age": 31, "tags": ["a", "b"]}, {"name": "b\"ob", "age": "42", "admin": true}],
+		"total": 2.5, "next": null, "escaped": 1} `)
+	doc := json.ParseAny(data)
+	t.Run("get", func(t *testing.T) {
+		assertEq(t, "kind", json.ObjectNode, doc.Kind())
+		assertEq(t, "name", "alice", doc.Get("users", 0, "name").ToString())
+		assertEq(t, "escaped name", `b"ob`, doc.Get("users", 1, "name").ToString())
+		assertEq(t, "age", 31, doc.Get("users", 0, "age").ToInt())
+		assertEq(t, "age from string", int64(42), doc.Get("users", 1, "age").ToInt64())
+		assertEq(t, "admin", true, doc.Get("users", 1, "admin").ToBool())
+		assertEq(t, "tag", "b", doc.Get("users", 0, "tags", 1).ToString())
+		assertEq(t, "total", 2.5, doc.Get("total").ToFloat64())
+		assertEq(t, "total as int", 2, doc.Get("total").ToInt())
+		assertEq(t, "next", json.NullNode, doc.Get("next").Kind())
+		assertEq(t, "next exists", true, doc.Get("next").Exists())
+		assertEq(t, "escaped key", 1, doc.Get("escaped").ToInt())
+		assertEq(t, "raw", `["a", "b"]`, string(doc.Get("users", 0, "tags").Raw()))
+	})
+	t.Run("cached", func(t *testing.T) {
+		users := doc.Get("users")
+		if users != doc.Get("users") || users.Get(1) != doc.Get("users", 1) {
+			t.Fatal("expected lookups to return the cached value")
+		}
+		assertEq(t, "len", 2, users.Len())
+		assertEq(t, "keys", "[users total next escaped]", fmt.Sprint(doc.Keys()))
+	})
+	t.Run("missing", func(t *testing.T) {
+		for _, path := range [][]interface{}{
+			{"nothing"}, {"users", 5}, {"users", -1}, {"total", "x"}, {"users", "x"}, {"users", 0, "name", 0}, {1.5},
+			{"nothing", "deeper"},
+		} {
+			v := doc.Get(path...)
+			if v.Err() == nil {
+				t.Fatalf("%v: expected error", path)
+			}
+			assertEq(t, fmt.Sprint(path), "", v.ToString())
+			assertEq(t, fmt.Sprint(path), 0, v.ToInt())
+		}
+	})
+	t.Run("lazy", func(t *testing.T) {
+		v := json.ParseAny([]byte(`{"a": 1, "b": [1, }`))
+		if v.Err() == nil {
+			t.Fatal("expected error for an unbalanced document")
+		}
+		v = json.ParseAny([]byte(`{"a": 1, "b" 2}`))
+		assertEq(t, "before the error", 1, v.Get("a").ToInt())
+		if v.Get("b").Err() == nil {
+			t.Fatal("expected syntax error")
+		}
+	})
+	t.Run("decode", func(t *testing.T) {
+		var user struct {
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		}
+		assertErr(t, doc.Get("users", 0).Decode(&user))
+		assertEq(t, "name", "alice", user.Name)
+		assertEq(t, "tags", "[a b]", fmt.Sprint(user.Tags))
+	})
+	t.Run("field", func(t *testing.T) {
+		var v struct {
+			ID      int       `json:"id"`
+			Payload *json.Any `json:"payload"`
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"id":1,"payload":{"kind":"x","n":[1,2,3]}}`), &v))
+		assertEq(t, "kind", "x", v.Payload.Get("kind").ToString())
+		assertEq(t, "n", 3, v.Payload.Get("n").Len())
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"id":1,"payload":{"kind":"x","n":[1,2,3]}}`, string(bytes))
+	})
+}
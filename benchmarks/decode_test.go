@@ -3,6 +3,7 @@ package benchmark
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"testing"
 
 	gojay "github.com/francoispqt/gojay"
@@ -333,3 +334,76 @@ func Benchmark_Decode_LargeStruct_Stream_GoJson(b *testing.B) {
 		}
 	}
 }
+
+// SparsePayload extracts a single member of LargeFixture, skipping everything else.
+type SparsePayload struct {
+	Topics struct {
+		MoreTopicsUrl string `json:"more_topics_url"`
+	} `json:"topics"`
+}
+
+func Benchmark_Decode_SparseStruct_Unmarshal_EncodingJson(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		result := SparsePayload{}
+		if err := json.Unmarshal(LargeFixture, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Decode_SparseStruct_Unmarshal_GoJson(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		result := SparsePayload{}
+		if err := gojson.Unmarshal(LargeFixture, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Decode_SparseStruct_Stream_GoJson(b *testing.B) {
+	b.ReportAllocs()
+	reader := bytes.NewReader(LargeFixture)
+	for i := 0; i < b.N; i++ {
+		result := SparsePayload{}
+		reader.Reset(LargeFixture)
+		if err := gojson.NewDecoder(reader).Decode(&result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// FloatsFixture is an array of coordinates, as found in geographic data.
+var FloatsFixture = func() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, "[%.6f,%.13f,%g]", float64(i)*0.37-180, float64(i)*1.234567+0.1, float64(i)/7)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}()
+
+func Benchmark_Decode_Floats_Unmarshal_EncodingJson(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		var result [][]float64
+		if err := json.Unmarshal(FloatsFixture, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Decode_Floats_Unmarshal_GoJson(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		var result [][]float64
+		if err := gojson.Unmarshal(FloatsFixture, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
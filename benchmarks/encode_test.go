@@ -119,3 +119,51 @@ func Benchmark_Encode_LargeStruct_GoJson(b *testing.B) {
 		}
 	}
 }
+
+func floatsValue(b *testing.B) [][]float64 {
+	var v [][]float64
+	if err := json.Unmarshal(FloatsFixture, &v); err != nil {
+		b.Fatal(err)
+	}
+	return v
+}
+
+func Benchmark_Encode_Floats_EncodingJson(b *testing.B) {
+	v := floatsValue(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Encode_Floats_GoJson(b *testing.B) {
+	v := floatsValue(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gojson.Marshal(v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Encode_MediumStruct_Indent_EncodingJson(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.MarshalIndent(NewMediumPayload(), "", "  "); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func Benchmark_Encode_MediumStruct_Indent_GoJson(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := gojson.MarshalIndent(NewMediumPayload(), "", "  "); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package json
+
+import (
+	"encoding"
+	"encoding/base64"
+	"reflect"
+	"unsafe"
+)
+
+var (
+	marshalBinaryType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	unmarshalBinaryType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+// binaryType returns how values of typ are encoded as the base64 string of MarshalBinary
+// and decoded with UnmarshalBinary, with SetBinaryMarshalers and UseBinaryUnmarshalers,
+// or nil. A side whose type has JSON or text methods keeps using them. The methods are
+// called on the address of the value, so those of the pointer type are used as well.
+func binaryType(typ *rtype, encodes, decodes bool) *wellKnownType {
+	if typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Interface {
+		return nil
+	}
+	ptrType := ptrTo(typ)
+	encodes = encodes && ptrType.Implements(marshalBinaryType) && !isMarshalerType(rtype2type(ptrType))
+	decodes = decodes && ptrType.Implements(unmarshalBinaryType) &&
+		!ptrType.Implements(unmarshalFromType) && !ptrType.Implements(unmarshalJSONType) &&
+		!ptrType.Implements(unmarshalTextType)
+	if !encodes && !decodes {
+		return nil
+	}
+	t := rtype2type(typ)
+	w := &wellKnownType{sourceFunc: "MarshalBinary"}
+	if encodes {
+		w.appendText = func(b []byte, p unsafe.Pointer) ([]byte, error) {
+			data, err := reflect.NewAt(t, p).Interface().(encoding.BinaryMarshaler).MarshalBinary()
+			if err != nil {
+				return b, err
+			}
+			start := len(b)
+			b = append(b, make([]byte, base64.StdEncoding.EncodedLen(len(data)))...)
+			base64.StdEncoding.Encode(b[start:], data)
+			return b, nil
+		}
+	}
+	if decodes {
+		w.decodeString = func(s []byte, p unsafe.Pointer) error {
+			data := make([]byte, base64.StdEncoding.DecodedLen(len(s)))
+			n, err := base64.StdEncoding.Decode(data, s)
+			if err != nil {
+				return err
+			}
+			return reflect.NewAt(t, p).Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data[:n])
+		}
+	}
+	return w
+}
@@ -0,0 +1,106 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type binaryDigest struct {
+	sum [4]byte
+}
+
+func (d binaryDigest) MarshalBinary() ([]byte, error) {
+	if d == (binaryDigest{}) {
+		return nil, errors.New("empty digest")
+	}
+	return d.sum[:], nil
+}
+
+func (d *binaryDigest) UnmarshalBinary(b []byte) error {
+	if len(b) != len(d.sum) {
+		return errors.New("invalid digest length")
+	}
+	copy(d.sum[:], b)
+	return nil
+}
+
+type binaryTextKey struct {
+	id int
+}
+
+func (binaryTextKey) MarshalBinary() ([]byte, error) { return []byte{1}, nil }
+func (binaryTextKey) MarshalText() ([]byte, error)   { return []byte("text"), nil }
+
+type binaryRecord struct {
+	Digest binaryDigest  `json:"digest"`
+	Ptr    *binaryDigest `json:"ptr"`
+	Key    binaryTextKey `json:"key"`
+}
+
+func Test_BinaryMarshalers(t *testing.T) {
+	encode := func(v interface{}, on bool) (string, error) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetBinaryMarshalers(on)
+		err := enc.Encode(v)
+		return strings.TrimSpace(buf.String()), err
+	}
+	v := binaryRecord{Digest: binaryDigest{[4]byte{1, 2, 3, 4}}}
+	t.Run("disabled", func(t *testing.T) {
+		got, err := encode(v, false)
+		assertErr(t, err)
+		assertEq(t, "struct", `{"digest":{},"ptr":null,"key":"text"}`, got)
+	})
+	t.Run("encode", func(t *testing.T) {
+		got, err := encode(v, true)
+		assertErr(t, err)
+		assertEq(t, "base64", `{"digest":"AQIDBA==","ptr":null,"key":"text"}`, got)
+		got, err = encode(&binaryDigest{[4]byte{5, 6, 7, 8}}, true)
+		assertErr(t, err)
+		assertEq(t, "pointer", `"BQYHCA=="`, got)
+		got, err = encode([]interface{}{binaryDigest{[4]byte{1, 2, 3, 4}}}, true)
+		assertErr(t, err)
+		assertEq(t, "interface", `["AQIDBA=="]`, got)
+		_, err = encode(binaryRecord{}, true)
+		var merr *json.MarshalerError
+		if !errors.As(err, &merr) || !strings.Contains(err.Error(), "MarshalBinary") {
+			t.Fatalf("expected MarshalBinary error, got %v", err)
+		}
+	})
+	t.Run("decode", func(t *testing.T) {
+		var decoded binaryRecord
+		dec := json.NewDecoder(strings.NewReader(`{"digest":"AQIDBA==","ptr":"BQYHCA=="}`))
+		dec.UseBinaryUnmarshalers()
+		assertErr(t, dec.Decode(&decoded))
+		assertEq(t, "digest", binaryDigest{[4]byte{1, 2, 3, 4}}, decoded.Digest)
+		if decoded.Ptr == nil || *decoded.Ptr != (binaryDigest{[4]byte{5, 6, 7, 8}}) {
+			t.Fatalf("unexpected pointer %v", decoded.Ptr)
+		}
+
+		for _, src := range []string{`{"digest":"AQI"}`, `{"digest":"AQI="}`, `{"digest":12}`} {
+			dec := json.NewDecoder(strings.NewReader(src))
+			dec.UseBinaryUnmarshalers()
+			if err := dec.Decode(&decoded); err == nil {
+				t.Fatalf("expected error for %s", src)
+			}
+		}
+	})
+	t.Run("config", func(t *testing.T) {
+		api := json.Config{BinaryMarshalers: true, BinaryUnmarshalers: true}.Freeze()
+		bytes, err := api.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"digest":"AQIDBA==","ptr":null,"key":"text"}`, string(bytes))
+		var decoded binaryRecord
+		assertErr(t, api.Unmarshal([]byte(`{"digest":"CQoLDA==","ptr":null}`), &decoded))
+		assertEq(t, "unmarshal", binaryDigest{[4]byte{9, 10, 11, 12}}, decoded.Digest)
+	})
+	t.Run("cached apart", func(t *testing.T) {
+		got, err := encode(v, false)
+		assertErr(t, err)
+		assertEq(t, "struct", `{"digest":{},"ptr":null,"key":"text"}`, got)
+	})
+}
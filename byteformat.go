@@ -0,0 +1,128 @@
+package json
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	hexenc "encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// byteFormats are the converters of the "format" option of []byte fields,
+// which select the representation of the bytes instead of standard base64:
+//
+//	Digest []byte `json:"digest,format:hex"`
+//
+// The formats are base64, base64url, base32, hex and array, a JSON array of numbers.
+var byteFormats = map[string]*Converter{
+	"base64":    stringByteFormat(base64.StdEncoding.EncodeToString, base64.StdEncoding.DecodeString),
+	"base64url": stringByteFormat(base64.URLEncoding.EncodeToString, base64.URLEncoding.DecodeString),
+	"base32":    stringByteFormat(base32.StdEncoding.EncodeToString, base32.StdEncoding.DecodeString),
+	"hex":       stringByteFormat(hexenc.EncodeToString, hexenc.DecodeString),
+	"array":     {Encode: encodeByteArray, Decode: decodeByteArray},
+}
+
+var bytesType = reflect.TypeOf([]byte(nil))
+
+// fieldByteFormat returns the converter named by the "format" option of the field
+// tag options, or nil.
+func fieldByteFormat(field reflect.StructField, opts []string) (*Converter, error) {
+	for _, opt := range opts[1:] {
+		if !strings.HasPrefix(opt, "format:") {
+			continue
+		}
+		name := opt[len("format:"):]
+		c, exists := byteFormats[name]
+		if !exists {
+			return nil, fmt.Errorf("json: unknown format %q for field %s", name, field.Name)
+		}
+		if field.Type.Kind() != reflect.Slice || field.Type.Elem().Kind() != reflect.Uint8 {
+			return nil, fmt.Errorf("json: format %q for field %s of type %s, which is not a []byte", name, field.Name, field.Type)
+		}
+		return c, nil
+	}
+	return nil, nil
+}
+
+func stringByteFormat(encode func([]byte) string, decode func(string) ([]byte, error)) *Converter {
+	return &Converter{
+		Encode: func(v interface{}) (interface{}, error) {
+			b := reflect.ValueOf(v).Bytes()
+			if b == nil {
+				return nil, nil
+			}
+			return encode(b), nil
+		},
+		Decode: func(v interface{}) (interface{}, error) {
+			switch v := v.(type) {
+			case nil:
+				return nil, nil
+			case string:
+				return decode(v)
+			}
+			return nil, &UnmarshalTypeError{Value: jsonValueKind(v), Type: bytesType}
+		},
+	}
+}
+
+func encodeByteArray(v interface{}) (interface{}, error) {
+	b := reflect.ValueOf(v).Bytes()
+	if b == nil {
+		return nil, nil
+	}
+	numbers := make([]uint16, len(b))
+	for i, c := range b {
+		numbers[i] = uint16(c)
+	}
+	return numbers, nil
+}
+
+func decodeByteArray(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	elems, ok := v.([]interface{})
+	if !ok {
+		return nil, &UnmarshalTypeError{Value: jsonValueKind(v), Type: bytesType}
+	}
+	b := make([]byte, len(elems))
+	for i, elem := range elems {
+		var f float64
+		switch elem := elem.(type) {
+		case float64:
+			f = elem
+		case Number:
+			n, err := elem.Float64()
+			if err != nil {
+				return nil, err
+			}
+			f = n
+		default:
+			return nil, &UnmarshalTypeError{Value: jsonValueKind(elem), Type: bytesType.Elem()}
+		}
+		if f < 0 || f > math.MaxUint8 || f != math.Trunc(f) {
+			return nil, &UnmarshalTypeError{Value: fmt.Sprintf("number %v", f), Type: bytesType.Elem()}
+		}
+		b[i] = byte(f)
+	}
+	return b, nil
+}
+
+// jsonValueKind describes a value decoded into an interface{} for an UnmarshalTypeError.
+func jsonValueKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64, Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/goccy/go-json"
+)
+
+// diffFiles prints the differences between the values of two files
+// and returns 1 if there are any.
+func diffFiles(w io.Writer, name1, name2 string) (int, error) {
+	v1, err := decodeFile(name1)
+	if err != nil {
+		return 0, err
+	}
+	v2, err := decodeFile(name2)
+	if err != nil {
+		return 0, err
+	}
+	d := &differ{w: w}
+	d.diff(".", v1, v2)
+	if d.err != nil {
+		return 0, d.err
+	}
+	if d.found {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func decodeFile(name string) (interface{}, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return normalize(v), nil
+}
+
+// normalize converts the map[interface{}]interface{} objects that nested
+// objects are decoded into to map[string]interface{}.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = normalize(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+	}
+	return v
+}
+
+type differ struct {
+	w     io.Writer
+	found bool
+	err   error
+}
+
+func (d *differ) diff(path string, v1, v2 interface{}) {
+	switch v1 := v1.(type) {
+	case map[string]interface{}:
+		if v2, ok := v2.(map[string]interface{}); ok {
+			d.diffObjects(path, v1, v2)
+			return
+		}
+	case []interface{}:
+		if v2, ok := v2.([]interface{}); ok {
+			d.diffArrays(path, v1, v2)
+			return
+		}
+	}
+	if !scalarEqual(v1, v2) {
+		d.print("~", path, v1, v2)
+	}
+}
+
+func (d *differ) diffObjects(path string, v1, v2 map[string]interface{}) {
+	keys := make([]string, 0, len(v1)+len(v2))
+	for k := range v1 {
+		keys = append(keys, k)
+	}
+	for k := range v2 {
+		if _, exists := v1[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e1, in1 := v1[k]
+		e2, in2 := v2[k]
+		switch {
+		case !in2:
+			d.print("-", join(path, k), e1)
+		case !in1:
+			d.print("+", join(path, k), e2)
+		default:
+			d.diff(join(path, k), e1, e2)
+		}
+	}
+}
+
+func (d *differ) diffArrays(path string, v1, v2 []interface{}) {
+	for i := 0; i < len(v1) || i < len(v2); i++ {
+		elemPath := join(path, strconv.Itoa(i))
+		switch {
+		case i >= len(v2):
+			d.print("-", elemPath, v1[i])
+		case i >= len(v1):
+			d.print("+", elemPath, v2[i])
+		default:
+			d.diff(elemPath, v1[i], v2[i])
+		}
+	}
+}
+
+// scalarEqual reports whether two values, of which at most one is an object
+// or array, are equal. Numbers are equal when their values are.
+func scalarEqual(v1, v2 interface{}) bool {
+	if n1, ok := v1.(json.Number); ok {
+		if n2, ok := v2.(json.Number); ok {
+			f1, err1 := n1.Float64()
+			f2, err2 := n2.Float64()
+			if err1 == nil && err2 == nil {
+				return f1 == f2
+			}
+			return n1 == n2
+		}
+		return false
+	}
+	switch v1.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return v1 == v2
+}
+
+func join(path, elem string) string {
+	if path == "." {
+		return elem
+	}
+	return path + "." + elem
+}
+
+func (d *differ) print(op, path string, values ...interface{}) {
+	d.found = true
+	if d.err != nil {
+		return
+	}
+	line := op + " " + path + ":"
+	for i, v := range values {
+		if i > 0 {
+			line += " ->"
+		}
+		text, err := encode(v)
+		if err != nil {
+			d.err = err
+			return
+		}
+		line += " " + text
+	}
+	_, d.err = fmt.Fprintln(d.w, line)
+}
+
+// encode returns the compact JSON text of v with sorted object keys.
+func encode(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSpace(buf.Bytes())), nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/goccy/go-json/jsontext"
+)
+
+// ANSI escape sequences of the colors of fmt -color.
+const (
+	colorReset  = "\x1b[0m"
+	colorName   = "\x1b[1;34m"
+	colorString = "\x1b[32m"
+	colorNumber = "\x1b[36m"
+	colorBool   = "\x1b[33m"
+	colorNull   = "\x1b[90m"
+)
+
+// printer writes the tokens of a stream of values compactly, or one member
+// or element per line when indent is set, keeping the order of the members.
+type printer struct {
+	w      *bufio.Writer
+	pretty bool
+	indent string
+	color  bool
+	stack  []container
+}
+
+// container is an object or array being written.
+type container struct {
+	object bool
+	n      int // number of names and values written in it
+}
+
+// format writes every top-level value read from r to w, each followed by a newline.
+func (p *printer) format(w io.Writer, r io.Reader) error {
+	p.w = bufio.NewWriter(w)
+	dec := jsontext.NewDecoder(r)
+	for {
+		tok, err := dec.ReadToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			p.w.Flush()
+			return err
+		}
+		p.token(tok)
+	}
+	return p.w.Flush()
+}
+
+func (p *printer) token(tok jsontext.Token) {
+	k := tok.Kind()
+	if len(p.stack) > 0 {
+		top := &p.stack[len(p.stack)-1]
+		switch {
+		case k == jsontext.EndObjectKind || k == jsontext.EndArrayKind:
+			p.stack = p.stack[:len(p.stack)-1]
+			if top.n > 0 {
+				p.newline()
+			}
+			p.w.Write(tok.Raw())
+			p.end()
+			return
+		case top.object && top.n%2 == 0:
+			if top.n > 0 {
+				p.w.WriteByte(',')
+			}
+			p.newline()
+			p.colored(colorName, tok.Raw())
+			p.w.WriteByte(':')
+			if p.pretty {
+				p.w.WriteByte(' ')
+			}
+			top.n++
+			return
+		case top.object:
+			top.n++
+		default:
+			if top.n > 0 {
+				p.w.WriteByte(',')
+			}
+			p.newline()
+			top.n++
+		}
+	}
+	switch k {
+	case jsontext.BeginObjectKind, jsontext.BeginArrayKind:
+		p.w.Write(tok.Raw())
+		p.stack = append(p.stack, container{object: k == jsontext.BeginObjectKind})
+		return
+	case jsontext.StringKind:
+		p.colored(colorString, tok.Raw())
+	case jsontext.NumberKind:
+		p.colored(colorNumber, tok.Raw())
+	case jsontext.TrueKind, jsontext.FalseKind:
+		p.colored(colorBool, tok.Raw())
+	default:
+		p.colored(colorNull, tok.Raw())
+	}
+	p.end()
+}
+
+// end ends the line after a complete top-level value.
+func (p *printer) end() {
+	if len(p.stack) == 0 {
+		p.w.WriteByte('\n')
+	}
+}
+
+func (p *printer) newline() {
+	if p.pretty {
+		p.w.WriteByte('\n')
+		p.w.WriteString(strings.Repeat(p.indent, len(p.stack)))
+	}
+}
+
+func (p *printer) colored(color string, raw []byte) {
+	if !p.color {
+		p.w.Write(raw)
+		return
+	}
+	p.w.WriteString(color)
+	p.w.Write(raw)
+	p.w.WriteString(colorReset)
+}
@@ -0,0 +1,182 @@
+// Gojson validates, formats, queries and compares JSON documents.
+//
+// Usage:
+//
+//	gojson validate [file ...]
+//	gojson compact [file ...]
+//	gojson fmt [-indent string] [-color] [file ...]
+//	gojson query path [file ...]
+//	gojson diff file1 file2
+//
+// Without files, input is read from the standard input. compact, fmt and query
+// handle every top-level value of a stream of concatenated values, such as
+// JSON Lines. compact and fmt keep the order of object members and write their
+// output token by token as the input is read. A query path is a list of object keys and array indexes separated
+// by dots, like users.0.name. diff prints the members that were removed (-),
+// added (+) or changed (~) from file1 to file2.
+//
+// The exit status is 1 when validate finds an issue or diff a difference,
+// and 2 on errors.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+const usage = `usage:
+	gojson validate [file ...]
+	gojson compact [file ...]
+	gojson fmt [-indent string] [-color] [file ...]
+	gojson query path [file ...]
+	gojson diff file1 file2
+`
+
+// run runs the command line args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("gojson "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	indent := fs.String("indent", "  ", "indentation of fmt")
+	color := fs.Bool("color", false, "colorize the output of fmt")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	args = fs.Args()
+	var err error
+	status := 0
+	switch cmd {
+	case "validate":
+		status, err = validate(args, stdin, stdout)
+	case "compact":
+		err = formatFiles(args, stdin, stdout, &printer{})
+	case "fmt":
+		err = formatFiles(args, stdin, stdout, &printer{pretty: true, indent: *indent, color: *color})
+	case "query":
+		if len(args) == 0 {
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+		path := parsePath(args[0])
+		err = eachValue(args[1:], stdin, func(v json.RawMessage) error {
+			return query(stdout, v, path)
+		})
+	case "diff":
+		if len(args) != 2 {
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+		status, err = diffFiles(stdout, args[0], args[1])
+	default:
+		fmt.Fprintf(stderr, "gojson: unknown command %q\n%s", cmd, usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gojson %s: %v\n", cmd, err)
+		return 2
+	}
+	return status
+}
+
+// input calls fn with each named file, or with stdin if there are none.
+func input(files []string, stdin io.Reader, fn func(name string, r io.Reader) error) error {
+	if len(files) == 0 {
+		return fn("<stdin>", stdin)
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = fn(name, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachValue calls fn with each top-level value of the input.
+func eachValue(files []string, stdin io.Reader, fn func(json.RawMessage) error) error {
+	return input(files, stdin, func(name string, r io.Reader) error {
+		s := json.SplitStream(r)
+		for s.Next() {
+			if err := fn(s.Raw()); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		if err := s.Err(); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	})
+}
+
+func validate(files []string, stdin io.Reader, stdout io.Writer) (int, error) {
+	status := 0
+	err := input(files, stdin, func(name string, r io.Reader) error {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		for _, issue := range json.ValidateAll(data) {
+			fmt.Fprintf(stdout, "%s:%s\n", name, issue)
+			status = 1
+		}
+		return nil
+	})
+	return status, err
+}
+
+// formatFiles writes the values of the input with p, reading them token by token.
+func formatFiles(files []string, stdin io.Reader, stdout io.Writer, p *printer) error {
+	return input(files, stdin, func(name string, r io.Reader) error {
+		if err := p.format(stdout, r); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	})
+}
+
+// parsePath splits a query path into object keys and array indexes.
+func parsePath(path string) []string {
+	if path == "" || path == "." {
+		return nil
+	}
+	return strings.Split(strings.TrimPrefix(path, "."), ".")
+}
+
+func query(w io.Writer, v json.RawMessage, path []string) error {
+	cur := json.ParseAny(v)
+	for _, elem := range path {
+		if i, err := strconv.Atoi(elem); err == nil && cur.Kind() == json.ArrayNode {
+			cur = cur.Get(i)
+		} else {
+			cur = cur.Get(elem)
+		}
+		if err := cur.Err(); err != nil {
+			return err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	return (&printer{}).format(w, bytes.NewReader(cur.Raw()))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCommand(t *testing.T, stdin string, args ...string) (string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	if status == 2 {
+		t.Logf("stderr: %s", stderr.String())
+	}
+	return stdout.String(), status
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdin  string
+		args   []string
+		want   string
+		status int
+	}{
+		{"validate", `{"a": 1}`, []string{"validate"}, "", 0},
+		{"validate issues", `{"a": 1,}`, []string{"validate"}, "<stdin>:1:8: trailing comma before }\n", 1},
+		{"compact", "{ \"a\" : [1, 2] }\n[ true ]", []string{"compact"}, "{\"a\":[1,2]}\n[true]\n", 0},
+		{"fmt", `{"z":[1],"b":{},"a":[]} 2`, []string{"fmt"}, "{\n  \"z\": [\n    1\n  ],\n  \"b\": {},\n  \"a\": []\n}\n2\n", 0},
+		{"fmt indent", `[1]`, []string{"fmt", "-indent", "\t"}, "[\n\t1\n]\n", 0},
+		{"fmt color", `{"k":"v","n":-1.5,"t":true,"z":null}`, []string{"fmt", "-color", "-indent", ""},
+			"{\n\x1b[1;34m\"k\"\x1b[0m: \x1b[32m\"v\"\x1b[0m,\n\x1b[1;34m\"n\"\x1b[0m: \x1b[36m-1.5\x1b[0m,\n" +
+				"\x1b[1;34m\"t\"\x1b[0m: \x1b[33mtrue\x1b[0m,\n\x1b[1;34m\"z\"\x1b[0m: \x1b[90mnull\x1b[0m\n}\n", 0},
+		{"query", `{"users":[{"name":"a"},{"name":"b"}]}` + "\n" + `{"users":[{},{"name":"c"}]}`,
+			[]string{"query", "users.1.name"}, "\"b\"\n\"c\"\n", 0},
+		{"query object", `{"0":{"x": [1, 2]}}`, []string{"query", "0"}, "{\"x\":[1,2]}\n", 0},
+		{"query missing", `{"a":1}`, []string{"query", "b"}, "", 2},
+		{"syntax error", `{"a":}`, []string{"compact"}, `{"a":`, 2},
+		{"unknown command", ``, []string{"frobnicate"}, "", 2},
+		{"no command", ``, nil, "", 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, status := runCommand(t, test.stdin, test.args...)
+			if status != test.status {
+				t.Fatalf("expected status %d but got %d", test.status, status)
+			}
+			if got != test.want {
+				t.Fatalf("expected output %q but got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gojson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a := writeFile(t, dir, "a.json", `{"name":"x","tags":["a","b"],"n":1,"obj":{"k":1},"gone":true}`)
+	b := writeFile(t, dir, "b.json", `{"name":"y","tags":["a"],"n":1.0,"obj":{"k":1,"new":[1]}}`)
+	got, status := runCommand(t, "", "diff", a, b)
+	want := "- gone: true\n~ name: \"x\" -> \"y\"\n+ obj.new: [1]\n- tags.1: \"b\"\n"
+	if status != 1 || got != want {
+		t.Fatalf("expected status 1 and %q but got %d and %q", want, status, got)
+	}
+	got, status = runCommand(t, "", "diff", a, a)
+	if status != 0 || got != "" {
+		t.Fatalf("expected no difference but got %d and %q", status, got)
+	}
+}
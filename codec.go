@@ -0,0 +1,150 @@
+package json
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"unsafe"
+)
+
+// Codec encodes and decodes the values of one type with the codes compiled for it once,
+// as Marshal and Unmarshal do, without looking them up in the caches for every call.
+// Frameworks can hold a Codec per message type:
+//
+//	codec, err := json.Compile(reflect.TypeOf(Request{}))
+//	...
+//	var req Request
+//	err = codec.Unmarshal(body, &req)
+//	b, err := codec.Marshal(resp)
+//
+// A Codec is safe for concurrent use.
+type Codec struct {
+	typ   *rtype
+	codes *opcodeSet // nil for the types encoded without compiled codes
+	dec   decoder    // of the pointer type
+}
+
+// Compile compiles the encoder and the decoder of the values of typ, with the options of
+// Marshal and Unmarshal, and returns the Codec using them. It returns the first error of
+// a type that cannot be encoded or decoded.
+func Compile(typ reflect.Type) (*Codec, error) {
+	t := type2rtype(typ)
+	c := &Codec{typ: t}
+	if !isDataWordKind(typ.Kind()) {
+		enc := NewEncoder(nil)
+		defer enc.release()
+		key := opcodeKey{
+			typeptr: uintptr(unsafe.Pointer(t)),
+			v2:      enc.v2,
+		}
+		if c.codes = enc.opcodes.get(key); c.codes == nil {
+			if _, _, err := enc.compileOpcodeSet(key, t); err != nil {
+				return nil, err
+			}
+			c.codes = enc.opcodes.get(key)
+		}
+	}
+	var d Decoder
+	dec, err := d.compiledDecoder(type2rtype(reflect.PtrTo(typ)))
+	if err != nil {
+		return nil, err
+	}
+	c.dec = dec
+	return c, nil
+}
+
+// Type returns the type of the values of c.
+func (c *Codec) Type() reflect.Type {
+	return rtype2type(c.typ)
+}
+
+// Marshal returns the JSON encoding of v, which must be a value of the type of c or a pointer
+// to one, as Marshal does.
+func (c *Codec) Marshal(v interface{}) ([]byte, error) {
+	enc := NewEncoder(nil)
+	defer enc.release()
+	if err := c.encode(enc, v); err != nil {
+		return nil, err
+	}
+	copied := make([]byte, len(enc.buf))
+	copy(copied, enc.buf)
+	return copied, nil
+}
+
+// Encode writes the JSON encoding of v, which must be a value of the type of c or a pointer
+// to one, to w, as Encoder.Encode does.
+func (c *Codec) Encode(w io.Writer, v interface{}) error {
+	enc := NewEncoder(w)
+	defer enc.release()
+	if err := c.encode(enc, v); err != nil {
+		return err
+	}
+	_, err := w.Write(enc.buf)
+	return err
+}
+
+func (c *Codec) encode(enc *Encoder, v interface{}) error {
+	header := (*interfaceHeader)(unsafe.Pointer(&v))
+	if header.typ != c.typ {
+		if header.typ == nil || header.typ.Kind() != reflect.Ptr || header.typ.Elem() != c.typ {
+			return fmt.Errorf("json: Codec of %s cannot encode %T", c.Type(), v)
+		}
+		return c.encodeElem(enc, header.ptr)
+	}
+	if c.codes == nil {
+		return enc.encodeValue(v)
+	}
+	return c.run(enc, valuePointer(c.typ, header), header)
+}
+
+// encodeElem encodes the value of the type of c that p points to, or null if p is nil.
+func (c *Codec) encodeElem(enc *Encoder, p unsafe.Pointer) error {
+	if p == nil {
+		enc.encodeNull()
+		return nil
+	}
+	if c.codes == nil {
+		return enc.encodeValue(reflect.NewAt(c.Type(), p).Elem().Interface())
+	}
+	ptr := uintptr(p)
+	switch c.typ.Kind() {
+	case reflect.Struct, reflect.Array:
+	default:
+		if isDirectIface(c.Type()) {
+			// the codes take the value itself, as held in an interface
+			ptr = uintptr(*(*unsafe.Pointer)(p))
+		}
+	}
+	return c.run(enc, ptr, p)
+}
+
+// run runs the codes of c on the value at ptr, keeping the memory of ref alive.
+func (c *Codec) run(enc *Encoder, ptr uintptr, ref interface{}) error {
+	code := c.codes.code.Get().(*opcode)
+	code.ptr = ptr
+	err := enc.run(code)
+	runtime.KeepAlive(ref)
+	if err != nil {
+		return err
+	}
+	c.codes.code.Put(code)
+	return nil
+}
+
+// Unmarshal parses the JSON-encoded data and stores the result in the value pointed to by v,
+// which must be a non-nil pointer to the type of c, as Unmarshal does.
+func (c *Codec) Unmarshal(data []byte, v interface{}) error {
+	header := (*interfaceHeader)(unsafe.Pointer(&v))
+	if header.typ == nil || header.typ.Kind() != reflect.Ptr || header.ptr == nil {
+		return &InvalidUnmarshalError{Type: headerType(header)}
+	}
+	if header.typ.Elem() != c.typ {
+		return fmt.Errorf("json: Codec of %s cannot decode into %T", c.Type(), v)
+	}
+	src := make([]byte, len(data)+1) // append nul byte to end
+	copy(src, data)
+	_, err := c.dec.decode(newRuntimeContext(src, &defaultDecodeOptions), utf8BOMLength(src), uintptr(header.ptr))
+	runtime.KeepAlive(v)
+	return err
+}
@@ -0,0 +1,96 @@
+package json_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type codecMessage struct {
+	ID   int      `json:"id"`
+	Tags []string `json:"tags"`
+	Body string   `json:"body,omitempty"`
+}
+
+func Test_Compile(t *testing.T) {
+	codec, err := json.Compile(reflect.TypeOf(codecMessage{}))
+	assertErr(t, err)
+	assertEq(t, "type", reflect.TypeOf(codecMessage{}), codec.Type())
+	msg := codecMessage{ID: 1, Tags: []string{"<a>"}}
+	t.Run("marshal", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			b, err := codec.Marshal(msg)
+			assertErr(t, err)
+			expected, err := json.Marshal(msg)
+			assertErr(t, err)
+			assertEq(t, "marshal", string(expected), string(b))
+		}
+	})
+	t.Run("encode", func(t *testing.T) {
+		var buf bytes.Buffer
+		assertErr(t, codec.Encode(&buf, msg))
+		assertEq(t, "encode", `{"id":1,"tags":["\u003ca\u003e"]}`, buf.String())
+	})
+	t.Run("unmarshal", func(t *testing.T) {
+		var v codecMessage
+		assertErr(t, codec.Unmarshal([]byte(`{"id":2,"tags":["x"],"body":"b"}`), &v))
+		assertEq(t, "id", 2, v.ID)
+		assertEq(t, "tag", "x", v.Tags[0])
+		assertEq(t, "body", "b", v.Body)
+	})
+	t.Run("pointer", func(t *testing.T) {
+		b, err := codec.Marshal(&msg)
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"id":1,"tags":["\u003ca\u003e"]}`, string(b))
+		b, err = codec.Marshal((*codecMessage)(nil))
+		assertErr(t, err)
+		assertEq(t, "nil", `null`, string(b))
+		n := 1
+		for _, v := range []interface{}{
+			map[string]int{"a": 1},
+			&n,
+			struct{ P *int }{&n},
+			[1]*int{&n},
+			"s",
+		} {
+			codec, err := json.Compile(reflect.TypeOf(v))
+			assertErr(t, err)
+			expected, err := json.Marshal(v)
+			assertErr(t, err)
+			p := reflect.New(reflect.TypeOf(v))
+			p.Elem().Set(reflect.ValueOf(v))
+			b, err := codec.Marshal(p.Interface())
+			assertErr(t, err)
+			assertEq(t, "marshal", string(expected), string(b))
+		}
+	})
+	t.Run("wrong type", func(t *testing.T) {
+		if _, err := codec.Marshal(new(int)); err == nil {
+			t.Fatal("expected an error for another pointer type")
+		}
+		var n int
+		if err := codec.Unmarshal([]byte(`1`), &n); err == nil {
+			t.Fatal("expected an error for another type")
+		}
+		if err := codec.Unmarshal([]byte(`{}`), codecMessage{}); err == nil {
+			t.Fatal("expected an error for a non-pointer")
+		}
+	})
+	t.Run("scalar", func(t *testing.T) {
+		codec, err := json.Compile(reflect.TypeOf(""))
+		assertErr(t, err)
+		b, err := codec.Marshal("a")
+		assertErr(t, err)
+		assertEq(t, "marshal", `"a"`, string(b))
+		var s string
+		assertErr(t, codec.Unmarshal([]byte(`"b"`), &s))
+		assertEq(t, "unmarshal", "b", s)
+	})
+	t.Run("unsupported", func(t *testing.T) {
+		if _, err := json.Compile(reflect.TypeOf(complex64(0))); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -0,0 +1,156 @@
+package json
+
+import "io"
+
+// Config is the configuration of an API, set once and frozen with Freeze:
+//
+//	var api = json.Config{
+//		EscapeHTML:            true,
+//		SortMapKeys:           true,
+//		DisallowUnknownFields: true,
+//	}.Freeze()
+//
+//	b, err := api.Marshal(v)
+//
+// The zero Config does not escape HTML characters, unlike Marshal.
+// Each field enables the option of Encoder or Decoder of the same name.
+type Config struct {
+	// encoding
+	EscapeHTML               bool
+	EscapeLineTerminators    bool
+	SortMapKeys              bool
+	OmitNull                 bool
+	TrustedRaw               bool
+	IgnorePromotedMarshalers bool
+	BinaryMarshalers         bool
+	Stringers                bool
+	ErrorMessages            bool
+	FieldOrder               FieldOrder
+	EncodeKeyTransformer     func(key string) string // the naming convention of the written object keys
+
+	// decoding
+	DecodeKeyTransformer       func(key string) string // the naming convention of the read object keys
+	DisallowUnknownFields      bool
+	IgnorePromotedUnmarshalers bool
+	BinaryUnmarshalers         bool
+	UseNumber                  bool
+	AllowSingleQuotes          bool
+	AllowUnquotedKeys          bool
+	AllowControlChars          bool
+	AllowLeadingPlus           bool
+	StrictStrings              bool
+	StrictNumbers              bool
+	AllowExponentIntegers      bool
+	AllowScalarStrings         bool
+	DisallowNull               bool
+	ResetMissingFields         bool
+	ValidateValues             bool
+
+	// both
+	V2Semantics bool
+}
+
+// API encodes and decodes values as configured by the Config it was frozen from.
+// Its configuration cannot change, and it has its own caches of compiled codecs, so that
+// the configurations of different parts of a program do not affect each other.
+// An API is safe for concurrent use.
+type API struct {
+	config   Config
+	opcodes  opcodeMap
+	decoders decoderMap
+}
+
+// Freeze returns the API of a copy of c.
+func (c Config) Freeze() *API {
+	return &API{config: c}
+}
+
+// Config returns the configuration of a.
+func (a *API) Config() Config {
+	return a.config
+}
+
+// Marshal is like the Marshal function, with the configuration of a.
+func (a *API) Marshal(v interface{}) ([]byte, error) {
+	enc := a.NewEncoder(nil)
+	defer enc.release()
+	return enc.encodeForMarshal(v)
+}
+
+// MarshalIndent is like the MarshalIndent function, with the configuration of a.
+func (a *API) MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	enc := a.NewEncoder(nil)
+	defer enc.release()
+	enc.SetIndent(prefix, indent)
+	return enc.encodeForMarshal(v)
+}
+
+// Unmarshal is like the Unmarshal function, with the configuration of a.
+func (a *API) Unmarshal(data []byte, v interface{}) error {
+	src := make([]byte, len(data)+1) // append nul byte to end
+	copy(src, data)
+	opts := a.config.decodeOptions()
+	dec := Decoder{
+		decoders:              &a.decoders,
+		disallowUnknownFields: a.config.DisallowUnknownFields,
+		ignorePromoted:        a.config.IgnorePromotedUnmarshalers,
+		binaryUnmarshalers:    a.config.BinaryUnmarshalers,
+		opts:                  &opts,
+	}
+	return dec.decodeForUnmarshal(src, v)
+}
+
+// NewEncoder returns an encoder writing to w with the configuration of a.
+// Its settings may still be changed, without affecting a.
+func (a *API) NewEncoder(w io.Writer) *Encoder {
+	enc := NewEncoder(w)
+	enc.opcodes = &a.opcodes
+	c := a.config
+	enc.SetV2Semantics(c.V2Semantics)
+	enc.SetEscapeHTML(c.EscapeHTML)
+	enc.SetEscapeLineTerminators(c.EscapeLineTerminators)
+	enc.SetSortMapKeys(c.SortMapKeys)
+	enc.SetOmitNull(c.OmitNull)
+	enc.SetTrustedRaw(c.TrustedRaw)
+	enc.SetIgnorePromotedMarshalers(c.IgnorePromotedMarshalers)
+	enc.SetBinaryMarshalers(c.BinaryMarshalers)
+	enc.SetStringers(c.Stringers)
+	enc.SetErrorMessages(c.ErrorMessages)
+	enc.SetFieldOrder(c.FieldOrder)
+	enc.SetKeyTransformer(c.EncodeKeyTransformer)
+	return enc
+}
+
+// NewDecoder returns a decoder reading from r with the configuration of a.
+// Its settings may still be changed, without affecting a.
+func (a *API) NewDecoder(r io.Reader) *Decoder {
+	dec := NewDecoder(r)
+	dec.decoders = &a.decoders
+	c := a.config
+	dec.SetKeyTransformer(c.DecodeKeyTransformer)
+	dec.disallowUnknownFields = c.DisallowUnknownFields
+	dec.ignorePromoted = c.IgnorePromotedUnmarshalers
+	dec.binaryUnmarshalers = c.BinaryUnmarshalers
+	dec.s.decodeOptions = c.decodeOptions()
+	return dec
+}
+
+// decodeOptions returns the options of c the decoders read while decoding.
+func (c *Config) decodeOptions() decodeOptions {
+	return decodeOptions{
+		useNumber:         c.UseNumber,
+		allowSingleQuotes: c.AllowSingleQuotes,
+		allowUnquotedKeys: c.AllowUnquotedKeys,
+		allowControlChars: c.AllowControlChars,
+		allowLeadingPlus:  c.AllowLeadingPlus,
+		strictStrings:     c.StrictStrings,
+		strictNumbers:     c.StrictNumbers,
+		exponentIntegers:  c.AllowExponentIntegers,
+		scalarStrings:     c.AllowScalarStrings,
+		disallowNull:      c.DisallowNull,
+		resetMissing:      c.ResetMissingFields,
+		validate:          c.ValidateValues,
+		v2:                c.V2Semantics,
+		keyTransformer:    c.DecodeKeyTransformer,
+	}
+}
@@ -0,0 +1,133 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_Config(t *testing.T) {
+	type T struct {
+		UserName string            `json:"user_name"`
+		Tags     map[string]string `json:"tags"`
+	}
+	t.Run("encoding", func(t *testing.T) {
+		api := json.Config{
+			EscapeHTML:           true,
+			SortMapKeys:          true,
+			EncodeKeyTransformer: strings.ToUpper,
+		}.Freeze()
+		v := T{UserName: "<a>", Tags: map[string]string{"b": "2", "a": "1"}}
+		bytes, err := api.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"USER_NAME":"\u003ca\u003e","TAGS":{"A":"1","B":"2"}}`, string(bytes))
+		bytes, err = api.MarshalIndent(v, "", " ")
+		assertErr(t, err)
+		assertEq(t, "indent", "{\n \"USER_NAME\": \"\\u003ca\\u003e\",\n \"TAGS\": {\n  \"A\": \"1\",\n  \"B\": \"2\"\n }\n}", string(bytes))
+		bytes, err = json.Config{}.Freeze().Marshal(v.UserName)
+		assertErr(t, err)
+		assertEq(t, "zero config", `"<a>"`, string(bytes))
+	})
+	t.Run("isolated", func(t *testing.T) {
+		strict := json.Config{DisallowUnknownFields: true}.Freeze()
+		lax := json.Config{}.Freeze()
+		src := []byte(`{"user_name":"a","extra":1}`)
+		for i := 0; i < 2; i++ {
+			var v T
+			if err := strict.Unmarshal(src, &v); err == nil {
+				t.Fatal("expected error for the unknown field")
+			}
+			assertErr(t, lax.Unmarshal(src, &v))
+			assertEq(t, "lax", "a", v.UserName)
+			var buf bytes.Buffer
+			buf.Write(src)
+			if err := strict.NewDecoder(&buf).Decode(&v); err == nil {
+				t.Fatal("expected stream error for the unknown field")
+			}
+			assertErr(t, json.Unmarshal(src, &v))
+		}
+	})
+	t.Run("decoding", func(t *testing.T) {
+		api := json.Config{
+			UseNumber:            true,
+			AllowSingleQuotes:    true,
+			AllowUnquotedKeys:    true,
+			DecodeKeyTransformer: strings.ToLower,
+		}.Freeze()
+		var v map[string]interface{}
+		assertErr(t, api.Unmarshal([]byte(`{Count: 1.5, 'Name': 'x'}`), &v))
+		assertEq(t, "number", json.Number("1.5"), v["count"])
+		assertEq(t, "string", "x", v["name"])
+		if err := json.Unmarshal([]byte(`{Count: 1}`), &v); err == nil {
+			t.Fatal("expected the package functions to be strict")
+		}
+	})
+	t.Run("decoding byte slices", func(t *testing.T) {
+		type V struct {
+			N int
+			U uint
+			F float64
+			S string
+			I interface{}
+			M map[string]int
+			P configPositive
+		}
+		tests := []struct {
+			name   string
+			config json.Config
+			src    string
+			err    bool
+		}{
+			{name: "leading plus", config: json.Config{AllowLeadingPlus: true}, src: `{"N":+1,"U":+2,"F":+1.5,"I":+3}`},
+			{name: "strict strings", config: json.Config{StrictStrings: true}, src: `{"S":"\x"}`, err: true},
+			{name: "strict numbers", config: json.Config{StrictNumbers: true}, src: `{"N":1.5}`, err: true},
+			{name: "exponent integers", config: json.Config{AllowExponentIntegers: true}, src: `{"N":1e3,"U":2.5e1}`},
+			{name: "scalar strings", config: json.Config{AllowScalarStrings: true}, src: `{"S":123}`},
+			{name: "scalar bool strings", config: json.Config{AllowScalarStrings: true}, src: `{"S":true}`},
+			{name: "disallow null", config: json.Config{DisallowNull: true}, src: `{"S":null}`, err: true},
+			{name: "reset missing fields", config: json.Config{ResetMissingFields: true}, src: `{"N":2}`},
+			{name: "validate values", config: json.Config{ValidateValues: true}, src: `{"P":-1}`, err: true},
+			{name: "key transformer", config: json.Config{DecodeKeyTransformer: strings.ToUpper}, src: `{"n":1,"s":"x"}`},
+			{name: "unquoted keys", config: json.Config{AllowUnquotedKeys: true}, src: `{N:1,M:{a:1}}`},
+			{name: "single quotes", config: json.Config{AllowSingleQuotes: true}, src: `{'S':'x','I':'y'}`},
+			{name: "use number", config: json.Config{UseNumber: true}, src: `{"I":1.5}`},
+			{name: "control chars", config: json.Config{AllowControlChars: true}, src: "{\"S\":\"a\tb\"}"},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				api := test.config.Freeze()
+				got := V{S: "old", N: 7}
+				err := api.Unmarshal([]byte(test.src), &got)
+				want := V{S: "old", N: 7}
+				wantErr := api.NewDecoder(strings.NewReader(test.src)).Decode(&want)
+				assertEq(t, "error", test.err, err != nil)
+				assertEq(t, "stream error", test.err, wantErr != nil)
+				if !test.err {
+					assertEq(t, "value", fmt.Sprintf("%#v", want), fmt.Sprintf("%#v", got))
+				}
+			})
+		}
+	})
+	t.Run("frozen", func(t *testing.T) {
+		c := json.Config{SortMapKeys: true}
+		api := c.Freeze()
+		c.SortMapKeys = false
+		assertEq(t, "copied", true, api.Config().SortMapKeys)
+		enc := api.NewEncoder(&bytes.Buffer{})
+		enc.SetSortMapKeys(false)
+		assertEq(t, "unchanged", true, api.Config().SortMapKeys)
+	})
+}
+
+type configPositive int
+
+func (p *configPositive) Validate() error {
+	if *p < 0 {
+		return errors.New("negative")
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// Converter converts the value of a struct field between its Go representation
+// and the representation used in JSON. A field refers to a converter registered
+// with RegisterConverter by name, using the "conv" option of its tag:
+//
+//	// Amount is written and read as an integer number of cents.
+//	Amount float64 `json:"amount,conv=cents"`
+type Converter struct {
+	// Encode returns the value encoded in place of the field value v.
+	// If Encode is nil, the field value is encoded as is.
+	Encode func(v interface{}) (interface{}, error)
+
+	// Decode returns the field value for v, the JSON value decoded as into an interface{}.
+	// The result must be assignable or convertible to the type of the field.
+	// If Decode is nil, the JSON value is decoded into the field as is.
+	Decode func(v interface{}) (interface{}, error)
+}
+
+var (
+	convertersMu sync.RWMutex
+	converters   = map[string]*Converter{}
+)
+
+// RegisterConverter registers c under name for use in the "conv" option of struct field tags.
+// Converters must be registered before the first encoding or decoding of a type using them,
+// typically from an init function; registering a name again replaces the converter.
+func RegisterConverter(name string, c Converter) {
+	convertersMu.Lock()
+	defer convertersMu.Unlock()
+	converters[name] = &c
+}
+
+// fieldConverter returns the converter named by the "conv" option of the field tag options, or nil.
+func fieldConverter(field reflect.StructField, opts []string) (*Converter, error) {
+	if len(opts) < 2 {
+		return nil, nil
+	}
+	if format, err := fieldByteFormat(field, opts); format != nil || err != nil {
+		return format, err
+	}
+	for _, opt := range opts[1:] {
+		if !strings.HasPrefix(opt, "conv=") {
+			continue
+		}
+		name := opt[len("conv="):]
+		convertersMu.RLock()
+		c, exists := converters[name]
+		convertersMu.RUnlock()
+		if !exists {
+			return nil, fmt.Errorf("json: unknown converter %q for field %s", name, field.Name)
+		}
+		return c, nil
+	}
+	return nil, nil
+}
+
+type convertCode struct {
+	*opcodeHeader
+	conv *Converter
+}
+
+func (c *convertCode) copy(codeMap map[uintptr]*opcode) *opcode {
+	if c == nil {
+		return nil
+	}
+	addr := uintptr(unsafe.Pointer(c))
+	if code, exists := codeMap[addr]; exists {
+		return code
+	}
+	conv := &convertCode{conv: c.conv}
+	code := (*opcode)(unsafe.Pointer(conv))
+	codeMap[addr] = code
+
+	conv.opcodeHeader = c.opcodeHeader.copy(codeMap)
+	return code
+}
+
+func (c *opcode) toConvertCode() *convertCode {
+	return (*convertCode)(unsafe.Pointer(c))
+}
+
+func (e *Encoder) compileConvert(typ *rtype, conv *Converter) *opcode {
+	return (*opcode)(unsafe.Pointer(&convertCode{
+		opcodeHeader: &opcodeHeader{
+			op:     opConvert,
+			typ:    typ,
+			indent: e.indent,
+			next:   newEndOp(e.indent),
+		},
+		conv: conv,
+	}))
+}
+
+func (e *Encoder) encodeConvert(code *convertCode) error {
+	v := reflect.NewAt(rtype2type(code.typ), unsafe.Pointer(code.ptr)).Elem().Interface()
+	converted, err := code.conv.Encode(v)
+	if err != nil {
+		switch err.(type) {
+		case *UnsupportedValueError, *MarshalerError:
+			return err
+		}
+		return &MarshalerError{Type: rtype2type(code.typ), Err: err, sourceFunc: "Converter.Encode"}
+	}
+	return e.encodeInterfaceValue(converted, code.indent)
+}
+
+// valueEmptyFunc returns the omitempty check of a field whose emptiness
+// cannot be read from the opcode of its value, such as a converted field.
+func valueEmptyFunc(typ *rtype) func(uintptr) bool {
+	t := rtype2type(typ)
+	return func(p uintptr) bool {
+		return isOmittedValue(reflect.NewAt(t, unsafe.Pointer(p)).Elem())
+	}
+}
+
+// isOmittedValue reports whether v is empty for omitempty, like the value of an opcode.
+func isOmittedValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Struct:
+		return false
+	case reflect.Ptr:
+		return v.IsNil()
+	}
+	return isDeepEmptyValue(v)
+}
+
+// convertDecoder decodes a JSON value as into an interface{} and stores the result of Converter.Decode.
+// Strings are decoded by the string decoder, so they are unescaped and copied like string fields.
+type convertDecoder struct {
+	typ   *rtype
+	conv  *Converter
+	iface *interfaceDecoder
+	str   *stringDecoder
+	dummy unsafe.Pointer // for escape value
+}
+
+func newConvertDecoder(typ *rtype, conv *Converter) *convertDecoder {
+	return &convertDecoder{
+		typ:   typ,
+		conv:  conv,
+		iface: newInterfaceDecoder(emptyInterfaceType),
+		str:   newStringDecoder(),
+	}
+}
+
+var emptyInterfaceType = type2rtype(reflect.TypeOf((*interface{})(nil)).Elem())
+
+func (d *convertDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
+	d.iface.setDisallowUnknownFields(disallowUnknownFields)
+}
+
+func (d *convertDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	if s.char() == '"' {
+		var str string
+		if err := d.str.decodeStream(s, uintptr(unsafe.Pointer(&str))); err != nil {
+			return err
+		}
+		return d.store(str, p, s.totalOffset())
+	}
+	var v interface{}
+	ptr := unsafe.Pointer(&v)
+	d.dummy = ptr
+	if err := d.iface.decodeStream(s, uintptr(ptr)); err != nil {
+		return err
+	}
+	return d.store(v, p, s.totalOffset())
+}
+
+func (d *convertDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] == '"' {
+		var str string
+		c, err := d.str.decode(ctx, cursor, uintptr(unsafe.Pointer(&str)))
+		if err != nil {
+			return 0, err
+		}
+		if err := d.store(str, p, c); err != nil {
+			return 0, err
+		}
+		return c, nil
+	}
+	var v interface{}
+	ptr := unsafe.Pointer(&v)
+	d.dummy = ptr
+	cursor, err := d.iface.decode(ctx, cursor, uintptr(ptr))
+	if err != nil {
+		return 0, err
+	}
+	if err := d.store(v, p, cursor); err != nil {
+		return 0, err
+	}
+	return cursor, nil
+}
+
+func (d *convertDecoder) store(v interface{}, p uintptr, offset int64) error {
+	converted, err := d.conv.Decode(v)
+	if err != nil {
+		if typeErr, ok := err.(*UnmarshalTypeError); ok && typeErr.Offset == 0 {
+			typeErr.Offset = offset
+		}
+		return err
+	}
+	typ := rtype2type(d.typ)
+	field := reflect.NewAt(typ, unsafe.Pointer(p)).Elem()
+	if converted == nil {
+		field.Set(reflect.Zero(typ))
+		return nil
+	}
+	rv := reflect.ValueOf(converted)
+	switch {
+	case rv.Type().AssignableTo(typ):
+		field.Set(rv)
+	case rv.Type().ConvertibleTo(typ):
+		field.Set(rv.Convert(typ))
+	default:
+		return &UnmarshalTypeError{
+			Value:  rv.Type().String(),
+			Type:   typ,
+			Offset: offset,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package json_test
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func init() {
+	json.RegisterConverter("cents", json.Converter{
+		Encode: func(v interface{}) (interface{}, error) {
+			return int64(math.Round(v.(float64) * 100)), nil
+		},
+		Decode: func(v interface{}) (interface{}, error) {
+			cents, ok := v.(float64)
+			if !ok {
+				return nil, errors.New("cents must be a number")
+			}
+			return cents / 100, nil
+		},
+	})
+	json.RegisterConverter("mask", json.Converter{
+		Encode: func(v interface{}) (interface{}, error) {
+			s := v.(string)
+			if len(s) <= 4 {
+				return s, nil
+			}
+			return strings.Repeat("*", len(s)-4) + s[len(s)-4:], nil
+		},
+	})
+	json.RegisterConverter("upper", json.Converter{
+		Decode: func(v interface{}) (interface{}, error) {
+			s, _ := v.(string)
+			return strings.ToUpper(s), nil
+		},
+	})
+}
+
+type convertedAccount struct {
+	Name    string  `json:"name,conv=upper"`
+	SSN     string  `json:"ssn,conv=mask"`
+	Amount  float64 `json:"amount,conv=cents"`
+	Balance float64 `json:"balance,omitempty,conv=cents"`
+}
+
+func Test_Converter(t *testing.T) {
+	t.Run("encode", func(t *testing.T) {
+		bytes, err := json.Marshal(convertedAccount{Name: "bob", SSN: "123456789", Amount: 12.34})
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"name":"bob","ssn":"*****6789","amount":1234}`, string(bytes))
+	})
+	t.Run("encode pointer", func(t *testing.T) {
+		bytes, err := json.Marshal(&convertedAccount{Amount: 1, Balance: 2.5})
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"name":"","ssn":"","amount":100,"balance":250}`, string(bytes))
+	})
+	t.Run("decode", func(t *testing.T) {
+		var v convertedAccount
+		assertErr(t, json.Unmarshal([]byte(`{"name":"bob","ssn":"123","amount":1234}`), &v))
+		assertEq(t, "name", "BOB", v.Name)
+		assertEq(t, "ssn", "123", v.SSN)
+		assertEq(t, "amount", 12.34, v.Amount)
+	})
+	t.Run("decode stream", func(t *testing.T) {
+		var v convertedAccount
+		assertErr(t, json.NewDecoder(strings.NewReader(`{"amount":5,"balance":250}`)).Decode(&v))
+		assertEq(t, "amount", 0.05, v.Amount)
+		assertEq(t, "balance", 2.5, v.Balance)
+	})
+	t.Run("decode error", func(t *testing.T) {
+		var v convertedAccount
+		if err := json.Unmarshal([]byte(`{"amount":"x"}`), &v); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("type mismatch", func(t *testing.T) {
+		var v struct {
+			N int `json:"n,conv=upper"`
+		}
+		err := json.Unmarshal([]byte(`{"n":"a"}`), &v)
+		if _, ok := err.(*json.UnmarshalTypeError); !ok {
+			t.Fatalf("expected *json.UnmarshalTypeError but got %v", err)
+		}
+	})
+	t.Run("unknown converter", func(t *testing.T) {
+		var v struct {
+			A int `json:"a,conv=unknown"`
+		}
+		if _, err := json.Marshal(v); err == nil {
+			t.Fatal("expected error")
+		}
+		if err := json.Unmarshal([]byte(`{"a":1}`), &v); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func Test_ByteFormat(t *testing.T) {
+	type digestBytes []byte
+	type formats struct {
+		Std    []byte      `json:"std,format:base64"`
+		URL    []byte      `json:"url,format:base64url"`
+		Base32 []byte      `json:"base32,format:base32"`
+		Hex    digestBytes `json:"hex,format:hex"`
+		Array  []byte      `json:"array,format:array"`
+		Empty  []byte      `json:"empty,omitempty,format:hex"`
+		Nil    []byte      `json:"nil,format:array"`
+	}
+	b := []byte{0xfb, 0xff, 0x01}
+	encoded := `{"std":"+/8B","url":"-_8B","base32":"7P7QC===","hex":"fbff01","array":[251,255,1],"nil":null}`
+	t.Run("encode", func(t *testing.T) {
+		bytes, err := json.Marshal(formats{Std: b, URL: b, Base32: b, Hex: b, Array: b, Empty: []byte{}})
+		assertErr(t, err)
+		assertEq(t, "encoded", encoded, string(bytes))
+	})
+	t.Run("decode", func(t *testing.T) {
+		for _, dec := range []func([]byte, interface{}) error{
+			json.Unmarshal,
+			func(data []byte, v interface{}) error {
+				return json.NewDecoder(strings.NewReader(string(data))).Decode(v)
+			},
+		} {
+			var v formats
+			assertErr(t, dec([]byte(encoded), &v))
+			for name, got := range map[string][]byte{"std": v.Std, "url": v.URL, "base32": v.Base32, "hex": v.Hex, "array": v.Array} {
+				assertEq(t, name, string(b), string(got))
+			}
+			assertEq(t, "nil", true, v.Nil == nil)
+			v = formats{}
+			assertErr(t, dec([]byte(`{"std":"+\/8B"}`), &v))
+			assertEq(t, "escaped", string(b), string(v.Std))
+		}
+	})
+	t.Run("decode errors", func(t *testing.T) {
+		for _, data := range []string{`{"hex":"xyz"}`, `{"hex":12}`, `{"array":[256]}`, `{"array":[1.5]}`, `{"array":"AQ=="}`} {
+			var v formats
+			if err := json.Unmarshal([]byte(data), &v); err == nil {
+				t.Errorf("%s: expected error", data)
+			}
+		}
+	})
+	t.Run("invalid tags", func(t *testing.T) {
+		if _, err := json.Marshal(struct {
+			N int `json:"n,format:hex"`
+		}{}); err == nil {
+			t.Error("expected error for a format of a non-[]byte field")
+		}
+		if _, err := json.Marshal(struct {
+			B []byte `json:"b,format:base58"`
+		}{}); err == nil {
+			t.Error("expected error for an unknown format")
+		}
+	})
+}
@@ -1,11 +1,13 @@
 package json
 
 import (
+	"bufio"
+	"context"
 	"encoding"
 	"io"
 	"reflect"
-	"strconv"
 	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -16,35 +18,64 @@ func (d Delim) String() string {
 }
 
 type decoder interface {
-	decode([]byte, int64, uintptr) (int64, error)
+	decode(*runtimeContext, int64, uintptr) (int64, error)
 	decodeStream(*stream, uintptr) error
 	setDisallowUnknownFields(bool)
 }
 
 type Decoder struct {
 	s                     *stream
+	decoders              *decoderMap // the compiled decoders by type, of the API that created the decoder, if any
 	disallowUnknownFields bool
+	ignorePromoted        bool           // whether unmarshaler methods promoted from embedded fields are ignored
+	binaryUnmarshalers    bool           // whether types with only UnmarshalBinary are decoded from base64 strings
+	compiled              bool           // whether the last top-level value needed compiling
+	opts                  *decodeOptions // the options of the byte decoders, set by API.Unmarshal, or nil
+
+	// nested is set for the Decoder passed to UnmarshalJSONFrom, whose values are
+	// part of the value being decoded. tokenStack holds the delimiters of the objects
+	// and arrays opened by Token and not closed yet, and tokenState tells what is
+	// expected next in the innermost of them.
+	nested     bool
+	tokenStack []Delim
+	tokenState int
+
+	frames *bufio.Reader // the rest of the input, read by DecodeFrame
 }
 
 type decoderMap struct {
 	sync.Map
 }
 
-func (m *decoderMap) get(k uintptr) decoder {
+// decoderKey identifies a compiled decoder by the type it decodes and the options
+// compiled into it, so that differently configured decoders never share one.
+type decoderKey struct {
+	typeptr               uintptr
+	disallowUnknownFields bool
+	ignorePromoted        bool
+	binaryUnmarshalers    bool
+	v2                    bool
+}
+
+func (m *decoderMap) get(k decoderKey) decoder {
 	if v, ok := m.Load(k); ok {
 		return v.(decoder)
 	}
 	return nil
 }
 
-func (m *decoderMap) set(k uintptr, dec decoder) {
-	m.Store(k, dec)
+func (m *decoderMap) set(k decoderKey, dec decoder) {
+	if _, loaded := m.LoadOrStore(k, dec); !loaded {
+		countStat(&stats.DecoderCacheSize)
+	}
 }
 
 var (
 	cachedDecoder     decoderMap
 	unmarshalJSONType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	unmarshalFromType = reflect.TypeOf((*UnmarshalerFrom)(nil)).Elem()
 	unmarshalTextType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	writerType        = type2rtype(reflect.TypeOf((*io.Writer)(nil)).Elem())
 )
 
 func init() {
@@ -62,15 +93,68 @@ const (
 func NewDecoder(r io.Reader) *Decoder {
 	s := &stream{r: r}
 	s.read()
+	s.skipUTF8BOM()
+	s.v2 = v2Semantics()
 	return &Decoder{s: s}
 }
 
+// A DecodeOption configures a Decoder, for the functions that create one internally.
+// The methods of Decoder without arguments are options as method expressions:
+//
+//	err := json.UnmarshalFile(path, &v, (*json.Decoder).UseNumber)
+type DecodeOption func(*Decoder)
+
 // Buffered returns a reader of the data remaining in the Decoder's
 // buffer. The reader is valid until the next call to Decode.
 func (d *Decoder) Buffered() io.Reader {
 	return d.s.buffered()
 }
 
+// compiledDecoder returns the decoder of typ compiled with the options of d,
+// compiling it on first use.
+func (d *Decoder) compiledDecoder(typ *rtype) (decoder, error) {
+	key := decoderKey{
+		typeptr:               uintptr(unsafe.Pointer(typ)),
+		disallowUnknownFields: d.disallowUnknownFields,
+		ignorePromoted:        d.ignorePromoted,
+		binaryUnmarshalers:    d.binaryUnmarshalers,
+		v2:                    d.options().v2,
+	}
+	cache := d.decoderCache()
+	if dec := cache.get(key); dec != nil {
+		return dec, nil
+	}
+	d.compiled = true
+	countStat(&stats.DecoderCompiles)
+	dec, err := d.compileHead(typ)
+	if err != nil {
+		return nil, err
+	}
+	dec.setDisallowUnknownFields(d.disallowUnknownFields)
+	cache.set(key, dec)
+	return dec, nil
+}
+
+// options returns the options the decoders of d read: those of API.Unmarshal, of the stream
+// of d, or of Unmarshal.
+func (d *Decoder) options() *decodeOptions {
+	switch {
+	case d.opts != nil:
+		return d.opts
+	case d.s != nil:
+		return &d.s.decodeOptions
+	}
+	return packageDecodeOptions()
+}
+
+// decoderCache returns the cache of the compiled decoders the Decoder uses.
+func (d *Decoder) decoderCache() *decoderMap {
+	if d.decoders == nil {
+		return &cachedDecoder
+	}
+	return d.decoders
+}
+
 func (d *Decoder) validateType(typ *rtype, p uintptr) error {
 	if typ.Kind() != reflect.Ptr || p == 0 {
 		return &InvalidUnmarshalError{Type: rtype2type(typ)}
@@ -78,7 +162,38 @@ func (d *Decoder) validateType(typ *rtype, p uintptr) error {
 	return nil
 }
 
-func (d *Decoder) decode(src []byte, header *interfaceHeader) error {
+// decode decodes src into the value of header. A non-nil ctx is carried to the operation hooks,
+// and the decoding stops with ctx.Err() once ctx is done.
+func (d *Decoder) decode(ctx context.Context, src []byte, header *interfaceHeader) error {
+	rctx := newRuntimeContext(src, d.options())
+	rctx.ctx = ctx
+	typ := headerType(header)
+	done := startOperation(ctx, DecodeOperation, typ)
+	if done == nil {
+		return d.decodeValue(rctx, header)
+	}
+	start := time.Now()
+	d.compiled = false
+	err := d.decodeValue(rctx, header)
+	done(OperationStats{
+		Op:       DecodeOperation,
+		Type:     typ,
+		Bytes:    int64(len(src) - 1), // without the terminating nul
+		Duration: time.Since(start),
+		CacheHit: !d.compiled,
+		Err:      err,
+	})
+	return err
+}
+
+func headerType(header *interfaceHeader) reflect.Type {
+	if header.typ == nil {
+		return nil
+	}
+	return rtype2type(header.typ)
+}
+
+func (d *Decoder) decodeValue(ctx *runtimeContext, header *interfaceHeader) error {
 	typ := header.typ
 	typeptr := uintptr(unsafe.Pointer(typ))
 
@@ -89,18 +204,14 @@ func (d *Decoder) decode(src []byte, header *interfaceHeader) error {
 	if err := d.validateType(copiedType, ptr); err != nil {
 		return err
 	}
-	dec := cachedDecoder.get(typeptr)
-	if dec == nil {
-
-		compiledDec, err := d.compileHead(copiedType)
-		if err != nil {
-			return err
-		}
-		cachedDecoder.set(typeptr, compiledDec)
-		dec = compiledDec
+	dec, err := d.compiledDecoder(copiedType)
+	if err != nil {
+		return err
 	}
-	dec.setDisallowUnknownFields(d.disallowUnknownFields)
-	if _, err := dec.decode(src, 0, ptr); err != nil {
+	if _, err := dec.decode(ctx, utf8BOMLength(ctx.buf), ptr); err != nil {
+		if ctx.ctxErr != nil {
+			return ctx.ctxErr
+		}
 		return err
 	}
 	return nil
@@ -109,12 +220,36 @@ func (d *Decoder) decode(src []byte, header *interfaceHeader) error {
 func (d *Decoder) decodeForUnmarshal(src []byte, v interface{}) error {
 	header := (*interfaceHeader)(unsafe.Pointer(&v))
 	header.typ.escape()
-	return d.decode(src, header)
+	return d.decode(nil, src, header)
+}
+
+// decodeForUnmarshalContext is like decodeForUnmarshal but stops with ctx.Err() once ctx is done.
+func (d *Decoder) decodeForUnmarshalContext(ctx context.Context, src []byte, v interface{}) error {
+	header := (*interfaceHeader)(unsafe.Pointer(&v))
+	header.typ.escape()
+	return d.decode(ctx, src, header)
+}
+
+func (d *Decoder) decodeForUnmarshalValue(src []byte, rv reflect.Value) error {
+	var header interfaceHeader
+	switch {
+	case rv.CanSet():
+		header.typ = type2rtype(reflect.PtrTo(rv.Type()))
+		header.ptr = unsafe.Pointer(rv.UnsafeAddr())
+	case rv.Kind() == reflect.Ptr && !rv.IsNil():
+		header.typ = type2rtype(rv.Type())
+		header.ptr = unsafe.Pointer(rv.Pointer())
+	case rv.IsValid():
+		return &InvalidUnmarshalError{Type: rv.Type()}
+	default:
+		return &InvalidUnmarshalError{}
+	}
+	return d.decode(nil, src, &header)
 }
 
 func (d *Decoder) decodeForUnmarshalNoEscape(src []byte, v interface{}) error {
 	header := (*interfaceHeader)(unsafe.Pointer(&v))
-	return d.decode(src, header)
+	return d.decode(nil, src, header)
 }
 
 func (d *Decoder) prepareForDecode() error {
@@ -144,6 +279,27 @@ func (d *Decoder) prepareForDecode() error {
 // See the documentation for Unmarshal for details about
 // the conversion of JSON into a Go value.
 func (d *Decoder) Decode(v interface{}) error {
+	typ := reflect.TypeOf(v)
+	done := startOperation(d.s.ctx, DecodeOperation, typ)
+	if done == nil {
+		return d.decodeStreamValue(v)
+	}
+	start := time.Now()
+	offset := d.s.totalOffset()
+	d.compiled = false
+	err := d.decodeStreamValue(v)
+	done(OperationStats{
+		Op:       DecodeOperation,
+		Type:     typ,
+		Bytes:    d.s.totalOffset() - offset,
+		Duration: time.Since(start),
+		CacheHit: !d.compiled,
+		Err:      err,
+	})
+	return err
+}
+
+func (d *Decoder) decodeStreamValue(v interface{}) (err error) {
 	header := (*interfaceHeader)(unsafe.Pointer(&v))
 	typ := header.typ
 	ptr := uintptr(header.ptr)
@@ -155,110 +311,532 @@ func (d *Decoder) Decode(v interface{}) error {
 		return err
 	}
 
-	dec := cachedDecoder.get(typeptr)
-	if dec == nil {
-		compiledDec, err := d.compileHead(typ)
-		if err != nil {
+	// typ, unlike copiedType, lets v escape, as the value it points to is written in the stream
+	dec, err := d.compiledDecoder(typ)
+	if err != nil {
+		return err
+	}
+	if len(d.tokenStack) == 0 {
+		if err := d.prepareForDecode(); err != nil {
 			return err
 		}
-		cachedDecoder.set(typeptr, compiledDec)
-		dec = compiledDec
-	}
-	dec.setDisallowUnknownFields(d.disallowUnknownFields)
-	if err := d.prepareForDecode(); err != nil {
-		return err
+	} else {
+		if err := d.tokenPrepareForValue(); err != nil {
+			return err
+		}
+		defer func() {
+			if valueRead(err) {
+				d.tokenEndValue()
+			}
+		}()
 	}
 	s := d.s
+	if d.nested {
+		return dec.decodeStream(s, ptr)
+	}
+	s.allocated = 0
+	s.elementErrors = nil
+	if s.timeout > 0 {
+		s.deadline = time.Now().Add(s.timeout)
+		defer func() { s.deadline = time.Time{} }()
+	}
 	if err := dec.decodeStream(s, ptr); err != nil {
+		if s.ctxErr != nil {
+			// the input was cut short by the interruption
+			err = s.ctxErr
+			s.ctxErr = nil
+		}
 		return err
 	}
+	if len(s.elementErrors) > 0 {
+		return &ElementErrors{Errors: s.elementErrors}
+	}
 	return nil
 }
 
+// valueRead reports whether the value was read whole by a Decode returning err,
+// so that the next one follows it.
+func valueRead(err error) bool {
+	switch err.(type) {
+	case nil, *ElementErrors, *ValidationError:
+		return true
+	}
+	return false
+}
+
+// DecodeContext is like Decode but stops with ctx.Err() once ctx is done.
+// The context is checked each time the decoder reads more input and periodically
+// between the elements and members of arrays and objects.
+func (d *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s := d.s
+	s.ctx = ctx
+	err := d.Decode(v)
+	s.ctx = nil
+	if s.ctxErr != nil {
+		err = s.ctxErr
+		s.ctxErr = nil
+	}
+	return err
+}
+
+// The states of the reading of the innermost object or array opened by Token,
+// telling whether a value, a key or a separator is expected next.
+const (
+	tokenTopValue = iota
+	tokenArrayStart
+	tokenArrayValue
+	tokenArrayComma
+	tokenObjectStart
+	tokenObjectKey
+	tokenObjectColon
+	tokenObjectValue
+	tokenObjectComma
+)
+
+// More reports whether there is another element in the current array or object,
+// opened by Token, or another value at the top level of the input.
 func (d *Decoder) More() bool {
+	c := d.tokenPeek()
+	return c != nul && c != ']' && c != '}'
+}
+
+// tokenPeek skips whitespace and returns the next character, or nul at the end of the input.
+func (d *Decoder) tokenPeek() byte {
 	s := d.s
 	for {
-		switch s.char() {
+		switch c := s.char(); c {
 		case ' ', '\n', '\r', '\t':
 			s.cursor++
-			continue
-		case '}', ']':
-			return false
 		case nul:
-			if s.read() {
-				continue
+			if !s.read() {
+				return nul
 			}
-			return false
+		default:
+			return c
 		}
-		break
 	}
+}
+
+// tokenError returns the error of c where it does not fit the state of the
+// innermost object or array.
+func (d *Decoder) tokenError(c byte) error {
+	if c == nul {
+		return io.EOF
+	}
+	context := "beginning of value"
+	switch d.tokenState {
+	case tokenArrayComma:
+		context = "array element separator"
+	case tokenObjectStart, tokenObjectKey:
+		context = "object key"
+	case tokenObjectColon:
+		context = "object key separator"
+	case tokenObjectComma:
+		context = "object member separator"
+	}
+	return errInvalidCharacter(c, context, d.s.totalOffset())
+}
+
+// tokenBeginValue checks that a value starting with c may be read next,
+// an object key being a string.
+func (d *Decoder) tokenBeginValue(c byte) error {
+	switch d.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayValue, tokenObjectValue:
+		return nil
+	case tokenObjectStart, tokenObjectKey:
+		if c == '"' || c == '\'' {
+			return nil
+		}
+	}
+	return d.tokenError(c)
+}
+
+// tokenEndValue moves the state past the value just read.
+func (d *Decoder) tokenEndValue() {
+	switch d.tokenState {
+	case tokenArrayStart, tokenArrayValue:
+		d.tokenState = tokenArrayComma
+	case tokenObjectStart, tokenObjectKey:
+		d.tokenState = tokenObjectColon
+	case tokenObjectValue:
+		d.tokenState = tokenObjectComma
+	}
+}
+
+// tokenSeparator reads the separator c if the state expects it, and reports whether it did.
+func (d *Decoder) tokenSeparator(c byte) bool {
+	switch {
+	case c == ',' && d.tokenState == tokenArrayComma:
+		d.tokenState = tokenArrayValue
+	case c == ',' && d.tokenState == tokenObjectComma:
+		d.tokenState = tokenObjectKey
+	case c == ':' && d.tokenState == tokenObjectColon:
+		d.tokenState = tokenObjectValue
+	default:
+		return false
+	}
+	d.s.cursor++
 	return true
 }
 
+// tokenPrepareForValue reads the separator expected before the next value
+// of the innermost object or array opened by Token.
+func (d *Decoder) tokenPrepareForValue() error {
+	c := d.tokenPeek()
+	if d.tokenSeparator(c) {
+		c = d.tokenPeek()
+	}
+	return d.tokenBeginValue(c)
+}
+
+// Token returns the next JSON token in the input stream.
+// At the end of the input stream, Token returns nil, io.EOF.
+//
+// Inside the objects and arrays it opened, Token checks the commas and colons
+// between the tokens and does not return them. Delimiters are returned as Delim
+// and must be balanced; a closing delimiter returns to the enclosing value.
 func (d *Decoder) Token() (Token, error) {
 	s := d.s
 	for {
-		c := s.char()
-		switch c {
-		case ' ', '\n', '\r', '\t':
-			s.cursor++
-		case '{', '[', ']', '}':
-			s.cursor++
-			return Delim(c), nil
-		case ',', ':':
-			s.cursor++
-		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			bytes := floatBytes(s)
-			s := *(*string)(unsafe.Pointer(&bytes))
-			f64, err := strconv.ParseFloat(s, 64)
-			if err != nil {
-				return nil, err
-			}
-			return f64, nil
-		case '"':
-			bytes, err := stringBytes(s)
-			if err != nil {
-				return nil, err
+		c := d.tokenPeek()
+		if c == ',' || c == ':' {
+			if len(d.tokenStack) == 0 {
+				s.cursor++
+				continue
 			}
-			return string(bytes), nil
-		case 't':
-			if err := trueBytes(s); err != nil {
-				return nil, err
+			if !d.tokenSeparator(c) {
+				return nil, d.tokenError(c)
 			}
-			return true, nil
-		case 'f':
-			if err := falseBytes(s); err != nil {
-				return nil, err
+			continue
+		}
+		if c == nul {
+			return nil, io.EOF
+		}
+		if c == ']' || c == '}' {
+			depth := len(d.tokenStack)
+			if depth == 0 {
+				return nil, d.tokenError(c)
 			}
-			return false, nil
-		case 'n':
-			if err := nullBytes(s); err != nil {
-				return nil, err
+			switch d.tokenState {
+			case tokenArrayStart, tokenArrayComma:
+				if c != ']' {
+					return nil, d.tokenError(c)
+				}
+			case tokenObjectStart, tokenObjectComma:
+				if c != '}' {
+					return nil, d.tokenError(c)
+				}
+			default:
+				return nil, d.tokenError(c)
 			}
-			return nil, nil
-		case nul:
-			if s.read() {
-				continue
+			s.cursor++
+			d.tokenStack = d.tokenStack[:depth-1]
+			switch {
+			case depth == 1:
+				d.tokenState = tokenTopValue
+			case d.tokenStack[depth-2] == '[':
+				d.tokenState = tokenArrayValue
+			default:
+				d.tokenState = tokenObjectValue
 			}
-			return nil, io.EOF
+			d.tokenEndValue()
+			return Delim(c), nil
+		}
+		if err := d.tokenBeginValue(c); err != nil {
+			return nil, err
+		}
+		tok, err := d.tokenValue(c)
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case '[':
+			d.tokenStack = append(d.tokenStack, Delim(c))
+			d.tokenState = tokenArrayStart
+		case '{':
+			d.tokenStack = append(d.tokenStack, Delim(c))
+			d.tokenState = tokenObjectStart
 		default:
-			return nil, errInvalidCharacter(s.char(), "token", s.totalOffset())
+			d.tokenEndValue()
 		}
+		return tok, nil
 	}
-	return nil, io.EOF
+}
+
+// tokenValue reads the token starting with c, other than a separator or a closing delimiter.
+func (d *Decoder) tokenValue(c byte) (Token, error) {
+	s := d.s
+	switch c {
+	case '{', '[':
+		s.cursor++
+		return Delim(c), nil
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		f64, err := parseFloat(floatBytes(s))
+		if err != nil {
+			return nil, err
+		}
+		return f64, nil
+	case '"':
+		bytes, err := stringBytes(s)
+		if err != nil {
+			return nil, err
+		}
+		return string(bytes), nil
+	case '\'':
+		if !s.allowSingleQuotes {
+			return nil, errInvalidCharacter(c, "token", s.totalOffset())
+		}
+		bytes, err := stringBytes(s)
+		if err != nil {
+			return nil, err
+		}
+		return string(bytes), nil
+	case '+':
+		if !s.allowLeadingPlus {
+			return nil, errInvalidCharacter(c, "token", s.totalOffset())
+		}
+		s.cursor++
+		if s.char() == nul {
+			s.read()
+		}
+		if c := s.char(); c < '0' || c > '9' {
+			return nil, errInvalidCharacter(c, "token", s.totalOffset())
+		}
+		return d.tokenValue(s.char())
+	case 't':
+		if err := trueBytes(s); err != nil {
+			return nil, err
+		}
+		return true, nil
+	case 'f':
+		if err := falseBytes(s); err != nil {
+			return nil, err
+		}
+		return false, nil
+	case 'n':
+		if err := nullBytes(s); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	return nil, errInvalidCharacter(c, "token", s.totalOffset())
 }
 
 // DisallowUnknownFields causes the Decoder to return an error when the destination
 // is a struct and the input contains object keys which do not match any
-// non-ignored, exported fields in the destination.
+// non-ignored, exported fields in the destination. The error is an *UnknownFieldError
+// giving the key and its offset.
 func (d *Decoder) DisallowUnknownFields() {
 	d.disallowUnknownFields = true
 }
 
+// IgnorePromotedUnmarshalers causes the Decoder to ignore the UnmarshalJSON, UnmarshalText and
+// other unmarshaler methods a struct type promotes from its embedded fields, and to decode
+// the fields of the struct instead. By default, as in encoding/json, such a method decodes
+// the whole struct. Methods declared by the struct type itself are always used.
+func (d *Decoder) IgnorePromotedUnmarshalers() {
+	d.ignorePromoted = true
+}
+
+// UseBinaryUnmarshalers causes the Decoder to decode values of types whose pointers implement
+// encoding.BinaryUnmarshaler, but none of the JSON and text unmarshaler interfaces, from
+// base64 strings with their UnmarshalBinary method, as written with SetBinaryMarshalers.
+// null leaves such values unchanged.
+func (d *Decoder) UseBinaryUnmarshalers() {
+	d.binaryUnmarshalers = true
+}
+
+// SetV2Semantics specifies whether the Decoder follows the defaults of the encoding/json/v2
+// proposal described at the SetV2Semantics function, which gives the default of NewDecoder.
+func (d *Decoder) SetV2Semantics(on bool) {
+	d.s.v2 = on
+}
+
+// InputOffset returns the offset in the input of the current position of the Decoder,
+// counting all the bytes read before it. It gives the end of the last value or token
+// returned and the beginning of the next one.
 func (d *Decoder) InputOffset() int64 {
 	return d.s.totalOffset()
 }
 
+// AllowSingleQuotes causes the Decoder to accept strings delimited by
+// single quotes (e.g. 'value') in addition to double quoted strings.
+func (d *Decoder) AllowSingleQuotes() {
+	d.s.allowSingleQuotes = true
+}
+
+// AllowUnquotedKeys causes the Decoder to accept object keys that are
+// written as bare identifiers (e.g. {key: 1}).
+// Identifiers consist of ASCII letters, digits, '_' and '$' and must not start with a digit.
+func (d *Decoder) AllowUnquotedKeys() {
+	d.s.allowUnquotedKeys = true
+}
+
+// AllowControlChars causes the Decoder to accept unescaped control characters
+// (U+0000 through U+001F) inside strings. By default, they are rejected like with
+// encoding/json, by Decoders as well as by Unmarshal and Valid, which accepted them before
+// this option was added. Config.AllowControlChars accepts them when decoding byte slices.
+func (d *Decoder) AllowControlChars() {
+	d.s.allowControlChars = true
+}
+
+// StrictStrings causes the Decoder to return a SyntaxError for strings holding
+// unescaped control characters, even with AllowControlChars, invalid escape sequences
+// such as \x or \u12 and unpaired surrogate escapes such as \ud800, which are
+// otherwise kept as is or decoded as U+FFFD.
+func (d *Decoder) StrictStrings() {
+	d.s.strictStrings = true
+}
+
+// AllowLeadingPlus causes the Decoder to accept numbers with an explicit
+// leading plus sign (e.g. +1).
+func (d *Decoder) AllowLeadingPlus() {
+	d.s.allowLeadingPlus = true
+}
+
+// StrictNumbers causes the Decoder to return an UnmarshalTypeError when a number
+// with a fractional or exponential part, such as 1.5 or 1e3, is decoded into an
+// integer, instead of stopping at the integer part.
+func (d *Decoder) StrictNumbers() {
+	d.s.strictNumbers = true
+}
+
+// AllowExponentIntegers causes the Decoder to accept a number in exponent form,
+// such as 1e3 or 2.5e1, into an integer when its value is an integer, as emitted
+// by systems writing integers in scientific notation. Other numbers with an
+// exponent, like 1.5e0, return an UnmarshalTypeError. Numbers with a fraction
+// and no exponent are handled as without this option, see StrictNumbers.
+func (d *Decoder) AllowExponentIntegers() {
+	d.s.exponentIntegers = true
+}
+
+// DisallowNull causes the Decoder to return an UnmarshalTypeError when a struct field that
+// cannot be nil, such as a string or a struct, is null, instead of leaving it unchanged.
+// Fields of pointers, interfaces, maps and slices, and of types with an UnmarshalJSON method,
+// still accept null. The "notnull" tag option rejects null for a field with any decoder:
+//
+//	Amount int `json:"amount,notnull"`
+func (d *Decoder) DisallowNull() {
+	d.s.disallowNull = true
+}
+
+// ResetMissingFields causes the Decoder to set the fields of a struct that are absent from
+// its object to their zero value, instead of keeping their values, so that a struct reused
+// across decodes, for example from a sync.Pool, holds nothing of the previous documents.
+// The fields present in the object are decoded as usual.
+func (d *Decoder) ResetMissingFields() {
+	d.s.resetMissing = true
+}
+
+// AllowScalarStrings causes the Decoder to accept numbers and booleans into strings,
+// storing their text as written, such as "123" for 123 and "true" for true. It tolerates
+// sources that change between quoted and bare values without a custom type.
+// Object keys still have to be strings.
+func (d *Decoder) AllowScalarStrings() {
+	d.s.scalarStrings = true
+}
+
+// SetTimeout limits the time each call to Decode may take. Decode returns a *DecodeTimeoutError
+// once the timeout is exceeded, protecting request handlers from inputs that are slow to
+// send or to decode. The clock is checked each time more input is read and periodically
+// between the elements and members of arrays and objects. A timeout of zero, the default,
+// means no limit. The deadline of a context passed to DecodeContext is honored as well,
+// with ctx.Err() as the error.
+func (d *Decoder) SetTimeout(timeout time.Duration) {
+	d.s.timeout = timeout
+}
+
+// ContinueOnElementError causes the Decoder to skip array and slice elements that fail to decode,
+// for example because of a type mismatch, instead of aborting the whole value.
+// Skipped slice elements are left out of the slice and skipped array elements are left zero.
+// Decode then returns an *ElementErrors listing every skipped element once the rest
+// of the value has been decoded. Syntax errors still abort decoding.
+func (d *Decoder) ContinueOnElementError() {
+	d.s.continueOnElementError = true
+}
+
+// ValidateValues causes the Decoder to call the Validate method of the decoded values whose
+// types implement Validator, such as to check the ranges of their fields, and to return the
+// first failure as a *ValidationError locating the value. Combined with ContinueOnElementError,
+// the array elements failing validation are skipped and all the failures are collected.
+func (d *Decoder) ValidateValues() {
+	d.s.validate = true
+}
+
+// SetMemoryBudget limits the approximate number of bytes that may be
+// allocated for the strings, slices, maps and pointers of each decoded value.
+// Decode returns a *MemoryBudgetError as soon as the budget is exceeded,
+// which guards against small inputs that expand into huge results.
+// A budget of zero, the default, means no limit.
+func (d *Decoder) SetMemoryBudget(bytes int64) {
+	d.s.memoryBudget = bytes
+}
+
+// DecodeProgress is passed to the function set by Decoder.SetProgressFunc.
+type DecodeProgress struct {
+	Offset int64 // bytes read from the input so far
+	Total  int64 // size of the input as given to SetProgressFunc, or zero if unknown
+}
+
+// Percent returns the percentage of the input read so far, or -1 if its size is unknown.
+func (p DecodeProgress) Percent() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return float64(p.Offset) * 100 / float64(p.Total)
+}
+
+// SetProgressFunc makes the Decoder call fn each time another interval bytes of the input
+// have been read, and once more when the end of the input is reached, for example to report
+// the progress of a long import or to feed a watchdog. total is the size of the input if
+// known, such as the size of a file, to compute percentages, or zero.
+// fn is called from Decode and Token and may read the offset with InputOffset
+// to follow the values decoded rather than the bytes read ahead.
+// An interval below 1 or a nil fn removes the function.
+func (d *Decoder) SetProgressFunc(interval, total int64, fn func(DecodeProgress)) {
+	if interval < 1 || fn == nil {
+		d.s.progressFn = nil
+		return
+	}
+	d.s.progressFn = fn
+	d.s.progressInterval = interval
+	d.s.progressTotal = total
+	d.s.progressNext = interval
+	d.s.reportProgress()
+}
+
+// SetKeyTransformer sets a function applied to every object key before it is
+// matched against struct fields or stored as a string map key, for example to
+// strip a prefix or map legacy names without retagging structs.
+// SetKeyTransformer(nil) removes the function.
+func (d *Decoder) SetKeyTransformer(fn func(key string) string) {
+	d.s.keyTransformer = fn
+}
+
+// ReuseInterfaceContainers causes the Decoder, when decoding into an interface{} that
+// already holds a non-nil map[string]interface{}, map[interface{}]interface{} or a
+// []interface{} with spare capacity, to decode into that container instead of allocating
+// a new one, and likewise for the containers nested in it. Keys missing from the input
+// are deleted from reused maps, so the result equals a fresh decode, while loops that
+// decode similar documents into the same value allocate far less.
+// The previous contents are overwritten, so they must not be used after the decode.
+func (d *Decoder) ReuseInterfaceContainers() {
+	d.s.reuseContainers = true
+}
+
+// TranscodeUTF16 causes the Decoder to detect input encoded in UTF-16, with or without
+// a byte order mark, as described in RFC 4627, and to transcode it to UTF-8 before
+// parsing, for files written by tools that default to UTF-16.
+// Offsets in errors and InputOffset then count bytes of the transcoded input.
+// It must be called before the first call to Decode or Token.
+// A UTF-8 byte order mark at the start of the input is always skipped.
+func (d *Decoder) TranscodeUTF16() {
+	d.s.transcodeUTF16()
+}
+
 // UseNumber causes the Decoder to unmarshal a number into an interface{} as a
 // Number instead of as a float64.
 func (d *Decoder) UseNumber() {
@@ -0,0 +1,114 @@
+//go:build go1.18
+// +build go1.18
+
+package json
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// DecodeAll decodes each of inputs into a value of T, as Unmarshal does, with up to
+// parallelism goroutines sharing the codec compiled for T. A parallelism below 1 uses
+// runtime.GOMAXPROCS(0) goroutines. The values are in the order of inputs; the errors
+// are nil if every input is decoded, and otherwise hold the error of each input by its index.
+func DecodeAll[T any](inputs [][]byte, parallelism int) ([]T, []error) {
+	values := make([]T, len(inputs))
+	codec, err := Compile(reflect.TypeOf(values).Elem())
+	if err != nil {
+		errs := make([]error, len(inputs))
+		for i := range errs {
+			errs[i] = err
+		}
+		return values, errs
+	}
+	errs := make([]error, len(inputs))
+	var (
+		next   int64 = -1
+		failed int32
+		wg     sync.WaitGroup
+	)
+	for n := decodeWorkers(parallelism, len(inputs)); n > 0; n-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(inputs) {
+					return
+				}
+				if errs[i] = codec.Unmarshal(inputs[i], &values[i]); errs[i] != nil {
+					atomic.StoreInt32(&failed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if failed == 0 {
+		return values, nil
+	}
+	return values, errs
+}
+
+// Decoded is the result of decoding the input of index Index received by DecodeEach.
+type Decoded[T any] struct {
+	Index int
+	Value T
+	Err   error
+}
+
+// DecodeEach is the streaming form of DecodeAll: it decodes each input received from inputs
+// into a value of T, with up to parallelism goroutines, and sends the results as they complete,
+// so not necessarily in the order of inputs. The returned channel is closed once inputs is
+// closed and all of its inputs are decoded.
+func DecodeEach[T any](inputs <-chan []byte, parallelism int) <-chan Decoded[T] {
+	workers := decodeWorkers(parallelism, -1)
+	results := make(chan Decoded[T], workers)
+	codec, err := Compile(reflect.TypeOf((*T)(nil)).Elem())
+	type indexed struct {
+		index int
+		data  []byte
+	}
+	work := make(chan indexed, workers)
+	go func() {
+		i := 0
+		for data := range inputs {
+			work <- indexed{index: i, data: data}
+			i++
+		}
+		close(work)
+	}()
+	var wg sync.WaitGroup
+	for n := workers; n > 0; n-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for w := range work {
+				r := Decoded[T]{Index: w.index, Err: err}
+				if err == nil {
+					r.Err = codec.Unmarshal(w.data, &r.Value)
+				}
+				results <- r
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// decodeWorkers returns the number of goroutines decoding n inputs, or an unknown number
+// for a negative n, with parallelism.
+func decodeWorkers(parallelism, n int) int {
+	if parallelism < 1 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+	if n >= 0 && parallelism > n {
+		return n
+	}
+	return parallelism
+}
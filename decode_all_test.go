@@ -0,0 +1,78 @@
+//go:build go1.18
+// +build go1.18
+
+package json_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type bulkRecord struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func Test_DecodeAll(t *testing.T) {
+	inputs := make([][]byte, 100)
+	for i := range inputs {
+		inputs[i] = []byte(fmt.Sprintf(`{"id":%d,"name":"n%d"}`, i, i))
+	}
+	t.Run("values", func(t *testing.T) {
+		values, errs := json.DecodeAll[bulkRecord](inputs, 4)
+		if errs != nil {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+		assertEq(t, "count", len(inputs), len(values))
+		for i, v := range values {
+			assertEq(t, "id", i, v.ID)
+			assertEq(t, "name", fmt.Sprintf("n%d", i), v.Name)
+		}
+	})
+	t.Run("errors", func(t *testing.T) {
+		values, errs := json.DecodeAll[bulkRecord]([][]byte{[]byte(`{"id":1}`), []byte(`{"id":"x"}`)}, 0)
+		assertEq(t, "errors", 2, len(errs))
+		assertErr(t, errs[0])
+		if errs[1] == nil {
+			t.Fatal("expected an error for the second input")
+		}
+		assertEq(t, "id", 1, values[0].ID)
+	})
+	t.Run("empty", func(t *testing.T) {
+		values, errs := json.DecodeAll[bulkRecord](nil, 2)
+		assertEq(t, "values", 0, len(values))
+		if errs != nil {
+			t.Fatalf("unexpected errors: %v", errs)
+		}
+	})
+	t.Run("each", func(t *testing.T) {
+		ch := make(chan []byte)
+		go func() {
+			for _, in := range inputs {
+				ch <- in
+			}
+			ch <- []byte(`not json`)
+			close(ch)
+		}()
+		var ids []int
+		failed := 0
+		for r := range json.DecodeEach[bulkRecord](ch, 3) {
+			if r.Err != nil {
+				assertEq(t, "index", len(inputs), r.Index)
+				failed++
+				continue
+			}
+			assertEq(t, "id", r.Index, r.Value.ID)
+			ids = append(ids, r.Value.ID)
+		}
+		assertEq(t, "failed", 1, failed)
+		sort.Ints(ids)
+		assertEq(t, "count", len(inputs), len(ids))
+		for i, id := range ids {
+			assertEq(t, "id", i, id)
+		}
+	})
+}
@@ -33,7 +33,7 @@ func (d *arrayDecoder) decodeStream(s *stream, p uintptr) error {
 			idx := 0
 			for {
 				s.cursor++
-				if err := d.valueDecoder.decodeStream(s, p+uintptr(idx)*d.size); err != nil {
+				if _, err := s.decodeElement(d.valueDecoder, d.elemType, idx, p+uintptr(idx)*d.size); err != nil {
 					return err
 				}
 				s.skipWhiteSpace()
@@ -66,7 +66,8 @@ ERROR:
 	return errUnexpectedEndOfJSON("array", s.totalOffset())
 }
 
-func (d *arrayDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
+func (d *arrayDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
 	buflen := int64(len(buf))
 	for ; cursor < buflen; cursor++ {
 		switch buf[cursor] {
@@ -91,8 +92,11 @@ func (d *arrayDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error
 		case '[':
 			idx := 0
 			for {
+				if err := ctx.checkCanceled(); err != nil {
+					return 0, err
+				}
 				cursor++
-				c, err := d.valueDecoder.decode(buf, cursor, p+uintptr(idx)*d.size)
+				c, err := d.valueDecoder.decode(ctx, cursor, p+uintptr(idx)*d.size)
 				if err != nil {
 					return 0, err
 				}
@@ -88,7 +88,8 @@ ERROR:
 	return errUnexpectedEndOfJSON("bool", s.totalOffset())
 }
 
-func (d *boolDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
+func (d *boolDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
 	buflen := int64(len(buf))
 	cursor = skipWhiteSpace(buf, cursor)
 	switch buf[cursor] {
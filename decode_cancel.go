@@ -0,0 +1,42 @@
+package json
+
+import "context"
+
+// cancelCheckInterval is the number of elements and members decoded from a byte slice
+// between checks of the context it is decoded with.
+const cancelCheckInterval = 256
+
+// runtimeContext is the state of the decoding of a byte slice, passed to the byte decoders
+// with the cursor. It is only used by the goroutine decoding the input.
+type runtimeContext struct {
+	buf []byte
+	*decodeOptions
+
+	ctx    context.Context // the context of UnmarshalContext, or nil
+	ctxErr error           // error of ctx once it is done
+	checks int
+}
+
+func newRuntimeContext(buf []byte, opts *decodeOptions) *runtimeContext {
+	return &runtimeContext{buf: buf, decodeOptions: opts}
+}
+
+// withBuf returns the state of the decoding of buf, which is decoded as part of ctx.buf.
+func (ctx *runtimeContext) withBuf(buf []byte) *runtimeContext {
+	c := *ctx
+	c.buf = buf
+	return &c
+}
+
+// checkCanceled returns the error of the context the input is decoded with once it is done.
+// The context is only checked every cancelCheckInterval calls.
+func (ctx *runtimeContext) checkCanceled() error {
+	if ctx.ctx == nil || ctx.ctxErr != nil {
+		return ctx.ctxErr
+	}
+	ctx.checks++
+	if ctx.checks%cancelCheckInterval == 0 {
+		ctx.ctxErr = ctx.ctx.Err()
+	}
+	return ctx.ctxErr
+}
@@ -7,19 +7,63 @@ import (
 )
 
 func (d *Decoder) compileHead(typ *rtype) (decoder, error) {
-	if typ.Implements(unmarshalJSONType) {
-		return newUnmarshalJSONDecoder(typ), nil
-	} else if typ.Implements(unmarshalTextType) {
-		return newUnmarshalTextDecoder(typ), nil
+	if dec := newGeneratedDecoder(typ.Elem()); dec != nil {
+		return d.withHooks(dec, typ.Elem()), nil
+	}
+	if dec := newWellKnownDecoder(typ.Elem(), d.binaryUnmarshalers); dec != nil {
+		return d.withHooks(dec, typ.Elem()), nil
+	}
+	if typ.Elem() == rawMessageType {
+		return newRawMessageDecoder(), nil
+	}
+	if d.implements(typ, unmarshalFromType) {
+		return d.withHooks(newUnmarshalJSONFromDecoder(typ), typ.Elem()), nil
+	} else if d.implements(typ, unmarshalJSONType) {
+		return d.withHooks(newUnmarshalJSONDecoder(typ), typ.Elem()), nil
+	} else if d.implements(typ, unmarshalTextType) {
+		return d.withHooks(newUnmarshalTextDecoder(typ), typ.Elem()), nil
 	}
 	return d.compile(typ.Elem())
 }
 
 func (d *Decoder) compile(typ *rtype) (decoder, error) {
-	if typ.Implements(unmarshalJSONType) {
-		return newUnmarshalJSONDecoder(typ), nil
-	} else if typ.Implements(unmarshalTextType) {
-		return newUnmarshalTextDecoder(typ), nil
+	dec, err := d.compileValue(typ)
+	if err != nil {
+		return nil, err
+	}
+	return d.withHooks(dec, typ), nil
+}
+
+func (d *Decoder) compileValue(typ *rtype) (decoder, error) {
+	if dec := newGeneratedDecoder(typ); dec != nil {
+		return dec, nil
+	}
+	if typ == rawMessageType {
+		return newRawMessageDecoder(), nil
+	}
+	if typ == rawViewType {
+		return newRawViewDecoder(), nil
+	}
+	if typ == rawSinkType {
+		return newRawSinkDecoder(), nil
+	}
+	if conv := enumConverter(typ); conv != nil {
+		return newConvertDecoder(typ, conv), nil
+	}
+	if dec := newWellKnownDecoder(typ, d.binaryUnmarshalers); dec != nil {
+		return dec, nil
+	}
+	if typ.Kind() != reflect.Ptr {
+		// decoders receive the address of the value,
+		// so methods are looked up on the pointer type.
+		ptrType := ptrTo(typ)
+		if d.implements(ptrType, unmarshalFromType) {
+			return newUnmarshalJSONFromDecoder(ptrType), nil
+		} else if d.implements(ptrType, unmarshalJSONType) {
+			return newUnmarshalJSONDecoder(ptrType), nil
+		} else if d.implements(ptrType, unmarshalTextType) {
+			return newUnmarshalTextDecoder(ptrType), nil
+		}
 	}
 	switch typ.Kind() {
 	case reflect.Ptr:
@@ -33,27 +77,30 @@ func (d *Decoder) compile(typ *rtype) (decoder, error) {
 	case reflect.Map:
 		return d.compileMap(typ)
 	case reflect.Interface:
+		if typ == writerType {
+			return newWriterDecoder(typ), nil
+		}
 		return d.compileInterface(typ)
 	case reflect.Int:
-		return d.compileInt()
+		return d.compileInt(typ)
 	case reflect.Int8:
-		return d.compileInt8()
+		return d.compileInt8(typ)
 	case reflect.Int16:
-		return d.compileInt16()
+		return d.compileInt16(typ)
 	case reflect.Int32:
-		return d.compileInt32()
+		return d.compileInt32(typ)
 	case reflect.Int64:
-		return d.compileInt64()
+		return d.compileInt64(typ)
 	case reflect.Uint:
-		return d.compileUint()
+		return d.compileUint(typ)
 	case reflect.Uint8:
-		return d.compileUint8()
+		return d.compileUint8(typ)
 	case reflect.Uint16:
-		return d.compileUint16()
+		return d.compileUint16(typ)
 	case reflect.Uint32:
-		return d.compileUint32()
+		return d.compileUint32(typ)
 	case reflect.Uint64:
-		return d.compileUint64()
+		return d.compileUint64(typ)
 	case reflect.String:
 		return d.compileString()
 	case reflect.Bool:
@@ -74,62 +121,62 @@ func (d *Decoder) compilePtr(typ *rtype) (decoder, error) {
 	return newPtrDecoder(dec, typ.Elem()), nil
 }
 
-func (d *Decoder) compileInt() (decoder, error) {
-	return newIntDecoder(func(p uintptr, v int64) {
+func (d *Decoder) compileInt(typ *rtype) (decoder, error) {
+	return newIntDecoder(typ, func(p uintptr, v int64) {
 		*(*int)(unsafe.Pointer(p)) = int(v)
 	}), nil
 }
 
-func (d *Decoder) compileInt8() (decoder, error) {
-	return newIntDecoder(func(p uintptr, v int64) {
+func (d *Decoder) compileInt8(typ *rtype) (decoder, error) {
+	return newIntDecoder(typ, func(p uintptr, v int64) {
 		*(*int8)(unsafe.Pointer(p)) = int8(v)
 	}), nil
 }
 
-func (d *Decoder) compileInt16() (decoder, error) {
-	return newIntDecoder(func(p uintptr, v int64) {
+func (d *Decoder) compileInt16(typ *rtype) (decoder, error) {
+	return newIntDecoder(typ, func(p uintptr, v int64) {
 		*(*int16)(unsafe.Pointer(p)) = int16(v)
 	}), nil
 }
 
-func (d *Decoder) compileInt32() (decoder, error) {
-	return newIntDecoder(func(p uintptr, v int64) {
+func (d *Decoder) compileInt32(typ *rtype) (decoder, error) {
+	return newIntDecoder(typ, func(p uintptr, v int64) {
 		*(*int32)(unsafe.Pointer(p)) = int32(v)
 	}), nil
 }
 
-func (d *Decoder) compileInt64() (decoder, error) {
-	return newIntDecoder(func(p uintptr, v int64) {
+func (d *Decoder) compileInt64(typ *rtype) (decoder, error) {
+	return newIntDecoder(typ, func(p uintptr, v int64) {
 		*(*int64)(unsafe.Pointer(p)) = v
 	}), nil
 }
 
-func (d *Decoder) compileUint() (decoder, error) {
-	return newUintDecoder(func(p uintptr, v uint64) {
+func (d *Decoder) compileUint(typ *rtype) (decoder, error) {
+	return newUintDecoder(typ, func(p uintptr, v uint64) {
 		*(*uint)(unsafe.Pointer(p)) = uint(v)
 	}), nil
 }
 
-func (d *Decoder) compileUint8() (decoder, error) {
-	return newUintDecoder(func(p uintptr, v uint64) {
+func (d *Decoder) compileUint8(typ *rtype) (decoder, error) {
+	return newUintDecoder(typ, func(p uintptr, v uint64) {
 		*(*uint8)(unsafe.Pointer(p)) = uint8(v)
 	}), nil
 }
 
-func (d *Decoder) compileUint16() (decoder, error) {
-	return newUintDecoder(func(p uintptr, v uint64) {
+func (d *Decoder) compileUint16(typ *rtype) (decoder, error) {
+	return newUintDecoder(typ, func(p uintptr, v uint64) {
 		*(*uint16)(unsafe.Pointer(p)) = uint16(v)
 	}), nil
 }
 
-func (d *Decoder) compileUint32() (decoder, error) {
-	return newUintDecoder(func(p uintptr, v uint64) {
+func (d *Decoder) compileUint32(typ *rtype) (decoder, error) {
+	return newUintDecoder(typ, func(p uintptr, v uint64) {
 		*(*uint32)(unsafe.Pointer(p)) = uint32(v)
 	}), nil
 }
 
-func (d *Decoder) compileUint64() (decoder, error) {
-	return newUintDecoder(func(p uintptr, v uint64) {
+func (d *Decoder) compileUint64(typ *rtype) (decoder, error) {
+	return newUintDecoder(typ, func(p uintptr, v uint64) {
 		*(*uint64)(unsafe.Pointer(p)) = v
 	}), nil
 }
@@ -181,34 +228,63 @@ func (d *Decoder) compileMap(typ *rtype) (decoder, error) {
 	if err != nil {
 		return nil, err
 	}
+	if typ == rawMessageMapType {
+		return newRawMessageMapDecoder(newMapDecoder(typ, keyDec, valueDec)), nil
+	}
 	return newMapDecoder(typ, keyDec, valueDec), nil
 }
 
 func (d *Decoder) compileInterface(typ *rtype) (decoder, error) {
-	return newInterfaceDecoder(typ), nil
+	if u := lookupUnion(typ); u != nil {
+		return newTypeResolverDecoder(newUnionDecoder(typ, u), typ), nil
+	}
+	if typ.NumMethod() > 0 {
+		return newTypeResolverDecoder(newNonEmptyInterfaceDecoder(typ), typ), nil
+	}
+	return newTypeResolverDecoder(newInterfaceDecoder(typ), typ), nil
 }
 
 func (d *Decoder) getTag(field reflect.StructField) string {
-	return field.Tag.Get("json")
+	return structTag(field)
 }
 
 func (d *Decoder) isIgnoredStructField(field reflect.StructField) bool {
-	if field.PkgPath != "" && !field.Anonymous {
-		// private field
-		return true
-	}
 	tag := d.getTag(field)
+	if field.PkgPath != "" && (!field.Anonymous || field.Type.Kind() == reflect.Interface) {
+		// private field, or embedded interface of an unexported type,
+		// unless it is a computed field
+		return !isMethodField(tag)
+	}
 	if tag == "-" {
 		return true
 	}
 	return false
 }
 
+// fieldAliases returns the names given by the "alias" options of the field tag options.
+// A field is also decoded from the members named by its aliases, while it is always encoded under its name.
+func fieldAliases(opts []string) []string {
+	var aliases []string
+	for _, opt := range opts[1:] {
+		if strings.HasPrefix(opt, "alias=") {
+			aliases = append(aliases, opt[len("alias="):])
+		}
+	}
+	return aliases
+}
+
 func (d *Decoder) compileStruct(typ *rtype) (decoder, error) {
 	fieldNum := typ.NumField()
 	fieldMap := map[string]*structFieldSet{}
+	aliasMap := map[string]*structFieldSet{}
+	var inline *inlineField
+	presenceOffset := -1
 	for i := 0; i < fieldNum; i++ {
 		field := typ.Field(i)
+		if type2rtype(field.Type) == presenceType {
+			presenceOffset = int(field.Offset)
+			continue
+		}
 		if d.isIgnoredStructField(field) {
 			continue
 		}
@@ -220,14 +296,88 @@ func (d *Decoder) compileStruct(typ *rtype) (decoder, error) {
 				keyName = opts[0]
 			}
 		}
-		dec, err := d.compile(type2rtype(field.Type))
+		if isInlineField(opts) {
+			if err := checkInlineField(typ, field, inline != nil); err != nil {
+				return nil, err
+			}
+			fieldType := type2rtype(field.Type)
+			dec, err := d.compile(fieldType.Elem())
+			if err != nil {
+				return nil, err
+			}
+			inline = &inlineField{typ: fieldType, offset: field.Offset, dec: dec}
+			continue
+		}
+		conv, err := fieldConverter(field, opts)
+		if err != nil {
+			return nil, err
+		}
+		var dec decoder
+		if methodName(opts) != "" {
+			// accepted, as it is in the output of the struct
+			dec = skipDecoder{}
+		} else if conv != nil && conv.Decode != nil {
+			dec = newConvertDecoder(type2rtype(field.Type), conv)
+		} else {
+			dec, err = d.compile(type2rtype(field.Type))
+			if err != nil {
+				return nil, err
+			}
+			if resolverDec, ok := dec.(*typeResolverDecoder); ok {
+				resolverDec.setField(typ, field)
+			}
+		}
+		option, limit, err := fieldLimit(field, opts)
 		if err != nil {
 			return nil, err
 		}
-		fieldSet := &structFieldSet{dec: dec, offset: field.Offset}
+		if option != "" {
+			dec = newLimitDecoder(dec, typ, keyName, type2rtype(field.Type), option, limit)
+		}
+		if isDeprecatedField(opts) {
+			dec = newDeprecatedDecoder(dec, typ, keyName, type2rtype(field.Type))
+		}
+		fieldType := type2rtype(field.Type)
+		fieldSet := &structFieldSet{
+			dec:      dec,
+			offset:   field.Offset,
+			name:     keyName,
+			typ:      fieldType,
+			notNull:  isNotNullField(opts),
+			nullable: isNullable(fieldType),
+		}
+		if path := fieldPath(keyName, opts); path != nil {
+			addPathField(fieldMap, path, fieldSet)
+			continue
+		}
+		if d.options().v2 {
+			// names match exactly
+			fieldMap[keyName] = fieldSet
+			for _, alias := range fieldAliases(opts) {
+				aliasMap[alias] = fieldSet
+			}
+			continue
+		}
 		fieldMap[field.Name] = fieldSet
 		fieldMap[keyName] = fieldSet
 		fieldMap[strings.ToLower(keyName)] = fieldSet
+		for _, alias := range fieldAliases(opts) {
+			aliasMap[alias] = fieldSet
+			aliasMap[strings.ToLower(alias)] = fieldSet
+		}
+	}
+	for alias, fieldSet := range aliasMap {
+		// the names of fields take precedence over aliases
+		if _, exists := fieldMap[alias]; !exists {
+			fieldMap[alias] = fieldSet
+		}
+	}
+	dec := newStructDecoder(fieldMap)
+	dec.structName = typ.Name()
+	dec.inline = inline
+	dec.indexFields()
+	if presenceOffset >= 0 {
+		dec.presence = newPresenceField(uintptr(presenceOffset), dec)
 	}
-	return newStructDecoder(fieldMap), nil
+	return newMigrationDecoder(typ, dec), nil
 }
@@ -1,7 +1,10 @@
 package json
 
+import "bytes"
+
 var (
 	isWhiteSpace = [256]bool{}
+	isStructural = [256]bool{} // characters skipSubtree stops at
 )
 
 func init() {
@@ -9,6 +12,9 @@ func init() {
 	isWhiteSpace['\n'] = true
 	isWhiteSpace['\t'] = true
 	isWhiteSpace['\r'] = true
+	for _, c := range []byte{nul, '{', '[', '}', ']', '"'} {
+		isStructural[c] = true
+	}
 }
 
 func skipWhiteSpace(buf []byte, cursor int64) int64 {
@@ -20,6 +26,18 @@ LOOP:
 	return cursor
 }
 
+// skipNumberTail returns the cursor after the fraction and exponent of the number before it.
+func skipNumberTail(buf []byte, cursor int64) int64 {
+	for {
+		switch buf[cursor] {
+		case '.', 'e', 'E', '+', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			cursor++
+			continue
+		}
+		return cursor
+	}
+}
+
 func skipValue(buf []byte, cursor int64) (int64, error) {
 	cursor = skipWhiteSpace(buf, cursor)
 	braceCount := 0
@@ -38,8 +56,14 @@ func skipValue(buf []byte, cursor int64) (int64, error) {
 			if braceCount == -1 && bracketCount == 0 {
 				return cursor, nil
 			}
+			if braceCount == 0 && bracketCount == 0 {
+				return cursor + 1, nil
+			}
 		case ']':
 			bracketCount--
+			if braceCount == 0 && bracketCount == 0 {
+				return cursor + 1, nil
+			}
 		case ',':
 			if bracketCount == 0 && braceCount == 0 {
 				return cursor, nil
@@ -134,3 +158,62 @@ func skipValue(buf []byte, cursor int64) (int64, error) {
 	}
 	return cursor, errUnexpectedEndOfJSON("value of object", cursor)
 }
+
+// skipSubtree skips the value at cursor like skipValue, but skips an object or array
+// with a raw structural scan that only tracks brackets and string boundaries,
+// without checking the tokens between them. It is used for values no field is decoded from.
+func skipSubtree(buf []byte, cursor int64) (int64, error) {
+	cursor = skipWhiteSpace(buf, cursor)
+	if c := buf[cursor]; c != '{' && c != '[' {
+		return skipValue(buf, cursor)
+	}
+	var stack [32]byte
+	closers := stack[:0] // the closing brackets of the open objects and arrays
+	for {
+		for !isStructural[buf[cursor]] {
+			cursor++
+		}
+		switch c := buf[cursor]; c {
+		case nul:
+			return 0, errUnexpectedEndOfJSON("value of object", cursor)
+		case '{':
+			closers = append(closers, '}')
+		case '[':
+			closers = append(closers, ']')
+		case '}', ']':
+			if expected := closers[len(closers)-1]; c != expected {
+				return 0, errMismatchedBracket(c, expected, cursor)
+			}
+			closers = closers[:len(closers)-1]
+			if len(closers) == 0 {
+				return cursor + 1, nil
+			}
+		case '"':
+			end, err := skipStringBytes(buf, cursor)
+			if err != nil {
+				return 0, err
+			}
+			cursor = end
+			continue
+		}
+		cursor++
+	}
+}
+
+// skipStringBytes returns the cursor after the string whose opening quote is at cursor.
+func skipStringBytes(buf []byte, cursor int64) (int64, error) {
+	for {
+		idx := bytes.IndexByte(buf[cursor+1:], '"')
+		if idx < 0 {
+			return 0, errUnexpectedEndOfJSON("value of string", int64(len(buf))-1)
+		}
+		cursor += int64(idx) + 1
+		escaped := false
+		for i := cursor - 1; buf[i] == '\\'; i-- {
+			escaped = !escaped
+		}
+		if !escaped {
+			return cursor + 1, nil
+		}
+	}
+}
@@ -1,10 +1,5 @@
 package json
 
-import (
-	"strconv"
-	"unsafe"
-)
-
 type floatDecoder struct {
 	op func(uintptr, float64)
 }
@@ -28,6 +23,7 @@ var floatTable = [256]bool{
 	'e': true,
 	'E': true,
 	'+': true,
+	'-': true,
 }
 
 func floatBytes(s *stream) []byte {
@@ -57,6 +53,15 @@ func (d *floatDecoder) decodeStreamByte(s *stream) ([]byte, error) {
 			continue
 		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 			return floatBytes(s), nil
+		case '+':
+			if !s.allowLeadingPlus {
+				goto ERROR
+			}
+			s.cursor++
+			if !numTable[s.char()] {
+				goto ERROR
+			}
+			continue
 		case nul:
 			if s.read() {
 				continue
@@ -70,12 +75,18 @@ ERROR:
 	return nil, errUnexpectedEndOfJSON("float", s.totalOffset())
 }
 
-func (d *floatDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, error) {
+func (d *floatDecoder) decodeByte(ctx *runtimeContext, cursor int64) ([]byte, int64, error) {
+	buf := ctx.buf
 	buflen := int64(len(buf))
 	for ; cursor < buflen; cursor++ {
 		switch buf[cursor] {
 		case ' ', '\n', '\t', '\r':
 			continue
+		case '+':
+			if !ctx.allowLeadingPlus || !numTable[buf[cursor+1]] {
+				return nil, 0, errUnexpectedEndOfJSON("float", cursor)
+			}
+			continue
 		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 			start := cursor
 			cursor++
@@ -99,8 +110,7 @@ func (d *floatDecoder) decodeStream(s *stream, p uintptr) error {
 	if err != nil {
 		return err
 	}
-	str := *(*string)(unsafe.Pointer(&bytes))
-	f64, err := strconv.ParseFloat(str, 64)
+	f64, err := parseFloat(bytes)
 	if err != nil {
 		return err
 	}
@@ -108,14 +118,13 @@ func (d *floatDecoder) decodeStream(s *stream, p uintptr) error {
 	return nil
 }
 
-func (d *floatDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
-	bytes, c, err := d.decodeByte(buf, cursor)
+func (d *floatDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	bytes, c, err := d.decodeByte(ctx, cursor)
 	if err != nil {
 		return 0, err
 	}
 	cursor = c
-	s := *(*string)(unsafe.Pointer(&bytes))
-	f64, err := strconv.ParseFloat(s, 64)
+	f64, err := parseFloat(bytes)
 	if err != nil {
 		return 0, err
 	}
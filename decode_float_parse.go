@@ -0,0 +1,178 @@
+package json
+
+//go:generate go run decode_float_pow10_gen.go
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+	"unsafe"
+)
+
+// float64pow10 holds the powers of ten represented exactly by a float64.
+var float64pow10 = [...]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10,
+	1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22,
+}
+
+// parseFloat returns the float64 closest to the number b, like strconv.ParseFloat.
+// Numbers of up to 19 significant digits are converted by an exact computation
+// or the Eisel-Lemire algorithm; strconv.ParseFloat handles the rest, including
+// the few inputs Eisel-Lemire cannot decide, values out of range and syntax errors.
+func parseFloat(b []byte) (float64, error) {
+	if f, ok := fastParseFloat(b); ok {
+		return f, nil
+	}
+	return strconv.ParseFloat(*(*string)(unsafe.Pointer(&b)), 64)
+}
+
+func fastParseFloat(b []byte) (float64, bool) {
+	n := len(b)
+	i := 0
+	neg := false
+	if i < n && b[i] == '-' {
+		neg = true
+		i++
+	}
+	var (
+		man        uint64
+		digits     int // significant digits in man
+		exp10      int
+		sawDigits  bool
+		sawDot     bool
+		fracDigits bool
+	)
+	for ; i < n; i++ {
+		c := b[i]
+		if c == '.' {
+			if sawDot || !sawDigits {
+				return 0, false
+			}
+			sawDot = true
+			continue
+		}
+		if c < '0' || '9' < c {
+			break
+		}
+		sawDigits = true
+		if sawDot {
+			fracDigits = true
+			exp10--
+		}
+		if man == 0 && c == '0' {
+			continue // leading zero
+		}
+		if digits == 19 {
+			return 0, false
+		}
+		man = man*10 + uint64(c-'0')
+		digits++
+	}
+	if !sawDigits || sawDot && !fracDigits {
+		return 0, false
+	}
+	if i < n && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		expNeg := false
+		if i < n && (b[i] == '+' || b[i] == '-') {
+			expNeg = b[i] == '-'
+			i++
+		}
+		if i == n {
+			return 0, false
+		}
+		e := 0
+		for ; i < n && '0' <= b[i] && b[i] <= '9'; i++ {
+			if e < 10000 {
+				e = e*10 + int(b[i]-'0')
+			}
+		}
+		if expNeg {
+			e = -e
+		}
+		exp10 += e
+	}
+	if i != n {
+		return 0, false
+	}
+	if man == 0 {
+		if neg {
+			return math.Copysign(0, -1), true
+		}
+		return 0, true
+	}
+	if man>>53 == 0 {
+		// man and the power of ten are exact, so a single rounding gives the closest float
+		f := float64(man)
+		if neg {
+			f = -f
+		}
+		switch {
+		case exp10 == 0:
+			return f, true
+		case 0 < exp10 && exp10 < len(float64pow10):
+			return f * float64pow10[exp10], true
+		case -len(float64pow10) < exp10 && exp10 < 0:
+			return f / float64pow10[-exp10], true
+		}
+	}
+	return eiselLemire64(man, exp10, neg)
+}
+
+// eiselLemire64 returns the float64 closest to man*10^exp10 for a non-zero man,
+// or false when it cannot be decided from the 128-bit approximation of 10^exp10
+// or the result is subnormal, infinite or out of the range of the table.
+func eiselLemire64(man uint64, exp10 int, neg bool) (float64, bool) {
+	if exp10 < pow10MinExp10 || pow10MaxExp10 < exp10 {
+		return 0, false
+	}
+	pow := &pow10Mantissas[exp10-pow10MinExp10]
+
+	// normalize man so that its most significant bit is set
+	clz := bits.LeadingZeros64(man)
+	man <<= uint(clz)
+	const float64ExponentBias = 1023
+	// 217706/2^16 approximates log2(10)
+	retExp2 := uint64(217706*exp10>>16+64+float64ExponentBias) - uint64(clz)
+
+	xHi, xLo := bits.Mul64(man, pow[1])
+	if xHi&0x1FF == 0x1FF && xLo+man < man {
+		// the low bits may be affected by the truncation of the power, use all 128 bits
+		yHi, yLo := bits.Mul64(man, pow[0])
+		mergedHi, mergedLo := xHi, xLo+yHi
+		if mergedLo < xLo {
+			mergedHi++
+		}
+		if mergedHi&0x1FF == 0x1FF && mergedLo+1 == 0 && yLo+man < man {
+			return 0, false
+		}
+		xHi, xLo = mergedHi, mergedLo
+	}
+
+	// keep 54 bits, one more than the mantissa for rounding
+	msb := xHi >> 63
+	retMan := xHi >> (msb + 9)
+	retExp2 -= 1 ^ msb
+
+	if xLo == 0 && xHi&0x1FF == 0 && retMan&3 == 1 {
+		// exactly half-way between two floats, which the approximation cannot tell apart
+		return 0, false
+	}
+
+	// round to 53 bits
+	retMan += retMan & 1
+	retMan >>= 1
+	if retMan>>53 > 0 {
+		retMan >>= 1
+		retExp2++
+	}
+	// zero or an underflow means a subnormal, 0x7FF or above an infinity
+	if retExp2-1 >= 0x7FF-1 {
+		return 0, false
+	}
+	retBits := retExp2<<52 | retMan&(1<<52-1)
+	if neg {
+		retBits |= 1 << 63
+	}
+	return math.Float64frombits(retBits), true
+}
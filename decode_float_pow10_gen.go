@@ -0,0 +1,81 @@
+//go:build ignore
+// +build ignore
+
+// This program generates decode_float_pow10.go, the table of 128-bit
+// mantissas of powers of ten used by the Eisel-Lemire float parser.
+// Run it with: go run decode_float_pow10_gen.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"math/big"
+)
+
+const (
+	minExp10 = -348
+	maxExp10 = 347
+)
+
+var one = big.NewInt(1)
+
+// mantissa returns the 128-bit mantissa of 10^q, which has the same bits as 5^q:
+// truncated for q >= 0 and rounded up at 128 bits or truncated past them for q < 0,
+// as required by the algorithm.
+func mantissa(q int) *big.Int {
+	limit := new(big.Int).Lsh(one, 128)
+	if q >= 0 {
+		m := new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(q)), nil)
+		min := new(big.Int).Lsh(one, 127)
+		for m.Cmp(min) < 0 {
+			m.Lsh(m, 1)
+		}
+		for m.Cmp(limit) >= 0 {
+			m.Rsh(m, 1)
+		}
+		return m
+	}
+	power5 := new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(-q)), nil)
+	z := uint(power5.BitLen())
+	b := 2*z + 2*64
+	if q >= -27 {
+		b = z + 127
+	}
+	m := new(big.Int).Lsh(one, b)
+	m.Quo(m, power5)
+	m.Add(m, one)
+	for m.Cmp(limit) >= 0 {
+		m.Rsh(m, 1)
+	}
+	return m
+}
+
+func main() {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by decode_float_pow10_gen.go; DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package json")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "const (\n\tpow10MinExp10 = %d\n\tpow10MaxExp10 = %d\n)\n\n", minExp10, maxExp10)
+	fmt.Fprintln(&buf, "// pow10Mantissas holds the low and high 64 bits of the 128-bit mantissa")
+	fmt.Fprintln(&buf, "// of 10^q at index q-pow10MinExp10.")
+	fmt.Fprintf(&buf, "var pow10Mantissas = [%d][2]uint64{\n", maxExp10-minExp10+1)
+	mask := new(big.Int).SetUint64(^uint64(0))
+	for q := minExp10; q <= maxExp10; q++ {
+		m := mantissa(q)
+		lo := new(big.Int).And(m, mask).Uint64()
+		hi := new(big.Int).Rsh(m, 64).Uint64()
+		fmt.Fprintf(&buf, "\t{0x%016X, 0x%016X}, // 1e%d\n", lo, hi, q)
+	}
+	fmt.Fprintln(&buf, "}")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("decode_float_pow10.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
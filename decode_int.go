@@ -1,37 +1,100 @@
 package json
 
+import (
+	"bytes"
+	"math"
+)
+
 type intDecoder struct {
-	op func(uintptr, int64)
+	typ  *rtype
+	bits uint // the size of the integers of typ
+	op   func(uintptr, int64)
 }
 
-func newIntDecoder(op func(uintptr, int64)) *intDecoder {
-	return &intDecoder{op: op}
+func newIntDecoder(typ *rtype, op func(uintptr, int64)) *intDecoder {
+	return &intDecoder{typ: typ, bits: uint(typ.Size()) * 8, op: op}
 }
 
-var (
-	pow10i64 = [...]int64{
-		1e00, 1e01, 1e02, 1e03, 1e04, 1e05, 1e06, 1e07, 1e08, 1e09,
-		1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18,
+// parseInt returns the value of the integer literal b, an optional minus sign
+// followed by digits, or false if b has no digits or overflows an int64.
+func parseInt(b []byte) (int64, bool) {
+	neg := len(b) > 0 && b[0] == '-'
+	if neg {
+		b = b[1:]
 	}
-)
+	n, ok := parseUint(b)
+	if !ok {
+		return 0, false
+	}
+	if neg {
+		if n > -math.MinInt64 {
+			return 0, false
+		}
+		return -int64(n), true
+	}
+	if n > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(n), true
+}
 
-func (d *intDecoder) parseInt(b []byte) int64 {
-	isNegative := false
-	if b[0] == '-' {
-		b = b[1:]
-		isNegative = true
+// expandExponent returns the digits of the number b, with its sign, if b is in
+// exponent form and its value is an integer of at most 20 digits, and nil otherwise.
+func expandExponent(b []byte) []byte {
+	exp := bytes.IndexAny(b, "eE")
+	if exp < 0 || !validNumber(b) {
+		return nil
 	}
-	maxDigit := len(b)
-	sum := int64(0)
-	for i := 0; i < maxDigit; i++ {
-		c := int64(b[i]) - 48
-		digitValue := pow10i64[maxDigit-i-1]
-		sum += c * digitValue
+	e, ok := parseInt(bytes.TrimPrefix(b[exp+1:], []byte("+")))
+	if !ok {
+		return nil
+	}
+	mantissa := b[:exp]
+	var digits []byte
+	if mantissa[0] == '-' {
+		digits = append(digits, '-')
+		mantissa = mantissa[1:]
+	}
+	sign := len(digits)
+	if dot := bytes.IndexByte(mantissa, '.'); dot >= 0 {
+		e -= int64(len(mantissa) - dot - 1)
+		digits = append(append(digits, mantissa[:dot]...), mantissa[dot+1:]...)
+	} else {
+		digits = append(digits, mantissa...)
+	}
+	for e < 0 && len(digits) > sign && digits[len(digits)-1] == '0' {
+		digits = digits[:len(digits)-1]
+		e++
+	}
+	for len(digits) > sign+1 && digits[sign] == '0' {
+		digits = append(digits[:sign], digits[sign+1:]...)
+	}
+	if e < 0 {
+		if len(digits) > sign+1 || digits[sign] != '0' {
+			return nil
+		}
+		e = 0
+	}
+	if int64(len(digits)-sign)+e > 20 {
+		return nil
+	}
+	for ; e > 0; e-- {
+		digits = append(digits, '0')
+	}
+	return digits
+}
+
+func (d *intDecoder) store(b []byte, p uintptr, offset int64) error {
+	n, ok := parseInt(b)
+	if ok && d.bits < 64 {
+		limit := int64(1) << (d.bits - 1)
+		ok = n >= -limit && n < limit
 	}
-	if isNegative {
-		return -1 * sum
+	if !ok {
+		return &UnmarshalTypeError{Value: "number " + string(b), Type: rtype2type(d.typ), Offset: offset}
 	}
-	return sum
+	d.op(p, n)
+	return nil
 }
 
 var (
@@ -57,6 +120,15 @@ func (d *intDecoder) decodeStreamByte(s *stream) ([]byte, error) {
 		case ' ', '\n', '\t', '\r':
 			s.cursor++
 			continue
+		case '+':
+			if !s.allowLeadingPlus {
+				goto ERROR
+			}
+			s.cursor++
+			if !numTable[s.char()] {
+				goto ERROR
+			}
+			continue
 		case '-':
 			start := s.cursor
 			for {
@@ -71,6 +143,9 @@ func (d *intDecoder) decodeStreamByte(s *stream) ([]byte, error) {
 				}
 				break
 			}
+			if s.strictNumbers || s.exponentIntegers {
+				s.skipNumberTail()
+			}
 			num := s.buf[start:s.cursor]
 			s.reset()
 			if len(num) < 2 {
@@ -91,6 +166,9 @@ func (d *intDecoder) decodeStreamByte(s *stream) ([]byte, error) {
 				}
 				break
 			}
+			if s.strictNumbers || s.exponentIntegers {
+				s.skipNumberTail()
+			}
 			num := s.buf[start:s.cursor]
 			s.reset()
 			return num, nil
@@ -107,12 +185,19 @@ ERROR:
 	return nil, errUnexpectedEndOfJSON("number(integer)", s.totalOffset())
 }
 
-func (d *intDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, error) {
+func (d *intDecoder) decodeByte(ctx *runtimeContext, cursor int64) ([]byte, int64, error) {
+	buf := ctx.buf
 	for {
 		switch buf[cursor] {
 		case ' ', '\n', '\t', '\r':
 			cursor++
 			continue
+		case '+':
+			if !ctx.allowLeadingPlus || !numTable[buf[cursor+1]] {
+				return nil, 0, errInvalidCharacter(buf[cursor], "number(integer)", cursor)
+			}
+			cursor++
+			continue
 		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 			start := cursor
 			cursor++
@@ -121,6 +206,9 @@ func (d *intDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, error)
 				cursor++
 				goto LOOP
 			}
+			if ctx.strictNumbers || ctx.exponentIntegers {
+				cursor = skipNumberTail(buf, cursor)
+			}
 			num := buf[start:cursor]
 			return num, cursor, nil
 		default:
@@ -135,16 +223,32 @@ func (d *intDecoder) decodeStream(s *stream, p uintptr) error {
 	if err != nil {
 		return err
 	}
-	d.op(p, d.parseInt(bytes))
-	return nil
+	if s.exponentIntegers {
+		if digits := expandExponent(bytes); digits != nil {
+			if d.store(digits, p, s.totalOffset()) != nil {
+				return &UnmarshalTypeError{Value: "number " + string(bytes), Type: rtype2type(d.typ), Offset: s.totalOffset()}
+			}
+			return nil
+		}
+	}
+	return d.store(bytes, p, s.totalOffset())
 }
 
-func (d *intDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
-	bytes, c, err := d.decodeByte(buf, cursor)
+func (d *intDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	bytes, c, err := d.decodeByte(ctx, cursor)
 	if err != nil {
 		return 0, err
 	}
-	cursor = c
-	d.op(p, d.parseInt(bytes))
-	return cursor, nil
+	if ctx.exponentIntegers {
+		if digits := expandExponent(bytes); digits != nil {
+			if d.store(digits, p, c) != nil {
+				return 0, &UnmarshalTypeError{Value: "number " + string(bytes), Type: rtype2type(d.typ), Offset: c}
+			}
+			return c, nil
+		}
+	}
+	if err := d.store(bytes, p, c); err != nil {
+		return 0, err
+	}
+	return c, nil
 }
@@ -21,8 +21,8 @@ func (d *interfaceDecoder) setDisallowUnknownFields(disallowUnknownFields bool)
 	d.disallowUnknownFields = disallowUnknownFields
 }
 
-func (d *interfaceDecoder) numDecoder(s *stream) decoder {
-	if s.useNumber {
+func (d *interfaceDecoder) numDecoder(useNumber bool) decoder {
+	if useNumber {
 		return newNumberDecoder(func(p uintptr, v Number) {
 			*(*interface{})(unsafe.Pointer(p)) = v
 		})
@@ -43,6 +43,11 @@ func (d *interfaceDecoder) decodeStream(s *stream, p uintptr) error {
 	for {
 		switch s.char() {
 		case '{':
+			if s.reuseContainers {
+				if m := reusableMap(p); m != nil {
+					return d.decodeReusedMapStream(s, m)
+				}
+			}
 			var v map[interface{}]interface{}
 			ptr := unsafe.Pointer(&v)
 			d.dummy = ptr
@@ -58,6 +63,11 @@ func (d *interfaceDecoder) decodeStream(s *stream, p uintptr) error {
 			*(*interface{})(unsafe.Pointer(p)) = v
 			return nil
 		case '[':
+			if s.reuseContainers {
+				if old, ok := (*(*interface{})(unsafe.Pointer(p))).([]interface{}); ok && cap(old) > 0 {
+					return d.decodeReusedSliceStream(s, p, old)
+				}
+			}
 			var v []interface{}
 			ptr := unsafe.Pointer(&v)
 			d.dummy = ptr // escape ptr
@@ -73,24 +83,45 @@ func (d *interfaceDecoder) decodeStream(s *stream, p uintptr) error {
 			*(*interface{})(unsafe.Pointer(p)) = v
 			return nil
 		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-			return d.numDecoder(s).decodeStream(s, p)
-		case '"':
+			return d.numDecoder(s.useNumber).decodeStream(s, p)
+		case '+':
+			if !s.allowLeadingPlus {
+				break
+			}
+			return d.numDecoder(s.useNumber).decodeStream(s, p)
+		case '"', '\'':
+			quote := s.char()
+			if quote == '\'' && !s.allowSingleQuotes {
+				break
+			}
 			s.cursor++
 			start := s.cursor
 			for {
-				switch s.char() {
-				case '\\':
+				c := s.char()
+				switch {
+				case c == '\\':
 					s.cursor++
-				case '"':
+				case c == quote:
 					literal := s.buf[start:s.cursor]
+					if s.strictStrings {
+						if err := checkStrictString(literal, s.offset+start, s.allowSingleQuotes); err != nil {
+							return err
+						}
+					}
 					s.cursor++
+					if err := s.allocate(int64(len(literal))); err != nil {
+						return err
+					}
+					literal = unescapeString(literal)
 					*(*interface{})(unsafe.Pointer(p)) = *(*string)(unsafe.Pointer(&literal))
 					return nil
-				case nul:
+				case c == nul:
 					if s.read() {
 						continue
 					}
 					return errUnexpectedEndOfJSON("string", s.totalOffset())
+				case c < 0x20 && !s.allowControlChars:
+					return errInvalidCharacter(c, "string", s.totalOffset())
 				}
 				s.cursor++
 			}
@@ -123,7 +154,8 @@ func (d *interfaceDecoder) decodeStream(s *stream, p uintptr) error {
 	return errNotAtBeginningOfValue(s.totalOffset())
 }
 
-func (d *interfaceDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
+func (d *interfaceDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
 	cursor = skipWhiteSpace(buf, cursor)
 	switch buf[cursor] {
 	case '{':
@@ -136,7 +168,7 @@ func (d *interfaceDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, e
 			newInterfaceDecoder(d.typ),
 		)
 		dec.setDisallowUnknownFields(d.disallowUnknownFields)
-		cursor, err := dec.decode(buf, cursor, uintptr(ptr))
+		cursor, err := dec.decode(ctx, cursor, uintptr(ptr))
 		if err != nil {
 			return 0, err
 		}
@@ -152,34 +184,29 @@ func (d *interfaceDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, e
 			d.typ.Size(),
 		)
 		dec.setDisallowUnknownFields(d.disallowUnknownFields)
-		cursor, err := dec.decode(buf, cursor, uintptr(ptr))
+		cursor, err := dec.decode(ctx, cursor, uintptr(ptr))
 		if err != nil {
 			return 0, err
 		}
 		*(*interface{})(unsafe.Pointer(p)) = v
 		return cursor, nil
 	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		return newFloatDecoder(func(p uintptr, v float64) {
-			*(*interface{})(unsafe.Pointer(p)) = v
-		}).decode(buf, cursor, p)
-	case '"':
-		cursor++
-		start := cursor
-		for {
-			switch buf[cursor] {
-			case '\\':
-				cursor++
-			case '"':
-				literal := buf[start:cursor]
-				cursor++
-				*(*interface{})(unsafe.Pointer(p)) = *(*string)(unsafe.Pointer(&literal))
-				return cursor, nil
-			case '\000':
-				return 0, errUnexpectedEndOfJSON("string", cursor)
-			}
-			cursor++
+		return d.numDecoder(ctx.useNumber).decode(ctx, cursor, p)
+	case '+':
+		if !ctx.allowLeadingPlus {
+			break
 		}
-		return 0, errUnexpectedEndOfJSON("string", cursor)
+		return d.numDecoder(ctx.useNumber).decode(ctx, cursor, p)
+	case '"', '\'':
+		if buf[cursor] == '\'' && !ctx.allowSingleQuotes {
+			break
+		}
+		literal, c, err := stringLiteralByte(ctx, cursor)
+		if err != nil {
+			return 0, err
+		}
+		*(*interface{})(unsafe.Pointer(p)) = *(*string)(unsafe.Pointer(&literal))
+		return c, nil
 	case 't':
 		if cursor+3 >= int64(len(buf)) {
 			return 0, errUnexpectedEndOfJSON("bool(true)", cursor)
@@ -234,3 +261,98 @@ func (d *interfaceDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, e
 	}
 	return cursor, errNotAtBeginningOfValue(cursor)
 }
+
+// nonEmptyInterfaceDecoder decodes an interface with methods, such as an embedded fmt.Stringer,
+// which no decoded value implements by itself. Like encoding/json, it decodes into the value
+// the interface holds a non-nil pointer to and sets the interface to nil for null;
+// other values are an UnmarshalTypeError.
+type nonEmptyInterfaceDecoder struct {
+	typ                   *rtype
+	disallowUnknownFields bool
+}
+
+func newNonEmptyInterfaceDecoder(typ *rtype) *nonEmptyInterfaceDecoder {
+	return &nonEmptyInterfaceDecoder{typ: typ}
+}
+
+func (d *nonEmptyInterfaceDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
+	d.disallowUnknownFields = disallowUnknownFields
+}
+
+func (d *nonEmptyInterfaceDecoder) field(p uintptr) reflect.Value {
+	return reflect.NewAt(rtype2type(d.typ), unsafe.Pointer(p)).Elem()
+}
+
+// target returns the pointer held by the interface at p and its decoder compiled for opts,
+// or a nil decoder if the interface holds no pointer to decode into.
+func (d *nonEmptyInterfaceDecoder) target(opts *decodeOptions, p uintptr) (reflect.Value, decoder, error) {
+	v := d.field(p).Elem()
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() {
+		return reflect.Value{}, nil, nil
+	}
+	target := Decoder{disallowUnknownFields: d.disallowUnknownFields, opts: opts}
+	dec, err := target.compiledDecoder(type2rtype(v.Type()))
+	return v, dec, err
+}
+
+func (d *nonEmptyInterfaceDecoder) typeError(c byte, cursor int64) error {
+	return &UnmarshalTypeError{Value: valueKind(c), Type: rtype2type(d.typ), Offset: cursor}
+}
+
+func (d *nonEmptyInterfaceDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	if s.char() == 'n' {
+		if err := nullBytes(s); err != nil {
+			return err
+		}
+		d.field(p).Set(reflect.Zero(rtype2type(d.typ)))
+		return nil
+	}
+	v, dec, err := d.target(&s.decodeOptions, p)
+	if err != nil {
+		return err
+	}
+	if dec == nil {
+		return d.typeError(s.char(), s.totalOffset())
+	}
+	return dec.decodeStream(s, v.Pointer())
+}
+
+func (d *nonEmptyInterfaceDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] == 'n' {
+		end, err := skipValue(buf, cursor)
+		if err != nil {
+			return 0, err
+		}
+		if string(buf[cursor:end]) != "null" {
+			return 0, errInvalidCharacter(buf[cursor], "null", cursor)
+		}
+		d.field(p).Set(reflect.Zero(rtype2type(d.typ)))
+		return end, nil
+	}
+	v, dec, err := d.target(ctx.decodeOptions, p)
+	if err != nil {
+		return 0, err
+	}
+	if dec == nil {
+		return 0, d.typeError(buf[cursor], cursor)
+	}
+	return dec.decode(ctx, cursor, v.Pointer())
+}
+
+// valueKind describes the JSON value starting with c for an UnmarshalTypeError.
+func valueKind(c byte) string {
+	switch c {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "bool"
+	}
+	return "number"
+}
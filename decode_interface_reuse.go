@@ -0,0 +1,237 @@
+package json
+
+import (
+	"unsafe"
+)
+
+// maxInternedKeys bounds the object keys remembered by ReuseInterfaceContainers,
+// so that documents with ever-changing keys do not grow the table without limit.
+const maxInternedKeys = 4096
+
+// reuseFrame is the state of a reused object being decoded at some nesting depth.
+// Frames are allocated once per depth and kept by the stream, so their addresses are stable.
+type reuseFrame struct {
+	value interface{}         // the value being decoded, taken from the map
+	seen  map[string]struct{} // keys decoded, to remove the keys missing from the input
+}
+
+// reusedMap is a map held by an interface{} that objects are decoded into.
+// Keys are interface values holding strings.
+type reusedMap interface {
+	lookup(key interface{}) interface{}
+	store(key, value interface{})
+	count() int
+	sweep(seen map[string]struct{})
+}
+
+type reusedStringMap map[string]interface{}
+
+func (m reusedStringMap) lookup(key interface{}) interface{} { return m[key.(string)] }
+func (m reusedStringMap) store(key, value interface{})       { m[key.(string)] = value }
+func (m reusedStringMap) count() int                         { return len(m) }
+
+func (m reusedStringMap) sweep(seen map[string]struct{}) {
+	for k := range m {
+		if _, ok := seen[k]; !ok {
+			delete(m, k)
+		}
+	}
+}
+
+type reusedInterfaceMap map[interface{}]interface{}
+
+func (m reusedInterfaceMap) lookup(key interface{}) interface{} { return m[key] }
+func (m reusedInterfaceMap) store(key, value interface{})       { m[key] = value }
+func (m reusedInterfaceMap) count() int                         { return len(m) }
+
+func (m reusedInterfaceMap) sweep(seen map[string]struct{}) {
+	for k := range m {
+		if s, ok := k.(string); ok {
+			if _, ok := seen[s]; ok {
+				continue
+			}
+		}
+		delete(m, k)
+	}
+}
+
+// reusableMap returns the non-nil map held by the interface value at p, or nil.
+func reusableMap(p uintptr) reusedMap {
+	switch m := (*(*interface{})(unsafe.Pointer(p))).(type) {
+	case map[string]interface{}:
+		if m != nil {
+			return reusedStringMap(m)
+		}
+	case map[interface{}]interface{}:
+		if m != nil {
+			return reusedInterfaceMap(m)
+		}
+	}
+	return nil
+}
+
+// internKey returns key as an interface value holding a string that does not refer to the buffer.
+// Keys seen before are returned without allocating.
+func (s *stream) internKey(key string) interface{} {
+	if k, ok := s.internedKeys[key]; ok {
+		return k
+	}
+	buf := make([]byte, len(key))
+	copy(buf, key)
+	var owned interface{} = *(*string)(unsafe.Pointer(&buf))
+	if s.internedKeys == nil {
+		s.internedKeys = map[string]interface{}{}
+	}
+	if len(s.internedKeys) < maxInternedKeys {
+		s.internedKeys[owned.(string)] = owned
+	}
+	return owned
+}
+
+func (s *stream) pushReuseFrame() *reuseFrame {
+	if s.reuseDepth == len(s.reuseFrames) {
+		s.reuseFrames = append(s.reuseFrames, &reuseFrame{seen: map[string]struct{}{}})
+	}
+	frame := s.reuseFrames[s.reuseDepth]
+	s.reuseDepth++
+	return frame
+}
+
+func (s *stream) popReuseFrame(frame *reuseFrame) {
+	frame.value = nil
+	for k := range frame.seen {
+		delete(frame.seen, k)
+	}
+	s.reuseDepth--
+}
+
+// decodeReusedMapStream decodes the object at the cursor into m, which is cleared
+// of the keys missing from the object. Values already in m are decoded into in turn.
+func (d *interfaceDecoder) decodeReusedMapStream(s *stream, m reusedMap) error {
+	frame := s.pushReuseFrame()
+	err := d.decodeReusedMapEntriesStream(s, m, frame)
+	if err == nil && m.count() > len(frame.seen) {
+		m.sweep(frame.seen)
+	}
+	s.popReuseFrame(frame)
+	return err
+}
+
+func (d *interfaceDecoder) decodeReusedMapEntriesStream(s *stream, m reusedMap, frame *reuseFrame) error {
+	s.cursor++
+	s.skipWhiteSpace()
+	if s.char() == '}' {
+		s.cursor++
+		return nil
+	}
+	var keyDecoder stringDecoder
+	for {
+		b, err := keyDecoder.decodeStreamKeyByte(s)
+		if err != nil {
+			return err
+		}
+		k := *(*string)(unsafe.Pointer(&b))
+		if s.keyTransformer != nil {
+			k = s.keyTransformer(k)
+		}
+		if _, exists := frame.seen[k]; exists && s.v2 {
+			return errDuplicateName(k, s.totalOffset())
+		}
+		key := s.internKey(k)
+		s.skipWhiteSpace()
+		if s.char() == nul {
+			s.read()
+		}
+		if s.char() != ':' {
+			return errExpected("colon after object key", s.totalOffset())
+		}
+		s.cursor++
+		if s.end() {
+			return errUnexpectedEndOfJSON("map", s.totalOffset())
+		}
+		frame.value = m.lookup(key)
+		if err := d.decodeStream(s, uintptr(unsafe.Pointer(&frame.value))); err != nil {
+			return err
+		}
+		n := m.count()
+		m.store(key, frame.value)
+		if m.count() > n {
+			if err := s.allocate(int64(2 * unsafe.Sizeof(key))); err != nil {
+				return err
+			}
+		}
+		frame.seen[key.(string)] = struct{}{}
+		s.skipWhiteSpace()
+		if s.char() == nul {
+			s.read()
+		}
+		if s.char() == '}' {
+			s.cursor++
+			return nil
+		}
+		if s.char() != ',' {
+			return errExpected("semicolon after object value", s.totalOffset())
+		}
+		s.cursor++
+	}
+}
+
+// decodeReusedSliceStream decodes the array at the cursor into the backing array of old,
+// decoding into the elements it held before, and stores the result at p.
+func (d *interfaceDecoder) decodeReusedSliceStream(s *stream, p uintptr, old []interface{}) error {
+	s.cursor++
+	s.skipWhiteSpace()
+	buf := old[:cap(old)]
+	if s.char() == ']' {
+		s.cursor++
+		clearElems(buf)
+		*(*interface{})(unsafe.Pointer(p)) = buf[:0]
+		return nil
+	}
+	idx := 0
+	skipped := 0 // elements left out by ContinueOnElementError
+	for {
+		if idx == len(buf) {
+			if err := s.allocate(int64(len(buf)+1) * int64(d.typ.Size())); err != nil {
+				return err
+			}
+			buf = append(buf, nil)
+			buf = buf[:cap(buf)]
+		}
+		ok, err := s.decodeElement(d, d.typ, idx+skipped, uintptr(unsafe.Pointer(&buf[idx])))
+		if err != nil {
+			return err
+		}
+		if ok {
+			idx++
+		} else {
+			skipped++
+		}
+		s.skipWhiteSpace()
+	RETRY:
+		switch s.char() {
+		case ']':
+			s.cursor++
+			clearElems(buf[idx:])
+			*(*interface{})(unsafe.Pointer(p)) = buf[:idx]
+			return nil
+		case ',':
+		case nul:
+			if s.read() {
+				goto RETRY
+			}
+			return errUnexpectedEndOfJSON("slice", s.totalOffset())
+		default:
+			return errUnexpectedEndOfJSON("slice", s.totalOffset())
+		}
+		s.cursor++
+	}
+}
+
+// clearElems drops the values the elements past the decoded length still hold,
+// so they are not kept reachable through the backing array.
+func clearElems(elems []interface{}) {
+	for i := range elems {
+		elems[i] = nil
+	}
+}
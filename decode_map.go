@@ -1,6 +1,7 @@
 package json
 
 import (
+	"reflect"
 	"unsafe"
 )
 
@@ -8,7 +9,7 @@ type mapDecoder struct {
 	mapType      *rtype
 	keyDecoder   decoder
 	valueDecoder decoder
-	dummy        *interfaceHeader
+	entrySize    int64
 }
 
 func newMapDecoder(mapType *rtype, keyDec decoder, valueDec decoder) *mapDecoder {
@@ -16,6 +17,7 @@ func newMapDecoder(mapType *rtype, keyDec decoder, valueDec decoder) *mapDecoder
 		mapType:      mapType,
 		keyDecoder:   keyDec,
 		valueDecoder: valueDec,
+		entrySize:    int64(mapType.Key().Size() + mapType.Elem().Size()),
 	}
 }
 
@@ -31,26 +33,60 @@ func (d *mapDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
 	d.valueDecoder.setDisallowUnknownFields(disallowUnknownFields)
 }
 
-func (d *mapDecoder) setKey(buf []byte, cursor int64, key interface{}) (int64, error) {
-	header := (*interfaceHeader)(unsafe.Pointer(&key))
-	return d.keyDecoder.decode(buf, cursor, uintptr(header.ptr))
-}
-
-func (d *mapDecoder) setValue(buf []byte, cursor int64, key interface{}) (int64, error) {
-	header := (*interfaceHeader)(unsafe.Pointer(&key))
-	return d.valueDecoder.decode(buf, cursor, uintptr(header.ptr))
+// transformKey applies fn to a decoded string key.
+// Keys of string kind are transformed, and interface keys holding a string.
+func (d *mapDecoder) transformKey(fn func(string) string, key unsafe.Pointer) {
+	switch d.mapType.Key().Kind() {
+	case reflect.String:
+		k := (*string)(key)
+		*k = fn(*k)
+	case reflect.Interface:
+		k := (*interface{})(key)
+		if str, ok := (*k).(string); ok {
+			*k = fn(str)
+		}
+	}
 }
 
-func (d *mapDecoder) setKeyStream(s *stream, key interface{}) error {
-	header := (*interfaceHeader)(unsafe.Pointer(&key))
-	d.dummy = header
-	return d.keyDecoder.decodeStream(s, uintptr(header.ptr))
+func (d *mapDecoder) setKeyStream(s *stream, key unsafe.Pointer) error {
+	s.skipWhiteSpace()
+	if isUnquotedKey(s) {
+		// decode bare key as if it were written as a quoted string
+		literal := unquotedKeyBytes(s)
+		quoted := make([]byte, 0, len(literal)+3)
+		quoted = append(quoted, '"')
+		quoted = append(quoted, literal...)
+		quoted = append(quoted, '"', nul)
+		_, err := d.keyDecoder.decode(newRuntimeContext(quoted, &s.decodeOptions), 0, uintptr(key))
+		return err
+	}
+	if dec, ok := d.keyDecoder.(*stringDecoder); ok {
+		return dec.decodeStreamKey(s, uintptr(key))
+	}
+	return d.keyDecoder.decodeStream(s, uintptr(key))
 }
 
-func (d *mapDecoder) setValueStream(s *stream, key interface{}) error {
-	header := (*interfaceHeader)(unsafe.Pointer(&key))
-	d.dummy = header
-	return d.valueDecoder.decodeStream(s, uintptr(header.ptr))
+func (d *mapDecoder) setKey(ctx *runtimeContext, cursor int64, key unsafe.Pointer) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	if dec, ok := d.keyDecoder.(*stringDecoder); ok {
+		return dec.decodeKey(ctx, cursor, uintptr(key))
+	}
+	if ctx.allowUnquotedKeys && unquotedKeyStartTable[buf[cursor]] {
+		// decode bare key as if it were written as a quoted string
+		start := cursor
+		for cursor++; unquotedKeyTable[buf[cursor]]; cursor++ {
+		}
+		quoted := make([]byte, 0, cursor-start+3)
+		quoted = append(quoted, '"')
+		quoted = append(quoted, buf[start:cursor]...)
+		quoted = append(quoted, '"', nul)
+		if _, err := d.keyDecoder.decode(ctx.withBuf(quoted), 0, uintptr(key)); err != nil {
+			return 0, err
+		}
+		return cursor, nil
+	}
+	return d.keyDecoder.decode(ctx, cursor, uintptr(key))
 }
 
 func (d *mapDecoder) decodeStream(s *stream, p uintptr) error {
@@ -72,12 +108,16 @@ func (d *mapDecoder) decodeStream(s *stream, p uintptr) error {
 		s.cursor++
 		return nil
 	}
-	for {
+	v2 := s.v2
+	for size := 0; ; size++ {
 		s.cursor++
-		var key interface{}
-		if err := d.setKeyStream(s, &key); err != nil {
+		key := unsafe.Pointer(unsafe_New(d.mapType.Key()))
+		if err := d.setKeyStream(s, key); err != nil {
 			return err
 		}
+		if s.keyTransformer != nil {
+			d.transformKey(s.keyTransformer, key)
+		}
 		s.skipWhiteSpace()
 		if s.char() == nul {
 			s.read()
@@ -89,11 +129,22 @@ func (d *mapDecoder) decodeStream(s *stream, p uintptr) error {
 		if s.end() {
 			return errUnexpectedEndOfJSON("map", s.totalOffset())
 		}
-		var value interface{}
-		if err := d.setValueStream(s, &value); err != nil {
+		value := unsafe.Pointer(unsafe_New(d.mapType.Elem()))
+		if err := d.valueDecoder.decodeStream(s, uintptr(value)); err != nil {
+			return withMapKeyPath(err, d.mapType, key)
+		}
+		if err := s.allocate(d.entrySize); err != nil {
+			return err
+		}
+		mapassign(d.mapType, mapValue, key, value)
+		if v2 {
+			if err := d.checkDuplicateKey(mapValue, size, key, s.totalOffset()); err != nil {
+				return err
+			}
+		}
+		if err := s.checkDeadline(); err != nil {
 			return err
 		}
-		mapassign(d.mapType, mapValue, unsafe.Pointer(&key), unsafe.Pointer(&value))
 		s.skipWhiteSpace()
 		if s.char() == nul {
 			s.read()
@@ -110,7 +161,8 @@ func (d *mapDecoder) decodeStream(s *stream, p uintptr) error {
 	return nil
 }
 
-func (d *mapDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
+func (d *mapDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
 	cursor = skipWhiteSpace(buf, cursor)
 	buflen := int64(len(buf))
 	if buflen < 2 {
@@ -144,12 +196,19 @@ func (d *mapDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error)
 		cursor++
 		return cursor, nil
 	}
-	for ; cursor < buflen; cursor++ {
-		var key interface{}
-		keyCursor, err := d.setKey(buf, cursor, &key)
+	v2 := ctx.v2
+	for size := 0; cursor < buflen; cursor, size = cursor+1, size+1 {
+		if err := ctx.checkCanceled(); err != nil {
+			return 0, err
+		}
+		key := unsafe.Pointer(unsafe_New(d.mapType.Key()))
+		keyCursor, err := d.setKey(ctx, cursor, key)
 		if err != nil {
 			return 0, err
 		}
+		if ctx.keyTransformer != nil {
+			d.transformKey(ctx.keyTransformer, key)
+		}
 		cursor = keyCursor
 		cursor = skipWhiteSpace(buf, cursor)
 		if buf[cursor] != ':' {
@@ -159,13 +218,18 @@ func (d *mapDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error)
 		if cursor >= buflen {
 			return 0, errUnexpectedEndOfJSON("map", cursor)
 		}
-		var value interface{}
-		valueCursor, err := d.setValue(buf, cursor, &value)
+		value := unsafe.Pointer(unsafe_New(d.mapType.Elem()))
+		valueCursor, err := d.valueDecoder.decode(ctx, cursor, uintptr(value))
 		if err != nil {
 			return 0, err
 		}
 		cursor = valueCursor
-		mapassign(d.mapType, mapValue, unsafe.Pointer(&key), unsafe.Pointer(&value))
+		mapassign(d.mapType, mapValue, key, value)
+		if v2 {
+			if err := d.checkDuplicateKey(mapValue, size, key, cursor); err != nil {
+				return 0, err
+			}
+		}
 		cursor = skipWhiteSpace(buf, valueCursor)
 		if buf[cursor] == '}' {
 			*(*unsafe.Pointer)(unsafe.Pointer(p)) = mapValue
@@ -28,8 +28,8 @@ func (d *numberDecoder) decodeStream(s *stream, p uintptr) error {
 	return nil
 }
 
-func (d *numberDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
-	bytes, c, err := d.floatDecoder.decodeByte(buf, cursor)
+func (d *numberDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	bytes, c, err := d.floatDecoder.decodeByte(ctx, cursor)
 	if err != nil {
 		return 0, err
 	}
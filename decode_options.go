@@ -0,0 +1,37 @@
+package json
+
+// decodeOptions are the options of a Decoder or a Config that the decoders read while decoding,
+// shared by the decoding of streams and of byte slices.
+type decodeOptions struct {
+	useNumber bool
+
+	allowSingleQuotes bool
+	allowUnquotedKeys bool
+	allowControlChars bool
+	allowLeadingPlus  bool
+	strictStrings     bool
+	strictNumbers     bool
+	exponentIntegers  bool
+	scalarStrings     bool
+	disallowNull      bool
+	resetMissing      bool
+	validate          bool // whether the Validate methods of decoded values are called
+	v2                bool // whether the decoders follow the semantics of SetV2Semantics
+
+	keyTransformer func(string) string
+}
+
+// defaultDecodeOptions and v2DecodeOptions are the options of Unmarshal and its variants,
+// without and with SetV2Semantics. They are never changed.
+var (
+	defaultDecodeOptions decodeOptions
+	v2DecodeOptions      = decodeOptions{v2: true}
+)
+
+// packageDecodeOptions returns the options of Unmarshal and its variants.
+func packageDecodeOptions() *decodeOptions {
+	if v2Semantics() {
+		return &v2DecodeOptions
+	}
+	return &defaultDecodeOptions
+}
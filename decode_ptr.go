@@ -21,6 +21,9 @@ func (d *ptrDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
 }
 
 func (d *ptrDecoder) decodeStream(s *stream, p uintptr) error {
+	if err := s.allocate(int64(d.typ.Size())); err != nil {
+		return err
+	}
 	newptr := unsafe_New(d.typ)
 	if err := d.dec.decodeStream(s, newptr); err != nil {
 		return err
@@ -29,9 +32,9 @@ func (d *ptrDecoder) decodeStream(s *stream, p uintptr) error {
 	return nil
 }
 
-func (d *ptrDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
+func (d *ptrDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
 	newptr := unsafe_New(d.typ)
-	c, err := d.dec.decode(buf, cursor, newptr)
+	c, err := d.dec.decode(ctx, cursor, newptr)
 	if err != nil {
 		return 0, err
 	}
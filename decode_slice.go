@@ -1,7 +1,6 @@
 package json
 
 import (
-	"reflect"
 	"sync"
 	"unsafe"
 )
@@ -51,7 +50,7 @@ func (d *sliceDecoder) releaseSlice(p *sliceHeader) {
 }
 
 //go:linkname copySlice reflect.typedslicecopy
-func copySlice(elemType *rtype, dst, src reflect.SliceHeader) int
+func copySlice(elemType *rtype, dst, src sliceHeader) int
 
 //go:linkname newArray reflect.unsafe_NewArray
 func newArray(*rtype, int) unsafe.Pointer
@@ -75,53 +74,73 @@ func (d *sliceDecoder) decodeStream(s *stream, p uintptr) error {
 			s.cursor++
 			s.skipWhiteSpace()
 			if s.char() == ']' {
-				*(*reflect.SliceHeader)(unsafe.Pointer(p)) = reflect.SliceHeader{
-					Data: uintptr(newArray(d.elemType, 0)),
-					Len:  0,
-					Cap:  0,
+				*(*sliceHeader)(unsafe.Pointer(p)) = sliceHeader{
+					data: newArray(d.elemType, 0),
+					len:  0,
+					cap:  0,
 				}
 				s.cursor++
 				return nil
 			}
 			idx := 0
+			skipped := 0 // elements left out by ContinueOnElementError
 			slice := d.newSlice()
 			cap := slice.cap
 			data := slice.data
 			for {
 				if cap <= idx {
-					src := reflect.SliceHeader{Data: uintptr(data), Len: idx, Cap: cap}
+					if err := s.allocate(int64(cap*2) * int64(d.size)); err != nil {
+						slice.cap = cap
+						slice.data = data
+						d.releaseSlice(slice)
+						return err
+					}
+					src := sliceHeader{data: data, len: idx, cap: cap}
 					cap *= 2
 					data = newArray(d.elemType, cap)
-					dst := reflect.SliceHeader{Data: uintptr(data), Len: idx, Cap: cap}
+					dst := sliceHeader{data: data, len: idx, cap: cap}
 					copySlice(d.elemType, dst, src)
 				}
-				if err := d.valueDecoder.decodeStream(s, uintptr(data)+uintptr(idx)*d.size); err != nil {
+				ok, err := s.decodeElement(d.valueDecoder, d.elemType, idx+skipped, uintptr(data)+uintptr(idx)*d.size)
+				if err != nil {
 					return err
 				}
 				s.skipWhiteSpace()
 			RETRY:
 				switch s.char() {
 				case ']':
+					length := idx
+					if ok {
+						length++
+					}
 					slice.cap = cap
-					slice.len = idx + 1
+					slice.len = length
 					slice.data = data
-					dstCap := idx + 1
-					dst := reflect.SliceHeader{
-						Data: uintptr(newArray(d.elemType, dstCap)),
-						Len:  idx + 1,
-						Cap:  dstCap,
+					dstCap := length
+					if err := s.allocate(int64(dstCap) * int64(d.size)); err != nil {
+						d.releaseSlice(slice)
+						return err
 					}
-					copySlice(d.elemType, dst, reflect.SliceHeader{
-						Data: uintptr(slice.data),
-						Len:  slice.len,
-						Cap:  slice.cap,
+					dst := sliceHeader{
+						data: newArray(d.elemType, dstCap),
+						len:  length,
+						cap:  dstCap,
+					}
+					copySlice(d.elemType, dst, sliceHeader{
+						data: slice.data,
+						len:  slice.len,
+						cap:  slice.cap,
 					})
-					*(*reflect.SliceHeader)(unsafe.Pointer(p)) = dst
+					*(*sliceHeader)(unsafe.Pointer(p)) = dst
 					d.releaseSlice(slice)
 					s.cursor++
 					return nil
 				case ',':
-					idx++
+					if ok {
+						idx++
+					} else {
+						skipped++
+					}
 				case nul:
 					if s.read() {
 						goto RETRY
@@ -149,7 +168,8 @@ ERROR:
 	return errUnexpectedEndOfJSON("slice", s.totalOffset())
 }
 
-func (d *sliceDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
+func (d *sliceDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
 	buflen := int64(len(buf))
 	for ; cursor < buflen; cursor++ {
 		switch buf[cursor] {
@@ -175,10 +195,10 @@ func (d *sliceDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error
 			cursor++
 			cursor = skipWhiteSpace(buf, cursor)
 			if buf[cursor] == ']' {
-				*(*reflect.SliceHeader)(unsafe.Pointer(p)) = reflect.SliceHeader{
-					Data: uintptr(newArray(d.elemType, 0)),
-					Len:  0,
-					Cap:  0,
+				*(*sliceHeader)(unsafe.Pointer(p)) = sliceHeader{
+					data: newArray(d.elemType, 0),
+					len:  0,
+					cap:  0,
 				}
 				cursor++
 				return cursor, nil
@@ -188,14 +208,17 @@ func (d *sliceDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error
 			cap := slice.cap
 			data := slice.data
 			for {
+				if err := ctx.checkCanceled(); err != nil {
+					return 0, err
+				}
 				if cap <= idx {
-					src := reflect.SliceHeader{Data: uintptr(data), Len: idx, Cap: cap}
+					src := sliceHeader{data: data, len: idx, cap: cap}
 					cap *= 2
 					data = newArray(d.elemType, cap)
-					dst := reflect.SliceHeader{Data: uintptr(data), Len: idx, Cap: cap}
+					dst := sliceHeader{data: data, len: idx, cap: cap}
 					copySlice(d.elemType, dst, src)
 				}
-				c, err := d.valueDecoder.decode(buf, cursor, uintptr(data)+uintptr(idx)*d.size)
+				c, err := d.valueDecoder.decode(ctx, cursor, uintptr(data)+uintptr(idx)*d.size)
 				if err != nil {
 					return 0, err
 				}
@@ -207,17 +230,17 @@ func (d *sliceDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error
 					slice.len = idx + 1
 					slice.data = data
 					dstCap := idx + 1
-					dst := reflect.SliceHeader{
-						Data: uintptr(newArray(d.elemType, dstCap)),
-						Len:  idx + 1,
-						Cap:  dstCap,
+					dst := sliceHeader{
+						data: newArray(d.elemType, dstCap),
+						len:  idx + 1,
+						cap:  dstCap,
 					}
-					copySlice(d.elemType, dst, reflect.SliceHeader{
-						Data: uintptr(slice.data),
-						Len:  slice.len,
-						Cap:  slice.cap,
+					copySlice(d.elemType, dst, sliceHeader{
+						data: slice.data,
+						len:  slice.len,
+						cap:  slice.cap,
 					})
-					*(*reflect.SliceHeader)(unsafe.Pointer(p)) = dst
+					*(*sliceHeader)(unsafe.Pointer(p)) = dst
 					d.releaseSlice(slice)
 					cursor++
 					return cursor, nil
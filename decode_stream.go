@@ -2,21 +2,57 @@ package json
 
 import (
 	"bytes"
+	"context"
 	"io"
+	"reflect"
+	"time"
+	"unsafe"
 )
 
 const (
 	readChunkSize = 512
+
+	// deadlineCheckInterval is the number of elements and members decoded between
+	// checks of the deadline and of the context.
+	deadlineCheckInterval = 256
 )
 
 type stream struct {
-	buf       []byte
-	length    int64
-	r         io.Reader
-	offset    int64
-	cursor    int64
-	allRead   bool
-	useNumber bool
+	buf     []byte
+	length  int64
+	r       io.Reader
+	offset  int64
+	cursor  int64
+	allRead bool
+
+	decodeOptions
+
+	ctx    context.Context
+	ctxErr error // error of ctx or the timeout that interrupted the decoding
+
+	timeout        time.Duration
+	deadline       time.Time // zero without a timeout
+	deadlineChecks int
+
+	memoryBudget int64 // zero means unlimited
+	allocated    int64
+
+	progressFn       func(DecodeProgress)
+	progressInterval int64
+	progressTotal    int64
+	progressNext     int64 // bytes read at which progressFn is called next
+
+	typeResolvers      map[reflect.Type]TypeResolver
+	fieldTypeResolvers map[resolverField]TypeResolver
+
+	continueOnElementError bool
+	elementErrors          []*ElementError
+	retainBuffer           int // reset keeps the buffer while positive
+
+	reuseContainers bool
+	reuseFrames     []*reuseFrame // indexed by the depth of the reused objects being decoded
+	reuseDepth      int
+	internedKeys    map[string]interface{}
 }
 
 func (s *stream) buffered() io.Reader {
@@ -27,6 +63,19 @@ func (s *stream) totalOffset() int64 {
 	return s.offset + s.cursor
 }
 
+// allocate records n bytes allocated for the decoded value
+// and returns an error once the memory budget is exceeded.
+func (s *stream) allocate(n int64) error {
+	if s.memoryBudget == 0 {
+		return nil
+	}
+	s.allocated += n
+	if s.allocated > s.memoryBudget {
+		return &MemoryBudgetError{Budget: s.memoryBudget, Offset: s.totalOffset()}
+	}
+	return nil
+}
+
 func (s *stream) prevChar() byte {
 	return s.buf[s.cursor-1]
 }
@@ -49,15 +98,67 @@ func (s *stream) progressN(n int64) bool {
 }
 
 func (s *stream) reset() {
+	if s.retainBuffer > 0 {
+		return
+	}
+	s.offset += s.cursor
 	s.buf = s.buf[s.cursor:]
 	s.length -= s.cursor
 	s.cursor = 0
 }
 
+// decodeElement decodes the array element at index idx into p.
+// With continueOnElementError, an element that fails to decode but is well-formed
+// is skipped instead: its error is recorded, p is cleared and ok is false.
+func (s *stream) decodeElement(dec decoder, typ *rtype, idx int, p uintptr) (ok bool, err error) {
+	if err := s.checkDeadline(); err != nil {
+		return false, err
+	}
+	if !s.continueOnElementError {
+		return true, withIndexPath(dec.decodeStream(s, p), idx)
+	}
+	s.skipWhiteSpace()
+	start := s.cursor
+	s.retainBuffer++
+	err = dec.decodeStream(s, p)
+	s.retainBuffer--
+	if err == nil {
+		return true, nil
+	}
+	if _, budget := err.(*MemoryBudgetError); budget || s.ctxErr != nil {
+		return false, err
+	}
+	s.cursor = start
+	switch s.char() {
+	case ',', ':', ']', '}':
+		return false, err // no element to skip
+	}
+	if skipErr := s.skipValue(); skipErr != nil {
+		return false, err
+	}
+	s.elementErrors = append(s.elementErrors, &ElementError{
+		Index:  idx,
+		Offset: s.offset + start,
+		Err:    err,
+	})
+	t := rtype2type(typ)
+	reflect.NewAt(t, unsafe.Pointer(p)).Elem().Set(reflect.Zero(t))
+	return false, nil
+}
+
 func (s *stream) read() bool {
 	if s.allRead {
 		return false
 	}
+	if s.ctx != nil {
+		if err := s.ctx.Err(); err != nil {
+			s.ctxErr = err
+			return false
+		}
+	}
+	if !s.deadline.IsZero() && s.expired() {
+		return false
+	}
 	buf := make([]byte, readChunkSize)
 	n, err := s.r.Read(buf)
 	if err != nil && err != io.EOF {
@@ -82,13 +183,53 @@ func (s *stream) read() bool {
 		s.buf = buf
 		s.length = totalSize - 1
 	}
-	s.offset += s.cursor
+	if s.progressFn != nil {
+		s.reportProgress()
+	}
 	if n == 0 {
 		return false
 	}
 	return true
 }
 
+// checkDeadline returns an error once the deadline set for the timeout has passed
+// or the context of DecodeContext is done.
+// The clock and the context are only checked every deadlineCheckInterval calls.
+func (s *stream) checkDeadline() error {
+	if s.deadline.IsZero() && s.ctx == nil {
+		return nil
+	}
+	s.deadlineChecks++
+	if s.deadlineChecks%deadlineCheckInterval == 0 && s.ctxErr == nil {
+		if s.ctx != nil {
+			s.ctxErr = s.ctx.Err()
+		}
+		if !s.deadline.IsZero() {
+			s.expired()
+		}
+	}
+	return s.ctxErr
+}
+
+// expired reports whether the deadline has passed and records the timeout error if so.
+func (s *stream) expired() bool {
+	if s.ctxErr == nil && time.Now().After(s.deadline) {
+		s.ctxErr = &DecodeTimeoutError{Limit: s.timeout, Offset: s.totalOffset()}
+	}
+	return s.ctxErr != nil
+}
+
+// reportProgress calls progressFn if another interval of the input has been read
+// since the last call, or if the whole input has been read.
+func (s *stream) reportProgress() {
+	read := s.offset + s.length
+	if read < s.progressNext && !(s.allRead && read > s.progressNext-s.progressInterval) {
+		return
+	}
+	s.progressNext = (read/s.progressInterval + 1) * s.progressInterval
+	s.progressFn(DecodeProgress{Offset: read, Total: s.progressTotal})
+}
+
 func (s *stream) skipWhiteSpace() {
 LOOP:
 	c := s.char()
@@ -102,6 +243,86 @@ LOOP:
 	}
 }
 
+// skipNumberTail skips the fraction and exponent following the integer part of a number,
+// so that the integer decoders see the whole literal.
+func (s *stream) skipNumberTail() {
+	for {
+		switch s.char() {
+		case '.', 'e', 'E', '+', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			s.cursor++
+			continue
+		case nul:
+			if s.read() {
+				continue
+			}
+		}
+		return
+	}
+}
+
+// skipSubtree is the stream version of skipSubtree.
+func (s *stream) skipSubtree() error {
+	s.skipWhiteSpace()
+	if c := s.char(); c != '{' && c != '[' {
+		return s.skipValue()
+	}
+	var stack [32]byte
+	closers := stack[:0] // the closing brackets of the open objects and arrays
+	for {
+		for !isStructural[s.char()] {
+			s.cursor++
+		}
+		switch c := s.char(); c {
+		case nul:
+			if s.read() {
+				continue
+			}
+			return errUnexpectedEndOfJSON("value of object", s.totalOffset())
+		case '{':
+			closers = append(closers, '}')
+		case '[':
+			closers = append(closers, ']')
+		case '}', ']':
+			if expected := closers[len(closers)-1]; c != expected {
+				return errMismatchedBracket(c, expected, s.totalOffset())
+			}
+			closers = closers[:len(closers)-1]
+			if len(closers) == 0 {
+				s.cursor++
+				return nil
+			}
+		case '"':
+			if err := s.skipString(); err != nil {
+				return err
+			}
+			continue
+		}
+		s.cursor++
+	}
+}
+
+// skipString skips the string starting at the cursor, including its quotes.
+func (s *stream) skipString() error {
+	for {
+		s.cursor++
+		switch s.char() {
+		case '\\':
+			s.cursor++
+			if s.char() == nul && !s.read() {
+				return errUnexpectedEndOfJSON("value of string", s.totalOffset())
+			}
+		case '"':
+			s.cursor++
+			return nil
+		case nul:
+			if !s.read() {
+				return errUnexpectedEndOfJSON("value of string", s.totalOffset())
+			}
+			s.cursor-- // for retry current character
+		}
+	}
+}
+
 func (s *stream) skipValue() error {
 	s.skipWhiteSpace()
 	braceCount := 0
@@ -122,8 +343,16 @@ func (s *stream) skipValue() error {
 			if braceCount == -1 && bracketCount == 0 {
 				return nil
 			}
+			if braceCount == 0 && bracketCount == 0 {
+				s.cursor++
+				return nil
+			}
 		case ']':
 			bracketCount--
+			if braceCount == 0 && bracketCount == 0 {
+				s.cursor++
+				return nil
+			}
 		case ',':
 			if bracketCount == 0 && braceCount == 0 {
 				return nil
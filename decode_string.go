@@ -1,6 +1,9 @@
 package json
 
 import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -14,16 +17,33 @@ func newStringDecoder() *stringDecoder {
 func (d *stringDecoder) setDisallowUnknownFields(_ bool) {}
 
 func (d *stringDecoder) decodeStream(s *stream, p uintptr) error {
+	bytes, err := d.decodeStreamValueByte(s)
+	if err != nil {
+		return err
+	}
+	return storeStreamString(s, bytes, p)
+}
+
+// decodeStreamKey is decodeStream for the keys of maps, which are not numbers or
+// booleans with AllowScalarStrings.
+func (d *stringDecoder) decodeStreamKey(s *stream, p uintptr) error {
 	bytes, err := d.decodeStreamByte(s)
 	if err != nil {
 		return err
 	}
+	return storeStreamString(s, bytes, p)
+}
+
+func storeStreamString(s *stream, bytes []byte, p uintptr) error {
+	if err := s.allocate(int64(len(bytes))); err != nil {
+		return err
+	}
 	*(*string)(unsafe.Pointer(p)) = *(*string)(unsafe.Pointer(&bytes))
 	return nil
 }
 
-func (d *stringDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
-	bytes, c, err := d.decodeByte(buf, cursor)
+func (d *stringDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	bytes, c, err := d.decodeValueByte(ctx, cursor)
 	if err != nil {
 		return 0, err
 	}
@@ -33,22 +53,32 @@ func (d *stringDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, erro
 }
 
 func stringBytes(s *stream) ([]byte, error) {
+	quote := s.char()
 	s.cursor++
 	start := s.cursor
 	for {
-		switch s.char() {
-		case '\\':
+		c := s.char()
+		switch {
+		case c == '\\':
 			s.cursor++
-		case '"':
-			literal := s.buf[start:s.cursor]
+		case c == quote:
+			raw := s.buf[start:s.cursor]
+			if s.strictStrings {
+				if err := checkStrictString(raw, s.offset+start, s.allowSingleQuotes); err != nil {
+					return nil, err
+				}
+			}
+			literal := unescapeString(raw)
 			s.cursor++
 			s.reset()
 			return literal, nil
-		case nul:
+		case c == nul:
 			if s.read() {
 				continue
 			}
 			goto ERROR
+		case c < 0x20 && !s.allowControlChars:
+			return nil, errInvalidCharacter(c, "string", s.totalOffset())
 		}
 		s.cursor++
 	}
@@ -56,6 +86,50 @@ ERROR:
 	return nil, errUnexpectedEndOfJSON("string", s.totalOffset())
 }
 
+var (
+	unquotedKeyStartTable = [256]bool{'_': true, '$': true}
+	unquotedKeyTable      = [256]bool{'_': true, '$': true}
+)
+
+func init() {
+	for c := 'a'; c <= 'z'; c++ {
+		unquotedKeyStartTable[c] = true
+		unquotedKeyTable[c] = true
+	}
+	for c := 'A'; c <= 'Z'; c++ {
+		unquotedKeyStartTable[c] = true
+		unquotedKeyTable[c] = true
+	}
+	for c := '0'; c <= '9'; c++ {
+		unquotedKeyTable[c] = true
+	}
+}
+
+// isUnquotedKey reports whether the stream is positioned at a bare object key
+// that should be accepted because of AllowUnquotedKeys.
+func isUnquotedKey(s *stream) bool {
+	return s.allowUnquotedKeys && unquotedKeyStartTable[s.char()]
+}
+
+func unquotedKeyBytes(s *stream) []byte {
+	start := s.cursor
+	for {
+		s.cursor++
+		if unquotedKeyTable[s.char()] {
+			continue
+		} else if s.char() == nul {
+			if s.read() {
+				s.cursor-- // for retry current character
+				continue
+			}
+		}
+		break
+	}
+	literal := s.buf[start:s.cursor]
+	s.reset()
+	return literal
+}
+
 func nullBytes(s *stream) error {
 	if s.cursor+3 >= s.length {
 		if !s.read() {
@@ -86,6 +160,11 @@ func (d *stringDecoder) decodeStreamByte(s *stream) ([]byte, error) {
 			continue
 		case '"':
 			return stringBytes(s)
+		case '\'':
+			if !s.allowSingleQuotes {
+				break
+			}
+			return stringBytes(s)
 		case 'n':
 			if err := nullBytes(s); err != nil {
 				return nil, err
@@ -101,28 +180,126 @@ func (d *stringDecoder) decodeStreamByte(s *stream) ([]byte, error) {
 	return nil, errNotAtBeginningOfValue(s.totalOffset())
 }
 
-func (d *stringDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, error) {
+// decodeStreamValueByte is decodeStreamByte for values rather than object keys,
+// which may also be numbers and booleans with AllowScalarStrings.
+func (d *stringDecoder) decodeStreamValueByte(s *stream) ([]byte, error) {
+	if !s.scalarStrings {
+		return d.decodeStreamByte(s)
+	}
+	s.skipWhiteSpace()
+	switch s.char() {
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		literal := floatBytes(s)
+		if !validNumber(literal) {
+			return nil, errInvalidNumber(literal, s.totalOffset()-int64(len(literal)))
+		}
+		s.reset()
+		return literal, nil
+	case 't':
+		if err := trueBytes(s); err != nil {
+			return nil, err
+		}
+		return []byte("true"), nil
+	case 'f':
+		if err := falseBytes(s); err != nil {
+			return nil, err
+		}
+		return []byte("false"), nil
+	}
+	return d.decodeStreamByte(s)
+}
+
+func (d *stringDecoder) decodeStreamKeyByte(s *stream) ([]byte, error) {
+	s.skipWhiteSpace()
+	if isUnquotedKey(s) {
+		return unquotedKeyBytes(s), nil
+	}
+	return d.decodeStreamByte(s)
+}
+
+// decodeKey is decode for the keys of maps, which are not numbers or booleans
+// with AllowScalarStrings but may be bare identifiers with AllowUnquotedKeys.
+func (d *stringDecoder) decodeKey(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	bytes, c, err := d.decodeKeyByte(ctx, cursor)
+	if err != nil {
+		return 0, err
+	}
+	*(*string)(unsafe.Pointer(p)) = *(*string)(unsafe.Pointer(&bytes))
+	return c, nil
+}
+
+// decodeValueByte is decodeByte for values rather than object keys,
+// which may also be numbers and booleans with AllowScalarStrings.
+func (d *stringDecoder) decodeValueByte(ctx *runtimeContext, cursor int64) ([]byte, int64, error) {
+	if !ctx.scalarStrings {
+		return d.decodeByte(ctx, cursor)
+	}
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	switch buf[cursor] {
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		start := cursor
+		for cursor++; floatTable[buf[cursor]]; cursor++ {
+		}
+		literal := buf[start:cursor]
+		if !validNumber(literal) {
+			return nil, 0, errInvalidNumber(literal, start)
+		}
+		return literal, cursor, nil
+	case 't':
+		c, err := boolLiteralByte(buf, cursor, "true")
+		if err != nil {
+			return nil, 0, err
+		}
+		return []byte("true"), c, nil
+	case 'f':
+		c, err := boolLiteralByte(buf, cursor, "false")
+		if err != nil {
+			return nil, 0, err
+		}
+		return []byte("false"), c, nil
+	}
+	return d.decodeByte(ctx, cursor)
+}
+
+// boolLiteralByte checks that the literal lit is at the cursor and returns the cursor after it.
+func boolLiteralByte(buf []byte, cursor int64, lit string) (int64, error) {
+	if cursor+int64(len(lit)) >= int64(len(buf)) {
+		return 0, errUnexpectedEndOfJSON("bool("+lit+")", cursor)
+	}
+	for i := 1; i < len(lit); i++ {
+		if buf[cursor+int64(i)] != lit[i] {
+			return 0, errInvalidCharacter(buf[cursor+int64(i)], "bool("+lit+")", cursor)
+		}
+	}
+	return cursor + int64(len(lit)), nil
+}
+
+func (d *stringDecoder) decodeKeyByte(ctx *runtimeContext, cursor int64) ([]byte, int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	if ctx.allowUnquotedKeys && unquotedKeyStartTable[buf[cursor]] {
+		start := cursor
+		for cursor++; unquotedKeyTable[buf[cursor]]; cursor++ {
+		}
+		return buf[start:cursor], cursor, nil
+	}
+	return d.decodeByte(ctx, cursor)
+}
+
+func (d *stringDecoder) decodeByte(ctx *runtimeContext, cursor int64) ([]byte, int64, error) {
+	buf := ctx.buf
 	for {
 		switch buf[cursor] {
 		case ' ', '\n', '\t', '\r':
 			cursor++
-		case '"':
-			cursor++
-			start := cursor
-			for {
-				switch buf[cursor] {
-				case '\\':
-					cursor++
-				case '"':
-					literal := buf[start:cursor]
-					cursor++
-					return literal, cursor, nil
-				case nul:
-					return nil, 0, errUnexpectedEndOfJSON("string", cursor)
-				}
-				cursor++
+		case '\'':
+			if !ctx.allowSingleQuotes {
+				goto ERROR
 			}
-			return nil, 0, errUnexpectedEndOfJSON("string", cursor)
+			return stringLiteralByte(ctx, cursor)
+		case '"':
+			return stringLiteralByte(ctx, cursor)
 		case 'n':
 			buflen := int64(len(buf))
 			if cursor+3 >= buflen {
@@ -146,3 +323,160 @@ func (d *stringDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, err
 ERROR:
 	return nil, 0, errNotAtBeginningOfValue(cursor)
 }
+
+// stringLiteralByte decodes the string literal at the cursor, quoted by the character there.
+func stringLiteralByte(ctx *runtimeContext, cursor int64) ([]byte, int64, error) {
+	buf := ctx.buf
+	quote := buf[cursor]
+	cursor++
+	start := cursor
+	for {
+		switch c := buf[cursor]; {
+		case c == '\\':
+			cursor++
+		case c == quote:
+			raw := buf[start:cursor]
+			if ctx.strictStrings {
+				if err := checkStrictString(raw, start, ctx.allowSingleQuotes); err != nil {
+					return nil, 0, err
+				}
+			}
+			cursor++
+			return unescapeString(raw), cursor, nil
+		case c == nul:
+			return nil, 0, errUnexpectedEndOfJSON("string", cursor)
+		case c < 0x20 && !ctx.allowControlChars:
+			return nil, 0, errInvalidCharacter(c, "string", cursor)
+		}
+		cursor++
+	}
+}
+
+// unescapeString returns the value of the string literal b, without its quotes.
+// b itself is returned if it has no escape sequences; otherwise the value is built in a new slice.
+// Unpaired surrogate escapes become U+FFFD and unknown escapes are kept as is.
+func unescapeString(b []byte) []byte {
+	i := bytes.IndexByte(b, '\\')
+	if i < 0 {
+		return b
+	}
+	dst := make([]byte, i, len(b))
+	copy(dst, b)
+	for i < len(b) {
+		c := b[i]
+		if c != '\\' || i+1 == len(b) {
+			dst = append(dst, c)
+			i++
+			continue
+		}
+		i++
+		switch c := b[i]; c {
+		case 'b':
+			dst = append(dst, '\b')
+		case 'f':
+			dst = append(dst, '\f')
+		case 'n':
+			dst = append(dst, '\n')
+		case 'r':
+			dst = append(dst, '\r')
+		case 't':
+			dst = append(dst, '\t')
+		case '"', '\'', '\\', '/':
+			dst = append(dst, c)
+		case 'u':
+			r, ok := hexRune(b[i+1:])
+			if !ok {
+				dst = append(dst, '\\', c)
+				break
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				r2 := utf8.RuneError
+				if i+2 < len(b) && b[i+1] == '\\' && b[i+2] == 'u' {
+					r2, _ = hexRune(b[i+3:])
+				}
+				if pair := utf16.DecodeRune(r, r2); pair != utf8.RuneError {
+					r = pair
+					i += 6
+				} else {
+					r = utf8.RuneError
+				}
+			}
+			dst = append(dst, string(r)...)
+		default:
+			dst = append(dst, '\\', c)
+		}
+		i++
+	}
+	return dst
+}
+
+// checkStrictString returns the error of the first control character, invalid escape
+// sequence or unpaired surrogate escape of the string literal b, without its quotes,
+// which starts at offset in the input. \' is valid in inputs allowing single quotes.
+func checkStrictString(b []byte, offset int64, allowSingleQuotes bool) error {
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if c < 0x20 {
+			return errInvalidCharacter(c, "string", offset+int64(i))
+		}
+		if c != '\\' {
+			continue
+		}
+		start := i
+		i++
+		switch b[i] {
+		case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+		case '\'':
+			if !allowSingleQuotes {
+				return errInvalidEscape(b[start:i+1], offset+int64(start))
+			}
+		case 'u':
+			r, ok := hexRune(b[i+1:])
+			if !ok {
+				end := i + 5
+				if end > len(b) {
+					end = len(b)
+				}
+				return errInvalidEscape(b[start:end], offset+int64(start))
+			}
+			i += 4
+			if !utf16.IsSurrogate(r) {
+				break
+			}
+			r2 := utf8.RuneError
+			if i+2 < len(b) && b[i+1] == '\\' && b[i+2] == 'u' {
+				r2, _ = hexRune(b[i+3:])
+			}
+			if utf16.DecodeRune(r, r2) == utf8.RuneError {
+				return errUnpairedSurrogate(b[start:i+1], offset+int64(start))
+			}
+			i += 6
+		default:
+			return errInvalidEscape(b[start:i+1], offset+int64(start))
+		}
+	}
+	return nil
+}
+
+// hexRune returns the rune of the four hexadecimal digits at the start of b.
+func hexRune(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		case 'A' <= c && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, true
+}
@@ -1,19 +1,60 @@
 package json
 
 import (
-	"fmt"
+	"reflect"
 	"unsafe"
 )
 
 type structFieldSet struct {
-	dec    decoder
-	offset uintptr
+	dec      decoder
+	offset   uintptr
+	name     string // the name of the field in JSON, for errors
+	typ      *rtype
+	notNull  bool // set by the "notnull" tag option
+	nullable bool // whether null is a value of typ, which DisallowNull does not reject
+	index    int  // the index of the field set in the fields of its struct decoder
+}
+
+// isNotNullField reports whether the field tag options include "notnull".
+func isNotNullField(opts []string) bool {
+	for _, opt := range opts[1:] {
+		if opt == "notnull" {
+			return true
+		}
+	}
+	return false
+}
+
+// isNullable reports whether null is a value of typ: the nil of pointers, interfaces, maps
+// and slices, or whatever the UnmarshalJSON method of a type makes of it.
+func isNullable(typ *rtype) bool {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return true
+	}
+	ptrType := reflect.PtrTo(rtype2type(typ))
+	return ptrType.Implements(unmarshalJSONType) || ptrType.Implements(unmarshalFromType)
+}
+
+// rejectsNull reports whether a null value of field is an error, with disallowNull
+// for the DisallowNull option of the decoder.
+func (f *structFieldSet) rejectsNull(disallowNull bool) bool {
+	return f.notNull || disallowNull && !f.nullable
+}
+
+func errNullField(typ *rtype, cursor int64) *UnmarshalTypeError {
+	return &UnmarshalTypeError{Value: "null", Type: rtype2type(typ), Offset: cursor}
 }
 
 type structDecoder struct {
 	fieldMap              map[string]*structFieldSet
+	fields                []*structFieldSet // the distinct field sets of fieldMap, set by indexFields
 	keyDecoder            *stringDecoder
 	disallowUnknownFields bool
+	structName            string
+	inline                *inlineField // the map collecting the members matching no field, or nil
+	presence              *presenceField
+	union                 *union // the union the struct is decoded as a variant of, or nil
 }
 
 func newStructDecoder(fieldMap map[string]*structFieldSet) *structDecoder {
@@ -23,11 +64,47 @@ func newStructDecoder(fieldMap map[string]*structFieldSet) *structDecoder {
 	}
 }
 
+// indexFields lists the field sets of fieldMap, which has an entry for each of their names,
+// in fields, for ResetMissingFields. It must be called once fieldMap is complete.
+func (d *structDecoder) indexFields() {
+	indexed := map[*structFieldSet]bool{}
+	for _, field := range d.fieldMap {
+		if indexed[field] {
+			continue
+		}
+		indexed[field] = true
+		field.index = len(d.fields)
+		d.fields = append(d.fields, field)
+		if dec, ok := field.dec.(*pathDecoder); ok {
+			dec.indexFields()
+		}
+	}
+}
+
+// resetMissingFields sets the fields of the struct at p that are not seen to their zero value.
+// The fields reached through a path are reset unless their members were decoded.
+func (d *structDecoder) resetMissingFields(p uintptr, seen []bool) {
+	for i, field := range d.fields {
+		if seen != nil && seen[i] {
+			continue
+		}
+		if dec, ok := field.dec.(*pathDecoder); ok {
+			dec.resetMissingFields(p, nil)
+			continue
+		}
+		t := rtype2type(field.typ)
+		reflect.NewAt(t, unsafe.Pointer(p+field.offset)).Elem().Set(reflect.Zero(t))
+	}
+}
+
 func (d *structDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
 	d.disallowUnknownFields = disallowUnknownFields
 	for _, field := range d.fieldMap {
 		field.dec.setDisallowUnknownFields(disallowUnknownFields)
 	}
+	if d.inline != nil {
+		d.inline.setDisallowUnknownFields(disallowUnknownFields)
+	}
 }
 
 func (d *structDecoder) decodeStream(s *stream, p uintptr) error {
@@ -38,13 +115,39 @@ func (d *structDecoder) decodeStream(s *stream, p uintptr) error {
 	if s.char() != '{' {
 		return errNotAtBeginningOfValue(s.totalOffset())
 	}
+	var present []uint64
+	if d.presence != nil {
+		present = d.presence.reset(p)
+	}
 	s.cursor++
+	s.skipWhiteSpace()
+	if s.char() == nul {
+		s.read()
+	}
+	if s.char() == '}' {
+		s.cursor++
+		if s.resetMissing {
+			d.resetMissingFields(p, nil)
+		}
+		return nil
+	}
+	var seen []bool
+	if s.resetMissing {
+		seen = make([]bool, len(d.fields))
+	}
+	v2 := s.v2
+	var names objectNames
 	for {
 		s.reset()
-		key, err := d.keyDecoder.decodeStreamByte(s)
+		s.skipWhiteSpace()
+		keyOffset := s.totalOffset()
+		key, err := d.keyDecoder.decodeStreamKeyByte(s)
 		if err != nil {
 			return err
 		}
+		if v2 && !names.add(key) {
+			return errDuplicateName(string(key), s.totalOffset())
+		}
 		s.skipWhiteSpace()
 		if s.char() == nul {
 			s.read()
@@ -60,18 +163,38 @@ func (d *structDecoder) decodeStream(s *stream, p uintptr) error {
 			return errExpected("object value after colon", s.totalOffset())
 		}
 		k := *(*string)(unsafe.Pointer(&key))
+		if s.keyTransformer != nil {
+			k = s.keyTransformer(k)
+		}
 		field, exists := d.fieldMap[k]
 		if exists {
+			if seen != nil {
+				seen[field.index] = true
+			}
+			setPresent(present, field)
+			if field.rejectsNull(s.disallowNull) {
+				s.skipWhiteSpace()
+				if s.char() == 'n' {
+					return d.fieldError(errNullField(field.typ, s.totalOffset()), field)
+				}
+			}
 			if err := field.dec.decodeStream(s, p+field.offset); err != nil {
-				return err
+				return d.fieldError(err, field)
 			}
-		} else if d.disallowUnknownFields {
-			return fmt.Errorf("json: unknown field %q", k)
+		} else if d.inline != nil {
+			if err := d.inline.decodeStream(s, p, string(key)); err != nil {
+				return withFieldPath(err, string(key))
+			}
+		} else if d.disallowUnknownFields && !d.isDiscriminator(k, v2) {
+			return &UnknownFieldError{Field: string(key), Offset: keyOffset}
 		} else {
-			if err := s.skipValue(); err != nil {
+			if err := s.skipSubtree(); err != nil {
 				return err
 			}
 		}
+		if err := s.checkDeadline(); err != nil {
+			return err
+		}
 		s.skipWhiteSpace()
 		if s.char() == nul {
 			s.read()
@@ -79,6 +202,9 @@ func (d *structDecoder) decodeStream(s *stream, p uintptr) error {
 		c := s.char()
 		if c == '}' {
 			s.cursor++
+			if seen != nil {
+				d.resetMissingFields(p, seen)
+			}
 			return nil
 		}
 		if c != ',' {
@@ -89,7 +215,8 @@ func (d *structDecoder) decodeStream(s *stream, p uintptr) error {
 	return nil
 }
 
-func (d *structDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
+func (d *structDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
 	buflen := int64(len(buf))
 	cursor = skipWhiteSpace(buf, cursor)
 	if buf[cursor] != '{' {
@@ -98,12 +225,35 @@ func (d *structDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, erro
 	if buflen < 2 {
 		return 0, errUnexpectedEndOfJSON("object", cursor)
 	}
+	var present []uint64
+	if d.presence != nil {
+		present = d.presence.reset(p)
+	}
 	cursor++
+	if c := skipWhiteSpace(buf, cursor); buf[c] == '}' {
+		if ctx.resetMissing {
+			d.resetMissingFields(p, nil)
+		}
+		return c + 1, nil
+	}
+	var seen []bool
+	if ctx.resetMissing {
+		seen = make([]bool, len(d.fields))
+	}
+	v2 := ctx.v2
+	var names objectNames
 	for ; cursor < buflen; cursor++ {
-		key, c, err := d.keyDecoder.decodeByte(buf, cursor)
+		if err := ctx.checkCanceled(); err != nil {
+			return 0, err
+		}
+		keyOffset := skipWhiteSpace(buf, cursor)
+		key, c, err := d.keyDecoder.decodeKeyByte(ctx, cursor)
 		if err != nil {
 			return 0, err
 		}
+		if v2 && !names.add(key) {
+			return 0, errDuplicateName(string(key), cursor)
+		}
 		cursor = c
 		cursor = skipWhiteSpace(buf, cursor)
 		if buf[cursor] != ':' {
@@ -114,17 +264,36 @@ func (d *structDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, erro
 			return 0, errExpected("object value after colon", cursor)
 		}
 		k := *(*string)(unsafe.Pointer(&key))
+		if ctx.keyTransformer != nil {
+			k = ctx.keyTransformer(k)
+		}
 		field, exists := d.fieldMap[k]
 		if exists {
-			c, err := field.dec.decode(buf, cursor, p+field.offset)
+			if seen != nil {
+				seen[field.index] = true
+			}
+			setPresent(present, field)
+			if field.rejectsNull(ctx.disallowNull) {
+				cursor = skipWhiteSpace(buf, cursor)
+				if buf[cursor] == 'n' {
+					return 0, d.fieldError(errNullField(field.typ, cursor), field)
+				}
+			}
+			c, err := field.dec.decode(ctx, cursor, p+field.offset)
 			if err != nil {
-				return 0, err
+				return 0, d.fieldError(err, field)
+			}
+			cursor = c
+		} else if d.inline != nil {
+			c, err := d.inline.decode(ctx, cursor, p, string(key))
+			if err != nil {
+				return 0, withFieldPath(err, string(key))
 			}
 			cursor = c
-		} else if d.disallowUnknownFields {
-			return 0, fmt.Errorf("json: unknown field %q", k)
+		} else if d.disallowUnknownFields && !d.isDiscriminator(k, v2) {
+			return 0, &UnknownFieldError{Field: string(key), Offset: keyOffset}
 		} else {
-			c, err := skipValue(buf, cursor)
+			c, err := skipSubtree(buf, cursor)
 			if err != nil {
 				return 0, err
 			}
@@ -133,6 +302,9 @@ func (d *structDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, erro
 		cursor = skipWhiteSpace(buf, cursor)
 		if buf[cursor] == '}' {
 			cursor++
+			if seen != nil {
+				d.resetMissingFields(p, seen)
+			}
 			return cursor, nil
 		}
 		if buf[cursor] != ',' {
@@ -141,3 +313,28 @@ func (d *structDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, erro
 	}
 	return cursor, nil
 }
+
+// isDiscriminator reports whether key is the discriminator member of the union the struct
+// is decoded as a variant of, which is no unknown field.
+func (d *structDecoder) isDiscriminator(key string, v2 bool) bool {
+	return d.union != nil && d.union.isKey(key, v2)
+}
+
+// fieldError adds the struct and the path of field to a type error of its value,
+// as encoding/json does: the struct is the outermost one, and the path leads from it.
+func (d *structDecoder) fieldError(err error, field *structFieldSet) error {
+	if _, ok := err.(*ValidationError); ok {
+		return withFieldPath(err, field.name)
+	}
+	typeErr, ok := err.(*UnmarshalTypeError)
+	if !ok || field.name == "" {
+		return err
+	}
+	typeErr.Struct = d.structName
+	if typeErr.Field == "" {
+		typeErr.Field = field.name
+	} else {
+		typeErr.Field = field.name + "." + typeErr.Field
+	}
+	return typeErr
+}
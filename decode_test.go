@@ -1,10 +1,21 @@
 package json_test
 
 import (
+	"bytes"
+	"encoding/base64"
+	stdjson "encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
 	"reflect"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode/utf16"
 
 	"github.com/goccy/go-json"
 )
@@ -153,6 +164,12 @@ func Test_Decoder(t *testing.T) {
 		assertEq(t, "struct.D.AA", 2, v.D.AA)
 		assertEq(t, "struct.D.BB", "world", v.D.BB)
 		assertEq(t, "struct.D.CC", true, v.D.CC)
+		t.Run("empty object", func(t *testing.T) {
+			var v []T
+			assertErr(t, json.Unmarshal([]byte(`[{"aa":1}, { }]`), &v))
+			assertEq(t, "len", 2, len(v))
+			assertEq(t, "empty", T{}, v[1])
+		})
 		t.Run("struct.field null", func(t *testing.T) {
 			var v struct {
 				A string
@@ -240,6 +257,104 @@ func Test_Decoder_DisallowUnknownFields(t *testing.T) {
 	}
 }
 
+func Test_UnknownFieldError(t *testing.T) {
+	type T struct {
+		A int `json:"a"`
+		N struct {
+			X int
+		} `json:"n"`
+	}
+	const src = `[{"a": 1}, {"n": {"X": 1, "y": 2}}]`
+	offset := int64(strings.Index(src, `"y"`))
+	check := func(t *testing.T, err error) {
+		t.Helper()
+		e, ok := err.(*json.UnknownFieldError)
+		if !ok {
+			t.Fatalf("expected *json.UnknownFieldError but got %T: %v", err, err)
+		}
+		assertEq(t, "field", "y", e.Field)
+		assertEq(t, "offset", offset, e.Offset)
+	}
+	t.Run("stream", func(t *testing.T) {
+		var v []T
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.DisallowUnknownFields()
+		check(t, dec.Decode(&v))
+	})
+	t.Run("bytes", func(t *testing.T) {
+		var v []T
+		check(t, json.Config{DisallowUnknownFields: true}.Freeze().Unmarshal([]byte(src), &v))
+	})
+}
+
+func Test_Decoder_InputOffset(t *testing.T) {
+	var src strings.Builder
+	var ends []int64
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&src, "{\"id\": %d, \"name\": %q}\n", i, strings.Repeat("x", i%40))
+		ends = append(ends, int64(src.Len()-1))
+	}
+	dec := json.NewDecoder(strings.NewReader(src.String()))
+	assertEq(t, "start", int64(0), dec.InputOffset())
+	for i, end := range ends {
+		var v map[string]interface{}
+		assertErr(t, dec.Decode(&v))
+		if dec.InputOffset() != end {
+			t.Fatalf("value %d: expected offset %d but got %d", i, end, dec.InputOffset())
+		}
+	}
+	dec = json.NewDecoder(strings.NewReader(`[1, "ab"]`))
+	var offsets []int64
+	for {
+		if _, err := dec.Token(); err != nil {
+			break
+		}
+		offsets = append(offsets, dec.InputOffset())
+	}
+	assertEq(t, "tokens", "[1 2 8 9]", fmt.Sprint(offsets))
+}
+
+func Test_Decoder_DisallowUnknownFieldsVariants(t *testing.T) {
+	type T struct {
+		A int `json:"a"`
+		B []struct {
+			C int `json:"c"`
+		} `json:"b"`
+	}
+	src := `{"a":1,"b":[{"c":2,"d":3}]}`
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for i := 0; i < 100; i++ {
+		strict := i%2 == 0
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v T
+			dec := json.NewDecoder(strings.NewReader(src))
+			if strict {
+				dec.DisallowUnknownFields()
+			}
+			err := dec.Decode(&v)
+			switch {
+			case strict && err == nil:
+				errs <- fmt.Errorf("expected unknown field error")
+			case !strict && err != nil:
+				errs <- fmt.Errorf("unexpected error: %v", err)
+			case !strict && v.B[0].C != 2:
+				errs <- fmt.Errorf("unexpected value %+v", v)
+			}
+			if err := json.Unmarshal([]byte(src), &v); err != nil {
+				errs <- fmt.Errorf("unexpected error of Unmarshal: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
 type unmarshalJSON struct {
 	v int
 }
@@ -253,12 +368,41 @@ func (u *unmarshalJSON) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+type unmarshalJSONRaw struct {
+	raw string
+}
+
+func (u *unmarshalJSONRaw) UnmarshalJSON(b []byte) error {
+	u.raw = string(b)
+	return nil
+}
+
 func Test_UnmarshalJSON(t *testing.T) {
 	t.Run("*struct", func(t *testing.T) {
 		var v unmarshalJSON
 		assertErr(t, json.Unmarshal([]byte(`10`), &v))
 		assertEq(t, "unmarshal", v.v, 10)
 	})
+	t.Run("composite value", func(t *testing.T) {
+		for _, src := range []string{`{"a":[1]}`, `[1,{"b":2}]`} {
+			var v unmarshalJSONRaw
+			assertErr(t, json.Unmarshal([]byte(src), &v))
+			assertEq(t, "unmarshal", src, v.raw)
+			v = unmarshalJSONRaw{}
+			assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+			assertEq(t, "stream", src, v.raw)
+		}
+	})
+	t.Run("pointer receiver", func(t *testing.T) {
+		var v struct {
+			F unmarshalJSONRaw
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"F":{"a":1}}`), &v))
+		assertEq(t, "field", `{"a":1}`, v.F.raw)
+		var elems []unmarshalJSONRaw
+		assertErr(t, json.Unmarshal([]byte(`[{"a":1},[2]]`), &elems))
+		assertEq(t, "elements", `[{{"a":1}} {[2]}]`, fmt.Sprint(elems))
+	})
 }
 
 type unmarshalText struct {
@@ -345,3 +489,1388 @@ func Test_DecodeStream(t *testing.T) {
 	assertErr(t, err)
 	assertEq(t, "]", fmt.Sprint(tk), "]")
 }
+
+func Test_DecoderMore(t *testing.T) {
+	t.Run("nested arrays", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[[1, 2], [], [3]]`))
+		_, err := dec.Token()
+		assertErr(t, err)
+		var lens []int
+		for dec.More() {
+			_, err := dec.Token()
+			assertErr(t, err)
+			n := 0
+			for dec.More() {
+				var v int
+				assertErr(t, dec.Decode(&v))
+				n++
+			}
+			_, err = dec.Token()
+			assertErr(t, err)
+			lens = append(lens, n)
+		}
+		tk, err := dec.Token()
+		assertErr(t, err)
+		assertEq(t, "end", "]", fmt.Sprint(tk))
+		assertEq(t, "lengths", "[2 0 1]", fmt.Sprint(lens))
+		assertEq(t, "more", false, dec.More())
+	})
+	t.Run("object", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"a": [1], "b": {"c": 2}}`))
+		_, err := dec.Token()
+		assertErr(t, err)
+		var keys []string
+		for dec.More() {
+			key, err := dec.Token()
+			assertErr(t, err)
+			keys = append(keys, key.(string))
+			var v interface{}
+			assertErr(t, dec.Decode(&v))
+		}
+		tk, err := dec.Token()
+		assertErr(t, err)
+		assertEq(t, "end", "}", fmt.Sprint(tk))
+		assertEq(t, "keys", "[a b]", fmt.Sprint(keys))
+	})
+	t.Run("missing separator", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[1 2]`))
+		dec.Token()
+		var v int
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "more", true, dec.More())
+		assertNeq(t, "decode", nil, dec.Decode(&v))
+	})
+	t.Run("invalid tokens", func(t *testing.T) {
+		for _, src := range []string{`[1,]`, `[1}`, `{"a" 1}`, `{1: 2}`, `{"a": 1,}`, `]`} {
+			dec := json.NewDecoder(strings.NewReader(src))
+			var err error
+			for err == nil {
+				_, err = dec.Token()
+			}
+			if err == io.EOF {
+				t.Fatalf("%s: expected a syntax error", src)
+			}
+		}
+	})
+}
+
+func Test_Decoder_LenientSyntax(t *testing.T) {
+	type T struct {
+		A string
+		B int
+		C float64
+		D interface{}
+	}
+	t.Run("single quotes", func(t *testing.T) {
+		src := `{'a': 'hello', 'b': 1, 'd': 'world'}`
+		var v T
+		assertNeq(t, "default", nil, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.AllowSingleQuotes()
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "string", "hello", v.A)
+		assertEq(t, "int", 1, v.B)
+		assertEq(t, "interface", "world", v.D)
+	})
+	t.Run("unquoted keys", func(t *testing.T) {
+		src := `{a: "hello", b: 2}`
+		var v T
+		assertNeq(t, "default", nil, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.AllowUnquotedKeys()
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "string", "hello", v.A)
+		assertEq(t, "int", 2, v.B)
+	})
+	t.Run("control chars", func(t *testing.T) {
+		src := "{\"a\": \"hello\tworld\"}"
+		var v T
+		assertNeq(t, "default", nil, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.AllowControlChars()
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "string", "hello\tworld", v.A)
+
+		var u T
+		assertNeq(t, "unmarshal", nil, json.Unmarshal([]byte(src), &u))
+		var i interface{}
+		assertNeq(t, "unmarshal interface", nil, json.Unmarshal([]byte("[\"a\x01\"]"), &i))
+		assertEq(t, "valid", false, json.Valid([]byte(src)))
+		api := json.Config{AllowControlChars: true}.Freeze()
+		assertErr(t, api.Unmarshal([]byte(src), &u))
+		assertEq(t, "api", "hello\tworld", u.A)
+		assertErr(t, api.Unmarshal([]byte("[\"a\x01\"]"), &i))
+		assertEq(t, "api interface", "[a\x01]", fmt.Sprint(i))
+	})
+	t.Run("leading plus", func(t *testing.T) {
+		src := `{"b": +3, "c": +1.5, "d": +2}`
+		var v T
+		assertNeq(t, "default", nil, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.AllowLeadingPlus()
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "int", 3, v.B)
+		assertEq(t, "float", 1.5, v.C)
+		assertEq(t, "interface", 2.0, v.D)
+	})
+}
+
+func Test_Decoder_StrictNumbers(t *testing.T) {
+	type T struct {
+		A int
+		B uint8
+	}
+	for _, src := range []string{`{"A": 1.5}`, `{"A": 1e3}`, `{"B": 2.0}`, `{"A": -1E-2}`, `1.5`} {
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.StrictNumbers()
+		var v interface{} = &T{}
+		if src == `1.5` {
+			v = new(int)
+		}
+		err := dec.Decode(v)
+		if _, ok := err.(*json.UnmarshalTypeError); !ok {
+			t.Errorf("%s: expected UnmarshalTypeError, got %v", src, err)
+		}
+	}
+	t.Run("integers", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"A": -12, "B": 255} 7`))
+		dec.StrictNumbers()
+		var v T
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "int", -12, v.A)
+		assertEq(t, "uint", uint8(255), v.B)
+		var n int
+		assertErr(t, dec.Decode(&n))
+		assertEq(t, "top-level", 7, n)
+	})
+}
+
+func Test_Decoder_StrictStrings(t *testing.T) {
+	decode := func(src string, v interface{}, opts ...func(*json.Decoder)) error {
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.StrictStrings()
+		for _, opt := range opts {
+			opt(dec)
+		}
+		return dec.Decode(v)
+	}
+	for _, test := range []struct {
+		src    string
+		offset int64
+	}{
+		{"\"a\x01\"", 2},
+		{`"a\qb"`, 2},
+		{`"\u12"`, 1},
+		{`"\ud800"`, 1},
+		{`"x\ud800\u0041"`, 2},
+		{`"\udc00\ud800"`, 1},
+		{`"\'"`, 1},
+	} {
+		for _, v := range []interface{}{new(string), new(interface{}), &map[string]int{}} {
+			src := test.src
+			if _, ok := v.(*map[string]int); ok {
+				src = "{" + src + ":1}"
+			}
+			err := decode(src, v)
+			syntaxErr, ok := err.(*json.SyntaxError)
+			if !ok {
+				t.Fatalf("%s into %T: expected SyntaxError but got %v", src, v, err)
+			}
+			offset := test.offset
+			if src != test.src {
+				offset++
+			}
+			assertEq(t, "offset of "+src, offset, syntaxErr.Offset)
+		}
+	}
+	t.Run("valid", func(t *testing.T) {
+		var v []string
+		assertErr(t, decode(`["a\"\\\/\b\f\n\r\t", "\u00e9\ud83d\ude00", "\\u12"]`, &v))
+		assertEq(t, "len", 3, len(v))
+		assertEq(t, "escapes", "a\"\\/\b\f\n\r\t", v[0])
+		assertEq(t, "unicode", "\u00e9\U0001f600", v[1])
+		assertEq(t, "escaped backslash", `\u12`, v[2])
+	})
+	t.Run("options", func(t *testing.T) {
+		var s string
+		if err := decode("\"\x01\"", &s, (*json.Decoder).AllowControlChars); err == nil {
+			t.Fatal("expected control characters to be rejected")
+		}
+		assertErr(t, decode(`'it\'s'`, &s, (*json.Decoder).AllowSingleQuotes))
+		assertEq(t, "single quote", "it's", s)
+		assertErr(t, json.NewDecoder(strings.NewReader(`"\ud800"`)).Decode(&s))
+		assertEq(t, "lenient", "\ufffd", s)
+	})
+}
+
+func Test_Decoder_AllowScalarStrings(t *testing.T) {
+	type T struct {
+		ID    string            `json:"id"`
+		Flag  *string           `json:"flag"`
+		Attrs map[string]string `json:"attrs"`
+	}
+	decode := func(src string, v interface{}) error {
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.AllowScalarStrings()
+		return dec.Decode(v)
+	}
+	var v T
+	assertErr(t, decode(`{"id": -12.50e1, "flag":false, "attrs":{"a":"x","b":1,"c":true}}`, &v))
+	assertEq(t, "number", "-12.50e1", v.ID)
+	assertEq(t, "bool", "false", *v.Flag)
+	assertEq(t, "quoted", "x", v.Attrs["a"])
+	assertEq(t, "map number", "1", v.Attrs["b"])
+	assertEq(t, "map bool", "true", v.Attrs["c"])
+	assertErr(t, decode(`{"id":"123","flag":null}`, &v))
+	assertEq(t, "string", "123", v.ID)
+
+	for _, src := range []string{`{"id":1.2.3}`, `{"id":[1]}`, `{"attrs":{1:"a"}}`} {
+		if err := decode(src, &v); err == nil {
+			t.Fatalf("expected error for %s", src)
+		}
+	}
+	if err := json.NewDecoder(strings.NewReader(`{"id":123}`)).Decode(&v); err == nil {
+		t.Fatal("expected error without AllowScalarStrings")
+	}
+}
+
+type nullableValue struct {
+	null bool
+}
+
+func (v *nullableValue) UnmarshalJSON(b []byte) error {
+	v.null = string(b) == "null"
+	return nil
+}
+
+func Test_Decoder_DisallowNull(t *testing.T) {
+	t.Run("notnull", func(t *testing.T) {
+		type T struct {
+			Name  string `json:"name,notnull"`
+			Inner struct {
+				Label string `json:"label,notnull"`
+			} `json:"inner"`
+			Other string `json:"other"`
+		}
+		for _, test := range []struct {
+			src    string
+			field  string
+			offset int64
+		}{
+			{`{"name": null}`, "name", 9},
+			{`{"name":"a","inner":{"label":null}}`, "inner.label", 29},
+		} {
+			v := T{Name: "x"}
+			errs := []error{
+				json.Unmarshal([]byte(test.src), &v),
+				json.NewDecoder(strings.NewReader(test.src)).Decode(&v),
+			}
+			for _, err := range errs {
+				typeErr, ok := err.(*json.UnmarshalTypeError)
+				if !ok {
+					t.Fatalf("%s: expected UnmarshalTypeError but got %v", test.src, err)
+				}
+				assertEq(t, "field", test.field, typeErr.Field)
+				assertEq(t, "offset", test.offset, typeErr.Offset)
+				assertEq(t, "value", "null", typeErr.Value)
+			}
+		}
+		var v T
+		assertErr(t, json.Unmarshal([]byte(`{"name":"a","other":null}`), &v))
+		assertEq(t, "name", "a", v.Name)
+	})
+	t.Run("decoder option", func(t *testing.T) {
+		type T struct {
+			Name     string            `json:"name"`
+			Count    int               `json:"count"`
+			Tags     []string          `json:"tags"`
+			Attrs    map[string]string `json:"attrs"`
+			Any      interface{}       `json:"any"`
+			Raw      json.RawMessage   `json:"raw"`
+			Nullable nullableValue     `json:"nullable"`
+		}
+		decode := func(src string, v interface{}) error {
+			dec := json.NewDecoder(strings.NewReader(src))
+			dec.DisallowNull()
+			return dec.Decode(v)
+		}
+		var v T
+		assertErr(t, decode(`{"tags":null,"attrs":null,"any":null,"raw":null,"nullable":null}`, &v))
+		assertEq(t, "unmarshaler", true, v.Nullable.null)
+		for _, src := range []string{`{"name":null}`, `{"count":null}`} {
+			if _, ok := decode(src, &v).(*json.UnmarshalTypeError); !ok {
+				t.Fatalf("expected UnmarshalTypeError for %s", src)
+			}
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"name":null}`), &v))
+	})
+}
+
+func Test_Decoder_ResetMissingFields(t *testing.T) {
+	type inner struct {
+		A string `json:"a"`
+		B string `json:"b"`
+	}
+	type T struct {
+		ID    string            `json:"id"`
+		Tags  []string          `json:"tags"`
+		Attrs map[string]string `json:"attrs"`
+		Inner inner             `json:"inner"`
+		Other inner             `json:"other"`
+		City  string            `json:"address.city,path"`
+	}
+	decode := func(src string, v interface{}) error {
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.ResetMissingFields()
+		return dec.Decode(v)
+	}
+	v := T{
+		ID:    "1",
+		Tags:  []string{"a"},
+		Attrs: map[string]string{"k": "v"},
+		Inner: inner{A: "a", B: "b"},
+		Other: inner{A: "a"},
+		City:  "Tokyo",
+	}
+	assertErr(t, decode(`{"id":"2","inner":{"b":"c"},"address":{}}`, &v))
+	assertEq(t, "id", "2", v.ID)
+	assertEq(t, "tags", 0, len(v.Tags))
+	assertEq(t, "attrs", true, v.Attrs == nil)
+	assertEq(t, "inner", inner{B: "c"}, v.Inner)
+	assertEq(t, "other", inner{}, v.Other)
+	assertEq(t, "path", "", v.City)
+
+	v = T{ID: "1", City: "Tokyo"}
+	assertErr(t, decode(`{}`, &v))
+	if !reflect.DeepEqual(T{}, v) {
+		t.Fatalf("expected zero value but got %+v", v)
+	}
+
+	v = T{ID: "1", City: "Tokyo"}
+	assertErr(t, json.NewDecoder(strings.NewReader(`{"tags":["b"]}`)).Decode(&v))
+	assertEq(t, "kept", "1", v.ID)
+	assertEq(t, "kept path", "Tokyo", v.City)
+}
+
+type stringerTarget struct {
+	X int `json:"x"`
+}
+
+func (s *stringerTarget) String() string { return "target" }
+
+func Test_Decoder_NonEmptyInterface(t *testing.T) {
+	type T struct {
+		fmt.Stringer
+		N int `json:"n"`
+	}
+	decoders := map[string]func(string, interface{}) error{
+		"unmarshal": func(src string, v interface{}) error {
+			return json.Unmarshal([]byte(src), v)
+		},
+		"stream": func(src string, v interface{}) error {
+			return json.NewDecoder(strings.NewReader(src)).Decode(v)
+		},
+	}
+	for name, decode := range decoders {
+		t.Run(name, func(t *testing.T) {
+			var v T
+			err := decode(`{"n":1,"Stringer":"a"}`, &v)
+			typeErr, ok := err.(*json.UnmarshalTypeError)
+			if !ok {
+				t.Fatalf("expected UnmarshalTypeError but got %v", err)
+			}
+			assertEq(t, "value", "string", typeErr.Value)
+			assertEq(t, "field", "Stringer", typeErr.Field)
+			assertEq(t, "type", reflect.TypeOf((*fmt.Stringer)(nil)).Elem(), typeErr.Type)
+			assertEq(t, "nil", true, v.Stringer == nil)
+
+			target := &stringerTarget{}
+			v = T{Stringer: target}
+			assertErr(t, decode(`{"Stringer":{"x":2},"n":3}`, &v))
+			assertEq(t, "target", 2, target.X)
+			assertEq(t, "n", 3, v.N)
+			assertErr(t, decode(`{"Stringer":null}`, &v))
+			assertEq(t, "null", true, v.Stringer == nil)
+		})
+	}
+}
+
+func Test_Decoder_FieldLimits(t *testing.T) {
+	type T struct {
+		Name  string         `json:"name,maxlen=4"`
+		Nick  *string        `json:"nick,maxlen=2"`
+		Tags  []string       `json:"tags,maxitems=2"`
+		Attrs map[string]int `json:"attrs,maxitems=1"`
+	}
+	decoders := map[string]func(string, interface{}) error{
+		"unmarshal": func(src string, v interface{}) error {
+			return json.Unmarshal([]byte(src), v)
+		},
+		"stream": func(src string, v interface{}) error {
+			return json.NewDecoder(strings.NewReader(src)).Decode(v)
+		},
+	}
+	for name, decode := range decoders {
+		t.Run(name, func(t *testing.T) {
+			var v T
+			assertErr(t, decode(`{"name":"abcd","nick":"ab","tags":["a","b"],"attrs":{"a":1}}`, &v))
+			assertEq(t, "name", "abcd", v.Name)
+			for _, test := range []struct {
+				src    string
+				field  string
+				option string
+				length int
+				offset int64
+			}{
+				{`{"name": "abcde"}`, "name", "maxlen", 5, 9},
+				{`{"nick":"abc"}`, "nick", "maxlen", 3, 8},
+				{`{"tags":["a","b","c"]}`, "tags", "maxitems", 3, 8},
+				{`{"attrs":{"a":1,"b":2}}`, "attrs", "maxitems", 2, 9},
+				{`{"name":"ééé"}`, "name", "maxlen", 6, 8},
+			} {
+				var v T
+				err := decode(test.src, &v)
+				limitErr, ok := err.(*json.FieldLimitError)
+				if !ok {
+					t.Fatalf("%s: expected FieldLimitError but got %v", test.src, err)
+				}
+				assertEq(t, "struct", "T", limitErr.Struct)
+				assertEq(t, "field", test.field, limitErr.Field)
+				assertEq(t, "option", test.option, limitErr.Option)
+				assertEq(t, "length", test.length, limitErr.Length)
+				assertEq(t, "offset", test.offset, limitErr.Offset)
+			}
+			t.Run("rejected values are not stored", func(t *testing.T) {
+				nick := "n"
+				v := T{Name: "x", Nick: &nick, Tags: []string{"t"}, Attrs: map[string]int{"k": 1}}
+				for _, src := range []string{
+					`{"name":"abcde"}`,
+					`{"nick":"abc"}`,
+					`{"tags":["a","b","c"]}`,
+					`{"attrs":{"a":1,"b":2}}`,
+				} {
+					if _, ok := decode(src, &v).(*json.FieldLimitError); !ok {
+						t.Fatalf("%s: expected FieldLimitError", src)
+					}
+				}
+				assertEq(t, "name", "x", v.Name)
+				assertEq(t, "nick", "n", *v.Nick)
+				assertEq(t, "tags", `[t]`, fmt.Sprint(v.Tags))
+				assertEq(t, "attrs", `map[k:1]`, fmt.Sprint(v.Attrs))
+			})
+		})
+	}
+	t.Run("invalid options", func(t *testing.T) {
+		var v1 struct {
+			N int `json:"n,maxlen=1"`
+		}
+		var v2 struct {
+			S string `json:"s,maxlen=x"`
+		}
+		for _, v := range []interface{}{&v1, &v2} {
+			if err := json.Unmarshal([]byte(`{}`), v); err == nil {
+				t.Fatalf("expected error for %T", v)
+			}
+		}
+	})
+}
+
+func Test_Decoder_AllowExponentIntegers(t *testing.T) {
+	type T struct {
+		A int
+		B uint64
+		C int8
+	}
+	for _, test := range []struct {
+		src  string
+		want T
+	}{
+		{`{"A": 1e3, "B": 1.8446744073709551615E19}`, T{A: 1000, B: math.MaxUint64}},
+		{`{"A": -2.5e1, "C": 100e-2}`, T{A: -25, C: 1}},
+		{`{"A": 0.0e0, "B": 7, "C": -1.28e+2}`, T{B: 7, C: -128}},
+	} {
+		for _, strict := range []bool{false, true} {
+			dec := json.NewDecoder(strings.NewReader(test.src))
+			dec.AllowExponentIntegers()
+			if strict {
+				dec.StrictNumbers()
+			}
+			var v T
+			assertErr(t, dec.Decode(&v))
+			assertEq(t, test.src, test.want, v)
+		}
+	}
+	for _, src := range []string{`{"A": 1.5e0}`, `{"B": 1e20}`, `{"B": -1e0}`, `{"A": 1e999999999999}`, `{"A": 1.5}`} {
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.AllowExponentIntegers()
+		var v T
+		if _, ok := dec.Decode(&v).(*json.UnmarshalTypeError); !ok {
+			t.Errorf("%s: expected UnmarshalTypeError", src)
+		}
+	}
+	t.Run("strict", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"A": 1e3}`))
+		dec.StrictNumbers()
+		var v T
+		if _, ok := dec.Decode(&v).(*json.UnmarshalTypeError); !ok {
+			t.Error("expected UnmarshalTypeError without AllowExponentIntegers")
+		}
+	})
+}
+
+type negativeInner struct {
+	Count uint `json:"count"`
+}
+
+type negativeOuter struct {
+	Inner negativeInner `json:"inner"`
+	Small uint8
+}
+
+func Test_NegativeIntoUnsigned(t *testing.T) {
+	for _, src := range []string{`{"inner":{"count":-1}}`, `{"Small":-0}`, `{"inner":{"count":-12345678901234567890}}`} {
+		var want negativeOuter
+		wantErr := stdjson.Unmarshal([]byte(src), &want)
+		for name, err := range map[string]error{
+			"Unmarshal": json.Unmarshal([]byte(src), &negativeOuter{}),
+			"Decode":    json.NewDecoder(strings.NewReader(src)).Decode(&negativeOuter{}),
+		} {
+			if _, ok := err.(*json.UnmarshalTypeError); !ok {
+				t.Fatalf("%s(%s): expected UnmarshalTypeError, got %v", name, src, err)
+			}
+			assertEq(t, name+" message", wantErr.Error(), err.Error())
+		}
+	}
+	var n uint64
+	err := json.Unmarshal([]byte(`-3`), &n)
+	assertEq(t, "top-level", "json: cannot unmarshal number -3 into Go value of type uint64", fmt.Sprint(err))
+	assertNeq(t, "lone minus", nil, json.Unmarshal([]byte(`{"Small":-}`), &negativeOuter{}))
+}
+
+func Test_Decoder_SetMemoryBudget(t *testing.T) {
+	src := `{"a":["` + strings.Repeat("x", 1000) + `","y"],"b":{"c":"d"}}`
+	t.Run("within budget", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.SetMemoryBudget(1 << 20)
+		var v interface{}
+		assertErr(t, dec.Decode(&v))
+	})
+	t.Run("exceeded", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.SetMemoryBudget(512)
+		var v interface{}
+		err := dec.Decode(&v)
+		budgetErr, ok := err.(*json.MemoryBudgetError)
+		if !ok {
+			t.Fatalf("expected *json.MemoryBudgetError but got %v", err)
+		}
+		assertEq(t, "budget", int64(512), budgetErr.Budget)
+	})
+	t.Run("slice growth", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader("[" + strings.Repeat("1,", 1000) + "1]"))
+		dec.SetMemoryBudget(1024)
+		var v []int
+		if _, ok := dec.Decode(&v).(*json.MemoryBudgetError); !ok {
+			t.Fatal("expected *json.MemoryBudgetError")
+		}
+	})
+	t.Run("per value", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`"abcdef" "ghijkl" "mnopqr"`))
+		dec.SetMemoryBudget(10)
+		for i := 0; i < 3; i++ {
+			var v string
+			assertErr(t, dec.Decode(&v))
+		}
+	})
+}
+
+func Test_DecodeWriter(t *testing.T) {
+	type attachment struct {
+		Name string    `json:"name"`
+		Data io.Writer `json:"data"`
+	}
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	encoded := strings.Replace(base64.StdEncoding.EncodeToString(data), "/", `\/`, -1)
+	src := `{"name":"a.txt","data":"` + encoded + `"}`
+	t.Run("unmarshal", func(t *testing.T) {
+		var buf bytes.Buffer
+		v := attachment{Data: &buf}
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "name", "a.txt", v.Name)
+		assertEq(t, "data", true, bytes.Equal(data, buf.Bytes()))
+	})
+	t.Run("decoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		v := attachment{Data: &buf}
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		assertEq(t, "name", "a.txt", v.Name)
+		assertEq(t, "data", true, bytes.Equal(data, buf.Bytes()))
+	})
+	t.Run("nil writer", func(t *testing.T) {
+		var v attachment
+		if err := json.NewDecoder(strings.NewReader(src)).Decode(&v); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("null", func(t *testing.T) {
+		var v attachment
+		assertErr(t, json.NewDecoder(strings.NewReader(`{"data":null}`)).Decode(&v))
+	})
+}
+
+func Test_Decoder_SetKeyTransformer(t *testing.T) {
+	trim := func(key string) string { return strings.TrimPrefix(key, "x_") }
+	t.Run("struct", func(t *testing.T) {
+		var v struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		}
+		dec := json.NewDecoder(strings.NewReader(`{"x_name":"a","count":2}`))
+		dec.SetKeyTransformer(trim)
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "name", "a", v.Name)
+		assertEq(t, "count", 2, v.Count)
+	})
+	t.Run("map", func(t *testing.T) {
+		var v map[string]int
+		dec := json.NewDecoder(strings.NewReader(`{"x_a":1,"b":2}`))
+		dec.SetKeyTransformer(trim)
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "a", 1, v["a"])
+		assertEq(t, "b", 2, v["b"])
+	})
+	t.Run("interface", func(t *testing.T) {
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(`{"x_a":{"x_b":true}}`))
+		dec.SetKeyTransformer(strings.ToUpper)
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "interface", "map[X_A:map[X_B:true]]", fmt.Sprint(v))
+	})
+	t.Run("unknown field", func(t *testing.T) {
+		var v struct {
+			A int
+		}
+		dec := json.NewDecoder(strings.NewReader(`{"x_B":1}`))
+		dec.SetKeyTransformer(trim)
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&v)
+		fieldErr, ok := err.(*json.UnknownFieldError)
+		if !ok {
+			t.Fatalf("expected unknown field error but got %v", err)
+		}
+		assertEq(t, "field", "x_B", fieldErr.Field)
+		dec = json.NewDecoder(strings.NewReader(`{"x_B":1}`))
+		dec.DisallowUnknownFields()
+		assertEq(t, "same field", fieldErr.Error(), fmt.Sprint(dec.Decode(&v)))
+	})
+}
+
+func Test_Decoder_ContinueOnElementError(t *testing.T) {
+	type record struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	t.Run("slice", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[{"id":1,"name":"a"},{"id":"x","name":"b"},{"id":3,"name":"c"},{"id":4,"name":5}]`))
+		dec.ContinueOnElementError()
+		var v []record
+		err := dec.Decode(&v)
+		errs, ok := err.(*json.ElementErrors)
+		if !ok {
+			t.Fatalf("expected *json.ElementErrors but got %v", err)
+		}
+		assertEq(t, "decoded", fmt.Sprint([]record{{ID: 1, Name: "a"}, {ID: 3, Name: "c"}}), fmt.Sprint(v))
+		assertEq(t, "errors", 2, len(errs.Errors))
+		assertEq(t, "first index", 1, errs.Errors[0].Index)
+		assertEq(t, "first offset", int64(len(`[{"id":1,"name":"a"},`)), errs.Errors[0].Offset)
+		assertEq(t, "second index", 3, errs.Errors[1].Index)
+		if errs.Errors[0].Err == nil {
+			t.Fatal("expected element error")
+		}
+	})
+	t.Run("array", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[1, "two", 3]`))
+		dec.ContinueOnElementError()
+		var v [3]int
+		err := dec.Decode(&v)
+		if _, ok := err.(*json.ElementErrors); !ok {
+			t.Fatalf("expected *json.ElementErrors but got %v", err)
+		}
+		assertEq(t, "decoded", [3]int{1, 0, 3}, v)
+	})
+	t.Run("all elements fail", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`["a","b"]`))
+		dec.ContinueOnElementError()
+		var v []int
+		if err := dec.Decode(&v); err == nil {
+			t.Fatal("expected error")
+		}
+		assertEq(t, "length", 0, len(v))
+	})
+	t.Run("nested", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"items":[[1,"x"],[2]]} {"items":[[3]]}`))
+		dec.ContinueOnElementError()
+		var v struct {
+			Items [][]int `json:"items"`
+		}
+		err := dec.Decode(&v)
+		errs, ok := err.(*json.ElementErrors)
+		if !ok {
+			t.Fatalf("expected *json.ElementErrors but got %v", err)
+		}
+		assertEq(t, "index", 1, errs.Errors[0].Index)
+		assertEq(t, "decoded", "[[1] [2]]", fmt.Sprint(v.Items))
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "next value", "[[3]]", fmt.Sprint(v.Items))
+	})
+	t.Run("syntax error", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[1, tru, 3]`))
+		dec.ContinueOnElementError()
+		var v []int
+		err := dec.Decode(&v)
+		if _, ok := err.(*json.ElementErrors); ok || err == nil {
+			t.Fatalf("expected syntax error but got %v", err)
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		var v []int
+		err := json.NewDecoder(strings.NewReader(`[1,"x"]`)).Decode(&v)
+		if _, ok := err.(*json.ElementErrors); ok || err == nil {
+			t.Fatalf("expected element error but got %v", err)
+		}
+	})
+}
+
+func Test_Decoder_ReuseInterfaceContainers(t *testing.T) {
+	t.Run("reuse", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"a":[1,{"b":2}],"c":"x"} {"a":[3],"d":null} {"a":[4,5,6]}`))
+		dec.ReuseInterfaceContainers()
+		var v interface{}
+		assertErr(t, dec.Decode(&v))
+		m := v.(map[interface{}]interface{})
+		assertEq(t, "first", "map[a:[1 map[b:2]] c:x]", fmt.Sprint(v))
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "second", "map[a:[3] d:<nil>]", fmt.Sprint(v))
+		assertEq(t, "same map", reflect.ValueOf(m).Pointer(), reflect.ValueOf(v).Pointer())
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "third", "map[a:[4 5 6]]", fmt.Sprint(v))
+	})
+	t.Run("string keys", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"a":1,"b":2}`))
+		dec.ReuseInterfaceContainers()
+		m := map[string]interface{}{"b": 0, "z": 0}
+		var v interface{} = m
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "decoded", "map[a:1 b:2]", fmt.Sprint(m))
+	})
+	t.Run("shorter array", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`[{"a":1},"b",3] [4] []`))
+		dec.ReuseInterfaceContainers()
+		var v interface{}
+		assertErr(t, dec.Decode(&v))
+		assertErr(t, dec.Decode(&v))
+		s := v.([]interface{})
+		assertEq(t, "second", "[4]", fmt.Sprint(s))
+		assertEq(t, "second tail", "[4 <nil> <nil>]", fmt.Sprint(s[:cap(s)]))
+		assertErr(t, dec.Decode(&v))
+		s = v.([]interface{})
+		assertEq(t, "third tail", "[<nil> <nil> <nil>]", fmt.Sprint(s[:cap(s)]))
+	})
+	t.Run("allocations", func(t *testing.T) {
+		docs := strings.Repeat(`{"id":"x","tags":["a","b"],"items":[{"name":"p","ok":true},{"name":"q","ok":false}]} `, 200)
+		dec := json.NewDecoder(strings.NewReader(docs))
+		dec.ReuseInterfaceContainers()
+		var v interface{}
+		assertErr(t, dec.Decode(&v))
+		reused := testing.AllocsPerRun(50, func() {
+			assertErr(t, dec.Decode(&v))
+		})
+		dec = json.NewDecoder(strings.NewReader(docs))
+		fresh := testing.AllocsPerRun(50, func() {
+			var v interface{}
+			assertErr(t, dec.Decode(&v))
+		})
+		if reused*3 > fresh {
+			t.Fatalf("expected far fewer allocations when reusing, got %v and %v", reused, fresh)
+		}
+	})
+}
+
+func Test_UnmarshalValue(t *testing.T) {
+	type valueT struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	t.Run("pointer", func(t *testing.T) {
+		var v valueT
+		assertErr(t, json.UnmarshalValue([]byte(`{"a":1,"b":"x"}`), reflect.ValueOf(&v)))
+		assertEq(t, "decoded", valueT{A: 1, B: "x"}, v)
+	})
+	t.Run("settable", func(t *testing.T) {
+		var v struct {
+			Inner valueT
+			P     *valueT
+		}
+		rv := reflect.ValueOf(&v).Elem()
+		assertErr(t, json.UnmarshalValue([]byte(`{"a":2}`), rv.Field(0)))
+		assertEq(t, "field", valueT{A: 2}, v.Inner)
+		assertErr(t, json.UnmarshalValue([]byte(`{"b":"y"}`), rv.Field(1)))
+		assertEq(t, "pointer field", valueT{B: "y"}, *v.P)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		err := json.UnmarshalValue([]byte(`1`), reflect.ValueOf(1))
+		if _, ok := err.(*json.InvalidUnmarshalError); !ok {
+			t.Fatalf("expected *json.InvalidUnmarshalError but got %v", err)
+		}
+		err = json.UnmarshalValue([]byte(`1`), reflect.Value{})
+		if _, ok := err.(*json.InvalidUnmarshalError); !ok {
+			t.Fatalf("expected *json.InvalidUnmarshalError but got %v", err)
+		}
+	})
+}
+
+func Test_FieldAlias(t *testing.T) {
+	type aliasT struct {
+		Color string `json:"color,alias=colour,alias=hex_color"`
+		Size  int    `json:"size,alias=color"`
+	}
+	for _, src := range []string{`{"color":"red"}`, `{"colour":"red"}`, `{"hex_color":"red"}`} {
+		t.Run(src, func(t *testing.T) {
+			var v aliasT
+			assertErr(t, json.Unmarshal([]byte(src), &v))
+			assertEq(t, "color", "red", v.Color)
+			var stream aliasT
+			assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&stream))
+			assertEq(t, "stream color", "red", stream.Color)
+		})
+	}
+	t.Run("encode", func(t *testing.T) {
+		bytes, err := json.Marshal(aliasT{Color: "red", Size: 1})
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"color":"red","size":1}`, string(bytes))
+	})
+}
+
+func Test_SkipUnknownSubtree(t *testing.T) {
+	type T struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	src := `{"x":{"s":"}]\\","t":["\\\"{",[1e3,{"u":null}]],"v":true},"a":1,"y":[{},[]],"z":"\"","b":"ok"}`
+	t.Run("unmarshal", func(t *testing.T) {
+		var v T
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "a", 1, v.A)
+		assertEq(t, "b", "ok", v.B)
+	})
+	t.Run("stream", func(t *testing.T) {
+		var v T
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		assertEq(t, "a", 1, v.A)
+		assertEq(t, "b", "ok", v.B)
+	})
+	t.Run("mismatched brackets", func(t *testing.T) {
+		for _, src := range []string{`{"x":[1,2},"a":1}`, `{"x":{"k":1],"a":1}`} {
+			var v T
+			if err := json.Unmarshal([]byte(src), &v); err == nil {
+				t.Fatalf("%s: expected error", src)
+			}
+			if err := json.NewDecoder(strings.NewReader(src)).Decode(&v); err == nil {
+				t.Fatalf("%s: expected error from stream", src)
+			}
+		}
+	})
+	t.Run("unterminated", func(t *testing.T) {
+		for _, src := range []string{`{"x":{"s":[1,2}`, `{"x":["abc]}`, `{"x":["\\`} {
+			var v T
+			if err := json.Unmarshal([]byte(src), &v); err == nil {
+				t.Fatalf("%s: expected error", src)
+			}
+			if err := json.NewDecoder(strings.NewReader(src)).Decode(&v); err == nil {
+				t.Fatalf("%s: expected error from stream", src)
+			}
+		}
+	})
+}
+
+func Test_DecodeEscapedString(t *testing.T) {
+	src := `{"a\"b": "q\"\\\/\b\f\n\r\té😀\ud83d!", "c": "plain"}`
+	expected := "q\"\\/\b\f\n\r\té😀�!"
+	t.Run("unmarshal", func(t *testing.T) {
+		var v struct {
+			AB string `json:"a\"b"`
+			C  string `json:"c"`
+		}
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "escaped", expected, v.AB)
+		assertEq(t, "plain", "plain", v.C)
+	})
+	t.Run("stream", func(t *testing.T) {
+		var v map[string]string
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		assertEq(t, "escaped", expected, v["a\"b"])
+	})
+	t.Run("interface", func(t *testing.T) {
+		src := `["q\"\\\/\b\f\n\r\t\u00e9😀\ud83d!"]`
+		var v interface{}
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "unmarshal", expected, v.([]interface{})[0])
+		v = nil
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		assertEq(t, "stream", expected, v.([]interface{})[0])
+	})
+	t.Run("token", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`["a\nb"]`))
+		dec.Token()
+		tok, err := dec.Token()
+		assertErr(t, err)
+		assertEq(t, "token", "a\nb", tok)
+	})
+}
+
+func Test_DecodeFloat(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	inputs := []string{
+		"0", "-0", "1", "-1.5", "0.1", "1e23", "9007199254740993", "1.7976931348623157e308",
+		"4.9e-324", "2.2250738585072011e-308", "2.2250738585072012e-308", "1e-400", "12345678901234567890123",
+		"0.000000000000000000000000000001", "7.0420557077594588669468784357561207962098443483187940792729600000e+59",
+	}
+	for i := 0; i < 10000; i++ {
+		f := math.Float64frombits(r.Uint64())
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			continue
+		}
+		inputs = append(inputs,
+			strconv.FormatFloat(f, 'g', -1, 64),
+			strconv.FormatFloat(f, 'e', r.Intn(20), 64),
+			strconv.FormatUint(r.Uint64()>>uint(r.Intn(64)), 10)+"e"+strconv.Itoa(r.Intn(700)-350),
+		)
+	}
+	for _, src := range inputs {
+		expected, _ := strconv.ParseFloat(src, 64)
+		var v float64
+		json.Unmarshal([]byte(src), &v)
+		if math.Float64bits(expected) != math.Float64bits(v) && !math.IsInf(expected, 0) {
+			t.Fatalf("%s: expected %v but got %v", src, expected, v)
+		}
+	}
+	var v float64
+	if err := json.Unmarshal([]byte(`1e400`), &v); err == nil {
+		t.Fatal("expected error for a number out of range")
+	}
+}
+
+func Test_DecodeIntRange(t *testing.T) {
+	var i64 int64
+	assertErr(t, json.Unmarshal([]byte(`-9223372036854775808`), &i64))
+	assertEq(t, "min int64", int64(math.MinInt64), i64)
+	assertErr(t, json.Unmarshal([]byte(`9223372036854775807`), &i64))
+	assertEq(t, "max int64", int64(math.MaxInt64), i64)
+	var u64 uint64
+	assertErr(t, json.Unmarshal([]byte(`18446744073709551615`), &u64))
+	assertEq(t, "max uint64", uint64(math.MaxUint64), u64)
+	assertErr(t, json.Unmarshal([]byte(`00000000000000000000001`), &u64))
+	assertEq(t, "leading zeros", uint64(1), u64)
+	for _, src := range []string{`9223372036854775808`, `-9223372036854775809`, `123456789012345678901234567890`} {
+		err := json.Unmarshal([]byte(src), &i64)
+		if _, ok := err.(*json.UnmarshalTypeError); !ok {
+			t.Fatalf("%s: expected *json.UnmarshalTypeError but got %v", src, err)
+		}
+		err = json.NewDecoder(strings.NewReader(src)).Decode(&i64)
+		if _, ok := err.(*json.UnmarshalTypeError); !ok {
+			t.Fatalf("%s: expected *json.UnmarshalTypeError from stream but got %v", src, err)
+		}
+	}
+	for _, src := range []string{`18446744073709551616`, `99999999999999999999`} {
+		err := json.Unmarshal([]byte(src), &u64)
+		if _, ok := err.(*json.UnmarshalTypeError); !ok {
+			t.Fatalf("%s: expected *json.UnmarshalTypeError but got %v", src, err)
+		}
+	}
+	var (
+		i8  int8
+		i16 int16
+		i32 int32
+		u8  uint8
+		u16 uint16
+		u32 uint32
+	)
+	for _, tc := range []struct {
+		src string
+		v   interface{}
+	}{
+		{`128`, &i8}, {`-129`, &i8}, {`70000`, &i16}, {`-32769`, &i16}, {`2147483648`, &i32}, {`-2147483649`, &i32},
+		{`256`, &u8}, {`300`, &u8}, {`65536`, &u16}, {`4294967296`, &u32},
+	} {
+		err := json.Unmarshal([]byte(tc.src), tc.v)
+		if _, ok := err.(*json.UnmarshalTypeError); !ok {
+			t.Fatalf("%s into %T: expected *json.UnmarshalTypeError but got %v", tc.src, tc.v, err)
+		}
+		err = json.NewDecoder(strings.NewReader(tc.src)).Decode(tc.v)
+		if _, ok := err.(*json.UnmarshalTypeError); !ok {
+			t.Fatalf("%s into %T: expected *json.UnmarshalTypeError from stream but got %v", tc.src, tc.v, err)
+		}
+	}
+	assertErr(t, json.Unmarshal([]byte(`-128`), &i8))
+	assertEq(t, "min int8", int8(math.MinInt8), i8)
+	assertErr(t, json.Unmarshal([]byte(`32767`), &i16))
+	assertEq(t, "max int16", int16(math.MaxInt16), i16)
+	assertErr(t, json.Unmarshal([]byte(`255`), &u8))
+	assertEq(t, "max uint8", uint8(math.MaxUint8), u8)
+	assertErr(t, json.Unmarshal([]byte(`4294967295`), &u32))
+	assertEq(t, "max uint32", uint32(math.MaxUint32), u32)
+}
+
+// intSum decodes an array of integers into their sum, one element at a time.
+type intSum struct {
+	total int
+	calls int
+}
+
+func (s *intSum) UnmarshalJSONFrom(d *json.Decoder) error {
+	s.calls++
+	tok, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("expected an array but got %v", tok)
+	}
+	for d.More() {
+		var n int
+		if err := d.Decode(&n); err != nil {
+			return err
+		}
+		s.total += n
+	}
+	_, err = d.Token()
+	return err
+}
+
+// Its UnmarshalJSON method must not be called.
+func (s *intSum) UnmarshalJSON([]byte) error {
+	return fmt.Errorf("unexpected call of UnmarshalJSON")
+}
+
+// firstToken reads only the beginning of a value.
+type firstToken struct{}
+
+func (firstToken) UnmarshalJSONFrom(d *json.Decoder) error {
+	_, err := d.Token()
+	return err
+}
+
+func Test_UnmarshalerFrom(t *testing.T) {
+	type payload struct {
+		ID  int     `json:"id"`
+		Sum intSum  `json:"sum"`
+		Ptr *intSum `json:"ptr"`
+	}
+	const src = `{"id":1,"sum":[1, 2, 3],"ptr":[ 10 ,20 ]}`
+	t.Run("unmarshal", func(t *testing.T) {
+		var v payload
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "id", 1, v.ID)
+		assertEq(t, "sum", 6, v.Sum.total)
+		assertEq(t, "calls", 1, v.Sum.calls)
+		assertEq(t, "ptr", 30, v.Ptr.total)
+	})
+	t.Run("stream", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(src + "\n" + src))
+		for i := 0; i < 2; i++ {
+			var v payload
+			assertErr(t, dec.Decode(&v))
+			assertEq(t, "id", 1, v.ID)
+			assertEq(t, "sum", 6, v.Sum.total)
+			assertEq(t, "ptr", 30, v.Ptr.total)
+		}
+	})
+	t.Run("top level", func(t *testing.T) {
+		var s intSum
+		assertErr(t, json.Unmarshal([]byte(`[4,5]`), &s))
+		assertEq(t, "sum", 9, s.total)
+	})
+	t.Run("elements", func(t *testing.T) {
+		var v []intSum
+		assertErr(t, json.NewDecoder(strings.NewReader(`[[1],[2,3],[]]`)).Decode(&v))
+		assertEq(t, "len", 3, len(v))
+		assertEq(t, "sums", "1 5 0", fmt.Sprintf("%d %d %d", v[0].total, v[1].total, v[2].total))
+	})
+	t.Run("error", func(t *testing.T) {
+		var v payload
+		if err := json.Unmarshal([]byte(`{"sum":{}}`), &v); err == nil || err.Error() != "expected an array but got {" {
+			t.Fatalf("expected the error of UnmarshalJSONFrom but got %v", err)
+		}
+	})
+	t.Run("incomplete", func(t *testing.T) {
+		var v struct {
+			A firstToken `json:"a"`
+		}
+		err := json.Unmarshal([]byte(`{"a":[1]}`), &v)
+		if _, ok := err.(*json.SyntaxError); !ok {
+			t.Fatalf("expected *json.SyntaxError from Unmarshal but got %v", err)
+		}
+		err = json.NewDecoder(strings.NewReader(`{"a":[1]}`)).Decode(&v)
+		if _, ok := err.(*json.SyntaxError); !ok {
+			t.Fatalf("expected *json.SyntaxError from Decode but got %v", err)
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"a":1}`), &v))
+	})
+}
+
+func encodeUTF16(s string, bigEndian, bom bool) []byte {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+	b := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		if bigEndian {
+			b = append(b, byte(u>>8), byte(u))
+		} else {
+			b = append(b, byte(u), byte(u>>8))
+		}
+	}
+	return b
+}
+
+func Test_UnicodeInput(t *testing.T) {
+	type T struct {
+		Name  string
+		Count int
+	}
+	want := T{Name: "café \U0001f600 " + strings.Repeat("x", 600), Count: 3}
+	src := fmt.Sprintf(`{"Name": %q, "Count": 3}`, want.Name)
+	t.Run("utf-8 bom", func(t *testing.T) {
+		data := append([]byte("\xef\xbb\xbf"), src...)
+		var v T
+		assertErr(t, json.Unmarshal(data, &v))
+		assertEq(t, "Unmarshal", want, v)
+		v = T{}
+		assertErr(t, json.NewDecoder(bytes.NewReader(data)).Decode(&v))
+		assertEq(t, "Decode", want, v)
+		var n int
+		assertErr(t, json.Unmarshal([]byte("\xef\xbb\xbf1"), &n))
+		assertEq(t, "number", 1, n)
+		assertNeq(t, "bom inside", nil, json.Unmarshal([]byte(" \xef\xbb\xbf1"), &n))
+	})
+	t.Run("utf-16", func(t *testing.T) {
+		for _, bigEndian := range []bool{false, true} {
+			for _, bom := range []bool{false, true} {
+				data := encodeUTF16(src+"\n"+src, bigEndian, bom)
+				dec := json.NewDecoder(bytes.NewReader(data))
+				dec.TranscodeUTF16()
+				for i := 0; i < 2; i++ {
+					var v T
+					assertErr(t, dec.Decode(&v))
+					assertEq(t, fmt.Sprintf("bigEndian=%v bom=%v", bigEndian, bom), want, v)
+				}
+				var v T
+				assertEq(t, "end", io.EOF, dec.Decode(&v))
+			}
+		}
+		var n int
+		dec := json.NewDecoder(bytes.NewReader(encodeUTF16("7", false, false)))
+		dec.TranscodeUTF16()
+		assertErr(t, dec.Decode(&n))
+		assertEq(t, "single character", 7, n)
+	})
+	t.Run("utf-16 replacement", func(t *testing.T) {
+		data := append(encodeUTF16(`"a`, true, false), 0xd8, 0x00)
+		data = append(data, encodeUTF16(`b"`, true, false)...)
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.TranscodeUTF16()
+		var s string
+		assertErr(t, dec.Decode(&s))
+		assertEq(t, "unpaired surrogate", "a�b", s)
+	})
+	t.Run("utf-8 unchanged", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.TranscodeUTF16()
+		var v T
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "utf-8", want, v)
+	})
+}
+
+func Test_Decoder_SetProgressFunc(t *testing.T) {
+	src := "[" + strings.Repeat(`"abcdefghi",`, 400) + "0]"
+	var got []json.DecodeProgress
+	dec := json.NewDecoder(strings.NewReader(src))
+	dec.SetProgressFunc(1000, int64(len(src)), func(p json.DecodeProgress) {
+		got = append(got, p)
+	})
+	var v []interface{}
+	assertErr(t, dec.Decode(&v))
+	assertEq(t, "length", 401, len(v))
+	if len(got) != len(src)/1000+1 {
+		t.Fatalf("expected %d calls but got %v", len(src)/1000+1, got)
+	}
+	for i, p := range got[:len(got)-1] {
+		if p.Offset < int64(i+1)*1000 || p.Offset >= int64(i+2)*1000 {
+			t.Fatalf("unexpected offset of call %d: %v", i, got)
+		}
+	}
+	last := got[len(got)-1]
+	assertEq(t, "last offset", int64(len(src)), last.Offset)
+	assertEq(t, "last percent", float64(100), last.Percent())
+	assertEq(t, "unknown total", float64(-1), json.DecodeProgress{Offset: 1}.Percent())
+
+	t.Run("remove", func(t *testing.T) {
+		calls := 0
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.SetProgressFunc(100, 0, func(json.DecodeProgress) { calls++ })
+		dec.SetProgressFunc(0, 0, nil)
+		var v []interface{}
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "calls", 1, calls)
+	})
+}
+
+// waitPast blocks until the time t has passed.
+func waitPast(t time.Time) {
+	for !time.Now().After(t) {
+		time.Sleep(time.Until(t))
+	}
+}
+
+// deadlineReader returns data in chunks, blocking in the second read until timeout has passed.
+// The first read may happen before a Decoder sets its deadline, the second one happens after,
+// so a timeout no longer than the one of the reader has then expired.
+type deadlineReader struct {
+	data    []byte
+	timeout time.Duration
+	reads   int
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	r.reads++
+	if r.reads == 2 {
+		waitPast(time.Now().Add(r.timeout))
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// deadlineElement blocks in its first UnmarshalJSON call until deadlineElementTimeout has passed,
+// so a timeout set before decoding started has then expired.
+type deadlineElement struct{}
+
+var (
+	deadlineElementCalls   int
+	deadlineElementTimeout time.Duration
+)
+
+func (*deadlineElement) UnmarshalJSON([]byte) error {
+	deadlineElementCalls++
+	if deadlineElementCalls == 1 {
+		waitPast(time.Now().Add(deadlineElementTimeout))
+	}
+	return nil
+}
+
+func Test_Decoder_SetTimeout(t *testing.T) {
+	src := "[" + strings.Repeat("1,", 2000) + "1]"
+	t.Run("slow input", func(t *testing.T) {
+		dec := json.NewDecoder(&deadlineReader{data: []byte(src), timeout: time.Millisecond})
+		dec.SetTimeout(time.Millisecond)
+		var v []int
+		err := dec.Decode(&v)
+		timeoutErr, ok := err.(*json.DecodeTimeoutError)
+		if !ok {
+			t.Fatalf("expected DecodeTimeoutError but got %v", err)
+		}
+		assertEq(t, "limit", time.Millisecond, timeoutErr.Limit)
+		assertEq(t, "timeout", true, timeoutErr.Timeout())
+		if timeoutErr.Offset >= int64(len(src)) {
+			t.Fatalf("unexpected offset %d", timeoutErr.Offset)
+		}
+	})
+	t.Run("slow decoding", func(t *testing.T) {
+		deadlineElementCalls = 0
+		deadlineElementTimeout = time.Millisecond
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.SetTimeout(time.Millisecond)
+		var v []deadlineElement
+		if _, ok := dec.Decode(&v).(*json.DecodeTimeoutError); !ok {
+			t.Fatal("expected DecodeTimeoutError")
+		}
+		if deadlineElementCalls > 1000 {
+			t.Fatalf("expected decoding to stop early but decoded %d elements", deadlineElementCalls)
+		}
+	})
+	t.Run("in time", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(src + src))
+		dec.SetTimeout(time.Minute)
+		for i := 0; i < 2; i++ {
+			var v []int
+			assertErr(t, dec.Decode(&v))
+			assertEq(t, "length", 2001, len(v))
+		}
+	})
+}
+
+// Test_DecodeSliceGC decodes nested slices while the GC runs. The arrays of the slices are
+// only reachable through the headers written by the decoder, which the GC must see.
+func Test_DecodeSliceGC(t *testing.T) {
+	elems := make([]string, 100)
+	for i := range elems {
+		elems[i] = fmt.Sprintf(`["%d-a","%d-b"]`, i, i)
+	}
+	src := "[" + strings.Join(elems, ",") + "]"
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				runtime.GC()
+			}
+		}
+	}()
+	var mu sync.Mutex
+	var decoded [][][]string
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 500; n++ {
+				var v, w [][]string
+				if err := json.Unmarshal([]byte(src), &v); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := json.NewDecoder(strings.NewReader(src)).Decode(&w); err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				decoded = append(decoded, v, w)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	runtime.GC()
+	// reuse the memory of the arrays if they were freed
+	garbage := make([][]string, 0, 1000)
+	for i := 0; i < cap(garbage); i++ {
+		s := strconv.Itoa(i)
+		garbage = append(garbage, []string{s, s})
+	}
+	for _, v := range decoded {
+		for i, elem := range v {
+			if len(elem) != 2 || elem[0] != fmt.Sprintf("%d-a", i) || elem[1] != fmt.Sprintf("%d-b", i) {
+				t.Fatalf("element %d is corrupted: %v", i, elem)
+			}
+		}
+	}
+	runtime.KeepAlive(garbage)
+}
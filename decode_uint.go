@@ -1,27 +1,62 @@
 package json
 
+import (
+	"math"
+)
+
 type uintDecoder struct {
-	op func(uintptr, uint64)
+	typ  *rtype
+	bits uint // the size of the integers of typ
+	op   func(uintptr, uint64)
 }
 
-func newUintDecoder(op func(uintptr, uint64)) *uintDecoder {
-	return &uintDecoder{op: op}
+func newUintDecoder(typ *rtype, op func(uintptr, uint64)) *uintDecoder {
+	return &uintDecoder{typ: typ, bits: uint(typ.Size()) * 8, op: op}
 }
 
-var pow10u64 = [...]uint64{
-	1e00, 1e01, 1e02, 1e03, 1e04, 1e05, 1e06, 1e07, 1e08, 1e09,
-	1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19,
+// parseUint returns the value of the digits b, or false if b is empty,
+// has other characters or overflows a uint64.
+func parseUint(b []byte) (uint64, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	var n uint64
+	if len(b) < 20 {
+		// up to 19 digits cannot overflow
+		for _, c := range b {
+			digit := uint64(c - '0')
+			if digit > 9 {
+				return 0, false
+			}
+			n = n*10 + digit
+		}
+		return n, true
+	}
+	for _, c := range b {
+		if n > math.MaxUint64/10 {
+			return 0, false
+		}
+		n *= 10
+		digit := uint64(c - '0')
+		if digit > 9 || n+digit < n {
+			return 0, false
+		}
+		n += digit
+	}
+	return n, true
 }
 
-func (d *uintDecoder) parseUint(b []byte) uint64 {
-	maxDigit := len(b)
-	sum := uint64(0)
-	for i := 0; i < maxDigit; i++ {
-		c := uint64(b[i]) - 48
-		digitValue := pow10u64[maxDigit-i-1]
-		sum += c * digitValue
+// store sets the value at p to the number b, which may be negative to report it.
+func (d *uintDecoder) store(b []byte, p uintptr, offset int64) error {
+	n, ok := parseUint(b)
+	if ok && d.bits < 64 {
+		ok = n < uint64(1)<<d.bits
 	}
-	return sum
+	if !ok {
+		return &UnmarshalTypeError{Value: "number " + string(b), Type: rtype2type(d.typ), Offset: offset}
+	}
+	d.op(p, n)
+	return nil
 }
 
 func (d *uintDecoder) setDisallowUnknownFields(_ bool) {}
@@ -32,7 +67,17 @@ func (d *uintDecoder) decodeStreamByte(s *stream) ([]byte, error) {
 		case ' ', '\n', '\t', '\r':
 			s.cursor++
 			continue
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		case '+':
+			if !s.allowLeadingPlus {
+				break
+			}
+			s.cursor++
+			if !numTable[s.char()] {
+				break
+			}
+			continue
+		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			// a negative number is read whole for the error of store
 			start := s.cursor
 			for {
 				s.cursor++
@@ -46,7 +91,13 @@ func (d *uintDecoder) decodeStreamByte(s *stream) ([]byte, error) {
 				}
 				break
 			}
+			if s.strictNumbers || s.exponentIntegers {
+				s.skipNumberTail()
+			}
 			num := s.buf[start:s.cursor]
+			if len(num) < 2 && num[0] == '-' {
+				break
+			}
 			return num, nil
 		case nul:
 			if s.read() {
@@ -58,13 +109,19 @@ func (d *uintDecoder) decodeStreamByte(s *stream) ([]byte, error) {
 	return nil, errUnexpectedEndOfJSON("number(unsigned integer)", s.totalOffset())
 }
 
-func (d *uintDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, error) {
+func (d *uintDecoder) decodeByte(ctx *runtimeContext, cursor int64) ([]byte, int64, error) {
+	buf := ctx.buf
 	buflen := int64(len(buf))
 	for ; cursor < buflen; cursor++ {
 		switch buf[cursor] {
 		case ' ', '\n', '\t', '\r':
 			continue
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		case '+':
+			if !ctx.allowLeadingPlus || !numTable[buf[cursor+1]] {
+				return nil, 0, errInvalidCharacter(buf[cursor], "number(unsigned integer)", cursor)
+			}
+			continue
+		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 			start := cursor
 			cursor++
 			for ; cursor < buflen; cursor++ {
@@ -74,7 +131,13 @@ func (d *uintDecoder) decodeByte(buf []byte, cursor int64) ([]byte, int64, error
 				}
 				break
 			}
+			if ctx.strictNumbers || ctx.exponentIntegers {
+				cursor = skipNumberTail(buf, cursor)
+			}
 			num := buf[start:cursor]
+			if len(num) < 2 && num[0] == '-' {
+				return nil, 0, errInvalidCharacter(buf[cursor], "number(unsigned integer)", cursor)
+			}
 			return num, cursor, nil
 		default:
 			return nil, 0, errInvalidCharacter(buf[cursor], "number(unsigned integer)", cursor)
@@ -88,16 +151,32 @@ func (d *uintDecoder) decodeStream(s *stream, p uintptr) error {
 	if err != nil {
 		return err
 	}
-	d.op(p, d.parseUint(bytes))
-	return nil
+	if s.exponentIntegers {
+		if digits := expandExponent(bytes); digits != nil {
+			if d.store(digits, p, s.totalOffset()) != nil {
+				return &UnmarshalTypeError{Value: "number " + string(bytes), Type: rtype2type(d.typ), Offset: s.totalOffset()}
+			}
+			return nil
+		}
+	}
+	return d.store(bytes, p, s.totalOffset())
 }
 
-func (d *uintDecoder) decode(buf []byte, cursor int64, p uintptr) (int64, error) {
-	bytes, c, err := d.decodeByte(buf, cursor)
+func (d *uintDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	bytes, c, err := d.decodeByte(ctx, cursor)
 	if err != nil {
 		return 0, err
 	}
-	cursor = c
-	d.op(p, d.parseUint(bytes))
-	return cursor, nil
+	if ctx.exponentIntegers {
+		if digits := expandExponent(bytes); digits != nil {
+			if d.store(digits, p, c) != nil {
+				return 0, &UnmarshalTypeError{Value: "number " + string(bytes), Type: rtype2type(d.typ), Offset: c}
+			}
+			return c, nil
+		}
+	}
+	if err := d.store(bytes, p, c); err != nil {
+		return 0, err
+	}
+	return c, nil
 }
@@ -0,0 +1,77 @@
+package json
+
+import (
+	"unsafe"
+)
+
+type unmarshalJSONFromDecoder struct {
+	typ                   *rtype
+	disallowUnknownFields bool
+}
+
+func newUnmarshalJSONFromDecoder(typ *rtype) *unmarshalJSONFromDecoder {
+	return &unmarshalJSONFromDecoder{typ: typ}
+}
+
+func (d *unmarshalJSONFromDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
+	d.disallowUnknownFields = disallowUnknownFields
+}
+
+func (d *unmarshalJSONFromDecoder) unmarshal(s *stream, p uintptr) (*Decoder, error) {
+	dec := &Decoder{
+		s:                     s,
+		disallowUnknownFields: d.disallowUnknownFields,
+		nested:                true,
+	}
+	v := *(*interface{})(unsafe.Pointer(&interfaceHeader{
+		typ: d.typ,
+		ptr: unsafe.Pointer(p),
+	}))
+	if err := v.(UnmarshalerFrom).UnmarshalJSONFrom(dec); err != nil {
+		return nil, err
+	}
+	return dec, nil
+}
+
+func (d *unmarshalJSONFromDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	start := s.totalOffset()
+	dec, err := d.unmarshal(s, p)
+	if err != nil {
+		return err
+	}
+	// the end of the value is not known in a stream, so only an unread
+	// or unfinished value can be told apart
+	if len(dec.tokenStack) != 0 || s.totalOffset() == start {
+		return errIncompleteUnmarshalJSONFrom(d.typ, s.totalOffset())
+	}
+	return nil
+}
+
+func (d *unmarshalJSONFromDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	start := cursor
+	end, err := skipValue(buf, cursor)
+	if err != nil {
+		return 0, err
+	}
+	// a stream over a copy of the value, terminated by nul like any stream buffer
+	src := make([]byte, end-start+1)
+	copy(src, buf[start:end])
+	s := &stream{
+		buf:     src,
+		length:  end - start,
+		allRead: true,
+		offset:  start,
+	}
+	dec, err := d.unmarshal(s, p)
+	if err != nil {
+		return 0, err
+	}
+	s.skipWhiteSpace()
+	if len(dec.tokenStack) != 0 || s.cursor != s.length {
+		return 0, errIncompleteUnmarshalJSONFrom(d.typ, s.totalOffset())
+	}
+	return end, nil
+}
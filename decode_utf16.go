@@ -0,0 +1,157 @@
+package json
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// utf8BOM is the byte order mark some tools, notably on Windows, write at the
+// start of UTF-8 files. It is skipped at the start of the input.
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// utf8BOMLength returns the length of the byte order mark at the start of src, if any.
+func utf8BOMLength(src []byte) int64 {
+	if bytes.HasPrefix(src, utf8BOM) {
+		return int64(len(utf8BOM))
+	}
+	return 0
+}
+
+// detectUTF16 reports whether b, the start of the input, is UTF-16 and in which
+// byte order, and returns the length of its byte order mark.
+// Without a byte order mark the encoding is detected as in RFC 4627 section 3:
+// the first two characters of a JSON text are ASCII, so their zero bytes give it away.
+func detectUTF16(b []byte) (ok bool, bigEndian bool, bom int) {
+	if len(b) < 2 {
+		return false, false, 0
+	}
+	switch {
+	case b[0] == 0xfe && b[1] == 0xff:
+		return true, true, 2
+	case b[0] == 0xff && b[1] == 0xfe:
+		return true, false, 2
+	}
+	if len(b) >= 4 {
+		switch {
+		case b[0] == 0 && b[1] != 0 && b[2] == 0 && b[3] != 0:
+			return true, true, 0
+		case b[0] != 0 && b[1] == 0 && b[2] != 0 && b[3] == 0:
+			return true, false, 0
+		}
+		return false, false, 0
+	}
+	// a single character
+	switch {
+	case b[0] == 0 && b[1] != 0:
+		return true, true, 0
+	case b[0] != 0 && b[1] == 0:
+		return true, false, 0
+	}
+	return false, false, 0
+}
+
+// utf16Reader transcodes UTF-16 read from r to UTF-8.
+// Unpaired surrogates and a trailing odd byte are replaced by U+FFFD.
+type utf16Reader struct {
+	r         io.Reader
+	bigEndian bool
+	in        []byte // read bytes not transcoded yet
+	out       []byte // transcoded bytes not returned yet
+	err       error
+}
+
+// Read fills p as far as the input allows, since the stream takes a short read
+// for the end of the input.
+func (r *utf16Reader) Read(p []byte) (int, error) {
+	buf := make([]byte, readChunkSize)
+	for len(r.out) < len(p) && r.err == nil {
+		n, err := r.r.Read(buf)
+		r.in = append(r.in, buf[:n]...)
+		r.err = err
+		r.transcode()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	if len(r.out) > 0 {
+		return n, nil
+	}
+	return n, r.err
+}
+
+func (r *utf16Reader) transcode() {
+	in := r.in
+	for len(in) >= 2 {
+		c := r.unit(in)
+		if !utf16.IsSurrogate(rune(c)) {
+			r.out = appendRune(r.out, rune(c))
+			in = in[2:]
+			continue
+		}
+		if len(in) < 4 {
+			if r.err == nil {
+				break // wait for the second half of the pair
+			}
+			r.out = appendRune(r.out, utf8.RuneError)
+			in = in[2:]
+			continue
+		}
+		if ch := utf16.DecodeRune(rune(c), rune(r.unit(in[2:]))); ch != utf8.RuneError {
+			r.out = appendRune(r.out, ch)
+			in = in[4:]
+			continue
+		}
+		r.out = appendRune(r.out, utf8.RuneError)
+		in = in[2:]
+	}
+	if len(in) == 1 && r.err != nil {
+		r.out = appendRune(r.out, utf8.RuneError)
+		in = nil
+	}
+	r.in = append(r.in[:0], in...)
+}
+
+func (r *utf16Reader) unit(b []byte) uint16 {
+	if r.bigEndian {
+		return uint16(b[0])<<8 | uint16(b[1])
+	}
+	return uint16(b[1])<<8 | uint16(b[0])
+}
+
+func appendRune(b []byte, r rune) []byte {
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], r)
+	return append(b, buf[:n]...)
+}
+
+// skipUTF8BOM skips a byte order mark at the start of the stream.
+func (s *stream) skipUTF8BOM() {
+	if s.totalOffset() == 0 {
+		s.cursor = utf8BOMLength(s.buf[:s.length])
+	}
+}
+
+// transcodeUTF16 replaces the reader of the stream, which has not been decoded
+// from yet, with one transcoding its input to UTF-8 if it is UTF-16.
+func (s *stream) transcodeUTF16() {
+	if s.totalOffset() != 0 {
+		return
+	}
+	buffered := s.buf[:s.length]
+	ok, bigEndian, bom := detectUTF16(buffered)
+	if !ok {
+		return
+	}
+	rest := make([]byte, len(buffered)-bom)
+	copy(rest, buffered[bom:])
+	r := s.r
+	if s.allRead {
+		r = bytes.NewReader(nil)
+	}
+	s.r = &utf16Reader{r: io.MultiReader(bytes.NewReader(rest), r), bigEndian: bigEndian}
+	s.buf = nil
+	s.length = 0
+	s.allRead = false
+	s.read()
+}
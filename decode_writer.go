@@ -0,0 +1,173 @@
+package json
+
+import (
+	"encoding/base64"
+	"io"
+	"unsafe"
+)
+
+// writerDecoder decodes a base64 encoded string into the io.Writer held by the field,
+// so large binary values are written out in chunks instead of being held in memory.
+type writerDecoder struct {
+	typ *rtype
+}
+
+func newWriterDecoder(typ *rtype) *writerDecoder {
+	return &writerDecoder{typ: typ}
+}
+
+func (d *writerDecoder) setDisallowUnknownFields(_ bool) {}
+
+func (d *writerDecoder) writer(p uintptr, cursor int64) (*base64Writer, error) {
+	w := *(*io.Writer)(unsafe.Pointer(p))
+	if w == nil {
+		return nil, &UnmarshalTypeError{
+			Value:  "string",
+			Type:   rtype2type(d.typ),
+			Offset: cursor,
+		}
+	}
+	return &base64Writer{w: w}, nil
+}
+
+func (d *writerDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	if s.char() == nul {
+		s.read()
+	}
+	switch s.char() {
+	case 'n':
+		return nullBytes(s)
+	case '"':
+	default:
+		return errNotAtBeginningOfValue(s.totalOffset())
+	}
+	w, err := d.writer(p, s.totalOffset())
+	if err != nil {
+		return err
+	}
+	s.cursor++
+	start := s.cursor
+	for {
+		switch s.char() {
+		case '"':
+			if err := w.write(s.buf[start:s.cursor]); err != nil {
+				return err
+			}
+			s.cursor++
+			return w.close()
+		case '\\':
+			// the solidus is the only character of base64 text that may be escaped
+			if err := w.write(s.buf[start:s.cursor]); err != nil {
+				return err
+			}
+			s.cursor++
+			if s.char() == nul {
+				s.read()
+			}
+			if s.char() != '/' {
+				return errInvalidCharacter(s.char(), "base64 string", s.totalOffset())
+			}
+			start = s.cursor
+		case nul:
+			if err := w.write(s.buf[start:s.cursor]); err != nil {
+				return err
+			}
+			s.reset()
+			if !s.read() {
+				return errUnexpectedEndOfJSON("string", s.totalOffset())
+			}
+			start = s.cursor
+			continue
+		}
+		s.cursor++
+	}
+}
+
+func (d *writerDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	switch buf[cursor] {
+	case 'n':
+		buflen := int64(len(buf))
+		if cursor+3 >= buflen {
+			return 0, errUnexpectedEndOfJSON("null", cursor)
+		}
+		if buf[cursor+1] != 'u' {
+			return 0, errInvalidCharacter(buf[cursor+1], "null", cursor)
+		}
+		if buf[cursor+2] != 'l' {
+			return 0, errInvalidCharacter(buf[cursor+2], "null", cursor)
+		}
+		if buf[cursor+3] != 'l' {
+			return 0, errInvalidCharacter(buf[cursor+3], "null", cursor)
+		}
+		cursor += 4
+		return cursor, nil
+	case '"':
+	default:
+		return 0, errNotAtBeginningOfValue(cursor)
+	}
+	w, err := d.writer(p, cursor)
+	if err != nil {
+		return 0, err
+	}
+	cursor++
+	start := cursor
+	for {
+		switch buf[cursor] {
+		case '"':
+			if err := w.write(buf[start:cursor]); err != nil {
+				return 0, err
+			}
+			cursor++
+			return cursor, w.close()
+		case '\\':
+			if err := w.write(buf[start:cursor]); err != nil {
+				return 0, err
+			}
+			cursor++
+			if buf[cursor] != '/' {
+				return 0, errInvalidCharacter(buf[cursor], "base64 string", cursor)
+			}
+			start = cursor
+		case nul:
+			return 0, errUnexpectedEndOfJSON("string", cursor)
+		}
+		cursor++
+	}
+}
+
+// base64Writer decodes base64 text written in arbitrary pieces and writes the result to w.
+type base64Writer struct {
+	w       io.Writer
+	pending []byte
+	decoded []byte
+}
+
+func (b *base64Writer) write(src []byte) error {
+	b.pending = append(b.pending, src...)
+	n := len(b.pending) / 4 * 4
+	if n == 0 {
+		return nil
+	}
+	if size := base64.StdEncoding.DecodedLen(n); cap(b.decoded) < size {
+		b.decoded = make([]byte, size)
+	}
+	written, err := base64.StdEncoding.Decode(b.decoded[:cap(b.decoded)], b.pending[:n])
+	if err != nil {
+		return err
+	}
+	if _, err := b.w.Write(b.decoded[:written]); err != nil {
+		return err
+	}
+	b.pending = b.pending[:copy(b.pending, b.pending[n:])]
+	return nil
+}
+
+func (b *base64Writer) close() error {
+	if len(b.pending) != 0 {
+		return base64.CorruptInputError(len(b.pending))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package json
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// DeprecatedField describes a member of decoded input that was stored
+// in a struct field tagged with the "deprecated" option.
+type DeprecatedField struct {
+	Struct reflect.Type // the struct type containing the field
+	Field  string       // the JSON name of the field
+	Type   reflect.Type // the type of the field
+	Offset int64        // the value of the member starts after reading Offset bytes
+}
+
+type deprecatedFieldHookHolder struct {
+	fn func(DeprecatedField)
+}
+
+var deprecatedFieldHookValue atomic.Value
+
+// SetDeprecatedFieldHook registers fn to be called whenever decoded input contains
+// a member stored in a field tagged with the "deprecated" option, for example
+// to find the producers still sending it. fn is called synchronously from the
+// decoding goroutine, so it must be safe for concurrent use.
+// SetDeprecatedFieldHook(nil) removes the hook.
+func SetDeprecatedFieldHook(fn func(DeprecatedField)) {
+	deprecatedFieldHookValue.Store(deprecatedFieldHookHolder{fn: fn})
+}
+
+func deprecatedFieldHook() func(DeprecatedField) {
+	holder, _ := deprecatedFieldHookValue.Load().(deprecatedFieldHookHolder)
+	return holder.fn
+}
+
+func isDeprecatedField(opts []string) bool {
+	for _, opt := range opts[1:] {
+		if opt == "deprecated" {
+			return true
+		}
+	}
+	return false
+}
+
+// deprecatedDecoder reports the decoding of a deprecated field to the hook.
+type deprecatedDecoder struct {
+	dec   decoder
+	field DeprecatedField
+}
+
+func newDeprecatedDecoder(dec decoder, structType *rtype, key string, typ *rtype) *deprecatedDecoder {
+	return &deprecatedDecoder{
+		dec: dec,
+		field: DeprecatedField{
+			Struct: rtype2type(structType),
+			Field:  key,
+			Type:   rtype2type(typ),
+		},
+	}
+}
+
+func (d *deprecatedDecoder) report(offset int64) {
+	if hook := deprecatedFieldHook(); hook != nil {
+		field := d.field
+		field.Offset = offset
+		hook(field)
+	}
+}
+
+func (d *deprecatedDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
+	d.dec.setDisallowUnknownFields(disallowUnknownFields)
+}
+
+func (d *deprecatedDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	d.report(s.totalOffset())
+	return d.dec.decodeStream(s, p)
+}
+
+func (d *deprecatedDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	d.report(cursor)
+	return d.dec.decode(ctx, cursor, p)
+}
@@ -0,0 +1,42 @@
+package json_test
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_DeprecatedFieldHook(t *testing.T) {
+	type order struct {
+		ID       int    `json:"id"`
+		LegacyID string `json:"legacy_id,deprecated"`
+	}
+	var (
+		mu     sync.Mutex
+		fields []json.DeprecatedField
+	)
+	json.SetDeprecatedFieldHook(func(f json.DeprecatedField) {
+		mu.Lock()
+		defer mu.Unlock()
+		fields = append(fields, f)
+	})
+	defer json.SetDeprecatedFieldHook(nil)
+
+	src := `{"id":1, "legacy_id":"a"}`
+	var v order
+	assertErr(t, json.Unmarshal([]byte(src), &v))
+	assertEq(t, "decoded", "a", v.LegacyID)
+	assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+	assertErr(t, json.Unmarshal([]byte(`{"id":2}`), &v))
+
+	assertEq(t, "reports", 2, len(fields))
+	for _, f := range fields {
+		assertEq(t, "struct", reflect.TypeOf(order{}), f.Struct)
+		assertEq(t, "field", "legacy_id", f.Field)
+		assertEq(t, "type", reflect.TypeOf(""), f.Type)
+		assertEq(t, "offset", int64(strings.Index(src, `"a"`)), f.Offset)
+	}
+}
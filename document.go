@@ -0,0 +1,594 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// NodeKind describes the kind of JSON value held by a Node.
+type NodeKind int
+
+const (
+	NullNode NodeKind = iota
+	BoolNode
+	NumberNode
+	StringNode
+	ArrayNode
+	ObjectNode
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case NullNode:
+		return "null"
+	case BoolNode:
+		return "bool"
+	case NumberNode:
+		return "number"
+	case StringNode:
+		return "string"
+	case ArrayNode:
+		return "array"
+	case ObjectNode:
+		return "object"
+	}
+	return ""
+}
+
+// Document is a JSON (or JSONC) document parsed into a tree of nodes that
+// keeps every comment and every run of whitespace attached to the value it
+// surrounds. Serializing an unmodified Document reproduces the input byte for byte.
+type Document struct {
+	root *Node
+}
+
+// Node is a value inside a Document.
+type Node struct {
+	kind    NodeKind
+	literal []byte // raw text of scalar values
+	members []*Member
+	elems   []*Node
+
+	before      []byte // comments and whitespace before the value
+	after       []byte // comments and whitespace after the value
+	inner       []byte // comments and whitespace before the closing bracket of an object or array
+	hasTrailing bool   // object or array ends with a trailing comma
+}
+
+// Member is a key/value pair of an object node.
+type Member struct {
+	key      string
+	rawKey   []byte
+	before   []byte // comments and whitespace before the key
+	afterKey []byte // comments and whitespace between the key and the colon
+	value    *Node
+}
+
+// Key returns the decoded key of the member.
+func (m *Member) Key() string { return m.key }
+
+// Value returns the value of the member.
+func (m *Member) Value() *Node { return m.value }
+
+// ParseDocument parses data as JSON with optional // line and /* block */ comments
+// and trailing commas, retaining comments and whitespace.
+func ParseDocument(data []byte) (*Document, error) {
+	p := &documentParser{buf: data}
+	root, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	trivia, err := p.trivia()
+	if err != nil {
+		return nil, err
+	}
+	root.after = append(root.after, trivia...)
+	if p.cursor < len(p.buf) {
+		return nil, p.errorf("invalid character %c after top-level value", p.buf[p.cursor])
+	}
+	return &Document{root: root}, nil
+}
+
+// Root returns the top-level value of the document.
+func (d *Document) Root() *Node {
+	return d.root
+}
+
+// Bytes serializes the document including all retained comments and whitespace.
+func (d *Document) Bytes() []byte {
+	return d.root.appendTo(nil)
+}
+
+// Kind returns the kind of the value.
+func (n *Node) Kind() NodeKind {
+	return n.kind
+}
+
+// Raw returns the JSON text of the value, without the comments and
+// whitespace surrounding it. Comments inside objects and arrays are removed.
+func (n *Node) Raw() []byte {
+	return n.appendValue(nil, false)
+}
+
+// Decode stores the value of the node in the value pointed to by v, in the same manner as Unmarshal.
+func (n *Node) Decode(v interface{}) error {
+	return Unmarshal(n.Raw(), v)
+}
+
+// Len returns the number of members of an object or the number of elements of an array.
+func (n *Node) Len() int {
+	switch n.kind {
+	case ObjectNode:
+		return len(n.members)
+	case ArrayNode:
+		return len(n.elems)
+	}
+	return 0
+}
+
+// Members returns the members of an object node in document order.
+func (n *Node) Members() []*Member {
+	return n.members
+}
+
+// Get returns the value of the member named key, or nil if the node is not an object or has no such member.
+func (n *Node) Get(key string) *Node {
+	if m := n.member(key); m != nil {
+		return m.value
+	}
+	return nil
+}
+
+// Index returns the i'th element of an array node, or nil if it does not exist.
+func (n *Node) Index(i int) *Node {
+	if n.kind != ArrayNode || i < 0 || i >= len(n.elems) {
+		return nil
+	}
+	return n.elems[i]
+}
+
+// SetValue replaces the value of the node with the JSON encoding of v.
+// Comments and whitespace surrounding the node are kept.
+func (n *Node) SetValue(v interface{}) error {
+	value, err := newNode(v)
+	if err != nil {
+		return err
+	}
+	n.kind = value.kind
+	n.literal = value.literal
+	n.members = value.members
+	n.elems = value.elems
+	n.inner = value.inner
+	n.hasTrailing = value.hasTrailing
+	return nil
+}
+
+// Set sets the member named key of an object node to the JSON encoding of v.
+// An existing member keeps its comments; a new member is appended after the
+// last member, indented like it.
+func (n *Node) Set(key string, v interface{}) error {
+	if n.kind != ObjectNode {
+		return fmt.Errorf("json: cannot set key %q on %s node", key, n.kind)
+	}
+	if m := n.member(key); m != nil {
+		return m.value.SetValue(v)
+	}
+	value, err := newNode(v)
+	if err != nil {
+		return err
+	}
+	rawKey, err := Marshal(key)
+	if err != nil {
+		return err
+	}
+	m := &Member{key: key, rawKey: rawKey, value: value}
+	if len(n.members) > 0 {
+		last := n.members[len(n.members)-1]
+		m.before = lineIndent(last.before)
+		value.before = whitespaceOnly(last.value.before)
+	}
+	n.members = append(n.members, m)
+	return nil
+}
+
+// Delete removes the member named key from an object node and reports whether it existed.
+// The comments of the member are removed with it, including those following it on its line.
+func (n *Node) Delete(key string) bool {
+	for i, m := range n.members {
+		if m.key == key {
+			// the trivia before a member may start with the end of the line of the
+			// preceding member, which is kept, and the trivia after m with the end
+			// of the line of m, which is not
+			line := m.before[:trailingLen(m.before)]
+			next := &n.inner
+			if i+1 < len(n.members) {
+				next = &n.members[i+1].before
+			}
+			rest := (*next)[trailingLen(*next):]
+			if len(rest) == 0 && bytes.Contains(line, []byte("//")) {
+				rest = []byte{'\n'}
+			}
+			*next = append(append([]byte{}, line...), rest...)
+			n.members = append(n.members[:i], n.members[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Append appends the JSON encoding of v to an array node.
+func (n *Node) Append(v interface{}) error {
+	if n.kind != ArrayNode {
+		return fmt.Errorf("json: cannot append to %s node", n.kind)
+	}
+	value, err := newNode(v)
+	if err != nil {
+		return err
+	}
+	if len(n.elems) > 0 {
+		value.before = lineIndent(n.elems[len(n.elems)-1].before)
+	}
+	n.elems = append(n.elems, value)
+	return nil
+}
+
+func (n *Node) member(key string) *Member {
+	if n.kind != ObjectNode {
+		return nil
+	}
+	for _, m := range n.members {
+		if m.key == key {
+			return m
+		}
+	}
+	return nil
+}
+
+func newNode(v interface{}) (*Node, error) {
+	b, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	p := &documentParser{buf: b, strict: true}
+	return p.parseValue()
+}
+
+func (n *Node) appendTo(b []byte) []byte {
+	b = append(b, n.before...)
+	b = n.appendValue(b, true)
+	return append(b, n.after...)
+}
+
+func (n *Node) appendValue(b []byte, withTrivia bool) []byte {
+	switch n.kind {
+	case ObjectNode:
+		b = append(b, '{')
+		for i, m := range n.members {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if withTrivia {
+				b = append(b, m.before...)
+			}
+			b = append(b, m.rawKey...)
+			if withTrivia {
+				b = append(b, m.afterKey...)
+			}
+			b = append(b, ':')
+			if withTrivia {
+				b = m.value.appendTo(b)
+			} else {
+				b = m.value.appendValue(b, false)
+			}
+		}
+		if withTrivia {
+			if n.hasTrailing && len(n.members) > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, n.inner...)
+		}
+		return append(b, '}')
+	case ArrayNode:
+		b = append(b, '[')
+		for i, elem := range n.elems {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			if withTrivia {
+				b = elem.appendTo(b)
+			} else {
+				b = elem.appendValue(b, false)
+			}
+		}
+		if withTrivia {
+			if n.hasTrailing && len(n.elems) > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, n.inner...)
+		}
+		return append(b, ']')
+	}
+	return append(b, n.literal...)
+}
+
+// lineIndent returns the whitespace following the last newline of trivia,
+// preceded by that newline. It is used to lay out values added to a document.
+func lineIndent(trivia []byte) []byte {
+	idx := bytes.LastIndexByte(trivia, '\n')
+	if idx < 0 {
+		return whitespaceOnly(trivia)
+	}
+	return whitespaceOnly(trivia[idx:])
+}
+
+// trailingLen returns the length of the start of trivia that is on the line of the
+// preceding value, up to the first newline outside of block comments.
+// Trivia without a newline is on the line of the following value.
+func trailingLen(trivia []byte) int {
+	for i := 0; i < len(trivia); i++ {
+		switch {
+		case trivia[i] == '\n':
+			return i
+		case bytes.HasPrefix(trivia[i:], []byte("/*")):
+			end := bytes.Index(trivia[i+2:], []byte("*/"))
+			if end < 0 {
+				return 0
+			}
+			i += end + 3
+		}
+	}
+	return 0
+}
+
+func whitespaceOnly(trivia []byte) []byte {
+	for _, c := range trivia {
+		if !isWhiteSpace[c] {
+			return nil
+		}
+	}
+	return append([]byte{}, trivia...)
+}
+
+type documentParser struct {
+	buf    []byte
+	cursor int
+	strict bool // reject comments and trailing commas
+}
+
+func (p *documentParser) errorf(format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{msg: fmt.Sprintf(format, args...), Offset: int64(p.cursor)}
+}
+
+// trivia consumes whitespace and comments.
+func (p *documentParser) trivia() ([]byte, error) {
+	start := p.cursor
+	for p.cursor < len(p.buf) {
+		c := p.buf[p.cursor]
+		if isWhiteSpace[c] {
+			p.cursor++
+			continue
+		}
+		if c != '/' || p.cursor+1 >= len(p.buf) {
+			break
+		}
+		if p.strict {
+			return nil, p.errorf("invalid character / looking for beginning of value")
+		}
+		switch p.buf[p.cursor+1] {
+		case '/':
+			idx := bytes.IndexByte(p.buf[p.cursor:], '\n')
+			if idx < 0 {
+				p.cursor = len(p.buf)
+			} else {
+				p.cursor += idx + 1
+			}
+		case '*':
+			idx := bytes.Index(p.buf[p.cursor+2:], []byte("*/"))
+			if idx < 0 {
+				return nil, p.errorf("unexpected end of JSON input for comment")
+			}
+			p.cursor += idx + 4
+		default:
+			return nil, p.errorf("invalid character %c after /", p.buf[p.cursor+1])
+		}
+	}
+	return p.buf[start:p.cursor], nil
+}
+
+func (p *documentParser) parseValue() (*Node, error) {
+	before, err := p.trivia()
+	if err != nil {
+		return nil, err
+	}
+	if p.cursor >= len(p.buf) {
+		return nil, p.errorf("unexpected end of JSON input for value")
+	}
+	node := &Node{before: before}
+	switch c := p.buf[p.cursor]; c {
+	case '{':
+		err = p.parseObject(node)
+	case '[':
+		err = p.parseArray(node)
+	case '"':
+		node.kind = StringNode
+		node.literal, err = p.parseString()
+	case 't', 'f', 'n':
+		err = p.parseLiteral(node)
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		node.kind = NumberNode
+		err = p.parseNumber(node)
+	default:
+		return nil, p.errorf("invalid character %c looking for beginning of value", c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (p *documentParser) parseObject(node *Node) error {
+	node.kind = ObjectNode
+	node.members = []*Member{}
+	p.cursor++
+	for {
+		before, err := p.trivia()
+		if err != nil {
+			return err
+		}
+		if p.cursor >= len(p.buf) {
+			return p.errorf("unexpected end of JSON input for object")
+		}
+		if p.buf[p.cursor] == '}' {
+			if len(node.members) == 0 || (node.hasTrailing && !p.strict) {
+				node.inner = before
+				p.cursor++
+				return nil
+			}
+			return p.errorf("invalid character } looking for beginning of object key")
+		}
+		if p.buf[p.cursor] != '"' {
+			return p.errorf("invalid character %c looking for beginning of object key", p.buf[p.cursor])
+		}
+		keyStart := p.cursor
+		rawKey, err := p.parseString()
+		if err != nil {
+			return err
+		}
+		body := rawKey[1 : len(rawKey)-1]
+		if err := checkStrictString(body, int64(keyStart+1), false); err != nil {
+			return err
+		}
+		key := string(unescapeString(body))
+		afterKey, err := p.trivia()
+		if err != nil {
+			return err
+		}
+		if p.cursor >= len(p.buf) || p.buf[p.cursor] != ':' {
+			return p.errorf("expected colon after object key")
+		}
+		p.cursor++
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		if value.after, err = p.trivia(); err != nil {
+			return err
+		}
+		node.members = append(node.members, &Member{
+			key:      key,
+			rawKey:   rawKey,
+			before:   before,
+			afterKey: afterKey,
+			value:    value,
+		})
+		if p.cursor >= len(p.buf) {
+			return p.errorf("unexpected end of JSON input for object")
+		}
+		switch p.buf[p.cursor] {
+		case ',':
+			p.cursor++
+			node.hasTrailing = true
+		case '}':
+			p.cursor++
+			node.hasTrailing = false
+			return nil
+		default:
+			return p.errorf("invalid character %c after object key:value pair", p.buf[p.cursor])
+		}
+	}
+}
+
+func (p *documentParser) parseArray(node *Node) error {
+	node.kind = ArrayNode
+	node.elems = []*Node{}
+	p.cursor++
+	for {
+		start := p.cursor
+		before, err := p.trivia()
+		if err != nil {
+			return err
+		}
+		if p.cursor >= len(p.buf) {
+			return p.errorf("unexpected end of JSON input for array")
+		}
+		if p.buf[p.cursor] == ']' {
+			if len(node.elems) == 0 || (node.hasTrailing && !p.strict) {
+				node.inner = before
+				p.cursor++
+				return nil
+			}
+			return p.errorf("invalid character ] looking for beginning of value")
+		}
+		p.cursor = start
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		if value.after, err = p.trivia(); err != nil {
+			return err
+		}
+		node.elems = append(node.elems, value)
+		if p.cursor >= len(p.buf) {
+			return p.errorf("unexpected end of JSON input for array")
+		}
+		switch p.buf[p.cursor] {
+		case ',':
+			p.cursor++
+			node.hasTrailing = true
+		case ']':
+			p.cursor++
+			node.hasTrailing = false
+			return nil
+		default:
+			return p.errorf("invalid character %c after array element", p.buf[p.cursor])
+		}
+	}
+}
+
+func (p *documentParser) parseString() ([]byte, error) {
+	start := p.cursor
+	p.cursor++
+	for p.cursor < len(p.buf) {
+		switch p.buf[p.cursor] {
+		case '\\':
+			p.cursor++
+		case '"':
+			p.cursor++
+			return p.buf[start:p.cursor], nil
+		}
+		p.cursor++
+	}
+	return nil, p.errorf("unexpected end of JSON input for string")
+}
+
+func (p *documentParser) parseLiteral(node *Node) error {
+	for _, lit := range []struct {
+		text string
+		kind NodeKind
+	}{
+		{"true", BoolNode},
+		{"false", BoolNode},
+		{"null", NullNode},
+	} {
+		if bytes.HasPrefix(p.buf[p.cursor:], []byte(lit.text)) {
+			node.kind = lit.kind
+			node.literal = p.buf[p.cursor : p.cursor+len(lit.text)]
+			p.cursor += len(lit.text)
+			return nil
+		}
+	}
+	return p.errorf("invalid character %c looking for beginning of value", p.buf[p.cursor])
+}
+
+func (p *documentParser) parseNumber(node *Node) error {
+	start := p.cursor
+	p.cursor++
+	for p.cursor < len(p.buf) && (floatTable[p.buf[p.cursor]] || p.buf[p.cursor] == '-') {
+		p.cursor++
+	}
+	node.literal = p.buf[start:p.cursor]
+	if !validNumber(node.literal) {
+		return p.errorf("invalid number literal %s", node.literal)
+	}
+	return nil
+}
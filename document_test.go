@@ -0,0 +1,111 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_Document(t *testing.T) {
+	src := `// service configuration
+{
+  "name": "api", // service name
+  /* listening port */
+  "port": 8080,
+  "tags": [
+    "a", // first
+    "b",
+  ],
+}
+`
+	t.Run("round trip", func(t *testing.T) {
+		doc, err := json.ParseDocument([]byte(src))
+		assertErr(t, err)
+		assertEq(t, "round trip", src, string(doc.Bytes()))
+	})
+	t.Run("mutate", func(t *testing.T) {
+		doc, err := json.ParseDocument([]byte(src))
+		assertErr(t, err)
+		root := doc.Root()
+		assertEq(t, "kind", json.ObjectNode, root.Kind())
+		assertErr(t, root.Get("port").SetValue(9090))
+		assertErr(t, root.Set("debug", true))
+		assertErr(t, root.Get("tags").Append("c"))
+		expected := `// service configuration
+{
+  "name": "api", // service name
+  /* listening port */
+  "port": 9090,
+  "tags": [
+    "a", // first
+    "b",
+    "c",
+  ],
+  "debug": true,
+}
+`
+		assertEq(t, "mutated", expected, string(doc.Bytes()))
+	})
+	t.Run("raw and decode", func(t *testing.T) {
+		doc, err := json.ParseDocument([]byte(src))
+		assertErr(t, err)
+		assertEq(t, "raw", `["a","b"]`, string(doc.Root().Get("tags").Raw()))
+		var v struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		}
+		assertErr(t, doc.Root().Decode(&v))
+		assertEq(t, "name", "api", v.Name)
+		assertEq(t, "port", 8080, v.Port)
+	})
+	t.Run("delete", func(t *testing.T) {
+		doc, err := json.ParseDocument([]byte(`{"a": 1, /* b */ "b": 2}`))
+		assertErr(t, err)
+		assertEq(t, "deleted", true, doc.Root().Delete("a"))
+		assertEq(t, "result", `{ /* b */ "b": 2}`, string(doc.Bytes()))
+	})
+	t.Run("delete with comments", func(t *testing.T) {
+		src := `{
+  // a
+  "a": 1, // after a
+  /* b */
+  "b": 2, // after b
+  "c": 3 // after c
+}`
+		for _, tc := range []struct {
+			key      string
+			expected string
+		}{
+			{"a", "{\n  /* b */\n  \"b\": 2, // after b\n  \"c\": 3 // after c\n}"},
+			{"b", "{\n  // a\n  \"a\": 1, // after a\n  \"c\": 3 // after c\n}"},
+			{"c", "{\n  // a\n  \"a\": 1, // after a\n  /* b */\n  \"b\": 2 // after b\n}"},
+		} {
+			doc, err := json.ParseDocument([]byte(src))
+			assertErr(t, err)
+			assertEq(t, "deleted", true, doc.Root().Delete(tc.key))
+			assertEq(t, "result", tc.expected, string(doc.Bytes()))
+		}
+	})
+	t.Run("escaped key", func(t *testing.T) {
+		doc, err := json.ParseDocument([]byte(`{"a\/b": 1, "\u00e9": 2}`))
+		assertErr(t, err)
+		assertNeq(t, "slash", (*json.Node)(nil), doc.Root().Get("a/b"))
+		assertNeq(t, "unicode", (*json.Node)(nil), doc.Root().Get("é"))
+		for _, src := range []string{`{"\x41": 1}`, `{"\a": 1}`} {
+			_, err := json.ParseDocument([]byte(src))
+			assertNeq(t, src, nil, err)
+		}
+	})
+	t.Run("invalid number", func(t *testing.T) {
+		for _, src := range []string{`[01]`, `[+1]`, `[0x1p3]`, `[Inf]`, `[1_0]`, `[1.]`} {
+			_, err := json.ParseDocument([]byte(src))
+			assertNeq(t, src, nil, err)
+		}
+		_, err := json.ParseDocument([]byte(`[1.5e3, -0, 2E-2]`))
+		assertErr(t, err)
+	})
+	t.Run("syntax error", func(t *testing.T) {
+		_, err := json.ParseDocument([]byte(`{"a": 1 /* unterminated`))
+		assertNeq(t, "error", nil, err)
+	})
+}
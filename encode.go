@@ -1,12 +1,16 @@
 package json
 
 import (
-	"bytes"
+	"context"
 	"encoding"
+	"encoding/base64"
+	"hash"
 	"io"
 	"reflect"
+	"runtime"
 	"strconv"
 	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -17,11 +21,37 @@ type Encoder struct {
 	pool                           sync.Pool
 	enabledIndent                  bool
 	enabledHTMLEscape              bool
+	enabledLineTerminatorEscape    bool
+	enabledSyncMapKeySort          bool
+	enabledMapKeySort              bool
+	enabledMapKeyStringify         bool
+	enabledSync                    bool
+	enabledNullOmit                bool
+	trustedRaw                     bool // whether marshaled bytes are written without being checked
+	ignorePromoted                 bool // whether marshaler methods promoted from embedded fields are ignored
+	binaryMarshalers               bool // whether types with only MarshalBinary are encoded as base64 strings
+	stringers                      bool // whether unsupported types with a String method are encoded as its string
+	errorMessages                  bool // whether values of error interface types are encoded as their message
+	v2                             bool // whether the encoder follows the semantics of SetV2Semantics
+	fieldOrder                     FieldOrder
+	mapKeyCompare                  func(a, b string) bool
+	mapKeyPriority                 map[string]int
+	syncMapKeyFunc                 func(interface{}) (string, error)
+	keyTransformer                 func(string) string
 	prefix                         []byte
 	indentStr                      []byte
+	indentCache                    []byte // prefix followed by indentStr repeated, grown as needed
 	indent                         int
+	indentOffset                   int        // added to the indents of the opcodes of a recursive struct being run
+	opcodes                        *opcodeMap // the compiled codes by type, of the API that created the encoder
 	structTypeToCompiledCode       map[uintptr]*compiledCode
 	structTypeToCompiledIndentCode map[uintptr]*compiledCode
+	ctx                            context.Context
+	opCount                        int
+	compiled                       bool          // whether the last top-level value needed compiling
+	tokens                         *tokenWriter  // the value written by the MarshalJSONTo method being called
+	hookValues                     []interface{} // the copies encoded after their BeforeMarshalJSON method, kept alive
+	hash                           hash.Hash     // receives the output written to w, set by SetHash
 }
 
 type compiledCode struct {
@@ -30,6 +60,9 @@ type compiledCode struct {
 
 const (
 	bufSize = 1024
+
+	// ctxCheckInterval is the number of opcodes run between checks of ctx.Done().
+	ctxCheckInterval = 1024
 )
 
 type opcodeMap struct {
@@ -41,15 +74,29 @@ type opcodeSet struct {
 	code       sync.Pool
 }
 
-func (m *opcodeMap) get(k uintptr) *opcodeSet {
+// opcodeKey identifies compiled codes by the type they encode and the mode they were compiled in.
+// The options of Encoder are applied when the codes run, and indentation has codes of its own in the set.
+type opcodeKey struct {
+	typeptr          uintptr
+	v2               bool
+	ignorePromoted   bool
+	binaryMarshalers bool
+	stringers        bool
+	errorMessages    bool
+	fieldOrder       FieldOrder
+}
+
+func (m *opcodeMap) get(k opcodeKey) *opcodeSet {
 	if v, ok := m.Load(k); ok {
 		return v.(*opcodeSet)
 	}
 	return nil
 }
 
-func (m *opcodeMap) set(k uintptr, op *opcodeSet) {
-	m.Store(k, op)
+func (m *opcodeMap) set(k opcodeKey, op *opcodeSet) {
+	if _, loaded := m.LoadOrStore(k, op); !loaded {
+		countStat(&stats.EncoderCacheSize)
+	}
 }
 
 var (
@@ -57,27 +104,43 @@ var (
 	codePool        sync.Pool
 	cachedOpcode    opcodeMap
 	marshalJSONType reflect.Type
+	marshalToType   reflect.Type
+	appenderType    reflect.Type
 	marshalTextType reflect.Type
+	readerType      *rtype
 )
 
 func init() {
 	encPool = sync.Pool{
 		New: func() interface{} {
-			return &Encoder{
+			countStat(&stats.EncoderPoolMisses)
+			enc := &Encoder{
 				buf:                            make([]byte, 0, bufSize),
 				pool:                           encPool,
 				structTypeToCompiledCode:       map[uintptr]*compiledCode{},
 				structTypeToCompiledIndentCode: map[uintptr]*compiledCode{},
 			}
+			return enc
 		},
 	}
 	cachedOpcode = opcodeMap{}
 	marshalJSONType = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	marshalToType = reflect.TypeOf((*MarshalerTo)(nil)).Elem()
+	appenderType = reflect.TypeOf((*Appender)(nil)).Elem()
 	marshalTextType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	readerType = type2rtype(reflect.TypeOf((*io.Reader)(nil)).Elem())
 }
 
+// An EncodeOption configures an Encoder, for the functions that create one internally:
+//
+//	err := json.MarshalToFile(path, v, 0644, func(enc *json.Encoder) {
+//		enc.SetIndent("", "  ")
+//	})
+type EncodeOption func(*Encoder)
+
 // NewEncoder returns a new encoder that writes to w.
 func NewEncoder(w io.Writer) *Encoder {
+	countStat(&stats.EncoderPoolGets)
 	enc := encPool.Get().(*Encoder)
 	enc.w = w
 	enc.reset()
@@ -87,16 +150,36 @@ func NewEncoder(w io.Writer) *Encoder {
 // Encode writes the JSON encoding of v to the stream, followed by a newline character.
 //
 // See the documentation for Marshal for details about the conversion of Go values to JSON.
+//
+// Called from a MarshalJSONTo method, Encode writes v as the next value of the one
+// the method writes, without a newline.
 func (e *Encoder) Encode(v interface{}) error {
+	if e.tokens != nil {
+		return e.encodeToken(v)
+	}
 	if err := e.encode(v); err != nil {
 		return err
 	}
-	if _, err := e.w.Write(e.buf); err != nil {
+	if err := e.writeStream(e.buf); err != nil {
 		return err
 	}
+	if syncer, ok := e.w.(interface{ Sync() error }); ok && e.enabledSync {
+		return syncer.Sync()
+	}
 	return nil
 }
 
+// EncodeContext is like Encode but stops with ctx.Err() once ctx is done.
+// The context is checked periodically while values are encoded, so a long encode of a huge value can be aborted.
+func (e *Encoder) EncodeContext(ctx context.Context, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	e.ctx = ctx
+	defer func() { e.ctx = nil }()
+	return e.Encode(v)
+}
+
 // SetEscapeHTML specifies whether problematic HTML characters should be escaped inside JSON quoted strings.
 // The default behavior is to escape &, <, and > to \u0026, \u003c, and \u003e to avoid certain safety problems that can arise when embedding JSON in HTML.
 //
@@ -105,6 +188,170 @@ func (e *Encoder) SetEscapeHTML(on bool) {
 	e.enabledHTMLEscape = on
 }
 
+// SetV2Semantics specifies whether the encoder follows the defaults of the encoding/json/v2
+// proposal described at the SetV2Semantics function, which gives the default of NewEncoder.
+// It also sets whether HTML characters are escaped, so SetEscapeHTML must be called after it.
+func (e *Encoder) SetV2Semantics(on bool) {
+	e.v2 = on
+	e.enabledHTMLEscape = !on
+}
+
+// SetEscapeLineTerminators specifies whether U+2028 (LINE SEPARATOR) and U+2029 (PARAGRAPH SEPARATOR) should be escaped inside JSON quoted strings.
+// They are valid in JSON but terminate lines in JavaScript, so output inlined into <script> blocks or served as JSONP must escape them.
+//
+// HTML escaping always escapes them as well; this setting makes the behavior available when SetEscapeHTML(false) has been called.
+func (e *Encoder) SetEscapeLineTerminators(on bool) {
+	e.enabledLineTerminatorEscape = on
+}
+
+// SetHash sets a hash that the output written to the stream is also written to, in the same pass,
+// so that the digest of a payload is computed without reading or encoding it again, such as for
+// an ETag or Content-Digest header:
+//
+//	h := sha256.New()
+//	enc := json.NewEncoder(w)
+//	enc.SetHash(h)
+//	err := enc.Encode(v)
+//	digest := h.Sum(nil)
+//
+// The hash is not reset between calls to Encode. For EncodeFrame it receives the content of the
+// messages, without their headers. SetHash(nil) removes the hash.
+func (e *Encoder) SetHash(h hash.Hash) {
+	e.hash = h
+}
+
+// writeStream writes b to the stream, and to the hash set by SetHash.
+func (e *Encoder) writeStream(b []byte) error {
+	if _, err := e.w.Write(b); err != nil {
+		return err
+	}
+	if e.hash != nil {
+		e.hash.Write(b)
+	}
+	return nil
+}
+
+// SetSync specifies whether Encode calls the Sync method of the writer, such as of an *os.File,
+// after writing each value, so the value is on stable storage when Encode returns.
+// Writers without a Sync method are not affected.
+func (e *Encoder) SetSync(on bool) {
+	e.enabledSync = on
+}
+
+// SetOmitNull specifies whether the members of objects that would encode as null are omitted,
+// for APIs that forbid explicit nulls: struct fields holding nil pointers, nil interfaces and nil
+// RawMessage values, whatever their tags, and the null members produced by an ObjectFunc.
+// Nil slices and maps encode as [] and {} and are kept, as are map entries.
+func (e *Encoder) SetOmitNull(on bool) {
+	e.enabledNullOmit = on
+}
+
+// SetIgnorePromotedMarshalers specifies whether the MarshalJSON, MarshalText and other marshaler
+// methods a struct type promotes from its embedded fields are ignored. By default, as in
+// encoding/json, such a method encodes the whole struct, so embedding a type with a MarshalJSON
+// method replaces the fields of the outer struct in the output. Methods declared by the struct
+// type itself are always used.
+func (e *Encoder) SetIgnorePromotedMarshalers(on bool) {
+	e.ignorePromoted = on
+}
+
+// SetBinaryMarshalers specifies whether values of types implementing encoding.BinaryMarshaler,
+// but none of the JSON and text marshaler interfaces, are encoded as the base64 string of
+// their MarshalBinary method, as []byte values are. Such types, common for hashes and keys,
+// are otherwise encoded by their kind, often as an empty object.
+func (e *Encoder) SetBinaryMarshalers(on bool) {
+	e.binaryMarshalers = on
+}
+
+// SetStringers specifies whether values of types that cannot be encoded otherwise, that is
+// functions other than iterators, complex numbers and send-only channels, are encoded as the string of their
+// String method when they implement fmt.Stringer, instead of returning an UnsupportedTypeError.
+// It suits logging and debugging output of values that have no JSON representation.
+func (e *Encoder) SetStringers(on bool) {
+	e.stringers = on
+}
+
+// SetErrorMessages specifies whether values of the error type, and of other interface types
+// including it, are encoded as the string returned by their Error method, and as null if they
+// are nil. By default they are encoded as their dynamic value, which for most errors is {}.
+// The message is encoded even if the dynamic type of the error implements Marshaler.
+func (e *Encoder) SetErrorMessages(on bool) {
+	e.errorMessages = on
+}
+
+// SetTrustedRaw specifies whether the bytes of RawMessage values and of MarshalJSON and AppendJSON methods
+// are written as is. By default they are checked to be valid JSON and compacted, as encoding/json does,
+// and invalid bytes fail the encoding with a MarshalerError.
+//
+// Trusting them skips that work on hot paths whose methods are known to return valid JSON;
+// invalid bytes are then written to the output unnoticed.
+func (e *Encoder) SetTrustedRaw(on bool) {
+	e.trustedRaw = on
+}
+
+// SetSortMapKeys specifies whether the keys of maps are written in ascending order.
+// By default they are written in map iteration order.
+func (e *Encoder) SetSortMapKeys(on bool) {
+	e.enabledMapKeySort = on
+}
+
+// SetStringifyMapKeys specifies whether map keys without an object key form,
+// such as the bool or float keys of a map[interface{}]interface{} converted from YAML,
+// are written as their fmt.Sprint text, and nil keys as "null".
+// By default encoding such a key returns an UnsupportedTypeError.
+// String, integer and encoding.TextMarshaler keys are always written as in encoding/json.
+func (e *Encoder) SetStringifyMapKeys(on bool) {
+	e.enabledMapKeyStringify = on
+}
+
+// SetMapKeyOrder sets the comparator that orders the keys of maps.
+// less receives keys in their encoded string form and must report whether a is written before b.
+// SetMapKeyOrder(nil) removes the comparator.
+func (e *Encoder) SetMapKeyOrder(less func(a, b string) bool) {
+	e.mapKeyCompare = less
+}
+
+// SetMapKeyPriority makes the given keys come first in maps, in the given order, for example to always write "id" and "type" before any other key.
+// The remaining keys follow, ordered by SetMapKeyOrder or SetSortMapKeys when set.
+func (e *Encoder) SetMapKeyPriority(keys ...string) {
+	if len(keys) == 0 {
+		e.mapKeyPriority = nil
+		return
+	}
+	e.mapKeyPriority = make(map[string]int, len(keys))
+	for i, key := range keys {
+		if _, exists := e.mapKeyPriority[key]; !exists {
+			e.mapKeyPriority[key] = i
+		}
+	}
+}
+
+// SetKeyTransformer sets a function applied to every object key before it is written,
+// covering struct field names as well as map keys, for example to rename legacy keys without retagging structs.
+// SetKeyTransformer(nil) removes the function.
+func (e *Encoder) SetKeyTransformer(fn func(key string) string) {
+	e.keyTransformer = fn
+}
+
+func (e *Encoder) transformKey(key string) string {
+	if e.keyTransformer == nil {
+		return key
+	}
+	return e.keyTransformer(key)
+}
+
+// SetSyncMapKeyFunc sets the function converting the keys of a sync.Map to JSON object keys.
+// By default string, integer and encoding.TextMarshaler keys are supported and other keys cause an error.
+func (e *Encoder) SetSyncMapKeyFunc(fn func(key interface{}) (string, error)) {
+	e.syncMapKeyFunc = fn
+}
+
+// SetSortSyncMapKeys specifies whether the members of an object encoded from a sync.Map are sorted by key.
+// The default is to sort them, so the output is deterministic; SetSortSyncMapKeys(false) writes them in Range order.
+func (e *Encoder) SetSortSyncMapKeys(on bool) {
+	e.enabledSyncMapKeySort = on
+}
+
 // SetIndent instructs the encoder to format each subsequent encoded value as if indented by the package-level function Indent(dst, src, prefix, indent).
 // Calling SetIndent("", "") disables indentation.
 func (e *Encoder) SetIndent(prefix, indent string) {
@@ -112,21 +359,50 @@ func (e *Encoder) SetIndent(prefix, indent string) {
 		e.enabledIndent = false
 		return
 	}
-	e.prefix = []byte(prefix)
-	e.indentStr = []byte(indent)
+	e.prefix = append(e.prefix[:0], prefix...)
+	e.indentStr = append(e.indentStr[:0], indent...)
+	e.indentCache = e.indentCache[:0]
 	e.enabledIndent = true
 }
 
 func (e *Encoder) release() {
 	e.w = nil
+	addStat(&stats.ReleasedBufferBytes, int64(cap(e.buf)))
 	e.pool.Put(e)
 }
 
 func (e *Encoder) reset() {
 	e.buf = e.buf[:0]
+	e.opcodes = &cachedOpcode
 	e.indent = 0
-	e.enabledHTMLEscape = true
+	e.indentOffset = 0
+	e.tokens = nil
+	e.hookValues = nil
+	e.v2 = v2Semantics()
+	e.enabledHTMLEscape = !e.v2
+	e.enabledLineTerminatorEscape = false
+	e.enabledSyncMapKeySort = true
+	e.syncMapKeyFunc = nil
+	e.keyTransformer = nil
+	e.enabledMapKeySort = false
+	e.enabledMapKeyStringify = false
+	e.enabledSync = false
+	e.enabledNullOmit = false
+	e.trustedRaw = false
+	e.ignorePromoted = false
+	e.binaryMarshalers = false
+	e.stringers = false
+	e.errorMessages = false
+	e.fieldOrder = DeclarationOrder
+	e.hash = nil
+	e.mapKeyCompare = nil
+	e.mapKeyPriority = nil
 	e.enabledIndent = false
+	e.prefix = e.prefix[:0]
+	e.indentStr = e.indentStr[:0]
+	e.indentCache = e.indentCache[:0]
+	e.ctx = nil
+	e.opCount = 0
 }
 
 func (e *Encoder) encodeForMarshal(v interface{}) ([]byte, error) {
@@ -148,21 +424,82 @@ func (e *Encoder) encodeForMarshal(v interface{}) ([]byte, error) {
 	return copied, nil
 }
 
+func (e *Encoder) encodeForMarshalValue(rv reflect.Value) ([]byte, error) {
+	if !rv.IsValid() {
+		return e.encodeForMarshal(nil)
+	}
+	if !rv.CanInterface() {
+		return nil, &UnsupportedValueError{Value: rv, Str: "value obtained from unexported field"}
+	}
+	if !rv.CanAddr() {
+		return e.encodeForMarshal(rv.Interface())
+	}
+	// build the interface rv.Interface() would return around the addressed value instead of a copy
+	var header interfaceHeader
+	header.typ = type2rtype(rv.Type())
+	header.ptr = unsafe.Pointer(rv.UnsafeAddr())
+	if isDirectIface(rv.Type()) {
+		header.ptr = *(*unsafe.Pointer)(header.ptr)
+	}
+	bytes, err := e.encodeForMarshal(*(*interface{})(unsafe.Pointer(&header)))
+	runtime.KeepAlive(rv)
+	return bytes, err
+}
+
 func (e *Encoder) encode(v interface{}) error {
+	typ := reflect.TypeOf(v)
+	done := startOperation(e.ctx, EncodeOperation, typ)
+	if done == nil {
+		return e.encodeValue(v)
+	}
+	start := time.Now()
+	size := len(e.buf)
+	e.compiled = false
+	err := e.encodeValue(v)
+	done(OperationStats{
+		Op:       EncodeOperation,
+		Type:     typ,
+		Bytes:    int64(len(e.buf) - size),
+		Duration: time.Since(start),
+		CacheHit: !e.compiled,
+		Err:      err,
+	})
+	return err
+}
+
+func (e *Encoder) encodeValue(v interface{}) error {
 	header := (*interfaceHeader)(unsafe.Pointer(&v))
 	typ := header.typ
+	if typ == nil {
+		e.encodeNull()
+		return nil
+	}
+	if isDataWordKind(typ.Kind()) {
+		return e.encodeDataWordValue(reflect.ValueOf(v), e.indent)
+	}
 
 	typeptr := uintptr(unsafe.Pointer(typ))
-	if codeSet := cachedOpcode.get(typeptr); codeSet != nil {
+	key := opcodeKey{
+		typeptr:          typeptr,
+		v2:               e.v2,
+		ignorePromoted:   e.ignorePromoted,
+		binaryMarshalers: e.binaryMarshalers,
+		stringers:        e.stringers,
+		errorMessages:    e.errorMessages,
+		fieldOrder:       e.fieldOrder,
+	}
+	if codeSet := e.opcodes.get(key); codeSet != nil {
+		countStat(&stats.OpcodePoolGets)
 		var code *opcode
 		if e.enabledIndent {
 			code = codeSet.codeIndent.Get().(*opcode)
 		} else {
 			code = codeSet.code.Get().(*opcode)
 		}
-		p := uintptr(header.ptr)
-		code.ptr = p
-		if err := e.run(code); err != nil {
+		code.ptr = valuePointer(typ, header)
+		err := e.run(code)
+		runtime.KeepAlive(header)
+		if err != nil {
 			return err
 		}
 		if e.enabledIndent {
@@ -176,34 +513,50 @@ func (e *Encoder) encode(v interface{}) error {
 	// noescape trick for header.typ ( reflect.*rtype )
 	copiedType := (*rtype)(unsafe.Pointer(typeptr))
 
-	codeIndent, err := e.compileHead(copiedType, true)
+	e.compiled = true
+	code, codeIndent, err := e.compileOpcodeSet(key, copiedType)
 	if err != nil {
 		return err
 	}
-	code, err := e.compileHead(copiedType, false)
+	// the pools copy code and codeIndent when they are first used, so neither may be reassigned
+	run := code
+	if e.enabledIndent {
+		run = codeIndent
+	}
+	run.ptr = valuePointer(typ, header)
+	err = e.run(run)
+	runtime.KeepAlive(header)
+	return err
+}
+
+// compileOpcodeSet compiles the codes of typ and caches them under key.
+// It returns the compiled codes, which the cached pools copy.
+func (e *Encoder) compileOpcodeSet(key opcodeKey, typ *rtype) (*opcode, *opcode, error) {
+	countStat(&stats.EncoderCompiles)
+	codeIndent, err := e.compileHead(typ, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	code, err := e.compileHead(typ, false)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	codeSet := &opcodeSet{
 		codeIndent: sync.Pool{
 			New: func() interface{} {
+				countStat(&stats.OpcodePoolMisses)
 				return copyOpcode(codeIndent)
 			},
 		},
 		code: sync.Pool{
 			New: func() interface{} {
+				countStat(&stats.OpcodePoolMisses)
 				return copyOpcode(code)
 			},
 		},
 	}
-	cachedOpcode.set(typeptr, codeSet)
-	p := uintptr(header.ptr)
-	if e.enabledIndent {
-		codeIndent.ptr = p
-		return e.run(codeIndent)
-	}
-	code.ptr = p
-	return e.run(code)
+	e.opcodes.set(key, codeSet)
+	return code, codeIndent, nil
 }
 
 func (e *Encoder) encodeInt(v int) {
@@ -247,11 +600,11 @@ func (e *Encoder) encodeUint64(v uint64) {
 }
 
 func (e *Encoder) encodeFloat32(v float32) {
-	e.buf = strconv.AppendFloat(e.buf, float64(v), 'f', -1, 32)
+	e.buf = appendFloat32(e.buf, v)
 }
 
 func (e *Encoder) encodeFloat64(v float64) {
-	e.buf = strconv.AppendFloat(e.buf, v, 'f', -1, 64)
+	e.buf = appendFloat64(e.buf, v)
 }
 
 func (e *Encoder) encodeBool(v bool) {
@@ -262,6 +615,105 @@ func (e *Encoder) encodeBytes(b []byte) {
 	e.buf = append(e.buf, b...)
 }
 
+// encodeKey writes a key compiled by compiledKey. The key transformer is given the name
+// of the key and its result is escaped as a string.
+func (e *Encoder) encodeKey(key []byte) {
+	if e.keyTransformer == nil {
+		e.buf = append(e.buf, key...)
+		return
+	}
+	e.encodeString(e.keyTransformer(string(unescapeString(key[1 : len(key)-2]))))
+	e.encodeByte(':')
+}
+
+// compiledKey returns the key of the form `"name":` written for an object member named name.
+// The name is escaped with HTML escaping, which is still valid JSON when the escaping is off.
+func compiledKey(name string) []byte {
+	var e Encoder
+	e.encodeEscapedString(name)
+	return append(e.buf, ':')
+}
+
+// isDataWordKind reports whether values of kind k are held in the data word of an interface
+// instead of behind a pointer to them, which the opcodes of the kind expect.
+func isDataWordKind(k reflect.Kind) bool {
+	return k == reflect.Func || k == reflect.Chan
+}
+
+// isDirectIface reports whether a struct or array of type t is held in the data word of an interface,
+// which is the case when it consists of a single pointer-shaped element.
+// The opcodes of such types still expect a pointer to the value, that is the address of the data word.
+func isDirectIface(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return true
+	case reflect.Array:
+		return t.Len() == 1 && isDirectIface(t.Elem())
+	case reflect.Struct:
+		return t.NumField() == 1 && isDirectIface(t.Field(0).Type)
+	}
+	return false
+}
+
+// valuePointer returns the pointer the opcodes of typ expect for the value held in the interface header.
+func valuePointer(typ *rtype, header *interfaceHeader) uintptr {
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Array:
+		if isDirectIface(rtype2type(typ)) {
+			return uintptr(unsafe.Pointer(&header.ptr))
+		}
+	}
+	return uintptr(header.ptr)
+}
+
+// encodeDataWordValue encodes a value taken out of an interface whose kind satisfies isDataWordKind.
+func (e *Encoder) encodeDataWordValue(v reflect.Value, indent int) error {
+	if e.encodeStringerValue(v) {
+		return nil
+	}
+	if v.Kind() == reflect.Chan {
+		return e.encodeChan(v, indent)
+	}
+	return e.encodeIter(v, indent)
+}
+
+// encodeReader writes the contents of r as a base64 encoded string.
+// When the encoder writes to a stream, pending output is flushed first and
+// the encoded contents go straight to the stream, so they never have to be held in memory at once.
+func (e *Encoder) encodeReader(r io.Reader) error {
+	e.buf = append(e.buf, '"')
+	var w io.Writer = encodeBuffer{e: e}
+	if e.w != nil {
+		if err := e.writeStream(e.buf); err != nil {
+			return err
+		}
+		e.buf = e.buf[:0]
+		w = e.w
+		if e.hash != nil {
+			w = io.MultiWriter(e.w, e.hash)
+		}
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(enc, r); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	e.buf = append(e.buf, '"')
+	return nil
+}
+
+// encodeBuffer is an io.Writer appending to the buffer of the encoder.
+type encodeBuffer struct {
+	e *Encoder
+}
+
+func (b encodeBuffer) Write(p []byte) (int, error) {
+	b.e.buf = append(b.e.buf, p...)
+	return len(p), nil
+}
+
 func (e *Encoder) encodeNull() {
 	e.buf = append(e.buf, 'n', 'u', 'l', 'l')
 }
@@ -278,7 +730,116 @@ func (e *Encoder) encodeByte(b byte) {
 	e.buf = append(e.buf, b)
 }
 
+// encodeIndent writes the prefix followed by indent indentations,
+// sliced from the cache so that a line costs a single copy.
 func (e *Encoder) encodeIndent(indent int) {
-	e.buf = append(e.buf, e.prefix...)
-	e.buf = append(e.buf, bytes.Repeat(e.indentStr, indent)...)
+	n := len(e.prefix) + (indent+e.indentOffset)*len(e.indentStr)
+	if n > len(e.indentCache) {
+		e.growIndentCache(n)
+	}
+	e.buf = append(e.buf, e.indentCache[:n]...)
+}
+
+// growIndentCache extends the indentation cache to at least n bytes, adding whole indentations.
+// The cache keeps its levels until the indentation changes, so each level is built once.
+func (e *Encoder) growIndentCache(n int) {
+	if len(e.indentCache) == 0 {
+		e.indentCache = append(e.indentCache, e.prefix...)
+	}
+	for len(e.indentCache) < n {
+		e.indentCache = append(e.indentCache, e.indentStr...)
+	}
+}
+
+// encodeMarshaledBytes writes b, the encoding returned by a method of a value at the
+// given indentation. Unless raw bytes are trusted, b is checked to be valid JSON
+// and compacted. When indenting, b is re-indented to match the surrounding
+// document, as Indent would, instead of being written as is.
+// b may start at the end of the output when not indenting, as it is never outgrown.
+func (e *Encoder) encodeMarshaledBytes(b []byte, indent int) error {
+	if !e.trustedRaw {
+		if !checkValid(b) {
+			err := &SyntaxError{msg: "invalid JSON"}
+			if issues := ValidateAll(b); len(issues) > 0 {
+				err.msg, err.Offset = issues[0].Message, issues[0].Offset
+			}
+			return err
+		}
+	}
+	if !e.enabledIndent {
+		if e.trustedRaw {
+			e.encodeBytes(b)
+		} else {
+			e.buf = appendCompact(e.buf, b)
+		}
+		return nil
+	}
+	depth := 0
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; c {
+		case ' ', '\t', '\n', '\r':
+		case '"':
+			end := i + 1
+			for ; end < len(b) && b[end] != '"'; end++ {
+				if b[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(b) {
+				end = len(b) - 1
+			}
+			e.encodeBytes(b[i : end+1])
+			i = end
+		case '{', '[':
+			e.encodeByte(c)
+			next := i + 1
+			for next < len(b) && isWhiteSpace[b[next]] {
+				next++
+			}
+			if next < len(b) && (b[next] == '}' || b[next] == ']') {
+				e.encodeByte(b[next])
+				i = next
+				break
+			}
+			depth++
+			e.encodeByte('\n')
+			e.encodeIndent(indent + depth)
+		case '}', ']':
+			depth--
+			e.encodeByte('\n')
+			e.encodeIndent(indent + depth)
+			e.encodeByte(c)
+		case ',':
+			e.encodeByte(',')
+			e.encodeByte('\n')
+			e.encodeIndent(indent + depth)
+		case ':':
+			e.encodeByte(':')
+			e.encodeByte(' ')
+		default:
+			e.encodeByte(c)
+		}
+	}
+	return nil
+}
+
+// appendCompact appends the valid JSON src to dst without the whitespace outside of strings.
+func appendCompact(dst, src []byte) []byte {
+	for i := 0; i < len(src); i++ {
+		switch c := src[i]; c {
+		case ' ', '\t', '\n', '\r':
+		case '"':
+			end := i + 1
+			for ; src[end] != '"'; end++ {
+				if src[end] == '\\' {
+					end++
+				}
+			}
+			dst = append(dst, src[i:end+1]...)
+			i = end
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
 }
@@ -0,0 +1,62 @@
+package json
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+func (e *Encoder) compileChan(typ *rtype) (*opcode, error) {
+	if typ.ChanDir()&reflect.RecvDir == 0 {
+		return nil, &UnsupportedTypeError{Type: rtype2type(typ)}
+	}
+	return newOpCode(opChan, typ, e.indent, newEndOp(e.indent)), nil
+}
+
+func (e *Encoder) encodeChanPtr(typ *rtype, p uintptr, indent int) error {
+	return e.encodeChan(reflect.NewAt(rtype2type(typ), unsafe.Pointer(p)).Elem(), indent)
+}
+
+// encodeChan encodes the values received from ch until it is closed as an array.
+// When the encoder writes to a stream, the output is flushed after every element,
+// so consumers see the elements as they arrive.
+func (e *Encoder) encodeChan(ch reflect.Value, indent int) error {
+	if ch.Type().ChanDir()&reflect.RecvDir == 0 {
+		return &UnsupportedTypeError{Type: ch.Type()}
+	}
+	if ch.IsNil() {
+		e.encodeNull()
+		return nil
+	}
+	cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: ch}}
+	if e.ctx != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(e.ctx.Done())})
+	}
+	e.encodeByte('[')
+	for n := 0; ; n++ {
+		chosen, value, ok := reflect.Select(cases)
+		if chosen == 1 {
+			return e.ctx.Err()
+		}
+		if !ok {
+			if e.enabledIndent && n > 0 {
+				e.encodeByte('\n')
+				e.encodeIndent(indent)
+			}
+			e.encodeByte(']')
+			return nil
+		}
+		if n > 0 {
+			e.encodeByte(',')
+		}
+		if e.enabledIndent {
+			e.encodeByte('\n')
+			e.encodeIndent(indent + 1)
+		}
+		if err := e.encodeInterfaceValue(value.Interface(), indent+1); err != nil {
+			return err
+		}
+		if err := e.flushStream(); err != nil {
+			return err
+		}
+	}
+}
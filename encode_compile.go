@@ -8,10 +8,28 @@ import (
 )
 
 func (e *Encoder) compileHead(typ *rtype, withIndent bool) (*opcode, error) {
-	if typ.Implements(marshalJSONType) {
-		return newOpCode(opMarshalJSON, typ, e.indent, newEndOp(e.indent)), nil
-	} else if typ.Implements(marshalTextType) {
-		return newOpCode(opMarshalText, typ, e.indent, newEndOp(e.indent)), nil
+	valueType := typ
+	if typ.Kind() == reflect.Ptr {
+		// the pointer is the address of the value
+		valueType = typ.Elem()
+	}
+	if code := e.compileGenerated(valueType); code != nil {
+		return e.withBeforeMarshal(valueType, code), nil
+	}
+	if code := e.compileWellKnown(valueType); code != nil {
+		return e.withBeforeMarshal(valueType, code), nil
+	}
+	if valueType == rawMessageType {
+		return e.compileRawMessage(typ), nil
+	}
+	if e.implements(typ, marshalToType) {
+		return e.withBeforeMarshal(valueType, newOpCode(opMarshalJSONTo, typ, e.indent, newEndOp(e.indent))), nil
+	} else if e.implements(typ, appenderType) {
+		return e.withBeforeMarshal(valueType, newOpCode(opAppendJSON, typ, e.indent, newEndOp(e.indent))), nil
+	} else if e.implements(typ, marshalJSONType) {
+		return e.withBeforeMarshal(valueType, newOpCode(opMarshalJSON, typ, e.indent, newEndOp(e.indent))), nil
+	} else if e.implements(typ, marshalTextType) {
+		return e.withBeforeMarshal(valueType, newOpCode(opMarshalText, typ, e.indent, newEndOp(e.indent))), nil
 	}
 	if typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
@@ -24,10 +42,37 @@ func (e *Encoder) compileHead(typ *rtype, withIndent bool) (*opcode, error) {
 }
 
 func (e *Encoder) compile(typ *rtype, root, withIndent bool) (*opcode, error) {
-	if typ.Implements(marshalJSONType) {
-		return newOpCode(opMarshalJSON, typ, e.indent, newEndOp(e.indent)), nil
-	} else if typ.Implements(marshalTextType) {
-		return newOpCode(opMarshalText, typ, e.indent, newEndOp(e.indent)), nil
+	code, err := e.compileValue(typ, root, withIndent)
+	if err != nil {
+		return nil, err
+	}
+	return e.withBeforeMarshal(typ, code), nil
+}
+
+func (e *Encoder) compileValue(typ *rtype, root, withIndent bool) (*opcode, error) {
+	if code := e.compileGenerated(typ); code != nil {
+		return code, nil
+	}
+	if conv := enumConverter(typ); conv != nil {
+		return e.compileConvert(typ, conv), nil
+	}
+	if conv := e.errorMessageConverterOf(typ); conv != nil {
+		return e.compileConvert(typ, conv), nil
+	}
+	if code := e.compileWellKnown(typ); code != nil {
+		return code, nil
+	}
+	if typ == rawMessageType {
+		return e.compileRawMessage(typ), nil
+	}
+	if e.implements(typ, marshalToType) {
+		return e.compileMarshaler(opMarshalJSONTo, typ), nil
+	} else if e.implements(typ, appenderType) {
+		return e.compileMarshaler(opAppendJSON, typ), nil
+	} else if e.implements(typ, marshalJSONType) {
+		return e.compileMarshaler(opMarshalJSON, typ), nil
+	} else if e.implements(typ, marshalTextType) {
+		return e.compileMarshaler(opMarshalText, typ), nil
 	}
 	switch typ.Kind() {
 	case reflect.Ptr:
@@ -39,8 +84,14 @@ func (e *Encoder) compile(typ *rtype, root, withIndent bool) (*opcode, error) {
 	case reflect.Map:
 		return e.compileMap(typ, true, root, withIndent)
 	case reflect.Struct:
+		if typ == syncMapType {
+			return e.compileSyncMap(typ)
+		}
 		return e.compileStruct(typ, root, withIndent)
 	case reflect.Interface:
+		if typ == readerType {
+			return e.compileReader(typ)
+		}
 		return e.compileInterface(typ, root)
 	case reflect.Int:
 		return e.compileInt(typ)
@@ -72,6 +123,10 @@ func (e *Encoder) compile(typ *rtype, root, withIndent bool) (*opcode, error) {
 		return e.compileString(typ)
 	case reflect.Bool:
 		return e.compileBool(typ)
+	case reflect.Func:
+		return e.compileIter(typ)
+	case reflect.Chan:
+		return e.compileChan(typ)
 	}
 	return nil, &UnsupportedTypeError{Type: rtype2type(typ)}
 }
@@ -205,6 +260,16 @@ func (e *Encoder) optimizeStructFieldPtrHead(typ *rtype, code *opcode) *opcode {
 	return code
 }
 
+// compileMarshaler returns the code of a value whose type implements a marshaler.
+// The code receives a pointer to the value, so a pointer is loaded before its method is called.
+func (e *Encoder) compileMarshaler(op opType, typ *rtype) *opcode {
+	code := newOpCode(op, typ, e.indent, newEndOp(e.indent))
+	if typ.Kind() == reflect.Ptr {
+		return newOpCode(opPtr, typ, e.indent, code)
+	}
+	return code
+}
+
 func (e *Encoder) compilePtr(typ *rtype, root, withIndent bool) (*opcode, error) {
 	code, err := e.compile(typ.Elem(), root, withIndent)
 	if err != nil {
@@ -269,7 +334,14 @@ func (e *Encoder) compileBool(typ *rtype) (*opcode, error) {
 	return newOpCode(opBool, typ, e.indent, newEndOp(e.indent)), nil
 }
 
+func (e *Encoder) compileReader(typ *rtype) (*opcode, error) {
+	return newOpCode(opReader, typ, e.indent, newEndOp(e.indent)), nil
+}
+
 func (e *Encoder) compileInterface(typ *rtype, root bool) (*opcode, error) {
+	if u := lookupUnion(typ); u != nil {
+		return e.compileUnion(typ, u), nil
+	}
 	return (*opcode)(unsafe.Pointer(&interfaceCode{
 		opcodeHeader: &opcodeHeader{
 			op:     opInterface,
@@ -345,7 +417,8 @@ func (e *Encoder) compileArray(typ *rtype, root, withIndent bool) (*opcode, erro
 	header := newArrayHeaderCode(e.indent, alen)
 	elemCode := &arrayElemCode{
 		opcodeHeader: &opcodeHeader{
-			op: opArrayElem,
+			op:     opArrayElem,
+			indent: e.indent,
 		},
 		len:  uintptr(alen),
 		size: size,
@@ -367,18 +440,6 @@ func (e *Encoder) compileArray(typ *rtype, root, withIndent bool) (*opcode, erro
 	return (*opcode)(unsafe.Pointer(header)), nil
 }
 
-//go:linkname mapiterinit reflect.mapiterinit
-//go:noescape
-func mapiterinit(mapType *rtype, m unsafe.Pointer) unsafe.Pointer
-
-//go:linkname mapiterkey reflect.mapiterkey
-//go:noescape
-func mapiterkey(it unsafe.Pointer) unsafe.Pointer
-
-//go:linkname mapiternext reflect.mapiternext
-//go:noescape
-func mapiternext(it unsafe.Pointer)
-
 //go:linkname maplen reflect.maplen
 //go:noescape
 func maplen(m unsafe.Pointer) int
@@ -389,6 +450,9 @@ func (e *Encoder) compileMap(typ *rtype, withLoad, root, withIndent bool) (*opco
 	//                                     |_______________________|
 	e.indent++
 	keyType := typ.Key()
+	if keyType.Kind() != reflect.String {
+		keyType = type2rtype(stringType) // keys are converted to strings while iterating
+	}
 	keyCode, err := e.compile(keyType, false, withIndent)
 	if err != nil {
 		return nil, err
@@ -441,21 +505,129 @@ func (e *Encoder) compileMap(typ *rtype, withLoad, root, withIndent bool) (*opco
 }
 
 func (e *Encoder) getTag(field reflect.StructField) string {
-	return field.Tag.Get("json")
+	return structTag(field)
 }
 
 func (e *Encoder) isIgnoredStructField(field reflect.StructField) bool {
-	if field.PkgPath != "" && !field.Anonymous {
-		// private field
+	tag := e.getTag(field)
+	if type2rtype(field.Type) == presenceType {
+		// filled by decoding only
 		return true
 	}
-	tag := e.getTag(field)
+	if field.PkgPath != "" && (!field.Anonymous || field.Type.Kind() == reflect.Interface) {
+		// private field, or embedded interface of an unexported type,
+		// unless it is a computed field
+		return !isMethodField(tag)
+	}
 	if tag == "-" {
 		return true
 	}
 	return false
 }
 
+// deepEmptyFunc returns the emptiness check of a field tagged `json:",omitempty,deep"`.
+// A struct is empty when all of its encodable fields are empty, recursively.
+func deepEmptyFunc(typ *rtype) func(uintptr) bool {
+	t := rtype2type(typ)
+	return func(p uintptr) bool {
+		return isDeepEmptyValue(reflect.NewAt(t, unsafe.Pointer(p)).Elem())
+	}
+}
+
+// defaultFunc returns a function reporting whether a field equals the value of its
+// "default=value" option, or nil if it has none.
+func defaultFunc(field reflect.StructField, opts []string) (func(uintptr) bool, error) {
+	for _, opt := range opts[1:] {
+		if !strings.HasPrefix(opt, "default=") {
+			continue
+		}
+		def := reflect.New(field.Type)
+		if err := Unmarshal([]byte(opt[len("default="):]), def.Interface()); err != nil {
+			return nil, fmt.Errorf("json: invalid default value of field %s: %v", field.Name, err)
+		}
+		defValue := def.Elem().Interface()
+		t := field.Type
+		return func(p uintptr) bool {
+			return reflect.DeepEqual(reflect.NewAt(t, unsafe.Pointer(p)).Elem().Interface(), defValue)
+		}, nil
+	}
+	return nil, nil
+}
+
+// nullFunc returns the function reporting whether the value of typ at p encodes as null,
+// for the encoders omitting null members, or nil if it never does so without a method.
+func nullFunc(typ *rtype) func(uintptr) bool {
+	switch {
+	case typ.Kind() == reflect.Ptr, typ == rawMessageType:
+		return func(p uintptr) bool {
+			return *(*uintptr)(unsafe.Pointer(p)) == 0
+		}
+	case typ.Kind() == reflect.Interface:
+		t := rtype2type(typ)
+		return func(p uintptr) bool {
+			v := reflect.NewAt(t, unsafe.Pointer(p)).Elem()
+			if v.IsNil() {
+				return true
+			}
+			v = v.Elem()
+			return v.Kind() == reflect.Ptr && v.IsNil()
+		}
+	}
+	return nil
+}
+
+// isMarshalerType reports whether typ encodes itself with one of the marshaler interfaces.
+func isMarshalerType(typ reflect.Type) bool {
+	return typ.Implements(marshalToType) || typ.Implements(appenderType) ||
+		typ.Implements(marshalJSONType) || typ.Implements(marshalTextType)
+}
+
+func isDeepEmptyValue(v reflect.Value) bool {
+	if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface && v.CanInterface() &&
+		isMarshalerType(v.Type()) {
+		// the encoding of the value is opaque, only its zero value counts as empty
+		return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface:
+		return v.IsNil()
+	case reflect.Ptr:
+		if v.IsNil() {
+			return true
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			return isDeepEmptyValue(v.Elem())
+		}
+		return false
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			if structTag(field) == "-" {
+				continue
+			}
+			if !isDeepEmptyValue(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
 func (e *Encoder) optimizeStructHeaderOmitEmptyIndent(op opType) opType {
 	switch op {
 	case opInt:
@@ -773,24 +945,33 @@ func (e *Encoder) compileStruct(typ *rtype, root, withIndent bool) (*opcode, err
 			})), nil
 		}
 	}
+	// the struct is registered while its fields are compiled, so that only recursive uses jump to it
 	compiled := &compiledCode{}
 	if withIndent {
 		e.structTypeToCompiledIndentCode[typeptr] = compiled
+		defer delete(e.structTypeToCompiledIndentCode, typeptr)
 	} else {
 		e.structTypeToCompiledCode[typeptr] = compiled
+		defer delete(e.structTypeToCompiledCode, typeptr)
 	}
 	// header => code => structField => code => end
 	//                        ^          |
 	//                        |__________|
-	fieldNum := typ.NumField()
+	fieldIndexes, err := e.structFieldIndexes(typ)
+	if err != nil {
+		return nil, err
+	}
 	fieldIdx := 0
 	var (
 		head      *structFieldCode
 		code      *opcode
 		prevField *structFieldCode
+		paths     map[string]*pathNode // objects written for path fields by their key
+		inline    *inlineMap
+		declared  = map[string]bool{} // the keys of the fields, which the inline map does not write
 	)
 	e.indent++
-	for i := 0; i < fieldNum; i++ {
+	for _, i := range fieldIndexes {
 		field := typ.Field(i)
 		if e.isIgnoredStructField(field) {
 			continue
@@ -804,30 +985,147 @@ func (e *Encoder) compileStruct(typ *rtype, root, withIndent bool) (*opcode, err
 			}
 		}
 		isOmitEmpty := false
+		isOmitZero := false
+		isDeep := false
 		if len(opts) > 1 {
 			isOmitEmpty = opts[1] == "omitempty"
+			for _, opt := range opts[1:] {
+				isOmitZero = isOmitZero || opt == "omitzero"
+			}
+			for _, opt := range opts[2:] {
+				isDeep = isDeep || opt == "deep"
+			}
 		}
 		fieldType := type2rtype(field.Type)
-		valueCode, err := e.compile(fieldType, false, withIndent)
+		var fieldOffset uintptr
+		conv, err := fieldConverter(field, opts)
+		if err != nil {
+			return nil, err
+		}
+		method, err := fieldMethod(typ, field, opts)
 		if err != nil {
 			return nil, err
 		}
-		key := fmt.Sprintf(`"%s":`, keyName)
+		var valueCode *opcode
+		var pathObject *pathNode
+		var fieldInline *inlineMap
+		if isInlineField(opts) {
+			if err := checkInlineField(typ, field, inline != nil); err != nil {
+				return nil, err
+			}
+			inline = &inlineMap{typ: fieldType, declared: declared}
+			fieldInline = inline
+			fieldOffset = field.Offset
+			valueCode = newEndOp(e.indent) // replaced by the next field
+		} else if method != nil {
+			// the method is called on the struct
+			fieldType = typ
+			valueCode = e.compileConvert(typ, method)
+		} else if path := fieldPath(keyName, opts); path != nil {
+			omitEmpty := isOmitEmpty
+			for _, opt := range opts[1:] {
+				omitEmpty = omitEmpty || opt == "omitempty"
+			}
+			node, exists := paths[path[0]]
+			if !exists || node.typ != nil {
+				node = newPathNode(path[0])
+				if paths == nil {
+					paths = map[string]*pathNode{}
+				}
+				paths[path[0]] = node
+				pathObject = node
+			}
+			if len(path) == 1 {
+				node.typ = fieldType
+				node.offset = field.Offset
+				node.omitEmpty = omitEmpty
+				node.isEmpty = valueEmptyFunc(fieldType)
+			} else {
+				node.add(path[1:], field, omitEmpty)
+			}
+			if pathObject == nil {
+				// written with the object of an earlier path field
+				continue
+			}
+			keyName = path[0]
+			fieldType = typ
+			isOmitEmpty = omitEmpty // the object is omitted once all of its members are
+			valueCode = e.compilePath(typ, pathObject)
+		} else if conv != nil && conv.Encode != nil {
+			valueCode = e.compileConvert(fieldType, conv)
+			fieldOffset = field.Offset
+		} else {
+			fieldOffset = field.Offset
+			valueCode, err = e.compile(fieldType, false, withIndent)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if fieldInline == nil {
+			declared[keyName] = true
+		}
+		key := compiledKey(keyName)
 		fieldCode := &structFieldCode{
 			opcodeHeader: &opcodeHeader{
 				typ:    fieldType,
 				next:   valueCode,
 				indent: e.indent,
 			},
-			key:    []byte(key),
-			offset: field.Offset,
+			key:    key,
+			offset: fieldOffset,
+			inline: fieldInline,
+		}
+		if method != nil {
+			if isOmitEmpty || isOmitZero {
+				isOmitEmpty = true
+				fieldCode.isEmpty = methodEmptyFunc(typ, method)
+			}
+		} else if pathObject != nil {
+			fieldCode.isEmpty = pathObject.omitted
+		} else if (isOmitEmpty || isOmitZero) && fieldType.Implements(absentValueType) {
+			isOmitEmpty, fieldCode.isEmpty = true, zeroFunc(fieldType)
+		} else if isOmitZero || isOmitEmpty && e.v2 && !isDeep {
+			isOmitEmpty, fieldCode.isEmpty = omitFunc(fieldType, valueCode.op, isOmitEmpty, isOmitZero)
+		} else if isOmitEmpty && isDeep {
+			fieldCode.isEmpty = deepEmptyFunc(fieldType)
+		} else if isOmitEmpty && valueCode.op == opConvert {
+			fieldCode.isEmpty = valueEmptyFunc(fieldType)
+		}
+		if pathObject == nil && method == nil && fieldInline == nil && (conv == nil || conv.Encode == nil) {
+			fieldCode.isNull = nullFunc(fieldType)
+		}
+		optimizeOp := valueCode.op
+		if pathObject == nil && method == nil && fieldInline == nil {
+			isDefault, err := defaultFunc(field, opts)
+			if err != nil {
+				return nil, err
+			}
+			if isDefault != nil {
+				isEmpty := fieldCode.isEmpty
+				if isEmpty == nil && isOmitEmpty {
+					isEmpty = valueEmptyFunc(fieldType)
+				}
+				fieldCode.isEmpty = isDefault
+				if isEmpty != nil {
+					fieldCode.isEmpty = func(p uintptr) bool { return isEmpty(p) || isDefault(p) }
+				}
+				isOmitEmpty = true
+				// the specialized ops of the primitive types only omit their zero value
+				optimizeOp = opInterface
+			}
 		}
 		if fieldIdx == 0 {
 			fieldCode.indent--
 			head = fieldCode
 			code = (*opcode)(unsafe.Pointer(fieldCode))
 			prevField = fieldCode
-			op := e.optimizeStructHeader(valueCode.op, isOmitEmpty, withIndent)
+			op := e.optimizeStructHeader(optimizeOp, isOmitEmpty, withIndent)
+			if fieldInline != nil {
+				op = opStructFieldHeadInline
+				if withIndent {
+					op = opStructFieldHeadInlineIndent
+				}
+			}
 			fieldCode.op = op
 			switch op {
 			case opStructFieldHead,
@@ -841,7 +1139,13 @@ func (e *Encoder) compileStruct(typ *rtype, root, withIndent bool) (*opcode, err
 			prevField.nextField = (*opcode)(unsafe.Pointer(fieldCode))
 			prevField = fieldCode
 			code = (*opcode)(unsafe.Pointer(fieldCode))
-			op := e.optimizeStructField(valueCode.op, isOmitEmpty, withIndent)
+			op := e.optimizeStructField(optimizeOp, isOmitEmpty, withIndent)
+			if fieldInline != nil {
+				op = opStructFieldInline
+				if withIndent {
+					op = opStructFieldInlineIndent
+				}
+			}
 			fieldCode.op = op
 			switch op {
 			case opStructField,
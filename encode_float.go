@@ -0,0 +1,259 @@
+package json
+
+//go:generate go run encode_float_pow10_gen.go
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// appendFloat64 appends the shortest decimal representation of f that reads back as f,
+// in the format of encoding/json: like strconv.AppendFloat(b, f, 'f', -1, 64), except that
+// an exponent is used for magnitudes below 1e-6 and from 1e21 on, as in 1e-7 or 1e+21.
+// The digits are computed by the Schubfach algorithm.
+func appendFloat64(b []byte, f float64) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.AppendFloat(b, f, 'f', -1, 64)
+	}
+	fbits := math.Float64bits(f)
+	if fbits>>63 != 0 {
+		b = append(b, '-')
+	}
+	if fbits<<1 == 0 {
+		return append(b, '0')
+	}
+	digits, exp10 := schubfach64(fbits&(1<<52-1), int(fbits>>52&0x7FF))
+	abs := math.Abs(f)
+	return appendDecimal(b, digits, exp10, abs < 1e-6 || abs >= 1e21)
+}
+
+// appendFloat32 is like appendFloat64 for the shortest representation of a float32.
+func appendFloat32(b []byte, f float32) []byte {
+	f64 := float64(f)
+	if math.IsNaN(f64) || math.IsInf(f64, 0) {
+		return strconv.AppendFloat(b, f64, 'f', -1, 32)
+	}
+	fbits := math.Float32bits(f)
+	if fbits>>31 != 0 {
+		b = append(b, '-')
+	}
+	if fbits<<1 == 0 {
+		return append(b, '0')
+	}
+	digits, exp10 := schubfach32(fbits&(1<<23-1), int(fbits>>23&0xFF))
+	abs := float32(math.Abs(f64))
+	return appendDecimal(b, uint64(digits), exp10, abs < 1e-6 || abs >= 1e21)
+}
+
+// floorLog2Pow10 returns floor(log2(10^e)) for |e| <= 1233.
+func floorLog2Pow10(e int) int {
+	return e * 1741647 >> 19
+}
+
+// floorLog10Pow2 returns floor(log10(2^e)) for |e| <= 2620.
+func floorLog10Pow2(e int) int {
+	return e * 1262611 >> 22
+}
+
+// floorLog10ThreeQuartersPow2 returns floor(log10(3/4 * 2^e)) for |e| <= 2620.
+func floorLog10ThreeQuartersPow2(e int) int {
+	return (e*1262611 - 524031) >> 22
+}
+
+// roundToOdd64 returns the top 64 bits of g*cp, with the lowest bit set when the rest is inexact.
+func roundToOdd64(g *[2]uint64, cp uint64) uint64 {
+	xHi, _ := bits.Mul64(g[1], cp)
+	yHi, yLo := bits.Mul64(g[0], cp)
+	z, carry := bits.Add64(yLo, xHi, 0)
+	return (yHi + carry) | boolBit(z > 1)
+}
+
+// roundToOdd32 is like roundToOdd64 for the top 32 bits of g*cp.
+func roundToOdd32(g uint64, cp uint32) uint32 {
+	lo := uint64(uint32(g)) * uint64(cp)
+	hi := (g>>32)*uint64(cp) + lo>>32
+	return uint32(hi>>32) | uint32(boolBit(uint32(hi) > 1))
+}
+
+func boolBit(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// schubfach64 returns the shortest decimal digits*10^exp10 that rounds to the positive
+// float64 with the given mantissa and biased exponent fields.
+func schubfach64(mantissa uint64, exponent int) (uint64, int) {
+	const (
+		mantissaBits = 52
+		bias         = 1023 + mantissaBits
+	)
+	c, q := mantissa, 1-bias
+	if exponent != 0 {
+		c |= 1 << mantissaBits
+		q = exponent - bias
+		if -mantissaBits <= q && q <= 0 && c&(1<<uint(-q)-1) == 0 {
+			// an integer below 2^53
+			return trimZeros64(c>>uint(-q), 0)
+		}
+	}
+	even := c&1 == 0
+	lowerCloser := mantissa == 0 && exponent > 1
+
+	cbl := 4*c - 2 + boolBit(lowerCloser)
+	cb := 4 * c
+	cbr := 4*c + 2
+
+	var k int
+	if lowerCloser {
+		k = floorLog10ThreeQuartersPow2(q)
+	} else {
+		k = floorLog10Pow2(q)
+	}
+	h := uint(q + floorLog2Pow10(-k) + 1)
+	g := &schubfachPow10[-k-schubfachMinExp10]
+	vbl := roundToOdd64(g, cbl<<h)
+	vb := roundToOdd64(g, cb<<h)
+	vbr := roundToOdd64(g, cbr<<h)
+
+	lower := vbl + boolBit(!even)
+	upper := vbr - boolBit(!even)
+
+	s := vb / 4
+	if s >= 10 {
+		sp := s / 10
+		upInside := lower <= 40*sp
+		wpInside := 40*sp+40 <= upper
+		if upInside != wpInside {
+			return trimZeros64(sp+boolBit(wpInside), k+1)
+		}
+	}
+	uInside := lower <= 4*s
+	wInside := 4*s+4 <= upper
+	if uInside != wInside {
+		return trimZeros64(s+boolBit(wInside), k)
+	}
+	mid := 4*s + 2
+	roundUp := vb > mid || vb == mid && s&1 != 0
+	return trimZeros64(s+boolBit(roundUp), k)
+}
+
+// schubfach32 is like schubfach64 for a float32.
+func schubfach32(mantissa uint32, exponent int) (uint32, int) {
+	const (
+		mantissaBits = 23
+		bias         = 127 + mantissaBits
+	)
+	c, q := mantissa, 1-bias
+	if exponent != 0 {
+		c |= 1 << mantissaBits
+		q = exponent - bias
+		if -mantissaBits <= q && q <= 0 && c&(1<<uint(-q)-1) == 0 {
+			// an integer below 2^24
+			return trimZeros32(c>>uint(-q), 0)
+		}
+	}
+	even := c&1 == 0
+	lowerCloser := mantissa == 0 && exponent > 1
+
+	cbl := 4*c - 2 + uint32(boolBit(lowerCloser))
+	cb := 4 * c
+	cbr := 4*c + 2
+
+	var k int
+	if lowerCloser {
+		k = floorLog10ThreeQuartersPow2(q)
+	} else {
+		k = floorLog10Pow2(q)
+	}
+	h := uint(q + floorLog2Pow10(-k) + 1)
+	// the 64-bit approximation floor(beta/2^64)+1 of the 128-bit one
+	pow := &schubfachPow10[-k-schubfachMinExp10]
+	g := pow[0]
+	if pow[1] != 0 {
+		g++
+	}
+	vbl := roundToOdd32(g, cbl<<h)
+	vb := roundToOdd32(g, cb<<h)
+	vbr := roundToOdd32(g, cbr<<h)
+
+	lower := vbl + uint32(boolBit(!even))
+	upper := vbr - uint32(boolBit(!even))
+
+	s := vb / 4
+	if s >= 10 {
+		sp := s / 10
+		upInside := lower <= 40*sp
+		wpInside := 40*sp+40 <= upper
+		if upInside != wpInside {
+			return trimZeros32(sp+uint32(boolBit(wpInside)), k+1)
+		}
+	}
+	uInside := lower <= 4*s
+	wInside := 4*s+4 <= upper
+	if uInside != wInside {
+		return trimZeros32(s+uint32(boolBit(wInside)), k)
+	}
+	mid := 4*s + 2
+	roundUp := vb > mid || vb == mid && s&1 != 0
+	return trimZeros32(s+uint32(boolBit(roundUp)), k)
+}
+
+func trimZeros64(digits uint64, exp10 int) (uint64, int) {
+	for digits%10 == 0 {
+		digits /= 10
+		exp10++
+	}
+	return digits, exp10
+}
+
+func trimZeros32(digits uint32, exp10 int) (uint32, int) {
+	for digits%10 == 0 {
+		digits /= 10
+		exp10++
+	}
+	return digits, exp10
+}
+
+// appendDecimal appends the positive number digits*10^exp10, in scientific notation if exponential is set.
+func appendDecimal(b []byte, digits uint64, exp10 int, exponential bool) []byte {
+	var buf [20]byte
+	d := strconv.AppendUint(buf[:0], digits, 10)
+	n := len(d)
+	point := n + exp10 // position of the decimal point in d
+	if exponential {
+		b = append(b, d[0])
+		if n > 1 {
+			b = append(b, '.')
+			b = append(b, d[1:]...)
+		}
+		b = append(b, 'e')
+		e := point - 1
+		if e < 0 {
+			b = append(b, '-')
+			e = -e
+		} else {
+			b = append(b, '+')
+		}
+		return strconv.AppendInt(b, int64(e), 10)
+	}
+	switch {
+	case point <= 0:
+		b = append(b, '0', '.')
+		for i := point; i < 0; i++ {
+			b = append(b, '0')
+		}
+		return append(b, d...)
+	case point >= n:
+		b = append(b, d...)
+		for i := n; i < point; i++ {
+			b = append(b, '0')
+		}
+		return b
+	}
+	b = append(b, d[:point]...)
+	b = append(b, '.')
+	return append(b, d[point:]...)
+}
@@ -0,0 +1,77 @@
+//go:build ignore
+// +build ignore
+
+// This program generates encode_float_pow10.go, the table of 128-bit
+// approximations of powers of ten used by the Schubfach float formatter.
+// Run it with: go run encode_float_pow10_gen.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"math/big"
+)
+
+const (
+	minExp10 = -292
+	maxExp10 = 326
+)
+
+var one = big.NewInt(1)
+
+// approximation returns floor(beta)+1, where 10^k = beta*2^r with 2^127 <= beta < 2^128.
+func approximation(k int) *big.Int {
+	num := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(k))), nil)
+	den := big.NewInt(1)
+	if k < 0 {
+		num, den = den, num
+	}
+	// scale num/den into [2^127, 2^128)
+	min := new(big.Int).Lsh(one, 127)
+	max := new(big.Int).Lsh(one, 128)
+	for new(big.Int).Quo(num, den).Cmp(min) < 0 {
+		num.Lsh(num, 1)
+	}
+	for new(big.Int).Quo(num, den).Cmp(max) >= 0 {
+		den.Lsh(den, 1)
+	}
+	g := new(big.Int).Quo(num, den)
+	return g.Add(g, one)
+}
+
+func abs(k int) int {
+	if k < 0 {
+		return -k
+	}
+	return k
+}
+
+func main() {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by encode_float_pow10_gen.go; DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package json")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "const (\n\tschubfachMinExp10 = %d\n\tschubfachMaxExp10 = %d\n)\n\n", minExp10, maxExp10)
+	fmt.Fprintln(&buf, "// schubfachPow10 holds the high and low 64 bits of floor(beta)+1 at index k-schubfachMinExp10,")
+	fmt.Fprintln(&buf, "// where 10^k = beta*2^r and 2^127 <= beta < 2^128.")
+	fmt.Fprintf(&buf, "var schubfachPow10 = [%d][2]uint64{\n", maxExp10-minExp10+1)
+	mask := new(big.Int).SetUint64(^uint64(0))
+	for k := minExp10; k <= maxExp10; k++ {
+		g := approximation(k)
+		hi := new(big.Int).Rsh(g, 64).Uint64()
+		lo := new(big.Int).And(g, mask).Uint64()
+		fmt.Fprintf(&buf, "\t{0x%016X, 0x%016X}, // 1e%d\n", hi, lo, k)
+	}
+	fmt.Fprintln(&buf, "}")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile("encode_float_pow10.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,144 @@
+package json
+
+import (
+	"reflect"
+)
+
+// ArrayFunc produces the elements of an array one at a time, for example from
+// a database cursor. It returns ok false once there are no more elements.
+//
+// Values of type ArrayFunc encode as arrays of the values they produce,
+// wherever they appear.
+type ArrayFunc func() (v interface{}, ok bool, err error)
+
+// ObjectFunc produces the members of an object one at a time, for example
+// the sections of a report. It returns ok false once there are no more members.
+//
+// Values of type ObjectFunc encode as objects of the members they produce,
+// wherever they appear. Values that are an ArrayFunc or an ObjectFunc are streamed in turn.
+type ObjectFunc func() (key string, v interface{}, ok bool, err error)
+
+var (
+	arrayFuncType  = reflect.TypeOf(ArrayFunc(nil))
+	objectFuncType = reflect.TypeOf(ObjectFunc(nil))
+)
+
+// EncodeArrayFunc writes the array of the elements produced by next to the stream.
+// next is called until it returns ok false or an error, and every element is
+// written to the stream once it is encoded, so the array is never held in memory at once.
+// An error of next is returned after the elements before it were written.
+//
+// Called from a MarshalJSONTo method, EncodeArrayFunc writes the array as the next value
+// of the one the method writes.
+func (e *Encoder) EncodeArrayFunc(next ArrayFunc) error {
+	return e.Encode(next)
+}
+
+// EncodeObjectFunc writes the object of the members produced by next to the stream.
+// next is called until it returns ok false or an error, and every member is
+// written to the stream once it is encoded, like the elements written by EncodeArrayFunc.
+// Keys are written in the order they are produced, and are not checked for duplicates.
+//
+// Called from a MarshalJSONTo method, EncodeObjectFunc writes the object as the next value
+// of the one the method writes.
+func (e *Encoder) EncodeObjectFunc(next ObjectFunc) error {
+	return e.Encode(next)
+}
+
+// encodeArrayFunc encodes the elements produced by next as an array.
+// When the encoder writes to a stream, the output is flushed after every element.
+func (e *Encoder) encodeArrayFunc(next ArrayFunc, indent int) error {
+	if next == nil {
+		e.encodeNull()
+		return nil
+	}
+	e.encodeByte('[')
+	for n := 0; ; n++ {
+		v, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if e.enabledIndent && n > 0 {
+				e.encodeByte('\n')
+				e.encodeIndent(indent)
+			}
+			e.encodeByte(']')
+			return nil
+		}
+		if n > 0 {
+			e.encodeByte(',')
+		}
+		if e.enabledIndent {
+			e.encodeByte('\n')
+			e.encodeIndent(indent + 1)
+		}
+		if err := e.encodeInterfaceValue(v, indent+1); err != nil {
+			return err
+		}
+		if err := e.flushStream(); err != nil {
+			return err
+		}
+	}
+}
+
+// encodeObjectFunc encodes the members produced by next as an object.
+// When the encoder writes to a stream, the output is flushed after every member.
+func (e *Encoder) encodeObjectFunc(next ObjectFunc, indent int) error {
+	if next == nil {
+		e.encodeNull()
+		return nil
+	}
+	e.encodeByte('{')
+	for n := 0; ; n++ {
+		key, v, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if e.enabledIndent && n > 0 {
+				e.encodeByte('\n')
+				e.encodeIndent(indent)
+			}
+			e.encodeByte('}')
+			return nil
+		}
+		start := len(e.buf)
+		if n > 0 {
+			e.encodeByte(',')
+		}
+		if e.enabledIndent {
+			e.encodeByte('\n')
+			e.encodeIndent(indent + 1)
+		}
+		e.encodeString(e.transformKey(key))
+		e.encodeByte(':')
+		if e.enabledIndent {
+			e.encodeByte(' ')
+		}
+		valueStart := len(e.buf)
+		if err := e.encodeInterfaceValue(v, indent+1); err != nil {
+			return err
+		}
+		if e.enabledNullOmit && string(e.buf[valueStart:]) == "null" {
+			e.buf = e.buf[:start]
+			n--
+			continue
+		}
+		if err := e.flushStream(); err != nil {
+			return err
+		}
+	}
+}
+
+// flushStream writes the pending output to the stream the encoder writes to, if any.
+func (e *Encoder) flushStream() error {
+	if e.w == nil {
+		return nil
+	}
+	if err := e.writeStream(e.buf); err != nil {
+		return err
+	}
+	e.buf = e.buf[:0]
+	return nil
+}
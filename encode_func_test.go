@@ -0,0 +1,136 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+// rows returns an ArrayFunc producing values, like the rows of a database cursor.
+func rows(values ...interface{}) json.ArrayFunc {
+	return func() (interface{}, bool, error) {
+		if len(values) == 0 {
+			return nil, false, nil
+		}
+		v := values[0]
+		values = values[1:]
+		if err, ok := v.(error); ok {
+			return nil, false, err
+		}
+		return v, true, nil
+	}
+}
+
+func Test_Encoder_EncodeArrayFunc(t *testing.T) {
+	type row struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	t.Run("flush every element", func(t *testing.T) {
+		w := &recordingWriter{}
+		enc := json.NewEncoder(w)
+		assertErr(t, enc.EncodeArrayFunc(rows(row{1, "a"}, row{2, "b"})))
+		assertEq(t, "writes", 3, len(w.writes))
+		assertEq(t, "first write", `[{"id":1,"name":"a"}`, w.writes[0])
+		assertEq(t, "second write", `,{"id":2,"name":"b"}`, w.writes[1])
+		assertEq(t, "last write", `]`, w.writes[2])
+	})
+	t.Run("empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		assertErr(t, json.NewEncoder(&buf).EncodeArrayFunc(rows()))
+		assertEq(t, "empty", `[]`, buf.String())
+	})
+	t.Run("indent", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		assertErr(t, enc.EncodeArrayFunc(rows(1, []int{2, 3})))
+		assertEq(t, "indent", "[\n  1,\n  [\n    2,\n    3\n  ]\n]", buf.String())
+	})
+	t.Run("nested", func(t *testing.T) {
+		type T struct {
+			Rows json.ArrayFunc `json:"rows"`
+			None json.ArrayFunc `json:"none"`
+		}
+		b, err := json.Marshal(T{Rows: rows(row{1, "a"}, rows(true, false))})
+		assertErr(t, err)
+		assertEq(t, "nested", `{"rows":[{"id":1,"name":"a"},[true,false]],"none":null}`, string(b))
+	})
+	t.Run("error", func(t *testing.T) {
+		var buf bytes.Buffer
+		cursorErr := errors.New("cursor closed")
+		err := json.NewEncoder(&buf).EncodeArrayFunc(rows(1, 2, cursorErr))
+		if err != cursorErr {
+			t.Fatalf("expected cursor error but got %v", err)
+		}
+		assertEq(t, "written before the error", `[1,2`, buf.String())
+	})
+}
+
+// members returns an ObjectFunc producing the members of keys and values alternating in kvs.
+func members(kvs ...interface{}) json.ObjectFunc {
+	return func() (string, interface{}, bool, error) {
+		if len(kvs) == 0 {
+			return "", nil, false, nil
+		}
+		if err, ok := kvs[0].(error); ok {
+			return "", nil, false, err
+		}
+		key, v := kvs[0].(string), kvs[1]
+		kvs = kvs[2:]
+		return key, v, true, nil
+	}
+}
+
+func Test_Encoder_EncodeObjectFunc(t *testing.T) {
+	t.Run("flush every member", func(t *testing.T) {
+		w := &recordingWriter{}
+		enc := json.NewEncoder(w)
+		assertErr(t, enc.EncodeObjectFunc(members("title", "report", "total", 2)))
+		assertEq(t, "writes", 3, len(w.writes))
+		assertEq(t, "first write", `{"title":"report"`, w.writes[0])
+		assertEq(t, "second write", `,"total":2`, w.writes[1])
+		assertEq(t, "last write", `}`, w.writes[2])
+	})
+	t.Run("empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		assertErr(t, json.NewEncoder(&buf).EncodeObjectFunc(members()))
+		assertEq(t, "empty", `{}`, buf.String())
+	})
+	t.Run("streamed values", func(t *testing.T) {
+		w := &recordingWriter{}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		report := members(
+			"rows", rows(1, 2),
+			"summary", members("count", 2),
+		)
+		assertErr(t, enc.EncodeObjectFunc(report))
+		expected := "{\n  \"rows\": [\n    1,\n    2\n  ],\n  \"summary\": {\n    \"count\": 2\n  }\n}"
+		var got string
+		for _, s := range w.writes {
+			got += s
+		}
+		assertEq(t, "indent", expected, got)
+		assertEq(t, "first write", "{\n  \"rows\": [\n    1", w.writes[0])
+	})
+	t.Run("field", func(t *testing.T) {
+		type T struct {
+			Sections json.ObjectFunc `json:"sections"`
+		}
+		b, err := json.Marshal(T{Sections: members("b", true, "a", nil)})
+		assertErr(t, err)
+		assertEq(t, "field", `{"sections":{"b":true,"a":null}}`, string(b))
+	})
+	t.Run("error", func(t *testing.T) {
+		var buf bytes.Buffer
+		reportErr := errors.New("query failed")
+		err := json.NewEncoder(&buf).EncodeObjectFunc(members("a", 1, reportErr))
+		if err != reportErr {
+			t.Fatalf("expected query error but got %v", err)
+		}
+		assertEq(t, "written before the error", `{"a":1`, buf.String())
+	})
+}
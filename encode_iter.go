@@ -0,0 +1,104 @@
+package json
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// iterArity reports the number of values yielded by an iterator function type,
+// which is 1 for func(yield func(V) bool) such as iter.Seq[V]
+// and 2 for func(yield func(K, V) bool) such as iter.Seq2[K, V].
+// It returns 0 for any other type.
+func iterArity(typ reflect.Type) int {
+	if typ.Kind() != reflect.Func || typ.NumIn() != 1 || typ.NumOut() != 0 {
+		return 0
+	}
+	yield := typ.In(0)
+	if yield.Kind() != reflect.Func || yield.NumOut() != 1 || yield.Out(0).Kind() != reflect.Bool {
+		return 0
+	}
+	switch yield.NumIn() {
+	case 1:
+		return 1
+	case 2:
+		return 2
+	}
+	return 0
+}
+
+func (e *Encoder) compileIter(typ *rtype) (*opcode, error) {
+	if t := rtype2type(typ); t != arrayFuncType && t != objectFuncType && iterArity(t) == 0 {
+		return nil, &UnsupportedTypeError{Type: rtype2type(typ)}
+	}
+	return newOpCode(opIter, typ, e.indent, newEndOp(e.indent)), nil
+}
+
+func (e *Encoder) encodeIterPtr(typ *rtype, p uintptr, indent int) error {
+	return e.encodeIter(reflect.NewAt(rtype2type(typ), unsafe.Pointer(p)).Elem(), indent)
+}
+
+// encodeIter encodes the values yielded by fn as they are produced,
+// as an array for iter.Seq and as an object for iter.Seq2.
+func (e *Encoder) encodeIter(fn reflect.Value, indent int) error {
+	switch fn.Type() {
+	case arrayFuncType:
+		return e.encodeArrayFunc(fn.Interface().(ArrayFunc), indent)
+	case objectFuncType:
+		return e.encodeObjectFunc(fn.Interface().(ObjectFunc), indent)
+	}
+	arity := iterArity(fn.Type())
+	if arity == 0 {
+		return &UnsupportedTypeError{Type: fn.Type()}
+	}
+	if fn.IsNil() {
+		e.encodeNull()
+		return nil
+	}
+	start, end := byte('['), byte(']')
+	if arity == 2 {
+		start, end = '{', '}'
+	}
+	var (
+		n   int
+		err error
+	)
+	e.encodeByte(start)
+	yield := reflect.MakeFunc(fn.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		if n > 0 {
+			e.encodeByte(',')
+		}
+		if e.enabledIndent {
+			e.encodeByte('\n')
+			e.encodeIndent(indent + 1)
+		}
+		n++
+		value := args[0]
+		if arity == 2 {
+			var key string
+			key, err = objectKey(args[0].Interface())
+			if err != nil {
+				return []reflect.Value{reflect.ValueOf(false)}
+			}
+			e.encodeString(e.transformKey(key))
+			e.encodeByte(':')
+			if e.enabledIndent {
+				e.encodeByte(' ')
+			}
+			value = args[1]
+		}
+		if err = e.encodeInterfaceValue(value.Interface(), indent+1); err != nil {
+			return []reflect.Value{reflect.ValueOf(false)}
+		}
+		return []reflect.Value{reflect.ValueOf(true)}
+	})
+	fn.Call([]reflect.Value{yield})
+	if err != nil {
+		return err
+	}
+	if e.enabledIndent && n > 0 {
+		e.encodeByte('\n')
+		e.encodeIndent(indent)
+	}
+	e.encodeByte(end)
+	return nil
+}
@@ -0,0 +1,70 @@
+//go:build go1.23
+// +build go1.23
+
+package json_test
+
+import (
+	"iter"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_EncodeIter(t *testing.T) {
+	t.Run("seq", func(t *testing.T) {
+		b, err := json.Marshal(slices.Values([]int{1, 2, 3}))
+		assertErr(t, err)
+		assertEq(t, "iter.Seq", `[1,2,3]`, string(b))
+	})
+	t.Run("seq2", func(t *testing.T) {
+		seq := maps.All(map[string]bool{"a": true})
+		b, err := json.Marshal(seq)
+		assertErr(t, err)
+		assertEq(t, "iter.Seq2", `{"a":true}`, string(b))
+	})
+	t.Run("field", func(t *testing.T) {
+		type T struct {
+			Values iter.Seq[string]            `json:"values"`
+			Pairs  iter.Seq2[string, []int]    `json:"pairs"`
+			Nil    iter.Seq[int]               `json:"nil"`
+			Empty  iter.Seq2[string, struct{}] `json:"empty"`
+		}
+		v := T{
+			Values: slices.Values([]string{"x", "y"}),
+			Pairs: func(yield func(string, []int) bool) {
+				_ = yield("b", []int{1}) && yield("a", []int{2, 3})
+			},
+			Empty: func(yield func(string, struct{}) bool) {},
+		}
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "iter fields", `{"values":["x","y"],"pairs":{"b":[1],"a":[2,3]},"nil":null,"empty":{}}`, string(b))
+	})
+	t.Run("interface", func(t *testing.T) {
+		b, err := json.Marshal([]interface{}{slices.Values([]int{1})})
+		assertErr(t, err)
+		assertEq(t, "iter in interface", `[[1]]`, string(b))
+	})
+	t.Run("indent", func(t *testing.T) {
+		b, err := json.MarshalIndent(slices.Values([]int{1, 2}), "", "  ")
+		assertErr(t, err)
+		assertEq(t, "iter indent", "[\n  1,\n  2\n]", string(b))
+	})
+	t.Run("stops on error", func(t *testing.T) {
+		calls := 0
+		seq := func(yield func(interface{}) bool) {
+			for _, v := range []interface{}{1, func() {}, 2} {
+				calls++
+				if !yield(v) {
+					return
+				}
+			}
+		}
+		if _, err := json.Marshal(iter.Seq[interface{}](seq)); err == nil {
+			t.Fatal("expected error")
+		}
+		assertEq(t, "calls", 2, calls)
+	})
+}
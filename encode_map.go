@@ -0,0 +1,187 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// mapIter iterates over the entries of a map in output order.
+// By default the entries are copied one at a time into a key and a value,
+// which the opcodes read through pointers.
+// When the keys must be converted, transformed or ordered, mapIter iterates over
+// a snapshot instead: all keys and values are copied into addressable values.
+type mapIter struct {
+	iter   *reflect.MapIter // the entries copied one at a time, or nil with a snapshot
+	key    reflect.Value
+	value  reflect.Value
+	moved  bool // whether the entry in key and value was encoded, so that the next one is copied when its key is read
+	keys   []reflect.Value
+	values []reflect.Value
+	idx    int
+}
+
+func (e *Encoder) mapiterinit(mapType *rtype, m unsafe.Pointer) (*mapIter, error) {
+	typ := rtype2type(mapType)
+	rv := reflect.NewAt(typ, unsafe.Pointer(&m)).Elem()
+	if typ.Key().Kind() == reflect.String && e.keyTransformer == nil && !e.hasMapKeyOrder() {
+		iter := &mapIter{
+			iter:  rv.MapRange(),
+			key:   reflect.New(typ.Key()).Elem(),
+			value: reflect.New(typ.Elem()).Elem(),
+		}
+		iter.next()
+		return iter, nil
+	}
+	iter := &mapIter{
+		keys:   make([]reflect.Value, 0, rv.Len()),
+		values: make([]reflect.Value, 0, rv.Len()),
+	}
+	keyType := typ.Key()
+	if keyType.Kind() != reflect.String {
+		keyType = stringType // the key opcodes of such maps encode the keys converted by mapKey
+	}
+	valueType := typ.Elem()
+	r := rv.MapRange()
+	for r.Next() {
+		k := reflect.New(keyType).Elem()
+		if keyType == stringType {
+			key, err := e.mapKey(r.Key())
+			if err != nil {
+				return nil, err
+			}
+			k.SetString(key)
+		} else {
+			k.Set(r.Key())
+		}
+		if e.keyTransformer != nil {
+			k.SetString(e.keyTransformer(k.String()))
+		}
+		v := reflect.New(valueType).Elem()
+		v.Set(r.Value())
+		iter.keys = append(iter.keys, k)
+		iter.values = append(iter.values, v)
+	}
+	if e.hasMapKeyOrder() {
+		keys := make([]string, len(iter.keys))
+		for i, k := range iter.keys {
+			keys[i] = mapKeyString(k)
+		}
+		sort.Stable(&mapEntries{iter: iter, keys: keys, less: e.mapKeyLess})
+	}
+	return iter, nil
+}
+
+var stringType = reflect.TypeOf("")
+
+// mapKey returns the object key written for a map key that is not a string.
+// Integers and encoding.TextMarshalers are converted like encoding/json does;
+// other keys, such as bools or floats held in interface{} keys, are only written
+// as their fmt.Sprint text, or "null" for a nil key, when SetStringifyMapKeys is enabled.
+func (e *Encoder) mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.Interface {
+		if k.IsNil() {
+			if e.enabledMapKeyStringify {
+				return "null", nil
+			}
+			return "", &UnsupportedValueError{Value: k, Str: "nil map key"}
+		}
+		k = k.Elem()
+	}
+	key, err := objectKey(k.Interface())
+	if err == nil {
+		return key, nil
+	}
+	if _, unsupported := err.(*UnsupportedTypeError); unsupported && e.enabledMapKeyStringify {
+		return fmt.Sprint(k.Interface()), nil
+	}
+	return "", err
+}
+
+// next copies the next entry of it.iter into it.key and it.value.
+func (it *mapIter) next() {
+	if it.iter.Next() {
+		setIterKey(it.key, it.iter)
+		setIterValue(it.value, it.iter)
+	}
+}
+
+func mapiterkey(it *mapIter) unsafe.Pointer {
+	if it.iter != nil {
+		if it.moved {
+			it.moved = false
+			it.next()
+		}
+		return unsafe.Pointer(it.key.UnsafeAddr())
+	}
+	return unsafe.Pointer(it.keys[it.idx].UnsafeAddr())
+}
+
+func mapitervalue(it *mapIter) unsafe.Pointer {
+	if it.iter != nil {
+		return unsafe.Pointer(it.value.UnsafeAddr())
+	}
+	return unsafe.Pointer(it.values[it.idx].UnsafeAddr())
+}
+
+func mapiternext(it *mapIter) {
+	if it.iter != nil {
+		// the opcodes of the value still read it.value
+		it.moved = true
+		return
+	}
+	it.idx++
+}
+
+// mapKeyString returns the string a map key is ordered by.
+func mapKeyString(k reflect.Value) string {
+	if s, err := objectKey(k.Interface()); err == nil {
+		return s
+	}
+	return fmt.Sprint(k.Interface())
+}
+
+// mapEntries sorts the entries of a mapIter by their key strings.
+type mapEntries struct {
+	iter *mapIter
+	keys []string
+	less func(a, b string) bool
+}
+
+func (m *mapEntries) Len() int { return len(m.keys) }
+
+func (m *mapEntries) Less(i, j int) bool { return m.less(m.keys[i], m.keys[j]) }
+
+func (m *mapEntries) Swap(i, j int) {
+	m.keys[i], m.keys[j] = m.keys[j], m.keys[i]
+	m.iter.keys[i], m.iter.keys[j] = m.iter.keys[j], m.iter.keys[i]
+	m.iter.values[i], m.iter.values[j] = m.iter.values[j], m.iter.values[i]
+}
+
+// hasMapKeyOrder reports whether any map key ordering option is set.
+func (e *Encoder) hasMapKeyOrder() bool {
+	return e.enabledMapKeySort || e.mapKeyCompare != nil || len(e.mapKeyPriority) > 0
+}
+
+// mapKeyLess orders keys listed by SetMapKeyPriority first, in the listed order,
+// followed by the other keys ordered by the comparator of SetMapKeyOrder, or ascending.
+func (e *Encoder) mapKeyLess(a, b string) bool {
+	pa, okA := e.mapKeyPriority[a]
+	pb, okB := e.mapKeyPriority[b]
+	switch {
+	case okA && okB:
+		return pa < pb
+	case okA:
+		return true
+	case okB:
+		return false
+	}
+	if e.mapKeyCompare != nil {
+		return e.mapKeyCompare(a, b)
+	}
+	if e.enabledMapKeySort {
+		return a < b
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+//go:build !go1.18
+// +build !go1.18
+
+package json
+
+import "reflect"
+
+// setIterKey copies the key of the current entry of it into k.
+// reflect.Value.SetIterKey was only added in Go 1.18, so the key is read with Key.
+func setIterKey(k reflect.Value, it *reflect.MapIter) {
+	k.Set(it.Key())
+}
+
+// setIterValue copies the value of the current entry of it into v.
+func setIterValue(v reflect.Value, it *reflect.MapIter) {
+	v.Set(it.Value())
+}
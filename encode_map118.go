@@ -0,0 +1,17 @@
+//go:build go1.18
+// +build go1.18
+
+package json
+
+import "reflect"
+
+// setIterKey copies the key of the current entry of it into k.
+// Since Go 1.18 the key is copied without allocating.
+func setIterKey(k reflect.Value, it *reflect.MapIter) {
+	k.SetIterKey(it)
+}
+
+// setIterValue copies the value of the current entry of it into v.
+func setIterValue(v reflect.Value, it *reflect.MapIter) {
+	v.SetIterValue(it)
+}
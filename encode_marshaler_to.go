@@ -0,0 +1,200 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// tokenWriter is the state of the value written by a MarshalJSONTo method.
+type tokenWriter struct {
+	indent int           // indentation of the value
+	stack  []*tokenFrame // the objects and arrays being written, innermost last
+	values int           // values written at the top level
+}
+
+type tokenFrame struct {
+	delim   Delim // '{' or '['
+	written int   // members or elements written
+	key     bool  // an object key was written, its value is next
+}
+
+// encodeMarshalerTo calls the MarshalJSONTo method of the value of typ at p,
+// which is written at the given indentation.
+func (e *Encoder) encodeMarshalerTo(typ *rtype, p uintptr, indent int) error {
+	if p == 0 && typ.Kind() == reflect.Ptr {
+		e.encodeNull()
+		return nil
+	}
+	v := *(*interface{})(unsafe.Pointer(&interfaceHeader{
+		typ: typ,
+		ptr: unsafe.Pointer(p),
+	}))
+	outer := e.tokens
+	w := &tokenWriter{indent: indent}
+	e.tokens = w
+	err := v.(MarshalerTo).MarshalJSONTo(e)
+	e.tokens = outer
+	if err == nil && (w.values != 1 || len(w.stack) != 0) {
+		err = errors.New("did not write exactly one value")
+	}
+	if err != nil {
+		return &MarshalerError{
+			Type:       rtype2type(typ),
+			Err:        err,
+			sourceFunc: "MarshalJSONTo",
+		}
+	}
+	return nil
+}
+
+// WriteToken writes a token of the value written by a MarshalJSONTo method,
+// with the separators and indentation it needs. t holds a Delim, a bool,
+// a float64, a Number, a string or nil, like the tokens returned by Decoder.Token.
+// A string written where an object key is expected is written as the key.
+// WriteToken returns an error when it is not called from MarshalJSONTo or
+// when t cannot be written at this point of the value.
+func (e *Encoder) WriteToken(t Token) error {
+	w := e.tokens
+	if w == nil {
+		return errors.New("json: WriteToken called outside of MarshalJSONTo")
+	}
+	if d, ok := t.(Delim); ok && (d == '}' || d == ']') {
+		return w.close(e, d)
+	}
+	if frame := w.top(); frame != nil && frame.delim == '{' && !frame.key {
+		key, ok := t.(string)
+		if !ok {
+			return fmt.Errorf("json: WriteToken of %T instead of an object key", t)
+		}
+		e.writeTokenSeparator(frame, w.indent+len(w.stack))
+		e.encodeString(e.transformKey(key))
+		e.encodeByte(':')
+		if e.enabledIndent {
+			e.encodeByte(' ')
+		}
+		frame.key = true
+		return nil
+	}
+	if err := w.beforeValue(e); err != nil {
+		return err
+	}
+	switch t := t.(type) {
+	case Delim:
+		if t != '{' && t != '[' {
+			return fmt.Errorf("json: WriteToken of invalid delimiter %q", rune(t))
+		}
+		e.encodeByte(byte(t))
+		w.stack = append(w.stack, &tokenFrame{delim: t})
+		return nil
+	case bool:
+		e.encodeBool(t)
+	case float64:
+		if math.IsInf(t, 0) || math.IsNaN(t) {
+			return &UnsupportedValueError{
+				Value: reflect.ValueOf(t),
+				Str:   strconv.FormatFloat(t, 'g', -1, 64),
+			}
+		}
+		e.encodeFloat64(t)
+	case Number:
+		if t == "" {
+			t = "0"
+		}
+		if !validNumber([]byte(t)) {
+			return fmt.Errorf("json: WriteToken of invalid number literal %q", string(t))
+		}
+		e.encodeBytes([]byte(t))
+	case string:
+		e.encodeString(t)
+	case nil:
+		e.encodeNull()
+	default:
+		return fmt.Errorf("json: WriteToken of unsupported type %T", t)
+	}
+	w.afterValue()
+	return nil
+}
+
+// encodeToken encodes v as a value written by a MarshalJSONTo method.
+func (e *Encoder) encodeToken(v interface{}) error {
+	w := e.tokens
+	if frame := w.top(); frame != nil && frame.delim == '{' && !frame.key {
+		return fmt.Errorf("json: Encode of %T instead of an object key", v)
+	}
+	if err := w.beforeValue(e); err != nil {
+		return err
+	}
+	if err := e.encodeInterfaceValue(v, w.indent+len(w.stack)); err != nil {
+		return err
+	}
+	w.afterValue()
+	return nil
+}
+
+func (w *tokenWriter) top() *tokenFrame {
+	if len(w.stack) == 0 {
+		return nil
+	}
+	return w.stack[len(w.stack)-1]
+}
+
+// beforeValue writes what precedes the next value and checks that a value can be written.
+func (w *tokenWriter) beforeValue(e *Encoder) error {
+	frame := w.top()
+	switch {
+	case frame == nil:
+		if w.values > 0 {
+			return errors.New("json: MarshalJSONTo wrote more than one value")
+		}
+		w.values++
+	case frame.delim == '[':
+		e.writeTokenSeparator(frame, w.indent+len(w.stack))
+	}
+	return nil
+}
+
+func (w *tokenWriter) afterValue() {
+	if frame := w.top(); frame != nil {
+		frame.key = false
+		if frame.delim == '{' {
+			frame.written++
+		}
+	}
+}
+
+func (w *tokenWriter) close(e *Encoder, d Delim) error {
+	frame := w.top()
+	if frame == nil || frame.delim == '{' && d != '}' || frame.delim == '[' && d != ']' {
+		return fmt.Errorf("json: WriteToken of unexpected %q", rune(d))
+	}
+	if frame.key {
+		return errors.New("json: WriteToken of '}' after an object key")
+	}
+	w.stack = w.stack[:len(w.stack)-1]
+	if e.enabledIndent && frame.written > 0 {
+		e.encodeByte('\n')
+		e.encodeIndent(w.indent + len(w.stack))
+	}
+	e.encodeByte(byte(d))
+	w.afterValue()
+	return nil
+}
+
+// writeTokenSeparator writes the comma and indentation before a member or element
+// of frame, indented by indent, and counts an array element.
+func (e *Encoder) writeTokenSeparator(frame *tokenFrame, indent int) {
+	if frame.written > 0 {
+		e.encodeByte(',')
+	}
+	if e.enabledIndent {
+		e.encodeByte('\n')
+		e.encodeIndent(indent)
+	}
+	if frame.delim == '[' {
+		frame.written++
+	}
+}
@@ -28,7 +28,20 @@ const (
 	opInterface
 	opPtr
 	opMarshalJSON
+	opMarshalJSONTo
+	opAppendJSON
 	opMarshalText
+	opReader
+	opSyncMap
+	opIter
+	opChan
+	opConvert
+	opWellKnown
+	opGenerated
+	opRawMessage
+	opPath
+	opUnion
+	opBeforeMarshal
 
 	opSliceHead
 	opSliceElem
@@ -147,6 +160,11 @@ const (
 
 	opStructFieldRecursive
 
+	opStructFieldHeadInline
+	opStructFieldHeadInlineIndent
+	opStructFieldInline
+	opStructFieldInlineIndent
+
 	opStructFieldPtrHeadIndent
 	opStructFieldPtrHeadIntIndent
 	opStructFieldPtrHeadInt8Indent
@@ -304,8 +322,34 @@ func (t opType) String() string {
 		return "PTR"
 	case opMarshalJSON:
 		return "MARSHAL_JSON"
+	case opMarshalJSONTo:
+		return "MARSHAL_JSON_TO"
+	case opAppendJSON:
+		return "APPEND_JSON"
 	case opMarshalText:
 		return "MARSHAL_TEXT"
+	case opReader:
+		return "READER"
+	case opSyncMap:
+		return "SYNC_MAP"
+	case opIter:
+		return "ITER"
+	case opChan:
+		return "CHAN"
+	case opConvert:
+		return "CONVERT"
+	case opWellKnown:
+		return "WELL_KNOWN"
+	case opGenerated:
+		return "GENERATED"
+	case opRawMessage:
+		return "RAW_MESSAGE"
+	case opPath:
+		return "PATH"
+	case opUnion:
+		return "UNION"
+	case opBeforeMarshal:
+		return "BEFORE_MARSHAL"
 
 	case opSliceHead:
 		return "SLICE_HEAD"
@@ -366,6 +410,15 @@ func (t opType) String() string {
 
 	case opStructFieldRecursive:
 		return "STRUCT_FIELD_RECURSIVE"
+
+	case opStructFieldHeadInline:
+		return "STRUCT_FIELD_HEAD_INLINE"
+	case opStructFieldHeadInlineIndent:
+		return "STRUCT_FIELD_HEAD_INLINE_INDENT"
+	case opStructFieldInline:
+		return "STRUCT_FIELD_INLINE"
+	case opStructFieldInlineIndent:
+		return "STRUCT_FIELD_INLINE_INDENT"
 	case opStructFieldHead:
 		return "STRUCT_FIELD_HEAD"
 	case opStructFieldHeadInt:
@@ -837,7 +890,23 @@ func (c *opcode) copy(codeMap map[uintptr]*opcode) *opcode {
 		code = c.toMapValueCode().copy(codeMap)
 	case opStructFieldRecursive:
 		code = c.toRecursiveCode().copy(codeMap)
-	case opStructFieldHead,
+	case opInterface:
+		code = c.toInterfaceCode().copy(codeMap)
+	case opConvert:
+		code = c.toConvertCode().copy(codeMap)
+	case opWellKnown:
+		code = c.toWellKnownCode().copy(codeMap)
+	case opGenerated:
+		code = c.toGeneratedCode().copy(codeMap)
+	case opPath:
+		code = c.toPathCode().copy(codeMap)
+	case opUnion:
+		code = c.toUnionCode().copy(codeMap)
+	case opStructFieldHeadInline,
+		opStructFieldHeadInlineIndent,
+		opStructFieldInline,
+		opStructFieldInlineIndent,
+		opStructFieldHead,
 		opStructFieldHeadInt,
 		opStructFieldHeadInt8,
 		opStructFieldHeadInt16,
@@ -1031,7 +1100,13 @@ func (c *opcode) dump() string {
 	codes := []string{}
 	for code := c; code.op != opEnd; {
 		indent := strings.Repeat(" ", code.indent)
-		codes = append(codes, fmt.Sprintf("%s%s", indent, code.op))
+		line := fmt.Sprintf("%s%s", indent, code.op)
+		if code.op != opStructFieldRecursive && strings.HasPrefix(code.op.String(), "STRUCT_FIELD") {
+			if key := code.toStructFieldCode().key; len(key) > 0 {
+				line += " " + string(key[:len(key)-1])
+			}
+		}
+		codes = append(codes, line)
 		switch code.op {
 		case opArrayElem, opArrayElemIndent:
 			code = code.toArrayElemCode().end
@@ -1227,6 +1302,22 @@ type structFieldCode struct {
 	offset    uintptr
 	nextField *opcode
 	end       *opcode
+	isEmpty   func(uintptr) bool // set for omitempty fields tagged with the deep or conv option
+	isNull    func(uintptr) bool // set for fields whose value may encode as null
+	inline    *inlineMap         // set for the inline map field
+}
+
+// isNullValue reports whether the field value at p encodes as null.
+func (c *structFieldCode) isNullValue(p uintptr) bool {
+	return c.isNull != nil && c.isNull(p)
+}
+
+// isEmptyValue reports whether the omitempty field value at p must be omitted.
+func (c *structFieldCode) isEmptyValue(p uintptr) bool {
+	if c.isEmpty != nil {
+		return c.isEmpty(p)
+	}
+	return *(*uintptr)(unsafe.Pointer(p)) == 0
 }
 
 func (c *structFieldCode) copy(codeMap map[uintptr]*opcode) *opcode {
@@ -1238,8 +1329,11 @@ func (c *structFieldCode) copy(codeMap map[uintptr]*opcode) *opcode {
 		return code
 	}
 	field := &structFieldCode{
-		key:    c.key,
-		offset: c.offset,
+		key:     c.key,
+		offset:  c.offset,
+		isEmpty: c.isEmpty,
+		isNull:  c.isNull,
+		inline:  c.inline,
 	}
 	code := (*opcode)(unsafe.Pointer(field))
 	codeMap[addr] = code
@@ -1280,7 +1374,7 @@ type mapKeyCode struct {
 	*opcodeHeader
 	idx  int
 	len  int
-	iter unsafe.Pointer
+	iter *mapIter
 	end  *opcode
 }
 
@@ -1305,7 +1399,7 @@ func (c *mapKeyCode) copy(codeMap map[uintptr]*opcode) *opcode {
 	return code
 }
 
-func (c *mapKeyCode) set(len int, iter unsafe.Pointer) {
+func (c *mapKeyCode) set(len int, iter *mapIter) {
 	c.idx = 0
 	c.len = len
 	c.iter = iter
@@ -1313,7 +1407,7 @@ func (c *mapKeyCode) set(len int, iter unsafe.Pointer) {
 
 type mapValueCode struct {
 	*opcodeHeader
-	iter unsafe.Pointer
+	iter *mapIter
 }
 
 func (c *mapValueCode) copy(codeMap map[uintptr]*opcode) *opcode {
@@ -1334,7 +1428,7 @@ func (c *mapValueCode) copy(codeMap map[uintptr]*opcode) *opcode {
 	return code
 }
 
-func (c *mapValueCode) set(iter unsafe.Pointer) {
+func (c *mapValueCode) set(iter *mapIter) {
 	c.iter = iter
 }
 
@@ -1385,7 +1479,7 @@ func (c *interfaceCode) copy(codeMap map[uintptr]*opcode) *opcode {
 	if code, exists := codeMap[addr]; exists {
 		return code
 	}
-	iface := &interfaceCode{}
+	iface := &interfaceCode{root: c.root}
 	code := (*opcode)(unsafe.Pointer(iface))
 	codeMap[addr] = code
 
@@ -217,6 +217,9 @@ var safeSet = [utf8.RuneSelf]bool{
 
 var hex = "0123456789abcdef"
 
+// lineTerminatorLeadByte is the first byte of the UTF-8 encoding of both U+2028 and U+2029.
+const lineTerminatorLeadByte = 0xE2
+
 func (e *Encoder) encodeEscapedString(s string) {
 	valLen := len(s)
 	e.buf = append(e.buf, '"')
@@ -314,7 +317,7 @@ func (e *Encoder) encodeNoEscapedString(s string) {
 	i := 0
 	for ; i < valLen; i++ {
 		c := s[i]
-		if c > 31 && c != '"' && c != '\\' {
+		if c > 31 && c != '"' && c != '\\' && !(c == lineTerminatorLeadByte && e.enabledLineTerminatorEscape) {
 			e.buf = append(e.buf, c)
 		} else {
 			break
@@ -361,6 +364,19 @@ func (e *Encoder) writeStringSlowPath(i int, s string, valLen int) {
 			start = i
 			continue
 		}
+		if e.enabledLineTerminatorEscape && s[i] == lineTerminatorLeadByte {
+			c, size := utf8.DecodeRuneInString(s[i:])
+			if c == '\u2028' || c == '\u2029' {
+				if start < i {
+					e.buf = append(e.buf, s[start:i]...)
+				}
+				e.buf = append(e.buf, `\u202`...)
+				e.buf = append(e.buf, hex[c&0xF])
+				i += size
+				start = i
+				continue
+			}
+		}
 		i++
 		continue
 	}
@@ -0,0 +1,136 @@
+package json
+
+import (
+	"encoding"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+var syncMapType = type2rtype(reflect.TypeOf((*sync.Map)(nil)).Elem())
+
+type syncMapEntry struct {
+	key   string
+	value interface{}
+}
+
+func (e *Encoder) compileSyncMap(typ *rtype) (*opcode, error) {
+	return newOpCode(opSyncMap, typ, e.indent, newEndOp(e.indent)), nil
+}
+
+func (e *Encoder) encodeSyncMap(m *sync.Map, indent int) error {
+	keyFunc := e.syncMapKeyFunc
+	if keyFunc == nil {
+		keyFunc = objectKey
+	}
+	var (
+		entries []syncMapEntry
+		err     error
+	)
+	m.Range(func(k, v interface{}) bool {
+		var key string
+		key, err = keyFunc(k)
+		if err != nil {
+			return false
+		}
+		entries = append(entries, syncMapEntry{key: e.transformKey(key), value: v})
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if e.hasMapKeyOrder() {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return e.mapKeyLess(entries[i].key, entries[j].key)
+		})
+	} else if e.enabledSyncMapKeySort {
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].key < entries[j].key
+		})
+	}
+	if len(entries) == 0 {
+		e.encodeBytes([]byte{'{', '}'})
+		return nil
+	}
+	e.encodeByte('{')
+	for i, entry := range entries {
+		if i > 0 {
+			e.encodeByte(',')
+		}
+		if e.enabledIndent {
+			e.encodeByte('\n')
+			e.encodeIndent(indent + 1)
+		}
+		e.encodeString(entry.key)
+		e.encodeByte(':')
+		if e.enabledIndent {
+			e.encodeByte(' ')
+		}
+		if err := e.encodeInterfaceValue(entry.value, indent+1); err != nil {
+			return err
+		}
+	}
+	if e.enabledIndent {
+		e.encodeByte('\n')
+		e.encodeIndent(indent)
+	}
+	e.encodeByte('}')
+	return nil
+}
+
+// encodeInterfaceValue encodes the dynamic value of v the same way opInterface does.
+func (e *Encoder) encodeInterfaceValue(v interface{}, indent int) error {
+	header := (*interfaceHeader)(unsafe.Pointer(&v))
+	typ := header.typ
+	if typ == nil {
+		e.encodeNull()
+		return nil
+	}
+	if isDataWordKind(typ.Kind()) {
+		return e.encodeDataWordValue(reflect.ValueOf(v), indent)
+	}
+	e.indent = indent
+	var (
+		c   *opcode
+		err error
+	)
+	switch typ.Kind() {
+	case reflect.Map:
+		c, err = e.compileMap(typ, false, false, e.enabledIndent)
+	case reflect.Ptr:
+		c, err = e.compile(typ.Elem(), false, e.enabledIndent)
+	default:
+		c, err = e.compile(typ, false, e.enabledIndent)
+	}
+	if err != nil {
+		return err
+	}
+	c.ptr = valuePointer(typ, header)
+	err = e.run(c)
+	runtime.KeepAlive(header)
+	return err
+}
+
+// objectKey converts string, integer and encoding.TextMarshaler keys to object keys.
+func objectKey(k interface{}) (string, error) {
+	if tm, ok := k.(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		if err != nil {
+			return "", &MarshalerError{Type: reflect.TypeOf(k), Err: err, sourceFunc: "MarshalText"}
+		}
+		return string(text), nil
+	}
+	rv := reflect.ValueOf(k)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return "", &UnsupportedTypeError{Type: reflect.TypeOf(k)}
+}
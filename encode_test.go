@@ -1,8 +1,19 @@
 package json_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	stdjson "encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -91,6 +102,11 @@ func Test_Marshal(t *testing.T) {
 		assertErr(t, err)
 		assertEq(t, "string", `"hello world"`, string(bytes))
 	})
+	t.Run("nil", func(t *testing.T) {
+		bytes, err := json.Marshal(nil)
+		assertErr(t, err)
+		assertEq(t, "nil", `null`, string(bytes))
+	})
 	t.Run("struct", func(t *testing.T) {
 		bytes, err := json.Marshal(struct {
 			A int    `json:"a"`
@@ -345,6 +361,38 @@ func Test_Marshal(t *testing.T) {
 			assertErr(t, err)
 			assertEq(t, "map[string]interface{}", len(`{"a":1,"b":2.1,"c":{"A":10},"d":4}`), len(string(bytes)))
 		})
+		t.Run("map in interface", func(t *testing.T) {
+			bytes, err := json.Marshal([]interface{}{map[string]int{"a": 1}})
+			assertErr(t, err)
+			assertEq(t, "map in interface", `[{"a":1}]`, string(bytes))
+		})
+	})
+	t.Run("single pointer", func(t *testing.T) {
+		v := 1
+		bytes, err := json.Marshal(struct{ P *int }{&v})
+		assertErr(t, err)
+		assertEq(t, "struct", `{"P":1}`, string(bytes))
+		bytes, err = json.Marshal(struct{ P *int }{})
+		assertErr(t, err)
+		assertEq(t, "nil struct field", `{"P":null}`, string(bytes))
+		bytes, err = json.Marshal(struct{ P **int }{})
+		assertErr(t, err)
+		assertEq(t, "nil struct field of pointer to pointer", `{"P":null}`, string(bytes))
+		bytes, err = json.MarshalIndent(struct{ P *int }{}, "", " ")
+		assertErr(t, err)
+		assertEq(t, "nil struct field indented", "{\n \"P\": null\n}", string(bytes))
+		bytes, err = json.Marshal(struct {
+			P *int
+			Q int
+		}{})
+		assertErr(t, err)
+		assertEq(t, "nil field", `{"P":null,"Q":0}`, string(bytes))
+		bytes, err = json.Marshal([1]*int{&v})
+		assertErr(t, err)
+		assertEq(t, "array", `[1]`, string(bytes))
+		bytes, err = json.Marshal([]interface{}{struct{ M map[string]int }{map[string]int{"a": 1}}})
+		assertErr(t, err)
+		assertEq(t, "interface", `[{"M":{"a":1}}]`, string(bytes))
 	})
 }
 
@@ -365,6 +413,34 @@ func Test_MarshalJSON(t *testing.T) {
 		assertErr(t, err)
 		assertEq(t, "MarshalJSON", `"0001-01-01T00:00:00Z"`, string(bytes))
 	})
+	t.Run("pointer field", func(t *testing.T) {
+		bytes, err := json.Marshal(struct {
+			A *marshalJSON
+			B *marshalJSON
+			C *marshalJSON `json:",omitempty"`
+			D interface{}
+			E []*marshalJSON
+		}{A: &marshalJSON{}, D: &marshalJSON{}, E: []*marshalJSON{{}, nil}})
+		assertErr(t, err)
+		assertEq(t, "MarshalJSON", `{"A":1,"B":null,"D":1,"E":[1,null]}`, string(bytes))
+	})
+}
+
+type marshalText string
+
+func (t *marshalText) MarshalText() ([]byte, error) {
+	return []byte("text:" + *t), nil
+}
+
+func Test_MarshalText(t *testing.T) {
+	text := marshalText(`a"b`)
+	bytes, err := json.Marshal(struct {
+		A *marshalText
+		B *marshalText
+		C []interface{}
+	}{A: &text, C: []interface{}{&text}})
+	assertErr(t, err)
+	assertEq(t, "MarshalText", `{"A":"text:a\"b","B":null,"C":["text:a\"b"]}`, string(bytes))
 }
 
 func Test_MarshalIndent(t *testing.T) {
@@ -439,6 +515,115 @@ func Test_MarshalIndent(t *testing.T) {
 			assertEq(t, "map[string]interface{}", len(result), len(string(bytes)))
 		})
 	})
+	t.Run("pointer in interface", func(t *testing.T) {
+		type T struct {
+			E string
+			F bool
+		}
+		bytes, err := json.MarshalIndent([]interface{}{&T{E: "e"}}, prefix, indent)
+		assertErr(t, err)
+		assertEq(t, "pointer in interface", "[\n-\t{\n-\t\t\"E\": \"e\",\n-\t\t\"F\": false\n-\t}\n-]", string(bytes))
+	})
+	t.Run("deep nesting", func(t *testing.T) {
+		var v interface{} = []int{1}
+		for i := 0; i < 40; i++ {
+			v = []interface{}{v}
+		}
+		expected, err := stdjson.MarshalIndent(v, prefix, indent)
+		assertErr(t, err)
+		bytes, err := json.MarshalIndent(v, prefix, indent)
+		assertErr(t, err)
+		assertEq(t, "deep nesting", string(expected), string(bytes))
+	})
+	t.Run("nested containers", func(t *testing.T) {
+		type T struct {
+			A []int
+			B map[string][]int
+			C [][]int
+			D []int
+			E map[string]int
+			F [1]interface{}
+			H string
+			G struct {
+				A int `json:",omitempty"`
+			}
+		}
+		v := []T{{
+			A: []int{1, 2},
+			B: map[string][]int{"x": {3}},
+			C: [][]int{{4}, {}},
+			D: []int{},
+			E: map[string]int{},
+			F: [1]interface{}{map[string]interface{}{"y": []int{5}}},
+			H: "{",
+		}}
+		for _, in := range []string{"\t", "  ", "...."} {
+			expected, err := stdjson.MarshalIndent(v, prefix, in)
+			assertErr(t, err)
+			bytes, err := json.MarshalIndent(v, prefix, in)
+			assertErr(t, err)
+			assertEq(t, "nested containers", string(expected), string(bytes))
+		}
+	})
+	t.Run("struct types at several depths", func(t *testing.T) {
+		type point struct{ X, Y int }
+		type shape struct {
+			Origin point
+			Points []point
+		}
+		recursive := &recursiveT{A: &recursiveT{B: &recursiveU{T: &recursiveT{D: "x"}}}, D: "y"}
+		for _, v := range []interface{}{[]shape{{Points: []point{{1, 2}}}}, point{3, 4}, recursive} {
+			expected, err := stdjson.MarshalIndent(v, prefix, indent)
+			assertErr(t, err)
+			bytes, err := json.MarshalIndent(v, prefix, indent)
+			assertErr(t, err)
+			assertEq(t, "indented", string(expected), string(bytes))
+		}
+	})
+	t.Run("marshaler output", func(t *testing.T) {
+		type T struct {
+			Raw     json.RawMessage
+			Empty   json.RawMessage
+			Pair    pairMarshaler
+			Pairs   []pairMarshaler
+			ByKey   map[string]json.RawMessage
+			Any     interface{}
+			Literal json.RawMessage
+		}
+		v := []T{{
+			Raw:     json.RawMessage(` { "a" : [1, {"b":"x, [y]: \\\"z\\\""}],` + "\n" + `"e": { }, "f":[ ] }`),
+			Empty:   json.RawMessage(`{}`),
+			Pairs:   []pairMarshaler{{}, {}},
+			ByKey:   map[string]json.RawMessage{"k": json.RawMessage(`[1,2]`)},
+			Any:     json.RawMessage(`{"x":{"y":null}}`),
+			Literal: json.RawMessage(`"s"`),
+		}}
+		for _, in := range []string{"\t", "  "} {
+			expected, err := stdjson.MarshalIndent(v, prefix, in)
+			assertErr(t, err)
+			bytes, err := json.MarshalIndent(v, prefix, in)
+			assertErr(t, err)
+			assertEq(t, "marshaler output", string(expected), string(bytes))
+		}
+		bytes, err := json.Marshal(json.RawMessage(`{ "a": 1 }`))
+		assertErr(t, err)
+		assertEq(t, "compact", `{"a":1}`, string(bytes))
+	})
+	t.Run("compact after indented", func(t *testing.T) {
+		type firstIndented struct{ A []int }
+		v := firstIndented{A: []int{1}}
+		_, err := json.MarshalIndent(v, prefix, indent)
+		assertErr(t, err)
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "compact", `{"A":[1]}`, string(bytes))
+	})
+}
+
+type pairMarshaler struct{}
+
+func (pairMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"first":[1,2],"second":{"third":true}}`), nil
 }
 
 type marshalerError struct{}
@@ -453,3 +638,816 @@ func Test_MarshalerError(t *testing.T) {
 	expect := `json: error calling MarshalJSON for type *json_test.marshalerError: unexpected error`
 	assertEq(t, "marshaler error", expect, fmt.Sprint(err))
 }
+
+func Test_EscapeLineTerminators(t *testing.T) {
+	src := "a\u2028b\u2029c"
+	t.Run("html escape", func(t *testing.T) {
+		bytes, err := json.Marshal(src)
+		assertErr(t, err)
+		assertEq(t, "escaped", `"a\u2028b\u2029c"`, string(bytes))
+	})
+	t.Run("without html escape", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		assertErr(t, enc.Encode(src))
+		assertEq(t, "not escaped", "\"a\u2028b\u2029c\"", buf.String())
+	})
+	t.Run("line terminators only", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetEscapeLineTerminators(true)
+		assertErr(t, enc.Encode("<\u2028>é"))
+		assertEq(t, "escaped", "\"<\\u2028>é\"", buf.String())
+	})
+}
+
+func Test_EncodeReader(t *testing.T) {
+	type attachment struct {
+		Name string    `json:"name"`
+		Data io.Reader `json:"data"`
+		Size int       `json:"size"`
+	}
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	exp := `{"name":"a.txt","data":"` + base64.StdEncoding.EncodeToString(data) + `","size":10000}`
+	t.Run("marshal", func(t *testing.T) {
+		b, err := json.Marshal(attachment{Name: "a.txt", Data: bytes.NewReader(data), Size: len(data)})
+		assertErr(t, err)
+		assertEq(t, "attachment", exp, string(b))
+	})
+	t.Run("encoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		assertErr(t, enc.Encode(attachment{Name: "a.txt", Data: bytes.NewReader(data), Size: len(data)}))
+		assertEq(t, "attachment", exp, buf.String())
+	})
+	t.Run("nil", func(t *testing.T) {
+		b, err := json.Marshal(attachment{})
+		assertErr(t, err)
+		assertEq(t, "attachment", `{"name":"","data":null,"size":0}`, string(b))
+	})
+}
+
+func Test_EncodeSyncMap(t *testing.T) {
+	var m sync.Map
+	m.Store("b", 2)
+	m.Store("a", "x")
+	m.Store("c", struct {
+		A int `json:"a"`
+	}{A: 1})
+	t.Run("pointer", func(t *testing.T) {
+		b, err := json.Marshal(&m)
+		assertErr(t, err)
+		assertEq(t, "sync.Map", `{"a":"x","b":2,"c":{"a":1}}`, string(b))
+	})
+	t.Run("field", func(t *testing.T) {
+		type T struct {
+			M   *sync.Map `json:"m"`
+			Nil *sync.Map `json:"nil"`
+		}
+		b, err := json.Marshal(T{M: &m})
+		assertErr(t, err)
+		assertEq(t, "sync.Map", `{"m":{"a":"x","b":2,"c":{"a":1}},"nil":null}`, string(b))
+	})
+	t.Run("indent", func(t *testing.T) {
+		var n sync.Map
+		n.Store("b", 2)
+		n.Store("a", 1)
+		b, err := json.MarshalIndent(&n, "", "  ")
+		assertErr(t, err)
+		assertEq(t, "sync.Map", "{\n  \"a\": 1,\n  \"b\": 2\n}", string(b))
+	})
+	t.Run("key func", func(t *testing.T) {
+		var n sync.Map
+		n.Store(1, true)
+		n.Store(2.5, false)
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetSyncMapKeyFunc(func(k interface{}) (string, error) {
+			return fmt.Sprintf("k%v", k), nil
+		})
+		assertErr(t, enc.Encode(&n))
+		assertEq(t, "sync.Map", `{"k1":true,"k2.5":false}`, buf.String())
+	})
+	t.Run("unsupported key", func(t *testing.T) {
+		var n sync.Map
+		n.Store(2.5, false)
+		if _, err := json.Marshal(&n); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("unsorted", func(t *testing.T) {
+		var n sync.Map
+		n.Store("a", 1)
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetSortSyncMapKeys(false)
+		assertErr(t, enc.Encode(&n))
+		assertEq(t, "sync.Map", `{"a":1}`, buf.String())
+	})
+}
+
+func Test_OmitEmptyDeep(t *testing.T) {
+	type inner struct {
+		Name  string   `json:"name,omitempty"`
+		Tags  []string `json:"tags,omitempty"`
+		Level *int     `json:"level,omitempty"`
+	}
+	type options struct {
+		Enabled bool  `json:"enabled,omitempty"`
+		Inner   inner `json:"inner,omitempty,deep"`
+	}
+	type T struct {
+		ID      int      `json:"id"`
+		Options options  `json:"options,omitempty,deep"`
+		Ptr     *options `json:"ptr,omitempty,deep"`
+		Last    string   `json:"last"`
+	}
+	t.Run("empty", func(t *testing.T) {
+		b, err := json.Marshal(T{ID: 1, Ptr: &options{}})
+		assertErr(t, err)
+		assertEq(t, "deep omitempty", `{"id":1,"last":""}`, string(b))
+	})
+	t.Run("nested value", func(t *testing.T) {
+		v := T{ID: 1, Options: options{Inner: inner{Tags: []string{"a"}}}}
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "deep omitempty", `{"id":1,"options":{"inner":{"tags":["a"]}},"last":""}`, string(b))
+	})
+	t.Run("pointer value", func(t *testing.T) {
+		b, err := json.Marshal(T{ID: 1, Ptr: &options{Enabled: true}})
+		assertErr(t, err)
+		assertEq(t, "deep omitempty", `{"id":1,"ptr":{"enabled":true},"last":""}`, string(b))
+	})
+	t.Run("head field", func(t *testing.T) {
+		type U struct {
+			Options options `json:"options,omitempty,deep"`
+			ID      int     `json:"id"`
+		}
+		b, err := json.Marshal(U{ID: 1})
+		assertErr(t, err)
+		assertEq(t, "deep omitempty", `{"id":1}`, string(b))
+	})
+	t.Run("marshaler", func(t *testing.T) {
+		type U struct {
+			At struct {
+				Time time.Time `json:"time"`
+			} `json:"at,omitempty,deep"`
+		}
+		b, err := json.Marshal(U{})
+		assertErr(t, err)
+		assertEq(t, "deep omitempty", `{}`, string(b))
+	})
+}
+
+func Test_Encoder_SetOmitNull(t *testing.T) {
+	type inner struct {
+		P *int `json:"p"`
+	}
+	type T struct {
+		A *int            `json:"a"`
+		B interface{}     `json:"b"`
+		C *inner          `json:"c"`
+		D []int           `json:"d"`
+		E map[string]int  `json:"e"`
+		F json.RawMessage `json:"f"`
+		G *string         `json:"g,omitempty"`
+		H interface{}     `json:"h"`
+		I inner           `json:"i"`
+		J int             `json:"j"`
+	}
+	encode := func(t *testing.T, v interface{}, indent bool) string {
+		t.Helper()
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetOmitNull(true)
+		if indent {
+			enc.SetIndent("", " ")
+		}
+		assertErr(t, enc.Encode(v))
+		return buf.String()
+	}
+	t.Run("fields", func(t *testing.T) {
+		n := 1
+		assertEq(t, "nulls", `{"d":[],"e":{},"i":{},"j":0}`, encode(t, T{H: (*int)(nil)}, false))
+		assertEq(t, "values", `{"a":1,"b":2,"c":{"p":1},"d":[],"e":{},"i":{"p":1},"j":0}`,
+			encode(t, T{A: &n, B: 2, C: &inner{P: &n}, I: inner{P: &n}}, false))
+		assertEq(t, "indent", "{\n \"d\": [],\n \"e\": {},\n \"i\": {},\n \"j\": 0\n}", encode(t, T{}, true))
+	})
+	t.Run("only nulls", func(t *testing.T) {
+		assertEq(t, "compact", `{}`, encode(t, inner{}, false))
+		assertEq(t, "pointer", `{}`, encode(t, &inner{}, false))
+		assertEq(t, "indent", `{}`, encode(t, inner{}, true))
+	})
+	t.Run("object func", func(t *testing.T) {
+		assertEq(t, "members", `{"b":1}`, encode(t, members("a", nil, "b", 1, "c", (*inner)(nil)), false))
+	})
+	t.Run("disabled", func(t *testing.T) {
+		bytes, err := json.Marshal(struct {
+			C *inner `json:"c"`
+		}{})
+		assertErr(t, err)
+		assertEq(t, "null", `{"c":null}`, string(bytes))
+	})
+}
+
+type embeddedStringer string
+
+func (s embeddedStringer) String() string { return string(s) }
+
+type embeddedStringerPtr struct {
+	X int `json:"x"`
+}
+
+func (s *embeddedStringerPtr) String() string { return "ptr" }
+
+type unexportedStringer interface {
+	String() string
+}
+
+func Test_EmbeddedInterface(t *testing.T) {
+	type T struct {
+		fmt.Stringer
+		N int `json:"n"`
+	}
+	type U struct {
+		unexportedStringer
+		N int `json:"n"`
+	}
+	for _, test := range []struct {
+		v   interface{}
+		exp string
+	}{
+		{T{Stringer: embeddedStringer("a"), N: 1}, `{"Stringer":"a","n":1}`},
+		{T{Stringer: &embeddedStringerPtr{X: 2}, N: 1}, `{"Stringer":{"x":2},"n":1}`},
+		{&T{N: 1}, `{"Stringer":null,"n":1}`},
+		{U{unexportedStringer: embeddedStringer("a"), N: 1}, `{"n":1}`},
+	} {
+		bytes, err := json.Marshal(test.v)
+		assertErr(t, err)
+		assertEq(t, "embedded interface", test.exp, string(bytes))
+	}
+}
+
+func Test_OmitDefault(t *testing.T) {
+	type settings struct {
+		Name    string   `json:"name,default=\"main\""`
+		Limit   int      `json:"limit,default=100"`
+		Ratio   float64  `json:"ratio,omitempty,default=0.5"`
+		Verbose bool     `json:"verbose,default=true"`
+		Tags    []string `json:"tags,default=[\"a\"]"`
+		Retry   *int     `json:"retry,default=3"`
+		Last    string   `json:"last"`
+	}
+	three := 3
+	t.Run("defaults", func(t *testing.T) {
+		v := settings{Name: "main", Limit: 100, Ratio: 0.5, Verbose: true, Tags: []string{"a"}, Retry: &three}
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "omit default", `{"last":""}`, string(b))
+	})
+	t.Run("changed", func(t *testing.T) {
+		limit := 4
+		v := settings{Name: "other", Limit: 0, Ratio: 0.25, Tags: []string{"b"}, Retry: &limit, Last: "x"}
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "omit default", `{"name":"other","limit":0,"ratio":0.25,"verbose":false,"tags":["b"],"retry":4,"last":"x"}`, string(b))
+	})
+	t.Run("with omitempty", func(t *testing.T) {
+		b, err := json.Marshal(settings{Name: "main", Limit: 1, Verbose: true, Tags: []string{"a"}, Retry: &three})
+		assertErr(t, err)
+		assertEq(t, "omit default", `{"limit":1,"last":""}`, string(b))
+	})
+	t.Run("indent", func(t *testing.T) {
+		type T struct {
+			Limit int `json:"limit,default=100"`
+			Depth int `json:"depth,default=2"`
+		}
+		b, err := json.MarshalIndent(T{Limit: 100, Depth: 3}, "", "  ")
+		assertErr(t, err)
+		assertEq(t, "omit default", "{\n  \"depth\": 3\n}", string(b))
+	})
+	t.Run("invalid default", func(t *testing.T) {
+		type T struct {
+			Limit int `json:"limit,default=many"`
+		}
+		if _, err := json.Marshal(T{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+type recordingWriter struct {
+	writes []string
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func Test_EncodeChan(t *testing.T) {
+	produce := func(values ...int) chan int {
+		ch := make(chan int)
+		go func() {
+			for _, v := range values {
+				ch <- v
+			}
+			close(ch)
+		}()
+		return ch
+	}
+	t.Run("marshal", func(t *testing.T) {
+		b, err := json.Marshal(produce(1, 2, 3))
+		assertErr(t, err)
+		assertEq(t, "chan", `[1,2,3]`, string(b))
+	})
+	t.Run("field", func(t *testing.T) {
+		type T struct {
+			A   int        `json:"a"`
+			Ch  <-chan int `json:"ch"`
+			Nil chan int   `json:"nil"`
+		}
+		b, err := json.Marshal(T{A: 1, Ch: produce()})
+		assertErr(t, err)
+		assertEq(t, "chan", `{"a":1,"ch":[],"nil":null}`, string(b))
+	})
+	t.Run("flush", func(t *testing.T) {
+		w := &recordingWriter{}
+		enc := json.NewEncoder(w)
+		assertErr(t, enc.Encode(produce(1, 2)))
+		assertEq(t, "writes", `[1|,2|]`, strings.Join(w.writes, "|"))
+	})
+	t.Run("send only", func(t *testing.T) {
+		if _, err := json.Marshal(make(chan<- int)); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan int)
+		go func() {
+			ch <- 1
+			cancel()
+		}()
+		_, err := json.MarshalContext(ctx, ch)
+		assertEq(t, "canceled", context.Canceled, err)
+	})
+}
+
+func Test_MapKeyOrder(t *testing.T) {
+	m := map[string]int{"name": 1, "type": 2, "id": 3, "extra": 4, "alpha": 5}
+	encode := func(t *testing.T, v interface{}, setup func(enc *json.Encoder)) string {
+		t.Helper()
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		setup(enc)
+		assertErr(t, enc.Encode(v))
+		return buf.String()
+	}
+	t.Run("sorted", func(t *testing.T) {
+		act := encode(t, m, func(enc *json.Encoder) { enc.SetSortMapKeys(true) })
+		assertEq(t, "sorted", `{"alpha":5,"extra":4,"id":3,"name":1,"type":2}`, act)
+	})
+	t.Run("comparator", func(t *testing.T) {
+		act := encode(t, m, func(enc *json.Encoder) {
+			enc.SetMapKeyOrder(func(a, b string) bool { return a > b })
+		})
+		assertEq(t, "descending", `{"type":2,"name":1,"id":3,"extra":4,"alpha":5}`, act)
+	})
+	t.Run("priority", func(t *testing.T) {
+		act := encode(t, m, func(enc *json.Encoder) {
+			enc.SetSortMapKeys(true)
+			enc.SetMapKeyPriority("id", "type")
+		})
+		assertEq(t, "priority", `{"id":3,"type":2,"alpha":5,"extra":4,"name":1}`, act)
+	})
+	t.Run("nested", func(t *testing.T) {
+		v := map[string]interface{}{"b": map[string]bool{"10": true, "2": false}, "a": struct{ M map[string]int }{map[string]int{"y": 1, "x": 2}}}
+		act := encode(t, v, func(enc *json.Encoder) { enc.SetSortMapKeys(true) })
+		assertEq(t, "nested", `{"a":{"M":{"x":2,"y":1}},"b":{"10":true,"2":false}}`, act)
+	})
+	t.Run("sync.Map", func(t *testing.T) {
+		var sm sync.Map
+		sm.Store("a", 1)
+		sm.Store("id", 2)
+		act := encode(t, &sm, func(enc *json.Encoder) { enc.SetMapKeyPriority("id") })
+		assertEq(t, "sync.Map", `{"id":2,"a":1}`, act)
+	})
+	t.Run("unordered interface values", func(t *testing.T) {
+		m := map[string]interface{}{"a": "x", "b": map[string]int{"c": 1}, "d": json.Number("2"), "e": nil}
+		for i := 0; i < 20; i++ {
+			m[fmt.Sprint("key", i)] = i
+		}
+		b, err := json.Marshal(m)
+		assertErr(t, err)
+		var decoded map[string]interface{}
+		assertErr(t, json.Unmarshal(b, &decoded))
+		assertEq(t, "entries", fmt.Sprint(m), fmt.Sprint(decoded))
+	})
+	t.Run("unordered entries are copied one at a time", func(t *testing.T) {
+		large := map[string]int{}
+		for i := 0; i < 50; i++ {
+			large[fmt.Sprint("key", i)] = i
+		}
+		allocs := testing.AllocsPerRun(50, func() {
+			if _, err := json.Marshal(large); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs > 10 {
+			t.Fatalf("expected the entries to be copied one at a time but got %v allocations", allocs)
+		}
+	})
+}
+
+func Test_Encoder_SetKeyTransformer(t *testing.T) {
+	type T struct {
+		ID    int               `json:"id"`
+		Name  string            `json:"name,omitempty"`
+		Attrs map[string]string `json:"attrs"`
+	}
+	v := T{ID: 1, Name: "a", Attrs: map[string]string{"color": "red"}}
+	t.Run("prefix", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetKeyTransformer(func(key string) string { return "x_" + key })
+		assertErr(t, enc.Encode(v))
+		assertEq(t, "transformed", `{"x_id":1,"x_name":"a","x_attrs":{"x_color":"red"}}`, buf.String())
+	})
+	t.Run("indent", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", " ")
+		enc.SetKeyTransformer(strings.ToUpper)
+		assertErr(t, enc.Encode(struct{ a, B int }{B: 1}))
+		assertEq(t, "indent", "{\n \"B\": 1\n}", buf.String())
+	})
+	t.Run("escaped", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetKeyTransformer(func(key string) string { return key + `"` })
+		assertErr(t, enc.Encode(map[string]int{"a": 1}))
+		assertEq(t, "escaped", `{"a\"":1}`, buf.String())
+	})
+	t.Run("keys needing escaping", func(t *testing.T) {
+		type T struct {
+			A int `json:"a<b"`
+			B int `json:"x\\y"`
+			C int `json:"é"`
+		}
+		var keys []string
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetKeyTransformer(func(key string) string {
+			keys = append(keys, key)
+			return key + `"`
+		})
+		assertErr(t, enc.Encode(T{}))
+		assertEq(t, "keys", `[a<b x\y é]`, fmt.Sprint(keys))
+		assertEq(t, "transformed", `{"a\u003cb\"":0,"x\\y\"":0,"é\"":0}`, buf.String())
+		b, err := json.Marshal(T{})
+		assertErr(t, err)
+		assertEq(t, "untransformed", `{"a\u003cb":0,"x\\y":0,"é":0}`, string(b))
+	})
+	t.Run("reset", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetKeyTransformer(strings.ToUpper)
+		enc.SetKeyTransformer(nil)
+		assertErr(t, enc.Encode(v))
+		assertEq(t, "untransformed", `{"id":1,"name":"a","attrs":{"color":"red"}}`, buf.String())
+	})
+}
+
+func Test_MarshalValue(t *testing.T) {
+	type valueT struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	t.Run("addressable", func(t *testing.T) {
+		v := struct {
+			S valueT
+			P *valueT
+			M map[string]int
+			I interface{}
+		}{S: valueT{A: 1, B: "x"}, P: &valueT{A: 2}, M: map[string]int{"k": 3}, I: 4}
+		rv := reflect.ValueOf(&v).Elem()
+		expected := []string{`{"a":1,"b":"x"}`, `{"a":2,"b":""}`, `{"k":3}`, `4`}
+		for i, exp := range expected {
+			bytes, err := json.MarshalValue(rv.Field(i))
+			assertErr(t, err)
+			assertEq(t, rv.Type().Field(i).Name, exp, string(bytes))
+		}
+	})
+	t.Run("not addressable", func(t *testing.T) {
+		bytes, err := json.MarshalValue(reflect.ValueOf(valueT{A: 1}))
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"a":1,"b":""}`, string(bytes))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		bytes, err := json.MarshalValue(reflect.Value{})
+		assertErr(t, err)
+		assertEq(t, "encoded", `null`, string(bytes))
+	})
+	t.Run("unexported", func(t *testing.T) {
+		v := struct{ a int }{a: 1}
+		if _, err := json.MarshalValue(reflect.ValueOf(&v).Elem().Field(0)); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func Test_Encoder_SetStringifyMapKeys(t *testing.T) {
+	t.Run("integer keys", func(t *testing.T) {
+		bytes, err := json.Marshal(map[int]string{1: "a"})
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"1":"a"}`, string(bytes))
+	})
+	t.Run("interface keys", func(t *testing.T) {
+		bytes, err := json.Marshal(map[interface{}]interface{}{"a": 1})
+		assertErr(t, err)
+		assertEq(t, "string key", `{"a":1}`, string(bytes))
+		bytes, err = json.Marshal(map[interface{}]interface{}{2: "b"})
+		assertErr(t, err)
+		assertEq(t, "integer key", `{"2":"b"}`, string(bytes))
+		if _, err := json.Marshal(map[interface{}]interface{}{true: 1}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("stringify", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetStringifyMapKeys(true)
+		enc.SetSortMapKeys(true)
+		assertErr(t, enc.Encode(map[interface{}]interface{}{
+			true: 1,
+			1.5:  2,
+			"s":  map[interface{}]interface{}{nil: 3},
+		}))
+		assertEq(t, "encoded", `{"1.5":2,"s":{"null":3},"true":1}`, buf.String())
+	})
+}
+
+func Test_EncodeFloat(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		f64 := math.Float64frombits(r.Uint64())
+		f32 := math.Float32frombits(r.Uint32())
+		if i%2 == 0 {
+			f64 = float64(r.Int63n(1<<53)) / math.Pow(10, float64(r.Intn(30)))
+			f32 = float32(r.Int31n(1<<24)) / float32(math.Pow(10, float64(r.Intn(12))))
+		}
+		for _, v := range []interface{}{f64, f32} {
+			expected, err := stdjson.Marshal(v)
+			if err != nil {
+				continue // NaN or infinity
+			}
+			got, err := json.Marshal(v)
+			assertErr(t, err)
+			assertEq(t, fmt.Sprintf("%T %v", v, v), string(expected), string(got))
+		}
+	}
+	for _, v := range []interface{}{
+		1e21, 1e20, 1e-6, 1e-7, -0.0, 5e-324, math.MaxFloat64, float32(math.MaxFloat32), float32(1e-7), 100.0, 0.1,
+	} {
+		expected, _ := stdjson.Marshal(v)
+		got, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, fmt.Sprint(v), string(expected), string(got))
+	}
+}
+
+// tokenPoint writes itself as an object with WriteToken and Encode.
+type tokenPoint struct {
+	X, Y int
+	Tags []string
+}
+
+func (p *tokenPoint) MarshalJSONTo(e *json.Encoder) error {
+	for _, t := range []json.Token{json.Delim('{'), "x", float64(p.X), "y"} {
+		if err := e.WriteToken(t); err != nil {
+			return err
+		}
+	}
+	if err := e.Encode(p.Y); err != nil {
+		return err
+	}
+	if err := e.WriteToken("tags"); err != nil {
+		return err
+	}
+	if err := e.WriteToken(json.Delim('[')); err != nil {
+		return err
+	}
+	for _, tag := range p.Tags {
+		if err := e.WriteToken(tag); err != nil {
+			return err
+		}
+	}
+	if err := e.WriteToken(json.Delim(']')); err != nil {
+		return err
+	}
+	return e.WriteToken(json.Delim('}'))
+}
+
+// Its MarshalJSON method must not be called.
+func (p *tokenPoint) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("unexpected call of MarshalJSON")
+}
+
+// tokenPair writes two values, which is invalid.
+type tokenPair struct{}
+
+func (tokenPair) MarshalJSONTo(e *json.Encoder) error {
+	if err := e.WriteToken(true); err != nil {
+		return err
+	}
+	return e.WriteToken(false)
+}
+
+func Test_MarshalerTo(t *testing.T) {
+	type shape struct {
+		Name   string        `json:"name"`
+		Points []*tokenPoint `json:"points"`
+		Origin *tokenPoint   `json:"origin"`
+	}
+	v := shape{
+		Name:   "a",
+		Points: []*tokenPoint{{X: 1, Y: 2, Tags: []string{"a<b"}}, {X: 3}},
+	}
+	t.Run("marshal", func(t *testing.T) {
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "shape", `{"name":"a","points":[{"x":1,"y":2,"tags":["a\u003cb"]},{"x":3,"y":0,"tags":[]}],"origin":null}`, string(bytes))
+	})
+	t.Run("indent", func(t *testing.T) {
+		bytes, err := json.MarshalIndent(v, "", "  ")
+		assertErr(t, err)
+		expected := `{
+  "name": "a",
+  "points": [
+    {
+      "x": 1,
+      "y": 2,
+      "tags": [
+        "a\u003cb"
+      ]
+    },
+    {
+      "x": 3,
+      "y": 0,
+      "tags": []
+    }
+  ],
+  "origin": null
+}`
+		assertEq(t, "shape", expected, string(bytes))
+	})
+	t.Run("top level", func(t *testing.T) {
+		bytes, err := json.Marshal(&tokenPoint{X: -1})
+		assertErr(t, err)
+		assertEq(t, "point", `{"x":-1,"y":0,"tags":[]}`, string(bytes))
+	})
+	t.Run("interface", func(t *testing.T) {
+		bytes, err := json.Marshal([]interface{}{&tokenPoint{X: 5}})
+		assertErr(t, err)
+		assertEq(t, "point", `[{"x":5,"y":0,"tags":[]}]`, string(bytes))
+	})
+	t.Run("more than one value", func(t *testing.T) {
+		_, err := json.Marshal(struct{ A tokenPair }{})
+		if _, ok := err.(*json.MarshalerError); !ok {
+			t.Fatalf("expected *json.MarshalerError but got %v", err)
+		}
+	})
+	t.Run("outside of MarshalJSONTo", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).WriteToken(json.Delim('{')); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+// appendPoint appends itself as an array.
+// rawMarshaler returns itself from MarshalJSON, valid or not.
+type rawMarshaler string
+
+func (m rawMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(m), nil
+}
+
+// rawAppender appends itself in AppendJSON, valid or not.
+type rawAppender string
+
+func (m rawAppender) AppendJSON(dst []byte) ([]byte, error) {
+	return append(dst, m...), nil
+}
+
+func Test_MarshaledBytesValidation(t *testing.T) {
+	t.Run("compacted", func(t *testing.T) {
+		v := []interface{}{
+			rawMarshaler(` { "a" : [ 1, "x y" ] } `),
+			rawAppender("[ true,\n\tnull ]"),
+			json.RawMessage(` "s" `),
+		}
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "compacted", `[{"a":[1,"x y"]},[true,null],"s"]`, string(bytes))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, v := range []interface{}{
+			rawMarshaler(`{"a":1,}`),
+			rawMarshaler(``),
+			rawAppender(`[1 2]`),
+			struct{ R json.RawMessage }{R: json.RawMessage(`{"a"}`)},
+			json.RawMessage(`nul`),
+			json.RawMessage(`"a\x"`),
+			json.RawMessage(`1 2`),
+		} {
+			_, err := json.Marshal(v)
+			if _, ok := err.(*json.MarshalerError); !ok {
+				t.Fatalf("expected *json.MarshalerError for %v but got %v", v, err)
+			}
+			_, err = json.MarshalIndent(v, "", "  ")
+			if _, ok := err.(*json.MarshalerError); !ok {
+				t.Fatalf("expected *json.MarshalerError when indenting %v but got %v", v, err)
+			}
+		}
+	})
+	t.Run("trusted", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetTrustedRaw(true)
+		assertErr(t, enc.Encode([]interface{}{rawMarshaler(`{ "a" }`), rawAppender(`[ 1 ]`), json.RawMessage(` 2 `)}))
+		assertEq(t, "trusted", `[{ "a" },[ 1 ], 2 ]`, buf.String())
+	})
+}
+
+type appendPoint struct {
+	X, Y int
+}
+
+func (p appendPoint) AppendJSON(dst []byte) ([]byte, error) {
+	dst = append(dst, '[')
+	dst = strconv.AppendInt(dst, int64(p.X), 10)
+	dst = append(dst, ',')
+	dst = strconv.AppendInt(dst, int64(p.Y), 10)
+	return append(dst, ']'), nil
+}
+
+// Its MarshalJSON method must not be called.
+func (p appendPoint) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("unexpected call of MarshalJSON")
+}
+
+type appendFailure struct{}
+
+func (*appendFailure) AppendJSON(dst []byte) ([]byte, error) {
+	return dst, fmt.Errorf("failure")
+}
+
+func Test_Appender(t *testing.T) {
+	t.Run("fields", func(t *testing.T) {
+		v := struct {
+			A appendPoint   `json:"a"`
+			B *appendPoint  `json:"b"`
+			C []appendPoint `json:"c"`
+			D *appendPoint  `json:"d"`
+		}{
+			A: appendPoint{1, 2},
+			B: &appendPoint{3, 4},
+			C: []appendPoint{{5, 6}},
+		}
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "fields", `{"a":[1,2],"b":[3,4],"c":[[5,6]],"d":null}`, string(bytes))
+	})
+	t.Run("top level", func(t *testing.T) {
+		bytes, err := json.Marshal(appendPoint{-1, 0})
+		assertErr(t, err)
+		assertEq(t, "point", `[-1,0]`, string(bytes))
+	})
+	t.Run("interface", func(t *testing.T) {
+		bytes, err := json.Marshal(map[string]interface{}{"p": appendPoint{7, 8}})
+		assertErr(t, err)
+		assertEq(t, "point", `{"p":[7,8]}`, string(bytes))
+	})
+	t.Run("error", func(t *testing.T) {
+		_, err := json.Marshal(struct{ A *appendFailure }{A: &appendFailure{}})
+		if _, ok := err.(*json.MarshalerError); !ok {
+			t.Fatalf("expected *json.MarshalerError but got %v", err)
+		}
+	})
+	t.Run("allocations", func(t *testing.T) {
+		var v interface{} = []appendPoint{{1, 2}, {3, 4}}
+		buf := make([]byte, 64)
+		allocs := testing.AllocsPerRun(100, func() {
+			if _, err := json.MarshalTo(buf, v); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs > 0 {
+			t.Fatalf("expected no allocation but got %v", allocs)
+		}
+	})
+}
@@ -2,19 +2,36 @@ package json
 
 import (
 	"encoding"
+	"io"
 	"math"
 	"reflect"
 	"strconv"
+	"sync"
 	"unsafe"
 )
 
 func (e *Encoder) run(code *opcode) error {
 	for {
+		if e.ctx != nil {
+			e.opCount++
+			if e.opCount%ctxCheckInterval == 0 {
+				if err := e.ctx.Err(); err != nil {
+					return err
+				}
+			}
+		}
 		switch code.op {
 		case opPtr:
 			ptr := code.ptr
 			code = code.next
 			code.ptr = e.ptrToPtr(ptr)
+		case opBeforeMarshal:
+			ptr, err := e.beforeMarshal(code)
+			if err != nil {
+				return err
+			}
+			code = code.next
+			code.ptr = ptr
 		case opInt:
 			e.encodeInt(e.ptrToInt(code.ptr))
 			code = code.next
@@ -80,19 +97,46 @@ func (e *Encoder) run(code *opcode) error {
 			vv := rv.Interface()
 			header := (*interfaceHeader)(unsafe.Pointer(&vv))
 			typ := header.typ
-			if typ.Kind() == reflect.Ptr {
-				typ = typ.Elem()
+			if isDataWordKind(typ.Kind()) {
+				if err := e.encodeDataWordValue(reflect.ValueOf(vv), ifaceCode.indent); err != nil {
+					return err
+				}
+				code = ifaceCode.next
+				break
 			}
+			var (
+				c   *opcode
+				err error
+			)
 			e.indent = ifaceCode.indent
-			c, err := e.compile(typ, ifaceCode.root, e.enabledIndent)
+			switch typ.Kind() {
+			case reflect.Map:
+				// the map pointer itself is held in the data word, so it must not be loaded
+				c, err = e.compileMap(typ, false, ifaceCode.root, e.enabledIndent)
+			case reflect.Ptr:
+				if e.isMarshalerType(typ) {
+					// the data word is the pointer the method is called on
+					c, err = e.compileHead(typ, e.enabledIndent)
+				} else {
+					c, err = e.compile(typ.Elem(), ifaceCode.root, e.enabledIndent)
+				}
+			default:
+				c, err = e.compile(typ, ifaceCode.root, e.enabledIndent)
+			}
 			if err != nil {
 				return err
 			}
-			c.ptr = uintptr(header.ptr)
+			// ptr points to the interface value being encoded, whose data word outlives vv
+			c.ptr = valuePointer(typ, (*interfaceHeader)(unsafe.Pointer(ptr)))
 			c.beforeLastCode().next = code.next
 			code = c
 		case opMarshalJSON:
 			ptr := code.ptr
+			if ptr == 0 && code.typ.Kind() == reflect.Ptr {
+				e.encodeNull()
+				code = code.next
+				break
+			}
 			v := *(*interface{})(unsafe.Pointer(&interfaceHeader{
 				typ: code.typ,
 				ptr: unsafe.Pointer(ptr),
@@ -104,11 +148,61 @@ func (e *Encoder) run(code *opcode) error {
 					Err:  err,
 				}
 			}
-			e.encodeBytes(bytes)
+			if err := e.encodeMarshaledBytes(bytes, code.indent); err != nil {
+				return &MarshalerError{
+					Type: rtype2type(code.typ),
+					Err:  err,
+				}
+			}
+			code = code.next
+		case opMarshalJSONTo:
+			if err := e.encodeMarshalerTo(code.typ, code.ptr, code.indent); err != nil {
+				return err
+			}
+			code = code.next
+		case opAppendJSON:
+			ptr := code.ptr
+			if ptr == 0 && code.typ.Kind() == reflect.Ptr {
+				e.encodeNull()
+				code = code.next
+				break
+			}
+			v := *(*interface{})(unsafe.Pointer(&interfaceHeader{
+				typ: code.typ,
+				ptr: unsafe.Pointer(ptr),
+			}))
+			start := len(e.buf)
+			buf, err := v.(Appender).AppendJSON(e.buf)
+			if err != nil {
+				return &MarshalerError{
+					Type:       rtype2type(code.typ),
+					Err:        err,
+					sourceFunc: "AppendJSON",
+				}
+			}
+			e.buf = buf
+			if e.enabledIndent || !e.trustedRaw {
+				appended := buf[start:]
+				if e.enabledIndent {
+					appended = append([]byte(nil), appended...)
+				}
+				e.buf = buf[:start]
+				if err := e.encodeMarshaledBytes(appended, code.indent); err != nil {
+					return &MarshalerError{
+						Type:       rtype2type(code.typ),
+						Err:        err,
+						sourceFunc: "AppendJSON",
+					}
+				}
+			}
 			code = code.next
-			code.ptr = ptr
 		case opMarshalText:
 			ptr := code.ptr
+			if ptr == 0 && code.typ.Kind() == reflect.Ptr {
+				e.encodeNull()
+				code = code.next
+				break
+			}
 			v := *(*interface{})(unsafe.Pointer(&interfaceHeader{
 				typ: code.typ,
 				ptr: unsafe.Pointer(ptr),
@@ -116,13 +210,69 @@ func (e *Encoder) run(code *opcode) error {
 			bytes, err := v.(encoding.TextMarshaler).MarshalText()
 			if err != nil {
 				return &MarshalerError{
-					Type: rtype2type(code.typ),
-					Err:  err,
+					Type:       rtype2type(code.typ),
+					Err:        err,
+					sourceFunc: "MarshalText",
 				}
 			}
-			e.encodeBytes(bytes)
+			e.encodeString(string(bytes))
+			code = code.next
+		case opReader:
+			r := *(*io.Reader)(unsafe.Pointer(code.ptr))
+			if r == nil {
+				e.encodeNull()
+			} else if err := e.encodeReader(r); err != nil {
+				return err
+			}
+			code = code.next
+		case opSyncMap:
+			if code.ptr == 0 {
+				e.encodeNull()
+			} else if err := e.encodeSyncMap((*sync.Map)(unsafe.Pointer(code.ptr)), code.indent); err != nil {
+				return err
+			}
+			code = code.next
+		case opIter:
+			if err := e.encodeIterPtr(code.typ, code.ptr, code.indent); err != nil {
+				return err
+			}
+			code = code.next
+		case opChan:
+			if err := e.encodeChanPtr(code.typ, code.ptr, code.indent); err != nil {
+				return err
+			}
+			code = code.next
+		case opConvert:
+			if err := e.encodeConvert(code.toConvertCode()); err != nil {
+				return err
+			}
+			code = code.next
+		case opWellKnown:
+			if err := e.encodeWellKnown(code.toWellKnownCode()); err != nil {
+				return err
+			}
+			code = code.next
+		case opGenerated:
+			if err := e.encodeGenerated(code.toGeneratedCode()); err != nil {
+				return err
+			}
+			code = code.next
+		case opRawMessage:
+			if err := e.encodeRawMessage(code.ptr, code.indent); err != nil {
+				return err
+			}
+			code = code.next
+		case opUnion:
+			if err := e.encodeUnion(code.toUnionCode()); err != nil {
+				return err
+			}
+			code = code.next
+		case opPath:
+			c := code.toPathCode()
+			if err := e.encodePathNode(c.node, c.ptr, c.indent); err != nil {
+				return err
+			}
 			code = code.next
-			code.ptr = ptr
 		case opSliceHead:
 			p := code.ptr
 			headerCode := code.toSliceHeaderCode()
@@ -156,7 +306,6 @@ func (e *Encoder) run(code *opcode) error {
 			p := code.ptr
 			headerCode := code.toSliceHeaderCode()
 			if p == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = headerCode.end.next
 			} else {
@@ -168,8 +317,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = code.next
 					code.ptr = header.Data
 				} else {
-					e.encodeIndent(code.indent)
-					e.encodeBytes([]byte{'[', ']', '\n'})
+					e.encodeBytes([]byte{'[', ']'})
 					code = headerCode.end.next
 				}
 			}
@@ -177,7 +325,6 @@ func (e *Encoder) run(code *opcode) error {
 			p := code.ptr
 			headerCode := code.toSliceHeaderCode()
 			if p == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = headerCode.end.next
 			} else {
@@ -189,7 +336,6 @@ func (e *Encoder) run(code *opcode) error {
 					code = code.next
 					code.ptr = header.Data
 				} else {
-					e.encodeIndent(code.indent)
 					e.encodeBytes([]byte{'[', ']'})
 					code = headerCode.end.next
 				}
@@ -205,7 +351,7 @@ func (e *Encoder) run(code *opcode) error {
 			} else {
 				e.encodeByte('\n')
 				e.encodeIndent(code.indent)
-				e.encodeBytes([]byte{']', '\n'})
+				e.encodeBytes([]byte{']'})
 				code = c.end.next
 			}
 		case opRootSliceElemIndent:
@@ -254,19 +400,17 @@ func (e *Encoder) run(code *opcode) error {
 			p := code.ptr
 			headerCode := code.toArrayHeaderCode()
 			if p == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = headerCode.end.next
 			} else {
-				e.encodeBytes([]byte{'[', '\n'})
 				if headerCode.len > 0 {
+					e.encodeBytes([]byte{'[', '\n'})
 					e.encodeIndent(code.indent + 1)
 					code = code.next
 					code.ptr = p
 					headerCode.elem.ptr = p
 				} else {
-					e.encodeIndent(code.indent)
-					e.encodeBytes([]byte{']', '\n'})
+					e.encodeBytes([]byte{'[', ']'})
 					code = headerCode.end.next
 				}
 			}
@@ -281,7 +425,7 @@ func (e *Encoder) run(code *opcode) error {
 			} else {
 				e.encodeByte('\n')
 				e.encodeIndent(code.indent)
-				e.encodeBytes([]byte{']', '\n'})
+				e.encodeBytes([]byte{']'})
 				code = c.end.next
 			}
 		case opMapHead:
@@ -294,7 +438,10 @@ func (e *Encoder) run(code *opcode) error {
 				e.encodeByte('{')
 				mlen := maplen(unsafe.Pointer(ptr))
 				if mlen > 0 {
-					iter := mapiterinit(code.typ, unsafe.Pointer(ptr))
+					iter, err := e.mapiterinit(code.typ, unsafe.Pointer(ptr))
+					if err != nil {
+						return err
+					}
 					mapHeadCode.key.set(mlen, iter)
 					mapHeadCode.value.set(iter)
 					key := mapiterkey(iter)
@@ -317,7 +464,10 @@ func (e *Encoder) run(code *opcode) error {
 				e.encodeByte('{')
 				mlen := maplen(unsafe.Pointer(ptr))
 				if mlen > 0 {
-					iter := mapiterinit(code.typ, unsafe.Pointer(ptr))
+					iter, err := e.mapiterinit(code.typ, unsafe.Pointer(ptr))
+					if err != nil {
+						return err
+					}
 					mapHeadCode.key.set(mlen, iter)
 					mapHeadCode.value.set(iter)
 					key := mapiterkey(iter)
@@ -351,14 +501,16 @@ func (e *Encoder) run(code *opcode) error {
 			ptr := code.ptr
 			mapHeadCode := code.toMapHeadCode()
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = mapHeadCode.end.next
 			} else {
 				mlen := maplen(unsafe.Pointer(ptr))
 				if mlen > 0 {
 					e.encodeBytes([]byte{'{', '\n'})
-					iter := mapiterinit(code.typ, unsafe.Pointer(ptr))
+					iter, err := e.mapiterinit(code.typ, unsafe.Pointer(ptr))
+					if err != nil {
+						return err
+					}
 					mapHeadCode.key.set(mlen, iter)
 					mapHeadCode.value.set(iter)
 					key := mapiterkey(iter)
@@ -366,8 +518,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = code.next
 					e.encodeIndent(code.indent)
 				} else {
-					e.encodeIndent(code.indent)
-					e.encodeBytes([]byte{'{', '}', '\n'})
+					e.encodeBytes([]byte{'{', '}'})
 					code = mapHeadCode.end.next
 				}
 			}
@@ -375,7 +526,6 @@ func (e *Encoder) run(code *opcode) error {
 			ptr := code.ptr
 			mapHeadCode := code.toMapHeadCode()
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = mapHeadCode.end.next
 			} else {
@@ -384,7 +534,10 @@ func (e *Encoder) run(code *opcode) error {
 				mlen := maplen(unsafe.Pointer(ptr))
 				if mlen > 0 {
 					e.encodeBytes([]byte{'{', '\n'})
-					iter := mapiterinit(code.typ, unsafe.Pointer(ptr))
+					iter, err := e.mapiterinit(code.typ, unsafe.Pointer(ptr))
+					if err != nil {
+						return err
+					}
 					mapHeadCode.key.set(mlen, iter)
 					mapHeadCode.value.set(iter)
 					key := mapiterkey(iter)
@@ -392,8 +545,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = code.next
 					e.encodeIndent(code.indent)
 				} else {
-					e.encodeIndent(code.indent)
-					e.encodeBytes([]byte{'{', '}', '\n'})
+					e.encodeBytes([]byte{'{', '}'})
 					code = mapHeadCode.end.next
 				}
 			}
@@ -401,14 +553,16 @@ func (e *Encoder) run(code *opcode) error {
 			ptr := code.ptr
 			mapHeadCode := code.toMapHeadCode()
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = mapHeadCode.end.next
 			} else {
 				mlen := maplen(unsafe.Pointer(ptr))
 				if mlen > 0 {
 					e.encodeBytes([]byte{'{', '\n'})
-					iter := mapiterinit(code.typ, unsafe.Pointer(ptr))
+					iter, err := e.mapiterinit(code.typ, unsafe.Pointer(ptr))
+					if err != nil {
+						return err
+					}
 					mapHeadCode.key.set(mlen, iter)
 					mapHeadCode.value.set(iter)
 					key := mapiterkey(iter)
@@ -416,7 +570,6 @@ func (e *Encoder) run(code *opcode) error {
 					code = code.next
 					e.encodeIndent(code.indent)
 				} else {
-					e.encodeIndent(code.indent)
 					e.encodeBytes([]byte{'{', '}'})
 					code = mapHeadCode.end.next
 				}
@@ -433,7 +586,7 @@ func (e *Encoder) run(code *opcode) error {
 			} else {
 				e.encodeByte('\n')
 				e.encodeIndent(code.indent - 1)
-				e.encodeBytes([]byte{'}', '\n'})
+				e.encodeBytes([]byte{'}'})
 				code = c.end.next
 			}
 		case opRootMapKeyIndent:
@@ -460,10 +613,41 @@ func (e *Encoder) run(code *opcode) error {
 			code = c.next
 		case opStructFieldRecursive:
 			recursive := code.toRecursiveCode()
-			if err := e.run(newRecursiveCode(recursive)); err != nil {
+			c := newRecursiveCode(recursive)
+			// the code was compiled for the outer struct, at its depth
+			offset := recursive.indent - c.indent
+			e.indentOffset += offset
+			err := e.run(c)
+			e.indentOffset -= offset
+			if err != nil {
 				return err
 			}
 			code = recursive.next
+		case opStructFieldHeadInline, opStructFieldHeadInlineIndent:
+			field := code.toStructFieldCode()
+			if field.ptr == 0 {
+				e.encodeNull()
+				code = field.end.next
+			} else {
+				withIndent := code.op == opStructFieldHeadInlineIndent
+				if withIndent {
+					e.encodeBytes([]byte{'{', '\n'})
+				} else {
+					e.encodeByte('{')
+				}
+				if err := e.encodeInlineMap(field, code.indent+1, withIndent); err != nil {
+					return err
+				}
+				code = field.next
+				field.nextField.ptr = field.ptr
+			}
+		case opStructFieldInline, opStructFieldInlineIndent:
+			c := code.toStructFieldCode()
+			if err := e.encodeInlineMap(c, c.indent, code.op == opStructFieldInlineIndent); err != nil {
+				return err
+			}
+			code = code.next
+			c.nextField.ptr = c.ptr
 		case opStructFieldPtrHead:
 			if code.ptr != 0 {
 				code.ptr = e.ptrToPtr(code.ptr)
@@ -475,11 +659,14 @@ func (e *Encoder) run(code *opcode) error {
 			if ptr == 0 {
 				e.encodeNull()
 				code = field.end.next
+			} else if p := ptr + field.offset; e.enabledNullOmit && field.isNullValue(p) {
+				e.encodeByte('{')
+				code = field.nextField
+				field.nextField.ptr = field.ptr
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
-				code = field.next
-				code.ptr = field.ptr + field.offset
+				e.encodeKey(field.key)
+				code = e.fieldValueCode(field, p)
 				field.nextField.ptr = field.ptr
 			}
 		case opStructFieldPtrHeadInt:
@@ -493,7 +680,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeInt(e.ptrToInt(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -509,7 +696,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeInt8(e.ptrToInt8(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -525,7 +712,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeInt16(e.ptrToInt16(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -541,7 +728,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeInt32(e.ptrToInt32(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -557,7 +744,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeInt64(e.ptrToInt64(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -573,7 +760,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeUint(e.ptrToUint(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -589,7 +776,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeUint8(e.ptrToUint8(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -605,7 +792,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeUint16(e.ptrToUint16(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -621,7 +808,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeUint32(e.ptrToUint32(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -637,7 +824,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeUint64(e.ptrToUint64(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -653,7 +840,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeFloat32(e.ptrToFloat32(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -676,7 +863,7 @@ func (e *Encoder) run(code *opcode) error {
 					}
 				}
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeFloat64(v)
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -692,7 +879,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeString(e.ptrToString(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -708,7 +895,7 @@ func (e *Encoder) run(code *opcode) error {
 				code = field.end
 			} else {
 				e.encodeByte('{')
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeBool(e.ptrToBool(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
 				code = field.next
@@ -723,17 +910,18 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
+			} else if e.enabledNullOmit && field.isNullValue(ptr+field.offset) {
+				e.encodeBytes([]byte{'{', '\n'})
+				code = field.nextField
+				field.nextField.ptr = field.ptr
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
-				code = field.next
-				code.ptr = field.ptr + field.offset
+				code = e.fieldValueCode(field, field.ptr+field.offset)
 				field.nextField.ptr = field.ptr
 			}
 		case opStructFieldPtrHeadIntIndent:
@@ -743,13 +931,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeInt(e.ptrToInt(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -762,14 +949,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeInt8(e.ptrToInt8(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -785,10 +970,9 @@ func (e *Encoder) run(code *opcode) error {
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeInt16(e.ptrToInt16(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -801,14 +985,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeInt32(e.ptrToInt32(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -821,14 +1003,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeInt64(e.ptrToInt64(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -841,14 +1021,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeUint(e.ptrToUint(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -861,14 +1039,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeUint8(e.ptrToUint8(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -881,14 +1057,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeUint16(e.ptrToUint16(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -901,14 +1075,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeUint32(e.ptrToUint32(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -921,14 +1093,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeUint64(e.ptrToUint64(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -941,14 +1111,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeFloat32(e.ptrToFloat32(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -961,7 +1129,6 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
@@ -972,10 +1139,9 @@ func (e *Encoder) run(code *opcode) error {
 						Str:   strconv.FormatFloat(v, 'g', -1, 64),
 					}
 				}
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeFloat64(v)
 				field.nextField.ptr = field.ptr
@@ -988,14 +1154,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeString(e.ptrToString(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -1008,14 +1172,12 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				e.encodeIndent(code.indent + 1)
-				e.encodeBytes(field.key)
+				e.encodeKey(field.key)
 				e.encodeByte(' ')
 				e.encodeBool(e.ptrToBool(field.ptr + field.offset))
 				field.nextField.ptr = field.ptr
@@ -1036,13 +1198,12 @@ func (e *Encoder) run(code *opcode) error {
 				e.encodeIndent(code.indent)
 				e.encodeByte('{')
 				p := ptr + field.offset
-				if p == 0 || *(*uintptr)(unsafe.Pointer(p)) == 0 {
+				if p == 0 || field.isEmptyValue(p) || e.enabledNullOmit && field.isNullValue(p) {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
-					code = field.next
-					code.ptr = p
+					e.encodeKey(field.key)
+					code = e.fieldValueCode(field, p)
 				}
 				field.nextField.ptr = field.ptr
 			}
@@ -1065,7 +1226,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeInt(v)
 					code = field.next
 				}
@@ -1090,7 +1251,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeInt8(v)
 					code = field.next
 				}
@@ -1115,7 +1276,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeInt16(v)
 					code = field.next
 				}
@@ -1140,7 +1301,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeInt32(v)
 					code = field.next
 				}
@@ -1165,7 +1326,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeInt64(v)
 					code = field.next
 				}
@@ -1190,7 +1351,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeUint(v)
 					code = field.next
 				}
@@ -1215,7 +1376,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeUint8(v)
 					code = field.next
 				}
@@ -1240,7 +1401,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeUint16(v)
 					code = field.next
 				}
@@ -1265,7 +1426,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeUint32(v)
 					code = field.next
 				}
@@ -1290,7 +1451,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeUint64(v)
 					code = field.next
 				}
@@ -1315,7 +1476,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeFloat32(v)
 					code = field.next
 				}
@@ -1346,7 +1507,7 @@ func (e *Encoder) run(code *opcode) error {
 						}
 					}
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeFloat64(v)
 					code = field.next
 				}
@@ -1371,7 +1532,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeString(v)
 					code = field.next
 				}
@@ -1396,7 +1557,7 @@ func (e *Encoder) run(code *opcode) error {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeBool(v)
 					code = field.next
 				}
@@ -1412,21 +1573,18 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				p := ptr + field.offset
-				if p == 0 || *(*uintptr)(unsafe.Pointer(p)) == 0 {
+				if p == 0 || field.isEmptyValue(p) || e.enabledNullOmit && field.isNullValue(p) {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
-					code = field.next
-					code.ptr = p
+					code = e.fieldValueCode(field, p)
 				}
 				field.nextField.ptr = field.ptr
 			}
@@ -1439,18 +1597,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToInt(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeInt(v)
 					code = field.next
@@ -1466,18 +1622,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToInt8(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeInt8(v)
 					code = field.next
@@ -1493,18 +1647,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToInt16(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeInt16(v)
 					code = field.next
@@ -1520,18 +1672,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToInt32(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeInt32(v)
 					code = field.next
@@ -1547,18 +1697,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToInt64(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeInt64(v)
 					code = field.next
@@ -1574,18 +1722,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToUint(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeUint(v)
 					code = field.next
@@ -1601,18 +1747,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToUint8(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeUint8(v)
 					code = field.next
@@ -1628,18 +1772,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToUint16(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeUint16(v)
 					code = field.next
@@ -1655,18 +1797,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToUint32(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeUint32(v)
 					code = field.next
@@ -1682,18 +1822,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToUint64(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeUint64(v)
 					code = field.next
@@ -1709,18 +1847,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToFloat32(ptr + field.offset)
 				if v == 0 {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeFloat32(v)
 					code = field.next
@@ -1736,11 +1872,9 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToFloat64(ptr + field.offset)
 				if v == 0 {
@@ -1753,7 +1887,7 @@ func (e *Encoder) run(code *opcode) error {
 						}
 					}
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeFloat64(v)
 					code = field.next
@@ -1769,18 +1903,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToString(ptr + field.offset)
 				if v == "" {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeString(v)
 					code = field.next
@@ -1796,18 +1928,16 @@ func (e *Encoder) run(code *opcode) error {
 			field := code.toStructFieldCode()
 			ptr := field.ptr
 			if ptr == 0 {
-				e.encodeIndent(code.indent)
 				e.encodeNull()
 				code = field.end.next
 			} else {
-				e.encodeIndent(code.indent)
 				e.encodeBytes([]byte{'{', '\n'})
 				v := e.ptrToBool(ptr + field.offset)
 				if !v {
 					code = field.nextField
 				} else {
 					e.encodeIndent(code.indent + 1)
-					e.encodeBytes(field.key)
+					e.encodeKey(field.key)
 					e.encodeByte(' ')
 					e.encodeBool(v)
 					code = field.next
@@ -1815,13 +1945,17 @@ func (e *Encoder) run(code *opcode) error {
 				field.nextField.ptr = field.ptr
 			}
 		case opStructField:
+			c := code.toStructFieldCode()
+			if e.enabledNullOmit && c.isNullValue(c.ptr+c.offset) {
+				code = c.nextField
+				c.nextField.ptr = c.ptr
+				break
+			}
 			if e.buf[len(e.buf)-1] != '{' {
 				e.encodeByte(',')
 			}
-			c := code.toStructFieldCode()
-			e.encodeBytes(c.key)
-			code = code.next
-			code.ptr = c.ptr + c.offset
+			e.encodeKey(c.key)
+			code = e.fieldValueCode(c, c.ptr+c.offset)
 			c.nextField.ptr = c.ptr
 		case opStructFieldInt:
 			if e.buf[len(e.buf)-1] != '{' {
@@ -1829,7 +1963,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeInt(e.ptrToInt(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldInt8:
@@ -1838,7 +1972,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeInt8(e.ptrToInt8(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldInt16:
@@ -1847,7 +1981,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeInt16(e.ptrToInt16(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldInt32:
@@ -1856,7 +1990,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeInt32(e.ptrToInt32(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldInt64:
@@ -1865,7 +1999,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeInt64(e.ptrToInt64(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldUint:
@@ -1874,7 +2008,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeUint(e.ptrToUint(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldUint8:
@@ -1883,7 +2017,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeUint8(e.ptrToUint8(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldUint16:
@@ -1892,7 +2026,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeUint16(e.ptrToUint16(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldUint32:
@@ -1901,7 +2035,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeUint32(e.ptrToUint32(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldUint64:
@@ -1910,7 +2044,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeUint64(e.ptrToUint64(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldFloat32:
@@ -1919,7 +2053,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeFloat32(e.ptrToFloat32(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldFloat64:
@@ -1928,7 +2062,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			v := e.ptrToFloat64(c.ptr + c.offset)
 			if math.IsInf(v, 0) || math.IsNaN(v) {
 				return &UnsupportedValueError{
@@ -1944,7 +2078,7 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeString(e.ptrToString(c.ptr + c.offset))
 			code = code.next
 		case opStructFieldBool:
@@ -1953,149 +2087,153 @@ func (e *Encoder) run(code *opcode) error {
 			}
 			c := code.toStructFieldCode()
 			c.nextField.ptr = c.ptr
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeBool(e.ptrToBool(c.ptr + c.offset))
 			code = code.next
 
 		case opStructFieldIndent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.enabledNullOmit && c.isNullValue(c.ptr+c.offset) {
+				code = c.nextField
+				c.nextField.ptr = c.ptr
+				break
+			}
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
-			code = code.next
-			code.ptr = c.ptr + c.offset
+			code = e.fieldValueCode(c, c.ptr+c.offset)
 			c.nextField.ptr = c.ptr
 		case opStructFieldIntIndent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeInt(e.ptrToInt(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldInt8Indent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeInt8(e.ptrToInt8(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldInt16Indent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeInt16(e.ptrToInt16(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldInt32Indent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeInt32(e.ptrToInt32(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldInt64Indent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeInt64(e.ptrToInt64(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldUintIndent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeUint(e.ptrToUint(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldUint8Indent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeUint8(e.ptrToUint8(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldUint16Indent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeUint16(e.ptrToUint16(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldUint32Indent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeUint32(e.ptrToUint32(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldUint64Indent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeUint64(e.ptrToUint64(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldFloat32Indent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeFloat32(e.ptrToFloat32(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldFloat64Indent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			v := e.ptrToFloat64(c.ptr + c.offset)
 			if math.IsInf(v, 0) || math.IsNaN(v) {
@@ -2109,22 +2247,22 @@ func (e *Encoder) run(code *opcode) error {
 			c.nextField.ptr = c.ptr
 		case opStructFieldStringIndent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeString(e.ptrToString(c.ptr + c.offset))
 			code = code.next
 			c.nextField.ptr = c.ptr
 		case opStructFieldBoolIndent:
 			c := code.toStructFieldCode()
-			if e.buf[len(e.buf)-2] != '{' {
+			if e.buf[len(e.buf)-1] != '\n' {
 				e.encodeBytes([]byte{',', '\n'})
 			}
 			e.encodeIndent(c.indent)
-			e.encodeBytes(c.key)
+			e.encodeKey(c.key)
 			e.encodeByte(' ')
 			e.encodeBool(e.ptrToBool(c.ptr + c.offset))
 			code = code.next
@@ -2132,15 +2270,14 @@ func (e *Encoder) run(code *opcode) error {
 		case opStructFieldOmitEmpty:
 			c := code.toStructFieldCode()
 			p := c.ptr + c.offset
-			if p == 0 || *(*uintptr)(unsafe.Pointer(p)) == 0 {
+			if p == 0 || c.isEmptyValue(p) || e.enabledNullOmit && c.isNullValue(p) {
 				code = c.nextField
 			} else {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
-				code = code.next
-				code.ptr = p
+				e.encodeKey(c.key)
+				code = e.fieldValueCode(c, p)
 			}
 			c.nextField.ptr = c.ptr
 		case opStructFieldIntOmitEmpty:
@@ -2150,7 +2287,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeInt(v)
 			}
 			code = code.next
@@ -2162,7 +2299,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeInt8(v)
 			}
 			code = code.next
@@ -2174,7 +2311,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeInt16(v)
 			}
 			code = code.next
@@ -2186,7 +2323,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeInt32(v)
 			}
 			code = code.next
@@ -2198,7 +2335,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeInt64(v)
 			}
 			code = code.next
@@ -2210,7 +2347,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeUint(v)
 			}
 			code = code.next
@@ -2222,7 +2359,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeUint8(v)
 			}
 			code = code.next
@@ -2234,7 +2371,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeUint16(v)
 			}
 			code = code.next
@@ -2246,7 +2383,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeUint32(v)
 			}
 			code = code.next
@@ -2258,7 +2395,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeUint64(v)
 			}
 			code = code.next
@@ -2270,7 +2407,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeFloat32(v)
 			}
 			code = code.next
@@ -2288,7 +2425,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeFloat64(v)
 			}
 			code = code.next
@@ -2300,7 +2437,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeString(v)
 			}
 			code = code.next
@@ -2312,7 +2449,7 @@ func (e *Encoder) run(code *opcode) error {
 				if e.buf[len(e.buf)-1] != '{' {
 					e.encodeByte(',')
 				}
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeBool(v)
 			}
 			code = code.next
@@ -2321,28 +2458,27 @@ func (e *Encoder) run(code *opcode) error {
 		case opStructFieldOmitEmptyIndent:
 			c := code.toStructFieldCode()
 			p := c.ptr + c.offset
-			if p == 0 || *(*uintptr)(unsafe.Pointer(p)) == 0 {
+			if p == 0 || c.isEmptyValue(p) || e.enabledNullOmit && c.isNullValue(p) {
 				code = c.nextField
 			} else {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
-				code = code.next
-				code.ptr = p
+				code = e.fieldValueCode(c, p)
 			}
 			c.nextField.ptr = c.ptr
 		case opStructFieldIntOmitEmptyIndent:
 			c := code.toStructFieldCode()
 			v := e.ptrToInt(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeInt(v)
 			}
@@ -2352,11 +2488,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToInt8(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeInt8(v)
 			}
@@ -2366,11 +2502,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToInt16(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeInt16(v)
 			}
@@ -2380,11 +2516,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToInt32(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeInt32(v)
 			}
@@ -2394,11 +2530,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToInt64(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeInt64(v)
 			}
@@ -2408,11 +2544,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToUint(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeUint(v)
 			}
@@ -2422,11 +2558,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToUint8(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeUint8(v)
 			}
@@ -2436,11 +2572,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToUint16(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeUint16(v)
 			}
@@ -2450,11 +2586,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToUint32(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeUint32(v)
 			}
@@ -2464,11 +2600,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToUint64(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeUint64(v)
 			}
@@ -2478,11 +2614,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToFloat32(c.ptr + c.offset)
 			if v != 0 {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeFloat32(v)
 			}
@@ -2498,11 +2634,11 @@ func (e *Encoder) run(code *opcode) error {
 						Str:   strconv.FormatFloat(v, 'g', -1, 64),
 					}
 				}
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeFloat64(v)
 			}
@@ -2512,11 +2648,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToString(c.ptr + c.offset)
 			if v != "" {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeString(v)
 			}
@@ -2526,11 +2662,11 @@ func (e *Encoder) run(code *opcode) error {
 			c := code.toStructFieldCode()
 			v := e.ptrToBool(c.ptr + c.offset)
 			if v {
-				if e.buf[len(e.buf)-2] != '{' {
+				if e.buf[len(e.buf)-1] != '\n' {
 					e.encodeBytes([]byte{',', '\n'})
 				}
 				e.encodeIndent(c.indent)
-				e.encodeBytes(c.key)
+				e.encodeKey(c.key)
 				e.encodeByte(' ')
 				e.encodeBool(v)
 			}
@@ -2540,9 +2676,14 @@ func (e *Encoder) run(code *opcode) error {
 			e.encodeByte('}')
 			code = code.next
 		case opStructEndIndent:
-			e.encodeByte('\n')
-			e.encodeIndent(code.indent)
-			e.encodeByte('}')
+			if last := len(e.buf) - 1; e.buf[last] == '\n' {
+				// every field was omitted
+				e.buf[last] = '}'
+			} else {
+				e.encodeByte('\n')
+				e.encodeIndent(code.indent)
+				e.encodeByte('}')
+			}
 			code = code.next
 		case opEnd:
 			goto END
@@ -2552,6 +2693,17 @@ END:
 	return nil
 }
 
+// fieldValueCode returns the code encoding the value of field at p. A nil pointer,
+// whose codes would load it, is encoded as null and its codes are skipped.
+func (e *Encoder) fieldValueCode(field *structFieldCode, p uintptr) *opcode {
+	if field.next.op == opPtr && e.ptrToPtr(p) == 0 {
+		e.encodeNull()
+		return field.nextField
+	}
+	field.next.ptr = p
+	return field.next
+}
+
 func (e *Encoder) ptrToPtr(p uintptr) uintptr     { return *(*uintptr)(unsafe.Pointer(p)) }
 func (e *Encoder) ptrToInt(p uintptr) int         { return *(*int)(unsafe.Pointer(p)) }
 func (e *Encoder) ptrToInt8(p uintptr) int8       { return *(*int8)(unsafe.Pointer(p)) }
@@ -0,0 +1,54 @@
+package json
+
+import (
+	"io"
+)
+
+// Encoded returns an io.WriterTo encoding v when its WriteTo method is called, with an
+// Encoder configured by opts. A value can so be handed to code writing bodies and be
+// encoded straight to their writer, without an intermediate []byte:
+//
+//	w.Header().Set("Content-Type", "application/json")
+//	_, err := json.Encoded(resp).WriteTo(w)
+//
+// v is encoded anew by each call to WriteTo, which reports the bytes written
+// even if the encoding fails after part of the output was written.
+func Encoded(v interface{}, opts ...EncodeOption) io.WriterTo {
+	return &encodedValue{v: v, opts: opts}
+}
+
+type encodedValue struct {
+	v    interface{}
+	opts []EncodeOption
+}
+
+func (ev *encodedValue) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	enc := NewEncoder(cw)
+	defer enc.release()
+	for _, opt := range ev.opts {
+		opt(enc)
+	}
+	err := enc.Encode(ev.v)
+	return cw.n, err
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// Sync syncs w if it can be synced, for Encoder.SetSync.
+func (cw *countingWriter) Sync() error {
+	if syncer, ok := cw.w.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type syncRecorder struct {
+	bytes.Buffer
+	synced int
+}
+
+func (r *syncRecorder) Sync() error {
+	r.synced++
+	return nil
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func Test_Encoded(t *testing.T) {
+	v := struct {
+		A []int  `json:"a"`
+		B string `json:"b"`
+	}{A: []int{1, 2}, B: "<c>"}
+	expected, err := json.Marshal(v)
+	assertErr(t, err)
+	wt := json.Encoded(v)
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		n, err := wt.WriteTo(&buf)
+		assertErr(t, err)
+		assertEq(t, "encoded", string(expected), buf.String())
+		assertEq(t, "written", int64(len(expected)), n)
+	}
+	t.Run("options", func(t *testing.T) {
+		var rec syncRecorder
+		_, err := json.Encoded([]int{1}, func(enc *json.Encoder) {
+			enc.SetIndent("", " ")
+			enc.SetSync(true)
+		}).WriteTo(&rec)
+		assertErr(t, err)
+		assertEq(t, "indent", "[\n 1\n]", rec.String())
+		assertEq(t, "synced", 1, rec.synced)
+	})
+	t.Run("errors", func(t *testing.T) {
+		if _, err := json.Encoded(v).WriteTo(failingWriter{}); err == nil {
+			t.Fatal("expected write error")
+		}
+		var buf bytes.Buffer
+		if _, err := json.Encoded(func() {}).WriteTo(&buf); err == nil {
+			t.Fatal("expected error for an unsupported value")
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package json
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var (
+	enumsMu sync.RWMutex
+	enums   = map[reflect.Type]*Converter{}
+)
+
+// RegisterEnum makes the integer type of v encode as the name names gives its value
+// and decode from either that name or the number:
+//
+//	type Color int
+//
+//	json.RegisterEnum(Color(0), map[int64]string{0: "red", 1: "green"})
+//
+// Encoding a value without a name returns an UnsupportedValueError and decoding
+// an unknown name or number returns an UnmarshalTypeError.
+// Enums must be registered before the first encoding or decoding of a type using them,
+// typically from an init function. RegisterEnum panics if v is not of an integer type.
+func RegisterEnum(v interface{}, names map[int64]string) {
+	typ := reflect.TypeOf(v)
+	if typ == nil || !isIntegerKind(typ.Kind()) {
+		panic(fmt.Sprintf("json: RegisterEnum of non-integer type %v", typ))
+	}
+	values := make(map[string]int64, len(names))
+	for value, name := range names {
+		values[name] = value
+	}
+	enum := &Converter{
+		Encode: func(v interface{}) (interface{}, error) {
+			value, ok := integerValue(reflect.ValueOf(v))
+			name, exists := names[value]
+			if !ok || !exists {
+				return nil, &UnsupportedValueError{
+					Value: reflect.ValueOf(v),
+					Str:   fmt.Sprintf("%d is not a value of %v", v, typ),
+				}
+			}
+			return name, nil
+		},
+		Decode: func(v interface{}) (interface{}, error) {
+			var (
+				value  int64
+				exists bool
+			)
+			switch v := v.(type) {
+			case string:
+				value, exists = values[v]
+			case float64:
+				value = int64(v)
+				_, exists = names[value]
+				exists = exists && float64(value) == v
+			case Number:
+				n, err := v.Int64()
+				_, exists = names[n]
+				exists = exists && err == nil
+				value = n
+			}
+			if !exists {
+				return nil, &UnmarshalTypeError{Value: fmt.Sprintf("%v", v), Type: typ}
+			}
+			return reflect.ValueOf(value).Convert(typ).Interface(), nil
+		},
+	}
+	enumsMu.Lock()
+	defer enumsMu.Unlock()
+	enums[typ] = enum
+}
+
+// RegisterFlags makes the integer bitmask type of v encode as the array of the names
+// flags gives the bits set in its value and decode from such an array:
+//
+//	type Perm uint8
+//
+//	json.RegisterFlags(Perm(0), map[uint64]string{1: "READ", 2: "WRITE", 4: "EXEC"})
+//
+// Perm(3) then encodes as ["READ","WRITE"] and the zero value as []. A flag may cover
+// several bits, in which case it is written only if all of them are set.
+// Encoding a value with bits without a name returns an UnsupportedValueError and
+// decoding an unknown name returns an UnmarshalTypeError; null decodes as the zero value.
+// Flags must be registered before the first encoding or decoding of a type using them
+// and replace any enum registered for the type. RegisterFlags panics if v is not of an integer type
+// or a flag is zero.
+func RegisterFlags(v interface{}, flags map[uint64]string) {
+	typ := reflect.TypeOf(v)
+	if typ == nil || !isIntegerKind(typ.Kind()) {
+		panic(fmt.Sprintf("json: RegisterFlags of non-integer type %v", typ))
+	}
+	bits := make([]uint64, 0, len(flags))
+	values := make(map[string]uint64, len(flags))
+	for bit, name := range flags {
+		if bit == 0 {
+			panic(fmt.Sprintf("json: RegisterFlags of zero flag %q for %v", name, typ))
+		}
+		bits = append(bits, bit)
+		values[name] = bit
+	}
+	sort.Slice(bits, func(i, j int) bool { return bits[i] < bits[j] })
+	conv := &Converter{
+		Encode: func(v interface{}) (interface{}, error) {
+			value := bitsValue(reflect.ValueOf(v))
+			names := []string{}
+			rest := value
+			for _, bit := range bits {
+				if value&bit == bit {
+					names = append(names, flags[bit])
+					rest &^= bit
+				}
+			}
+			if rest != 0 {
+				return nil, &UnsupportedValueError{
+					Value: reflect.ValueOf(v),
+					Str:   fmt.Sprintf("%#x has bits without a flag of %v", rest, typ),
+				}
+			}
+			return names, nil
+		},
+		Decode: func(v interface{}) (interface{}, error) {
+			if v == nil {
+				return nil, nil
+			}
+			list, ok := v.([]interface{})
+			if !ok {
+				return nil, &UnmarshalTypeError{Value: fmt.Sprintf("%v", v), Type: typ}
+			}
+			var value uint64
+			for _, elem := range list {
+				name, _ := elem.(string)
+				bit, exists := values[name]
+				if !exists {
+					return nil, &UnmarshalTypeError{Value: fmt.Sprintf("flag %v", elem), Type: typ}
+				}
+				value |= bit
+			}
+			return reflect.ValueOf(value).Convert(typ).Interface(), nil
+		},
+	}
+	enumsMu.Lock()
+	defer enumsMu.Unlock()
+	enums[typ] = conv
+}
+
+// enumConverter returns the converter of the type typ registered by RegisterEnum or RegisterFlags, or nil.
+func enumConverter(typ *rtype) *Converter {
+	enumsMu.RLock()
+	defer enumsMu.RUnlock()
+	return enums[rtype2type(typ)]
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// integerValue returns the value of an integer as an int64 and whether it is representable as one,
+// which unsigned values above math.MaxInt64 are not.
+func integerValue(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		return int64(u), u <= math.MaxInt64
+	}
+	return v.Int(), true
+}
+
+func bitsValue(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	}
+	value := uint64(v.Int())
+	if size := v.Type().Bits(); size < 64 {
+		value &= 1<<uint(size) - 1 // drop the sign extension of negative values
+	}
+	return value
+}
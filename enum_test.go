@@ -0,0 +1,154 @@
+package json_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type enumColor int
+
+type enumLevel uint8
+
+type enumSize uint64
+
+func init() {
+	json.RegisterEnum(enumColor(0), map[int64]string{0: "red", 1: "green", 2: "blue"})
+	json.RegisterEnum(enumLevel(0), map[int64]string{1: "low", 2: "high"})
+	json.RegisterEnum(enumSize(0), map[int64]string{-1: "minus one", 1: "one"})
+}
+
+func Test_Enum(t *testing.T) {
+	type palette struct {
+		Main   enumColor   `json:"main"`
+		Others []enumColor `json:"others"`
+		Level  *enumLevel  `json:"level,omitempty"`
+	}
+	high := enumLevel(2)
+	t.Run("encode", func(t *testing.T) {
+		bytes, err := json.Marshal(palette{Main: 1, Others: []enumColor{2, 0}, Level: &high})
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"main":"green","others":["blue","red"],"level":"high"}`, string(bytes))
+		bytes, err = json.Marshal(enumColor(2))
+		assertErr(t, err)
+		assertEq(t, "root", `"blue"`, string(bytes))
+	})
+	t.Run("encode unknown", func(t *testing.T) {
+		_, err := json.Marshal(palette{Main: 7})
+		if _, ok := err.(*json.UnsupportedValueError); !ok {
+			t.Fatalf("expected *json.UnsupportedValueError but got %v", err)
+		}
+	})
+	t.Run("encode unsigned above int64", func(t *testing.T) {
+		_, err := json.Marshal(enumSize(math.MaxUint64))
+		if _, ok := err.(*json.UnsupportedValueError); !ok {
+			t.Fatalf("expected *json.UnsupportedValueError but got %v", err)
+		}
+		assertEq(t, "message", true, strings.Contains(err.Error(), "18446744073709551615"))
+	})
+	t.Run("decode", func(t *testing.T) {
+		var v palette
+		assertErr(t, json.Unmarshal([]byte(`{"main":"blue","others":[1,"red"],"level":"low"}`), &v))
+		assertEq(t, "main", enumColor(2), v.Main)
+		assertEq(t, "others", 2, len(v.Others))
+		assertEq(t, "others[0]", enumColor(1), v.Others[0])
+		assertEq(t, "others[1]", enumColor(0), v.Others[1])
+		assertEq(t, "level", enumLevel(1), *v.Level)
+	})
+	t.Run("decode escaped name", func(t *testing.T) {
+		src := `{"main":"bl\u0075e","others":["r\u0065d"]}`
+		var v palette
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "main", enumColor(2), v.Main)
+		assertEq(t, "others[0]", enumColor(0), v.Others[0])
+		v = palette{}
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		assertEq(t, "stream", enumColor(2), v.Main)
+	})
+	t.Run("decode stream", func(t *testing.T) {
+		var v palette
+		dec := json.NewDecoder(strings.NewReader(`{"main":2}`))
+		dec.UseNumber()
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "main", enumColor(2), v.Main)
+	})
+	t.Run("decode unknown", func(t *testing.T) {
+		for _, src := range []string{`{"main":"purple"}`, `{"main":9}`, `{"main":1.5}`, `{"main":true}`} {
+			var v palette
+			err := json.Unmarshal([]byte(src), &v)
+			typeErr, ok := err.(*json.UnmarshalTypeError)
+			if !ok {
+				t.Fatalf("%s: expected *json.UnmarshalTypeError but got %v", src, err)
+			}
+			assertNeq(t, "offset of "+src, int64(0), typeErr.Offset)
+		}
+	})
+}
+
+type flagPerm uint8
+
+type flagMode int8
+
+func init() {
+	json.RegisterFlags(flagPerm(0), map[uint64]string{1: "READ", 2: "WRITE", 4: "EXEC"})
+	json.RegisterFlags(flagMode(0), map[uint64]string{1: "A", 6: "BC", 0x80: "HIGH"})
+}
+
+func Test_Flags(t *testing.T) {
+	type file struct {
+		Perm flagPerm  `json:"perm"`
+		Mode *flagMode `json:"mode,omitempty"`
+	}
+	t.Run("encode", func(t *testing.T) {
+		mode := flagMode(-128 | 7)
+		bytes, err := json.Marshal(file{Perm: 5, Mode: &mode})
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"perm":["READ","EXEC"],"mode":["A","BC","HIGH"]}`, string(bytes))
+		bytes, err = json.Marshal(flagPerm(0))
+		assertErr(t, err)
+		assertEq(t, "zero", `[]`, string(bytes))
+	})
+	t.Run("encode unknown bits", func(t *testing.T) {
+		if _, err := json.Marshal(flagPerm(9)); err == nil {
+			t.Fatal("expected error")
+		}
+		if _, err := json.Marshal(flagMode(2)); err == nil {
+			t.Fatal("expected error for a partially set flag")
+		}
+	})
+	t.Run("decode", func(t *testing.T) {
+		var v file
+		assertErr(t, json.Unmarshal([]byte(`{"perm":["WRITE","READ","WRITE"],"mode":["HIGH","A"]}`), &v))
+		assertEq(t, "perm", flagPerm(3), v.Perm)
+		assertEq(t, "mode", flagMode(-127), *v.Mode)
+	})
+	t.Run("decode escaped name", func(t *testing.T) {
+		src := `{"perm":["R\u0045AD","EXEC"]}`
+		var v file
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "perm", flagPerm(5), v.Perm)
+		v = file{}
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		assertEq(t, "stream", flagPerm(5), v.Perm)
+	})
+	t.Run("decode stream", func(t *testing.T) {
+		v := file{Perm: 7}
+		dec := json.NewDecoder(strings.NewReader(`{"perm":null} {"perm":[]}`))
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "null", flagPerm(0), v.Perm)
+		v.Perm = 7
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "empty", flagPerm(0), v.Perm)
+	})
+	t.Run("decode unknown", func(t *testing.T) {
+		for _, src := range []string{`{"perm":["READ","DELETE"]}`, `{"perm":[1]}`, `{"perm":"READ"}`} {
+			var v file
+			err := json.Unmarshal([]byte(src), &v)
+			if _, ok := err.(*json.UnmarshalTypeError); !ok {
+				t.Fatalf("%s: expected *json.UnmarshalTypeError but got %v", src, err)
+			}
+		}
+	})
+}
@@ -1,11 +1,16 @@
 package json
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 )
 
+// ErrBufferTooSmall is returned by MarshalTo when the encoding does not fit into the given buffer.
+var ErrBufferTooSmall = errors.New("json: buffer too small")
+
 // Before Go 1.2, an InvalidUTF8Error was returned by Marshal when
 // attempting to encode a string value with invalid UTF-8 sequences.
 // As of Go 1.2, Marshal instead coerces the string to valid UTF-8 by
@@ -55,6 +60,90 @@ func (e *MarshalerError) Error() string {
 // Unwrap returns the underlying error.
 func (e *MarshalerError) Unwrap() error { return e.Err }
 
+// An ElementError describes an array element that failed to decode
+// and was skipped by a Decoder with ContinueOnElementError enabled.
+type ElementError struct {
+	Index  int   // index of the element in its array
+	Offset int64 // the element starts after reading Offset bytes
+	Err    error
+}
+
+func (e *ElementError) Error() string {
+	return fmt.Sprintf("json: array element %d: %s", e.Index, e.Err)
+}
+
+func (e *ElementError) Unwrap() error { return e.Err }
+
+// A MigrationError describes a document that could not be upgraded
+// by the migrations registered with RegisterMigration.
+type MigrationError struct {
+	Type    reflect.Type // the struct type decoded
+	Version int          // the version of the document being upgraded
+	Err     error
+}
+
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("json: migration of %s from version %d: %s", e.Type, e.Version, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error { return e.Err }
+
+// An ElementErrors is returned by Decode once the whole value has been decoded
+// if some array elements were skipped because they failed to decode.
+type ElementErrors struct {
+	Errors []*ElementError // in input order
+}
+
+func (e *ElementErrors) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e.Errors[0], len(e.Errors)-1)
+}
+
+// A MemoryBudgetError is returned by Decode when the decoded value
+// needs more memory than allowed by Decoder.SetMemoryBudget.
+type MemoryBudgetError struct {
+	Budget int64 // memory budget in bytes
+	Offset int64 // error occurred after reading Offset bytes
+}
+
+func (e *MemoryBudgetError) Error() string {
+	return fmt.Sprintf("json: decoded value exceeds memory budget of %d bytes (offset %d)", e.Budget, e.Offset)
+}
+
+// A DecodeTimeoutError is returned by Decode when decoding a value takes longer
+// than allowed by Decoder.SetTimeout.
+type DecodeTimeoutError struct {
+	Limit  time.Duration // timeout set by SetTimeout
+	Offset int64         // error occurred after reading Offset bytes
+}
+
+func (e *DecodeTimeoutError) Error() string {
+	return fmt.Sprintf("json: decoding exceeded timeout of %v (offset %d)", e.Limit, e.Offset)
+}
+
+// Timeout reports that the error is a timeout, like the errors of package net.
+func (e *DecodeTimeoutError) Timeout() bool { return true }
+
+// A FieldLimitError describes a decoded value longer than the limit of the "maxlen" or
+// "maxitems" option of its struct field. The length of strings is in bytes.
+// The field keeps the value it had before decoding.
+type FieldLimitError struct {
+	Struct string // name of the struct type containing the field
+	Field  string // name of the field in JSON
+	Option string // "maxlen" or "maxitems"
+	Limit  int    // the limit of the option
+	Length int    // the length of the decoded value
+	Offset int64  // the value starts after reading Offset bytes
+}
+
+func (e *FieldLimitError) Error() string {
+	return fmt.Sprintf("json: value of Go struct field %s.%s has length %d over %s=%d (offset %d)",
+		e.Struct, e.Field, e.Length, e.Option, e.Limit, e.Offset,
+	)
+}
+
 // A SyntaxError is a description of a JSON syntax error.
 type SyntaxError struct {
 	msg    string // description of error
@@ -98,6 +187,17 @@ func (e *UnmarshalTypeError) Error() string {
 	return fmt.Sprintf("json: cannot unmarshal %s into Go value of type %s", e.Value, e.Type)
 }
 
+// An UnknownFieldError is returned by a Decoder with DisallowUnknownFields when
+// an object key does not match any field of the struct it is decoded into.
+type UnknownFieldError struct {
+	Field  string // the object key, unescaped but not changed by SetKeyTransformer
+	Offset int64  // the offset of the key in the input
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("json: unknown field %q", e.Field)
+}
+
 // An UnsupportedTypeError is returned by Marshal when attempting
 // to encode an unsupported value type.
 type UnsupportedTypeError struct {
@@ -121,6 +221,13 @@ func errNotAtBeginningOfValue(cursor int64) *SyntaxError {
 	return &SyntaxError{msg: "not at beginning of value", Offset: cursor}
 }
 
+func errIncompleteUnmarshalJSONFrom(typ *rtype, cursor int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("UnmarshalJSONFrom of %s did not read exactly one value", rtype2type(typ)),
+		Offset: cursor,
+	}
+}
+
 func errUnexpectedEndOfJSON(msg string, cursor int64) *SyntaxError {
 	return &SyntaxError{
 		msg:    fmt.Sprintf("unexpected end of JSON input for %s", msg),
@@ -128,13 +235,61 @@ func errUnexpectedEndOfJSON(msg string, cursor int64) *SyntaxError {
 	}
 }
 
+func errInvalidEscape(seq []byte, cursor int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("invalid escape sequence %s in string", seq),
+		Offset: cursor,
+	}
+}
+
+func errUnpairedSurrogate(seq []byte, cursor int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("unpaired surrogate %s in string", seq),
+		Offset: cursor,
+	}
+}
+
+func errInvalidNumber(literal []byte, cursor int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("invalid number literal %s", literal),
+		Offset: cursor,
+	}
+}
+
 func errExpected(msg string, cursor int64) *SyntaxError {
 	return &SyntaxError{msg: fmt.Sprintf("expected %s", msg), Offset: cursor}
 }
 
+func errMismatchedBracket(c, expected byte, cursor int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("invalid character %c looking for %c", c, expected),
+		Offset: cursor,
+	}
+}
+
 func errInvalidCharacter(c byte, context string, cursor int64) *SyntaxError {
 	return &SyntaxError{
 		msg:    fmt.Sprintf("invalid character %c as %s", c, context),
 		Offset: cursor,
 	}
 }
+
+// A ValidationError is returned by Decode with ValidateValues when the Validate method
+// of a decoded value fails. With ContinueOnElementError, the array elements holding such
+// values are skipped and reported in an *ElementErrors like the elements that fail to decode,
+// so that the failures of all elements are collected.
+type ValidationError struct {
+	Type   reflect.Type // the type of the value
+	Path   string       // the location of the value, such as "items[2].price", or "" for the element or value itself
+	Offset int64        // the value starts after reading Offset bytes
+	Err    error        // the error of Validate
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("json: invalid %s: %s", e.Type, e.Err)
+	}
+	return fmt.Sprintf("json: invalid %s at %s: %s", e.Type, e.Path, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
@@ -0,0 +1,121 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Explain describes how values of the type of v are encoded and decoded,
+// listing the compiled encoder opcodes followed by the tree of decoders.
+// It shows which paths a type takes, such as Marshaler dispatch, pointer
+// indirections and maps, which is useful when tuning performance.
+// The output is meant for people and its format may change between versions.
+func Explain(v interface{}) string {
+	typ := reflect.TypeOf(v)
+	if typ == nil {
+		return "encoder:\nNULL\ndecoder:\nnone\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s\n", typ)
+	b.WriteString("encoder:\n")
+	// a fresh encoder, so struct types compiled earlier by a pooled one don't appear as recursive jumps
+	enc := &Encoder{
+		structTypeToCompiledCode:       map[uintptr]*compiledCode{},
+		structTypeToCompiledIndentCode: map[uintptr]*compiledCode{},
+	}
+	code, err := enc.compileHead(type2rtype(typ), false)
+	if err != nil {
+		fmt.Fprintf(&b, "unsupported: %v\n", err)
+	} else {
+		b.WriteString(code.dump())
+		b.WriteByte('\n')
+	}
+	b.WriteString("decoder:\n")
+	dec, err := (&Decoder{}).compile(type2rtype(typ))
+	if err != nil {
+		fmt.Fprintf(&b, "unsupported: %v\n", err)
+	} else {
+		explainDecoder(&b, dec, 0)
+	}
+	return b.String()
+}
+
+func explainDecoder(b *strings.Builder, dec decoder, depth int) {
+	b.WriteString(strings.Repeat(" ", depth))
+	switch d := dec.(type) {
+	case *structDecoder:
+		b.WriteString("STRUCT\n")
+		explainStructFields(b, d, depth+1)
+	case *ptrDecoder:
+		fmt.Fprintf(b, "PTR %s\n", d.typ)
+		explainDecoder(b, d.dec, depth+1)
+	case *sliceDecoder:
+		b.WriteString("SLICE\n")
+		explainDecoder(b, d.valueDecoder, depth+1)
+	case *arrayDecoder:
+		fmt.Fprintf(b, "ARRAY [%d]\n", d.alen)
+		explainDecoder(b, d.valueDecoder, depth+1)
+	case *mapDecoder:
+		fmt.Fprintf(b, "MAP %s\n", d.mapType)
+		explainDecoder(b, d.keyDecoder, depth+1)
+		explainDecoder(b, d.valueDecoder, depth+1)
+	case *rawMessageMapDecoder:
+		fmt.Fprintf(b, "RAW_MESSAGE_MAP %s\n", d.mapType)
+	case *interfaceDecoder:
+		b.WriteString("INTERFACE\n")
+	case *unmarshalJSONDecoder:
+		fmt.Fprintf(b, "UNMARSHAL_JSON %s\n", d.typ)
+	case *unmarshalJSONFromDecoder:
+		fmt.Fprintf(b, "UNMARSHAL_JSON_FROM %s\n", d.typ)
+	case *unmarshalTextDecoder:
+		fmt.Fprintf(b, "UNMARSHAL_TEXT %s\n", d.typ)
+	case *writerDecoder:
+		b.WriteString("WRITER\n")
+	case *convertDecoder:
+		fmt.Fprintf(b, "CONVERT %s\n", d.typ)
+	case *wellKnownDecoder:
+		fmt.Fprintf(b, "WELL_KNOWN %s\n", d.typ)
+	case *generatedDecoder:
+		fmt.Fprintf(b, "GENERATED %s\n", d.typ)
+	case *intDecoder:
+		b.WriteString("INT\n")
+	case *uintDecoder:
+		b.WriteString("UINT\n")
+	case *floatDecoder:
+		b.WriteString("FLOAT\n")
+	case *numberDecoder:
+		b.WriteString("NUMBER\n")
+	case *stringDecoder:
+		b.WriteString("STRING\n")
+	case *boolDecoder:
+		b.WriteString("BOOL\n")
+	case *rawMessageDecoder:
+		b.WriteString("RAW_MESSAGE\n")
+	default:
+		fmt.Fprintf(b, "%T\n", dec)
+	}
+}
+
+// explainStructFields lists the fields of a struct decoder in memory order
+// with all the keys that match each of them.
+func explainStructFields(b *strings.Builder, d *structDecoder, depth int) {
+	keys := map[*structFieldSet][]string{}
+	fields := []*structFieldSet{}
+	for key, field := range d.fieldMap {
+		if _, exists := keys[field]; !exists {
+			fields = append(fields, field)
+		}
+		keys[field] = append(keys[field], key)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].offset < fields[j].offset
+	})
+	for _, field := range fields {
+		names := keys[field]
+		sort.Strings(names)
+		fmt.Fprintf(b, "%sFIELD %q +%d\n", strings.Repeat(" ", depth), names, field.offset)
+		explainDecoder(b, field.dec, depth+1)
+	}
+}
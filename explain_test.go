@@ -0,0 +1,44 @@
+package json_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_Explain(t *testing.T) {
+	type T struct {
+		A    int `json:"a"`
+		B    *string
+		M    map[string][]int
+		When time.Time
+	}
+	for _, v := range []interface{}{T{}, &T{}} {
+		explained := json.Explain(v)
+		for _, expected := range []string{
+			`STRUCT_FIELD_HEAD_INT "a"`,
+			`STRUCT_FIELD "M"`,
+			"MAP_HEAD_LOAD",
+			"WELL_KNOWN",
+			`FIELD ["A" "a"] +0`,
+			"WELL_KNOWN time.Time",
+		} {
+			if !strings.Contains(explained, expected) {
+				t.Fatalf("expected %q in\n%s", expected, explained)
+			}
+		}
+	}
+	t.Run("unsupported", func(t *testing.T) {
+		explained := json.Explain(make(chan int))
+		if !strings.Contains(explained, "CHAN") || !strings.Contains(explained, "unsupported type") {
+			t.Fatalf("unexpected explanation\n%s", explained)
+		}
+	})
+	t.Run("nil", func(t *testing.T) {
+		if !strings.Contains(json.Explain(nil), "NULL") {
+			t.Fatal("expected NULL")
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package json
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldOrder is the order in which the fields of structs are encoded, set by SetFieldOrder.
+type FieldOrder int
+
+const (
+	// DeclarationOrder encodes the fields in the order of their declaration, the default.
+	DeclarationOrder FieldOrder = iota
+	// AlphabeticalOrder encodes the fields sorted by their key, for canonical outputs.
+	AlphabeticalOrder
+	// TagOrder encodes the fields by the numbers of their "order=n" tag options, in increasing
+	// order, for documents laid out by hand. Fields without the option follow in declaration order.
+	TagOrder
+)
+
+// SetFieldOrder sets the order in which the fields of structs are encoded.
+// Decoding accepts the members of objects in any order.
+func (e *Encoder) SetFieldOrder(order FieldOrder) {
+	e.fieldOrder = order
+}
+
+// fieldTagOrder returns the number of the "order=n" option of the field, and whether it has one.
+func fieldTagOrder(name string, opts []string) (int, bool, error) {
+	for _, opt := range opts[1:] {
+		if !strings.HasPrefix(opt, "order=") {
+			continue
+		}
+		n, err := strconv.Atoi(opt[len("order="):])
+		if err != nil {
+			return 0, false, fmt.Errorf("json: invalid order of field %s: %q", name, opt)
+		}
+		return n, true, nil
+	}
+	return 0, false, nil
+}
+
+// structFieldIndexes returns the indexes of the fields of typ in the order they are encoded.
+func (e *Encoder) structFieldIndexes(typ *rtype) ([]int, error) {
+	indexes := make([]int, typ.NumField())
+	for i := range indexes {
+		indexes[i] = i
+	}
+	switch e.fieldOrder {
+	case AlphabeticalOrder:
+		keys := make([]string, len(indexes))
+		for i := range keys {
+			field := typ.Field(i)
+			keys[i] = field.Name
+			if name := strings.Split(e.getTag(field), ",")[0]; name != "" {
+				keys[i] = name
+			}
+		}
+		sort.SliceStable(indexes, func(i, j int) bool {
+			return keys[indexes[i]] < keys[indexes[j]]
+		})
+	case TagOrder:
+		orders := make([]int, len(indexes))
+		tagged := make([]bool, len(indexes))
+		for i := range orders {
+			field := typ.Field(i)
+			n, ok, err := fieldTagOrder(field.Name, strings.Split(e.getTag(field), ","))
+			if err != nil {
+				return nil, err
+			}
+			orders[i], tagged[i] = n, ok
+		}
+		sort.SliceStable(indexes, func(i, j int) bool {
+			a, b := indexes[i], indexes[j]
+			if tagged[a] != tagged[b] {
+				return tagged[a]
+			}
+			return tagged[a] && orders[a] < orders[b]
+		})
+	}
+	return indexes, nil
+}
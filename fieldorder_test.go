@@ -0,0 +1,59 @@
+package json_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type orderedDocument struct {
+	Title   string `json:"title,order=2"`
+	Body    string `json:"body"`
+	ID      int    `json:"id,order=1"`
+	Authors string
+	Hidden  string `json:"-"`
+}
+
+func Test_SetFieldOrder(t *testing.T) {
+	doc := orderedDocument{Title: "t", Body: "b", ID: 1, Authors: "a", Hidden: "h"}
+	encode := func(order json.FieldOrder) string {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetFieldOrder(order)
+		if err := enc.Encode(doc); err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(buf.String())
+	}
+	assertEq(t, "declaration", `{"title":"t","body":"b","id":1,"Authors":"a"}`, encode(json.DeclarationOrder))
+	assertEq(t, "alphabetical", `{"Authors":"a","body":"b","id":1,"title":"t"}`, encode(json.AlphabeticalOrder))
+	assertEq(t, "tag", `{"id":1,"title":"t","body":"b","Authors":"a"}`, encode(json.TagOrder))
+	t.Run("default", func(t *testing.T) {
+		bytes, err := json.Marshal(doc)
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"title":"t","body":"b","id":1,"Authors":"a"}`, string(bytes))
+	})
+	t.Run("config", func(t *testing.T) {
+		bytes, err := json.Config{FieldOrder: json.AlphabeticalOrder}.Freeze().Marshal(doc)
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"Authors":"a","body":"b","id":1,"title":"t"}`, string(bytes))
+	})
+	t.Run("decode", func(t *testing.T) {
+		var v orderedDocument
+		assertErr(t, json.Unmarshal([]byte(`{"id":3,"title":"x"}`), &v))
+		assertEq(t, "id", 3, v.ID)
+		assertEq(t, "title", "x", v.Title)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetFieldOrder(json.TagOrder)
+		if err := enc.Encode(struct {
+			A int `json:"a,order=first"`
+		}{}); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
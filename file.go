@@ -0,0 +1,94 @@
+package json
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// UnmarshalFile reads the file named by path and unmarshals its content into v.
+// The file is read into a buffer of its size at once and decoded in place,
+// as with UnmarshalNoCopy, so strings and RawView values may refer to the buffer,
+// which is not used for anything else.
+//
+// With options, the content is decoded by a Decoder configured by them, still without
+// further reads or copies of the buffer, but RawView values are then copied.
+func UnmarshalFile(path string, v interface{}, opts ...DecodeOption) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	src := append(data, nul) // fits in the capacity ReadFile leaves for growth
+	if len(opts) == 0 {
+		var dec Decoder
+		return dec.decodeForUnmarshal(src, v)
+	}
+	s := &stream{buf: src, length: int64(len(data)), allRead: true}
+	s.skipUTF8BOM()
+	dec := &Decoder{s: s}
+	for _, opt := range opts {
+		opt(dec)
+	}
+	return dec.Decode(v)
+}
+
+// MarshalToFile writes the JSON encoding of v, followed by a newline, to the file
+// named by path with the permission bits perm, configured by opts, for example
+// to indent it with SetIndent. The encoding is written to a temporary file in the
+// same directory that then replaces path, so readers see the previous content or
+// the whole new one, and path is left unchanged when encoding fails.
+// With SetSync(true), the file and the directory entry are synced before
+// MarshalToFile returns, so the new content survives a crash.
+func MarshalToFile(path string, v interface{}, perm os.FileMode, opts ...EncodeOption) error {
+	dir := filepath.Dir(path)
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	enc := NewEncoder(f)
+	for _, opt := range opts {
+		opt(enc)
+	}
+	synced := enc.enabledSync
+	err = enc.encode(v)
+	if err == nil {
+		b := enc.buf
+		if last := len(b) - 1; last >= 0 && b[last] == '\n' {
+			b = b[:last]
+		}
+		enc.buf = append(b, '\n')
+		_, err = f.Write(enc.buf)
+	}
+	enc.release()
+	if err == nil && synced {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if synced {
+		syncDir(dir)
+	}
+	return nil
+}
+
+// syncDir syncs the directory entries of dir. Errors are ignored: not every
+// platform can sync directories, and the file content itself is synced already.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,121 @@
+package json_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func writeTempFile(t *testing.T, content string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "v.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_UnmarshalFile(t *testing.T) {
+	src := `{"kind":"event", "payload": {"id":1,"tags":["a"]}, "n": 12345678901234567890}`
+	path := writeTempFile(t, src)
+	t.Run("in place", func(t *testing.T) {
+		var v rawViewEnvelope
+		assertErr(t, json.UnmarshalFile(path, &v))
+		assertEq(t, "kind", "event", v.Kind)
+		assertEq(t, "payload", `{"id":1,"tags":["a"]}`, string(v.Payload))
+	})
+	t.Run("options", func(t *testing.T) {
+		var v map[string]interface{}
+		assertErr(t, json.UnmarshalFile(path, &v, (*json.Decoder).UseNumber))
+		assertEq(t, "number", json.Number("12345678901234567890"), v["n"])
+		var e struct {
+			Kind string `json:"kind"`
+		}
+		err := json.UnmarshalFile(path, &e, (*json.Decoder).DisallowUnknownFields)
+		if err == nil || !strings.Contains(err.Error(), "unknown field") {
+			t.Fatalf("expected unknown field error but got %v", err)
+		}
+	})
+	t.Run("bom", func(t *testing.T) {
+		path := writeTempFile(t, "\xef\xbb\xbf[1,2]")
+		for _, opts := range [][]json.DecodeOption{nil, {(*json.Decoder).UseNumber}} {
+			var v []int
+			assertErr(t, json.UnmarshalFile(path, &v, opts...))
+			assertEq(t, "length", 2, len(v))
+		}
+	})
+	t.Run("missing file", func(t *testing.T) {
+		var v interface{}
+		if err := json.UnmarshalFile(filepath.Join(filepath.Dir(path), "missing.json"), &v); !os.IsNotExist(err) {
+			t.Fatalf("expected not exist error but got %v", err)
+		}
+	})
+}
+
+type syncWriter struct {
+	bytes.Buffer
+	syncs int
+}
+
+func (w *syncWriter) Sync() error {
+	w.syncs++
+	return nil
+}
+
+func Test_MarshalToFile(t *testing.T) {
+	path := writeTempFile(t, `{"old":true}`)
+	v := struct {
+		A []int  `json:"a"`
+		B string `json:"b"`
+	}{A: []int{1, 2}, B: "x"}
+	t.Run("replace", func(t *testing.T) {
+		assertErr(t, json.MarshalToFile(path, v, 0640))
+		got, err := ioutil.ReadFile(path)
+		assertErr(t, err)
+		assertEq(t, "content", "{\"a\":[1,2],\"b\":\"x\"}\n", string(got))
+		info, err := os.Stat(path)
+		assertErr(t, err)
+		if runtime.GOOS != "windows" {
+			assertEq(t, "perm", os.FileMode(0640), info.Mode().Perm())
+		}
+	})
+	t.Run("options", func(t *testing.T) {
+		assertErr(t, json.MarshalToFile(path, v, 0600, func(enc *json.Encoder) {
+			enc.SetIndent("", "  ")
+			enc.SetSync(true)
+		}))
+		got, err := ioutil.ReadFile(path)
+		assertErr(t, err)
+		assertEq(t, "content", "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": \"x\"\n}\n", string(got))
+	})
+	t.Run("error", func(t *testing.T) {
+		assertErr(t, json.MarshalToFile(path, 1, 0600))
+		assertNeq(t, "error", nil, json.MarshalToFile(path, math.NaN(), 0600))
+		got, err := ioutil.ReadFile(path)
+		assertErr(t, err)
+		assertEq(t, "unchanged", "1\n", string(got))
+		entries, err := ioutil.ReadDir(filepath.Dir(path))
+		assertErr(t, err)
+		assertEq(t, "temporary file removed", 1, len(entries))
+	})
+	t.Run("sync", func(t *testing.T) {
+		var w syncWriter
+		enc := json.NewEncoder(&w)
+		assertErr(t, enc.Encode(1))
+		enc.SetSync(true)
+		assertErr(t, enc.Encode(2))
+		assertEq(t, "syncs", 1, w.syncs)
+	})
+}
@@ -0,0 +1,155 @@
+package json
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// contentLengthHeader is the header giving the size of the content of a framed message.
+const contentLengthHeader = "Content-Length"
+
+// EncodeFrame writes v to the stream as a message framed with a Content-Length header,
+// as used by the Language Server Protocol, the Debug Adapter Protocol and similar protocols:
+//
+//	Content-Length: 17\r\n
+//	\r\n
+//	{"jsonrpc":"2.0"}
+//
+// The header and the content are written with a single call to Write.
+func (e *Encoder) EncodeFrame(v interface{}) error {
+	e.buf = e.buf[:0]
+	// the content follows its header, so none of it is flushed to the stream while encoding,
+	// as is done for readers, channels and iterators
+	w := e.w
+	e.w = nil
+	err := e.encode(v)
+	e.w = w
+	if err != nil {
+		return err
+	}
+	var header [48]byte
+	h := append(header[:0], contentLengthHeader+": "...)
+	h = strconv.AppendInt(h, int64(len(e.buf)), 10)
+	h = append(h, "\r\n\r\n"...)
+	n := len(e.buf)
+	e.buf = append(e.buf, h...)
+	copy(e.buf[len(h):], e.buf[:n])
+	copy(e.buf, h)
+	if _, err := e.w.Write(e.buf); err != nil {
+		return err
+	}
+	if e.hash != nil {
+		e.hash.Write(e.buf[len(h):])
+	}
+	if syncer, ok := e.w.(interface{ Sync() error }); ok && e.enabledSync {
+		return syncer.Sync()
+	}
+	return nil
+}
+
+// DecodeFrame reads the next message framed with a Content-Length header, as written by
+// EncodeFrame, and stores the JSON value of its content in the value pointed to by v.
+// The headers end with an empty line and must include Content-Length; others, such as
+// Content-Type, are ignored. The content must hold exactly one value, up to whitespace.
+// Offsets in the errors of the content count from its start.
+//
+// DecodeFrame returns io.EOF at the end of the input between messages. The input is read
+// as needed for each message, so the messages of a pipe or a socket are decoded as they arrive.
+// After a message is read, InputOffset is the offset of its end in the input.
+// A Decoder reading messages must not be used with Decode or Token.
+func (d *Decoder) DecodeFrame(v interface{}) error {
+	s := d.s
+	if d.frames == nil {
+		pending := bytes.NewReader(s.buf[s.cursor:s.length])
+		// the input buffered by the stream is read again through frames
+		s.offset += s.cursor
+		s.buf = s.buf[s.length:]
+		s.length = 0
+		s.cursor = 0
+		d.frames = bufio.NewReader(io.MultiReader(pending, s.r))
+	}
+	length, size, err := readFrameHeader(d.frames)
+	if err != nil {
+		return err
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(d.frames, content); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	s.offset += size + length
+	return d.decodeFrameContent(content, v)
+}
+
+// readFrameHeader reads the headers of a message and returns its Content-Length
+// and the size of the headers.
+func readFrameHeader(r *bufio.Reader) (int64, int64, error) {
+	length := int64(-1)
+	var size int64
+	for first := true; ; first = false {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && (!first || line != "") {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, 0, err
+		}
+		size += int64(len(line))
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		idx := strings.IndexByte(line, ':')
+		if idx < 0 {
+			return 0, 0, fmt.Errorf("json: invalid message header %q", line)
+		}
+		if !strings.EqualFold(strings.TrimSpace(line[:idx]), contentLengthHeader) {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(line[idx+1:]), 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("json: invalid %s header %q", contentLengthHeader, line)
+		}
+		length = n
+	}
+	if length < 0 {
+		return 0, 0, fmt.Errorf("json: message without %s header", contentLengthHeader)
+	}
+	return length, size, nil
+}
+
+// decodeFrameContent decodes the one value of content into v with the options of d.
+func (d *Decoder) decodeFrameContent(content []byte, v interface{}) error {
+	s := *d.s
+	s.r = bytes.NewReader(content)
+	s.buf = nil
+	s.length = 0
+	s.cursor = 0
+	s.offset = 0
+	s.allRead = false
+	s.progressFn = nil
+	s.read()
+	// the content is decoded with all the options of d, by a Decoder of its own
+	frame := *d
+	frame.s = &s
+	frame.frames = nil
+	frame.tokenStack = nil
+	frame.tokenState = tokenTopValue
+	if err := frame.Decode(v); err != nil {
+		if err == io.EOF {
+			return errUnexpectedEndOfJSON("message", 0)
+		}
+		return err
+	}
+	s.skipWhiteSpace()
+	if s.char() != nul || s.read() {
+		return errInvalidCharacter(s.char(), "after the value of the message", s.totalOffset())
+	}
+	return nil
+}
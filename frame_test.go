@@ -0,0 +1,111 @@
+package json_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_Frame(t *testing.T) {
+	type message struct {
+		ID     int    `json:"id"`
+		Method string `json:"method"`
+	}
+	t.Run("round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		assertErr(t, enc.EncodeFrame(message{ID: 1, Method: "initialize"}))
+		assertErr(t, enc.EncodeFrame(message{ID: 2, Method: "shutdown"}))
+		assertEq(t, "frames", "Content-Length: 30\r\n\r\n{\"id\":1,\"method\":\"initialize\"}"+
+			"Content-Length: 28\r\n\r\n{\"id\":2,\"method\":\"shutdown\"}", buf.String())
+
+		dec := json.NewDecoder(iotest.OneByteReader(&buf))
+		for i, method := range []string{"initialize", "shutdown"} {
+			var v message
+			assertErr(t, dec.DecodeFrame(&v))
+			assertEq(t, "id", i+1, v.ID)
+			assertEq(t, "method", method, v.Method)
+		}
+		var v message
+		assertEq(t, "eof", io.EOF, dec.DecodeFrame(&v))
+	})
+	t.Run("pipe", func(t *testing.T) {
+		r, w := io.Pipe()
+		enc := json.NewEncoder(w)
+		go func() {
+			for i := 1; i <= 3; i++ {
+				enc.EncodeFrame(message{ID: i})
+			}
+			w.Close()
+		}()
+		dec := json.NewDecoder(r)
+		for i := 1; i <= 3; i++ {
+			var v message
+			assertErr(t, dec.DecodeFrame(&v))
+			assertEq(t, "id", i, v.ID)
+		}
+	})
+	t.Run("headers", func(t *testing.T) {
+		src := "Content-Type: application/vscode-jsonrpc; charset=utf-8\r\ncontent-length: 10\r\n\r\n{\"id\": 3}\n" +
+			"Content-Length:8\n\n{\"id\":4}"
+		dec := json.NewDecoder(strings.NewReader(src))
+		var v message
+		assertErr(t, dec.DecodeFrame(&v))
+		assertEq(t, "id", 3, v.ID)
+		assertEq(t, "offset", int64(strings.Index(src, "Content-Length:8")), dec.InputOffset())
+		assertErr(t, dec.DecodeFrame(&v))
+		assertEq(t, "id", 4, v.ID)
+		assertEq(t, "offset", int64(len(src)), dec.InputOffset())
+	})
+	t.Run("use number", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader("Content-Length: 10\r\n\r\n{\"id\":1.5}"))
+		dec.UseNumber()
+		var v map[string]interface{}
+		assertErr(t, dec.DecodeFrame(&v))
+		assertEq(t, "number", json.Number("1.5"), v["id"])
+	})
+	t.Run("errors", func(t *testing.T) {
+		for _, src := range []string{
+			"Content-Type: application/json\r\n\r\n{}",
+			"Content-Length: x\r\n\r\n{}",
+			"Content-Length\r\n\r\n{}",
+			"Content-Length: 4\r\n\r\n{} x",
+			"Content-Length: 2\r\n\r\n{\"id\":1}",
+			"Content-Length: 8\r\n\r\n{}",
+			"Content-Length: 2\r\n",
+		} {
+			var v message
+			if err := json.NewDecoder(strings.NewReader(src)).DecodeFrame(&v); err == nil || err == io.EOF {
+				t.Fatalf("expected error for %q, got %v", src, err)
+			}
+		}
+	})
+	t.Run("streamed values", func(t *testing.T) {
+		ch := make(chan int, 2)
+		ch <- 1
+		ch <- 2
+		close(ch)
+		v := struct {
+			R  io.Reader  `json:"r"`
+			Ch <-chan int `json:"ch"`
+		}{R: strings.NewReader("abc"), Ch: ch}
+		var buf bytes.Buffer
+		assertErr(t, json.NewEncoder(&buf).EncodeFrame(v))
+		assertEq(t, "frame", "Content-Length: 23\r\n\r\n{\"r\":\"YWJj\",\"ch\":[1,2]}", buf.String())
+	})
+	t.Run("decoder options", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader("Content-Length: 23\r\n\r\n{'id': 1, 'unknown': 2}"))
+		dec.AllowSingleQuotes()
+		dec.DisallowUnknownFields()
+		var v message
+		err := dec.DecodeFrame(&v)
+		if _, ok := err.(*json.UnknownFieldError); !ok {
+			t.Fatalf("expected *json.UnknownFieldError but got %v", err)
+		}
+		assertEq(t, "id", 1, v.ID)
+	})
+}
@@ -0,0 +1,176 @@
+package json
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// generatedCodec holds the functions registered by RegisterGenerated for a type.
+type generatedCodec struct {
+	encode func(b []byte, v interface{}) ([]byte, error)
+	decode func(data []byte, v interface{}) error
+}
+
+var (
+	generatedMu     sync.RWMutex
+	generatedCodecs = map[*rtype]*generatedCodec{}
+)
+
+// RegisterGenerated makes values of typ encode with enc and decode with dec instead of the
+// codec compiled by reflection, and instead of their marshaler methods. Either may be nil
+// to keep the compiled codec for that direction.
+//
+// enc appends the JSON encoding of the value to b and returns the extended buffer.
+// dec decodes data, the JSON text of a single value that may be null, into the value.
+// Both receive a pointer to the value, of type *T for a typ of T. Like with UnmarshalJSON,
+// dec must copy data if it keeps it.
+//
+// It is meant for code produced by generation tools, which register their codecs from an
+// init function of the generated file, next to the //go:generate directive producing it:
+//
+//	//go:generate jsongen -type User
+//
+//	func init() {
+//		json.RegisterGenerated(reflect.TypeOf(User{}), encodeUser, decodeUser)
+//	}
+//
+// Hand-written codecs can be registered in the same way.
+// Codecs must be registered before the first encoding or decoding of a type using them.
+// RegisterGenerated panics if typ is nil.
+func RegisterGenerated(typ reflect.Type, enc func(b []byte, v interface{}) ([]byte, error), dec func(data []byte, v interface{}) error) {
+	if typ == nil {
+		panic("json: RegisterGenerated of nil type")
+	}
+	generatedMu.Lock()
+	defer generatedMu.Unlock()
+	generatedCodecs[type2rtype(typ)] = &generatedCodec{encode: enc, decode: dec}
+}
+
+func lookupGenerated(typ *rtype) *generatedCodec {
+	generatedMu.RLock()
+	defer generatedMu.RUnlock()
+	return generatedCodecs[typ]
+}
+
+type generatedCode struct {
+	*opcodeHeader
+	encode func(b []byte, v interface{}) ([]byte, error)
+}
+
+func (c *generatedCode) copy(codeMap map[uintptr]*opcode) *opcode {
+	if c == nil {
+		return nil
+	}
+	addr := uintptr(unsafe.Pointer(c))
+	if code, exists := codeMap[addr]; exists {
+		return code
+	}
+	generated := &generatedCode{encode: c.encode}
+	code := (*opcode)(unsafe.Pointer(generated))
+	codeMap[addr] = code
+
+	generated.opcodeHeader = c.opcodeHeader.copy(codeMap)
+	return code
+}
+
+func (c *opcode) toGeneratedCode() *generatedCode {
+	return (*generatedCode)(unsafe.Pointer(c))
+}
+
+// compileGenerated returns the opcodes of typ or of the type typ points to,
+// or nil if no encoder of it was registered by RegisterGenerated.
+func (e *Encoder) compileGenerated(typ *rtype) *opcode {
+	elem := typ
+	if typ.Kind() == reflect.Ptr {
+		elem = typ.Elem()
+	}
+	g := lookupGenerated(elem)
+	if g == nil || g.encode == nil {
+		return nil
+	}
+	code := (*opcode)(unsafe.Pointer(&generatedCode{
+		opcodeHeader: &opcodeHeader{
+			op:     opGenerated,
+			typ:    elem,
+			indent: e.indent,
+			next:   newEndOp(e.indent),
+		},
+		encode: g.encode,
+	}))
+	if typ != elem {
+		return newOpCode(opPtr, typ, e.indent, code)
+	}
+	return code
+}
+
+func (e *Encoder) encodeGenerated(code *generatedCode) error {
+	if code.ptr == 0 {
+		e.encodeNull()
+		return nil
+	}
+	v := reflect.NewAt(rtype2type(code.typ), unsafe.Pointer(code.ptr)).Interface()
+	start := len(e.buf)
+	buf, err := code.encode(e.buf, v)
+	if err != nil {
+		e.buf = e.buf[:start]
+		return &MarshalerError{Type: rtype2type(code.typ), Err: err, sourceFunc: "generated encoder"}
+	}
+	e.buf = buf
+	if !e.enabledIndent && e.trustedRaw {
+		return nil
+	}
+	appended := buf[start:]
+	if e.enabledIndent {
+		appended = append([]byte(nil), appended...)
+	}
+	e.buf = buf[:start]
+	if err := e.encodeMarshaledBytes(appended, code.indent); err != nil {
+		return &MarshalerError{Type: rtype2type(code.typ), Err: err, sourceFunc: "generated encoder"}
+	}
+	return nil
+}
+
+// generatedDecoder decodes a type with the decoder registered by RegisterGenerated.
+type generatedDecoder struct {
+	typ *rtype
+	fn  func(data []byte, v interface{}) error
+}
+
+// newGeneratedDecoder returns the decoder of typ, or nil if no decoder of it
+// was registered by RegisterGenerated.
+func newGeneratedDecoder(typ *rtype) *generatedDecoder {
+	g := lookupGenerated(typ)
+	if g == nil || g.decode == nil {
+		return nil
+	}
+	return &generatedDecoder{typ: typ, fn: g.decode}
+}
+
+func (d *generatedDecoder) setDisallowUnknownFields(_ bool) {}
+
+func (d *generatedDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	start := s.cursor
+	if err := s.skipValue(); err != nil {
+		return err
+	}
+	return d.call(s.buf[start:s.cursor], p)
+}
+
+func (d *generatedDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	end, err := skipValue(buf, cursor)
+	if err != nil {
+		return 0, err
+	}
+	if err := d.call(buf[cursor:end], p); err != nil {
+		return 0, err
+	}
+	return end, nil
+}
+
+func (d *generatedDecoder) call(src []byte, p uintptr) error {
+	return d.fn(src, reflect.NewAt(rtype2type(d.typ), unsafe.Pointer(p)).Interface())
+}
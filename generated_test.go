@@ -0,0 +1,121 @@
+package json_test
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+// genPoint has methods that the registered codec takes precedence over.
+type genPoint struct {
+	X, Y int
+}
+
+func (p genPoint) MarshalJSON() ([]byte, error) {
+	return []byte(`"method"`), nil
+}
+
+func (p *genPoint) UnmarshalJSON([]byte) error {
+	return errors.New("method called")
+}
+
+type genEncodeOnly struct {
+	N int
+}
+
+func init() {
+	json.RegisterGenerated(reflect.TypeOf(genPoint{}),
+		func(b []byte, v interface{}) ([]byte, error) {
+			p := v.(*genPoint)
+			if p.X < 0 {
+				return b, errors.New("negative")
+			}
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(p.X), 10)
+			b = append(b, ',')
+			b = strconv.AppendInt(b, int64(p.Y), 10)
+			return append(b, ']'), nil
+		},
+		func(data []byte, v interface{}) error {
+			if string(data) == "null" {
+				return nil
+			}
+			var xy []int
+			if err := json.Unmarshal(data, &xy); err != nil {
+				return err
+			}
+			if len(xy) != 2 {
+				return errors.New("expected two coordinates")
+			}
+			p := v.(*genPoint)
+			p.X, p.Y = xy[0], xy[1]
+			return nil
+		})
+	json.RegisterGenerated(reflect.TypeOf(genEncodeOnly{}), func(b []byte, v interface{}) ([]byte, error) {
+		return append(b, `"only"`...), nil
+	}, nil)
+}
+
+func Test_RegisterGenerated(t *testing.T) {
+	type shape struct {
+		Center *genPoint     `json:"center"`
+		Points []genPoint    `json:"points"`
+		Named  genPoint      `json:"named"`
+		Only   genEncodeOnly `json:"only"`
+	}
+	v := shape{Center: &genPoint{1, 2}, Points: []genPoint{{3, 4}}, Named: genPoint{5, 6}, Only: genEncodeOnly{7}}
+	src := `{"center":[1,2],"points":[[3,4]],"named":[5,6],"only":"only"}`
+	t.Run("encode", func(t *testing.T) {
+		got, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "struct", src, string(got))
+		got, err = json.Marshal(&genPoint{8, 9})
+		assertErr(t, err)
+		assertEq(t, "pointer", "[8,9]", string(got))
+		got, err = json.Marshal(shape{Points: v.Points})
+		assertErr(t, err)
+		assertEq(t, "nil", `{"center":null,"points":[[3,4]],"named":[0,0],"only":"only"}`, string(got))
+		got, err = json.MarshalIndent(map[string]genPoint{"p": {1, 2}}, "", " ")
+		assertErr(t, err)
+		assertEq(t, "indent", "{\n \"p\": [\n  1,\n  2\n ]\n}", string(got))
+		_, err = json.Marshal(genPoint{X: -1})
+		if err == nil || !strings.Contains(err.Error(), "negative") {
+			t.Fatalf("expected error of the generated encoder but got %v", err)
+		}
+	})
+	t.Run("decode", func(t *testing.T) {
+		type decoded struct {
+			Center *genPoint  `json:"center"`
+			Points []genPoint `json:"points"`
+			Named  genPoint   `json:"named"`
+			Only   genEncodeOnly
+		}
+		input := `{"center":[1,2],"points":[[3,4]],"named":[5,6],"Only":{"N":7}}`
+		want := decoded{Center: &genPoint{1, 2}, Points: []genPoint{{3, 4}}, Named: genPoint{5, 6}, Only: genEncodeOnly{7}}
+		var got decoded
+		assertErr(t, json.Unmarshal([]byte(input), &got))
+		assertEq(t, "Unmarshal", true, reflect.DeepEqual(want, got))
+		got = decoded{}
+		assertErr(t, json.NewDecoder(strings.NewReader(input)).Decode(&got))
+		assertEq(t, "Decode", true, reflect.DeepEqual(want, got))
+		var p genPoint
+		assertErr(t, json.Unmarshal([]byte(` [7, 8] `), &p))
+		assertEq(t, "top-level", genPoint{7, 8}, p)
+		err := json.Unmarshal([]byte(`[1]`), &p)
+		if err == nil || err.Error() != "expected two coordinates" {
+			t.Fatalf("expected error of the generated decoder but got %v", err)
+		}
+	})
+	t.Run("explain", func(t *testing.T) {
+		explained := json.Explain(shape{})
+		for _, expected := range []string{"GENERATED", "GENERATED json_test.genPoint"} {
+			if !strings.Contains(explained, expected) {
+				t.Fatalf("expected %q in\n%s", expected, explained)
+			}
+		}
+	})
+}
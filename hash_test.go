@@ -0,0 +1,42 @@
+package json_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_SetHash(t *testing.T) {
+	v := map[string]interface{}{"id": 1, "body": strings.NewReader("data")}
+	t.Run("encode", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := sha256.New()
+		enc := json.NewEncoder(&buf)
+		enc.SetHash(h)
+		assertErr(t, enc.Encode(v))
+		expected := sha256.Sum256(buf.Bytes())
+		assertEq(t, "digest", string(expected[:]), string(h.Sum(nil)))
+	})
+	t.Run("encoded", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := sha256.New()
+		_, err := json.Encoded([]int{1, 2}, func(enc *json.Encoder) { enc.SetHash(h) }).WriteTo(&buf)
+		assertErr(t, err)
+		expected := sha256.Sum256(buf.Bytes())
+		assertEq(t, "digest", string(expected[:]), string(h.Sum(nil)))
+	})
+	t.Run("frame", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := sha256.New()
+		enc := json.NewEncoder(&buf)
+		enc.SetHash(h)
+		assertErr(t, enc.EncodeFrame(struct {
+			ID int `json:"id"`
+		}{ID: 1}))
+		expected := sha256.Sum256([]byte(`{"id":1}`))
+		assertEq(t, "digest", string(expected[:]), string(h.Sum(nil)))
+	})
+}
@@ -0,0 +1,131 @@
+package json
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// AfterUnmarshaler is the interface implemented by types whose values are completed or
+// checked once they are decoded, for example to normalize fields, to compute derived
+// fields or to reject invalid combinations of fields. AfterUnmarshalJSON is called on
+// the address of the value after the JSON value is decoded into it, including by an
+// UnmarshalJSON method, and its error is returned by the decoding.
+// It is not called when the JSON value is null, which leaves the value unchanged.
+type AfterUnmarshaler interface {
+	AfterUnmarshalJSON() error
+}
+
+var afterUnmarshalerType = reflect.TypeOf((*AfterUnmarshaler)(nil)).Elem()
+
+// afterUnmarshalDecoder calls AfterUnmarshalJSON once dec decoded a value.
+type afterUnmarshalDecoder struct {
+	dec decoder
+	typ *rtype // the pointer type implementing AfterUnmarshaler
+}
+
+// withHooks returns dec, the decoder of typ, calling the AfterUnmarshalJSON and Validate
+// methods of the values it decodes.
+func (d *Decoder) withHooks(dec decoder, typ *rtype) decoder {
+	return d.withValidate(d.withAfterUnmarshal(dec, typ), typ)
+}
+
+// withAfterUnmarshal returns dec, the decoder of typ, calling AfterUnmarshalJSON
+// if the pointer type of typ implements AfterUnmarshaler.
+func (d *Decoder) withAfterUnmarshal(dec decoder, typ *rtype) decoder {
+	if typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Interface {
+		return dec
+	}
+	ptrType := ptrTo(typ)
+	if !d.implements(ptrType, afterUnmarshalerType) {
+		return dec
+	}
+	return &afterUnmarshalDecoder{dec: dec, typ: ptrType}
+}
+
+func (d *afterUnmarshalDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
+	d.dec.setDisallowUnknownFields(disallowUnknownFields)
+}
+
+func (d *afterUnmarshalDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	isNull := s.char() == 'n'
+	if err := d.dec.decodeStream(s, p); err != nil {
+		return err
+	}
+	if isNull {
+		return nil
+	}
+	return d.afterUnmarshal(p)
+}
+
+func (d *afterUnmarshalDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	isNull := buf[cursor] == 'n'
+	c, err := d.dec.decode(ctx, cursor, p)
+	if err != nil {
+		return 0, err
+	}
+	if isNull {
+		return c, nil
+	}
+	if err := d.afterUnmarshal(p); err != nil {
+		return 0, err
+	}
+	return c, nil
+}
+
+func (d *afterUnmarshalDecoder) afterUnmarshal(p uintptr) error {
+	v := *(*interface{})(unsafe.Pointer(&interfaceHeader{
+		typ: d.typ,
+		ptr: unsafe.Pointer(p),
+	}))
+	return v.(AfterUnmarshaler).AfterUnmarshalJSON()
+}
+
+// BeforeMarshaler is the interface implemented by types whose values are prepared before
+// they are encoded, for example to refresh computed fields or to enforce invariants.
+// BeforeMarshalJSON is called right before the value is encoded, including before its MarshalJSON
+// method, and an error stops the encoding with a MarshalerError. A method of the pointer type is
+// called on a copy of the value, which is encoded instead, so that encoding never modifies values.
+// It is not called for nil pointers, nor for maps encoded directly.
+type BeforeMarshaler interface {
+	BeforeMarshalJSON() error
+}
+
+var beforeMarshalerType = reflect.TypeOf((*BeforeMarshaler)(nil)).Elem()
+
+// withBeforeMarshal returns code, the opcodes of typ, preceded by a call to
+// BeforeMarshalJSON if the pointer type of typ implements BeforeMarshaler.
+func (e *Encoder) withBeforeMarshal(typ *rtype, code *opcode) *opcode {
+	if typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Interface {
+		return code
+	}
+	if !e.implements(ptrTo(typ), beforeMarshalerType) {
+		return code
+	}
+	return newOpCode(opBeforeMarshal, typ, code.indent, code)
+}
+
+// beforeMarshal calls BeforeMarshalJSON on the value at code.ptr and returns the address
+// of the value to encode, which is that of a copy kept by the encoder for pointer methods.
+func (e *Encoder) beforeMarshal(code *opcode) (uintptr, error) {
+	if code.ptr == 0 {
+		return 0, nil
+	}
+	t := rtype2type(code.typ)
+	v := reflect.NewAt(t, unsafe.Pointer(code.ptr))
+	ptr := code.ptr
+	if !code.typ.Implements(beforeMarshalerType) {
+		// the value may be read-only, such as one held by an interface
+		copied := reflect.New(t)
+		copied.Elem().Set(v.Elem())
+		e.hookValues = append(e.hookValues, copied.Interface())
+		v = copied
+		ptr = copied.Pointer()
+	}
+	if err := v.Interface().(BeforeMarshaler).BeforeMarshalJSON(); err != nil {
+		return 0, &MarshalerError{Type: t, Err: err, sourceFunc: "BeforeMarshalJSON"}
+	}
+	return ptr, nil
+}
@@ -0,0 +1,172 @@
+package json_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type hookRange struct {
+	Min   int `json:"min"`
+	Max   int `json:"max"`
+	Width int `json:"-"`
+}
+
+func (r *hookRange) AfterUnmarshalJSON() error {
+	if r.Min > r.Max {
+		return errors.New("min above max")
+	}
+	r.Width = r.Max - r.Min
+	return nil
+}
+
+type hookName string
+
+func (n *hookName) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*n = hookName(s)
+	return nil
+}
+
+func (n *hookName) AfterUnmarshalJSON() error {
+	*n = hookName(strings.ToLower(string(*n)))
+	return nil
+}
+
+type hookList []int
+
+func (l *hookList) AfterUnmarshalJSON() error {
+	if *l == nil {
+		return errors.New("called for null")
+	}
+	return nil
+}
+
+type hookRecord struct {
+	Name   hookName    `json:"name"`
+	Range  hookRange   `json:"range"`
+	List   hookList    `json:"list"`
+	Ranges []hookRange `json:"ranges"`
+}
+
+func Test_AfterUnmarshaler(t *testing.T) {
+	src := `{"name":"Alice","range":{"min":1,"max":4},"list":null,"ranges":[{"min":0,"max":2}]}`
+	for _, stream := range []bool{false, true} {
+		var v hookRecord
+		var err error
+		if stream {
+			err = json.NewDecoder(strings.NewReader(src)).Decode(&v)
+		} else {
+			err = json.Unmarshal([]byte(src), &v)
+		}
+		assertErr(t, err)
+		assertEq(t, "unmarshaler", hookName("alice"), v.Name)
+		assertEq(t, "field", 3, v.Range.Width)
+		assertEq(t, "null", true, v.List == nil)
+		assertEq(t, "element", 2, v.Ranges[0].Width)
+	}
+	t.Run("top-level", func(t *testing.T) {
+		var r hookRange
+		assertErr(t, json.Unmarshal([]byte(`{"min":2,"max":7}`), &r))
+		assertEq(t, "width", 5, r.Width)
+		var n hookName
+		assertErr(t, json.NewDecoder(strings.NewReader(`"BOB"`)).Decode(&n))
+		assertEq(t, "name", hookName("bob"), n)
+	})
+	t.Run("error", func(t *testing.T) {
+		src := `{"range":{"min":5,"max":1}}`
+		var v hookRecord
+		if err := json.Unmarshal([]byte(src), &v); err == nil || err.Error() != "min above max" {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if err := json.NewDecoder(strings.NewReader(src)).Decode(&v); err == nil || err.Error() != "min above max" {
+			t.Fatalf("unexpected stream error %v", err)
+		}
+	})
+}
+
+type hookTotal struct {
+	Items []int `json:"items"`
+	Total int   `json:"total"`
+}
+
+func (t *hookTotal) BeforeMarshalJSON() error {
+	if len(t.Items) > 3 {
+		return errors.New("too many items")
+	}
+	t.Total = 0
+	for _, v := range t.Items {
+		t.Total += v
+	}
+	return nil
+}
+
+type hookCelsius float64
+
+func (c hookCelsius) BeforeMarshalJSON() error {
+	if c < -273.15 {
+		return errors.New("below absolute zero")
+	}
+	return nil
+}
+
+func (c hookCelsius) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + strconv.FormatFloat(float64(c), 'f', 1, 64) + `C"`), nil
+}
+
+type hookNode struct {
+	Items []int     `json:"items"`
+	Total int       `json:"total"`
+	Next  *hookNode `json:"next,omitempty"`
+}
+
+func (n *hookNode) BeforeMarshalJSON() error {
+	n.Total = len(n.Items)
+	return nil
+}
+
+func Test_BeforeMarshaler(t *testing.T) {
+	v := &hookTotal{Items: []int{1, 2}}
+	bytes, err := json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "pointer", `{"items":[1,2],"total":3}`, string(bytes))
+	assertEq(t, "unmodified", 0, v.Total)
+
+	bytes, err = json.Marshal(struct {
+		Value  hookTotal     `json:"value"`
+		Ptr    *hookTotal    `json:"ptr"`
+		Values []hookTotal   `json:"values"`
+		Temp   hookCelsius   `json:"temp"`
+		Temps  []interface{} `json:"temps"`
+	}{
+		Value:  hookTotal{Items: []int{4}},
+		Values: []hookTotal{{Items: []int{5, 6}}},
+		Temp:   21.5,
+		Temps:  []interface{}{hookCelsius(3), &hookTotal{Items: []int{7}}},
+	})
+	assertErr(t, err)
+	assertEq(t, "fields", `{"value":{"items":[4],"total":4},"ptr":null,"values":[{"items":[5,6],"total":11}],"temp":"21.5C","temps":["3.0C",{"items":[7],"total":7}]}`, string(bytes))
+
+	bytes, err = json.Marshal(hookNode{Items: []int{1}, Next: &hookNode{Items: []int{1, 2}}})
+	assertErr(t, err)
+	assertEq(t, "recursive", `{"items":[1],"total":1,"next":{"items":[1,2],"total":2}}`, string(bytes))
+
+	bytes, err = json.MarshalIndent(hookTotal{Items: []int{1}}, "", " ")
+	assertErr(t, err)
+	assertEq(t, "read-only", "{\n \"items\": [\n  1\n ],\n \"total\": 1\n}", string(bytes))
+
+	_, err = json.Marshal([]hookCelsius{-300})
+	var merr *json.MarshalerError
+	if !errors.As(err, &merr) || !strings.Contains(err.Error(), "BeforeMarshalJSON") {
+		t.Fatalf("expected BeforeMarshalJSON error, got %v", err)
+	}
+	if _, err := json.Marshal(&hookTotal{Items: []int{1, 2, 3, 4}}); err == nil {
+		t.Fatal("expected error")
+	}
+}
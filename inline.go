@@ -0,0 +1,117 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// isInlineField reports whether the field tag options include "inline".
+func isInlineField(opts []string) bool {
+	for _, opt := range opts[1:] {
+		if opt == "inline" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkInlineField returns the error of an inline field of typ that is not a map with string keys,
+// or of the second inline field of typ, given whether it has one already.
+func checkInlineField(typ *rtype, field reflect.StructField, exists bool) error {
+	if field.Type.Kind() != reflect.Map || field.Type.Key().Kind() != reflect.String {
+		return fmt.Errorf("json: inline field %s of %s must be a map with string keys", field.Name, rtype2type(typ))
+	}
+	if exists {
+		return fmt.Errorf("json: %s has more than one inline field", rtype2type(typ))
+	}
+	return nil
+}
+
+// inlineMap is the map field whose entries are written as members of the object of its struct.
+type inlineMap struct {
+	typ      *rtype
+	declared map[string]bool // the keys of the other fields, whose entries are not written
+}
+
+// encodeInlineMap writes the entries of the inline map of c as members of the object being written,
+// at indent with withIndent.
+func (e *Encoder) encodeInlineMap(c *structFieldCode, indent int, withIndent bool) error {
+	m := reflect.NewAt(rtype2type(c.inline.typ), unsafe.Pointer(c.ptr+c.offset)).Elem()
+	keys := make([]string, 0, m.Len())
+	values := make(map[string]reflect.Value, m.Len())
+	iter := m.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		if c.inline.declared[key] {
+			continue
+		}
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+	if e.hasMapKeyOrder() {
+		sort.Slice(keys, func(i, j int) bool { return e.mapKeyLess(keys[i], keys[j]) })
+	}
+	for _, key := range keys {
+		if withIndent {
+			if e.buf[len(e.buf)-1] != '\n' {
+				e.encodeBytes([]byte{',', '\n'})
+			}
+			e.encodeIndent(indent)
+		} else if e.buf[len(e.buf)-1] != '{' {
+			e.encodeByte(',')
+		}
+		e.encodeString(key)
+		e.encodeByte(':')
+		if withIndent {
+			e.encodeByte(' ')
+		}
+		if err := e.encodeInterfaceValue(values[key].Interface(), indent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inlineField decodes the members of an object that match no field of its struct
+// into the entries of the inline map of the struct.
+type inlineField struct {
+	typ    *rtype // the map type
+	offset uintptr
+	dec    decoder // of the values
+}
+
+func (f *inlineField) setDisallowUnknownFields(disallowUnknownFields bool) {
+	f.dec.setDisallowUnknownFields(disallowUnknownFields)
+}
+
+// store sets the entry key of the map of the struct at p to the value at elem, making the map if it is nil.
+func (f *inlineField) store(p uintptr, key string, elem uintptr) {
+	typ := rtype2type(f.typ)
+	m := reflect.NewAt(typ, unsafe.Pointer(p+f.offset)).Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(typ))
+	}
+	value := reflect.NewAt(typ.Elem(), unsafe.Pointer(elem)).Elem()
+	m.SetMapIndex(reflect.ValueOf(key).Convert(typ.Key()), value)
+}
+
+func (f *inlineField) decodeStream(s *stream, p uintptr, key string) error {
+	elem := unsafe_New(f.typ.Elem())
+	if err := f.dec.decodeStream(s, elem); err != nil {
+		return err
+	}
+	f.store(p, key, elem)
+	return nil
+}
+
+func (f *inlineField) decode(ctx *runtimeContext, cursor int64, p uintptr, key string) (int64, error) {
+	elem := unsafe_New(f.typ.Elem())
+	c, err := f.dec.decode(ctx, cursor, elem)
+	if err != nil {
+		return 0, err
+	}
+	f.store(p, key, elem)
+	return c, nil
+}
@@ -0,0 +1,100 @@
+package json_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type inlineDocument struct {
+	ID    int                    `json:"id"`
+	Extra map[string]interface{} `json:",inline"`
+	Name  string                 `json:"name"`
+}
+
+type inlineOnly struct {
+	Labels map[string]string `json:",inline"`
+}
+
+func Test_InlineMap(t *testing.T) {
+	t.Run("encode", func(t *testing.T) {
+		doc := inlineDocument{ID: 1, Name: "n", Extra: map[string]interface{}{"x-b": 2, "x-a": true, "id": 9}}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetSortMapKeys(true)
+		assertErr(t, enc.Encode(doc))
+		assertEq(t, "encode", `{"id":1,"x-a":true,"x-b":2,"name":"n"}`, strings.TrimSpace(buf.String()))
+	})
+	t.Run("encode head", func(t *testing.T) {
+		bytes, err := json.Marshal(&inlineOnly{Labels: map[string]string{"a": "1"}})
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"a":"1"}`, string(bytes))
+		bytes, err = json.Marshal(inlineOnly{})
+		assertErr(t, err)
+		assertEq(t, "empty", `{}`, string(bytes))
+	})
+	t.Run("encode indent", func(t *testing.T) {
+		v := struct {
+			Only inlineOnly        `json:"only"`
+			Doc  *inlineDocument   `json:"doc"`
+			More map[string]string `json:",inline"`
+		}{
+			Only: inlineOnly{Labels: map[string]string{"a": "1"}},
+			Doc:  &inlineDocument{ID: 2, Extra: map[string]interface{}{"x": 1}},
+		}
+		bytes, err := json.MarshalIndent(v, "", "  ")
+		assertErr(t, err)
+		expected := `{
+  "only": {
+    "a": "1"
+  },
+  "doc": {
+    "id": 2,
+    "x": 1,
+    "name": ""
+  }
+}`
+		assertEq(t, "indent", expected, string(bytes))
+	})
+	t.Run("decode", func(t *testing.T) {
+		src := `{"id":3,"x":"y","name":"q","z":null}`
+		expected := map[string]interface{}{"x": "y", "z": nil}
+		var v inlineDocument
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "id", 3, v.ID)
+		assertEq(t, "name", "q", v.Name)
+		if !reflect.DeepEqual(expected, v.Extra) {
+			t.Fatalf("unexpected extra: %v", v.Extra)
+		}
+		var w inlineDocument
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&w))
+		if !reflect.DeepEqual(expected, w.Extra) {
+			t.Fatalf("unexpected extra: %v", w.Extra)
+		}
+	})
+	t.Run("decode disallowing unknown fields", func(t *testing.T) {
+		var v inlineOnly
+		dec := json.NewDecoder(strings.NewReader(`{"a":"1"}`))
+		dec.DisallowUnknownFields()
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "label", "1", v.Labels["a"])
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := json.Marshal(struct {
+			M map[int]int `json:",inline"`
+		}{})
+		if err == nil {
+			t.Fatal("expected an error for a map without string keys")
+		}
+		var v struct {
+			A map[string]int `json:",inline"`
+			B map[string]int `json:",inline"`
+		}
+		if err := json.Unmarshal([]byte(`{}`), &v); err == nil {
+			t.Fatal("expected an error for two inline fields")
+		}
+	})
+}
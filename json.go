@@ -2,7 +2,9 @@ package json
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"reflect"
 	"strconv"
 )
 
@@ -12,6 +14,28 @@ type Marshaler interface {
 	MarshalJSON() ([]byte, error)
 }
 
+// MarshalerTo is the interface implemented by types that encode themselves
+// by writing tokens and values to an Encoder, with WriteToken and Encode,
+// instead of returning the []byte that MarshalJSON returns. MarshalJSONTo must
+// write exactly one JSON value. Separators, indentation and escaping are applied
+// as for the rest of the output. It is preferred over MarshalJSON when a type
+// implements both.
+//
+// The Encoder passed to MarshalJSONTo appends to the output of the enclosing
+// Encode or Marshal: Encode writes a value into it instead of to the stream.
+type MarshalerTo interface {
+	MarshalJSONTo(*Encoder) error
+}
+
+// Appender is the interface implemented by types that append their JSON encoding
+// to a buffer, so that encoding them allocates nothing. AppendJSON must append
+// exactly one valid JSON value to dst, which is not validated, and return the extended
+// buffer without modifying the contents of dst. It is preferred over MarshalJSON
+// when a type implements both, MarshalJSONTo is preferred over it.
+type Appender interface {
+	AppendJSON(dst []byte) ([]byte, error)
+}
+
 // Unmarshaler is the interface implemented by types
 // that can unmarshal a JSON description of themselves.
 // The input can be assumed to be a valid encoding of
@@ -24,9 +48,25 @@ type Unmarshaler interface {
 	UnmarshalJSON([]byte) error
 }
 
+// UnmarshalerFrom is the interface implemented by types that decode themselves
+// by reading tokens and values from a Decoder, without the []byte holding the
+// whole JSON value that UnmarshalJSON receives. UnmarshalJSONFrom must read
+// exactly one JSON value, for example with Token, More and Decode.
+// It is preferred over UnmarshalJSON when a type implements both.
+//
+// The Decoder passed to UnmarshalJSONFrom is only valid during the call.
+// It reads from the input of the enclosing Decode or Unmarshal: with a Decoder
+// the value is read as a stream, with Unmarshal from a copy of its bytes.
+type UnmarshalerFrom interface {
+	UnmarshalJSONFrom(*Decoder) error
+}
+
 // Marshal returns the JSON encoding of v.
 //
 // Marshal traverses the value v recursively.
+// If an encountered value implements the MarshalerTo interface,
+// Marshal calls its MarshalJSONTo method in preference to MarshalJSON,
+// and likewise the AppendJSON method of the Appender interface.
 // If an encountered value implements the Marshaler interface
 // and is not a nil pointer, Marshal calls its MarshalJSON method
 // to produce JSON. If no MarshalJSON method is present but the
@@ -36,6 +76,10 @@ type Unmarshaler interface {
 // but mimics a similar, necessary exception in the behavior of
 // UnmarshalJSON.
 //
+// The JSON produced by MarshalJSON and AppendJSON methods, and by RawMessage values,
+// is checked to be valid and compacted; invalid JSON fails with a MarshalerError.
+// Encoder.SetTrustedRaw writes it as is instead.
+//
 // Otherwise, Marshal uses the following type-dependent default encodings:
 //
 // Boolean values encode as JSON booleans.
@@ -70,6 +114,79 @@ type Unmarshaler interface {
 // false, 0, a nil pointer, a nil interface value, and any empty array,
 // slice, map, or string.
 //
+// Adding the "deep" option after "omitempty" also treats a struct, or a pointer
+// to a struct, as empty when all of its encodable fields are empty, recursively:
+//
+//   // Field is omitted instead of being encoded as {} when all fields of Options are empty.
+//   Field Options `json:"options,omitempty,deep"`
+//
+// The "default=value" option also omits the field when it is equal to value,
+// the JSON encoding of its documented default, so that only settings changed from
+// their defaults are written. The value cannot contain a comma. Decoding does not
+// apply the default, which is left to the code initializing the struct:
+//
+//   // Field is omitted when it is 100.
+//   Field int `json:"limit,default=100"`
+//
+// The "conv=name" option converts the field value with the Converter
+// registered under name by RegisterConverter, on both encoding and decoding:
+//
+//   // Field is written and read through the "cents" converter.
+//   Field float64 `json:"amount,conv=cents"`
+//
+// The "format:name" option of a []byte field selects its representation instead of
+// a base64 string: base64, base64url, base32, hex, or array for an array of numbers:
+//
+//   // Field is written and read as a hex string.
+//   Field []byte `json:"digest,format:hex"`
+//
+// The "path" option treats the name as a dot-separated path into nested objects,
+// so a flat struct can map members of an envelope without intermediate structs.
+// Fields sharing a prefix are written together into the same objects:
+//
+//   // Field is read from and written to {"user":{"address":{"city":...}}}.
+//   Field string `json:"user.address.city,path"`
+//
+// Each "alias=name" option adds a name the field is also decoded from,
+// for example a legacy name during an API migration; the field is always encoded under its name:
+//
+//   // Field is read from "color", "colour" or "hex_color" and written as "color".
+//   Field string `json:"color,alias=colour,alias=hex_color"`
+//
+// The "deprecated" option marks a field whose presence in decoded input is reported
+// to the hook registered by SetDeprecatedFieldHook; the field is decoded as usual.
+//
+// The "notnull" option makes decoding a null value into the field an error,
+// instead of leaving the field unchanged; see also Decoder.DisallowNull.
+//
+// The "maxlen=n" option limits a decoded string to n bytes of its UTF-8 encoding, not n
+// characters, and the "maxitems=n" option a decoded slice, array or map to n elements.
+// Longer values fail with a FieldLimitError and leave the field unchanged:
+//
+//   Field []string `json:"tags,maxitems=100"`
+//
+// The "method=Name" option encodes the result of the exported method Name of the
+// struct, which takes no arguments and returns a value and optionally an error, in place
+// of the value of the field. Its member is skipped when decoding. The field may be blank or
+// unexported, though go vet reports json tags on such fields:
+//
+//   // The result of Total appears in JSON as key "total".
+//   Sum struct{} `json:"total,method=Total"`
+//
+// The "inline" option of a field of a map type with string keys writes the entries of the
+// map as members of the object of the struct, and decodes the members matching no other
+// field into the map, for objects with known fields and arbitrary extensions. The entries
+// with the key of another field are not written, so the fields take precedence:
+//
+//   // The members other than "id" are read from and written to Extra.
+//   Extra map[string]interface{} `json:",inline"`
+//
+// The "order=n" option places the field by the number n when encoding with TagOrder,
+// see Encoder.SetFieldOrder:
+//
+//   // Field is written first, then the fields of greater orders.
+//   Field string `json:"id,order=1"`
+//
 // As a special case, if the field tag is "-", the field is always omitted.
 // Note that a field with name "-" can still be generated using the tag "-,".
 //
@@ -154,8 +271,7 @@ type Unmarshaler interface {
 // an infinite recursion.
 //
 func Marshal(v interface{}) ([]byte, error) {
-	var b *bytes.Buffer
-	enc := NewEncoder(b)
+	enc := NewEncoder(nil)
 	bytes, err := enc.encodeForMarshal(v)
 	if err != nil {
 		enc.release()
@@ -165,12 +281,25 @@ func Marshal(v interface{}) ([]byte, error) {
 	return bytes, nil
 }
 
+// MarshalValue is like Marshal but encodes the value rv holds.
+// An addressable rv is encoded in place with the compiled encoder of rv.Type(),
+// without the allocation of rv.Interface().
+func MarshalValue(rv reflect.Value) ([]byte, error) {
+	enc := NewEncoder(nil)
+	bytes, err := enc.encodeForMarshalValue(rv)
+	if err != nil {
+		enc.release()
+		return nil, err
+	}
+	enc.release()
+	return bytes, nil
+}
+
 // MarshalIndent is like Marshal but applies Indent to format the output.
 // Each JSON element in the output will begin on a new line beginning with prefix
 // followed by one or more copies of indent according to the indentation nesting.
 func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
-	var b *bytes.Buffer
-	enc := NewEncoder(b)
+	enc := NewEncoder(nil)
 	enc.SetIndent(prefix, indent)
 	bytes, err := enc.encodeForMarshal(v)
 	if err != nil {
@@ -181,6 +310,59 @@ func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
 	return bytes, nil
 }
 
+// MarshalContext is like Marshal but stops with ctx.Err() once ctx is done.
+func MarshalContext(ctx context.Context, v interface{}) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	enc := NewEncoder(nil)
+	enc.ctx = ctx
+	bytes, err := enc.encodeForMarshal(v)
+	enc.ctx = nil
+	if err != nil {
+		enc.release()
+		return nil, err
+	}
+	enc.release()
+	return bytes, nil
+}
+
+// MarshalTo is like Marshal but writes the encoding of v into buf and returns the number of bytes written.
+// It never grows buf: if the encoding does not fit, MarshalTo returns the size it needs along with ErrBufferTooSmall,
+// leaving the contents of buf unspecified.
+// The encoding runs in a pooled buffer, so MarshalTo does not allocate once the pool is warm.
+func MarshalTo(buf []byte, v interface{}) (int, error) {
+	enc := NewEncoder(nil)
+	if err := enc.encode(v); err != nil {
+		enc.release()
+		return 0, err
+	}
+	n := len(enc.buf)
+	if n > len(buf) {
+		enc.release()
+		return n, ErrBufferTooSmall
+	}
+	copy(buf, enc.buf)
+	enc.release()
+	return n, nil
+}
+
+// EncodedSize returns the exact number of bytes Marshal produces for v,
+// so callers can allocate buffers, set Content-Length or reject oversized payloads up front.
+// The encoding runs in a pooled buffer and nothing is copied out of it.
+// Values that are consumed when encoded, such as io.Reader fields and channels,
+// are consumed by EncodedSize as well.
+func EncodedSize(v interface{}) (int, error) {
+	enc := NewEncoder(nil)
+	if err := enc.encode(v); err != nil {
+		enc.release()
+		return 0, err
+	}
+	size := len(enc.buf)
+	enc.release()
+	return size, nil
+}
+
 // Unmarshal parses the JSON-encoded data and stores the result
 // in the value pointed to by v. If v is nil or not a pointer,
 // Unmarshal returns an InvalidUnmarshalError.
@@ -197,7 +379,8 @@ func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
 //
 // To unmarshal JSON into a value implementing the Unmarshaler interface,
 // Unmarshal calls that value's UnmarshalJSON method, including
-// when the input is a JSON null.
+// when the input is a JSON null. The UnmarshalJSONFrom method of
+// the UnmarshalerFrom interface is called in the same way, in preference.
 // Otherwise, if the value implements encoding.TextUnmarshaler
 // and the input is a JSON quoted string, Unmarshal calls that value's
 // UnmarshalText method with the unquoted form of the string.
@@ -255,6 +438,9 @@ func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
 // Instead, they are replaced by the Unicode replacement
 // character U+FFFD.
 //
+// A UTF-8 byte order mark at the start of data is skipped. Input encoded in
+// UTF-16 can be decoded with a Decoder using TranscodeUTF16.
+//
 func Unmarshal(data []byte, v interface{}) error {
 	src := make([]byte, len(data)+1) // append nul byte to end
 	copy(src, data)
@@ -262,6 +448,16 @@ func Unmarshal(data []byte, v interface{}) error {
 	return dec.decodeForUnmarshal(src, v)
 }
 
+// UnmarshalValue is like Unmarshal but stores the result in the value rv refers to.
+// A settable rv, such as a struct field reached through a pointer, receives the result itself;
+// otherwise rv must be a non-nil pointer and the result is stored in the value it points to.
+func UnmarshalValue(data []byte, rv reflect.Value) error {
+	src := make([]byte, len(data)+1) // append nul byte to end
+	copy(src, data)
+	var dec Decoder
+	return dec.decodeForUnmarshalValue(src, rv)
+}
+
 func UnmarshalNoEscape(data []byte, v interface{}) error {
 	src := make([]byte, len(data)+1) // append nul byte to end
 	copy(src, data)
@@ -269,6 +465,18 @@ func UnmarshalNoEscape(data []byte, v interface{}) error {
 	return dec.decodeForUnmarshalNoEscape(src, v)
 }
 
+// UnmarshalContext is like Unmarshal but stops with ctx.Err() once ctx is done.
+// The context is checked periodically between the elements and members of arrays and objects.
+func UnmarshalContext(ctx context.Context, data []byte, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	src := make([]byte, len(data)+1) // append nul byte to end
+	copy(src, data)
+	var dec Decoder
+	return dec.decodeForUnmarshalContext(ctx, src, v)
+}
+
 // A Token holds a value of one of these types:
 //
 //	Delim, for the four JSON delimiters [ ] { }
@@ -303,6 +511,10 @@ func (n Number) MarshalJSON() ([]byte, error) {
 // RawMessage is a raw encoded JSON value.
 // It implements Marshaler and Unmarshaler and can
 // be used to delay JSON decoding or precompute a JSON encoding.
+//
+// Unmarshal decodes a map[string]RawMessage with a single copy of the object,
+// which the values of the map refer to. Marshal checks and compacts a RawMessage
+// like the output of MarshalJSON methods.
 type RawMessage []byte
 
 // MarshalJSON returns m as the JSON encoding of m.
@@ -2,6 +2,9 @@ package json_test
 
 import (
 	"bytes"
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/goccy/go-json"
@@ -116,3 +119,139 @@ func TestIndent(t *testing.T) {
 		}
 	}
 }
+
+func Test_Context(t *testing.T) {
+	large := "[" + strings.Repeat(`{"a":"b","c":[1,2,3]},`, 10000) + "{}]"
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	t.Run("unmarshal", func(t *testing.T) {
+		var v []struct {
+			A string `json:"a"`
+			C []int  `json:"c"`
+		}
+		assertErr(t, json.UnmarshalContext(context.Background(), []byte(large), &v))
+		assertEq(t, "len", 10001, len(v))
+		assertEq(t, "canceled", context.Canceled, json.UnmarshalContext(canceled, []byte(large), &v))
+	})
+	t.Run("unmarshal canceled while decoding", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var v [2000]cancelingValue
+		v[10].cancel = cancel
+		src := "[" + strings.Repeat(`{},`, len(v)-1) + "{}]"
+		assertEq(t, "canceled", context.Canceled, json.UnmarshalContext(ctx, []byte(src), &v))
+		assertEq(t, "decoded", false, v[len(v)-1].decoded)
+	})
+	t.Run("decoder", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		dec := json.NewDecoder(&cancelingReader{r: strings.NewReader(large), cancel: cancel, after: 4})
+		var v []interface{}
+		assertEq(t, "canceled", context.Canceled, dec.DecodeContext(ctx, &v))
+	})
+	t.Run("marshal", func(t *testing.T) {
+		v := make([]struct{ A, B, C int }, 10000)
+		b, err := json.MarshalContext(context.Background(), v)
+		assertErr(t, err)
+		exp, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "marshal", string(exp), string(b))
+		_, err = json.MarshalContext(canceled, v)
+		assertEq(t, "canceled", context.Canceled, err)
+	})
+	t.Run("encoder", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		v := make([]struct{ A, B, C int }, 10000)
+		enc := json.NewEncoder(&cancelingWriter{})
+		assertErr(t, enc.EncodeContext(ctx, v))
+		cancel()
+		assertEq(t, "canceled", context.Canceled, enc.EncodeContext(ctx, v))
+	})
+}
+
+func Test_EncodedSize(t *testing.T) {
+	values := []interface{}{
+		nil,
+		"hello <world>",
+		[]int{1, 2, 3},
+		map[string]interface{}{"a": []string{"x"}, "b": nil},
+		struct {
+			A int    `json:"a"`
+			B string `json:"b,omitempty"`
+		}{A: 1},
+	}
+	for _, v := range values {
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		size, err := json.EncodedSize(v)
+		assertErr(t, err)
+		assertEq(t, fmt.Sprintf("size of %v", v), len(bytes), size)
+	}
+	t.Run("unsupported", func(t *testing.T) {
+		if _, err := json.EncodedSize(complex(1, 2)); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func Test_MarshalTo(t *testing.T) {
+	v := map[string]int{"a": 1}
+	t.Run("fits", func(t *testing.T) {
+		buf := make([]byte, 16)
+		n, err := json.MarshalTo(buf, v)
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"a":1}`, string(buf[:n]))
+	})
+	t.Run("exact", func(t *testing.T) {
+		buf := make([]byte, 7)
+		n, err := json.MarshalTo(buf, v)
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"a":1}`, string(buf[:n]))
+	})
+	t.Run("too small", func(t *testing.T) {
+		buf := make([]byte, 6)
+		n, err := json.MarshalTo(buf, v)
+		assertEq(t, "error", json.ErrBufferTooSmall, err)
+		assertEq(t, "needed", 7, n)
+	})
+	t.Run("allocations", func(t *testing.T) {
+		buf := make([]byte, 64)
+		v := struct{ A, B int }{1, 2}
+		json.MarshalTo(buf, &v)
+		allocs := testing.AllocsPerRun(100, func() {
+			json.MarshalTo(buf, &v)
+		})
+		assertEq(t, "allocations", float64(0), allocs)
+	})
+}
+
+// cancelingReader calls cancel after the given number of reads.
+type cancelingReader struct {
+	r      *strings.Reader
+	cancel func()
+	after  int
+}
+
+func (r *cancelingReader) Read(p []byte) (int, error) {
+	r.after--
+	if r.after == 0 {
+		r.cancel()
+	}
+	return r.r.Read(p)
+}
+
+// cancelingValue calls cancel, if any, when it is decoded.
+type cancelingValue struct {
+	cancel  func()
+	decoded bool
+}
+
+func (v *cancelingValue) UnmarshalJSON([]byte) error {
+	if v.cancel != nil {
+		v.cancel()
+	}
+	v.decoded = true
+	return nil
+}
+
+type cancelingWriter struct{}
+
+func (cancelingWriter) Write(p []byte) (int, error) { return len(p), nil }
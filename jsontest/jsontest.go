@@ -0,0 +1,231 @@
+// Package jsontest compares package json with encoding/json, so that users can
+// check that it is a drop-in replacement for their own types before migrating.
+//
+// The Compare functions run an operation with both packages and describe how
+// the results differ. The Check functions do the same for many values or
+// inputs at once from a test:
+//
+//	func TestCompatibility(t *testing.T) {
+//		jsontest.CheckMarshal(t, jsontest.Semantic, Order{ID: 1}, Order{Items: []Item{{}}})
+//		jsontest.CheckUnmarshal(t, jsontest.Semantic, &Order{}, `{"id":1}`, `{"items":null}`)
+//	}
+package jsontest
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+// Level selects which differences count as divergences.
+type Level int
+
+const (
+	// Bytes reports any difference in the encoded output or in error messages,
+	// including a different map key order or number formatting.
+	Bytes Level = iota
+	// Semantic reports only differences in the encoded JSON values, compared
+	// after decoding, in decoded Go values, or in whether an error is returned.
+	Semantic
+)
+
+// A Divergence describes how an operation differs between package json and encoding/json.
+type Divergence struct {
+	Func  string // the function compared, such as "Marshal"
+	Input string // the value or input passed to Func
+
+	// GoJSON and Std are the results of package json and encoding/json:
+	// the output, the decoded value or the error.
+	GoJSON string
+	Std    string
+
+	// Semantic reports whether the results differ at the Semantic level,
+	// not only at the Bytes level.
+	Semantic bool
+}
+
+func (d *Divergence) String() string {
+	kind := "byte-level"
+	if d.Semantic {
+		kind = "semantic"
+	}
+	return fmt.Sprintf("%s(%s): %s divergence: go-json %s, encoding/json %s", d.Func, d.Input, kind, d.GoJSON, d.Std)
+}
+
+// CompareMarshal marshals v with both packages and returns how the results differ
+// at the given level, or nil if they agree.
+func CompareMarshal(level Level, v interface{}) *Divergence {
+	got, gotErr := json.Marshal(v)
+	want, wantErr := stdjson.Marshal(v)
+	return compareOutputs(level, "Marshal", fmt.Sprintf("%#v", v), "", got, gotErr, want, wantErr)
+}
+
+// CompareMarshalIndent is like CompareMarshal for MarshalIndent.
+func CompareMarshalIndent(level Level, v interface{}, prefix, indent string) *Divergence {
+	got, gotErr := json.MarshalIndent(v, prefix, indent)
+	want, wantErr := stdjson.MarshalIndent(v, prefix, indent)
+	return compareOutputs(level, "MarshalIndent", fmt.Sprintf("%#v, %q, %q", v, prefix, indent), prefix, got, gotErr, want, wantErr)
+}
+
+// CompareUnmarshal unmarshals data with both packages into new values of the type
+// ptr points to and returns how the results differ at the given level, or nil if they agree.
+// The decoded values are compared with reflect.DeepEqual at both levels.
+func CompareUnmarshal(level Level, data []byte, ptr interface{}) *Divergence {
+	typ := reflect.TypeOf(ptr)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		panic(fmt.Sprintf("jsontest: CompareUnmarshal of non-pointer %T", ptr))
+	}
+	got := reflect.New(typ.Elem())
+	gotErr := json.Unmarshal(data, got.Interface())
+	want := reflect.New(typ.Elem())
+	wantErr := stdjson.Unmarshal(data, want.Interface())
+	d := &Divergence{
+		Func:   "Unmarshal",
+		Input:  fmt.Sprintf("%q, %T", data, ptr),
+		GoJSON: result(fmt.Sprintf("%#v", got.Elem().Interface()), gotErr),
+		Std:    result(fmt.Sprintf("%#v", want.Elem().Interface()), wantErr),
+	}
+	switch {
+	case (gotErr == nil) != (wantErr == nil):
+		d.Semantic = true
+	case gotErr != nil:
+		if level == Semantic || gotErr.Error() == wantErr.Error() {
+			return nil
+		}
+	case !reflect.DeepEqual(got.Interface(), want.Interface()):
+		d.Semantic = true
+	default:
+		return nil
+	}
+	return d
+}
+
+// compareOutputs compares encoded outputs, whose lines after the first begin with prefix.
+func compareOutputs(level Level, fn, input, prefix string, got []byte, gotErr error, want []byte, wantErr error) *Divergence {
+	d := &Divergence{
+		Func:   fn,
+		Input:  input,
+		GoJSON: result(string(got), gotErr),
+		Std:    result(string(want), wantErr),
+	}
+	switch {
+	case (gotErr == nil) != (wantErr == nil):
+		d.Semantic = true
+	case gotErr != nil:
+		if level == Semantic || gotErr.Error() == wantErr.Error() {
+			return nil
+		}
+	case bytes.Equal(got, want):
+		return nil
+	default:
+		d.Semantic = !equalJSON(stripPrefix(got, prefix), stripPrefix(want, prefix))
+		if level == Semantic && !d.Semantic {
+			return nil
+		}
+	}
+	return d
+}
+
+// stripPrefix removes prefix from the lines of out after the first, which makes it
+// valid JSON again: MarshalIndent does not write prefix before the first line.
+func stripPrefix(out []byte, prefix string) []byte {
+	if prefix == "" {
+		return out
+	}
+	return bytes.Replace(out, []byte("\n"+prefix), []byte("\n"), -1)
+}
+
+func result(output string, err error) string {
+	if err != nil {
+		return "error " + err.Error()
+	}
+	return output
+}
+
+// CheckMarshal reports the divergences of CompareMarshal for each of values as errors of t.
+func CheckMarshal(t testing.TB, level Level, values ...interface{}) {
+	t.Helper()
+	for _, v := range values {
+		if d := CompareMarshal(level, v); d != nil {
+			t.Error(d)
+		}
+	}
+}
+
+// CheckUnmarshal reports the divergences of CompareUnmarshal for each of inputs as errors of t.
+func CheckUnmarshal(t testing.TB, level Level, ptr interface{}, inputs ...string) {
+	t.Helper()
+	for _, input := range inputs {
+		if d := CompareUnmarshal(level, []byte(input), ptr); d != nil {
+			t.Error(d)
+		}
+	}
+}
+
+// equalJSON reports whether a and b encode the same JSON value, comparing numbers exactly.
+func equalJSON(a, b []byte) bool {
+	va, err := decodeJSON(a)
+	if err != nil {
+		return false
+	}
+	vb, err := decodeJSON(b)
+	if err != nil {
+		return false
+	}
+	return equalValues(va, vb)
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := stdjson.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func equalValues(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, va := range a {
+			vb, ok := b[k]
+			if !ok || !equalValues(va, vb) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalValues(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case stdjson.Number:
+		b, ok := b.(stdjson.Number)
+		if !ok {
+			return false
+		}
+		ra, okA := new(big.Rat).SetString(a.String())
+		rb, okB := new(big.Rat).SetString(b.String())
+		if !okA || !okB {
+			return a == b
+		}
+		return ra.Cmp(rb) == 0
+	}
+	return a == b
+}
@@ -0,0 +1,60 @@
+package jsontest_test
+
+import (
+	"testing"
+
+	"github.com/goccy/go-json/jsontest"
+)
+
+type order struct {
+	ID    int      `json:"id"`
+	Tags  []string `json:"tags,omitempty"`
+	Price float64  `json:"price"`
+}
+
+func Test_CompareMarshal(t *testing.T) {
+	t.Run("agree", func(t *testing.T) {
+		jsontest.CheckMarshal(t, jsontest.Bytes, order{ID: 1, Price: 2.5}, []int{1, 2}, "a<b", nil)
+	})
+	t.Run("semantic", func(t *testing.T) {
+		v := struct {
+			Name string `json:"user.name,path"`
+		}{Name: "x"}
+		d := jsontest.CompareMarshal(jsontest.Semantic, v)
+		if d == nil || !d.Semantic {
+			t.Fatalf("expected a semantic divergence for a path field but got %v", d)
+		}
+	})
+	t.Run("byte-level", func(t *testing.T) {
+		v := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8}
+		if d := jsontest.CompareMarshal(jsontest.Semantic, v); d != nil {
+			t.Fatalf("expected no semantic divergence but got %v", d)
+		}
+		var found bool
+		for i := 0; i < 20 && !found; i++ {
+			if d := jsontest.CompareMarshal(jsontest.Bytes, v); d != nil {
+				found = !d.Semantic
+			}
+		}
+		if !found {
+			t.Fatal("expected a byte-level divergence for the order of map keys")
+		}
+	})
+	t.Run("indent", func(t *testing.T) {
+		if d := jsontest.CompareMarshalIndent(jsontest.Bytes, []order{{ID: 1, Tags: []string{"x"}}}, ">", "  "); d != nil {
+			t.Fatal(d)
+		}
+	})
+}
+
+func Test_CompareUnmarshal(t *testing.T) {
+	t.Run("agree", func(t *testing.T) {
+		jsontest.CheckUnmarshal(t, jsontest.Semantic, &order{}, `{"id":1,"tags":["a"],"price":1e2}`, `{"id":"x"}`, `{"price":-0.5,"tags":[]}`)
+	})
+	t.Run("error", func(t *testing.T) {
+		d := jsontest.CompareUnmarshal(jsontest.Semantic, []byte(`{"id":1`), &order{})
+		if d != nil {
+			t.Fatalf("expected both to fail but got %v", d)
+		}
+	})
+}
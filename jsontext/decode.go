@@ -0,0 +1,483 @@
+package jsontext
+
+import "io"
+
+// frame is an object or array being read or written.
+type frame struct {
+	kind Kind // BeginObjectKind or BeginArrayKind
+	n    int  // number of names and values read or written in it
+}
+
+// state tracks the nesting of the objects and arrays of a token stream.
+type state struct {
+	stack []frame
+}
+
+func (s *state) top() *frame {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return &s.stack[len(s.stack)-1]
+}
+
+// advance records that a token of kind k has been read or written.
+func (s *state) advance(k Kind) {
+	switch k {
+	case EndObjectKind, EndArrayKind:
+		s.stack = s.stack[:len(s.stack)-1]
+		return
+	}
+	if top := s.top(); top != nil {
+		top.n++
+	}
+	if k == BeginObjectKind || k == BeginArrayKind {
+		s.stack = append(s.stack, frame{kind: k})
+	}
+}
+
+// StackDepth returns the number of objects and arrays the current position is nested in.
+func (s *state) StackDepth() int {
+	return len(s.stack)
+}
+
+func closerOf(k Kind) Kind {
+	if k == BeginObjectKind {
+		return EndObjectKind
+	}
+	return EndArrayKind
+}
+
+func contextOf(k Kind) string {
+	if k == BeginObjectKind {
+		return "object"
+	}
+	return "array"
+}
+
+func isValueStart(k Kind) bool {
+	return k != InvalidKind && k != EndObjectKind && k != EndArrayKind
+}
+
+// Decoder reads a stream of JSON tokens and values, such as a sequence of
+// whitespace separated top-level values, checking the grammar as it goes.
+// The commas and colons between tokens are consumed and never returned.
+type Decoder struct {
+	r      io.Reader
+	buf    []byte
+	pos    int
+	tok    int   // start of the token being read
+	mark   int   // start of the bytes kept in buf when reading more
+	base   int64 // input offset of buf[0]
+	err    error // read error, io.EOF at the end of the input
+	fail   error // syntax error returned by every later call
+	peeked Kind  // kind of the token at pos if its separator has already been consumed
+	state
+}
+
+// NewDecoder returns a Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// NewBytesDecoder returns a Decoder that reads from b, which must not be modified while it is in use.
+// Tokens and values it returns refer to b itself.
+func NewBytesDecoder(b []byte) *Decoder {
+	return &Decoder{buf: b, err: io.EOF}
+}
+
+// InputOffset returns the input offset of the end of the last token or value read.
+func (d *Decoder) InputOffset() int64 {
+	return d.base + int64(d.pos)
+}
+
+// PeekKind returns the kind of the next token without consuming it,
+// or InvalidKind at the end of the input or on an error, which the next read then returns.
+func (d *Decoder) PeekKind() Kind {
+	if d.fail != nil {
+		return InvalidKind
+	}
+	d.mark = d.pos
+	k, err := d.before()
+	if err != nil {
+		if err != io.EOF {
+			d.fail = err
+		}
+		return InvalidKind
+	}
+	return k
+}
+
+// ReadToken reads the next token. It returns io.EOF at the end of the input
+// if every object and array has been closed.
+func (d *Decoder) ReadToken() (Token, error) {
+	if d.fail != nil {
+		return Token{}, d.fail
+	}
+	d.mark = d.pos
+	k, err := d.before()
+	if err != nil {
+		return Token{}, d.failed(err)
+	}
+	d.mark = d.pos
+	if err := d.scan(k); err != nil {
+		return Token{}, d.failed(err)
+	}
+	return Token{kind: k, raw: d.buf[d.tok:d.pos]}, nil
+}
+
+// ReadValue reads the next value, including all members or elements of an object or array,
+// and returns its raw text. It returns io.EOF at the end of the input
+// if every object and array has been closed. Within an object, it reads the next name or value.
+func (d *Decoder) ReadValue() (Value, error) {
+	start, err := d.readValue(true)
+	if err != nil {
+		return nil, err
+	}
+	return Value(d.buf[start:d.pos]), nil
+}
+
+// SkipValue skips the next value like ReadValue, without keeping it in memory.
+func (d *Decoder) SkipValue() error {
+	_, err := d.readValue(false)
+	return err
+}
+
+func (d *Decoder) readValue(keep bool) (int, error) {
+	if d.fail != nil {
+		return 0, d.fail
+	}
+	d.mark = d.pos
+	k, err := d.before()
+	if err != nil {
+		return 0, d.failed(err)
+	}
+	d.mark = d.pos
+	depth := len(d.stack)
+	if err := d.scan(k); err != nil {
+		return 0, d.failed(err)
+	}
+	for len(d.stack) > depth {
+		if !keep {
+			d.mark = d.pos
+		}
+		k, err := d.before()
+		if err != nil {
+			return 0, d.failed(err)
+		}
+		if err := d.scan(k); err != nil {
+			return 0, d.failed(err)
+		}
+	}
+	return d.mark, nil
+}
+
+func (d *Decoder) failed(err error) error {
+	if err != io.EOF {
+		d.fail = err
+	}
+	return err
+}
+
+// fill reads more input into buf, dropping the bytes before mark.
+func (d *Decoder) fill() bool {
+	if d.err != nil {
+		return false
+	}
+	if d.mark > 0 {
+		n := copy(d.buf, d.buf[d.mark:])
+		d.buf = d.buf[:n]
+		d.pos -= d.mark
+		d.tok -= d.mark
+		d.base += int64(d.mark)
+		d.mark = 0
+	}
+	if cap(d.buf)-len(d.buf) < 512 {
+		buf := make([]byte, len(d.buf), 2*cap(d.buf)+4096)
+		copy(buf, d.buf)
+		d.buf = buf
+	}
+	for {
+		n, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+		d.buf = d.buf[:len(d.buf)+n]
+		if err != nil {
+			d.err = err
+			return n > 0
+		}
+		if n > 0 {
+			return true
+		}
+	}
+}
+
+// need makes sure the byte at pos is in buf.
+func (d *Decoder) need(context string) error {
+	if d.pos < len(d.buf) || d.fill() {
+		return nil
+	}
+	return d.readErr(context)
+}
+
+func (d *Decoder) readErr(context string) error {
+	if d.err != nil && d.err != io.EOF {
+		return d.err
+	}
+	return errUnexpectedEnd(context, d.InputOffset())
+}
+
+func (d *Decoder) peek() (byte, bool) {
+	if d.pos < len(d.buf) || d.fill() {
+		return d.buf[d.pos], true
+	}
+	return 0, false
+}
+
+func (d *Decoder) skipWhiteSpace() {
+	for {
+		for d.pos < len(d.buf) {
+			switch d.buf[d.pos] {
+			case ' ', '\t', '\r', '\n':
+				d.pos++
+			default:
+				return
+			}
+		}
+		if !d.fill() {
+			return
+		}
+	}
+}
+
+// before consumes the whitespace and separator before the next token
+// and returns its kind, checking that it may appear at the current position.
+func (d *Decoder) before() (Kind, error) {
+	if d.peeked != InvalidKind {
+		return d.peeked, nil
+	}
+	d.skipWhiteSpace()
+	c, ok := d.peek()
+	top := d.top()
+	if top == nil {
+		if !ok {
+			if d.err == io.EOF {
+				return InvalidKind, io.EOF
+			}
+			return InvalidKind, d.err
+		}
+		return d.peekValue(c, "looking for beginning of value")
+	}
+	if !ok {
+		return InvalidKind, d.readErr(contextOf(top.kind))
+	}
+	closer := closerOf(top.kind)
+	switch {
+	case top.kind == BeginObjectKind && top.n%2 == 1:
+		if c != ':' {
+			return InvalidKind, errInvalidCharacter(c, "after object name", d.InputOffset())
+		}
+		return d.afterSeparator("looking for beginning of value")
+	case Kind(c) == closer:
+		d.peeked = closer
+		return closer, nil
+	case top.n > 0:
+		if c != ',' {
+			if top.kind == BeginObjectKind {
+				return InvalidKind, errInvalidCharacter(c, "after object value", d.InputOffset())
+			}
+			return InvalidKind, errInvalidCharacter(c, "after array element", d.InputOffset())
+		}
+		if top.kind == BeginObjectKind {
+			return d.afterSeparator("looking for beginning of object name")
+		}
+		return d.afterSeparator("looking for beginning of value")
+	case top.kind == BeginObjectKind:
+		return d.peekName(c)
+	}
+	return d.peekValue(c, "looking for beginning of value")
+}
+
+func (d *Decoder) afterSeparator(context string) (Kind, error) {
+	d.pos++
+	d.skipWhiteSpace()
+	c, ok := d.peek()
+	if !ok {
+		return InvalidKind, d.readErr(contextOf(d.top().kind))
+	}
+	if context == "looking for beginning of object name" {
+		return d.peekName(c)
+	}
+	return d.peekValue(c, context)
+}
+
+func (d *Decoder) peekName(c byte) (Kind, error) {
+	if c != '"' {
+		return InvalidKind, errInvalidCharacter(c, "looking for beginning of object name", d.InputOffset())
+	}
+	d.peeked = StringKind
+	return StringKind, nil
+}
+
+func (d *Decoder) peekValue(c byte, context string) (Kind, error) {
+	k := kindOf(c)
+	if !isValueStart(k) {
+		return InvalidKind, errInvalidCharacter(c, context, d.InputOffset())
+	}
+	d.peeked = k
+	return k, nil
+}
+
+// scan reads the token of kind k at pos.
+func (d *Decoder) scan(k Kind) error {
+	d.peeked = InvalidKind
+	d.tok = d.pos
+	var err error
+	switch k {
+	case BeginObjectKind, BeginArrayKind, EndObjectKind, EndArrayKind:
+		d.pos++
+	case StringKind:
+		err = d.scanString()
+	case NumberKind:
+		err = d.scanNumber()
+	case NullKind:
+		err = d.scanLiteral("null")
+	case TrueKind:
+		err = d.scanLiteral("true")
+	case FalseKind:
+		err = d.scanLiteral("false")
+	}
+	if err != nil {
+		return err
+	}
+	d.advance(k)
+	return nil
+}
+
+func (d *Decoder) scanString() error {
+	d.pos++
+	for {
+		for d.pos < len(d.buf) {
+			if c := d.buf[d.pos]; c == '"' || c == '\\' || c < 0x20 {
+				break
+			}
+			d.pos++
+		}
+		if err := d.need("string"); err != nil {
+			return err
+		}
+		switch c := d.buf[d.pos]; {
+		case c == '"':
+			d.pos++
+			return nil
+		case c < 0x20:
+			return errInvalidCharacter(c, "in string literal", d.InputOffset())
+		case c != '\\':
+			d.pos++
+			continue
+		}
+		d.pos++
+		if err := d.need("string"); err != nil {
+			return err
+		}
+		switch c := d.buf[d.pos]; c {
+		case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			d.pos++
+		case 'u':
+			d.pos++
+			for i := 0; i < 4; i++ {
+				if err := d.need("string"); err != nil {
+					return err
+				}
+				if !isHex(d.buf[d.pos]) {
+					return errInvalidCharacter(d.buf[d.pos], `in \u hexadecimal character escape`, d.InputOffset())
+				}
+				d.pos++
+			}
+		default:
+			return errInvalidCharacter(c, "in string escape code", d.InputOffset())
+		}
+	}
+}
+
+func (d *Decoder) scanNumber() error {
+	if d.buf[d.pos] == '-' {
+		d.pos++
+		if err := d.need("number"); err != nil {
+			return err
+		}
+	}
+	switch c := d.buf[d.pos]; {
+	case c == '0':
+		d.pos++
+	case '1' <= c && c <= '9':
+		d.scanDigits()
+	default:
+		return errInvalidCharacter(c, "in numeric literal", d.InputOffset())
+	}
+	if c, ok := d.peek(); ok && c == '.' {
+		d.pos++
+		if err := d.needDigit(); err != nil {
+			return err
+		}
+		d.scanDigits()
+	}
+	if c, ok := d.peek(); ok && (c == 'e' || c == 'E') {
+		d.pos++
+		if c, ok := d.peek(); ok && (c == '+' || c == '-') {
+			d.pos++
+		}
+		if err := d.needDigit(); err != nil {
+			return err
+		}
+		d.scanDigits()
+	}
+	return d.afterScalar("after number")
+}
+
+func (d *Decoder) needDigit() error {
+	if err := d.need("number"); err != nil {
+		return err
+	}
+	if c := d.buf[d.pos]; c < '0' || '9' < c {
+		return errInvalidCharacter(c, "in numeric literal", d.InputOffset())
+	}
+	return nil
+}
+
+func (d *Decoder) scanDigits() {
+	for {
+		c, ok := d.peek()
+		if !ok || c < '0' || '9' < c {
+			return
+		}
+		d.pos++
+	}
+}
+
+func (d *Decoder) scanLiteral(lit string) error {
+	for i := 0; i < len(lit); i++ {
+		if err := d.need("literal " + lit); err != nil {
+			return err
+		}
+		if d.buf[d.pos] != lit[i] {
+			return errInvalidCharacter(d.buf[d.pos], "in literal "+lit, d.InputOffset())
+		}
+		d.pos++
+	}
+	return d.afterScalar("after literal " + lit)
+}
+
+// afterScalar checks that a number or literal is followed by a delimiter.
+func (d *Decoder) afterScalar(context string) error {
+	c, ok := d.peek()
+	if !ok {
+		return nil
+	}
+	switch c {
+	case ' ', '\t', '\r', '\n', ',', ':', ']', '}':
+		return nil
+	}
+	return errInvalidCharacter(c, context, d.InputOffset())
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
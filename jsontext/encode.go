@@ -0,0 +1,171 @@
+package jsontext
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// flushSize is the size of buffered output an Encoder writes while values are still open.
+const flushSize = 16 << 10
+
+// Encoder writes a stream of JSON tokens and values, inserting the commas and colons
+// between them and checking the grammar. Each top-level value is followed by a newline
+// and written to the underlying writer once it is complete.
+type Encoder struct {
+	w      io.Writer
+	buf    []byte
+	offset int64 // bytes written to w
+	err    error
+	state
+}
+
+// NewEncoder returns an Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// OutputOffset returns the number of bytes written so far, including buffered ones.
+func (e *Encoder) OutputOffset() int64 {
+	return e.offset + int64(len(e.buf))
+}
+
+// WriteToken writes the next token. A string token read by a Decoder is written as read;
+// one created by String is quoted and escaped.
+func (e *Encoder) WriteToken(t Token) error {
+	if e.err != nil {
+		return e.err
+	}
+	k := t.kind
+	switch k {
+	case InvalidKind:
+		return fmt.Errorf("jsontext: invalid zero Token")
+	case NumberKind:
+		if !isNumber(t.raw) {
+			return fmt.Errorf("jsontext: invalid number %q", t.raw)
+		}
+	}
+	if err := e.before(k); err != nil {
+		return err
+	}
+	if k == StringKind && t.raw == nil {
+		e.buf = appendQuote(e.buf, t.str)
+	} else {
+		e.buf = append(e.buf, t.raw...)
+	}
+	e.advance(k)
+	return e.finish()
+}
+
+// WriteValue writes the next value, which must be exactly one valid JSON value.
+// Within an object, it writes the next name or value.
+// Surrounding whitespace is dropped; whitespace within v is written as is.
+func (e *Encoder) WriteValue(v Value) error {
+	if e.err != nil {
+		return e.err
+	}
+	dec := NewBytesDecoder(v)
+	if _, err := dec.ReadValue(); err != nil {
+		return err
+	}
+	if _, err := dec.ReadToken(); err != io.EOF {
+		return fmt.Errorf("jsontext: invalid data after the value at offset %d", dec.InputOffset())
+	}
+	v = bytes.TrimSpace(v)
+	k := kindOf(v[0])
+	if err := e.before(k); err != nil {
+		return err
+	}
+	e.buf = append(e.buf, v...)
+	if top := e.top(); top != nil {
+		top.n++
+	}
+	return e.finish()
+}
+
+// before writes the separator before a token of kind k, checking that it may appear at the current position.
+func (e *Encoder) before(k Kind) error {
+	top := e.top()
+	if k == EndObjectKind || k == EndArrayKind {
+		switch {
+		case top == nil:
+			return errInvalidToken(k, "at top level", e.OutputOffset())
+		case closerOf(top.kind) != k:
+			return errInvalidToken(k, "in "+contextOf(top.kind), e.OutputOffset())
+		case top.kind == BeginObjectKind && top.n%2 == 1:
+			return errInvalidToken(k, "after object name", e.OutputOffset())
+		}
+		return nil
+	}
+	if top == nil {
+		return nil
+	}
+	if top.kind == BeginObjectKind {
+		if top.n%2 == 1 {
+			e.buf = append(e.buf, ':')
+			return nil
+		}
+		if k != StringKind {
+			return errInvalidToken(k, "as object name", e.OutputOffset())
+		}
+	}
+	if top.n > 0 {
+		e.buf = append(e.buf, ',')
+	}
+	return nil
+}
+
+// finish ends a complete top-level value with a newline and writes the buffered output.
+func (e *Encoder) finish() error {
+	if len(e.stack) == 0 {
+		e.buf = append(e.buf, '\n')
+	} else if len(e.buf) < flushSize {
+		return nil
+	}
+	n, err := e.w.Write(e.buf)
+	e.offset += int64(n)
+	e.buf = e.buf[:0]
+	if err != nil {
+		e.err = err
+	}
+	return err
+}
+
+// isNumber reports whether b is a valid JSON number.
+func isNumber(b []byte) bool {
+	i := 0
+	if i < len(b) && b[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(b) && b[i] == '0':
+		i++
+	case i < len(b) && '1' <= b[i] && b[i] <= '9':
+		i = skipDigits(b, i)
+	default:
+		return false
+	}
+	if i < len(b) && b[i] == '.' {
+		if i++; i == len(b) || b[i] < '0' || '9' < b[i] {
+			return false
+		}
+		i = skipDigits(b, i)
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		if i++; i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		if i == len(b) || b[i] < '0' || '9' < b[i] {
+			return false
+		}
+		i = skipDigits(b, i)
+	}
+	return i == len(b)
+}
+
+func skipDigits(b []byte, i int) int {
+	for i < len(b) && '0' <= b[i] && b[i] <= '9' {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,36 @@
+package jsontext
+
+import "fmt"
+
+// A SyntaxError is a description of a JSON syntax or grammar error.
+type SyntaxError struct {
+	msg    string // description of error
+	Offset int64  // error occurred after reading Offset bytes
+}
+
+func (e *SyntaxError) Error() string { return e.msg }
+
+func errUnexpectedEnd(context string, offset int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("jsontext: unexpected end of JSON input for %s", context),
+		Offset: offset,
+	}
+}
+
+func errInvalidCharacter(c byte, context string, offset int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("jsontext: invalid character %q %s", c, context),
+		Offset: offset,
+	}
+}
+
+func errInvalidToken(kind Kind, context string, offset int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("jsontext: invalid %v token %s", kind, context),
+		Offset: offset,
+	}
+}
+
+func errNotNumber(kind Kind) error {
+	return fmt.Errorf("jsontext: %v token is not a number", kind)
+}
@@ -0,0 +1,228 @@
+// Package jsontext implements the syntactic layer of JSON: reading and writing
+// a stream of tokens and raw values without reflection or Go type mapping.
+//
+// A Decoder reads tokens or whole raw values from a []byte or an io.Reader and
+// checks the grammar as it goes, consuming the commas and colons between them.
+// An Encoder writes tokens and raw values, inserting the separators itself.
+// Together they are the building blocks for custom processors such as filters,
+// reformatters or transcoders that do not need the codec of package json:
+//
+//	dec := jsontext.NewDecoder(r)
+//	enc := jsontext.NewEncoder(w)
+//	for {
+//		tok, err := dec.ReadToken()
+//		if err == io.EOF {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		if tok.Kind() == jsontext.StringKind {
+//			tok = jsontext.String(strings.ToUpper(tok.String()))
+//		}
+//		if err := enc.WriteToken(tok); err != nil {
+//			return err
+//		}
+//	}
+package jsontext
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+)
+
+// Kind is the kind of a token or value, named by its first character.
+type Kind byte
+
+const (
+	InvalidKind     Kind = 0
+	NullKind        Kind = 'n'
+	FalseKind       Kind = 'f'
+	TrueKind        Kind = 't'
+	StringKind      Kind = '"'
+	NumberKind      Kind = '0'
+	BeginObjectKind Kind = '{'
+	EndObjectKind   Kind = '}'
+	BeginArrayKind  Kind = '['
+	EndArrayKind    Kind = ']'
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NullKind:
+		return "null"
+	case FalseKind:
+		return "false"
+	case TrueKind:
+		return "true"
+	case StringKind:
+		return "string"
+	case NumberKind:
+		return "number"
+	case BeginObjectKind:
+		return "{"
+	case EndObjectKind:
+		return "}"
+	case BeginArrayKind:
+		return "["
+	case EndArrayKind:
+		return "]"
+	}
+	return "invalid"
+}
+
+// kindOf returns the kind of the token starting with c.
+func kindOf(c byte) Kind {
+	switch c {
+	case 'n', 'f', 't', '"', '{', '}', '[', ']':
+		return Kind(c)
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return NumberKind
+	}
+	return InvalidKind
+}
+
+// Token is a single JSON token: a literal, a string, a number or a delimiter.
+// Tokens read by a Decoder refer to its buffer and are only valid until its next call;
+// use Clone to keep one.
+type Token struct {
+	kind Kind
+	raw  []byte // literal text; for a string, including its quotes
+	str  string // unquoted string of a String token
+}
+
+var (
+	Null        = Token{kind: NullKind, raw: []byte("null")}
+	False       = Token{kind: FalseKind, raw: []byte("false")}
+	True        = Token{kind: TrueKind, raw: []byte("true")}
+	BeginObject = Token{kind: BeginObjectKind, raw: []byte("{")}
+	EndObject   = Token{kind: EndObjectKind, raw: []byte("}")}
+	BeginArray  = Token{kind: BeginArrayKind, raw: []byte("[")}
+	EndArray    = Token{kind: EndArrayKind, raw: []byte("]")}
+)
+
+// Bool returns the True or False token.
+func Bool(b bool) Token {
+	if b {
+		return True
+	}
+	return False
+}
+
+// String returns a string token for s, quoted and escaped when written.
+func String(s string) Token {
+	return Token{kind: StringKind, str: s}
+}
+
+// Int returns a number token for n.
+func Int(n int64) Token {
+	return Token{kind: NumberKind, raw: strconv.AppendInt(nil, n, 10)}
+}
+
+// Uint returns a number token for n.
+func Uint(n uint64) Token {
+	return Token{kind: NumberKind, raw: strconv.AppendUint(nil, n, 10)}
+}
+
+// Float returns a number token for f. Writing the token of a NaN or an infinity fails.
+func Float(f float64) Token {
+	return Token{kind: NumberKind, raw: strconv.AppendFloat(nil, f, 'g', -1, 64)}
+}
+
+// RawNumber returns a number token written as the literal s, such as "1.50" or "1e100".
+// Writing the token fails if s is not a valid JSON number.
+func RawNumber(s string) Token {
+	return Token{kind: NumberKind, raw: []byte(s)}
+}
+
+// Kind returns the kind of t, or InvalidKind for the zero Token.
+func (t Token) Kind() Kind {
+	return t.kind
+}
+
+// String returns the unquoted value of a string token and the JSON text of any other token.
+func (t Token) String() string {
+	if t.kind != StringKind {
+		return string(t.raw)
+	}
+	if t.raw == nil {
+		return t.str
+	}
+	return unquote(t.raw)
+}
+
+// Bool reports whether t is the true token.
+func (t Token) Bool() bool {
+	return t.kind == TrueKind
+}
+
+// Int returns the value of an integer number token.
+func (t Token) Int() (int64, error) {
+	if t.kind != NumberKind {
+		return 0, errNotNumber(t.kind)
+	}
+	return strconv.ParseInt(string(t.raw), 10, 64)
+}
+
+// Uint returns the value of a non-negative integer number token.
+func (t Token) Uint() (uint64, error) {
+	if t.kind != NumberKind {
+		return 0, errNotNumber(t.kind)
+	}
+	return strconv.ParseUint(string(t.raw), 10, 64)
+}
+
+// Float returns the value of a number token.
+func (t Token) Float() (float64, error) {
+	if t.kind != NumberKind {
+		return 0, errNotNumber(t.kind)
+	}
+	return strconv.ParseFloat(string(t.raw), 64)
+}
+
+// Raw returns the JSON text of t as read by a Decoder, including the quotes of a string.
+// It returns nil for a string token created by String.
+func (t Token) Raw() []byte {
+	return t.raw
+}
+
+// Clone returns a copy of t that does not refer to the buffer of a Decoder.
+func (t Token) Clone() Token {
+	if t.raw != nil {
+		t.raw = append([]byte{}, t.raw...)
+	}
+	return t
+}
+
+// Value is the raw JSON text of a single value, such as an object with all its members.
+// Values read by a Decoder refer to its buffer and are only valid until its next call;
+// use Clone to keep one.
+type Value []byte
+
+// Kind returns the kind of v, named by its first character.
+func (v Value) Kind() Kind {
+	v = bytes.TrimLeft(v, " \t\r\n")
+	if len(v) == 0 {
+		return InvalidKind
+	}
+	return kindOf(v[0])
+}
+
+// IsValid reports whether v is exactly one valid JSON value, optionally surrounded by whitespace.
+func (v Value) IsValid() bool {
+	dec := NewBytesDecoder(v)
+	if _, err := dec.ReadValue(); err != nil {
+		return false
+	}
+	_, err := dec.ReadToken()
+	return err == io.EOF
+}
+
+// Clone returns a copy of v.
+func (v Value) Clone() Value {
+	if v == nil {
+		return nil
+	}
+	return append(Value{}, v...)
+}
@@ -0,0 +1,231 @@
+package jsontext_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/goccy/go-json/jsontext"
+)
+
+func assertErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+func assertEq(t *testing.T, msg string, exp interface{}, act interface{}) {
+	t.Helper()
+	if exp != act {
+		t.Fatalf("failed to test for %s. exp=[%v] but act=[%v]", msg, exp, act)
+	}
+}
+
+const document = ` {"name": "goé\n😀", "tags": ["a", "b"], "n": -1.5e3, "ok": true, "none": null,
+	"nested": {"list": [0, {}, []]}} 42 "tail" `
+
+func readTokens(t *testing.T, dec *jsontext.Decoder) []string {
+	t.Helper()
+	var tokens []string
+	for {
+		tok, err := dec.ReadToken()
+		if err == io.EOF {
+			return tokens
+		}
+		assertErr(t, err)
+		tokens = append(tokens, tok.Kind().String()+":"+tok.String())
+	}
+}
+
+func Test_Decoder_ReadToken(t *testing.T) {
+	expected := strings.Join([]string{
+		"{:{", "string:name", "string:goé\n😀", "string:tags", "[:[", "string:a", "string:b", "]:]",
+		"string:n", "number:-1.5e3", "string:ok", "true:true", "string:none", "null:null",
+		"string:nested", "{:{", "string:list", "[:[", "number:0", "{:{", "}:}", "[:[", "]:]", "]:]", "}:}", "}:}",
+		"number:42", "string:tail",
+	}, " ")
+	t.Run("bytes", func(t *testing.T) {
+		tokens := readTokens(t, jsontext.NewBytesDecoder([]byte(document)))
+		assertEq(t, "tokens", expected, strings.Join(tokens, " "))
+	})
+	t.Run("reader", func(t *testing.T) {
+		tokens := readTokens(t, jsontext.NewDecoder(iotest.OneByteReader(strings.NewReader(document))))
+		assertEq(t, "tokens", expected, strings.Join(tokens, " "))
+	})
+	t.Run("numbers", func(t *testing.T) {
+		dec := jsontext.NewBytesDecoder([]byte(`[12, 1.5, 18446744073709551615]`))
+		dec.ReadToken()
+		tok, _ := dec.ReadToken()
+		n, err := tok.Int()
+		assertErr(t, err)
+		assertEq(t, "int", int64(12), n)
+		tok, _ = dec.ReadToken()
+		f, err := tok.Float()
+		assertErr(t, err)
+		assertEq(t, "float", 1.5, f)
+		tok, _ = dec.ReadToken()
+		u, err := tok.Uint()
+		assertErr(t, err)
+		assertEq(t, "uint", uint64(math.MaxUint64), u)
+	})
+}
+
+func Test_Token_String(t *testing.T) {
+	for src, expected := range map[string]string{
+		`"plain"`:               "plain",
+		`"\ud83d\ude00 \u00e9"`: "😀 é",
+		`"\ud83d x"`:            "� x",
+		`"\ud83d\u0041"`:        "�A",
+		`"\"\\\/\b\f\n\r\t"`:    "\"\\/\b\f\n\r\t",
+	} {
+		tok, err := jsontext.NewBytesDecoder([]byte(src)).ReadToken()
+		assertErr(t, err)
+		assertEq(t, src, expected, tok.String())
+	}
+}
+
+func Test_Decoder_ReadValue(t *testing.T) {
+	dec := jsontext.NewDecoder(iotest.OneByteReader(strings.NewReader(document)))
+	tok, err := dec.ReadToken()
+	assertErr(t, err)
+	assertEq(t, "begin", jsontext.BeginObjectKind, tok.Kind())
+	var members []string
+	for dec.PeekKind() != jsontext.EndObjectKind {
+		name, err := dec.ReadToken()
+		assertErr(t, err)
+		key := name.String() // name refers to the buffer of dec until the next read
+		if key == "tags" {
+			assertErr(t, dec.SkipValue())
+			continue
+		}
+		value, err := dec.ReadValue()
+		assertErr(t, err)
+		members = append(members, key+"="+string(value))
+	}
+	assertEq(t, "members", `name="goé\n😀" n=-1.5e3 ok=true none=null nested={"list": [0, {}, []]}`, strings.Join(members, " "))
+	assertEq(t, "depth", 1, dec.StackDepth())
+	dec.ReadToken()
+	assertEq(t, "depth after end", 0, dec.StackDepth())
+	value, err := dec.ReadValue()
+	assertErr(t, err)
+	assertEq(t, "top-level value", "42", string(value))
+	value, err = dec.ReadValue()
+	assertErr(t, err)
+	assertEq(t, "offset", int64(len(document)-1), dec.InputOffset())
+	if _, err := dec.ReadValue(); err != io.EOF {
+		t.Fatalf("expected io.EOF but got %v", err)
+	}
+}
+
+func Test_Decoder_SyntaxError(t *testing.T) {
+	for _, src := range []string{
+		`[1,]`, `{"a":1,}`, `{"a" 1}`, `{"a":1 "b":2}`, `{1:2}`, `[1}`, `{"a":1]`, `]`, `[1 2]`,
+		`01`, `1.`, `-`, `1e`, `1.5x`, `truex`, `nul`, `"\x"`, `"\u12g4"`, "\"a\nb\"", `"abc`, `[1,2`, `{"a":`,
+	} {
+		dec := jsontext.NewDecoder(strings.NewReader(src))
+		var err error
+		for err == nil {
+			_, err = dec.ReadToken()
+		}
+		if _, ok := err.(*jsontext.SyntaxError); !ok {
+			t.Fatalf("%s: expected *jsontext.SyntaxError but got %v", src, err)
+		}
+		if _, again := dec.ReadToken(); again != err {
+			t.Fatalf("%s: expected the error to be returned again but got %v", src, again)
+		}
+		if jsontext.Value(src).IsValid() {
+			t.Fatalf("%s: expected invalid value", src)
+		}
+	}
+}
+
+func Test_Value(t *testing.T) {
+	assertEq(t, "valid", true, jsontext.Value(` {"a": [1, "x", null]} `).IsValid())
+	assertEq(t, "two values", false, jsontext.Value(`1 2`).IsValid())
+	assertEq(t, "empty", false, jsontext.Value(``).IsValid())
+	assertEq(t, "kind", jsontext.BeginArrayKind, jsontext.Value(" [1]").Kind())
+	assertEq(t, "number kind", jsontext.NumberKind, jsontext.Value("-1").Kind())
+}
+
+func Test_Encoder(t *testing.T) {
+	t.Run("tokens", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := jsontext.NewEncoder(&buf)
+		for _, tok := range []jsontext.Token{
+			jsontext.BeginObject,
+			jsontext.String("name"), jsontext.String("a\"b\\\n\x01<"),
+			jsontext.String("list"), jsontext.BeginArray, jsontext.Int(-1), jsontext.Uint(2), jsontext.Float(1.5),
+			jsontext.RawNumber("1e100"), jsontext.True, jsontext.Bool(false), jsontext.Null, jsontext.EndArray,
+			jsontext.EndObject,
+			jsontext.String("next"),
+		} {
+			assertErr(t, enc.WriteToken(tok))
+		}
+		assertEq(t, "encoded", "{\"name\":\"a\\\"b\\\\\\n\\u0001<\",\"list\":[-1,2,1.5,1e100,true,false,null]}\n\"next\"\n", buf.String())
+		assertEq(t, "offset", int64(buf.Len()), enc.OutputOffset())
+	})
+	t.Run("values", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := jsontext.NewEncoder(&buf)
+		assertErr(t, enc.WriteToken(jsontext.BeginObject))
+		assertErr(t, enc.WriteValue(jsontext.Value(` "raw" `)))
+		assertErr(t, enc.WriteValue(jsontext.Value(`{"x": [1, 2]}`)))
+		assertErr(t, enc.WriteToken(jsontext.String("n")))
+		assertErr(t, enc.WriteValue(jsontext.Value(`3`)))
+		assertErr(t, enc.WriteToken(jsontext.EndObject))
+		assertEq(t, "encoded", "{\"raw\":{\"x\": [1, 2]},\"n\":3}\n", buf.String())
+		if err := enc.WriteValue(jsontext.Value(`[1,`)); err == nil {
+			t.Fatal("expected error for invalid value")
+		}
+		if err := enc.WriteValue(jsontext.Value(`1 2`)); err == nil {
+			t.Fatal("expected error for two values")
+		}
+	})
+	t.Run("grammar", func(t *testing.T) {
+		for _, tokens := range [][]jsontext.Token{
+			{jsontext.EndObject},
+			{jsontext.BeginArray, jsontext.EndObject},
+			{jsontext.BeginObject, jsontext.Int(1)},
+			{jsontext.BeginObject, jsontext.String("a"), jsontext.EndObject},
+			{jsontext.Float(math.NaN())},
+			{jsontext.RawNumber("01")},
+			{{}},
+		} {
+			enc := jsontext.NewEncoder(&bytes.Buffer{})
+			var err error
+			for _, tok := range tokens {
+				if err = enc.WriteToken(tok); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				t.Fatalf("expected error for %v", tokens)
+			}
+		}
+	})
+	t.Run("copy", func(t *testing.T) {
+		var buf bytes.Buffer
+		dec := jsontext.NewBytesDecoder([]byte(document))
+		enc := jsontext.NewEncoder(&buf)
+		for {
+			tok, err := dec.ReadToken()
+			if err == io.EOF {
+				break
+			}
+			assertErr(t, err)
+			assertErr(t, enc.WriteToken(tok))
+		}
+		expected := `{"name":"goé\n😀","tags":["a","b"],"n":-1.5e3,"ok":true,"none":null,"nested":{"list":[0,{},[]]}}` +
+			"\n42\n\"tail\"\n"
+		assertEq(t, "copied", expected, buf.String())
+	})
+	t.Run("invalid utf-8", func(t *testing.T) {
+		var buf bytes.Buffer
+		assertErr(t, jsontext.NewEncoder(&buf).WriteToken(jsontext.String("a\xffb")))
+		assertEq(t, "replaced", "\"a�b\"\n", buf.String())
+	})
+}
@@ -0,0 +1,136 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// limitDecoder checks the length of a decoded field against its "maxlen" or "maxitems" option.
+// The value is decoded into a copy of the field, which is stored in the field unless it is
+// over the limit, so that a rejected value is not left in the field.
+type limitDecoder struct {
+	dec        decoder
+	typ        *rtype
+	option     string
+	limit      int
+	structName string
+	field      string
+}
+
+// fieldLimit returns the option among "maxlen" and "maxitems" in the field tag options and its limit,
+// or an empty option if there is none. maxlen applies to strings and maxitems to slices, arrays and maps,
+// or pointers to them.
+func fieldLimit(field reflect.StructField, opts []string) (string, int, error) {
+	var option string
+	var limit int
+	for _, opt := range opts[1:] {
+		idx := strings.IndexByte(opt, '=')
+		if idx < 0 {
+			continue
+		}
+		name := opt[:idx]
+		if name != "maxlen" && name != "maxitems" {
+			continue
+		}
+		if option != "" {
+			return "", 0, fmt.Errorf("json: field %s has both %s and %s options", field.Name, option, name)
+		}
+		n, err := strconv.Atoi(opt[idx+1:])
+		if err != nil || n < 0 {
+			return "", 0, fmt.Errorf("json: invalid %s option of field %s", opt, field.Name)
+		}
+		option, limit = name, n
+	}
+	if option == "" {
+		return "", 0, nil
+	}
+	typ := field.Type
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch k := typ.Kind(); {
+	case option == "maxlen" && k == reflect.String,
+		option == "maxitems" && (k == reflect.Slice || k == reflect.Array || k == reflect.Map):
+		return option, limit, nil
+	}
+	return "", 0, fmt.Errorf("json: %s option of field %s of type %s", option, field.Name, field.Type)
+}
+
+func newLimitDecoder(dec decoder, structType *rtype, key string, typ *rtype, option string, limit int) *limitDecoder {
+	return &limitDecoder{
+		dec:        dec,
+		typ:        typ,
+		option:     option,
+		limit:      limit,
+		structName: structType.Name(),
+		field:      key,
+	}
+}
+
+func (d *limitDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
+	d.dec.setDisallowUnknownFields(disallowUnknownFields)
+}
+
+// check returns a FieldLimitError if the value at p, decoded from offset, is over the limit.
+func (d *limitDecoder) check(p uintptr, offset int64) error {
+	v := reflect.NewAt(rtype2type(d.typ), unsafe.Pointer(p)).Elem()
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if n := v.Len(); n > d.limit {
+		return &FieldLimitError{
+			Struct: d.structName,
+			Field:  d.field,
+			Option: d.option,
+			Limit:  d.limit,
+			Length: n,
+			Offset: offset,
+		}
+	}
+	return nil
+}
+
+// values returns the field at p and a copy of it to decode into.
+func (d *limitDecoder) values(p uintptr) (field reflect.Value, value reflect.Value) {
+	field = reflect.NewAt(rtype2type(d.typ), unsafe.Pointer(p)).Elem()
+	value = reflect.New(field.Type()).Elem()
+	value.Set(field)
+	return field, value
+}
+
+func (d *limitDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	offset := s.totalOffset()
+	field, value := d.values(p)
+	if err := d.dec.decodeStream(s, value.Addr().Pointer()); err != nil {
+		field.Set(value)
+		return err
+	}
+	if err := d.check(value.Addr().Pointer(), offset); err != nil {
+		return err
+	}
+	field.Set(value)
+	return nil
+}
+
+func (d *limitDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	field, value := d.values(p)
+	end, err := d.dec.decode(ctx, cursor, value.Addr().Pointer())
+	if err != nil {
+		field.Set(value)
+		return 0, err
+	}
+	if err := d.check(value.Addr().Pointer(), cursor); err != nil {
+		return 0, err
+	}
+	field.Set(value)
+	return end, nil
+}
@@ -0,0 +1,97 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// methodName returns the name given by the "method=Name" option of a computed field:
+//
+//	Sum struct{} `json:"total,method=Total"`
+//
+// The result of the method of the struct is encoded under the key of the field instead of
+// the value of the field, which may be unexported or blank. The field is not decoded.
+func methodName(opts []string) string {
+	for _, opt := range opts[1:] {
+		if strings.HasPrefix(opt, "method=") {
+			return opt[len("method="):]
+		}
+	}
+	return ""
+}
+
+// isMethodField reports whether the field with the tag is a computed field.
+func isMethodField(tag string) bool {
+	return methodName(strings.Split(tag, ",")) != ""
+}
+
+// fieldMethod returns the converter calling the method named by the "method" option of
+// the field tag options, on the struct of type typ the field belongs to, or nil.
+// The method must be exported, have no parameters and return a value, optionally followed by an error.
+// Methods of the pointer type are called on a copy of the struct.
+func fieldMethod(typ *rtype, field reflect.StructField, opts []string) (*Converter, error) {
+	name := methodName(opts)
+	if name == "" {
+		return nil, nil
+	}
+	t := rtype2type(typ)
+	onPtr := false
+	m, exists := t.MethodByName(name)
+	if !exists {
+		m, exists = reflect.PtrTo(t).MethodByName(name)
+		onPtr = true
+	}
+	if !exists {
+		return nil, fmt.Errorf("json: unknown method %s of %s for field %s", name, t, field.Name)
+	}
+	mt := m.Type
+	returnsErr := mt.NumOut() == 2 && mt.Out(1) == errorType
+	if mt.NumIn() != 1 || mt.NumOut() != 1 && !returnsErr {
+		return nil, fmt.Errorf("json: method %s of %s for field %s must have no parameters and return a value, optionally followed by an error", name, t, field.Name)
+	}
+	return &Converter{
+		Encode: func(v interface{}) (interface{}, error) {
+			rv := reflect.ValueOf(v)
+			if onPtr {
+				ptr := reflect.New(t)
+				ptr.Elem().Set(rv)
+				rv = ptr
+			}
+			out := rv.Method(m.Index).Call(nil)
+			if returnsErr && !out[1].IsNil() {
+				return nil, &MarshalerError{Type: t, Err: out[1].Interface().(error), sourceFunc: name}
+			}
+			return out[0].Interface(), nil
+		},
+	}, nil
+}
+
+// methodEmptyFunc returns the omitempty check of a computed field of the struct type typ,
+// which calls the method once more to check its result.
+func methodEmptyFunc(typ *rtype, method *Converter) func(uintptr) bool {
+	t := rtype2type(typ)
+	return func(p uintptr) bool {
+		v, err := method.Encode(reflect.NewAt(t, unsafe.Pointer(p)).Elem().Interface())
+		if err != nil {
+			// returned when the field is encoded
+			return false
+		}
+		return isOmittedValue(reflect.ValueOf(v))
+	}
+}
+
+// skipDecoder skips the JSON value of a computed field.
+type skipDecoder struct{}
+
+func (skipDecoder) setDisallowUnknownFields(_ bool) {}
+
+func (skipDecoder) decodeStream(s *stream, _ uintptr) error {
+	return s.skipValue()
+}
+
+func (skipDecoder) decode(ctx *runtimeContext, cursor int64, _ uintptr) (int64, error) {
+	buf := ctx.buf
+	return skipValue(buf, cursor)
+}
@@ -0,0 +1,84 @@
+package json_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type methodOrder struct {
+	Items []int    `json:"items"`
+	Sum   struct{} `json:"total,method=Total"`
+	Tag   struct{} `json:"label,omitempty,method=Label"`
+	N     struct{} `json:"count,method=Count"`
+}
+
+func (o methodOrder) Total() int {
+	total := 0
+	for _, v := range o.Items {
+		total += v
+	}
+	return total
+}
+
+func (o *methodOrder) Label() (string, error) {
+	if len(o.Items) > 3 {
+		return "", errors.New("too many items")
+	}
+	if len(o.Items) == 0 {
+		return "", nil
+	}
+	return "order", nil
+}
+
+func (o methodOrder) Count() int { return len(o.Items) }
+
+type methodUnknown struct {
+	X struct{} `json:"x,method=Missing"`
+}
+
+type methodInvalid struct {
+	X struct{} `json:"x,method=Invalid"`
+}
+
+func (methodInvalid) Invalid(int) int { return 0 }
+
+func Test_MethodFields(t *testing.T) {
+	t.Run("encode", func(t *testing.T) {
+		bytes, err := json.Marshal(methodOrder{Items: []int{1, 2}})
+		assertErr(t, err)
+		assertEq(t, "computed", `{"items":[1,2],"total":3,"label":"order","count":2}`, string(bytes))
+		bytes, err = json.Marshal(&methodOrder{})
+		assertErr(t, err)
+		assertEq(t, "omitempty", `{"items":[],"total":0,"count":0}`, string(bytes))
+		bytes, err = json.MarshalIndent([]methodOrder{{Items: []int{4}}}, "", " ")
+		assertErr(t, err)
+		assertEq(t, "indent", "[\n {\n  \"items\": [\n   4\n  ],\n  \"total\": 4,\n  \"label\": \"order\",\n  \"count\": 1\n }\n]", string(bytes))
+	})
+	t.Run("errors", func(t *testing.T) {
+		_, err := json.Marshal(methodOrder{Items: []int{1, 2, 3, 4}})
+		var merr *json.MarshalerError
+		if !errors.As(err, &merr) || !strings.Contains(err.Error(), "Label") {
+			t.Fatalf("expected error of Label, got %v", err)
+		}
+		if _, err := json.Marshal(methodUnknown{}); err == nil || !strings.Contains(err.Error(), "unknown method") {
+			t.Fatalf("expected unknown method error, got %v", err)
+		}
+		if _, err := json.Marshal(methodInvalid{}); err == nil {
+			t.Fatal("expected invalid method error")
+		}
+	})
+	t.Run("decode", func(t *testing.T) {
+		src := `{"items":[1,2],"total":3,"label":"order","count":{"n":[2]}}`
+		var v methodOrder
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "items", 2, len(v.Items))
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.DisallowUnknownFields()
+		v = methodOrder{}
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "stream items", 2, len(v.Items))
+	})
+}
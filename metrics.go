@@ -0,0 +1,60 @@
+package json
+
+import (
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// Operation identifies the kind of operation an OperationStats describes.
+type Operation int
+
+const (
+	// EncodeOperation is the encoding of a value by Marshal, an Encoder or one of their variants.
+	EncodeOperation Operation = iota
+	// DecodeOperation is the decoding of a value by Unmarshal, a Decoder or one of their variants.
+	DecodeOperation
+)
+
+func (op Operation) String() string {
+	if op == EncodeOperation {
+		return "encode"
+	}
+	return "decode"
+}
+
+// OperationStats describes a single encoding or decoding of a top-level value.
+type OperationStats struct {
+	Op   Operation
+	Type reflect.Type // the type of the value passed in, nil for a nil interface
+
+	// Bytes is the number of bytes written or read. For encoding, output that
+	// io.Reader fields and channels stream straight to an Encoder's writer is not counted.
+	Bytes int64
+
+	Duration time.Duration
+
+	// CacheHit reports whether the codec for Type was already compiled.
+	CacheHit bool
+
+	Err error
+}
+
+type metricsHookHolder struct {
+	fn func(OperationStats)
+}
+
+var metricsHookValue atomic.Value
+
+// SetMetricsHook registers fn to be called after every encoding and decoding
+// of a top-level value, for example to export codec metrics.
+// fn is called synchronously from the encoding or decoding goroutine, so it must be
+// safe for concurrent use and should return quickly. SetMetricsHook(nil) removes the hook.
+func SetMetricsHook(fn func(OperationStats)) {
+	metricsHookValue.Store(metricsHookHolder{fn: fn})
+}
+
+func metricsHook() func(OperationStats) {
+	holder, _ := metricsHookValue.Load().(metricsHookHolder)
+	return holder.fn
+}
@@ -0,0 +1,93 @@
+package json_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type metricsRecorder struct {
+	mu    sync.Mutex
+	stats []json.OperationStats
+}
+
+func (r *metricsRecorder) record(s json.OperationStats) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats = append(r.stats, s)
+}
+
+func (r *metricsRecorder) last(t *testing.T) json.OperationStats {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.stats) == 0 {
+		t.Fatal("no stats recorded")
+	}
+	return r.stats[len(r.stats)-1]
+}
+
+func Test_MetricsHook(t *testing.T) {
+	type metricsT struct {
+		A int    `json:"a"`
+		B string `json:"b"`
+	}
+	var r metricsRecorder
+	json.SetMetricsHook(r.record)
+	defer json.SetMetricsHook(nil)
+
+	t.Run("marshal", func(t *testing.T) {
+		v := metricsT{A: 1, B: "x"}
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		stats := r.last(t)
+		assertEq(t, "op", json.EncodeOperation, stats.Op)
+		assertEq(t, "type", reflect.TypeOf(v), stats.Type)
+		assertEq(t, "bytes", int64(len(bytes)), stats.Bytes)
+		assertEq(t, "first marshal compiles", false, stats.CacheHit)
+		_, err = json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "second marshal is cached", true, r.last(t).CacheHit)
+	})
+	t.Run("encoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		assertErr(t, json.NewEncoder(&buf).Encode([]int{1, 2}))
+		assertEq(t, "bytes", int64(len("[1,2]")), r.last(t).Bytes)
+	})
+	t.Run("unmarshal", func(t *testing.T) {
+		src := `{"a":1,"b":"x"}`
+		var v metricsT
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		stats := r.last(t)
+		assertEq(t, "op", json.DecodeOperation, stats.Op)
+		assertEq(t, "type", reflect.TypeOf(&v), stats.Type)
+		assertEq(t, "bytes", int64(len(src)), stats.Bytes)
+	})
+	t.Run("decoder", func(t *testing.T) {
+		dec := json.NewDecoder(strings.NewReader(`{"a":1} {"a":22}`))
+		var v metricsT
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "first bytes", int64(len(`{"a":1}`)), r.last(t).Bytes)
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "second bytes", int64(len(` {"a":22}`)), r.last(t).Bytes)
+	})
+	t.Run("error", func(t *testing.T) {
+		var v metricsT
+		err := json.Unmarshal([]byte(`{"a":"x"}`), &v)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		assertEq(t, "error", err, r.last(t).Err)
+	})
+	t.Run("removed", func(t *testing.T) {
+		json.SetMetricsHook(nil)
+		n := len(r.stats)
+		_, err := json.Marshal(1)
+		assertErr(t, err)
+		assertEq(t, "no new stats", n, len(r.stats))
+	})
+}
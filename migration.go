@@ -0,0 +1,176 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// A Migration upgrades a decoded document of a schema version to the next version,
+// for example by renaming its members or filling the ones added by the version.
+// The document is an object whose numbers are Numbers as with Decoder.UseNumber.
+type Migration func(doc map[string]interface{}) error
+
+// typeMigrations are the migrations registered by RegisterMigration for a type.
+type typeMigrations struct {
+	versionKey string
+	steps      map[int]Migration // by the version they upgrade from
+	latest     int
+}
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   = map[*rtype]*typeMigrations{}
+)
+
+// RegisterMigration registers fn to upgrade the documents decoded into values of
+// the struct type typ from version from to version from+1.
+// The version of a document is the integer stored in its member versionKey,
+// and 0 without the member.
+//
+// A document older than the latest version, one past the highest from registered,
+// runs the chain of migrations from its version before populating the struct,
+// and its version member is set to the latest version:
+//
+//	json.RegisterMigration(reflect.TypeOf(Config{}), "version", 1, func(doc map[string]interface{}) error {
+//		doc["timeout_ms"] = doc["timeout"] // renamed in version 2
+//		delete(doc, "timeout")
+//		return nil
+//	})
+//
+// Errors of migrations are returned as a MigrationError.
+// All migrations of a type must share versionKey and be registered
+// before the first decoding of the type.
+// RegisterMigration panics if typ is not a struct type or if versionKey differs
+// from the one of earlier migrations of typ.
+func RegisterMigration(typ reflect.Type, versionKey string, from int, fn Migration) {
+	if typ == nil || typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("json: RegisterMigration of non-struct type %v", typ))
+	}
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	m, exists := migrations[type2rtype(typ)]
+	if !exists {
+		m = &typeMigrations{versionKey: versionKey, steps: map[int]Migration{}}
+		migrations[type2rtype(typ)] = m
+	}
+	if m.versionKey != versionKey {
+		panic(fmt.Sprintf("json: RegisterMigration of %v with version key %q instead of %q", typ, versionKey, m.versionKey))
+	}
+	m.steps[from] = fn
+	if from+1 > m.latest {
+		m.latest = from + 1
+	}
+}
+
+func lookupMigrations(typ *rtype) *typeMigrations {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	return migrations[typ]
+}
+
+// migrate returns the upgraded encoding of src, or nil if it is at the latest version.
+func (m *typeMigrations) migrate(typ *rtype, src []byte) ([]byte, error) {
+	if bytes.Equal(src, []byte("null")) {
+		return nil, nil
+	}
+	var doc map[string]interface{}
+	dec := NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	version := 0
+	if v, exists := doc[m.versionKey]; exists {
+		n, ok := v.(Number)
+		if ok {
+			i, err := strconv.Atoi(string(n))
+			version, ok = i, err == nil
+		}
+		if !ok {
+			return nil, &MigrationError{Type: rtype2type(typ), Err: fmt.Errorf("version %v is not an integer", v)}
+		}
+	}
+	if version >= m.latest {
+		return nil, nil
+	}
+	for ; version < m.latest; version++ {
+		fn, exists := m.steps[version]
+		if !exists {
+			return nil, &MigrationError{Type: rtype2type(typ), Version: version, Err: fmt.Errorf("no migration to version %d", version+1)}
+		}
+		if err := fn(doc); err != nil {
+			return nil, &MigrationError{Type: rtype2type(typ), Version: version, Err: err}
+		}
+	}
+	doc[m.versionKey] = m.latest
+	migrated, err := Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return append(migrated, nul), nil
+}
+
+// migrationDecoder runs the migrations of a struct type before decoding it with dec.
+type migrationDecoder struct {
+	typ        *rtype
+	migrations *typeMigrations
+	dec        decoder
+}
+
+// newMigrationDecoder returns dec, or a decoder running the migrations
+// registered for typ by RegisterMigration before decoding with dec.
+func newMigrationDecoder(typ *rtype, dec decoder) decoder {
+	m := lookupMigrations(typ)
+	if m == nil {
+		return dec
+	}
+	return &migrationDecoder{typ: typ, migrations: m, dec: dec}
+}
+
+func (d *migrationDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
+	d.dec.setDisallowUnknownFields(disallowUnknownFields)
+}
+
+func (d *migrationDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	start := s.cursor
+	s.retainBuffer++
+	err := s.skipValue()
+	s.retainBuffer--
+	if err != nil {
+		return err
+	}
+	migrated, err := d.migrations.migrate(d.typ, s.buf[start:s.cursor])
+	if err != nil {
+		return err
+	}
+	if migrated == nil {
+		s.cursor = start
+		return d.dec.decodeStream(s, p)
+	}
+	_, err = d.dec.decode(newRuntimeContext(migrated, &s.decodeOptions), 0, p)
+	return err
+}
+
+func (d *migrationDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	end, err := skipValue(buf, cursor)
+	if err != nil {
+		return 0, err
+	}
+	migrated, err := d.migrations.migrate(d.typ, buf[cursor:end])
+	if err != nil {
+		return 0, err
+	}
+	if migrated == nil {
+		return d.dec.decode(ctx, cursor, p)
+	}
+	if _, err := d.dec.decode(ctx.withBuf(migrated), 0, p); err != nil {
+		return 0, err
+	}
+	return end, nil
+}
@@ -0,0 +1,112 @@
+package json_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+// migrationConfig is at version 2: version 1 renamed "timeout" in seconds
+// to "timeout_ms", and version 2 added "retries".
+type migrationConfig struct {
+	Version   int    `json:"version"`
+	Name      string `json:"name"`
+	TimeoutMS int    `json:"timeout_ms"`
+	Retries   int    `json:"retries"`
+}
+
+func init() {
+	typ := reflect.TypeOf(migrationConfig{})
+	json.RegisterMigration(typ, "version", 0, func(doc map[string]interface{}) error {
+		if timeout, exists := doc["timeout"]; exists {
+			n, err := timeout.(json.Number).Int64()
+			if err != nil {
+				return err
+			}
+			doc["timeout_ms"] = n * 1000
+			delete(doc, "timeout")
+		}
+		return nil
+	})
+	json.RegisterMigration(typ, "version", 1, func(doc map[string]interface{}) error {
+		if _, exists := doc["retries"]; !exists {
+			doc["retries"] = 3
+		}
+		if doc["name"] == "broken" {
+			return errors.New("broken document")
+		}
+		return nil
+	})
+}
+
+func Test_RegisterMigration(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected migrationConfig
+	}{
+		{
+			name:     "without version",
+			src:      `{"name":"a","timeout":2}`,
+			expected: migrationConfig{Version: 2, Name: "a", TimeoutMS: 2000, Retries: 3},
+		},
+		{
+			name:     "version 1",
+			src:      `{"version":1,"name":"b","timeout_ms":500}`,
+			expected: migrationConfig{Version: 2, Name: "b", TimeoutMS: 500, Retries: 3},
+		},
+		{
+			name:     "latest version",
+			src:      `{"version":2,"name":"c","timeout_ms":500,"retries":0}`,
+			expected: migrationConfig{Version: 2, Name: "c", TimeoutMS: 500},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v migrationConfig
+			assertErr(t, json.Unmarshal([]byte(test.src), &v))
+			assertEq(t, "unmarshal", test.expected, v)
+
+			var s migrationConfig
+			assertErr(t, json.NewDecoder(strings.NewReader(test.src)).Decode(&s))
+			assertEq(t, "decode", test.expected, s)
+		})
+	}
+	t.Run("nested", func(t *testing.T) {
+		src := `[{"name":"a","timeout":1}, {"version":2,"name":"b"}]`
+		expected := []migrationConfig{
+			{Version: 2, Name: "a", TimeoutMS: 1000, Retries: 3},
+			{Version: 2, Name: "b"},
+		}
+		var v []migrationConfig
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		if !reflect.DeepEqual(expected, v) {
+			t.Fatalf("unmarshal: expected %+v but got %+v", expected, v)
+		}
+
+		var s []migrationConfig
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&s))
+		if !reflect.DeepEqual(expected, s) {
+			t.Fatalf("decode: expected %+v but got %+v", expected, s)
+		}
+	})
+	t.Run("errors", func(t *testing.T) {
+		for _, src := range []string{
+			`{"name":"broken"}`,
+			`{"version":"1"}`,
+		} {
+			var v migrationConfig
+			err := json.Unmarshal([]byte(src), &v)
+			var migrationErr *json.MigrationError
+			if !errors.As(err, &migrationErr) {
+				t.Fatalf("expected MigrationError for %s but got %v", src, err)
+			}
+		}
+		var v migrationConfig
+		err := json.Unmarshal([]byte(`{"name":"broken"}`), &v)
+		assertEq(t, "error", "json: migration of json_test.migrationConfig from version 1: broken document", err.Error())
+	})
+}
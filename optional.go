@@ -0,0 +1,90 @@
+//go:build go1.18
+// +build go1.18
+
+package json
+
+// optionalState is whether an Optional is absent, null or holds a value.
+type optionalState uint8
+
+const (
+	optionalAbsent optionalState = iota
+	optionalNull
+	optionalPresent
+)
+
+// Optional is a value of type T that records whether its member was absent from the
+// decoded object, explicitly null, or present with a value, as PATCH requests need
+// to tell "leave unchanged" from "clear" and "set":
+//
+//	type UserPatch struct {
+//		Name  json.Optional[string] `json:"name,omitempty"`
+//		Email json.Optional[string] `json:"email,omitempty"`
+//	}
+//
+// The zero Optional is absent. A member decoded from null is null, and any other value
+// is decoded into T with the options of the decoder. Decoding never makes an Optional
+// absent again, unless ResetMissingFields resets the fields of missing members.
+//
+// A present Optional encodes as its value, and the others as null; fields tagged with
+// omitempty or omitzero leave out absent values, so that encoding reproduces the members
+// that were decoded.
+type Optional[T any] struct {
+	value T
+	state optionalState
+}
+
+// Some returns the Optional holding v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{value: v, state: optionalPresent}
+}
+
+// Null returns the null Optional of T.
+func Null[T any]() Optional[T] {
+	return Optional[T]{state: optionalNull}
+}
+
+// IsAbsent reports whether o has no value and is not null, such as a missing member.
+func (o Optional[T]) IsAbsent() bool { return o.state == optionalAbsent }
+
+// IsNull reports whether o is null.
+func (o Optional[T]) IsNull() bool { return o.state == optionalNull }
+
+// IsPresent reports whether o holds a value.
+func (o Optional[T]) IsPresent() bool { return o.state == optionalPresent }
+
+// Get returns the value of o, or the zero value of T, and whether o holds a value.
+func (o Optional[T]) Get() (T, bool) {
+	return o.value, o.state == optionalPresent
+}
+
+// IsZero reports whether o is absent, for the omitzero option.
+func (o Optional[T]) IsZero() bool { return o.state == optionalAbsent }
+
+func (o Optional[T]) isAbsent() bool { return o.state == optionalAbsent }
+
+// MarshalJSONTo implements MarshalerTo, so that the value is encoded with the options of enc.
+func (o Optional[T]) MarshalJSONTo(enc *Encoder) error {
+	if o.state != optionalPresent {
+		return enc.Encode(nil)
+	}
+	return enc.Encode(o.value)
+}
+
+// UnmarshalJSONFrom implements UnmarshalerFrom, so that the value is decoded with the options of dec.
+func (o *Optional[T]) UnmarshalJSONFrom(dec *Decoder) error {
+	s := dec.s
+	s.skipWhiteSpace()
+	if s.char() == 'n' {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		*o = Null[T]()
+		return nil
+	}
+	var v T
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	*o = Some(v)
+	return nil
+}
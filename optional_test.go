@@ -0,0 +1,75 @@
+//go:build go1.18
+// +build go1.18
+
+package json_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type optionalPatch struct {
+	Name  json.Optional[string] `json:"name,omitempty"`
+	Age   json.Optional[int]    `json:"age,omitzero"`
+	Email json.Optional[string] `json:"email"`
+}
+
+func Test_Optional(t *testing.T) {
+	t.Run("decode", func(t *testing.T) {
+		for _, stream := range []bool{false, true} {
+			src := `{"name":"alice","email":null}`
+			var v optionalPatch
+			if stream {
+				assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+			} else {
+				assertErr(t, json.Unmarshal([]byte(src), &v))
+			}
+			name, ok := v.Name.Get()
+			assertEq(t, "name present", true, ok)
+			assertEq(t, "name", "alice", name)
+			assertEq(t, "age absent", true, v.Age.IsAbsent())
+			assertEq(t, "email null", true, v.Email.IsNull())
+			assertEq(t, "email present", false, v.Email.IsPresent())
+		}
+	})
+	t.Run("decode error", func(t *testing.T) {
+		var v optionalPatch
+		if err := json.Unmarshal([]byte(`{"age":"x"}`), &v); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+	t.Run("encode", func(t *testing.T) {
+		bytes, err := json.Marshal(optionalPatch{})
+		assertErr(t, err)
+		assertEq(t, "absent", `{"email":null}`, string(bytes))
+		bytes, err = json.Marshal(optionalPatch{
+			Name:  json.Null[string](),
+			Age:   json.Some(30),
+			Email: json.Some("a@example.com"),
+		})
+		assertErr(t, err)
+		assertEq(t, "set", `{"name":null,"age":30,"email":"a@example.com"}`, string(bytes))
+	})
+	t.Run("round trip", func(t *testing.T) {
+		src := `{"name":null,"age":3,"email":"b@example.com"}`
+		var v optionalPatch
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "round trip", src, string(bytes))
+	})
+	t.Run("nested options", func(t *testing.T) {
+		var v struct {
+			Item json.Optional[struct {
+				A int `json:"a"`
+			}] `json:"item"`
+		}
+		dec := json.NewDecoder(strings.NewReader(`{"item":{"a":1,"b":2}}`))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&v); err == nil {
+			t.Fatal("expected an unknown field error")
+		}
+	})
+}
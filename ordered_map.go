@@ -0,0 +1,180 @@
+package json
+
+import (
+	"bytes"
+	"reflect"
+)
+
+// OrderedMap is a JSON object that keeps its members in insertion order.
+// Unmarshaling into an OrderedMap preserves the order in which members appear
+// in the input, and marshaling writes them back in that same order.
+//
+// Nested objects are decoded as *OrderedMap, arrays as []interface{}
+// and all other values as Unmarshal does for interface{}.
+type OrderedMap struct {
+	items []OrderedMapItem
+	index map[string]int
+}
+
+// OrderedMapItem is a member of an OrderedMap.
+type OrderedMapItem struct {
+	Key   string
+	Value interface{}
+}
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{}
+}
+
+// Len returns the number of members.
+func (m *OrderedMap) Len() int {
+	return len(m.items)
+}
+
+// Get returns the value of the member named key and whether it exists.
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	if i, exists := m.index[key]; exists {
+		return m.items[i].Value, true
+	}
+	return nil, false
+}
+
+// Set sets the value of the member named key.
+// An existing member keeps its position; a new member is appended.
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if i, exists := m.index[key]; exists {
+		m.items[i].Value = value
+		return
+	}
+	if m.index == nil {
+		m.index = map[string]int{}
+	}
+	m.index[key] = len(m.items)
+	m.items = append(m.items, OrderedMapItem{Key: key, Value: value})
+}
+
+// Delete removes the member named key and reports whether it existed.
+func (m *OrderedMap) Delete(key string) bool {
+	i, exists := m.index[key]
+	if !exists {
+		return false
+	}
+	m.items = append(m.items[:i], m.items[i+1:]...)
+	delete(m.index, key)
+	for j := i; j < len(m.items); j++ {
+		m.index[m.items[j].Key] = j
+	}
+	return true
+}
+
+// Keys returns the keys of the members in order.
+func (m *OrderedMap) Keys() []string {
+	keys := make([]string, 0, len(m.items))
+	for _, item := range m.items {
+		keys = append(keys, item.Key)
+	}
+	return keys
+}
+
+// Items returns the members in order.
+// The returned slice must not be modified.
+func (m *OrderedMap) Items() []OrderedMapItem {
+	return m.items
+}
+
+// MarshalJSON implements the Marshaler interface.
+func (m OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, item := range m.items {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := Marshal(item.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := Marshal(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements the Unmarshaler interface.
+// Members already in m are discarded.
+func (m *OrderedMap) UnmarshalJSON(data []byte) error {
+	p := &documentParser{buf: data, strict: true}
+	node, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	if _, err := p.trivia(); err != nil {
+		return err
+	}
+	if p.cursor < len(p.buf) {
+		return p.errorf("invalid character %c after top-level value", p.buf[p.cursor])
+	}
+	if node.kind == NullNode {
+		return nil
+	}
+	if node.kind != ObjectNode {
+		return &UnmarshalTypeError{
+			Value:  node.kind.String(),
+			Type:   reflect.TypeOf(m).Elem(),
+			Offset: int64(len(node.before)),
+		}
+	}
+	m.items = nil
+	m.index = nil
+	return m.setMembers(node)
+}
+
+func (m *OrderedMap) setMembers(node *Node) error {
+	for _, member := range node.members {
+		value, err := orderedValue(member.value)
+		if err != nil {
+			return err
+		}
+		m.Set(member.key, value)
+	}
+	return nil
+}
+
+func orderedValue(node *Node) (interface{}, error) {
+	switch node.kind {
+	case ObjectNode:
+		m := NewOrderedMap()
+		if err := m.setMembers(node); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case ArrayNode:
+		values := make([]interface{}, 0, len(node.elems))
+		for _, elem := range node.elems {
+			value, err := orderedValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case StringNode:
+		var s string
+		if err := Unmarshal(node.literal, &s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	var v interface{}
+	if err := Unmarshal(node.literal, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
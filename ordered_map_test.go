@@ -0,0 +1,82 @@
+package json_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_OrderedMap(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		src := `{"z":1,"a":{"y":true,"b":null},"m":[{"q":"x","c":2}],"k":"v"}`
+		m := json.NewOrderedMap()
+		assertErr(t, json.Unmarshal([]byte(src), m))
+		assertEq(t, "keys", true, reflect.DeepEqual([]string{"z", "a", "m", "k"}, m.Keys()))
+		nested, _ := m.Get("a")
+		assertEq(t, "nested keys", true, reflect.DeepEqual([]string{"y", "b"}, nested.(*json.OrderedMap).Keys()))
+		b, err := json.Marshal(m)
+		assertErr(t, err)
+		assertEq(t, "marshal", src, string(b))
+	})
+	t.Run("set and delete", func(t *testing.T) {
+		m := json.NewOrderedMap()
+		m.Set("b", 1)
+		m.Set("a", 2)
+		m.Set("c", 3)
+		m.Set("b", 4)
+		assertEq(t, "deleted", true, m.Delete("a"))
+		assertEq(t, "deleted", false, m.Delete("a"))
+		m.Set("a", 5)
+		b, err := json.Marshal(m)
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"b":4,"c":3,"a":5}`, string(b))
+	})
+	t.Run("struct field", func(t *testing.T) {
+		var v struct {
+			M json.OrderedMap `json:"m"`
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"m":{"b":1,"a":2}}`), &v))
+		assertEq(t, "len", 2, v.M.Len())
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"m":{"b":1,"a":2}}`, string(b))
+	})
+	t.Run("comments and trailing commas", func(t *testing.T) {
+		for _, src := range []string{`{"a":1 /* c */}`, `{"a":[1,]}`, `{"a":1,}`} {
+			m := json.NewOrderedMap()
+			if err := m.UnmarshalJSON([]byte(src)); err == nil {
+				t.Fatalf("expected error for %s", src)
+			}
+		}
+	})
+	t.Run("escaped value", func(t *testing.T) {
+		src := `{"ké":"a\nbé","l":["\"q\""]}`
+		m := json.NewOrderedMap()
+		assertErr(t, json.Unmarshal([]byte(src), m))
+		v, _ := m.Get("ké")
+		assertEq(t, "value", "a\nbé", v)
+		l, _ := m.Get("l")
+		assertEq(t, "element", `"q"`, l.([]interface{})[0])
+		b, err := json.Marshal(m)
+		assertErr(t, err)
+		assertEq(t, "marshal", src, string(b))
+	})
+	t.Run("escaped key", func(t *testing.T) {
+		m := json.NewOrderedMap()
+		assertErr(t, json.Unmarshal([]byte(`{"a\/b":1,"q\"\u00e9":2}`), m))
+		assertEq(t, "keys", true, reflect.DeepEqual([]string{"a/b", `q"é`}, m.Keys()))
+		b, err := json.Marshal(m)
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"a/b":1,"q\"é":2}`, string(b))
+		if err := json.Unmarshal([]byte(`{"\x41":1}`), m); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("not an object", func(t *testing.T) {
+		m := json.NewOrderedMap()
+		if err := json.Unmarshal([]byte(`[1]`), m); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package json
+
+import (
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// fieldPath returns the members of the nested JSON location a struct field is mapped to
+// by the "path" option of its tag, for example `json:"user.address.city,path"`, or nil.
+func fieldPath(keyName string, opts []string) []string {
+	if len(opts) < 2 {
+		return nil
+	}
+	for _, opt := range opts[1:] {
+		if opt == "path" {
+			return strings.Split(keyName, ".")
+		}
+	}
+	return nil
+}
+
+// pathNode is a member of the nested objects written for the path fields of a struct.
+// A leaf holds the field; the offsets of all leaves are relative to the struct.
+type pathNode struct {
+	name      string
+	key       []byte // "name":
+	children  []*pathNode
+	typ       *rtype
+	offset    uintptr
+	omitEmpty bool
+	isEmpty   func(uintptr) bool
+}
+
+func newPathNode(name string) *pathNode {
+	return &pathNode{name: name, key: compiledKey(name)}
+}
+
+// add adds the field at path below n, creating the objects leading to it.
+func (n *pathNode) add(path []string, field reflect.StructField, omitEmpty bool) {
+	name := path[0]
+	var child *pathNode
+	for _, c := range n.children {
+		if c.name == name && c.typ == nil {
+			child = c
+			break
+		}
+	}
+	if child == nil {
+		child = newPathNode(name)
+		n.children = append(n.children, child)
+	}
+	if len(path) > 1 {
+		child.add(path[1:], field, omitEmpty)
+		return
+	}
+	child.typ = type2rtype(field.Type)
+	child.offset = field.Offset
+	child.omitEmpty = omitEmpty
+	child.isEmpty = valueEmptyFunc(child.typ)
+}
+
+// omitted reports whether n is left out of the struct at p:
+// an omitempty field that is empty or an object whose members are all omitted.
+func (n *pathNode) omitted(p uintptr) bool {
+	if n.typ != nil {
+		return n.omitEmpty && n.isEmpty(p+n.offset)
+	}
+	for _, c := range n.children {
+		if !c.omitted(p) {
+			return false
+		}
+	}
+	return true
+}
+
+type pathCode struct {
+	*opcodeHeader
+	node *pathNode
+}
+
+func (c *pathCode) copy(codeMap map[uintptr]*opcode) *opcode {
+	if c == nil {
+		return nil
+	}
+	addr := uintptr(unsafe.Pointer(c))
+	if code, exists := codeMap[addr]; exists {
+		return code
+	}
+	path := &pathCode{node: c.node}
+	code := (*opcode)(unsafe.Pointer(path))
+	codeMap[addr] = code
+
+	path.opcodeHeader = c.opcodeHeader.copy(codeMap)
+	return code
+}
+
+func (c *opcode) toPathCode() *pathCode {
+	return (*pathCode)(unsafe.Pointer(c))
+}
+
+// compilePath returns the opcode writing the object node of the struct typ.
+// It receives the pointer to the struct itself.
+func (e *Encoder) compilePath(typ *rtype, node *pathNode) *opcode {
+	return (*opcode)(unsafe.Pointer(&pathCode{
+		opcodeHeader: &opcodeHeader{
+			op:     opPath,
+			typ:    typ,
+			indent: e.indent,
+			next:   newEndOp(e.indent),
+		},
+		node: node,
+	}))
+}
+
+func (e *Encoder) encodePathNode(node *pathNode, p uintptr, indent int) error {
+	if node.typ != nil {
+		v := reflect.NewAt(rtype2type(node.typ), unsafe.Pointer(p+node.offset)).Elem().Interface()
+		return e.encodeInterfaceValue(v, indent)
+	}
+	e.encodeByte('{')
+	first := true
+	for _, c := range node.children {
+		if c.omitted(p) {
+			continue
+		}
+		if !first {
+			e.encodeByte(',')
+		}
+		if e.enabledIndent {
+			e.encodeByte('\n')
+			e.encodeIndent(indent + 1)
+		}
+		e.encodeKey(c.key)
+		if e.enabledIndent {
+			e.encodeByte(' ')
+		}
+		if err := e.encodePathNode(c, p, indent+1); err != nil {
+			return err
+		}
+		first = false
+	}
+	if e.enabledIndent && !first {
+		e.encodeByte('\n')
+		e.encodeIndent(indent)
+	}
+	e.encodeByte('}')
+	return nil
+}
+
+// pathDecoder decodes an object leading to path fields into the struct the fields belong to.
+type pathDecoder struct {
+	*structDecoder
+}
+
+// addPathField maps the member at path of the JSON object decoded into a struct
+// to fieldSet, nesting a pathDecoder for every object leading to it.
+func addPathField(fieldMap map[string]*structFieldSet, path []string, fieldSet *structFieldSet) {
+	name := path[0]
+	if len(path) == 1 {
+		fieldMap[name] = fieldSet
+		fieldMap[strings.ToLower(name)] = fieldSet
+		return
+	}
+	var dec *pathDecoder
+	if parent, exists := fieldMap[name]; exists {
+		dec, _ = parent.dec.(*pathDecoder)
+	}
+	if dec == nil {
+		dec = &pathDecoder{newStructDecoder(map[string]*structFieldSet{})}
+		parent := &structFieldSet{dec: dec, offset: 0}
+		fieldMap[name] = parent
+		fieldMap[strings.ToLower(name)] = parent
+	}
+	addPathField(dec.fieldMap, path[1:], fieldSet)
+}
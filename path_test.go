@@ -0,0 +1,65 @@
+package json_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type pathEnvelope struct {
+	ID      int    `json:"id"`
+	Name    string `json:"data.user.name,path"`
+	City    string `json:"data.user.address.city,path"`
+	Zip     string `json:"data.user.address.zip,omitempty,path"`
+	Version int    `json:"meta.version,path"`
+	Status  string `json:"status"`
+}
+
+func Test_PathField(t *testing.T) {
+	v := pathEnvelope{ID: 1, Name: "bob", City: "Tokyo", Version: 2, Status: "ok"}
+	src := `{"id":1,"data":{"user":{"name":"bob","address":{"city":"Tokyo"}}},"meta":{"version":2},"status":"ok"}`
+	t.Run("encode", func(t *testing.T) {
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "encoded", src, string(bytes))
+	})
+	t.Run("encode indent", func(t *testing.T) {
+		bytes, err := json.MarshalIndent(struct {
+			A int `json:"x.a,path"`
+			B int `json:"x.b,path"`
+		}{A: 1, B: 2}, "", "  ")
+		assertErr(t, err)
+		assertEq(t, "encoded", "{\n  \"x\": {\n    \"a\": 1,\n    \"b\": 2\n  }\n}", string(bytes))
+	})
+	t.Run("omitempty", func(t *testing.T) {
+		bytes, err := json.Marshal(struct {
+			A string `json:"x.a,omitempty,path"`
+			B int    `json:"b"`
+		}{B: 1})
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"b":1}`, string(bytes))
+	})
+	t.Run("decode", func(t *testing.T) {
+		var got pathEnvelope
+		assertErr(t, json.Unmarshal([]byte(`{"id":1,"data":{"user":{"name":"bob","address":{"city":"Tokyo","zip":"100"}}},"meta":{"version":2},"status":"ok"}`), &got))
+		exp := v
+		exp.Zip = "100"
+		assertEq(t, "decoded", exp, got)
+	})
+	t.Run("decode stream", func(t *testing.T) {
+		var got pathEnvelope
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&got))
+		assertEq(t, "decoded", v, got)
+	})
+	t.Run("dotted key without path option", func(t *testing.T) {
+		var got struct {
+			A int `json:"a.b"`
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"a.b":1}`), &got))
+		assertEq(t, "decoded", 1, got.A)
+		bytes, err := json.Marshal(got)
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"a.b":1}`, string(bytes))
+	})
+}
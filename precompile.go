@@ -0,0 +1,76 @@
+package json
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Precompile compiles the encoders and decoders of types and caches them, so that the
+// first Marshal and Unmarshal of their values do not pay for the compilation.
+// It suits the instantiations of generic types used by a program, which are types of
+// their own with codecs of their own:
+//
+//	err := json.Precompile(
+//		reflect.TypeOf(Page[User]{}),
+//		reflect.TypeOf(Result[Order]{}),
+//	)
+//
+// The codecs of a type and of the pointer to it are both compiled for encoding.
+// Precompile returns the first error of a type that cannot be encoded or decoded.
+func Precompile(types ...reflect.Type) error {
+	enc := NewEncoder(nil)
+	defer enc.release()
+	return precompile(enc, &Decoder{}, types)
+}
+
+// Precompile is like the Precompile function, for the caches of a.
+func (a *API) Precompile(types ...reflect.Type) error {
+	enc := a.NewEncoder(nil)
+	defer enc.release()
+	opts := a.config.decodeOptions()
+	dec := &Decoder{
+		decoders:              &a.decoders,
+		disallowUnknownFields: a.config.DisallowUnknownFields,
+		ignorePromoted:        a.config.IgnorePromotedUnmarshalers,
+		binaryUnmarshalers:    a.config.BinaryUnmarshalers,
+		opts:                  &opts,
+	}
+	return precompile(enc, dec, types)
+}
+
+func precompile(enc *Encoder, dec *Decoder, types []reflect.Type) error {
+	for _, t := range types {
+		if isDataWordKind(t.Kind()) {
+			// encoded without compiled codes, and never decoded
+			continue
+		}
+		ptrType := reflect.PtrTo(t)
+		encodeTypes := []reflect.Type{t, ptrType}
+		if t.Kind() == reflect.Interface {
+			// values of interfaces are encoded by their dynamic type
+			encodeTypes = encodeTypes[1:]
+		}
+		for _, et := range encodeTypes {
+			typ := type2rtype(et)
+			key := opcodeKey{
+				typeptr:          uintptr(unsafe.Pointer(typ)),
+				v2:               enc.v2,
+				ignorePromoted:   enc.ignorePromoted,
+				binaryMarshalers: enc.binaryMarshalers,
+				stringers:        enc.stringers,
+				errorMessages:    enc.errorMessages,
+				fieldOrder:       enc.fieldOrder,
+			}
+			if enc.opcodes.get(key) != nil {
+				continue
+			}
+			if _, _, err := enc.compileOpcodeSet(key, typ); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.compiledDecoder(type2rtype(ptrType)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+//go:build go1.18
+// +build go1.18
+
+package json_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type precompilePage[T any] struct {
+	Items []T  `json:"items"`
+	Next  *int `json:"next,omitempty"`
+}
+
+type precompileUser struct {
+	Name string `json:"name"`
+}
+
+func Test_Precompile(t *testing.T) {
+	t.Run("instantiations", func(t *testing.T) {
+		assertErr(t, json.Precompile(
+			reflect.TypeOf(precompilePage[precompileUser]{}),
+			reflect.TypeOf(precompilePage[int]{}),
+		))
+		before := json.Stats()
+		b, err := json.Marshal(precompilePage[precompileUser]{Items: []precompileUser{{Name: "a"}}})
+		assertErr(t, err)
+		assertEq(t, "users", `{"items":[{"name":"a"}]}`, string(b))
+		b, err = json.Marshal(&precompilePage[int]{Items: []int{1, 2}})
+		assertErr(t, err)
+		assertEq(t, "ints", `{"items":[1,2]}`, string(b))
+		var v precompilePage[int]
+		assertErr(t, json.Unmarshal([]byte(`{"items":[3]}`), &v))
+		assertEq(t, "decoded", 3, v.Items[0])
+		after := json.Stats()
+		assertEq(t, "encoder compiles", before.EncoderCompiles, after.EncoderCompiles)
+		assertEq(t, "decoder compiles", before.DecoderCompiles, after.DecoderCompiles)
+	})
+	t.Run("api", func(t *testing.T) {
+		api := json.Config{DisallowUnknownFields: true}.Freeze()
+		assertErr(t, api.Precompile(reflect.TypeOf(precompilePage[string]{})))
+		before := json.Stats()
+		var v precompilePage[string]
+		if err := api.Unmarshal([]byte(`{"items":["a"],"extra":1}`), &v); err == nil {
+			t.Fatal("expected error for the unknown field")
+		}
+		assertEq(t, "decoder compiles", before.DecoderCompiles, json.Stats().DecoderCompiles)
+	})
+	t.Run("unsupported", func(t *testing.T) {
+		if err := json.Precompile(reflect.TypeOf(precompilePage[complex128]{})); err == nil {
+			t.Fatal("expected error for an unsupported type")
+		}
+	})
+}
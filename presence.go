@@ -0,0 +1,90 @@
+package json
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// Presence records which members of the decoded object matched the fields of the struct
+// it is a field of, so that a field left at its zero value can be told apart from a field
+// decoded from its zero value without making it a pointer:
+//
+//	type UserPatch struct {
+//		Name    string        `json:"name"`
+//		Age     int           `json:"age"`
+//		Present json.Presence `json:"-"`
+//	}
+//
+//	if patch.Present.Has("age") {
+//		user.Age = patch.Age
+//	}
+//
+// A field of type Presence, exported or not, is filled for every object decoded into the struct,
+// replacing the members of an earlier decoding, and is never encoded. Members matching no field
+// are not recorded; a member decoded from null is.
+type Presence struct {
+	names []string // the names of the fields of the struct, shared by its decoder
+	bits  []uint64 // the fields present, by their index in names
+}
+
+var presenceType = type2rtype(reflect.TypeOf(Presence{}))
+
+// Has reports whether the member name of a field, as given by its tag or the name of the field,
+// was present in the decoded object. Aliases of a field record the field under its name.
+func (p Presence) Has(name string) bool {
+	for i, n := range p.names {
+		if n == name {
+			return p.bits[i/64]&(1<<(uint(i)%64)) != 0
+		}
+	}
+	return false
+}
+
+// Names returns the sorted names of the fields that were present in the decoded object.
+func (p Presence) Names() []string {
+	var names []string
+	for i, n := range p.names {
+		if p.bits[i/64]&(1<<(uint(i)%64)) != 0 {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// presenceField fills the Presence field of a struct.
+type presenceField struct {
+	offset uintptr
+	names  []string
+}
+
+// newPresenceField returns the filling of the Presence field at offset of the struct decoded by dec.
+func newPresenceField(offset uintptr, dec *structDecoder) *presenceField {
+	names := make([]string, len(dec.fields))
+	for key, field := range dec.fieldMap {
+		switch {
+		case field.name != "":
+			names[field.index] = field.name
+		case names[field.index] == "" || key != strings.ToLower(key):
+			// the object of path fields, mapped by its name as by its lowercase name
+			names[field.index] = key
+		}
+	}
+	return &presenceField{offset: offset, names: names}
+}
+
+// reset records no field as present in the struct at p, and returns the bits to set.
+func (f *presenceField) reset(p uintptr) []uint64 {
+	bits := make([]uint64, (len(f.names)+63)/64)
+	*(*Presence)(unsafe.Pointer(p + f.offset)) = Presence{names: f.names, bits: bits}
+	return bits
+}
+
+// setPresent sets the bit of field in bits, if the struct records its presence.
+func setPresent(bits []uint64, field *structFieldSet) {
+	if bits != nil {
+		bits[field.index/64] |= 1 << (uint(field.index) % 64)
+	}
+}
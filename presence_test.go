@@ -0,0 +1,62 @@
+package json_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type presencePatch struct {
+	Name    string   `json:"name"`
+	Age     int      `json:"age,alias=years"`
+	City    string   `json:"user.address.city,path"`
+	Tags    []string `json:"tags"`
+	present json.Presence
+}
+
+func Test_Presence(t *testing.T) {
+	src := `{"age":0,"user":{"address":{"city":"x"}},"tags":null,"other":1}`
+	for _, stream := range []bool{false, true} {
+		var v presencePatch
+		if stream {
+			assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		} else {
+			assertErr(t, json.Unmarshal([]byte(src), &v))
+		}
+		assertEq(t, "name", false, v.present.Has("name"))
+		assertEq(t, "age", true, v.present.Has("age"))
+		assertEq(t, "tags", true, v.present.Has("tags"))
+		assertEq(t, "user", true, v.present.Has("user"))
+		assertEq(t, "other", false, v.present.Has("other"))
+		if names := v.present.Names(); !reflect.DeepEqual([]string{"age", "tags", "user"}, names) {
+			t.Fatalf("unexpected names: %v", names)
+		}
+	}
+	t.Run("alias", func(t *testing.T) {
+		var v presencePatch
+		assertErr(t, json.Unmarshal([]byte(`{"years":3}`), &v))
+		assertEq(t, "age", true, v.present.Has("age"))
+	})
+	t.Run("replaced", func(t *testing.T) {
+		var v presencePatch
+		assertErr(t, json.Unmarshal([]byte(`{"name":"a"}`), &v))
+		assertErr(t, json.Unmarshal([]byte(`{"age":1}`), &v))
+		assertEq(t, "name", false, v.present.Has("name"))
+		assertEq(t, "names", 1, len(v.present.Names()))
+	})
+	t.Run("zero", func(t *testing.T) {
+		var p json.Presence
+		assertEq(t, "has", false, p.Has("name"))
+	})
+	t.Run("encode", func(t *testing.T) {
+		v := struct {
+			Name    string `json:"name"`
+			Present json.Presence
+		}{Name: "a"}
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"name":"a"}`, string(bytes))
+	})
+}
@@ -0,0 +1,89 @@
+package json
+
+import (
+	"reflect"
+	"runtime"
+)
+
+// isPromotedMethod reports whether the method name of typ, or of the struct it points to,
+// is promoted from an embedded field rather than declared by the struct type.
+// The methods a struct type promotes are wrappers generated by the compiler,
+// and so are those of its pointer type calling the methods of the value.
+func isPromotedMethod(typ reflect.Type, name string) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || !embedsMethod(typ, name) {
+		return false
+	}
+	for _, t := range []reflect.Type{typ, reflect.PtrTo(typ)} {
+		if m, exists := t.MethodByName(name); exists && !isGeneratedMethod(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// embedsMethod reports whether an embedded field of the struct type typ has the method name.
+func embedsMethod(typ reflect.Type, name string) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.Anonymous {
+			continue
+		}
+		if _, exists := field.Type.MethodByName(name); exists {
+			return true
+		}
+		if field.Type.Kind() != reflect.Ptr {
+			if _, exists := reflect.PtrTo(field.Type).MethodByName(name); exists {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isGeneratedMethod(m reflect.Method) bool {
+	fn := runtime.FuncForPC(m.Func.Pointer())
+	if fn == nil {
+		return false
+	}
+	file, _ := fn.FileLine(fn.Entry())
+	return file == "<autogenerated>"
+}
+
+// implementsDeclared reports whether typ implements iface with methods it does not promote.
+func implementsDeclared(typ *rtype, iface reflect.Type) bool {
+	if !typ.Implements(iface) {
+		return false
+	}
+	t := rtype2type(typ)
+	for i := 0; i < iface.NumMethod(); i++ {
+		if isPromotedMethod(t, iface.Method(i).Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// implements reports whether the encoder uses the methods of iface to encode values of typ.
+func (e *Encoder) implements(typ *rtype, iface reflect.Type) bool {
+	if e.ignorePromoted {
+		return implementsDeclared(typ, iface)
+	}
+	return typ.Implements(iface)
+}
+
+// isMarshalerType is like the isMarshalerType function, for the options of the encoder.
+func (e *Encoder) isMarshalerType(typ *rtype) bool {
+	return e.implements(typ, marshalToType) || e.implements(typ, appenderType) ||
+		e.implements(typ, marshalJSONType) || e.implements(typ, marshalTextType)
+}
+
+// implements reports whether the decoder uses the methods of iface to decode values of typ.
+func (d *Decoder) implements(typ *rtype, iface reflect.Type) bool {
+	if d.ignorePromoted {
+		return implementsDeclared(typ, iface)
+	}
+	return typ.Implements(iface)
+}
@@ -0,0 +1,80 @@
+package json_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type promotedMarshaler struct {
+	ID int `json:"id"`
+}
+
+func (promotedMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`"marshaler"`), nil
+}
+
+func (m *promotedMarshaler) UnmarshalJSON(b []byte) error {
+	m.ID = -1
+	return nil
+}
+
+type promotingStruct struct {
+	promotedMarshaler
+	Name string `json:"name"`
+}
+
+type declaringStruct struct {
+	promotedMarshaler
+	Name string `json:"name"`
+}
+
+func (declaringStruct) MarshalJSON() ([]byte, error) {
+	return []byte(`"declared"`), nil
+}
+
+func Test_PromotedMethods(t *testing.T) {
+	v := promotingStruct{promotedMarshaler: promotedMarshaler{ID: 1}, Name: "a"}
+	t.Run("promoted", func(t *testing.T) {
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "promoted", `"marshaler"`, string(bytes))
+		var decoded promotingStruct
+		assertErr(t, json.Unmarshal([]byte(`{"name":"b"}`), &decoded))
+		assertEq(t, "unmarshaler", -1, decoded.ID)
+		assertEq(t, "name", "", decoded.Name)
+	})
+	t.Run("ignored", func(t *testing.T) {
+		encode := func(v interface{}) string {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetIgnorePromotedMarshalers(true)
+			assertErr(t, enc.Encode(v))
+			return strings.TrimSpace(buf.String())
+		}
+		assertEq(t, "fields", `{"promotedMarshaler":"marshaler","name":"a"}`, encode(v))
+		assertEq(t, "declared", `"declared"`, encode(declaringStruct{Name: "a"}))
+
+		var decoded promotingStruct
+		dec := json.NewDecoder(strings.NewReader(`{"name":"b"}`))
+		dec.IgnorePromotedUnmarshalers()
+		assertErr(t, dec.Decode(&decoded))
+		assertEq(t, "name", "b", decoded.Name)
+		assertEq(t, "id", 0, decoded.ID)
+
+		api := json.Config{IgnorePromotedMarshalers: true, IgnorePromotedUnmarshalers: true}.Freeze()
+		bytes, err := api.Marshal(&v)
+		assertErr(t, err)
+		assertEq(t, "api", `{"promotedMarshaler":"marshaler","name":"a"}`, string(bytes))
+		decoded = promotingStruct{}
+		assertErr(t, api.Unmarshal([]byte(`{"name":"c"}`), &decoded))
+		assertEq(t, "api name", "c", decoded.Name)
+	})
+	t.Run("cached apart", func(t *testing.T) {
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "promoted", `"marshaler"`, string(bytes))
+	})
+}
@@ -0,0 +1,154 @@
+package json
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+var (
+	rawMessageType    = type2rtype(reflect.TypeOf(RawMessage(nil)))
+	rawMessageMapType = type2rtype(reflect.TypeOf(map[string]RawMessage(nil)))
+)
+
+// compileRawMessage returns the opcode writing a RawMessage without calling
+// its MarshalJSON method through an interface.
+func (e *Encoder) compileRawMessage(typ *rtype) *opcode {
+	return newOpCode(opRawMessage, typ, e.indent, newEndOp(e.indent))
+}
+
+// encodeRawMessage writes the RawMessage at p as MarshalJSON does.
+func (e *Encoder) encodeRawMessage(p uintptr, indent int) error {
+	if p == 0 {
+		e.encodeNull()
+		return nil
+	}
+	m := *(*RawMessage)(unsafe.Pointer(p))
+	if m == nil {
+		e.encodeNull()
+		return nil
+	}
+	if err := e.encodeMarshaledBytes(m, indent); err != nil {
+		return &MarshalerError{Type: reflect.TypeOf(m), Err: err}
+	}
+	return nil
+}
+
+// rawMessageDecoder stores a copy of the raw value in a RawMessage, as UnmarshalJSON does.
+type rawMessageDecoder struct{}
+
+func newRawMessageDecoder() *rawMessageDecoder {
+	return &rawMessageDecoder{}
+}
+
+func (d *rawMessageDecoder) setDisallowUnknownFields(bool) {}
+
+func (d *rawMessageDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	start := s.cursor
+	s.retainBuffer++
+	err := s.skipValue()
+	s.retainBuffer--
+	if err != nil {
+		return err
+	}
+	raw := s.buf[start:s.cursor]
+	if len(raw) == 0 {
+		return errNotAtBeginningOfValue(s.totalOffset())
+	}
+	if err := s.allocate(int64(len(raw))); err != nil {
+		return err
+	}
+	m := (*RawMessage)(unsafe.Pointer(p))
+	*m = append((*m)[0:0], raw...)
+	return nil
+}
+
+func (d *rawMessageDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	start := cursor
+	end, err := skipValue(buf, cursor)
+	if err != nil {
+		return 0, err
+	}
+	if end == start {
+		return 0, errNotAtBeginningOfValue(start)
+	}
+	m := (*RawMessage)(unsafe.Pointer(p))
+	*m = append((*m)[0:0], buf[start:end]...)
+	return end, nil
+}
+
+// rawMessageMapDecoder decodes a map[string]RawMessage, the shape of many routing
+// and demultiplexing layers. From a buffer, the object is copied once and its values
+// refer to the copy instead of being copied one by one.
+// Streams, null and invalid objects are decoded by the map decoder.
+type rawMessageMapDecoder struct {
+	*mapDecoder
+	keyDecoder *stringDecoder
+}
+
+func newRawMessageMapDecoder(dec *mapDecoder) *rawMessageMapDecoder {
+	return &rawMessageMapDecoder{mapDecoder: dec, keyDecoder: newStringDecoder()}
+}
+
+func (d *rawMessageMapDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] != '{' {
+		return d.mapDecoder.decode(ctx, cursor, p)
+	}
+	end, err := skipValue(buf, cursor)
+	if err != nil {
+		return 0, err
+	}
+	object := make([]byte, end-cursor+1) // nul terminated
+	copy(object, buf[cursor:end])
+	m, ok := d.decodeObject(ctx.withBuf(object))
+	if !ok {
+		// for the error at its offset in buf
+		return d.mapDecoder.decode(ctx, cursor, p)
+	}
+	*(*map[string]RawMessage)(unsafe.Pointer(p)) = m
+	return end, nil
+}
+
+// decodeObject decodes the members of object, reporting false if it is invalid.
+func (d *rawMessageMapDecoder) decodeObject(ctx *runtimeContext) (map[string]RawMessage, bool) {
+	object := ctx.buf
+	m := map[string]RawMessage{}
+	v2 := ctx.v2
+	cursor := skipWhiteSpace(object, 1)
+	if object[cursor] == '}' {
+		return m, true
+	}
+	for {
+		var key string
+		c, err := d.keyDecoder.decode(ctx, cursor, uintptr(unsafe.Pointer(&key)))
+		if err != nil {
+			return nil, false
+		}
+		cursor = skipWhiteSpace(object, c)
+		if object[cursor] != ':' {
+			return nil, false
+		}
+		start := skipWhiteSpace(object, cursor+1)
+		end, err := skipValue(object, start)
+		if err != nil || end == start {
+			return nil, false
+		}
+		if _, exists := m[key]; exists && v2 {
+			return nil, false
+		}
+		m[key] = RawMessage(object[start:end:end])
+		cursor = skipWhiteSpace(object, end)
+		switch object[cursor] {
+		case '}':
+			return m, true
+		case ',':
+			cursor = skipWhiteSpace(object, cursor+1)
+		default:
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package json_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_RawMessageMap(t *testing.T) {
+	src := `{"user": {"id":1,"tags":["a","b"]} , "count":2,"escaped":"x\"y", "none":null}`
+	expected := map[string]string{
+		"user":    `{"id":1,"tags":["a","b"]}`,
+		"count":   `2`,
+		"escaped": `"x\"y"`,
+		"none":    `null`,
+	}
+	check := func(t *testing.T, m map[string]json.RawMessage) {
+		t.Helper()
+		got := map[string]string{}
+		for k, v := range m {
+			got[k] = string(v)
+		}
+		if !reflect.DeepEqual(expected, got) {
+			t.Fatalf("expected %q but got %q", expected, got)
+		}
+	}
+	t.Run("unmarshal", func(t *testing.T) {
+		data := []byte(src)
+		var m map[string]json.RawMessage
+		assertErr(t, json.Unmarshal(data, &m))
+		check(t, m)
+		copy(data, strings.Repeat(" ", len(data)))
+		check(t, m)
+	})
+	t.Run("decoder", func(t *testing.T) {
+		var m map[string]json.RawMessage
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&m))
+		check(t, m)
+	})
+	t.Run("field", func(t *testing.T) {
+		var v struct {
+			Routes map[string]json.RawMessage `json:"routes"`
+			Raw    json.RawMessage            `json:"raw"`
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"routes":`+src+`,"raw":[1, 2]}`), &v))
+		check(t, v.Routes)
+		assertEq(t, "raw", `[1, 2]`, string(v.Raw))
+	})
+	t.Run("empty and null", func(t *testing.T) {
+		var m map[string]json.RawMessage
+		assertErr(t, json.Unmarshal([]byte(` { } `), &m))
+		assertEq(t, "empty", 0, len(m))
+		assertEq(t, "empty is not nil", true, m != nil)
+		assertErr(t, json.Unmarshal([]byte(`null`), &m))
+		assertEq(t, "null keeps the map", 0, len(m))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, src := range []string{`{"a":}`, `{"a" 1}`, `{"a":1,}`, `{"a":1 "b":2}`, `{1:2}`} {
+			var m map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(src), &m); err == nil {
+				t.Fatalf("expected error for %s", src)
+			}
+		}
+	})
+	t.Run("marshal", func(t *testing.T) {
+		m := map[string]json.RawMessage{"a": json.RawMessage(`{"b":[1,2]}`), "n": nil}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		enc.SetSortMapKeys(true)
+		assertErr(t, enc.Encode(m))
+		assertEq(t, "indent", "{\n  \"a\": {\n    \"b\": [\n      1,\n      2\n    ]\n  },\n  \"n\": null\n}", buf.String())
+		var raw *json.RawMessage
+		b, err := json.Marshal(raw)
+		assertErr(t, err)
+		assertEq(t, "nil pointer", `null`, string(b))
+		b, err = json.Marshal(&json.RawMessage{'1'})
+		assertErr(t, err)
+		assertEq(t, "pointer", `1`, string(b))
+	})
+}
@@ -0,0 +1,143 @@
+package json
+
+import (
+	"io"
+	"reflect"
+	"unsafe"
+)
+
+// RawSink receives the raw encoded JSON of a value while it is decoded, so that a huge
+// embedded document can be piped to a file, for example, instead of being held in memory:
+//
+//	var msg struct {
+//		ID   string       `json:"id"`
+//		Blob json.RawSink `json:"blob"`
+//	}
+//	msg.Blob = json.RawSink{Writer: file}
+//	err := json.NewDecoder(r).Decode(&msg)
+//
+// The sink must be set before decoding. A Decoder writes objects, arrays and strings
+// to it in pieces as it reads them, so its buffer does not grow with the value.
+// The bytes are copied as they are, after a scan for the end of the value, which
+// does not validate them. A JSON null is written as null. A RawSink encodes as null.
+type RawSink struct {
+	io.Writer
+}
+
+var rawSinkType = type2rtype(reflect.TypeOf(RawSink{}))
+
+// MarshalJSON returns null, the output of a sink cannot be read back.
+func (RawSink) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+type rawSinkDecoder struct{}
+
+func newRawSinkDecoder() *rawSinkDecoder {
+	return &rawSinkDecoder{}
+}
+
+func (d *rawSinkDecoder) setDisallowUnknownFields(_ bool) {}
+
+func (d *rawSinkDecoder) writer(p uintptr, cursor int64) (io.Writer, error) {
+	w := (*RawSink)(unsafe.Pointer(p)).Writer
+	if w == nil {
+		return nil, &UnmarshalTypeError{
+			Value:  "value",
+			Type:   rtype2type(rawSinkType),
+			Offset: cursor,
+		}
+	}
+	return w, nil
+}
+
+func (d *rawSinkDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	w, err := d.writer(p, s.totalOffset())
+	if err != nil {
+		return err
+	}
+	switch s.char() {
+	case '{', '[', '"':
+		return copyValueStream(s, w)
+	}
+	// scalars are short, so they are kept in the buffer whole
+	start := s.cursor
+	s.retainBuffer++
+	err = s.skipValue()
+	s.retainBuffer--
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(s.buf[start:s.cursor])
+	return err
+}
+
+// copyValueStream writes the object, array or string at the cursor to w.
+// What has been scanned is written out each time more input is read, and dropped
+// from the buffer unless it is retained.
+func copyValueStream(s *stream, w io.Writer) error {
+	start := s.cursor
+	depth := 0
+	inString := false
+	escaped := false
+	for {
+		c := s.char()
+		switch {
+		case c == nul:
+			if _, err := w.Write(s.buf[start:s.cursor]); err != nil {
+				return err
+			}
+			s.reset()
+			if !s.read() {
+				return errUnexpectedEndOfJSON("value", s.totalOffset())
+			}
+			start = s.cursor
+			continue
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				if depth == 0 {
+					s.cursor++
+					_, err := w.Write(s.buf[start:s.cursor])
+					return err
+				}
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				s.cursor++
+				_, err := w.Write(s.buf[start:s.cursor])
+				return err
+			}
+		}
+		s.cursor++
+	}
+}
+
+func (d *rawSinkDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	w, err := d.writer(p, cursor)
+	if err != nil {
+		return 0, err
+	}
+	start := cursor
+	end, err := skipValue(buf, cursor)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(buf[start:end]); err != nil {
+		return 0, err
+	}
+	return end, nil
+}
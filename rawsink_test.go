@@ -0,0 +1,55 @@
+package json_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type rawSinkEnvelope struct {
+	ID   string       `json:"id"`
+	Blob json.RawSink `json:"blob"`
+	N    int          `json:"n"`
+}
+
+func Test_RawSink(t *testing.T) {
+	blob := `{"items":[` + strings.Repeat(`{"s":"a\"}]b","v":[1,2,{}]},`, 2000) + `null]}`
+	src := `{"id":"x", "blob": ` + blob + ` ,"n":3}`
+	t.Run("decoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		v := rawSinkEnvelope{Blob: json.RawSink{Writer: &buf}}
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		assertEq(t, "id", "x", v.ID)
+		assertEq(t, "n", 3, v.N)
+		assertEq(t, "blob", blob, buf.String())
+	})
+	t.Run("unmarshal", func(t *testing.T) {
+		var buf bytes.Buffer
+		v := rawSinkEnvelope{Blob: json.RawSink{Writer: &buf}}
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "n", 3, v.N)
+		assertEq(t, "blob", blob, buf.String())
+	})
+	t.Run("scalars", func(t *testing.T) {
+		for _, raw := range []string{`"a\\\"b"`, `-1.5e3`, `true`, `null`} {
+			var buf bytes.Buffer
+			v := rawSinkEnvelope{Blob: json.RawSink{Writer: &buf}}
+			assertErr(t, json.NewDecoder(strings.NewReader(`{"blob":`+raw+`,"n":1}`)).Decode(&v))
+			assertEq(t, "blob", raw, buf.String())
+			assertEq(t, "n", 1, v.N)
+		}
+	})
+	t.Run("nil sink", func(t *testing.T) {
+		var v rawSinkEnvelope
+		if err := json.NewDecoder(strings.NewReader(src)).Decode(&v); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("marshal", func(t *testing.T) {
+		bytes, err := json.Marshal(rawSinkEnvelope{ID: "x"})
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"id":"x","blob":null,"n":0}`, string(bytes))
+	})
+}
@@ -0,0 +1,96 @@
+package json
+
+import (
+	"errors"
+	"reflect"
+	"unsafe"
+)
+
+// RawView is a raw encoded JSON value like RawMessage, except that decoding it
+// with Unmarshal or UnmarshalNoCopy does not copy the value: the view refers to
+// the input buffer. It suits routers that inspect a field or two of a document
+// and forward the rest of it as is:
+//
+//	var msg struct {
+//		Kind    string       `json:"kind"`
+//		Payload json.RawView `json:"payload"`
+//	}
+//	err := json.UnmarshalNoCopy(body, &msg)
+//
+// A view keeps the whole input buffer alive. A Decoder reuses its buffer, so
+// decoding from an io.Reader copies the value as RawMessage does.
+type RawView []byte
+
+var rawViewType = type2rtype(reflect.TypeOf(RawView(nil)))
+
+// MarshalJSON returns v as the JSON encoding of v.
+func (v RawView) MarshalJSON() ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+	return v, nil
+}
+
+// UnmarshalJSON sets *v to a copy of data.
+func (v *RawView) UnmarshalJSON(data []byte) error {
+	if v == nil {
+		return errors.New("json.RawView: UnmarshalJSON on nil pointer")
+	}
+	*v = append((*v)[0:0], data...)
+	return nil
+}
+
+// UnmarshalNoCopy is like Unmarshal but decodes data in place when it is followed
+// by a nul byte within its capacity, as when data is buf[:len(buf)-1] of a buffer
+// ending with a nul byte; otherwise data is copied once as Unmarshal does.
+// RawView values and decoded strings may then refer to data, so data must not
+// be modified while they are in use.
+func UnmarshalNoCopy(data []byte, v interface{}) error {
+	var src []byte
+	if cap(data) > len(data) && data[:len(data)+1][len(data)] == nul {
+		src = data[:len(data)+1]
+	} else {
+		src = make([]byte, len(data)+1) // append nul byte to end
+		copy(src, data)
+	}
+	var dec Decoder
+	return dec.decodeForUnmarshal(src, v)
+}
+
+// rawViewDecoder stores the raw value in a RawView, referring to the buffer when decoding from one.
+type rawViewDecoder struct{}
+
+func newRawViewDecoder() *rawViewDecoder {
+	return &rawViewDecoder{}
+}
+
+func (d *rawViewDecoder) setDisallowUnknownFields(bool) {}
+
+func (d *rawViewDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	start := s.cursor
+	s.retainBuffer++
+	err := s.skipValue()
+	s.retainBuffer--
+	if err != nil {
+		return err
+	}
+	raw := s.buf[start:s.cursor]
+	if err := s.allocate(int64(len(raw))); err != nil {
+		return err
+	}
+	*(*RawView)(unsafe.Pointer(p)) = append(RawView{}, raw...)
+	return nil
+}
+
+func (d *rawViewDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	start := cursor
+	end, err := skipValue(buf, cursor)
+	if err != nil {
+		return 0, err
+	}
+	*(*RawView)(unsafe.Pointer(p)) = RawView(buf[start:end:end])
+	return end, nil
+}
@@ -0,0 +1,51 @@
+package json_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type rawViewEnvelope struct {
+	Kind    string       `json:"kind"`
+	Payload json.RawView `json:"payload"`
+}
+
+func Test_RawView(t *testing.T) {
+	src := `{"kind":"event", "payload": {"id":1,"tags":["a"]} }`
+	payload := `{"id":1,"tags":["a"]}`
+	t.Run("in place", func(t *testing.T) {
+		buf := append([]byte(src), 0)
+		data := buf[:len(buf)-1]
+		var v rawViewEnvelope
+		assertErr(t, json.UnmarshalNoCopy(data, &v))
+		assertEq(t, "kind", "event", v.Kind)
+		assertEq(t, "payload", payload, string(v.Payload))
+		assertEq(t, "refers to input", &data[strings.Index(src, payload)], &v.Payload[0])
+	})
+	t.Run("copied input", func(t *testing.T) {
+		data := []byte(src)
+		data = data[:len(data):len(data)]
+		var v rawViewEnvelope
+		assertErr(t, json.UnmarshalNoCopy(data, &v))
+		assertEq(t, "payload", payload, string(v.Payload))
+		data[strings.Index(src, payload)+1] = 'x'
+		assertEq(t, "independent of input", payload, string(v.Payload))
+	})
+	t.Run("decoder", func(t *testing.T) {
+		var v rawViewEnvelope
+		assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&v))
+		assertEq(t, "payload", payload, string(v.Payload))
+	})
+	t.Run("marshal", func(t *testing.T) {
+		var v rawViewEnvelope
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "encoded", `{"kind":"event","payload":`+payload+`}`, string(bytes))
+		bytes, err = json.Marshal(rawViewEnvelope{})
+		assertErr(t, err)
+		assertEq(t, "nil view", `{"kind":"","payload":null}`, string(bytes))
+	})
+}
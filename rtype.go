@@ -255,6 +255,10 @@ type interfaceHeader struct {
 	ptr unsafe.Pointer
 }
 
+func ptrTo(t *rtype) *rtype {
+	return type2rtype(reflect.PtrTo(rtype2type(t)))
+}
+
 func type2rtype(t reflect.Type) *rtype {
 	return (*rtype)(((*interfaceHeader)(unsafe.Pointer(&t))).ptr)
 }
@@ -0,0 +1,210 @@
+//go:build go1.21
+// +build go1.21
+
+package json
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// SlogHandler is a slog.Handler writing records as JSON lines with this package's encoder,
+// in the format of slog.JSONHandler. Values of attributes of kind slog.KindAny are
+// encoded like with Marshal, so logged values look like the API payloads containing them.
+type SlogHandler struct {
+	w    io.Writer
+	mu   *sync.Mutex // serializes the writes of the handlers sharing w
+	opts slog.HandlerOptions
+	goas []groupOrAttrs // the groups and attributes added by WithGroup and WithAttrs, in order
+}
+
+// groupOrAttrs is a group opened by WithGroup or the attributes added by WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewSlogHandler returns a handler writing to w with opts, which may be nil for the defaults.
+//
+//	logger := slog.New(json.NewSlogHandler(os.Stdout, nil))
+func NewSlogHandler(w io.Writer, opts *slog.HandlerOptions) *SlogHandler {
+	h := &SlogHandler{w: w, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// Enabled reports whether records of level are handled.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	min := slog.LevelInfo
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return level >= min
+}
+
+// WithAttrs returns a handler adding attrs to every record, in the groups opened so far.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup returns a handler writing the attributes added later in the group name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *SlogHandler) with(goa groupOrAttrs) *SlogHandler {
+	h2 := *h
+	h2.goas = make([]groupOrAttrs, len(h.goas)+1)
+	copy(h2.goas, h.goas)
+	h2.goas[len(h.goas)] = goa
+	return &h2
+}
+
+// Handle writes r as a line holding a JSON object.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	enc := NewEncoder(nil)
+	defer enc.release()
+	enc.SetEscapeHTML(false)
+	enc.encodeByte('{')
+	if !r.Time.IsZero() {
+		h.encodeAttr(enc, nil, slog.Time(slog.TimeKey, r.Time))
+	}
+	h.encodeAttr(enc, nil, slog.Any(slog.LevelKey, r.Level))
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		h.encodeAttr(enc, nil, slog.Any(slog.SourceKey, &slog.Source{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		}))
+	}
+	h.encodeAttr(enc, nil, slog.String(slog.MessageKey, r.Message))
+
+	goas := h.goas
+	if r.NumAttrs() == 0 {
+		// groups without attributes are omitted
+		for len(goas) > 0 && goas[len(goas)-1].group != "" {
+			goas = goas[:len(goas)-1]
+		}
+	}
+	var groups []string
+	for _, goa := range goas {
+		if goa.group != "" {
+			enc.encodeSlogKey(goa.group)
+			enc.encodeByte('{')
+			groups = append(groups, goa.group)
+			continue
+		}
+		for _, a := range goa.attrs {
+			h.encodeAttr(enc, groups, a)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.encodeAttr(enc, groups, a)
+		return true
+	})
+	for range groups {
+		enc.encodeByte('}')
+	}
+	enc.encodeBytes([]byte("}\n"))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(enc.buf)
+	return err
+}
+
+// encodeAttr writes a as a member of the object being written, in groups.
+func (h *SlogHandler) encodeAttr(enc *Encoder, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		enc.encodeSlogKey(a.Key)
+		enc.encodeSlogValue(a.Value)
+		return
+	}
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+	if a.Key == "" {
+		// the attributes of a group without a key are inlined
+		for _, ga := range attrs {
+			h.encodeAttr(enc, groups, ga)
+		}
+		return
+	}
+	enc.encodeSlogKey(a.Key)
+	enc.encodeByte('{')
+	groups = append(groups, a.Key)
+	for _, ga := range attrs {
+		h.encodeAttr(enc, groups, ga)
+	}
+	enc.encodeByte('}')
+}
+
+func (e *Encoder) encodeSlogKey(key string) {
+	if e.buf[len(e.buf)-1] != '{' {
+		e.encodeByte(',')
+	}
+	e.encodeString(key)
+	e.encodeByte(':')
+}
+
+// encodeSlogValue writes v as slog.JSONHandler does, except that values of kind
+// slog.KindAny are encoded with this package. An error encoding a value is written
+// in a string instead, following "!ERROR:".
+func (e *Encoder) encodeSlogValue(v slog.Value) {
+	switch v.Kind() {
+	case slog.KindString:
+		e.encodeString(v.String())
+	case slog.KindInt64:
+		e.buf = strconv.AppendInt(e.buf, v.Int64(), 10)
+	case slog.KindUint64:
+		e.buf = strconv.AppendUint(e.buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		e.encodeSlogAny(v.Float64())
+	case slog.KindBool:
+		e.encodeBool(v.Bool())
+	case slog.KindDuration:
+		e.buf = strconv.AppendInt(e.buf, int64(v.Duration()), 10)
+	case slog.KindTime:
+		e.encodeSlogAny(v.Time())
+	default:
+		e.encodeSlogAny(v.Any())
+	}
+}
+
+func (e *Encoder) encodeSlogAny(v interface{}) {
+	if err, ok := v.(error); ok {
+		if _, marshaler := v.(Marshaler); !marshaler {
+			e.encodeString(err.Error())
+			return
+		}
+	}
+	start := len(e.buf)
+	if err := e.encode(v); err != nil {
+		e.buf = e.buf[:start]
+		e.encodeString("!ERROR:" + err.Error())
+	}
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
@@ -0,0 +1,86 @@
+//go:build go1.21
+// +build go1.21
+
+package json_test
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_SlogHandler(t *testing.T) {
+	t.Run("slogtest", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := json.NewSlogHandler(&buf, nil)
+		results := func() []map[string]interface{} {
+			var ms []map[string]interface{}
+			for _, line := range bytes.Split(buf.Bytes(), []byte{'\n'}) {
+				if len(line) == 0 {
+					continue
+				}
+				var m map[string]interface{}
+				if err := stdjson.Unmarshal(line, &m); err != nil {
+					t.Fatalf("%s: %v", line, err)
+				}
+				ms = append(ms, m)
+			}
+			return ms
+		}
+		if err := slogtest.TestHandler(h, results); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("same output as slog.JSONHandler", func(t *testing.T) {
+		type payload struct {
+			ID   int      `json:"id"`
+			Tags []string `json:"tags,omitempty"`
+		}
+		log := func(h slog.Handler) {
+			logger := slog.New(h).With("service", "api").WithGroup("req")
+			logger.Info("handled <request>",
+				"path", "/users",
+				"status", 200,
+				"size", uint64(512),
+				"ratio", 0.5,
+				"cached", false,
+				"took", 1500*time.Millisecond,
+				"at", time.Date(2020, 1, 2, 3, 4, 5, 678901234, time.UTC),
+				"err", errors.New("partial"),
+				"user", payload{ID: 1},
+				slog.Group("db", "rows", 3),
+			)
+		}
+		replace := func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		}
+		var got, expected bytes.Buffer
+		log(json.NewSlogHandler(&got, &slog.HandlerOptions{ReplaceAttr: replace}))
+		log(slog.NewJSONHandler(&expected, &slog.HandlerOptions{ReplaceAttr: replace}))
+		assertEq(t, "output", expected.String(), got.String())
+	})
+	t.Run("level and unsupported value", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(json.NewSlogHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		logger.Info("dropped")
+		logger.Warn("kept", "nan", math.NaN())
+		out := buf.String()
+		if strings.Contains(out, "dropped") {
+			t.Fatalf("record below the level was written: %s", out)
+		}
+		if !strings.Contains(out, `"level":"WARN","msg":"kept","nan":"!ERROR:`) {
+			t.Fatalf("unexpected output: %s", out)
+		}
+	})
+}
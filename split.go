@@ -0,0 +1,189 @@
+package json
+
+import (
+	"bufio"
+	"io"
+)
+
+// recordSeparator delimits JSON texts in a JSON text sequence (RFC 7464).
+const recordSeparator = 0x1E
+
+// StreamSplitter reads a stream of concatenated top-level JSON values
+// and yields each one as a RawMessage without decoding it.
+// Values may be separated by whitespace, newlines or record separators (0x1E).
+//
+// A typical use fans the raw values out to worker goroutines:
+//
+//	s := json.SplitStream(r)
+//	for s.Next() {
+//		jobs <- s.Raw()
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type StreamSplitter struct {
+	r      *bufio.Reader
+	raw    RawMessage
+	err    error
+	offset int64
+}
+
+// SplitStream returns a StreamSplitter that reads from r.
+func SplitStream(r io.Reader) *StreamSplitter {
+	return &StreamSplitter{r: bufio.NewReader(r)}
+}
+
+// Next advances to the next top-level value, which will then be available
+// through Raw. It returns false when the stream ends or an error occurs.
+func (s *StreamSplitter) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	s.raw = nil
+	c, err := s.skipDelimiters()
+	if err != nil {
+		if err != io.EOF {
+			s.err = err
+		}
+		return false
+	}
+	buf := []byte{c}
+	switch c {
+	case '{', '[':
+		buf, err = s.readComposite(buf)
+	case '"':
+		buf, err = s.readString(buf)
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 't', 'f', 'n':
+		buf, err = s.readScalar(buf)
+	default:
+		err = errInvalidCharacter(c, "beginning of value", s.offset-1)
+	}
+	if err != nil {
+		s.err = err
+		return false
+	}
+	s.raw = buf
+	return true
+}
+
+// Raw returns the most recent value read by Next.
+// The returned slice is not reused by later calls to Next,
+// so it is safe to hand to another goroutine.
+func (s *StreamSplitter) Raw() RawMessage {
+	return s.raw
+}
+
+// Err returns the first error encountered, or nil if the stream ended cleanly.
+func (s *StreamSplitter) Err() error {
+	return s.err
+}
+
+func (s *StreamSplitter) readByte() (byte, error) {
+	c, err := s.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	s.offset++
+	return c, nil
+}
+
+func (s *StreamSplitter) unreadByte() {
+	s.r.UnreadByte()
+	s.offset--
+}
+
+func (s *StreamSplitter) skipDelimiters() (byte, error) {
+	for {
+		c, err := s.readByte()
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case ' ', '\n', '\t', '\r', recordSeparator:
+			continue
+		}
+		return c, nil
+	}
+}
+
+func (s *StreamSplitter) readString(buf []byte) ([]byte, error) {
+	for {
+		c, err := s.readByte()
+		if err != nil {
+			return nil, s.unexpectedEnd("string", err)
+		}
+		buf = append(buf, c)
+		switch c {
+		case '\\':
+			c, err := s.readByte()
+			if err != nil {
+				return nil, s.unexpectedEnd("string", err)
+			}
+			buf = append(buf, c)
+		case '"':
+			return buf, nil
+		}
+	}
+}
+
+func (s *StreamSplitter) readScalar(buf []byte) ([]byte, error) {
+	for {
+		c, err := s.readByte()
+		if err == io.EOF {
+			return buf, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case '0' <= c && c <= '9', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', c == '-', c == '+', c == '.':
+			buf = append(buf, c)
+		default:
+			s.unreadByte()
+			return buf, nil
+		}
+	}
+}
+
+func (s *StreamSplitter) readComposite(buf []byte) ([]byte, error) {
+	closers := []byte{closerOf(buf[0])}
+	for len(closers) > 0 {
+		c, err := s.readByte()
+		if err != nil {
+			return nil, s.unexpectedEnd("value", err)
+		}
+		switch c {
+		case '{', '[':
+			buf = append(buf, c)
+			closers = append(closers, closerOf(c))
+		case '}', ']':
+			if closers[len(closers)-1] != c {
+				return nil, errInvalidCharacter(c, "end of value", s.offset-1)
+			}
+			buf = append(buf, c)
+			closers = closers[:len(closers)-1]
+		case '"':
+			buf, err = s.readString(append(buf, c))
+			if err != nil {
+				return nil, err
+			}
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf, nil
+}
+
+func (s *StreamSplitter) unexpectedEnd(context string, err error) error {
+	if err == io.EOF {
+		return errUnexpectedEndOfJSON(context, s.offset)
+	}
+	return err
+}
+
+func closerOf(c byte) byte {
+	if c == '{' {
+		return '}'
+	}
+	return ']'
+}
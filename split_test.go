@@ -0,0 +1,45 @@
+package json_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_SplitStream(t *testing.T) {
+	t.Run("delimiters", func(t *testing.T) {
+		src := "{\"a\":[1,{\"b\":\"}\"}]}\n[1,2] \"x\\\"y\"\x1e123\x1etrue\r\nnull{}"
+		s := json.SplitStream(strings.NewReader(src))
+		var values []string
+		for s.Next() {
+			values = append(values, string(s.Raw()))
+		}
+		assertErr(t, s.Err())
+		exp := []string{`{"a":[1,{"b":"}"}]}`, `[1,2]`, `"x\"y"`, `123`, `true`, `null`, `{}`}
+		assertEq(t, "count", len(exp), len(values))
+		for i := range exp {
+			assertEq(t, "value", exp[i], values[i])
+		}
+	})
+	t.Run("empty", func(t *testing.T) {
+		s := json.SplitStream(strings.NewReader(" \n\x1e"))
+		assertEq(t, "next", false, s.Next())
+		assertErr(t, s.Err())
+	})
+	t.Run("truncated", func(t *testing.T) {
+		s := json.SplitStream(strings.NewReader(`{"a":1} {"b":`))
+		assertEq(t, "first", true, s.Next())
+		assertEq(t, "second", false, s.Next())
+		if s.Err() == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("mismatched", func(t *testing.T) {
+		s := json.SplitStream(strings.NewReader(`[1}`))
+		assertEq(t, "next", false, s.Next())
+		if s.Err() == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
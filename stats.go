@@ -0,0 +1,96 @@
+package json
+
+import (
+	"sync/atomic"
+)
+
+// CacheStats holds counters of the codec caches and pools of the package.
+// The counters are cumulative over the time they are collected, enabled by SetStatsEnabled.
+type CacheStats struct {
+	// EncoderCacheSize and DecoderCacheSize count the types whose codec was cached.
+	// A steady increase points to types created at run time.
+	EncoderCacheSize int64
+	DecoderCacheSize int64
+
+	// EncoderCompiles and DecoderCompiles count the compilations of codecs for top-level types.
+	// They exceed the cache sizes when several goroutines compile the same type at once.
+	EncoderCompiles int64
+	DecoderCompiles int64
+
+	// EncoderPoolGets counts the Encoders taken from the pool by NewEncoder, Marshal and
+	// their variants, EncoderPoolMisses those of them that had to be allocated.
+	EncoderPoolGets   int64
+	EncoderPoolMisses int64
+
+	// OpcodePoolGets counts the copies of compiled encoders taken from their pools,
+	// OpcodePoolMisses those of them that had to be copied anew.
+	OpcodePoolGets   int64
+	OpcodePoolMisses int64
+
+	// ReleasedBufferBytes is the total capacity of the buffers of the Encoders put back
+	// into the pool. Divided by EncoderPoolGets, it is the average size of the buffers
+	// the pool retains, so a steady increase points to a few large values inflating them.
+	ReleasedBufferBytes int64
+}
+
+// EncoderPoolHitRate returns the fraction of EncoderPoolGets served by a pooled Encoder.
+func (s CacheStats) EncoderPoolHitRate() float64 {
+	return hitRate(s.EncoderPoolGets, s.EncoderPoolMisses)
+}
+
+// OpcodePoolHitRate returns the fraction of OpcodePoolGets served by a pooled copy.
+func (s CacheStats) OpcodePoolHitRate() float64 {
+	return hitRate(s.OpcodePoolGets, s.OpcodePoolMisses)
+}
+
+func hitRate(gets, misses int64) float64 {
+	if gets == 0 {
+		return 0
+	}
+	return float64(gets-misses) / float64(gets)
+}
+
+// stats is updated atomically while statsEnabled is non-zero. All of its fields are int64,
+// so as a global they are 64-bit aligned on 32-bit platforms too.
+var (
+	stats        CacheStats
+	statsEnabled int32
+)
+
+// SetStatsEnabled sets whether the counters returned by Stats are collected.
+// They are not by default, to keep the shared counters out of the encoding and decoding
+// of every value. The counters keep their values while they are not collected.
+func SetStatsEnabled(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&statsEnabled, v)
+}
+
+// Stats returns a snapshot of the cache and pool counters.
+// The counters are read one by one, so they may be slightly inconsistent with each other
+// while other goroutines encode or decode.
+func Stats() CacheStats {
+	return CacheStats{
+		EncoderCacheSize:    atomic.LoadInt64(&stats.EncoderCacheSize),
+		DecoderCacheSize:    atomic.LoadInt64(&stats.DecoderCacheSize),
+		EncoderCompiles:     atomic.LoadInt64(&stats.EncoderCompiles),
+		DecoderCompiles:     atomic.LoadInt64(&stats.DecoderCompiles),
+		EncoderPoolGets:     atomic.LoadInt64(&stats.EncoderPoolGets),
+		EncoderPoolMisses:   atomic.LoadInt64(&stats.EncoderPoolMisses),
+		OpcodePoolGets:      atomic.LoadInt64(&stats.OpcodePoolGets),
+		OpcodePoolMisses:    atomic.LoadInt64(&stats.OpcodePoolMisses),
+		ReleasedBufferBytes: atomic.LoadInt64(&stats.ReleasedBufferBytes),
+	}
+}
+
+func countStat(counter *int64) {
+	addStat(counter, 1)
+}
+
+func addStat(counter *int64, n int64) {
+	if atomic.LoadInt32(&statsEnabled) != 0 {
+		atomic.AddInt64(counter, n)
+	}
+}
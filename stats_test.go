@@ -0,0 +1,72 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_Stats(t *testing.T) {
+	type statsT struct {
+		A int `json:"a"`
+	}
+	json.SetStatsEnabled(true)
+	defer json.SetStatsEnabled(false)
+	t.Run("encoder", func(t *testing.T) {
+		before := json.Stats()
+		_, err := json.Marshal(statsT{A: 1})
+		assertErr(t, err)
+		compiled := json.Stats()
+		assertEq(t, "cache size", before.EncoderCacheSize+1, compiled.EncoderCacheSize)
+		assertEq(t, "compiles", before.EncoderCompiles+1, compiled.EncoderCompiles)
+		assertEq(t, "encoder pool gets", before.EncoderPoolGets+1, compiled.EncoderPoolGets)
+		_, err = json.Marshal(statsT{A: 2})
+		assertErr(t, err)
+		cached := json.Stats()
+		assertEq(t, "cache size", compiled.EncoderCacheSize, cached.EncoderCacheSize)
+		assertEq(t, "compiles", compiled.EncoderCompiles, cached.EncoderCompiles)
+		assertEq(t, "opcode pool gets", compiled.OpcodePoolGets+1, cached.OpcodePoolGets)
+	})
+	t.Run("decoder", func(t *testing.T) {
+		before := json.Stats()
+		var v statsT
+		assertErr(t, json.Unmarshal([]byte(`{"a":1}`), &v))
+		compiled := json.Stats()
+		assertEq(t, "cache size", before.DecoderCacheSize+1, compiled.DecoderCacheSize)
+		assertEq(t, "compiles", before.DecoderCompiles+1, compiled.DecoderCompiles)
+		assertErr(t, json.Unmarshal([]byte(`{"a":2}`), &v))
+		cached := json.Stats()
+		assertEq(t, "cache size", compiled.DecoderCacheSize, cached.DecoderCacheSize)
+		assertEq(t, "compiles", compiled.DecoderCompiles, cached.DecoderCompiles)
+	})
+	t.Run("pools", func(t *testing.T) {
+		before := json.Stats()
+		_, err := json.Marshal(statsT{A: 3})
+		assertErr(t, err)
+		stats := json.Stats()
+		if stats.ReleasedBufferBytes <= before.ReleasedBufferBytes {
+			t.Fatalf("expected released buffer bytes to grow from %d but got %d", before.ReleasedBufferBytes, stats.ReleasedBufferBytes)
+		}
+		for name, rate := range map[string]float64{
+			"encoder": stats.EncoderPoolHitRate(),
+			"opcode":  stats.OpcodePoolHitRate(),
+		} {
+			if rate < 0 || rate > 1 {
+				t.Fatalf("%s pool hit rate %f out of range", name, rate)
+			}
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		json.SetStatsEnabled(false)
+		defer json.SetStatsEnabled(true)
+		type disabledT struct {
+			B int `json:"b"`
+		}
+		before := json.Stats()
+		_, err := json.Marshal(disabledT{B: 1})
+		assertErr(t, err)
+		var v disabledT
+		assertErr(t, json.Unmarshal([]byte(`{"b":1}`), &v))
+		assertEq(t, "unchanged", before, json.Stats())
+	})
+}
@@ -0,0 +1,80 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// isUnsupportedType reports whether values of typ cannot be encoded by their kind and
+// may fall back to their String method: complex numbers, functions other than iterators,
+// ArrayFunc and ObjectFunc, and channels that cannot be received from.
+func isUnsupportedType(typ *rtype) bool {
+	switch typ.Kind() {
+	case reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Func:
+		t := rtype2type(typ)
+		return t != arrayFuncType && t != objectFuncType && iterArity(t) == 0
+	case reflect.Chan:
+		return typ.ChanDir()&reflect.RecvDir == 0
+	}
+	return false
+}
+
+// stringerWellKnownType returns how values of typ are encoded as the string of their
+// String method with SetStringers, or nil if typ is supported or has no String method.
+// The method is called on the address of the value, so that of the pointer type is used as well.
+func stringerWellKnownType(typ *rtype) *wellKnownType {
+	if !isUnsupportedType(typ) || !ptrTo(typ).Implements(stringerType) {
+		return nil
+	}
+	t := rtype2type(typ)
+	return &wellKnownType{
+		appendText: func(b []byte, p unsafe.Pointer) ([]byte, error) {
+			return append(b, reflect.NewAt(t, p).Interface().(fmt.Stringer).String()...), nil
+		},
+		sourceFunc: "String",
+	}
+}
+
+// encodeStringerValue encodes v with its String method if the encoder falls back to it
+// for v, which is a function or a channel, and reports whether it did.
+func (e *Encoder) encodeStringerValue(v reflect.Value) bool {
+	if !e.stringers || !isUnsupportedType(type2rtype(v.Type())) {
+		return false
+	}
+	s, ok := v.Interface().(fmt.Stringer)
+	if !ok {
+		return false
+	}
+	e.encodeString(s.String())
+	return true
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// errorMessageConverter encodes error values as their message with SetErrorMessages.
+// nil errors, including nil pointers, encode as null.
+var errorMessageConverter = &Converter{
+	Encode: func(v interface{}) (interface{}, error) {
+		if v == nil {
+			return nil, nil
+		}
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, nil
+		}
+		return v.(error).Error(), nil
+	},
+}
+
+// errorMessageConverterOf returns the converter of typ with SetErrorMessages,
+// or nil if typ is not an interface type including the error interface.
+func (e *Encoder) errorMessageConverterOf(typ *rtype) *Converter {
+	if !e.errorMessages || typ.Kind() != reflect.Interface || !typ.Implements(errorType) {
+		return nil
+	}
+	return errorMessageConverter
+}
@@ -0,0 +1,114 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type stringerFunc func()
+
+func (stringerFunc) String() string { return "func" }
+
+type stringerComplex complex128
+
+func (c *stringerComplex) String() string { return fmt.Sprint(complex128(*c)) }
+
+type stringerChan chan<- int
+
+func (stringerChan) String() string { return `chan "out"` }
+
+type stringerInt int
+
+func (stringerInt) String() string { return "int" }
+
+type stringerRecord struct {
+	Func    stringerFunc    `json:"func"`
+	Complex stringerComplex `json:"complex"`
+	Chan    stringerChan    `json:"chan"`
+	Int     stringerInt     `json:"int"`
+}
+
+func Test_Stringers(t *testing.T) {
+	encode := func(v interface{}, on bool) (string, error) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetStringers(on)
+		err := enc.Encode(v)
+		return strings.TrimSpace(buf.String()), err
+	}
+	v := stringerRecord{Complex: 1 + 2i, Int: 3}
+	if _, err := encode(v, false); err == nil {
+		t.Fatal("expected UnsupportedTypeError")
+	}
+	got, err := encode(v, true)
+	assertErr(t, err)
+	assertEq(t, "fields", `{"func":"func","complex":"(1+2i)","chan":"chan \"out\"","int":3}`, got)
+	got, err = encode([]interface{}{stringerFunc(nil), stringerChan(nil), &v.Complex}, true)
+	assertErr(t, err)
+	assertEq(t, "interfaces", `["func","chan \"out\"","(1+2i)"]`, got)
+	got, err = encode(stringerFunc(nil), true)
+	assertErr(t, err)
+	assertEq(t, "top-level", `"func"`, got)
+	if _, err := encode(func() {}, true); err == nil {
+		t.Fatal("expected UnsupportedTypeError for a func without String")
+	}
+
+	api := json.Config{Stringers: true}.Freeze()
+	bytes, err := api.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "api", `{"func":"func","complex":"(1+2i)","chan":"chan \"out\"","int":3}`, string(bytes))
+	if _, err := json.Marshal(v); err == nil {
+		t.Fatal("expected UnsupportedTypeError from the default encoder")
+	}
+}
+
+type codedError interface {
+	error
+	Code() int
+}
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string { return fmt.Sprintf("status %d", e.code) }
+func (e *statusError) Code() int     { return e.code }
+
+type errorResult struct {
+	Err    error      `json:"err"`
+	Nil    error      `json:"nil"`
+	Omit   error      `json:"omit,omitempty"`
+	Coded  codedError `json:"coded"`
+	Errors []error    `json:"errors"`
+}
+
+func Test_ErrorMessages(t *testing.T) {
+	v := errorResult{
+		Err:    errors.New("failed"),
+		Coded:  &statusError{code: 404},
+		Errors: []error{errors.New("a"), nil, (*statusError)(nil)},
+	}
+	encode := func(v interface{}, on bool) string {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetErrorMessages(on)
+		assertErr(t, enc.Encode(v))
+		return strings.TrimSpace(buf.String())
+	}
+	assertEq(t, "disabled", `{"err":{},"nil":null,"coded":{},"errors":[{},null,null]}`, encode(v, false))
+	expected := `{"err":"failed","nil":null,"coded":"status 404","errors":["a",null,null]}`
+	assertEq(t, "messages", expected, encode(v, true))
+	var err error = &statusError{code: 500}
+	assertEq(t, "pointer", `"status 500"`, encode(&err, true))
+	assertEq(t, "map", `{"k":"failed"}`, encode(map[string]error{"k": v.Err}, true))
+
+	api := json.Config{ErrorMessages: true}.Freeze()
+	bytes, err := api.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "api", expected, string(bytes))
+}
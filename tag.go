@@ -0,0 +1,40 @@
+package json
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+var fallbackTagKeysValue atomic.Value
+
+// SetFallbackTagKeys makes the encoder and decoder read the tag under the first of keys
+// present on a struct field that has no json tag, so structs already tagged for
+// another package can be used without retagging them:
+//
+//	json.SetFallbackTagKeys("yaml", "mapstructure")
+//
+// A field tagged `yaml:"name,omitempty"` is then handled like one tagged `json:"name,omitempty"`;
+// the options of a fallback tag are read as json options, so options json does not know are ignored.
+// Like converters, fallback keys must be set before the first encoding or decoding of a type
+// using them, typically from an init function. SetFallbackTagKeys() removes them.
+func SetFallbackTagKeys(keys ...string) {
+	fallbackTagKeysValue.Store(append([]string{}, keys...))
+}
+
+func fallbackTagKeys() []string {
+	keys, _ := fallbackTagKeysValue.Load().([]string)
+	return keys
+}
+
+// structTag returns the json tag of field, or its first fallback tag when it has none.
+func structTag(field reflect.StructField) string {
+	if tag, exists := field.Tag.Lookup("json"); exists {
+		return tag
+	}
+	for _, key := range fallbackTagKeys() {
+		if tag, exists := field.Tag.Lookup(key); exists {
+			return tag
+		}
+	}
+	return ""
+}
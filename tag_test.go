@@ -0,0 +1,28 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type fallbackTagged struct {
+	Name    string `yaml:"name"`
+	Port    int    `mapstructure:"port,omitempty"`
+	Both    string `json:"json_name" yaml:"yaml_name"`
+	Ignored string `yaml:"-"`
+	Plain   string
+}
+
+func Test_FallbackTagKeys(t *testing.T) {
+	json.SetFallbackTagKeys("yaml", "mapstructure")
+	defer json.SetFallbackTagKeys()
+
+	bytes, err := json.Marshal(fallbackTagged{Name: "a", Both: "b", Ignored: "c", Plain: "d"})
+	assertErr(t, err)
+	assertEq(t, "encoded", `{"name":"a","json_name":"b","Plain":"d"}`, string(bytes))
+
+	var v fallbackTagged
+	assertErr(t, json.Unmarshal([]byte(`{"name":"a","port":80,"json_name":"b","yaml_name":"x","Ignored":"c","Plain":"d"}`), &v))
+	assertEq(t, "decoded", fallbackTagged{Name: "a", Port: 80, Both: "b", Plain: "d"}, v)
+}
@@ -0,0 +1,65 @@
+package json
+
+import (
+	"context"
+	"reflect"
+)
+
+// Tracer records encoding and decoding operations, for example as spans of a distributed trace.
+// A Tracer is attached to a context with WithTracer and used by MarshalContext, UnmarshalContext,
+// Encoder.EncodeContext and Decoder.DecodeContext. An OpenTelemetry adapter can start a span
+// only when ctx already carries a recording one:
+//
+//	func (otelTracer) Start(ctx context.Context, op json.Operation, typ reflect.Type) func(json.OperationStats) {
+//		if !trace.SpanFromContext(ctx).IsRecording() {
+//			return nil
+//		}
+//		_, span := otel.Tracer("json").Start(ctx, "json."+op.String())
+//		return func(stats json.OperationStats) {
+//			span.SetAttributes(attribute.String("json.type", fmt.Sprint(stats.Type)), attribute.Int64("json.bytes", stats.Bytes))
+//			if stats.Err != nil {
+//				span.RecordError(stats.Err)
+//			}
+//			span.End()
+//		}
+//	}
+type Tracer interface {
+	// Start is called before a top-level value of type typ is encoded or decoded.
+	// The returned function, if not nil, is called with the stats of the operation once it ends.
+	Start(ctx context.Context, op Operation, typ reflect.Type) func(OperationStats)
+}
+
+type tracerKey struct{}
+
+// WithTracer returns a copy of ctx that makes the context-aware functions of this package report to t.
+func WithTracer(ctx context.Context, t Tracer) context.Context {
+	return context.WithValue(ctx, tracerKey{}, t)
+}
+
+func tracerFromContext(ctx context.Context) Tracer {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(tracerKey{}).(Tracer)
+	return t
+}
+
+// startOperation returns the function to call with the stats of an operation
+// for the metrics hook and the tracer of ctx, or nil if nothing observes it.
+func startOperation(ctx context.Context, op Operation, typ reflect.Type) func(OperationStats) {
+	hook := metricsHook()
+	var end func(OperationStats)
+	if t := tracerFromContext(ctx); t != nil {
+		end = t.Start(ctx, op, typ)
+	}
+	if end == nil {
+		return hook
+	}
+	if hook == nil {
+		return end
+	}
+	return func(stats OperationStats) {
+		end(stats)
+		hook(stats)
+	}
+}
@@ -0,0 +1,81 @@
+package json_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type recordedSpan struct {
+	name  string
+	stats json.OperationStats
+	ended bool
+}
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, op json.Operation, typ reflect.Type) func(json.OperationStats) {
+	span := &recordedSpan{name: "json." + op.String()}
+	t.spans = append(t.spans, span)
+	return func(stats json.OperationStats) {
+		span.stats = stats
+		span.ended = true
+	}
+}
+
+func Test_Tracer(t *testing.T) {
+	type tracedT struct {
+		A int `json:"a"`
+	}
+	t.Run("marshal", func(t *testing.T) {
+		var tracer recordingTracer
+		ctx := json.WithTracer(context.Background(), &tracer)
+		bytes, err := json.MarshalContext(ctx, tracedT{A: 1})
+		assertErr(t, err)
+		assertEq(t, "spans", 1, len(tracer.spans))
+		span := tracer.spans[0]
+		assertEq(t, "name", "json.encode", span.name)
+		assertEq(t, "ended", true, span.ended)
+		assertEq(t, "type", reflect.TypeOf(tracedT{}), span.stats.Type)
+		assertEq(t, "bytes", int64(len(bytes)), span.stats.Bytes)
+	})
+	t.Run("unmarshal", func(t *testing.T) {
+		var tracer recordingTracer
+		ctx := json.WithTracer(context.Background(), &tracer)
+		var v tracedT
+		assertErr(t, json.UnmarshalContext(ctx, []byte(`{"a":1}`), &v))
+		assertEq(t, "spans", 1, len(tracer.spans))
+		span := tracer.spans[0]
+		assertEq(t, "name", "json.decode", span.name)
+		assertEq(t, "type", reflect.TypeOf(&v), span.stats.Type)
+		assertEq(t, "bytes", int64(len(`{"a":1}`)), span.stats.Bytes)
+	})
+	t.Run("error", func(t *testing.T) {
+		var tracer recordingTracer
+		ctx := json.WithTracer(context.Background(), &tracer)
+		var v tracedT
+		err := json.UnmarshalContext(ctx, []byte(`{"a":"x"}`), &v)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		assertEq(t, "error", err, tracer.spans[0].stats.Err)
+	})
+	t.Run("encoder", func(t *testing.T) {
+		var tracer recordingTracer
+		ctx := json.WithTracer(context.Background(), &tracer)
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		assertErr(t, enc.EncodeContext(ctx, 1))
+		assertErr(t, enc.Encode(2))
+		assertEq(t, "spans", 1, len(tracer.spans))
+	})
+	t.Run("without tracer", func(t *testing.T) {
+		_, err := json.MarshalContext(context.Background(), tracedT{})
+		assertErr(t, err)
+	})
+}
@@ -0,0 +1,174 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// TypeResolver supplies the concrete type to allocate when a Decoder decodes
+// a value into an interface type, for designs where the implementations are
+// chosen by the caller rather than registered globally.
+//
+// ResolveType receives the struct field being decoded, or nil for an element,
+// map value or top-level value, the interface type and the raw JSON value,
+// which is never null. The returned type must implement the interface;
+// a pointer type decodes into a new value it points to. A nil type falls back
+// to the default decoding of the interface.
+type TypeResolver interface {
+	ResolveType(field *reflect.StructField, iface reflect.Type, raw []byte) (reflect.Type, error)
+}
+
+// TypeResolverFunc is an adapter to use an ordinary function as a TypeResolver.
+type TypeResolverFunc func(field *reflect.StructField, iface reflect.Type, raw []byte) (reflect.Type, error)
+
+// ResolveType calls f(field, iface, raw).
+func (f TypeResolverFunc) ResolveType(field *reflect.StructField, iface reflect.Type, raw []byte) (reflect.Type, error) {
+	return f(field, iface, raw)
+}
+
+type resolverField struct {
+	structType reflect.Type
+	name       string
+}
+
+// SetTypeResolver makes the Decoder decode the values of the interface type iface points to
+// into the types returned by r, wherever they appear in the decoded value:
+//
+//	dec.SetTypeResolver((*Storage)(nil), resolver)
+//
+// Unlike RegisterUnionResolver, the resolver only applies to this Decoder and takes
+// precedence over any union registered for the interface. SetTypeResolver(iface, nil)
+// removes the resolver. SetTypeResolver panics if iface is not a pointer to an interface type.
+func (d *Decoder) SetTypeResolver(iface interface{}, r TypeResolver) {
+	typ := unionInterfaceType("SetTypeResolver", iface)
+	if r == nil {
+		delete(d.s.typeResolvers, typ)
+		return
+	}
+	if d.s.typeResolvers == nil {
+		d.s.typeResolvers = map[reflect.Type]TypeResolver{}
+	}
+	d.s.typeResolvers[typ] = r
+}
+
+// SetFieldTypeResolver makes the Decoder decode the interface-typed field with the Go name field
+// of the struct type of structValue into the types returned by r, taking precedence over
+// a resolver set by SetTypeResolver for the interface:
+//
+//	dec.SetFieldTypeResolver(Config{}, "Backup", resolver)
+//
+// SetFieldTypeResolver(structValue, field, nil) removes the resolver. SetFieldTypeResolver panics
+// if structValue is not a struct or a pointer to one, or the struct has no interface-typed field named field.
+func (d *Decoder) SetFieldTypeResolver(structValue interface{}, field string, r TypeResolver) {
+	typ := reflect.TypeOf(structValue)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("json: SetFieldTypeResolver of non-struct type %v", typ))
+	}
+	if f, exists := typ.FieldByName(field); !exists || f.Type.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("json: SetFieldTypeResolver of %v has no interface field %s", typ, field))
+	}
+	key := resolverField{structType: typ, name: field}
+	if r == nil {
+		delete(d.s.fieldTypeResolvers, key)
+		return
+	}
+	if d.s.fieldTypeResolvers == nil {
+		d.s.fieldTypeResolvers = map[resolverField]TypeResolver{}
+	}
+	d.s.fieldTypeResolvers[key] = r
+}
+
+// typeResolverDecoder decodes an interface value into the type picked by the resolver
+// set on the Decoder, and with dec when none is set.
+type typeResolverDecoder struct {
+	dec                   decoder
+	typ                   *rtype
+	field                 *reflect.StructField // nil outside struct fields
+	key                   resolverField
+	disallowUnknownFields bool
+}
+
+func newTypeResolverDecoder(dec decoder, typ *rtype) *typeResolverDecoder {
+	return &typeResolverDecoder{dec: dec, typ: typ}
+}
+
+// setField makes d decode the field of structType, for which a field resolver may be set.
+func (d *typeResolverDecoder) setField(structType *rtype, field reflect.StructField) {
+	d.field = &field
+	d.key = resolverField{structType: rtype2type(structType), name: field.Name}
+}
+
+func (d *typeResolverDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
+	d.disallowUnknownFields = disallowUnknownFields
+	d.dec.setDisallowUnknownFields(disallowUnknownFields)
+}
+
+func (d *typeResolverDecoder) resolver(s *stream) TypeResolver {
+	if d.field != nil {
+		if r, exists := s.fieldTypeResolvers[d.key]; exists {
+			return r
+		}
+	}
+	return s.typeResolvers[rtype2type(d.typ)]
+}
+
+func (d *typeResolverDecoder) decodeStream(s *stream, p uintptr) error {
+	if s.typeResolvers == nil && s.fieldTypeResolvers == nil {
+		return d.dec.decodeStream(s, p)
+	}
+	r := d.resolver(s)
+	if r == nil {
+		return d.dec.decodeStream(s, p)
+	}
+	s.skipWhiteSpace()
+	start := s.cursor
+	s.retainBuffer++
+	defer func() { s.retainBuffer-- }()
+	if err := s.skipValue(); err != nil {
+		return err
+	}
+	raw := s.buf[start:s.cursor]
+	iface := rtype2type(d.typ)
+	field := reflect.NewAt(iface, unsafe.Pointer(p)).Elem()
+	if string(raw) == "null" {
+		field.Set(reflect.Zero(iface))
+		return nil
+	}
+	typ, err := r.ResolveType(d.field, iface, raw)
+	if err != nil {
+		return err
+	}
+	s.cursor = start
+	if typ == nil {
+		return d.dec.decodeStream(s, p)
+	}
+	if !typ.Implements(iface) {
+		return &UnmarshalTypeError{Value: string(raw[:1]), Type: iface, Offset: s.offset + start}
+	}
+	elem := typ
+	if typ.Kind() == reflect.Ptr {
+		elem = typ.Elem()
+	}
+	v := reflect.New(elem)
+	resolved := Decoder{disallowUnknownFields: d.disallowUnknownFields, opts: &s.decodeOptions}
+	dec, err := resolved.compiledDecoder(type2rtype(v.Type()))
+	if err != nil {
+		return err
+	}
+	if err := dec.decodeStream(s, v.Pointer()); err != nil {
+		return err
+	}
+	if typ.Kind() != reflect.Ptr {
+		v = v.Elem()
+	}
+	field.Set(v)
+	return nil
+}
+
+func (d *typeResolverDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	return d.dec.decode(ctx, cursor, p)
+}
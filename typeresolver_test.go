@@ -0,0 +1,110 @@
+package json_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type resolverStore interface {
+	Name() string
+}
+
+type resolverMemoryStore struct {
+	Size int `json:"size"`
+}
+
+func (s resolverMemoryStore) Name() string { return "memory" }
+
+type resolverDiskStore struct {
+	Path   string        `json:"path"`
+	Backup resolverStore `json:"backup"`
+}
+
+func (s *resolverDiskStore) Name() string { return "disk" }
+
+type resolverConfig struct {
+	Primary resolverStore   `json:"primary"`
+	Backup  resolverStore   `json:"backup"`
+	Extra   []resolverStore `json:"extra"`
+}
+
+func resolveByPath(field *reflect.StructField, iface reflect.Type, raw []byte) (reflect.Type, error) {
+	if bytes.Contains(raw, []byte(`"path"`)) {
+		return reflect.TypeOf(&resolverDiskStore{}), nil
+	}
+	return reflect.TypeOf(resolverMemoryStore{}), nil
+}
+
+func Test_TypeResolver(t *testing.T) {
+	src := `{"primary":{"path":"/data","backup":{"size":2}},"backup":{"size":1},"extra":[{"size":3},null]}`
+	t.Run("per type", func(t *testing.T) {
+		var v resolverConfig
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.SetTypeResolver((*resolverStore)(nil), json.TypeResolverFunc(resolveByPath))
+		assertErr(t, dec.Decode(&v))
+		disk := v.Primary.(*resolverDiskStore)
+		assertEq(t, "path", "/data", disk.Path)
+		assertEq(t, "nested", resolverStore(resolverMemoryStore{Size: 2}), disk.Backup)
+		assertEq(t, "backup", resolverStore(resolverMemoryStore{Size: 1}), v.Backup)
+		assertEq(t, "extra", 2, len(v.Extra))
+		assertEq(t, "element", resolverStore(resolverMemoryStore{Size: 3}), v.Extra[0])
+		assertEq(t, "null element", nil, v.Extra[1])
+	})
+	t.Run("per field", func(t *testing.T) {
+		var fields []string
+		var v resolverConfig
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.SetTypeResolver((*resolverStore)(nil), json.TypeResolverFunc(resolveByPath))
+		dec.SetFieldTypeResolver(resolverConfig{}, "Backup", json.TypeResolverFunc(
+			func(field *reflect.StructField, iface reflect.Type, raw []byte) (reflect.Type, error) {
+				fields = append(fields, field.Name)
+				return reflect.TypeOf(&resolverDiskStore{}), nil
+			},
+		))
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "fields", "[Backup]", fmt.Sprint(fields))
+		assertEq(t, "backup", "disk", v.Backup.Name())
+		assertEq(t, "disk backup", resolverStore(resolverMemoryStore{Size: 2}), v.Primary.(*resolverDiskStore).Backup)
+	})
+	t.Run("fallback", func(t *testing.T) {
+		var v struct {
+			Value interface{} `json:"value"`
+		}
+		dec := json.NewDecoder(strings.NewReader(`{"value":{"a":1}}`))
+		dec.SetTypeResolver((*interface{})(nil), json.TypeResolverFunc(
+			func(*reflect.StructField, reflect.Type, []byte) (reflect.Type, error) { return nil, nil },
+		))
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "value", "map[a:1]", fmt.Sprint(v.Value))
+	})
+	t.Run("not implemented", func(t *testing.T) {
+		var v resolverConfig
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.SetTypeResolver((*resolverStore)(nil), json.TypeResolverFunc(
+			func(*reflect.StructField, reflect.Type, []byte) (reflect.Type, error) {
+				return reflect.TypeOf(resolverDiskStore{}), nil
+			},
+		))
+		err := dec.Decode(&v)
+		if _, ok := err.(*json.UnmarshalTypeError); !ok {
+			t.Fatalf("expected *json.UnmarshalTypeError but got %v", err)
+		}
+	})
+	t.Run("options", func(t *testing.T) {
+		var v resolverConfig
+		dec := json.NewDecoder(strings.NewReader(`{"primary":{"PATH":"/data"}}`))
+		dec.SetKeyTransformer(strings.ToLower)
+		dec.SetTypeResolver((*resolverStore)(nil), json.TypeResolverFunc(
+			func(*reflect.StructField, reflect.Type, []byte) (reflect.Type, error) {
+				return reflect.TypeOf(&resolverDiskStore{}), nil
+			},
+		))
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "path", "/data", v.Primary.(*resolverDiskStore).Path)
+	})
+}
@@ -0,0 +1,380 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// union describes how the values of an interface type are decoded into concrete types.
+type union struct {
+	typ     reflect.Type
+	key     string                  // discriminator member
+	types   map[string]reflect.Type // concrete type by discriminator value
+	names   map[reflect.Type]string // discriminator value by concrete type
+	resolve func(raw []byte) (reflect.Type, error)
+}
+
+var (
+	unionsMu sync.RWMutex
+	unions   = map[reflect.Type]*union{}
+)
+
+// RegisterDiscriminator makes fields, elements and map values of the interface type iface points to
+// decode into the type registered in variants for the value of the key member of their object,
+// and adds that member when encoding a value of a registered type:
+//
+//	json.RegisterDiscriminator((*Shape)(nil), "type", map[string]interface{}{
+//		"circle": Circle{},
+//		"rect":   &Rect{},
+//	})
+//
+// An object with "type":"circle" then decodes into a Shape holding a Circle and one with "type":"rect"
+// into a Shape holding a *Rect. Like converters, discriminators must be registered before
+// the first encoding or decoding of a type using them, typically from an init function.
+// RegisterDiscriminator panics if iface is not a pointer to an interface type
+// or a variant does not implement it.
+func RegisterDiscriminator(iface interface{}, key string, variants map[string]interface{}) {
+	typ := unionInterfaceType("RegisterDiscriminator", iface)
+	u := &union{
+		typ:   typ,
+		key:   key,
+		types: make(map[string]reflect.Type, len(variants)),
+		names: make(map[reflect.Type]string, len(variants)),
+	}
+	for name, variant := range variants {
+		vt := reflect.TypeOf(variant)
+		if vt == nil || !vt.Implements(typ) {
+			panic(fmt.Sprintf("json: variant %q of %v is %v, which does not implement it", name, typ, vt))
+		}
+		u.types[name] = vt
+		u.names[vt] = name
+	}
+	registerUnion(u)
+}
+
+// RegisterUnionResolver makes fields, elements and map values of the interface type iface points to
+// decode into the type returned by resolve for their raw JSON value, for unions without a discriminator:
+//
+//	json.RegisterUnionResolver((*Payment)(nil), func(raw []byte) (reflect.Type, error) {
+//		if bytes.Contains(raw, []byte(`"iban"`)) {
+//			return reflect.TypeOf(BankTransfer{}), nil
+//		}
+//		return reflect.TypeOf(&Card{}), nil
+//	})
+//
+// The returned type must implement the interface; a pointer type decodes into a new value it points to.
+// Values are encoded as is. Resolvers must be registered before the first encoding or decoding
+// of a type using them and replace any discriminator registered for the interface.
+// RegisterUnionResolver panics if iface is not a pointer to an interface type.
+func RegisterUnionResolver(iface interface{}, resolve func(raw []byte) (reflect.Type, error)) {
+	registerUnion(&union{
+		typ:     unionInterfaceType("RegisterUnionResolver", iface),
+		resolve: resolve,
+	})
+}
+
+func unionInterfaceType(funcName string, iface interface{}) reflect.Type {
+	typ := reflect.TypeOf(iface)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Interface {
+		panic(fmt.Sprintf("json: %s of non-interface type %v", funcName, typ))
+	}
+	return typ.Elem()
+}
+
+func registerUnion(u *union) {
+	unionsMu.Lock()
+	defer unionsMu.Unlock()
+	unions[u.typ] = u
+}
+
+func lookupUnion(typ *rtype) *union {
+	unionsMu.RLock()
+	defer unionsMu.RUnlock()
+	return unions[rtype2type(typ)]
+}
+
+// resolvedType returns the type the value raw of the union decodes into, as returned by its resolver.
+func (u *union) resolvedType(raw []byte, offset int64) (reflect.Type, error) {
+	typ, err := u.resolve(raw)
+	if err != nil {
+		return nil, err
+	}
+	if typ == nil || !typ.Implements(u.typ) {
+		return nil, &UnmarshalTypeError{Value: "object", Type: u.typ, Offset: offset}
+	}
+	return typ, nil
+}
+
+// discriminatedType returns the type the object at cursor decodes into, named by its discriminator.
+// The members before the discriminator are skipped, and those after it are not read.
+func (u *union) discriminatedType(ctx *runtimeContext, cursor, offset int64) (reflect.Type, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	if buf[cursor] != '{' {
+		return nil, &UnmarshalTypeError{Value: valueKind(buf[cursor]), Type: u.typ, Offset: offset}
+	}
+	var keyDecoder stringDecoder
+	cursor = skipWhiteSpace(buf, cursor+1)
+	for buf[cursor] != '}' {
+		key, c, err := keyDecoder.decodeKeyByte(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = skipWhiteSpace(buf, c)
+		if buf[cursor] != ':' {
+			return nil, errExpected("colon after object key", cursor)
+		}
+		cursor = skipWhiteSpace(buf, cursor+1)
+		if u.isKey(string(key), ctx.v2) {
+			var name string
+			if _, err := keyDecoder.decode(ctx, cursor, uintptr(unsafe.Pointer(&name))); err != nil {
+				return nil, err
+			}
+			typ, exists := u.types[name]
+			if !exists {
+				return nil, fmt.Errorf("json: unknown %s %q for %v (offset %d)", u.key, name, u.typ, offset)
+			}
+			return typ, nil
+		}
+		end, err := skipValue(buf, cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = skipWhiteSpace(buf, end)
+		if buf[cursor] != ',' {
+			break
+		}
+		cursor = skipWhiteSpace(buf, cursor+1)
+	}
+	return nil, fmt.Errorf("json: missing discriminator %q for %v (offset %d)", u.key, u.typ, offset)
+}
+
+// isKey reports whether an object member named key is the discriminator. Without v2 semantics,
+// the name matches case-insensitively, as the names of struct fields do.
+func (u *union) isKey(key string, v2 bool) bool {
+	return key == u.key || !v2 && strings.EqualFold(key, u.key)
+}
+
+type unionCode struct {
+	*opcodeHeader
+	union *union
+}
+
+func (c *unionCode) copy(codeMap map[uintptr]*opcode) *opcode {
+	if c == nil {
+		return nil
+	}
+	addr := uintptr(unsafe.Pointer(c))
+	if code, exists := codeMap[addr]; exists {
+		return code
+	}
+	u := &unionCode{union: c.union}
+	code := (*opcode)(unsafe.Pointer(u))
+	codeMap[addr] = code
+
+	u.opcodeHeader = c.opcodeHeader.copy(codeMap)
+	return code
+}
+
+func (c *opcode) toUnionCode() *unionCode {
+	return (*unionCode)(unsafe.Pointer(c))
+}
+
+func (e *Encoder) compileUnion(typ *rtype, u *union) *opcode {
+	return (*opcode)(unsafe.Pointer(&unionCode{
+		opcodeHeader: &opcodeHeader{
+			op:     opUnion,
+			typ:    typ,
+			indent: e.indent,
+			next:   newEndOp(e.indent),
+		},
+		union: u,
+	}))
+}
+
+func (e *Encoder) encodeUnion(code *unionCode) error {
+	rv := reflect.NewAt(rtype2type(code.typ), unsafe.Pointer(code.ptr)).Elem()
+	if rv.IsNil() {
+		e.encodeNull()
+		return nil
+	}
+	v := rv.Elem()
+	start := len(e.buf)
+	if err := e.encodeInterfaceValue(v.Interface(), code.indent); err != nil {
+		return err
+	}
+	name, exists := code.union.names[v.Type()]
+	if !exists {
+		return nil
+	}
+	for start < len(e.buf) && isWhiteSpace[e.buf[start]] {
+		start++ // indentation written before the object
+	}
+	if start == len(e.buf) || e.buf[start] != '{' {
+		return nil
+	}
+	// rewrite the object with the discriminator as its first member
+	members := append([]byte{}, e.buf[start+1:]...)
+	empty := true
+	for _, c := range members {
+		if !isWhiteSpace[c] {
+			empty = c == '}'
+			break
+		}
+	}
+	e.buf = e.buf[:start+1]
+	if e.enabledIndent {
+		e.encodeByte('\n')
+		e.encodeIndent(code.indent + 1)
+	}
+	e.encodeKey(compiledKey(code.union.key))
+	if e.enabledIndent {
+		e.encodeByte(' ')
+	}
+	e.encodeString(name)
+	if !empty {
+		e.encodeByte(',')
+		e.buf = append(e.buf, members...)
+		return nil
+	}
+	if e.enabledIndent {
+		e.encodeByte('\n')
+		e.encodeIndent(code.indent)
+	}
+	e.encodeByte('}')
+	return nil
+}
+
+// unionDecoder decodes a value into the concrete type picked by the union and stores it in the interface.
+// The decoders of the concrete types are compiled on first use, for the options of the decoder.
+type unionDecoder struct {
+	typ                   *rtype
+	union                 *union
+	disallowUnknownFields bool
+	variants              sync.Map // the decoders of the pointers to the concrete types, by type
+}
+
+func newUnionDecoder(typ *rtype, u *union) *unionDecoder {
+	return &unionDecoder{typ: typ, union: u}
+}
+
+func (d *unionDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
+	d.disallowUnknownFields = disallowUnknownFields
+}
+
+func (d *unionDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	start := s.cursor
+	s.retainBuffer++
+	defer func() { s.retainBuffer-- }()
+	if err := s.skipValue(); err != nil {
+		return err
+	}
+	field := d.field(p)
+	raw := s.buf[start:s.cursor]
+	if string(raw) == "null" {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	var typ reflect.Type
+	var err error
+	if d.union.resolve != nil {
+		typ, err = d.union.resolvedType(raw, s.offset+start)
+	} else {
+		typ, err = d.union.discriminatedType(newRuntimeContext(s.buf, &s.decodeOptions), start, s.offset+start)
+	}
+	if err != nil {
+		return err
+	}
+	v, dec, err := d.target(&s.decodeOptions, typ)
+	if err != nil {
+		return err
+	}
+	s.cursor = start
+	if err := dec.decodeStream(s, v.Pointer()); err != nil {
+		return err
+	}
+	d.store(field, typ, v)
+	return nil
+}
+
+func (d *unionDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	field := d.field(p)
+	var typ reflect.Type
+	var err error
+	switch {
+	case buf[cursor] == 'n':
+		end, err := skipValue(buf, cursor)
+		if err != nil {
+			return 0, err
+		}
+		if string(buf[cursor:end]) != "null" {
+			return 0, errInvalidCharacter(buf[cursor], "null", cursor)
+		}
+		field.Set(reflect.Zero(field.Type()))
+		return end, nil
+	case d.union.resolve != nil:
+		var end int64
+		if end, err = skipValue(buf, cursor); err != nil {
+			return 0, err
+		}
+		typ, err = d.union.resolvedType(buf[cursor:end], cursor)
+	default:
+		typ, err = d.union.discriminatedType(ctx, cursor, cursor)
+	}
+	if err != nil {
+		return 0, err
+	}
+	v, dec, err := d.target(ctx.decodeOptions, typ)
+	if err != nil {
+		return 0, err
+	}
+	end, err := dec.decode(ctx, cursor, v.Pointer())
+	if err != nil {
+		return 0, err
+	}
+	d.store(field, typ, v)
+	return end, nil
+}
+
+func (d *unionDecoder) field(p uintptr) reflect.Value {
+	return reflect.NewAt(rtype2type(d.typ), unsafe.Pointer(p)).Elem()
+}
+
+// target returns a new value of the concrete type typ, or of the type it points to,
+// and the decoder of the pointer to it, compiled for opts on first use.
+func (d *unionDecoder) target(opts *decodeOptions, typ reflect.Type) (reflect.Value, decoder, error) {
+	elem := typ
+	if typ.Kind() == reflect.Ptr {
+		elem = typ.Elem()
+	}
+	v := reflect.New(elem)
+	if dec, ok := d.variants.Load(elem); ok {
+		return v, dec.(decoder), nil
+	}
+	variant := Decoder{disallowUnknownFields: d.disallowUnknownFields, opts: opts}
+	dec, err := variant.compileHead(type2rtype(v.Type()))
+	if err != nil {
+		return reflect.Value{}, nil, err
+	}
+	dec.setDisallowUnknownFields(d.disallowUnknownFields)
+	if s, ok := dec.(*structDecoder); ok && d.union.resolve == nil {
+		// the discriminator is a member of the object
+		s.union = d.union
+	}
+	d.variants.Store(elem, dec)
+	return v, dec, nil
+}
+
+// store sets the interface field to v, the pointer to the decoded value of the concrete type typ.
+func (d *unionDecoder) store(field reflect.Value, typ reflect.Type, v reflect.Value) {
+	if typ.Kind() != reflect.Ptr {
+		v = v.Elem()
+	}
+	field.Set(v)
+}
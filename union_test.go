@@ -0,0 +1,189 @@
+package json_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type unionShape interface {
+	Area() float64
+}
+
+type unionCircle struct {
+	R float64 `json:"r"`
+}
+
+func (c unionCircle) Area() float64 { return 3 * c.R * c.R }
+
+type unionRect struct {
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+func (r *unionRect) Area() float64 { return r.W * r.H }
+
+type unionPoint struct{}
+
+func (unionPoint) Area() float64 { return 0 }
+
+type unionAny struct {
+	Value interface{} `json:"value"`
+}
+
+func (a *unionAny) Area() float64 { return 0 }
+
+type unionAnyHolder struct {
+	Shape unionShape `json:"shape"`
+}
+
+func init() {
+	json.RegisterDiscriminator((*unionShape)(nil), "type", map[string]interface{}{
+		"circle": unionCircle{},
+		"rect":   &unionRect{},
+		"point":  unionPoint{},
+		"any":    &unionAny{},
+	})
+}
+
+type unionDrawing struct {
+	Main   unionShape            `json:"main"`
+	Shapes []unionShape          `json:"shapes"`
+	Named  map[string]unionShape `json:"named,omitempty"`
+}
+
+func Test_Discriminator(t *testing.T) {
+	drawing := unionDrawing{
+		Main:   unionCircle{R: 1},
+		Shapes: []unionShape{&unionRect{W: 2, H: 3}, unionPoint{}, nil},
+	}
+	src := `{"main":{"type":"circle","r":1},"shapes":[{"type":"rect","w":2,"h":3},{"type":"point"},null]}`
+	t.Run("encode", func(t *testing.T) {
+		bytes, err := json.Marshal(drawing)
+		assertErr(t, err)
+		assertEq(t, "encoded", src, string(bytes))
+	})
+	t.Run("encode indent", func(t *testing.T) {
+		bytes, err := json.MarshalIndent(struct {
+			S unionShape `json:"s"`
+			P unionShape `json:"p"`
+		}{S: unionCircle{R: 1}, P: unionPoint{}}, "", "  ")
+		assertErr(t, err)
+		assertEq(t, "encoded", "{\n  \"s\": {\n    \"type\": \"circle\",\n    \"r\": 1\n  },\n  \"p\": {\n    \"type\": \"point\"\n  }\n}", string(bytes))
+	})
+	t.Run("decode", func(t *testing.T) {
+		var v unionDrawing
+		assertErr(t, json.Unmarshal([]byte(src), &v))
+		assertEq(t, "main", unionShape(unionCircle{R: 1}), v.Main)
+		assertEq(t, "shapes", 3, len(v.Shapes))
+		assertEq(t, "rect", float64(6), v.Shapes[0].Area())
+		assertEq(t, "point", unionShape(unionPoint{}), v.Shapes[1])
+		assertEq(t, "null", nil, v.Shapes[2])
+	})
+	t.Run("decode stream", func(t *testing.T) {
+		var v unionDrawing
+		assertErr(t, json.NewDecoder(strings.NewReader(`{"named":{"a":{"r":2,"type":"circle"}}}`)).Decode(&v))
+		assertEq(t, "named", unionShape(unionCircle{R: 2}), v.Named["a"])
+	})
+	t.Run("decoder options", func(t *testing.T) {
+		api := json.Config{DisallowUnknownFields: true, UseNumber: true}.Freeze()
+		var v struct {
+			Main unionShape `json:"main"`
+		}
+		src := `{"main":{"r":1,"type":"circle"}}`
+		assertErr(t, api.Unmarshal([]byte(src), &v))
+		assertEq(t, "main", unionShape(unionCircle{R: 1}), v.Main)
+		dec := api.NewDecoder(strings.NewReader(src))
+		assertErr(t, dec.Decode(&v))
+		assertEq(t, "stream main", unionShape(unionCircle{R: 1}), v.Main)
+		for _, unknown := range []string{`{"main":{"type":"circle","x":1}}`, `{"main":{"x":1,"type":"circle"}}`} {
+			err := api.Unmarshal([]byte(unknown), &v)
+			if _, ok := err.(*json.UnknownFieldError); !ok {
+				t.Errorf("%s: expected *json.UnknownFieldError but got %v", unknown, err)
+			}
+			err = api.NewDecoder(strings.NewReader(unknown)).Decode(&v)
+			if _, ok := err.(*json.UnknownFieldError); !ok {
+				t.Errorf("%s: Decode: expected *json.UnknownFieldError but got %v", unknown, err)
+			}
+		}
+		var holder unionAnyHolder
+		assertErr(t, api.Unmarshal([]byte(`{"shape":{"type":"any","value":1.5}}`), &holder))
+		assertEq(t, "number", json.Number("1.5"), holder.Shape.(*unionAny).Value)
+		assertErr(t, api.NewDecoder(strings.NewReader(`{"shape":{"type":"any","value":2}}`)).Decode(&holder))
+		assertEq(t, "stream number", json.Number("2"), holder.Shape.(*unionAny).Value)
+	})
+	t.Run("unknown discriminator", func(t *testing.T) {
+		var v unionDrawing
+		if err := json.Unmarshal([]byte(`{"main":{"type":"hexagon"}}`), &v); err == nil {
+			t.Fatal("expected error")
+		}
+		if err := json.Unmarshal([]byte(`{"main":{"r":1}}`), &v); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+type unionPayment interface {
+	Amount() int
+}
+
+type unionCard struct {
+	Number string `json:"number"`
+	Cents  int    `json:"cents"`
+}
+
+func (c *unionCard) Amount() int { return c.Cents }
+
+type unionTransfer struct {
+	IBAN  string `json:"iban"`
+	Cents int    `json:"cents"`
+}
+
+func (t unionTransfer) Amount() int { return t.Cents }
+
+func init() {
+	json.RegisterUnionResolver((*unionPayment)(nil), func(raw []byte) (reflect.Type, error) {
+		switch {
+		case bytes.Contains(raw, []byte(`"iban"`)):
+			return reflect.TypeOf(unionTransfer{}), nil
+		case bytes.Contains(raw, []byte(`"number"`)):
+			return reflect.TypeOf(&unionCard{}), nil
+		case bytes.Contains(raw, []byte(`"bad"`)):
+			return reflect.TypeOf(0), nil
+		}
+		return nil, errors.New("unknown payment")
+	})
+}
+
+func Test_UnionResolver(t *testing.T) {
+	type order struct {
+		Payments []unionPayment `json:"payments"`
+	}
+	src := `{"payments":[{"number":"4242","cents":100},{"iban":"DE00","cents":200},null]}`
+	var v order
+	assertErr(t, json.Unmarshal([]byte(src), &v))
+	assertEq(t, "payments", 3, len(v.Payments))
+	assertEq(t, "card", unionCard{Number: "4242", Cents: 100}, *v.Payments[0].(*unionCard))
+	assertEq(t, "transfer", unionPayment(unionTransfer{IBAN: "DE00", Cents: 200}), v.Payments[1])
+	assertEq(t, "null", nil, v.Payments[2])
+
+	var stream order
+	assertErr(t, json.NewDecoder(strings.NewReader(src)).Decode(&stream))
+	assertEq(t, "stream transfer", v.Payments[1], stream.Payments[1])
+
+	bytes, err := json.Marshal(v)
+	assertErr(t, err)
+	assertEq(t, "encoded", src, string(bytes))
+
+	if err := json.Unmarshal([]byte(`{"payments":[{"cash":1}]}`), &v); err == nil {
+		t.Fatal("expected error")
+	}
+	err = json.Unmarshal([]byte(`{"payments":[{"bad":1}]}`), &v)
+	if _, ok := err.(*json.UnmarshalTypeError); !ok {
+		t.Fatalf("expected *json.UnmarshalTypeError but got %v", err)
+	}
+}
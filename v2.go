@@ -0,0 +1,239 @@
+package json
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"unsafe"
+)
+
+var v2SemanticsValue atomic.Value
+
+// SetV2Semantics switches the package to the defaults of the encoding/json/v2 proposal,
+// so that code written against json/v2 gets the behavior it expects:
+//
+//   - object member names match struct fields case-sensitively: a member is
+//     decoded into a field only under its JSON name or its aliases, exactly;
+//   - an object with a duplicate member name fails to decode, into a struct,
+//     a map or an interface value;
+//   - HTML characters are not escaped in strings, unless enabled with SetEscapeHTML;
+//   - the omitempty option omits a field whose value encodes as null, "", {} or [],
+//     so it no longer omits false and zero numbers, while omitzero omits zero values.
+//
+// Nil slices and maps encode as [] and {} in either mode.
+// SetV2Semantics sets the mode of the package functions and the default of the Encoders
+// and Decoders created afterwards; Encoder.SetV2Semantics, Decoder.SetV2Semantics and
+// Config.V2Semantics set it for one of them. The codecs compiled in each mode are cached
+// apart, so switching the mode does not leave codecs of the other mode in use.
+func SetV2Semantics(on bool) {
+	v2SemanticsValue.Store(on)
+}
+
+func v2Semantics() bool {
+	on, _ := v2SemanticsValue.Load().(bool)
+	return on
+}
+
+func errDuplicateName(name string, cursor int64) *SyntaxError {
+	return &SyntaxError{
+		msg:    fmt.Sprintf("duplicate object member name %q", name),
+		Offset: cursor,
+	}
+}
+
+// objectNames records the member names of an object being decoded with v2 semantics,
+// to reject duplicates.
+type objectNames map[string]struct{}
+
+// add records name, which may refer to the decoder buffer, and reports whether it is new.
+func (n *objectNames) add(name []byte) bool {
+	if *n == nil {
+		*n = objectNames{}
+	}
+	if _, exists := (*n)[string(name)]; exists {
+		return false
+	}
+	(*n)[string(name)] = struct{}{}
+	return true
+}
+
+// checkDuplicateKey returns an error once a map being decoded with v2 semantics
+// did not grow with the entry stored under key, which is held in the key type of the map.
+func (d *mapDecoder) checkDuplicateKey(m unsafe.Pointer, size int, key unsafe.Pointer, cursor int64) error {
+	if maplen(m) > size {
+		return nil
+	}
+	k := reflect.NewAt(rtype2type(d.mapType.Key()), key).Elem()
+	return errDuplicateName(fmt.Sprint(k.Interface()), cursor)
+}
+
+// omitFunc returns whether a field compiled to valueOp is omitted by the omitempty
+// and omitzero options, and the emptiness check of the generic field opcodes.
+// It is used for omitzero and for omitempty with v2 semantics; the opcodes of
+// numbers, strings and bools compare their values to zero.
+func omitFunc(typ *rtype, valueOp opType, omitEmpty, omitZero bool) (bool, func(uintptr) bool) {
+	switch valueOp {
+	case opInt, opInt8, opInt16, opInt32, opInt64,
+		opUint, opUint8, opUint16, opUint32, opUint64,
+		opFloat32, opFloat64, opBool:
+		// never empty with v2 semantics
+		return omitZero, nil
+	case opString:
+		return true, nil
+	}
+	isZero := zeroFunc(typ)
+	isEmpty := v2EmptyFunc(typ)
+	switch {
+	case omitEmpty && omitZero:
+		return true, func(p uintptr) bool { return isZero(p) || isEmpty(p) }
+	case omitZero:
+		return true, isZero
+	}
+	return true, isEmpty
+}
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
+// absentValue is implemented by Optional, whose absent values omitempty omits as omitzero does.
+type absentValue interface {
+	isAbsent() bool
+}
+
+var absentValueType = reflect.TypeOf((*absentValue)(nil)).Elem()
+
+// zeroFunc returns the check of the omitzero option: a value is omitted if it is
+// the zero value of its type or if its IsZero method returns true.
+func zeroFunc(typ *rtype) func(uintptr) bool {
+	t := rtype2type(typ)
+	zero := reflect.Zero(t).Interface()
+	switch {
+	case t.Implements(isZeroerType):
+		return func(p uintptr) bool {
+			v := reflect.NewAt(t, unsafe.Pointer(p)).Elem()
+			if v.Kind() == reflect.Ptr && v.IsNil() {
+				return true
+			}
+			return v.Interface().(isZeroer).IsZero()
+		}
+	case reflect.PtrTo(t).Implements(isZeroerType):
+		return func(p uintptr) bool {
+			return reflect.NewAt(t, unsafe.Pointer(p)).Interface().(isZeroer).IsZero()
+		}
+	}
+	return func(p uintptr) bool {
+		return reflect.DeepEqual(reflect.NewAt(t, unsafe.Pointer(p)).Elem().Interface(), zero)
+	}
+}
+
+// v2EmptyFunc returns the check of the omitempty option with v2 semantics:
+// a value is omitted if it encodes as null, "", {} or [].
+func v2EmptyFunc(typ *rtype) func(uintptr) bool {
+	t := rtype2type(typ)
+	return func(p uintptr) bool {
+		return isV2EmptyValue(reflect.NewAt(t, unsafe.Pointer(p)).Elem())
+	}
+}
+
+// isV2EmptyValue reports whether the addressable value v encodes as null, "", {} or [].
+// Numbers and bools are never empty, and a struct is empty when all of its fields are omitted.
+// Only the values of marshaler types are encoded, by their methods, to be checked.
+func isV2EmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return true
+		}
+	}
+	if isMarshalerType(v.Type()) {
+		return isV2EmptyMarshaler(v.Interface())
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr:
+		return isV2EmptyValue(v.Elem())
+	case reflect.Interface:
+		elem := reflect.New(v.Elem().Type()).Elem() // addressable
+		elem.Set(v.Elem())
+		return isV2EmptyValue(elem)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := structTag(field)
+			if field.PkgPath != "" && !field.Anonymous {
+				if isMethodField(tag) {
+					// computed from the struct
+					return false
+				}
+				continue
+			}
+			if tag == "-" || type2rtype(field.Type) == presenceType {
+				continue
+			}
+			fv := reflect.NewAt(field.Type, unsafe.Pointer(v.Field(i).UnsafeAddr())).Elem()
+			if !isV2OmittedField(field, tag, fv) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isV2OmittedField reports whether the field of a struct holding fv is omitted with v2 semantics.
+func isV2OmittedField(field reflect.StructField, tag string, fv reflect.Value) bool {
+	opts := strings.Split(tag, ",")
+	isOmitEmpty := len(opts) > 1 && opts[1] == "omitempty"
+	isOmitZero := false
+	isDeep := false
+	for i, opt := range opts {
+		isOmitZero = isOmitZero || i > 0 && opt == "omitzero"
+		isDeep = isDeep || i > 1 && opt == "deep"
+	}
+	switch {
+	case isOmitZero && zeroFunc(type2rtype(field.Type))(fv.UnsafeAddr()):
+		return true
+	case isOmitEmpty && isDeep:
+		return isDeepEmptyValue(fv)
+	case isOmitEmpty:
+		return isV2EmptyValue(fv)
+	}
+	return false
+}
+
+// isV2EmptyMarshaler reports whether v, of a marshaler type, encodes as null, "", {} or [].
+func isV2EmptyMarshaler(v interface{}) bool {
+	var b []byte
+	var err error
+	switch m := v.(type) {
+	case MarshalerTo:
+		// written to an Encoder
+		b, err = Marshal(v)
+	case Appender:
+		b, err = m.AppendJSON(nil)
+	case Marshaler:
+		b, err = m.MarshalJSON()
+	case encoding.TextMarshaler:
+		b, err = m.MarshalText()
+		return err == nil && len(b) == 0
+	default:
+		return false
+	}
+	if err != nil {
+		// reported when the field is encoded
+		return false
+	}
+	switch string(bytes.TrimSpace(b)) {
+	case "null", `""`, "{}", "[]":
+		return true
+	}
+	return false
+}
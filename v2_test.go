@@ -0,0 +1,230 @@
+package json_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_V2SemanticsSwitch(t *testing.T) {
+	type T struct {
+		UserName string `json:"userName"`
+		Note     string `json:"note"`
+	}
+	v := T{UserName: "a", Note: "<b>"}
+	for i, on := range []bool{false, true, false} {
+		json.SetV2Semantics(on)
+		bytes, err := json.Marshal(v)
+		assertErr(t, err)
+		var got T
+		assertErr(t, json.Unmarshal([]byte(`{"username":"x"}`), &got))
+		if on {
+			assertEq(t, fmt.Sprint("v2 marshal ", i), `{"userName":"a","note":"<b>"}`, string(bytes))
+			assertEq(t, fmt.Sprint("v2 names ", i), "", got.UserName)
+		} else {
+			assertEq(t, fmt.Sprint("v1 marshal ", i), `{"userName":"a","note":"\u003cb\u003e"}`, string(bytes))
+			assertEq(t, fmt.Sprint("v1 names ", i), "x", got.UserName)
+		}
+	}
+	json.SetV2Semantics(false)
+}
+
+func Test_V2Semantics(t *testing.T) {
+	json.SetV2Semantics(true)
+	defer json.SetV2Semantics(false)
+
+	t.Run("case-sensitive names", func(t *testing.T) {
+		type v2Names struct {
+			UserName string `json:"userName"`
+			ID       int
+		}
+		var v v2Names
+		assertErr(t, json.Unmarshal([]byte(`{"username":"a","id":1}`), &v))
+		assertEq(t, "userName", "", v.UserName)
+		assertEq(t, "ID", 0, v.ID)
+		assertErr(t, json.Unmarshal([]byte(`{"userName":"b","ID":2}`), &v))
+		assertEq(t, "userName", "b", v.UserName)
+		assertEq(t, "ID", 2, v.ID)
+	})
+	t.Run("duplicate names", func(t *testing.T) {
+		type v2Dup struct {
+			A int `json:"a"`
+		}
+		for _, test := range []struct {
+			name string
+			data string
+			v    interface{}
+		}{
+			{"struct", `{"a":1,"a":2}`, &v2Dup{}},
+			{"unknown struct member", `{"a":1,"b":2,"b":3}`, &v2Dup{}},
+			{"map", `{"x":1,"y":2,"x":3}`, &map[string]int{}},
+			{"interface", `{"x":{"y":1,"y":2}}`, new(interface{})},
+		} {
+			err := json.Unmarshal([]byte(test.data), test.v)
+			if err == nil || !strings.Contains(err.Error(), "duplicate object member name") {
+				t.Errorf("%s: Unmarshal: expected duplicate name error, got %v", test.name, err)
+			}
+			err = json.NewDecoder(strings.NewReader(test.data)).Decode(test.v)
+			if err == nil || !strings.Contains(err.Error(), "duplicate object member name") {
+				t.Errorf("%s: Decode: expected duplicate name error, got %v", test.name, err)
+			}
+		}
+		var m map[string]int
+		assertErr(t, json.Unmarshal([]byte(`{"x":1,"y":2}`), &m))
+		assertEq(t, "len", 2, len(m))
+	})
+	t.Run("no HTML escaping", func(t *testing.T) {
+		b, err := json.Marshal("<a&b>")
+		assertErr(t, err)
+		assertEq(t, "marshal", `"<a&b>"`, string(b))
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(true)
+		assertErr(t, enc.Encode("<a>"))
+		assertEq(t, "escaped", `"\u003ca\u003e"`, strings.TrimSpace(buf.String()))
+	})
+	t.Run("omitempty", func(t *testing.T) {
+		type v2Inner struct {
+			A string `json:"a,omitempty"`
+		}
+		type v2Omit struct {
+			N     int               `json:"n,omitempty"`
+			B     bool              `json:"b,omitempty"`
+			S     string            `json:"s,omitempty"`
+			L     []int             `json:"l,omitempty"`
+			M     map[string]int    `json:"m,omitempty"`
+			P     *int              `json:"p,omitempty"`
+			Inner v2Inner           `json:"inner,omitempty"`
+			Raw   json.RawMessage   `json:"raw,omitempty"`
+			Any   interface{}       `json:"any,omitempty"`
+			Names map[string]string `json:"names"`
+		}
+		b, err := json.Marshal(v2Omit{L: []int{}, Any: ""})
+		assertErr(t, err)
+		assertEq(t, "empty", `{"n":0,"b":false,"names":{}}`, string(b))
+		b, err = json.Marshal(v2Omit{N: 1, S: "s", L: []int{1}, Inner: v2Inner{A: "a"}})
+		assertErr(t, err)
+		assertEq(t, "set", `{"n":1,"b":false,"s":"s","l":[1],"inner":{"a":"a"},"names":{}}`, string(b))
+	})
+}
+
+func Test_V2SemanticsOption(t *testing.T) {
+	type T struct {
+		UserName string `json:"userName"`
+		Note     string `json:"note"`
+	}
+	v := T{UserName: "a", Note: "<b>"}
+	t.Run("encoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetV2Semantics(true)
+		assertErr(t, enc.Encode(v))
+		assertEq(t, "v2", `{"userName":"a","note":"<b>"}`, strings.TrimSpace(buf.String()))
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "package", `{"userName":"a","note":"\u003cb\u003e"}`, string(b))
+	})
+	t.Run("decoder", func(t *testing.T) {
+		var got T
+		dec := json.NewDecoder(strings.NewReader(`{"username":"x"}`))
+		dec.SetV2Semantics(true)
+		assertErr(t, dec.Decode(&got))
+		assertEq(t, "v2 names", "", got.UserName)
+		dec = json.NewDecoder(strings.NewReader(`{"a":1,"a":2}`))
+		dec.SetV2Semantics(true)
+		var m map[string]int
+		if err := dec.Decode(&m); err == nil || !strings.Contains(err.Error(), "duplicate object member name") {
+			t.Errorf("expected duplicate name error, got %v", err)
+		}
+		assertErr(t, json.Unmarshal([]byte(`{"username":"x"}`), &got))
+		assertEq(t, "package names", "x", got.UserName)
+	})
+	t.Run("config", func(t *testing.T) {
+		api := json.Config{V2Semantics: true}.Freeze()
+		b, err := api.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "marshal", `{"userName":"a","note":"<b>"}`, string(b))
+		var got T
+		assertErr(t, api.Unmarshal([]byte(`{"username":"x"}`), &got))
+		assertEq(t, "unmarshal names", "", got.UserName)
+		if err := api.Unmarshal([]byte(`{"note":"a","note":"b"}`), &got); err == nil {
+			t.Error("expected duplicate name error")
+		}
+		json.SetV2Semantics(true)
+		defer json.SetV2Semantics(false)
+		b, err = json.Config{EscapeHTML: true}.Freeze().Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "v1 config", `{"userName":"a","note":"\u003cb\u003e"}`, string(b))
+	})
+}
+
+type v2EmptyText string
+
+func (t v2EmptyText) MarshalText() ([]byte, error) {
+	return []byte(t), nil
+}
+
+type v2EmptyJSON struct {
+	raw string
+}
+
+func (j *v2EmptyJSON) MarshalJSON() ([]byte, error) {
+	return []byte(j.raw), nil
+}
+
+func Test_V2SemanticsOmitEmpty(t *testing.T) {
+	type v2Nested struct {
+		E string    `json:"e,omitempty"`
+		N int       `json:"n,omitzero"`
+		P *v2Nested `json:"p,omitempty"`
+	}
+	type v2Fields struct {
+		Nested  v2Nested     `json:"nested,omitempty"`
+		Text    v2EmptyText  `json:"text,omitempty"`
+		JSON    *v2EmptyJSON `json:"json,omitempty"`
+		Any     interface{}  `json:"any,omitempty"`
+		Written struct {
+			A int
+		} `json:"written,omitempty"`
+	}
+	api := json.Config{V2Semantics: true}.Freeze()
+	b, err := api.Marshal(v2Fields{
+		Nested: v2Nested{P: &v2Nested{}},
+		JSON:   &v2EmptyJSON{raw: " {} "},
+		Any:    v2Nested{},
+	})
+	assertErr(t, err)
+	assertEq(t, "empty", `{"written":{"A":0}}`, string(b))
+	b, err = api.Marshal(v2Fields{
+		Nested: v2Nested{E: "e"},
+		Text:   "t",
+		JSON:   &v2EmptyJSON{raw: "0"},
+		Any:    &v2Nested{N: 1},
+	})
+	assertErr(t, err)
+	assertEq(t, "set", `{"nested":{"e":"e"},"text":"t","json":0,"any":{"n":1},"written":{"A":0}}`, string(b))
+}
+
+func Test_OmitZero(t *testing.T) {
+	type zeroPoint struct {
+		X, Y int
+	}
+	type zeroFields struct {
+		N    int       `json:"n,omitzero"`
+		P    zeroPoint `json:"p,omitzero"`
+		T    time.Time `json:"t,omitzero"`
+		L    []int     `json:"l,omitzero"`
+		Both []int     `json:"both,omitempty,omitzero"`
+	}
+	b, err := json.Marshal(zeroFields{L: []int{}, Both: []int{}})
+	assertErr(t, err)
+	assertEq(t, "zero", `{"l":[]}`, string(b))
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	b, err = json.Marshal(zeroFields{N: 1, P: zeroPoint{Y: 1}, T: at})
+	assertErr(t, err)
+	assertEq(t, "set", `{"n":1,"p":{"X":0,"Y":1},"t":"2020-01-02T03:04:05Z"}`, string(b))
+}
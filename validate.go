@@ -0,0 +1,628 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// An Issue is a syntax violation found by ValidateAll.
+type Issue struct {
+	Offset  int64  // offset of the violation in bytes
+	Line    int    // line of the violation, starting at 1
+	Column  int    // column of the violation in bytes, starting at 1
+	Message string // description of the violation
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%d:%d: %s", i.Line, i.Column, i.Message)
+}
+
+// ValidateAll reports every syntax violation of data as JSON, in the order they appear,
+// or nil if data is valid. Unlike Valid and Unmarshal it does not stop at the first error:
+// it skips what it cannot read and resynchronizes at the next comma, colon,
+// bracket or brace, so that hand-written JSON can be linted in one pass.
+// Common mistakes such as comments, trailing or missing commas, unquoted keys,
+// single-quoted strings and unclosed brackets are described as such.
+// After a violation, the issues that follow may be consequences of it.
+// Strings are not checked for invalid UTF-8, which Valid accepts, and the values nested
+// in more than 10000 objects and arrays, which encoding/json rejects, are not checked.
+func ValidateAll(data []byte) []Issue {
+	v := &validator{buf: data}
+	v.whitespace()
+	if v.cursor >= len(v.buf) {
+		v.issue("unexpected end of JSON input, expected a value")
+		return v.issues
+	}
+	start := v.cursor
+	v.value()
+	if v.cursor == start || v.done {
+		// a closing bracket or a separator, already reported
+		return v.issues
+	}
+	v.whitespace()
+	if v.cursor < len(v.buf) {
+		v.issue("invalid character %s after top-level value", quoteChar(v.buf[v.cursor]))
+	}
+	return v.issues
+}
+
+type validator struct {
+	buf    []byte
+	cursor int
+	issues []Issue
+	// closers holds the closing characters of the containers being read, innermost last,
+	// to tell a bracket closing an outer container from a stray one.
+	closers []byte
+	done    bool // whether the rest of the input is not checked
+}
+
+// maxValidateDepth is the nesting depth of objects and arrays past which ValidateAll
+// does not check values, like the limit of encoding/json, so that the recursion of the
+// validator is bounded.
+const maxValidateDepth = 10000
+
+func (v *validator) issue(format string, args ...interface{}) {
+	v.issueAt(v.cursor, format, args...)
+}
+
+func (v *validator) issueAt(offset int, format string, args ...interface{}) {
+	line := 1 + bytes.Count(v.buf[:offset], []byte{'\n'})
+	column := offset + 1
+	if idx := bytes.LastIndexByte(v.buf[:offset], '\n'); idx >= 0 {
+		column = offset - idx
+	}
+	v.issues = append(v.issues, Issue{
+		Offset:  int64(offset),
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) char() byte {
+	if v.cursor < len(v.buf) {
+		return v.buf[v.cursor]
+	}
+	return nul
+}
+
+// whitespace skips whitespace, and comments after reporting them.
+func (v *validator) whitespace() {
+	for v.cursor < len(v.buf) {
+		c := v.buf[v.cursor]
+		if isWhiteSpace[c] {
+			v.cursor++
+			continue
+		}
+		if c != '/' || v.cursor+1 >= len(v.buf) {
+			return
+		}
+		switch v.buf[v.cursor+1] {
+		case '/':
+			v.issue("comments are not allowed in JSON")
+			if idx := bytes.IndexByte(v.buf[v.cursor:], '\n'); idx >= 0 {
+				v.cursor += idx + 1
+			} else {
+				v.cursor = len(v.buf)
+			}
+		case '*':
+			v.issue("comments are not allowed in JSON")
+			if idx := bytes.Index(v.buf[v.cursor+2:], []byte("*/")); idx >= 0 {
+				v.cursor += idx + 4
+			} else {
+				v.issue("unexpected end of JSON input in comment")
+				v.cursor = len(v.buf)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// isDelimiter reports whether c ends an invalid run of characters being skipped.
+func isDelimiter(c byte) bool {
+	switch c {
+	case ',', ':', '[', ']', '{', '}', '"':
+		return true
+	}
+	return isWhiteSpace[c]
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$'
+}
+
+// startsValue reports whether c can begin a value or is a common mistake for the beginning of one.
+func startsValue(c byte) bool {
+	switch c {
+	case '{', '[', '"', '\'', '-', '+', '.':
+		return true
+	}
+	return isWordChar(c)
+}
+
+// skip moves past a run of characters that cannot be read, up to the next delimiter.
+func (v *validator) skip() {
+	v.cursor++
+	for v.cursor < len(v.buf) && !isDelimiter(v.buf[v.cursor]) {
+		v.cursor++
+	}
+}
+
+// value reads the value at the cursor, which is not whitespace. Closing brackets and
+// separators are left for the enclosing container, after reporting the missing value.
+func (v *validator) value() {
+	switch c := v.char(); {
+	case (c == '{' || c == '[') && len(v.closers) >= maxValidateDepth:
+		v.tooDeep()
+	case c == '{':
+		v.object()
+	case c == '[':
+		v.array()
+	case c == '"' || c == '\'':
+		v.str()
+	case c == '-' || c == '+' || c == '.' || c >= '0' && c <= '9':
+		v.number()
+	case isWordChar(c):
+		v.literal()
+	case v.cursor >= len(v.buf):
+		v.issue("unexpected end of JSON input, expected a value")
+	case c == ',' || c == ':' || c == ']' || c == '}':
+		v.issue("expected a value but found %s", quoteChar(c))
+	default:
+		v.issue("invalid character %s looking for beginning of value", quoteChar(c))
+		v.skip()
+	}
+}
+
+// tooDeep reports the container at the cursor, nested too deep to be checked, and skips it.
+// If it is not closed, the validation ends, rather than reporting each container enclosing it.
+func (v *validator) tooDeep() {
+	v.issue("exceeded max depth of %d nested objects and arrays", maxValidateDepth)
+	depth := 0
+	for ; v.cursor < len(v.buf); v.cursor++ {
+		switch v.buf[v.cursor] {
+		case '"':
+			for v.cursor++; v.cursor < len(v.buf) && v.buf[v.cursor] != '"'; v.cursor++ {
+				if v.buf[v.cursor] == '\\' {
+					v.cursor++
+				}
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				v.cursor++
+				return
+			}
+		}
+	}
+	v.done = true
+}
+
+// closesOuter reports whether c closes one of the containers enclosing the innermost one.
+func (v *validator) closesOuter(c byte) bool {
+	for i := len(v.closers) - 2; i >= 0; i-- {
+		if v.closers[i] == c {
+			return true
+		}
+	}
+	return false
+}
+
+// unclosed handles a character that should have been the closer of the innermost container
+// and reports whether the container has to end without it.
+func (v *validator) unclosed(kind string, start int) bool {
+	c := v.char()
+	switch {
+	case v.done:
+		return true
+	case c == v.closers[len(v.closers)-1]:
+		return false
+	case v.cursor >= len(v.buf):
+		v.issue("unexpected end of JSON input, %s opened at offset %d is not closed", kind, start)
+		return true
+	case (c == ']' || c == '}') && v.closesOuter(c):
+		v.issue("expected %s to be closed before %s", kind, quoteChar(c))
+		return true
+	}
+	return false
+}
+
+func (v *validator) object() {
+	start := v.cursor
+	v.cursor++
+	v.closers = append(v.closers, '}')
+	defer func() { v.closers = v.closers[:len(v.closers)-1] }()
+	needComma := false
+	trailing := -1 // offset of a comma not followed by a member yet
+	for {
+		v.whitespace()
+		if v.unclosed("object", start) {
+			return
+		}
+		switch c := v.char(); c {
+		case '}':
+			if trailing >= 0 {
+				v.issueAt(trailing, "trailing comma before }")
+			}
+			v.cursor++
+			return
+		case ',':
+			v.issue("expected an object key but found ','")
+			v.cursor++
+			continue
+		case ']':
+			v.issue("invalid character ']' in object")
+			v.cursor++
+			continue
+		}
+		if needComma {
+			v.issue("missing comma between object members")
+		}
+		v.member()
+		needComma = true
+		trailing = -1
+		v.whitespace()
+		switch c := v.char(); {
+		case c == ',':
+			needComma = false
+			trailing = v.cursor
+			v.cursor++
+		case c == '}' || v.cursor >= len(v.buf) || c == ']' && v.closesOuter(c):
+		case c == '"' || c == '\'' || isWordChar(c):
+			// a missing comma, reported by the next iteration
+		default:
+			v.issue("invalid character %s after object member", quoteChar(c))
+			v.skip()
+			needComma = false
+		}
+	}
+}
+
+// member reads a key/value pair of an object.
+func (v *validator) member() {
+	switch c := v.char(); {
+	case c == '{' || c == '[':
+		v.issue("missing object key before value")
+		v.value()
+		return
+	case c == ':':
+		v.issue("missing object key before ':'")
+	default:
+		v.key()
+		v.whitespace()
+		if v.char() != ':' {
+			v.issue("missing colon after object key")
+		}
+	}
+	if v.char() == ':' {
+		v.cursor++
+		v.whitespace()
+	}
+	switch c := v.char(); {
+	case v.cursor >= len(v.buf):
+	case c == ',' || c == '}':
+		v.issue("missing value after object key")
+	default:
+		v.value()
+	}
+}
+
+func (v *validator) key() {
+	switch c := v.char(); {
+	case c == '"' || c == '\'':
+		v.str()
+	case isWordChar(c):
+		start := v.cursor
+		for v.cursor < len(v.buf) && isWordChar(v.buf[v.cursor]) {
+			v.cursor++
+		}
+		v.issueAt(start, "object key %s must be a quoted string", v.buf[start:v.cursor])
+	default:
+		v.issue("invalid character %s looking for beginning of object key", quoteChar(c))
+		v.skip()
+	}
+}
+
+func (v *validator) array() {
+	start := v.cursor
+	v.cursor++
+	v.closers = append(v.closers, ']')
+	defer func() { v.closers = v.closers[:len(v.closers)-1] }()
+	elems := 0
+	trailing := -1
+	for {
+		v.whitespace()
+		if v.unclosed("array", start) {
+			return
+		}
+		switch c := v.char(); c {
+		case ']':
+			if trailing >= 0 {
+				v.issueAt(trailing, "trailing comma before ]")
+			}
+			v.cursor++
+			return
+		case ',':
+			v.issue("expected a value but found ','")
+			v.cursor++
+			continue
+		case '}', ':':
+			v.issue("invalid character %s in array", quoteChar(c))
+			v.cursor++
+			continue
+		}
+		if elems > 0 && trailing < 0 {
+			v.issue("missing comma between array elements")
+		}
+		v.value()
+		elems++
+		trailing = -1
+		v.whitespace()
+		switch c := v.char(); {
+		case c == ',':
+			trailing = v.cursor
+			v.cursor++
+		case c == ']' || v.cursor >= len(v.buf) || c == '}' && v.closesOuter(c):
+		case startsValue(c):
+			// a missing comma, reported by the next iteration
+		default:
+			v.issue("invalid character %s after array element", quoteChar(c))
+			v.skip()
+		}
+	}
+}
+
+// str reads a string. A string that is not closed on its line ends at the line break,
+// or before a comma ending the line, where it was most likely meant to end.
+func (v *validator) str() {
+	start := v.cursor
+	quote := v.buf[v.cursor]
+	if quote == '\'' {
+		v.issue("strings must be quoted with '\"', not \"'\"")
+	}
+	v.cursor++
+	for v.cursor < len(v.buf) {
+		c := v.buf[v.cursor]
+		switch {
+		case c == quote:
+			v.cursor++
+			return
+		case c == '\n':
+			v.issueAt(start, "string is not closed before the end of the line")
+			if end := bytes.TrimRight(v.buf[:v.cursor], " \t\r"); bytes.HasSuffix(end, []byte{','}) {
+				// the comma was meant to follow the string
+				v.cursor = len(end) - 1
+			}
+			return
+		case c < 0x20:
+			v.issue("invalid control character %s in string", quoteChar(c))
+		case c == '\\':
+			v.escape()
+			continue
+		}
+		v.cursor++
+	}
+	v.issueAt(start, "unexpected end of JSON input, string is not closed")
+}
+
+func (v *validator) escape() {
+	v.cursor++
+	switch v.char() {
+	case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+		v.cursor++
+	case 'u':
+		v.cursor++
+		for i := 0; i < 4; i++ {
+			if c := v.char(); !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+				v.issueAt(v.cursor-2-i, "invalid escape sequence \\u%s in string", v.buf[v.cursor-i:v.cursor])
+				return
+			}
+			v.cursor++
+		}
+	default:
+		if v.cursor < len(v.buf) && v.buf[v.cursor] != '\n' {
+			v.issueAt(v.cursor-1, "invalid escape sequence \\%c in string", v.buf[v.cursor])
+			v.cursor++
+		}
+	}
+}
+
+// number reads a number, taking in the letters and signs that follow it so that
+// mistakes like 1.0.0 or 0x10 are reported as one invalid literal.
+func (v *validator) number() {
+	start := v.cursor
+	v.cursor++
+	for v.cursor < len(v.buf) {
+		c := v.buf[v.cursor]
+		if !isWordChar(c) && c != '.' && c != '-' && c != '+' {
+			break
+		}
+		v.cursor++
+	}
+	if lit := v.buf[start:v.cursor]; !validNumber(lit) {
+		v.issueAt(start, "invalid number literal %s", lit)
+	}
+}
+
+// validNumber reports whether b matches the JSON number grammar.
+func validNumber(b []byte) bool {
+	i := 0
+	if i < len(b) && b[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		n := 0
+		for i < len(b) && b[i] >= '0' && b[i] <= '9' {
+			i++
+			n++
+		}
+		return n
+	}
+	switch {
+	case i < len(b) && b[i] == '0':
+		i++
+	case digits() == 0:
+		return false
+	}
+	if i < len(b) && b[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		i++
+		if i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(b)
+}
+
+func (v *validator) literal() {
+	start := v.cursor
+	for v.cursor < len(v.buf) && isWordChar(v.buf[v.cursor]) {
+		v.cursor++
+	}
+	switch lit := string(v.buf[start:v.cursor]); lit {
+	case "true", "false", "null":
+	case "True", "False", "TRUE", "FALSE", "Null", "NULL", "None", "nil":
+		v.issueAt(start, "invalid literal %s, literals are true, false and null in lowercase", lit)
+	default:
+		v.issueAt(start, "invalid literal %s, strings must be quoted", lit)
+	}
+}
+
+func quoteChar(c byte) string {
+	return fmt.Sprintf("%q", rune(c))
+}
+
+// checkValid reports whether data is valid JSON. Unlike ValidateAll it stops at the
+// first violation and does not allocate, for checking the output of MarshalJSON methods.
+func checkValid(data []byte) bool {
+	end, ok := validValue(data, skipSpace(data, 0))
+	return ok && skipSpace(data, end) == len(data)
+}
+
+func skipSpace(data []byte, i int) int {
+	for i < len(data) && isWhiteSpace[data[i]] {
+		i++
+	}
+	return i
+}
+
+// validValue reads the value at i, returning the offset following it and whether it is valid.
+func validValue(data []byte, i int) (int, bool) {
+	if i >= len(data) {
+		return i, false
+	}
+	var ok bool
+	switch c := data[i]; c {
+	case '{':
+		i = skipSpace(data, i+1)
+		if i < len(data) && data[i] == '}' {
+			return i + 1, true
+		}
+		for {
+			if i >= len(data) || data[i] != '"' {
+				return i, false
+			}
+			if i, ok = validString(data, i); !ok {
+				return i, false
+			}
+			i = skipSpace(data, i)
+			if i >= len(data) || data[i] != ':' {
+				return i, false
+			}
+			if i, ok = validValue(data, skipSpace(data, i+1)); !ok {
+				return i, false
+			}
+			i = skipSpace(data, i)
+			if i >= len(data) {
+				return i, false
+			}
+			switch data[i] {
+			case '}':
+				return i + 1, true
+			case ',':
+				i = skipSpace(data, i+1)
+			default:
+				return i, false
+			}
+		}
+	case '[':
+		i = skipSpace(data, i+1)
+		if i < len(data) && data[i] == ']' {
+			return i + 1, true
+		}
+		for {
+			if i, ok = validValue(data, i); !ok {
+				return i, false
+			}
+			i = skipSpace(data, i)
+			if i >= len(data) {
+				return i, false
+			}
+			switch data[i] {
+			case ']':
+				return i + 1, true
+			case ',':
+				i = skipSpace(data, i+1)
+			default:
+				return i, false
+			}
+		}
+	case '"':
+		return validString(data, i)
+	case 't':
+		return i + 4, bytes.HasPrefix(data[i:], []byte("true"))
+	case 'f':
+		return i + 5, bytes.HasPrefix(data[i:], []byte("false"))
+	case 'n':
+		return i + 4, bytes.HasPrefix(data[i:], []byte("null"))
+	}
+	start := i
+	for i < len(data) && (data[i] >= '0' && data[i] <= '9' || data[i] == '.' || data[i] == '-' || data[i] == '+' || data[i] == 'e' || data[i] == 'E') {
+		i++
+	}
+	return i, i > start && validNumber(data[start:i])
+}
+
+// validString reads the string at i, returning the offset following it and whether it is valid.
+func validString(data []byte, i int) (int, bool) {
+	for i++; i < len(data); i++ {
+		switch c := data[i]; {
+		case c == '"':
+			return i + 1, true
+		case c < 0x20:
+			return i, false
+		case c == '\\':
+			i++
+			if i >= len(data) {
+				return i, false
+			}
+			switch data[i] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			case 'u':
+				if i+4 >= len(data) {
+					return i, false
+				}
+				for _, h := range data[i+1 : i+5] {
+					if !('0' <= h && h <= '9' || 'a' <= h && h <= 'f' || 'A' <= h && h <= 'F') {
+						return i, false
+					}
+				}
+				i += 4
+			default:
+				return i, false
+			}
+		}
+	}
+	return i, false
+}
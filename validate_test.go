@@ -0,0 +1,100 @@
+package json_test
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+func Test_ValidateAll(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		for _, data := range []string{
+			`null`,
+			` {"a":[1,-2.5e+3,true,false,null],"b":{"c":"é\n"}} `,
+			`[]`,
+			`"x"`,
+		} {
+			if issues := json.ValidateAll([]byte(data)); issues != nil {
+				t.Errorf("%q: unexpected issues %v", data, issues)
+			}
+		}
+	})
+	t.Run("issues", func(t *testing.T) {
+		for _, test := range []struct {
+			data   string
+			issues []string
+		}{
+			{``, []string{"1:1: unexpected end of JSON input, expected a value"}},
+			{`{"a":1,}`, []string{"1:7: trailing comma before }"}},
+			{`{a:1}`, []string{"1:2: object key a must be a quoted string"}},
+			{`[1 2]`, []string{"1:4: missing comma between array elements"}},
+			{`{"a":[1,2}`, []string{"1:10: expected array to be closed before '}'"}},
+			{`[1,2`, []string{"1:5: unexpected end of JSON input, array opened at offset 0 is not closed"}},
+			{`{"a":1}}`, []string{"1:8: invalid character '}' after top-level value"}},
+			{`{"a" 1, "b":}`, []string{
+				"1:6: missing colon after object key",
+				"1:13: missing value after object key",
+			}},
+			{"{\n  \"a\": \"x,\n  \"b\": 2\n}", []string{"2:8: string is not closed before the end of the line"}},
+			{`[01, 1., -, 0x10, 1e5]`, []string{
+				"1:2: invalid number literal 01",
+				"1:6: invalid number literal 1.",
+				"1:10: invalid number literal -",
+				"1:13: invalid number literal 0x10",
+			}},
+			{`[True, undefined, 'x']`, []string{
+				"1:2: invalid literal True, literals are true, false and null in lowercase",
+				"1:8: invalid literal undefined, strings must be quoted",
+				`1:19: strings must be quoted with '"', not "'"`,
+			}},
+			{"// list\n[1,,2, /* two */ 3]", []string{
+				"1:1: comments are not allowed in JSON",
+				"2:4: expected a value but found ','",
+				"2:8: comments are not allowed in JSON",
+			}},
+			{`"\q\u12"`, []string{
+				`1:2: invalid escape sequence \q in string`,
+				`1:4: invalid escape sequence \u12 in string`,
+			}},
+			{`{"a":1 @ "b":2}`, []string{"1:8: invalid character '@' after object member"}},
+			{`{:1}`, []string{"1:2: missing object key before ':'"}},
+		} {
+			issues := json.ValidateAll([]byte(test.data))
+			got := make([]string, len(issues))
+			for i, issue := range issues {
+				got[i] = issue.String()
+			}
+			assertEq(t, test.data, fmt.Sprintf("%q", test.issues), fmt.Sprintf("%q", got))
+		}
+	})
+	t.Run("offset", func(t *testing.T) {
+		issues := json.ValidateAll([]byte("[\n1,\n]"))
+		assertEq(t, "issues", 1, len(issues))
+		assertEq(t, "offset", int64(3), issues[0].Offset)
+		assertEq(t, "line", 2, issues[0].Line)
+		assertEq(t, "column", 2, issues[0].Column)
+	})
+	t.Run("max depth", func(t *testing.T) {
+		issues := json.ValidateAll(bytes.Repeat([]byte("["), 2e6))
+		assertEq(t, "unclosed", `["1:10001: exceeded max depth of 10000 nested objects and arrays"]`, fmt.Sprintf("%q", issues))
+		deep := strings.Repeat("[", 10001) + `"]"` + strings.Repeat("]", 10001)
+		issues = json.ValidateAll([]byte(`{"a":` + deep[1:len(deep)-1] + `, "b":1 "c":2}`))
+		assertEq(t, "closed", `["1:10005: exceeded max depth of 10000 nested objects and arrays" "1:20017: missing comma between object members"]`, fmt.Sprintf("%q", issues))
+	})
+	t.Run("agrees with encoding/json", func(t *testing.T) {
+		for _, data := range []string{
+			`{"a":{"b":[{}]}}`, `[[[]]`, `{"a":1 "b":2}`, `[1,]`, `{"a"}`, `-0.0e-0`, `- 1`, `"\ud800"`,
+			"\"\t\"", `[1] [2]`, `{"":""}`, `nul`, `[,1]`, `1e`, `{]`, `[}`, `"`, `{"a":"b",`, "\"\xff\"",
+			strings.Repeat("[", 10001) + strings.Repeat("]", 10001),
+		} {
+			if got, want := json.ValidateAll([]byte(data)) == nil, stdjson.Valid([]byte(data)); got != want {
+				t.Errorf("%q: valid %v, encoding/json %v", data, got, want)
+			}
+		}
+		assertEq(t, "Valid", true, json.Valid([]byte("\"\xff\"")))
+	})
+}
@@ -0,0 +1,122 @@
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// Validator is the interface implemented by types that check their values, such as the
+// ranges of their fields. With Decoder.ValidateValues, Validate is called on the address of
+// every decoded value of such a type, after its AfterUnmarshalJSON method if any, and its
+// error is returned as a *ValidationError giving the location of the value.
+type Validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// validateDecoder calls Validate once dec decoded a value, with ValidateValues.
+type validateDecoder struct {
+	dec decoder
+	typ *rtype // the pointer type implementing Validator
+}
+
+// withValidate returns dec, the decoder of typ, calling Validate with ValidateValues
+// if the pointer type of typ implements Validator.
+func (d *Decoder) withValidate(dec decoder, typ *rtype) decoder {
+	if typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Interface {
+		return dec
+	}
+	ptrType := ptrTo(typ)
+	if !d.implements(ptrType, validatorType) {
+		return dec
+	}
+	return &validateDecoder{dec: dec, typ: ptrType}
+}
+
+func (d *validateDecoder) setDisallowUnknownFields(disallowUnknownFields bool) {
+	d.dec.setDisallowUnknownFields(disallowUnknownFields)
+}
+
+func (d *validateDecoder) decodeStream(s *stream, p uintptr) error {
+	if !s.validate {
+		return d.dec.decodeStream(s, p)
+	}
+	s.skipWhiteSpace()
+	isNull := s.char() == 'n'
+	start := s.totalOffset()
+	if err := d.dec.decodeStream(s, p); err != nil {
+		return err
+	}
+	if isNull {
+		return nil
+	}
+	return d.validate(p, start)
+}
+
+func (d *validateDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	if !ctx.validate {
+		return d.dec.decode(ctx, cursor, p)
+	}
+	cursor = skipWhiteSpace(ctx.buf, cursor)
+	isNull := ctx.buf[cursor] == 'n'
+	c, err := d.dec.decode(ctx, cursor, p)
+	if err != nil {
+		return 0, err
+	}
+	if isNull {
+		return c, nil
+	}
+	if err := d.validate(p, cursor); err != nil {
+		return 0, err
+	}
+	return c, nil
+}
+
+// validate calls the Validate method of the value at p, decoded from offset.
+func (d *validateDecoder) validate(p uintptr, offset int64) error {
+	v := *(*interface{})(unsafe.Pointer(&interfaceHeader{
+		typ: d.typ,
+		ptr: unsafe.Pointer(p),
+	}))
+	if err := v.(Validator).Validate(); err != nil {
+		return &ValidationError{Type: rtype2type(d.typ.Elem()), Offset: offset, Err: err}
+	}
+	return nil
+}
+
+// withFieldPath adds the member name of an object to the path of a *ValidationError.
+func withFieldPath(err error, name string) error {
+	if verr, ok := err.(*ValidationError); ok {
+		if verr.Path == "" || verr.Path[0] == '[' {
+			verr.Path = name + verr.Path
+		} else {
+			verr.Path = name + "." + verr.Path
+		}
+	}
+	return err
+}
+
+// withIndexPath adds the index of an array element to the path of a *ValidationError.
+func withIndexPath(err error, idx int) error {
+	if verr, ok := err.(*ValidationError); ok {
+		prefix := "[" + strconv.Itoa(idx) + "]"
+		if verr.Path == "" || verr.Path[0] == '[' {
+			verr.Path = prefix + verr.Path
+		} else {
+			verr.Path = prefix + "." + verr.Path
+		}
+	}
+	return err
+}
+
+// withMapKeyPath adds the key at p of a map of type mapType to the path of a *ValidationError.
+func withMapKeyPath(err error, mapType *rtype, p unsafe.Pointer) error {
+	if _, ok := err.(*ValidationError); !ok {
+		return err
+	}
+	key := reflect.NewAt(rtype2type(mapType.Key()), p).Elem().Interface()
+	return withFieldPath(err, fmt.Sprint(key))
+}
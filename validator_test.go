@@ -0,0 +1,115 @@
+package json_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+type validatedItem struct {
+	Name  string `json:"name"`
+	Price int    `json:"price"`
+}
+
+func (i *validatedItem) Validate() error {
+	if i.Price < 0 {
+		return errors.New("negative price")
+	}
+	return nil
+}
+
+type validatedOrder struct {
+	ID    string                   `json:"id"`
+	Items []validatedItem          `json:"items"`
+	Extra map[string]validatedItem `json:"extra"`
+}
+
+func (o *validatedOrder) Validate() error {
+	if o.ID == "" {
+		return errors.New("missing id")
+	}
+	return nil
+}
+
+func Test_ValidateValues(t *testing.T) {
+	decode := func(src string, v interface{}, continueOnError bool) error {
+		dec := json.NewDecoder(strings.NewReader(src))
+		dec.ValidateValues()
+		if continueOnError {
+			dec.ContinueOnElementError()
+		}
+		return dec.Decode(v)
+	}
+	t.Run("valid", func(t *testing.T) {
+		var o validatedOrder
+		assertErr(t, decode(`{"id":"a","items":[{"name":"x","price":1}]}`, &o, false))
+		assertEq(t, "price", 1, o.Items[0].Price)
+	})
+	t.Run("path", func(t *testing.T) {
+		var o validatedOrder
+		err := decode(`{"id":"a","items":[{"price":1},{"price":2},{"price":-3}]}`, &o, false)
+		var verr *json.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+		assertEq(t, "path", "items[2]", verr.Path)
+		assertEq(t, "message", "json: invalid json_test.validatedItem at items[2]: negative price", err.Error())
+		assertEq(t, "cause", "negative price", errors.Unwrap(err).Error())
+	})
+	t.Run("map", func(t *testing.T) {
+		var o validatedOrder
+		err := decode(`{"id":"a","extra":{"gift":{"price":-1}}}`, &o, false)
+		var verr *json.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+		assertEq(t, "path", "extra.gift", verr.Path)
+	})
+	t.Run("root", func(t *testing.T) {
+		var o validatedOrder
+		err := decode(`{"items":[]}`, &o, false)
+		var verr *json.ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+		assertEq(t, "path", "", verr.Path)
+		assertEq(t, "offset", int64(0), verr.Offset)
+	})
+	t.Run("null", func(t *testing.T) {
+		var o struct {
+			Items []validatedItem `json:"items"`
+		}
+		assertErr(t, decode(`{"items":null}`, &o, false))
+	})
+	t.Run("collect", func(t *testing.T) {
+		var items []validatedItem
+		err := decode(`[{"price":-1},{"price":2},{"price":-3}]`, &items, true)
+		var errs *json.ElementErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("expected element errors, got %v", err)
+		}
+		assertEq(t, "errors", 2, len(errs.Errors))
+		assertEq(t, "first", 0, errs.Errors[0].Index)
+		assertEq(t, "second", 2, errs.Errors[1].Index)
+		var verr *json.ValidationError
+		if !errors.As(errs.Errors[1].Err, &verr) {
+			t.Fatalf("expected a validation error, got %v", errs.Errors[1].Err)
+		}
+		assertEq(t, "items", 1, len(items))
+		assertEq(t, "kept", 2, items[0].Price)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		var o validatedOrder
+		assertErr(t, json.NewDecoder(strings.NewReader(`{"items":[{"price":-1}]}`)).Decode(&o))
+		assertErr(t, json.Unmarshal([]byte(`{"items":[{"price":-1}]}`), &o))
+	})
+	t.Run("config", func(t *testing.T) {
+		var o validatedOrder
+		dec := json.Config{ValidateValues: true}.Freeze().NewDecoder(strings.NewReader(`{"items":[]}`))
+		if err := dec.Decode(&o); err == nil {
+			t.Fatal("expected a validation error")
+		}
+	})
+}
@@ -0,0 +1,365 @@
+package json
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// wellKnownType encodes and decodes a standard library type that dominates many
+// payloads without calling its marshaler methods through an interface.
+// The result is the same as with the methods.
+type wellKnownType struct {
+	// appendText appends the text of the value at p to b, which is encoded
+	// as a JSON string, or is nil if values are encoded with their marshaler method.
+	appendText func(b []byte, p unsafe.Pointer) ([]byte, error)
+
+	// decodeString decodes the value at p from the content of a JSON string,
+	// or is nil if values are decoded with their unmarshaler method.
+	// null leaves the value unchanged, as the unmarshaler methods do.
+	decodeString func(s []byte, p unsafe.Pointer) error
+
+	// sourceFunc names the method in the errors of appendText, as in MarshalerError.
+	sourceFunc string
+}
+
+// wellKnownTypes holds time.Time, the net/netip types from wellknown_netip.go and the
+// UUID types of RegisterUUID. url.URL is not among them: it has no text methods,
+// so it is encoded as a struct like with encoding/json.
+var (
+	wellKnownTypesMu sync.RWMutex
+	wellKnownTypes   = map[*rtype]*wellKnownType{
+		type2rtype(reflect.TypeOf(time.Time{})): {
+			appendText:   appendTime,
+			decodeString: decodeTime,
+		},
+	}
+)
+
+func registerWellKnownType(typ reflect.Type, w *wellKnownType) {
+	wellKnownTypesMu.Lock()
+	defer wellKnownTypesMu.Unlock()
+	wellKnownTypes[type2rtype(typ)] = w
+}
+
+// lookupWellKnownType returns how values of typ are encoded and decoded
+// without their marshaler methods, or nil.
+func lookupWellKnownType(typ *rtype) *wellKnownType {
+	wellKnownTypesMu.RLock()
+	defer wellKnownTypesMu.RUnlock()
+	return wellKnownTypes[typ]
+}
+
+// RegisterUUID makes the [16]byte type of v, such as github.com/google/uuid.UUID,
+// encode and decode the canonical form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx in lowercase
+// directly, instead of calling its MarshalText and UnmarshalText methods:
+//
+//	json.RegisterUUID(uuid.UUID{})
+//
+// The methods are checked to use that form when v is registered. A method producing or
+// accepting another form is still called, as is UnmarshalText for the text of other forms.
+// UUID types must be registered before the first encoding or decoding of a type using them,
+// typically from an init function. RegisterUUID panics if v is not a [16]byte type with
+// text methods.
+func RegisterUUID(v interface{}) {
+	typ := reflect.TypeOf(v)
+	if typ == nil || !isUUIDArray(type2rtype(typ)) {
+		panic(fmt.Sprintf("json: RegisterUUID of %v, which is not a [16]byte type with text methods", typ))
+	}
+	registerWellKnownType(typ, uuidType(typ))
+}
+
+func appendTime(b []byte, p unsafe.Pointer) ([]byte, error) {
+	t := (*time.Time)(p)
+	_, offset := t.Zone()
+	if year := t.Year(); year < 0 || year > 9999 || offset <= -24*60*60 || offset >= 24*60*60 {
+		// out of the range of RFC 3339, for the error of MarshalJSON
+		_, err := t.MarshalJSON()
+		return b, err
+	}
+	return t.AppendFormat(b, time.RFC3339Nano), nil
+}
+
+func decodeTime(s []byte, p unsafe.Pointer) error {
+	return (*time.Time)(p).UnmarshalText(s)
+}
+
+// isUUIDArray reports whether typ is a [16]byte type with text methods,
+// the shape of UUID types such as github.com/google/uuid.UUID.
+func isUUIDArray(typ *rtype) bool {
+	if typ.Kind() != reflect.Array || typ.Len() != 16 || typ.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	return typ.Implements(marshalTextType) || ptrTo(typ).Implements(unmarshalTextType)
+}
+
+// uuidProbes are the values checked to recognize the canonical UUID text form.
+var uuidProbes = [][16]byte{
+	{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff},
+	{0xfe, 0xdc, 0xba, 0x98, 0x76, 0x54, 0x32, 0x10, 0x0f, 0x1e, 0x2d, 0x3c, 0x4b, 0x5a, 0x69, 0x78},
+}
+
+// uuidType returns the fast paths of a [16]byte type whose text methods use the
+// canonical form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx in lowercase, as checked on
+// uuidProbes. A side whose method produces or accepts another form, or that has
+// JSON methods taking precedence, keeps using its methods.
+func uuidType(typ reflect.Type) *wellKnownType {
+	rtyp, ptrType := type2rtype(typ), ptrTo(type2rtype(typ))
+	encodes := rtyp.Implements(marshalTextType) &&
+		!rtyp.Implements(marshalToType) && !rtyp.Implements(appenderType) && !rtyp.Implements(marshalJSONType)
+	decodes := ptrType.Implements(unmarshalTextType) &&
+		!ptrType.Implements(unmarshalJSONType) && !ptrType.Implements(unmarshalFromType)
+	for i := range uuidProbes {
+		probe := uuidProbes[i]
+		text := appendUUID(nil, &probe)
+		if encodes {
+			v := reflect.NewAt(typ, unsafe.Pointer(&probe)).Elem()
+			if got, err := v.Interface().(encoding.TextMarshaler).MarshalText(); err != nil || !bytes.Equal(got, text) {
+				encodes = false
+			}
+		}
+		if decodes {
+			decoded := reflect.New(typ)
+			err := decoded.Interface().(encoding.TextUnmarshaler).UnmarshalText(text)
+			if err != nil || *(*[16]byte)(unsafe.Pointer(decoded.Pointer())) != probe {
+				decodes = false
+			}
+		}
+	}
+	w := &wellKnownType{}
+	if encodes {
+		w.appendText = func(b []byte, p unsafe.Pointer) ([]byte, error) {
+			return appendUUID(b, (*[16]byte)(p)), nil
+		}
+	}
+	if decodes {
+		w.decodeString = func(s []byte, p unsafe.Pointer) error {
+			if parseUUID(s, (*[16]byte)(p)) {
+				return nil
+			}
+			v := reflect.NewAt(typ, p).Interface()
+			return v.(encoding.TextUnmarshaler).UnmarshalText(s)
+		}
+	}
+	return w
+}
+
+const lowerHex = "0123456789abcdef"
+
+// uuidDashes are the positions of the dashes in the canonical UUID form.
+var uuidDashes = [...]int{8, 13, 18, 23}
+
+func appendUUID(b []byte, u *[16]byte) []byte {
+	var text [36]byte
+	j := 0
+	for i, c := range u {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			text[j] = '-'
+			j++
+		}
+		text[j] = lowerHex[c>>4]
+		text[j+1] = lowerHex[c&0xf]
+		j += 2
+	}
+	return append(b, text[:]...)
+}
+
+// parseUUID parses s in the canonical lowercase UUID form into u and reports
+// whether it succeeded; other forms are left to the UnmarshalText method.
+func parseUUID(s []byte, u *[16]byte) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for _, i := range uuidDashes {
+		if s[i] != '-' {
+			return false
+		}
+	}
+	var parsed [16]byte
+	j := 0
+	for i := range parsed {
+		if j == 8 || j == 13 || j == 18 || j == 23 {
+			j++
+		}
+		hi, lo := lowerHexValue(s[j]), lowerHexValue(s[j+1])
+		if hi < 0 || lo < 0 {
+			return false
+		}
+		parsed[i] = byte(hi<<4 | lo)
+		j += 2
+	}
+	*u = parsed
+	return true
+}
+
+func lowerHexValue(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c - 'a' + 10)
+	}
+	return -1
+}
+
+type wellKnownCode struct {
+	*opcodeHeader
+	wellKnown *wellKnownType
+}
+
+func (c *wellKnownCode) copy(codeMap map[uintptr]*opcode) *opcode {
+	if c == nil {
+		return nil
+	}
+	addr := uintptr(unsafe.Pointer(c))
+	if code, exists := codeMap[addr]; exists {
+		return code
+	}
+	wellKnown := &wellKnownCode{wellKnown: c.wellKnown}
+	code := (*opcode)(unsafe.Pointer(wellKnown))
+	codeMap[addr] = code
+
+	wellKnown.opcodeHeader = c.opcodeHeader.copy(codeMap)
+	return code
+}
+
+func (c *opcode) toWellKnownCode() *wellKnownCode {
+	return (*wellKnownCode)(unsafe.Pointer(c))
+}
+
+// compileWellKnown returns the opcodes of typ or of the well-known type typ points to,
+// or nil if typ is not encoded by appendText of a well-known type.
+func (e *Encoder) compileWellKnown(typ *rtype) *opcode {
+	elem := typ
+	if typ.Kind() == reflect.Ptr {
+		elem = typ.Elem()
+	}
+	w := lookupWellKnownType(elem)
+	if w == nil && e.binaryMarshalers {
+		w = binaryType(elem, true, false)
+	}
+	if w == nil && e.stringers {
+		w = stringerWellKnownType(elem)
+	}
+	if w == nil || w.appendText == nil {
+		return nil
+	}
+	code := (*opcode)(unsafe.Pointer(&wellKnownCode{
+		opcodeHeader: &opcodeHeader{
+			op:     opWellKnown,
+			typ:    elem,
+			indent: e.indent,
+			next:   newEndOp(e.indent),
+		},
+		wellKnown: w,
+	}))
+	if typ != elem {
+		return newOpCode(opPtr, typ, e.indent, code)
+	}
+	return code
+}
+
+func (e *Encoder) encodeWellKnown(code *wellKnownCode) error {
+	if code.ptr == 0 {
+		e.encodeNull()
+		return nil
+	}
+	start := len(e.buf)
+	buf, err := code.wellKnown.appendText(append(e.buf, '"'), unsafe.Pointer(code.ptr))
+	if err != nil {
+		e.buf = buf[:start]
+		return &MarshalerError{Type: rtype2type(code.typ), Err: err, sourceFunc: code.wellKnown.sourceFunc}
+	}
+	for _, c := range buf[start+1:] {
+		if c < 0x20 || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' || c >= utf8.RuneSelf {
+			// rare, such as a zone of an IPv6 address
+			text := string(buf[start+1:])
+			e.buf = buf[:start]
+			e.encodeString(text)
+			return nil
+		}
+	}
+	e.buf = append(buf, '"')
+	return nil
+}
+
+// wellKnownDecoder decodes a well-known type with decodeString of its wellKnownType.
+type wellKnownDecoder struct {
+	typ       *rtype
+	wellKnown *wellKnownType
+}
+
+// newWellKnownDecoder returns the decoder of typ, or nil if typ is not decoded
+// by decodeString of a well-known type or, if binary is set, by UnmarshalBinary.
+func newWellKnownDecoder(typ *rtype, binary bool) *wellKnownDecoder {
+	w := lookupWellKnownType(typ)
+	if w == nil && binary {
+		w = binaryType(typ, false, true)
+	}
+	if w == nil || w.decodeString == nil {
+		return nil
+	}
+	return &wellKnownDecoder{typ: typ, wellKnown: w}
+}
+
+func (d *wellKnownDecoder) setDisallowUnknownFields(_ bool) {}
+
+func (d *wellKnownDecoder) decodeStream(s *stream, p uintptr) error {
+	s.skipWhiteSpace()
+	switch s.char() {
+	case 'n':
+		return nullBytes(s)
+	case '"':
+	default:
+		return d.errNotString(s.char(), s.totalOffset())
+	}
+	str, err := stringBytes(s)
+	if err != nil {
+		return err
+	}
+	return d.wellKnown.decodeString(str, unsafe.Pointer(p))
+}
+
+func (d *wellKnownDecoder) decode(ctx *runtimeContext, cursor int64, p uintptr) (int64, error) {
+	buf := ctx.buf
+	cursor = skipWhiteSpace(buf, cursor)
+	switch buf[cursor] {
+	case 'n', '"':
+	default:
+		return 0, d.errNotString(buf[cursor], cursor)
+	}
+	isNull := buf[cursor] == 'n'
+	str, c, err := (&stringDecoder{}).decodeByte(ctx, cursor)
+	if err != nil {
+		return 0, err
+	}
+	if isNull {
+		return c, nil
+	}
+	if err := d.wellKnown.decodeString(str, unsafe.Pointer(p)); err != nil {
+		return 0, err
+	}
+	return c, nil
+}
+
+func (d *wellKnownDecoder) errNotString(c byte, cursor int64) error {
+	var value string
+	switch c {
+	case '{':
+		value = "object"
+	case '[':
+		value = "array"
+	case 't', 'f':
+		value = "bool"
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		value = "number"
+	default:
+		return errNotAtBeginningOfValue(cursor)
+	}
+	return &UnmarshalTypeError{Value: value, Type: rtype2type(d.typ), Offset: cursor}
+}
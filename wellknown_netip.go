@@ -0,0 +1,37 @@
+//go:build go1.18
+// +build go1.18
+
+package json
+
+import (
+	"net/netip"
+	"reflect"
+	"unsafe"
+)
+
+func init() {
+	registerWellKnownType(reflect.TypeOf(netip.Addr{}), &wellKnownType{
+		appendText: func(b []byte, p unsafe.Pointer) ([]byte, error) {
+			return (*netip.Addr)(p).AppendTo(b), nil
+		},
+		decodeString: func(s []byte, p unsafe.Pointer) error {
+			return (*netip.Addr)(p).UnmarshalText(s)
+		},
+	})
+	registerWellKnownType(reflect.TypeOf(netip.AddrPort{}), &wellKnownType{
+		appendText: func(b []byte, p unsafe.Pointer) ([]byte, error) {
+			return (*netip.AddrPort)(p).AppendTo(b), nil
+		},
+		decodeString: func(s []byte, p unsafe.Pointer) error {
+			return (*netip.AddrPort)(p).UnmarshalText(s)
+		},
+	})
+	registerWellKnownType(reflect.TypeOf(netip.Prefix{}), &wellKnownType{
+		appendText: func(b []byte, p unsafe.Pointer) ([]byte, error) {
+			return (*netip.Prefix)(p).AppendTo(b), nil
+		},
+		decodeString: func(s []byte, p unsafe.Pointer) error {
+			return (*netip.Prefix)(p).UnmarshalText(s)
+		},
+	})
+}
@@ -0,0 +1,147 @@
+//go:build go1.18
+// +build go1.18
+
+package json_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+type canonicalUUID [16]byte
+
+func (u canonicalUUID) MarshalText() ([]byte, error) {
+	b := make([]byte, 36)
+	hex.Encode(b[0:8], u[0:4])
+	hex.Encode(b[9:13], u[4:6])
+	hex.Encode(b[14:18], u[6:8])
+	hex.Encode(b[19:23], u[8:10])
+	hex.Encode(b[24:], u[10:])
+	b[8], b[13], b[18], b[23] = '-', '-', '-', '-'
+	return b, nil
+}
+
+func (u *canonicalUUID) UnmarshalText(b []byte) error {
+	s := strings.TrimPrefix(strings.ToLower(string(b)), "urn:uuid:")
+	if len(s) != 36 {
+		return errors.New("invalid UUID length")
+	}
+	_, err := hex.Decode(u[:], []byte(strings.Replace(s, "-", "", -1)))
+	return err
+}
+
+type upperUUID [16]byte
+
+func (u upperUUID) MarshalText() ([]byte, error) {
+	return []byte(strings.ToUpper(hex.EncodeToString(u[:]))), nil
+}
+
+// unregisteredUUID has the methods of canonicalUUID but is not registered.
+type unregisteredUUID [16]byte
+
+func (u unregisteredUUID) MarshalText() ([]byte, error) {
+	return canonicalUUID(u).MarshalText()
+}
+
+func init() {
+	json.RegisterUUID(canonicalUUID{})
+	json.RegisterUUID(upperUUID{})
+}
+
+func Test_WellKnownTypes(t *testing.T) {
+	at := time.Date(2021, 3, 4, 5, 6, 7, 890, time.FixedZone("", 9*60*60))
+	t.Run("time", func(t *testing.T) {
+		type event struct {
+			At   time.Time  `json:"at"`
+			Ptr  *time.Time `json:"ptr"`
+			Any  interface{}
+			List []time.Time
+		}
+		want, _ := at.MarshalJSON()
+		b, err := json.Marshal(event{At: at, Any: at, List: []time.Time{at}})
+		assertErr(t, err)
+		assertEq(t, "event", `{"at":`+string(want)+`,"ptr":null,"Any":`+string(want)+`,"List":[`+string(want)+`]}`, string(b))
+		b, err = json.Marshal(&at)
+		assertErr(t, err)
+		assertEq(t, "pointer", string(want), string(b))
+
+		var v event
+		assertErr(t, json.Unmarshal([]byte(`{"at":`+string(want)+`,"ptr":`+string(want)+`}`), &v))
+		assertEq(t, "at", true, v.At.Equal(at))
+		assertEq(t, "ptr", true, v.Ptr != nil && v.Ptr.Equal(at))
+		assertErr(t, json.NewDecoder(strings.NewReader(`{"at":null}`)).Decode(&v))
+		assertEq(t, "null", true, v.At.Equal(at))
+		if err := json.Unmarshal([]byte(`{"at":"yesterday"}`), &v); err == nil {
+			t.Fatal("expected error for invalid time")
+		}
+		if err := json.Unmarshal([]byte(`{"at":1}`), &v); err == nil {
+			t.Fatal("expected error for number")
+		}
+		if _, err := json.Marshal(time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil {
+			t.Fatal("expected error for year outside of range")
+		}
+	})
+	t.Run("netip", func(t *testing.T) {
+		type host struct {
+			Addr   netip.Addr
+			Zoned  netip.Addr
+			Port   netip.AddrPort
+			Prefix netip.Prefix
+			Zero   netip.Addr
+		}
+		v := host{
+			Addr:   netip.MustParseAddr("2001:db8::1"),
+			Zoned:  netip.MustParseAddr(`fe80::1%a"b`),
+			Port:   netip.MustParseAddrPort("192.0.2.1:8080"),
+			Prefix: netip.MustParsePrefix("10.0.0.0/8"),
+		}
+		b, err := json.Marshal(v)
+		assertErr(t, err)
+		assertEq(t, "host", `{"Addr":"2001:db8::1","Zoned":"fe80::1%a\"b","Port":"192.0.2.1:8080","Prefix":"10.0.0.0/8","Zero":""}`, string(b))
+		var got host
+		assertErr(t, json.Unmarshal(b, &got))
+		assertEq(t, "decoded", v, got)
+		got = host{}
+		assertErr(t, json.NewDecoder(bytes.NewReader(b)).Decode(&got))
+		assertEq(t, "streamed", v, got)
+	})
+	t.Run("uuid", func(t *testing.T) {
+		u := canonicalUUID{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+		b, err := json.Marshal([]canonicalUUID{u})
+		assertErr(t, err)
+		assertEq(t, "uuid", `["123e4567-e89b-12d3-a456-426614174000"]`, string(b))
+		var got []canonicalUUID
+		assertErr(t, json.Unmarshal([]byte(`["123e4567-e89b-12d3-a456-426614174000","URN:UUID:123E4567-E89B-12D3-A456-426614174000"]`), &got))
+		assertEq(t, "len", 2, len(got))
+		assertEq(t, "canonical", u, got[0])
+		assertEq(t, "other form", u, got[1])
+		explained := json.Explain(u)
+		assertEq(t, "explain encoder", true, strings.Contains(explained, "encoder:\nWELL_KNOWN"))
+		assertEq(t, "explain decoder", true, strings.Contains(explained, "decoder:\nWELL_KNOWN"))
+
+		b, err = json.Marshal(upperUUID(u))
+		assertErr(t, err)
+		assertEq(t, "other text form", `"123E4567E89B12D3A456426614174000"`, string(b))
+		assertEq(t, "explain other text form", false, strings.Contains(json.Explain(upperUUID(u)), "WELL_KNOWN"))
+
+		b, err = json.Marshal(unregisteredUUID(u))
+		assertErr(t, err)
+		assertEq(t, "unregistered", `"123e4567-e89b-12d3-a456-426614174000"`, string(b))
+		assertEq(t, "explain unregistered", false, strings.Contains(json.Explain(unregisteredUUID(u)), "WELL_KNOWN"))
+	})
+	t.Run("register non-uuid", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic")
+			}
+		}()
+		json.RegisterUUID([16]byte{})
+	})
+}